@@ -14,6 +14,9 @@ const CtxDestinationId = "CtxDestinationId"
 
 var ErrTableNotExist = errors.New("table doesn't exist")
 
+//ErrObjectNotModified is returned by object storages GetObject if the object version (ETag or generation) wasn't changed
+var ErrObjectNotModified = errors.New("object wasn't modified")
+
 var notExistRegexp = regexp.MustCompile(`(?i)(not|doesn't)\sexist`)
 
 //SQLAdapter is a manager for DWH tables
@@ -27,6 +30,11 @@ type SQLAdapter interface {
 	Truncate(tableName string) error
 }
 
+//Pinger is implemented by adapters which can check the connection to the destination
+type Pinger interface {
+	Ping() error
+}
+
 //Adapter is an adapter for all destinations
 type Adapter interface {
 	io.Closer
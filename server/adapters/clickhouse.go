@@ -554,6 +554,13 @@ func (ch *ClickHouse) executeInsert(table *Table, headerWithQuotes []string, pla
 	return nil
 }
 
+//Ping checks the connection to ClickHouse
+func (ch *ClickHouse) Ping() error {
+	//keep select 1 and don't use Ping() because chproxy doesn't support /ping endpoint.
+	_, err := ch.dataSource.ExecContext(ch.ctx, "SELECT 1")
+	return err
+}
+
 //Close underlying sql.DB
 func (ch *ClickHouse) Close() error {
 	return ch.dataSource.Close()
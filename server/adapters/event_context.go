@@ -14,6 +14,10 @@ type EventContext struct {
 	ProcessedEvent events.Event
 	Table          *Table
 
+	//ConflictResolution is set by adapters which resolve primary key conflicts on insert:
+	//OnConflictUpdate if the existing row has been updated or OnConflictIgnore if the event has been skipped
+	ConflictResolution string
+
 	//HTTPRequest applicable only for HTTP events
 	HTTPRequest *Request
 }
@@ -6,12 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/schema"
+	"io/ioutil"
+	"net/http"
 	"strings"
 
 	"cloud.google.com/go/storage"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
 var ErrMalformedBQDataset = errors.New("bq_dataset must be alphanumeric (plus underscores) and must be at most 1024 characters long")
@@ -28,6 +32,11 @@ type GoogleConfig struct {
 	Dataset string      `mapstructure:"bq_dataset,omitempty" json:"bq_dataset,omitempty" yaml:"bq_dataset,omitempty"`
 	KeyFile interface{} `mapstructure:"key_file,omitempty" json:"key_file,omitempty" yaml:"key_file,omitempty"`
 
+	//ProxyURL, CABundle (PEM file path) and TLSSkipVerify configure HTTP client e.g. behind corporate proxies
+	ProxyURL      string `mapstructure:"proxy_url,omitempty" json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+	CABundle      string `mapstructure:"ca_bundle,omitempty" json:"ca_bundle,omitempty" yaml:"ca_bundle,omitempty"`
+	TLSSkipVerify bool   `mapstructure:"tls_skip_verify,omitempty" json:"tls_skip_verify,omitempty" yaml:"tls_skip_verify,omitempty"`
+
 	//will be set on validation
 	credentials option.ClientOption
 }
@@ -56,6 +65,9 @@ func (gc *GoogleConfig) Validate() error {
 			}
 		}
 	}
+	if err := validateStageTransport("Google", gc.ProxyURL, gc.CABundle); err != nil {
+		return err
+	}
 	switch gc.KeyFile.(type) {
 	case map[string]interface{}:
 		keyFileObject := gc.KeyFile.(map[string]interface{})
@@ -88,13 +100,20 @@ func (gc *GoogleConfig) Validate() error {
 }
 
 func NewGoogleCloudStorage(ctx context.Context, config *GoogleConfig) (*GoogleCloudStorage, error) {
-	var client *storage.Client
-	var err error
-	if config.credentials == nil {
-		client, err = storage.NewClient(ctx)
-	} else {
-		client, err = storage.NewClient(ctx, config.credentials)
+	var options []option.ClientOption
+	if config.credentials != nil {
+		options = append(options, config.credentials)
 	}
+	if isStageTransportConfigured(config.ProxyURL, config.CABundle, config.TLSSkipVerify) {
+		httpClient, err := newGoogleHTTPClient(ctx, config, options)
+		if err != nil {
+			return nil, err
+		}
+		//credentials are applied by the authorized transport
+		options = []option.ClientOption{option.WithHTTPClient(httpClient)}
+	}
+
+	client, err := storage.NewClient(ctx, options...)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating google cloud storage client: %v", err)
 	}
@@ -102,6 +121,21 @@ func NewGoogleCloudStorage(ctx context.Context, config *GoogleConfig) (*GoogleCl
 	return &GoogleCloudStorage{client: client, config: config, ctx: ctx}, nil
 }
 
+//newGoogleHTTPClient returns authorized HTTP client which uses configured proxy and CA bundle
+func newGoogleHTTPClient(ctx context.Context, config *GoogleConfig, options []option.ClientOption) (*http.Client, error) {
+	base, err := newStageTransport("Google", config.ProxyURL, config.CABundle, config.TLSSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := htransport.NewTransport(ctx, base, append(options, option.WithScopes(storage.ScopeFullControl))...)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating google cloud storage transport: %v", err)
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
 //Create named file on google cloud storage with payload
 func (gcs *GoogleCloudStorage) UploadBytes(fileName string, fileBytes []byte) error {
 	bucket := gcs.client.Bucket(gcs.config.Bucket)
@@ -131,6 +165,51 @@ func (gcs *GoogleCloudStorage) DeleteObject(key string) error {
 	return nil
 }
 
+//ListObjects returns objects under the prefix
+func (gcs *GoogleCloudStorage) ListObjects(prefix string) ([]*StageObject, error) {
+	it := gcs.client.Bucket(gcs.config.Bucket).Objects(gcs.ctx, &storage.Query{Prefix: prefix})
+	var objects []*StageObject
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error listing files with prefix %s from google cloud storage: %v", prefix, err)
+		}
+
+		objects = append(objects, &StageObject{Key: attrs.Name, LastModified: attrs.Updated})
+	}
+
+	return objects, nil
+}
+
+//GetObject returns object content and generation by key
+//returns ErrObjectNotModified if the object generation is equal to generation
+func (gcs *GoogleCloudStorage) GetObject(key string, generation int64) ([]byte, int64, error) {
+	object := gcs.client.Bucket(gcs.config.Bucket).Object(key)
+	attrs, err := object.Attrs(gcs.ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error getting file %s attributes from google cloud storage: %v", key, err)
+	}
+	if attrs.Generation == generation {
+		return nil, generation, ErrObjectNotModified
+	}
+
+	r, err := object.Generation(attrs.Generation).NewReader(gcs.ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error opening file %s from google cloud storage: %v", key, err)
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error reading file %s from google cloud storage: %v", key, err)
+	}
+
+	return b, attrs.Generation, nil
+}
+
 //ValidateWritePermission tries to create temporary file and remove it.
 //returns nil if file creation was successful.
 func (gcs *GoogleCloudStorage) ValidateWritePermission() error {
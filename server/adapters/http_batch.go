@@ -0,0 +1,236 @@
+package adapters
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	defaultHTTPBatchSize           = 100
+	defaultHTTPBatchTimeoutSeconds = 10
+	defaultHTTPBatchRetryCount     = 3
+	defaultHTTPBatchRetryDelayMs   = 1000
+	defaultHTTPBatchHMACHeader     = "X-Jitsu-Signature"
+)
+
+//HTTPBatchConfig is a dto for parsing HTTP batch destination configuration
+type HTTPBatchConfig struct {
+	URL            string            `mapstructure:"url,omitempty" json:"url,omitempty" yaml:"url,omitempty"`
+	Headers        map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty" yaml:"headers,omitempty"`
+	BatchSize      int               `mapstructure:"batch_size,omitempty" json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	TimeoutSeconds int               `mapstructure:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+	RetryCount     int               `mapstructure:"retry_count,omitempty" json:"retry_count,omitempty" yaml:"retry_count,omitempty"`
+	RetryDelayMs   int               `mapstructure:"retry_delay_ms,omitempty" json:"retry_delay_ms,omitempty" yaml:"retry_delay_ms,omitempty"`
+	TLS            *HTTPTLSConfig    `mapstructure:"tls,omitempty" json:"tls,omitempty" yaml:"tls,omitempty"`
+	HMACSecret     string            `mapstructure:"hmac_secret,omitempty" json:"hmac_secret,omitempty" yaml:"hmac_secret,omitempty"`
+	HMACHeader     string            `mapstructure:"hmac_header,omitempty" json:"hmac_header,omitempty" yaml:"hmac_header,omitempty"`
+}
+
+//HTTPResponseError is returned by HTTPBatch.Send if the server responds with non 2xx HTTP code
+type HTTPResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+//Error returns error message with HTTP code and response body
+func (hre *HTTPResponseError) Error() string {
+	return fmt.Sprintf("HTTP code = %d, body: %s", hre.StatusCode, hre.Body)
+}
+
+//Retryable returns true if the request can be retried: 5xx and 429 (Too Many Requests) responses
+//other 4xx responses are caused by data or configuration and retries won't help
+func (hre *HTTPResponseError) Retryable() bool {
+	return hre.StatusCode >= 500 || hre.StatusCode == http.StatusTooManyRequests
+}
+
+//HTTPTLSConfig is a dto for HTTP client TLS configuration. Certificates and keys are PEM encoded strings
+type HTTPTLSConfig struct {
+	CACert             string `mapstructure:"ca_cert,omitempty" json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"`
+	ClientCert         string `mapstructure:"client_cert,omitempty" json:"client_cert,omitempty" yaml:"client_cert,omitempty"`
+	ClientKey          string `mapstructure:"client_key,omitempty" json:"client_key,omitempty" yaml:"client_key,omitempty"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+}
+
+//Validate returns err if invalid and sets default values
+func (hbc *HTTPBatchConfig) Validate() error {
+	if hbc == nil {
+		return errors.New("HTTP config is required")
+	}
+	if hbc.URL == "" {
+		return errors.New("HTTP url is required parameter")
+	}
+	if hbc.BatchSize < 0 || hbc.TimeoutSeconds < 0 || hbc.RetryCount < 0 || hbc.RetryDelayMs < 0 {
+		return errors.New("HTTP batch_size, timeout_seconds, retry_count and retry_delay_ms must be positive")
+	}
+	if hbc.BatchSize == 0 {
+		hbc.BatchSize = defaultHTTPBatchSize
+	}
+	if hbc.TimeoutSeconds == 0 {
+		hbc.TimeoutSeconds = defaultHTTPBatchTimeoutSeconds
+	}
+	if hbc.RetryCount == 0 {
+		hbc.RetryCount = defaultHTTPBatchRetryCount
+	}
+	if hbc.RetryDelayMs == 0 {
+		hbc.RetryDelayMs = defaultHTTPBatchRetryDelayMs
+	}
+	if hbc.HMACHeader == "" {
+		hbc.HMACHeader = defaultHTTPBatchHMACHeader
+	}
+	if hbc.TLS != nil && (hbc.TLS.ClientCert == "") != (hbc.TLS.ClientKey == "") {
+		return errors.New("HTTP tls.client_cert and tls.client_key must be configured together")
+	}
+
+	return nil
+}
+
+//HTTPBatch is an adapter for sending batches of objects as JSON arrays in HTTP POST requests
+//with retries (exponential backoff) of network errors, 5xx and 429 responses and optional HMAC body signing
+type HTTPBatch struct {
+	config      *HTTPBatchConfig
+	client      *http.Client
+	debugLogger *logging.QueryLogger
+}
+
+//NewHTTPBatch returns configured HTTPBatch adapter
+func NewHTTPBatch(config *HTTPBatchConfig, debugLogger *logging.QueryLogger) (*HTTPBatch, error) {
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+	}
+
+	if config.TLS != nil {
+		tlsConfig, err := buildTLSConfig(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &HTTPBatch{
+		config: config,
+		client: &http.Client{
+			Timeout:   time.Duration(config.TimeoutSeconds) * time.Second,
+			Transport: transport,
+		},
+		debugLogger: debugLogger,
+	}, nil
+}
+
+//buildTLSConfig returns tls.Config with custom CA and client certificate
+func buildTLSConfig(config *HTTPTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CACert != "" {
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM([]byte(config.CACert)); !ok {
+			return nil, errors.New("Error parsing HTTP tls.ca_cert: PEM certificate is expected")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	if config.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(config.ClientCert), []byte(config.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing HTTP tls.client_cert and tls.client_key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+//BatchSize returns max objects quantity in one HTTP request
+func (hb *HTTPBatch) BatchSize() int {
+	return hb.config.BatchSize
+}
+
+//RetryCount returns quantity of retries after the first failed attempt
+func (hb *HTTPBatch) RetryCount() int {
+	return hb.config.RetryCount
+}
+
+//Send marshals objects as JSON array and sends it with retries
+//returns *HTTPResponseError (wrapped) if the server responds with non 2xx HTTP code. Not retryable responses aren't retried
+func (hb *HTTPBatch) Send(objects []map[string]interface{}) error {
+	body, err := json.Marshal(objects)
+	if err != nil {
+		return fmt.Errorf("Error marshalling batch: %v", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= hb.config.RetryCount; attempt++ {
+		if attempt > 0 {
+			//exponential backoff: delay, 2*delay, 4*delay, ...
+			time.Sleep(time.Duration(hb.config.RetryDelayMs) * time.Millisecond * time.Duration(1<<uint(attempt-1)))
+		}
+
+		lastErr = hb.doRequest(body)
+		if lastErr == nil {
+			return nil
+		}
+
+		var respErr *HTTPResponseError
+		if errors.As(lastErr, &respErr) && !respErr.Retryable() {
+			return fmt.Errorf("Error sending batch: %w", lastErr)
+		}
+	}
+
+	return fmt.Errorf("Error sending batch after %d retries: %w", hb.config.RetryCount, lastErr)
+}
+
+func (hb *HTTPBatch) doRequest(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hb.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range hb.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if hb.config.HMACSecret != "" {
+		req.Header.Set(hb.config.HMACHeader, SignHMAC(hb.config.HMACSecret, body))
+	}
+
+	if hb.debugLogger != nil {
+		hb.debugLogger.LogQuery(fmt.Sprintf("POST %s body size: %d bytes", hb.config.URL, len(body)))
+	}
+
+	resp, err := hb.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &HTTPResponseError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return nil
+}
+
+//SignHMAC returns 'sha256=<hex HMAC-SHA256 of body>' signature
+func SignHMAC(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//Close closes idle connections
+func (hb *HTTPBatch) Close() error {
+	hb.client.CloseIdleConnections()
+	return nil
+}
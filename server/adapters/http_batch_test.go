@@ -0,0 +1,128 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestHTTPBatchSend(t *testing.T) {
+	requests := atomic.NewInt32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//first request fails for checking retries
+		if requests.Inc() == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "value", r.Header.Get("X-Custom"))
+		require.Equal(t, SignHMAC("secret", body), r.Header.Get(defaultHTTPBatchHMACHeader))
+
+		var objects []map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &objects))
+		require.Len(t, objects, 2)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := &HTTPBatchConfig{URL: server.URL, Headers: map[string]string{"X-Custom": "value"}, RetryDelayMs: 1, HMACSecret: "secret"}
+	require.NoError(t, config.Validate())
+
+	adapter, err := NewHTTPBatch(config, nil)
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	require.NoError(t, adapter.Send([]map[string]interface{}{{"id": 1}, {"id": 2}}))
+	require.Equal(t, int32(2), requests.Load())
+}
+
+func TestHTTPBatchSendFailed(t *testing.T) {
+	tests := []struct {
+		name             string
+		statusCode       int
+		expectedRequests int32
+		expectedRetry    bool
+	}{
+		{
+			name:             "server error is retried",
+			statusCode:       http.StatusServiceUnavailable,
+			expectedRequests: 3,
+			expectedRetry:    true,
+		},
+		{
+			name:             "too many requests is retried",
+			statusCode:       http.StatusTooManyRequests,
+			expectedRequests: 3,
+			expectedRetry:    true,
+		},
+		{
+			name:             "bad request isn't retried",
+			statusCode:       http.StatusBadRequest,
+			expectedRequests: 1,
+		},
+		{
+			name:             "request entity too large isn't retried",
+			statusCode:       http.StatusRequestEntityTooLarge,
+			expectedRequests: 1,
+		},
+		{
+			name:             "unauthorized isn't retried",
+			statusCode:       http.StatusUnauthorized,
+			expectedRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := atomic.NewInt32(0)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Inc()
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte("error"))
+			}))
+			defer server.Close()
+
+			config := &HTTPBatchConfig{URL: server.URL, RetryCount: 2, RetryDelayMs: 1}
+			require.NoError(t, config.Validate())
+
+			adapter, err := NewHTTPBatch(config, nil)
+			require.NoError(t, err)
+			defer adapter.Close()
+
+			err = adapter.Send([]map[string]interface{}{{"id": 1}})
+			require.Error(t, err)
+			require.Equal(t, tt.expectedRequests, requests.Load(), "1 request + 2 retries if retryable")
+
+			var respErr *HTTPResponseError
+			require.True(t, errors.As(err, &respErr))
+			require.Equal(t, tt.statusCode, respErr.StatusCode)
+			require.Equal(t, "error", respErr.Body)
+			require.Equal(t, tt.expectedRetry, respErr.Retryable())
+		})
+	}
+}
+
+func TestHTTPBatchSendNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	//closed server: connection refused
+	server.Close()
+
+	config := &HTTPBatchConfig{URL: server.URL, RetryCount: 2, RetryDelayMs: 1}
+	require.NoError(t, config.Validate())
+
+	adapter, err := NewHTTPBatch(config, nil)
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	err = adapter.Send([]map[string]interface{}{{"id": 1}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "after 2 retries")
+}
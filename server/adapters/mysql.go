@@ -322,6 +322,11 @@ func (m *MySQL) Truncate(tableName string) error {
 	return sqlParams.commonTruncate(tableName, statement)
 }
 
+//Ping checks the connection to MySQL
+func (m *MySQL) Ping() error {
+	return m.dataSource.PingContext(m.ctx)
+}
+
 //Close underlying sql.DB
 func (m *MySQL) Close() error {
 	return m.dataSource.Close()
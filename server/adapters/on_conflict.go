@@ -0,0 +1,22 @@
+package adapters
+
+import "fmt"
+
+const (
+	//OnConflictInsert inserts a new row even if a row with the same primary key exists (default)
+	OnConflictInsert = "insert"
+	//OnConflictUpdate updates the existing row with the same primary key
+	OnConflictUpdate = "update"
+	//OnConflictIgnore keeps the existing row with the same primary key and skips the event
+	OnConflictIgnore = "ignore"
+)
+
+//ValidateOnConflict returns error if onConflict isn't empty and isn't one of OnConflictInsert, OnConflictUpdate, OnConflictIgnore
+func ValidateOnConflict(onConflict string) error {
+	switch onConflict {
+	case "", OnConflictInsert, OnConflictUpdate, OnConflictIgnore:
+		return nil
+	default:
+		return fmt.Errorf("Unknown on_conflict value: %s. Available values: [%s, %s, %s]", onConflict, OnConflictInsert, OnConflictUpdate, OnConflictIgnore)
+	}
+}
@@ -0,0 +1,31 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOnConflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		onConflict  string
+		expectedErr string
+	}{
+		{"empty", "", ""},
+		{"insert", OnConflictInsert, ""},
+		{"update", OnConflictUpdate, ""},
+		{"ignore", OnConflictIgnore, ""},
+		{"unknown", "replace", "Unknown on_conflict value: replace. Available values: [insert, update, ignore]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOnConflict(tt.onConflict)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
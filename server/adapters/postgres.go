@@ -731,6 +731,11 @@ func (p *Postgres) getDefaultValueStatement(sqlType string) string {
 	return "default 0"
 }
 
+//Ping checks the connection to Postgres
+func (p *Postgres) Ping() error {
+	return p.dataSource.PingContext(p.ctx)
+}
+
 //Close underlying sql.DB
 func (p *Postgres) Close() error {
 	return p.dataSource.Close()
@@ -6,12 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
 //S3 is a S3 adapter for uploading/deleting files
@@ -30,6 +33,11 @@ type S3Config struct {
 	Folder      string           `mapstructure:"folder,omitempty" json:"folder,omitempty" yaml:"folder,omitempty"`
 	Format      S3EncodingFormat `mapstructure:"format,omitempty" json:"format,omitempty" yaml:"format,omitempty"`
 	Compression S3Compression    `mapstructure:"compression,omitempty" json:"compression,omitempty" yaml:"compression,omitempty"`
+
+	//ProxyURL, CABundle (PEM file path) and TLSSkipVerify configure HTTP client e.g. behind corporate proxies
+	ProxyURL      string `mapstructure:"proxy_url,omitempty" json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+	CABundle      string `mapstructure:"ca_bundle,omitempty" json:"ca_bundle,omitempty" yaml:"ca_bundle,omitempty"`
+	TLSSkipVerify bool   `mapstructure:"tls_skip_verify,omitempty" json:"tls_skip_verify,omitempty" yaml:"tls_skip_verify,omitempty"`
 }
 
 type S3EncodingFormat string
@@ -60,7 +68,7 @@ func (s3c *S3Config) Validate() error {
 	if s3c.Region == "" {
 		return errors.New("S3 region is required parameter")
 	}
-	return nil
+	return validateStageTransport("S3", s3c.ProxyURL, s3c.CABundle)
 }
 
 //NewS3 returns configured S3 adapter
@@ -78,6 +86,13 @@ func NewS3(s3Config *S3Config) (*S3, error) {
 	if s3Config.Format == "" {
 		s3Config.Format = S3FormatFlatJSON
 	}
+	if isStageTransportConfigured(s3Config.ProxyURL, s3Config.CABundle, s3Config.TLSSkipVerify) {
+		transport, err := newStageTransport("S3", s3Config.ProxyURL, s3Config.CABundle, s3Config.TLSSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		awsConfig.WithHTTPClient(&http.Client{Transport: transport})
+	}
 	s3Session := session.Must(session.NewSession())
 
 	return &S3{client: s3.New(s3Session, awsConfig), config: s3Config}, nil
@@ -156,6 +171,57 @@ func (a *S3) DeleteObject(key string) error {
 	return nil
 }
 
+//ListObjects returns objects under the prefix. Config folder is applied to the prefix and removed from the returned keys
+//as well as gzip extension so the keys can be passed to DeleteObject
+func (a *S3) ListObjects(prefix string) ([]*StageObject, error) {
+	folderPrefix := ""
+	if a.config.Folder != "" {
+		folderPrefix = a.config.Folder + "/"
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.config.Bucket), Prefix: aws.String(folderPrefix + prefix)}
+	var objects []*StageObject
+	err := a.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			key := strings.TrimPrefix(aws.StringValue(object.Key), folderPrefix)
+			if a.config.Compression == S3CompressionGZIP {
+				key = strings.TrimSuffix(key, ".gz")
+			}
+			objects = append(objects, &StageObject{Key: key, LastModified: aws.TimeValue(object.LastModified)})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing files with prefix %s from s3: %v", folderPrefix+prefix, err)
+	}
+
+	return objects, nil
+}
+
+//GetObject returns object content and ETag by the full key (config folder isn't applied)
+//returns ErrObjectNotModified if the object ETag is equal to eTag
+func (a *S3) GetObject(key, eTag string) ([]byte, string, error) {
+	input := &s3.GetObjectInput{Bucket: aws.String(a.config.Bucket), Key: aws.String(key)}
+	if eTag != "" {
+		input.IfNoneMatch = aws.String(eTag)
+	}
+	output, err := a.client.GetObject(input)
+	if err != nil {
+		if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == http.StatusNotModified {
+			return nil, eTag, ErrObjectNotModified
+		}
+		return nil, "", fmt.Errorf("Error getting file %s from s3: %v", key, err)
+	}
+	defer output.Body.Close()
+
+	b, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("Error reading file %s from s3: %v", key, err)
+	}
+
+	return b, aws.StringValue(output.ETag), nil
+}
+
 func fileNameGZIP(fileName string) string {
 	return fileName + ".gz"
 }
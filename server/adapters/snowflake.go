@@ -3,41 +3,131 @@ package adapters
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/typing"
 	sf "github.com/snowflakedb/gosnowflake"
 )
 
 const (
-	tableExistenceSFQuery   = `SELECT count(*) from INFORMATION_SCHEMA.COLUMNS where TABLE_SCHEMA = ? and TABLE_NAME = ?`
-	descSchemaSFQuery       = `desc table %s.%s`
-	copyStatementFileFormat = ` FILE_FORMAT=(TYPE= 'CSV', FIELD_DELIMITER = '||' SKIP_HEADER = 1 EMPTY_FIELD_AS_NULL = true) `
-	gcpFrom                 = `FROM @%s
+	tableExistenceSFQuery      = `SELECT count(*) from INFORMATION_SCHEMA.COLUMNS where TABLE_SCHEMA = ? and TABLE_NAME = ?`
+	descSchemaSFQuery          = `desc table %s.%s`
+	tablesByPrefixSFQuery      = `SELECT TABLE_NAME from INFORMATION_SCHEMA.TABLES where TABLE_SCHEMA = ? and TABLE_TYPE = 'BASE TABLE' and STARTSWITH(TABLE_NAME, ?)`
+	copyStatementFileFormat    = ` FILE_FORMAT=(TYPE= 'CSV', FIELD_DELIMITER = '||' SKIP_HEADER = 1 EMPTY_FIELD_AS_NULL = true FIELD_OPTIONALLY_ENCLOSED_BY = '"' ESCAPE = NONE ESCAPE_UNENCLOSED_FIELD = NONE) `
+	copyStatementJSONFormat    = ` FILE_FORMAT=(TYPE= 'JSON') MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE `
+	copyStatementParquetFormat = ` FILE_FORMAT=(TYPE= 'PARQUET') MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE `
+	gcpFrom                    = `FROM @%s
    							   %s
                                PATTERN = '%s'`
 	awsS3From = `FROM 's3://%s/%s'
 					           CREDENTIALS = (aws_key_id='%s' aws_secret_key='%s') 
                                %s`
+	copyPurgeOption = ` PURGE = TRUE`
 
 	sfMergeStatement = `MERGE INTO %s.%s USING (SELECT %s FROM %s.%s) %s ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)`
+	//single row MERGE is used in stream mode with on_conflict: update or ignore. WHEN MATCHED clause is optional
+	sfMergeRowStatement = `MERGE INTO %s.%s USING (SELECT %s) %s ON %s%s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)`
+	sfMergeRowSource    = `jitsu_src`
+	//multi-row MERGE is used for updating many records with the same columns by unique ID (e.g. users recognition)
+	sfMergeUpdateStatement = `MERGE INTO %s.%s USING (%s) %s ON %s.%s = %s.%s WHEN MATCHED THEN UPDATE SET %s`
+	sfMergeUpdateKey       = `jitsu_update_key`
+	//sfTestQuery is used in the destination preflight
+	sfTestQuery = `SELECT 1`
+	//sfCopyHistoryQuery counts successful loads of the stage file into the table since the start time
+	sfCopyHistoryQuery = `SELECT COUNT(*) FROM TABLE(information_schema.copy_history(TABLE_NAME => ?, START_TIME => ?)) WHERE STATUS = 'Loaded' AND CONTAINS(FILE_NAME, ?)`
 
 	createSFDbSchemaIfNotExistsTemplate = `CREATE SCHEMA IF NOT EXISTS %s`
 	addSFColumnTemplate                 = `ALTER TABLE %s.%s ADD COLUMN %s`
+	alterSFColumnTypeTemplate           = `ALTER TABLE %s.%s ALTER COLUMN %s SET DATA TYPE %s`
 	createSFTableTemplate               = `CREATE TABLE %s.%s (%s)`
 	insertSFTemplate                    = `INSERT INTO %s.%s (%s) VALUES %s`
 	deleteSFTemplate                    = `DELETE FROM %s.%s WHERE %s`
 	dropSFTableTemplate                 = `DROP TABLE %s.%s`
 	truncateSFTableTemplate             = `TRUNCATE TABLE IF EXISTS %s.%s`
+	deleteOlderThanSFTemplate           = `DELETE FROM %s.%s WHERE %s < ?`
 	updateSFTemplate                    = `UPDATE %s.%s SET %s WHERE %s = ?`
+	createOrReplaceSFViewTemplate       = `CREATE OR REPLACE VIEW %s.%s AS %s`
+	currentSFSessionQuery               = `SELECT CURRENT_ROLE(), CURRENT_WAREHOUSE()`
+	useSFRoleTemplate                   = `USE ROLE %s`
+	useSFWarehouseTemplate              = `USE WAREHOUSE %s`
+	executeSFTaskTemplate               = `EXECUTE TASK %s`
+
+	//KeepStageFilesNever deletes stage files after every COPY (successful or not)
+	KeepStageFilesNever = "never"
+	//KeepStageFilesOnError keeps stage files which weren't copied into Snowflake
+	KeepStageFilesOnError = "on_error"
+	//KeepStageFilesAlways keeps all stage files
+	KeepStageFilesAlways = "always"
+
+	//StageSelectionHash spreads stage files across all configured stage buckets by the file name hash
+	StageSelectionHash = "hash"
+	//StageSelectionRegion uploads stage files into the bucket in the deployment region (s3 section bucket if there is no such bucket)
+	StageSelectionRegion = "region"
+
+	defaultStageFilesTTLHours = 24
+
+	defaultCopyFlushIntervalSec = 60
+
+	defaultUpdateBatchSize = 100
+
+	defaultStageUploadConcurrency = 4
+
+	defaultCloseTimeoutSec = 30
+
+	defaultRetentionIntervalHours = 24
+
+	defaultPostLoadMinIntervalSec = 60
+
+	//SnowflakeMaxIdentifierLength is a max length of Snowflake identifiers e.g. table names
+	SnowflakeMaxIdentifierLength = 255
+
+	//StageFormatCSV is a default stage files format: csv with '||' delimiter and header
+	StageFormatCSV = "csv"
+	//StageFormatJSON is a stage files format: json objects with \n delimiter
+	StageFormatJSON = "json"
+	//StageFormatParquet is a stage files format: apache parquet file
+	StageFormatParquet = "parquet"
+
+	//TableShardingDaily writes events into tables with the event date suffix e.g. events_20240101
+	TableShardingDaily = "daily"
+	//TableShardingMonthly writes events into tables with the event month suffix e.g. events_202401
+	TableShardingMonthly = "monthly"
+
+	//TimestampNTZ keeps wall clock time without timezone: time values are converted into UTC before loading
+	TimestampNTZ = "TIMESTAMP_NTZ"
+	//TimestampTZ keeps time with the timezone offset of the value
+	TimestampTZ = "TIMESTAMP_TZ"
+	//TimestampLTZ keeps UTC time which is shown in the session timezone
+	TimestampLTZ = "TIMESTAMP_LTZ"
 )
 
 var (
+	//sfReservedKeywords can't be used as unquoted identifiers
+	//https://docs.snowflake.com/en/sql-reference/reserved-keywords.html
+	sfReservedKeywords = map[string]bool{
+		"ACCOUNT": true, "ALL": true, "ALTER": true, "AND": true, "ANY": true, "AS": true, "BETWEEN": true, "BY": true,
+		"CASE": true, "CAST": true, "CHECK": true, "COLUMN": true, "CONNECT": true, "CONNECTION": true, "CONSTRAINT": true,
+		"CREATE": true, "CROSS": true, "CURRENT": true, "CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
+		"CURRENT_USER": true, "DATABASE": true, "DEFAULT": true, "DELETE": true, "DISTINCT": true, "DROP": true, "ELSE": true,
+		"EXISTS": true, "FALSE": true, "FOLLOWING": true, "FOR": true, "FROM": true, "FULL": true, "GRANT": true, "GROUP": true,
+		"GSCLUSTER": true, "HAVING": true, "ILIKE": true, "IN": true, "INCREMENT": true, "INNER": true, "INSERT": true,
+		"INTERSECT": true, "INTO": true, "IS": true, "ISSUE": true, "JOIN": true, "LATERAL": true, "LEFT": true, "LIKE": true,
+		"LOCALTIME": true, "LOCALTIMESTAMP": true, "MINUS": true, "NATURAL": true, "NOT": true, "NULL": true, "OF": true,
+		"ON": true, "OR": true, "ORDER": true, "ORGANIZATION": true, "QUALIFY": true, "REGEXP": true, "REVOKE": true,
+		"RIGHT": true, "RLIKE": true, "ROW": true, "ROWS": true, "SAMPLE": true, "SCHEMA": true, "SELECT": true, "SET": true,
+		"SOME": true, "START": true, "TABLE": true, "TABLESAMPLE": true, "THEN": true, "TO": true, "TRIGGER": true, "TRUE": true,
+		"TRY_CAST": true, "UNION": true, "UNIQUE": true, "UPDATE": true, "USING": true, "VALUES": true, "VIEW": true,
+		"WHEN": true, "WHENEVER": true, "WHERE": true, "WITH": true,
+	}
+
 	SchemaToSnowflake = map[typing.DataType]string{
 		typing.STRING:    "text",
 		typing.INT64:     "bigint",
@@ -57,10 +147,109 @@ type SnowflakeConfig struct {
 	Username   string             `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
 	Password   string             `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
 	Warehouse  string             `mapstructure:"warehouse,omitempty" json:"warehouse,omitempty" yaml:"warehouse,omitempty"`
+	Role       string             `mapstructure:"role,omitempty" json:"role,omitempty" yaml:"role,omitempty"`
 	Stage      string             `mapstructure:"stage,omitempty" json:"stage,omitempty" yaml:"stage,omitempty"`
 	Parameters map[string]*string `mapstructure:"parameters,omitempty" json:"parameters,omitempty" yaml:"parameters,omitempty"`
 	S3         *S3Config          `mapstructure:"s3,omitempty" json:"s3,omitempty" yaml:"s3,omitempty"`
 	Google     *GoogleConfig      `mapstructure:"google,omitempty" json:"google,omitempty" yaml:"google,omitempty"`
+
+	StageFormat        string `mapstructure:"stage_format,omitempty" json:"stage_format,omitempty" yaml:"stage_format,omitempty"`
+	KeepStageFiles     string `mapstructure:"keep_stage_files,omitempty" json:"keep_stage_files,omitempty" yaml:"keep_stage_files,omitempty"`
+	StageFilesTTLHours int    `mapstructure:"stage_files_ttl_hours,omitempty" json:"stage_files_ttl_hours,omitempty" yaml:"stage_files_ttl_hours,omitempty"`
+
+	MaxOpenConns       int `mapstructure:"max_open_conns,omitempty" json:"max_open_conns,omitempty" yaml:"max_open_conns,omitempty"`
+	MaxIdleConns       int `mapstructure:"max_idle_conns,omitempty" json:"max_idle_conns,omitempty" yaml:"max_idle_conns,omitempty"`
+	ConnMaxLifetimeSec int `mapstructure:"conn_max_lifetime_sec,omitempty" json:"conn_max_lifetime_sec,omitempty" yaml:"conn_max_lifetime_sec,omitempty"`
+	QueryTimeoutSec    int `mapstructure:"query_timeout_sec,omitempty" json:"query_timeout_sec,omitempty" yaml:"query_timeout_sec,omitempty"`
+
+	TableSharding     string `mapstructure:"table_sharding,omitempty" json:"table_sharding,omitempty" yaml:"table_sharding,omitempty"`
+	ShardingUnionView bool   `mapstructure:"sharding_union_view,omitempty" json:"sharding_union_view,omitempty" yaml:"sharding_union_view,omitempty"`
+	//TimestampField is the event timestamp field which is used by table sharding, retention and load metadata (_timestamp by default)
+	TimestampField string `mapstructure:"timestamp_field,omitempty" json:"timestamp_field,omitempty" yaml:"timestamp_field,omitempty"`
+	//TimestampType is a type of timestamp columns: TIMESTAMP_NTZ, TIMESTAMP_TZ or TIMESTAMP_LTZ (default: timestamp(6) which depends on TIMESTAMP_TYPE_MAPPING)
+	TimestampType string `mapstructure:"timestamp_type,omitempty" json:"timestamp_type,omitempty" yaml:"timestamp_type,omitempty"`
+
+	//CopyFlushRows enables accumulating stage files of the same table and loading them with a single COPY (0 - disabled)
+	CopyFlushRows int `mapstructure:"copy_flush_rows,omitempty" json:"copy_flush_rows,omitempty" yaml:"copy_flush_rows,omitempty"`
+	//CopyFlushInterval is a max time in seconds which accumulated stage files wait for COPY
+	CopyFlushInterval int `mapstructure:"copy_flush_interval,omitempty" json:"copy_flush_interval,omitempty" yaml:"copy_flush_interval,omitempty"`
+
+	//DDLWarehouse and DDLRole are used for schema changes (creating schemas, tables, views and columns) instead of warehouse and role
+	DDLWarehouse string `mapstructure:"ddl_warehouse,omitempty" json:"ddl_warehouse,omitempty" yaml:"ddl_warehouse,omitempty"`
+	DDLRole      string `mapstructure:"ddl_role,omitempty" json:"ddl_role,omitempty" yaml:"ddl_role,omitempty"`
+
+	//AddLoadMetadata enables writing load time, stage file name and destination ID into every row in batch mode
+	AddLoadMetadata bool `mapstructure:"add_load_metadata,omitempty" json:"add_load_metadata,omitempty" yaml:"add_load_metadata,omitempty"`
+
+	//CopyPurge enables PURGE = TRUE COPY option: successfully loaded stage files are deleted by Snowflake
+	CopyPurge bool `mapstructure:"copy_purge,omitempty" json:"copy_purge,omitempty" yaml:"copy_purge,omitempty"`
+
+	//StoreTimeoutSec is a max time of storing one table in batch mode (stage upload and COPY)
+	StoreTimeoutSec int `mapstructure:"store_timeout_sec,omitempty" json:"store_timeout_sec,omitempty" yaml:"store_timeout_sec,omitempty"`
+	//CloseTimeoutSec is a max time of closing every destination resource (streaming worker, connection, stage) on shutdown or reload
+	CloseTimeoutSec int `mapstructure:"close_timeout_sec,omitempty" json:"close_timeout_sec,omitempty" yaml:"close_timeout_sec,omitempty"`
+
+	//UpdateBatchSize is a max number of records which are updated with one MERGE statement (1 - one UPDATE statement per record)
+	UpdateBatchSize int `mapstructure:"update_batch_size,omitempty" json:"update_batch_size,omitempty" yaml:"update_batch_size,omitempty"`
+
+	//StageFileMaxRows splits table files with more rows into several stage files (chunks) which are loaded with one COPY (0 - no splitting)
+	StageFileMaxRows int `mapstructure:"stage_file_max_rows,omitempty" json:"stage_file_max_rows,omitempty" yaml:"stage_file_max_rows,omitempty"`
+	//StageUploadConcurrency is a max number of chunks of one table file which are uploaded into the stage in parallel
+	StageUploadConcurrency int `mapstructure:"stage_upload_concurrency,omitempty" json:"stage_upload_concurrency,omitempty" yaml:"stage_upload_concurrency,omitempty"`
+
+	//Preflight enables writing and deleting a probe object in the stage and running a test query on the destination creation
+	Preflight bool `mapstructure:"preflight,omitempty" json:"preflight,omitempty" yaml:"preflight,omitempty"`
+
+	//S3Stages are S3 buckets (e.g. regional ones) which are used for staging in addition to s3 section according to StageSelection
+	S3Stages []*S3Config `mapstructure:"s3_stages,omitempty" json:"s3_stages,omitempty" yaml:"s3_stages,omitempty"`
+	//StageSelection is a rule of choosing the stage bucket for every file: hash or region (the bucket in DeploymentRegion)
+	StageSelection   string `mapstructure:"stage_selection,omitempty" json:"stage_selection,omitempty" yaml:"stage_selection,omitempty"`
+	DeploymentRegion string `mapstructure:"deployment_region,omitempty" json:"deployment_region,omitempty" yaml:"deployment_region,omitempty"`
+
+	//RetentionDays enables background deletion of retention_tables rows which are older than N days by retention_column value
+	RetentionDays          int      `mapstructure:"retention_days,omitempty" json:"retention_days,omitempty" yaml:"retention_days,omitempty"`
+	RetentionColumn        string   `mapstructure:"retention_column,omitempty" json:"retention_column,omitempty" yaml:"retention_column,omitempty"`
+	RetentionTables        []string `mapstructure:"retention_tables,omitempty" json:"retention_tables,omitempty" yaml:"retention_tables,omitempty"`
+	RetentionIntervalHours int      `mapstructure:"retention_interval_hours,omitempty" json:"retention_interval_hours,omitempty" yaml:"retention_interval_hours,omitempty"`
+
+	//PostLoadSQL is a statement which is executed after successful loads (e.g. for triggering downstream transformations)
+	PostLoadSQL string `mapstructure:"post_load_sql,omitempty" json:"post_load_sql,omitempty" yaml:"post_load_sql,omitempty"`
+	//PostLoadTask is a Snowflake TASK name which is executed (EXECUTE TASK) after successful loads instead of post_load_sql
+	PostLoadTask string `mapstructure:"post_load_task,omitempty" json:"post_load_task,omitempty" yaml:"post_load_task,omitempty"`
+	//PostLoadMinIntervalSec is a min time between post-load hook runs: loads during this time are covered by one next run
+	PostLoadMinIntervalSec int `mapstructure:"post_load_min_interval_sec,omitempty" json:"post_load_min_interval_sec,omitempty" yaml:"post_load_min_interval_sec,omitempty"`
+}
+
+//NormalizeSnowflakeTimestamp returns time value in UTC if timestamp columns are TIMESTAMP_NTZ:
+//NTZ columns don't keep the offset, so values with different offsets must be loaded as UTC wall clock time
+func NormalizeSnowflakeTimestamp(timestampType string, value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok && timestampType == TimestampNTZ {
+		return t.UTC()
+	}
+
+	return value
+}
+
+//ColumnTypesMapping returns SchemaToSnowflake with the configured timestamp_type (microseconds precision)
+func (sc *SnowflakeConfig) ColumnTypesMapping() map[typing.DataType]string {
+	mapping := make(map[typing.DataType]string, len(SchemaToSnowflake))
+	for dataType, sqlType := range SchemaToSnowflake {
+		mapping[dataType] = sqlType
+	}
+	if sc.TimestampType != "" {
+		mapping[typing.TIMESTAMP] = sc.TimestampType + "(6)"
+	}
+
+	return mapping
+}
+
+//PostLoadStatement returns the statement of the post-load hook or empty string if the hook isn't configured
+func (sc *SnowflakeConfig) PostLoadStatement() string {
+	if sc.PostLoadTask != "" {
+		return fmt.Sprintf(executeSFTaskTemplate, sc.PostLoadTask)
+	}
+
+	return sc.PostLoadSQL
 }
 
 //Validate required fields in SnowflakeConfig
@@ -85,6 +274,113 @@ func (sc *SnowflakeConfig) Validate() error {
 		sc.Parameters = map[string]*string{}
 	}
 
+	switch sc.StageFormat {
+	case "":
+		sc.StageFormat = StageFormatCSV
+	case StageFormatCSV, StageFormatJSON, StageFormatParquet:
+	default:
+		return fmt.Errorf("Unknown Snowflake stage_format value: %s. Available values: [%s, %s, %s]", sc.StageFormat, StageFormatCSV, StageFormatJSON, StageFormatParquet)
+	}
+
+	switch sc.KeepStageFiles {
+	case "":
+		sc.KeepStageFiles = KeepStageFilesNever
+	case KeepStageFilesNever, KeepStageFilesOnError, KeepStageFilesAlways:
+	default:
+		return fmt.Errorf("Unknown Snowflake keep_stage_files value: %s. Available values: [%s, %s, %s]", sc.KeepStageFiles, KeepStageFilesNever, KeepStageFilesOnError, KeepStageFilesAlways)
+	}
+	if sc.CopyPurge && sc.KeepStageFiles == KeepStageFilesAlways {
+		return fmt.Errorf("Snowflake copy_purge can't be used with keep_stage_files: %s", KeepStageFilesAlways)
+	}
+	if sc.StageFilesTTLHours < 0 {
+		return errors.New("Snowflake stage_files_ttl_hours must be positive")
+	}
+	if sc.StageFilesTTLHours == 0 {
+		sc.StageFilesTTLHours = defaultStageFilesTTLHours
+	}
+	if sc.MaxOpenConns < 0 || sc.MaxIdleConns < 0 || sc.ConnMaxLifetimeSec < 0 || sc.QueryTimeoutSec < 0 {
+		return errors.New("Snowflake max_open_conns, max_idle_conns, conn_max_lifetime_sec and query_timeout_sec must be positive")
+	}
+	if sc.StoreTimeoutSec < 0 || sc.CloseTimeoutSec < 0 {
+		return errors.New("Snowflake store_timeout_sec and close_timeout_sec must be positive")
+	}
+	if sc.CloseTimeoutSec == 0 {
+		sc.CloseTimeoutSec = defaultCloseTimeoutSec
+	}
+	if sc.UpdateBatchSize < 0 {
+		return errors.New("Snowflake update_batch_size must be positive")
+	}
+	if sc.UpdateBatchSize == 0 {
+		sc.UpdateBatchSize = defaultUpdateBatchSize
+	}
+	if sc.StageFileMaxRows < 0 || sc.StageUploadConcurrency < 0 {
+		return errors.New("Snowflake stage_file_max_rows and stage_upload_concurrency must be positive")
+	}
+	if sc.StageUploadConcurrency == 0 {
+		sc.StageUploadConcurrency = defaultStageUploadConcurrency
+	}
+	if len(sc.S3Stages) > 0 {
+		switch sc.StageSelection {
+		case "":
+			sc.StageSelection = StageSelectionHash
+		case StageSelectionHash:
+		case StageSelectionRegion:
+			if sc.DeploymentRegion == "" {
+				return fmt.Errorf("Snowflake deployment_region is required parameter with stage_selection: %s", StageSelectionRegion)
+			}
+		default:
+			return fmt.Errorf("Unknown Snowflake stage_selection value: %s. Available values: [%s, %s]", sc.StageSelection, StageSelectionHash, StageSelectionRegion)
+		}
+		if sc.CopyFlushRows > 0 {
+			return errors.New("Snowflake copy_flush_rows can't be used with s3_stages: accumulated files must be in one stage bucket")
+		}
+	}
+	if sc.RetentionDays < 0 || sc.RetentionIntervalHours < 0 {
+		return errors.New("Snowflake retention_days and retention_interval_hours must be positive")
+	}
+	if sc.TimestampField == "" {
+		sc.TimestampField = timestamp.Key
+	}
+	switch sc.TimestampType {
+	case "", TimestampNTZ, TimestampTZ, TimestampLTZ:
+	default:
+		return fmt.Errorf("Unknown Snowflake timestamp_type value: %s. Available values: [%s, %s, %s]", sc.TimestampType, TimestampNTZ, TimestampTZ, TimestampLTZ)
+	}
+	if sc.RetentionDays > 0 {
+		if len(sc.RetentionTables) == 0 {
+			return errors.New("Snowflake retention_tables is required parameter if retention_days is set")
+		}
+		if sc.RetentionColumn == "" {
+			sc.RetentionColumn = sc.TimestampField
+		}
+		if sc.RetentionIntervalHours == 0 {
+			sc.RetentionIntervalHours = defaultRetentionIntervalHours
+		}
+	}
+	switch sc.TableSharding {
+	case "", TableShardingDaily, TableShardingMonthly:
+	default:
+		return fmt.Errorf("Unknown Snowflake table_sharding value: %s. Available values: [%s, %s]", sc.TableSharding, TableShardingDaily, TableShardingMonthly)
+	}
+	if sc.ShardingUnionView && sc.TableSharding == "" {
+		return errors.New("Snowflake sharding_union_view requires table_sharding")
+	}
+	if sc.CopyFlushRows < 0 || sc.CopyFlushInterval < 0 {
+		return errors.New("Snowflake copy_flush_rows and copy_flush_interval must be positive")
+	}
+	if sc.CopyFlushRows > 0 && sc.CopyFlushInterval == 0 {
+		sc.CopyFlushInterval = defaultCopyFlushIntervalSec
+	}
+	if sc.PostLoadSQL != "" && sc.PostLoadTask != "" {
+		return errors.New("Snowflake post_load_sql and post_load_task can't be used together")
+	}
+	if sc.PostLoadMinIntervalSec < 0 {
+		return errors.New("Snowflake post_load_min_interval_sec must be positive")
+	}
+	if sc.PostLoadMinIntervalSec == 0 {
+		sc.PostLoadMinIntervalSec = defaultPostLoadMinIntervalSec
+	}
+
 	sc.Schema = reformatValue(sc.Schema)
 	return nil
 }
@@ -97,6 +393,9 @@ type Snowflake struct {
 	dataSource  *sql.DB
 	queryLogger *logging.QueryLogger
 	sqlTypes    typing.SQLTypes
+
+	//onConflict is applied to Insert of tables with primary key fields
+	onConflict string
 }
 
 //NewSnowflake returns configured Snowflake adapter instance
@@ -110,6 +409,7 @@ func NewSnowflake(ctx context.Context, config *SnowflakeConfig, s3Config *S3Conf
 		Schema:    config.Schema,
 		Database:  config.Db,
 		Warehouse: config.Warehouse,
+		Role:      config.Role,
 		Params:    config.Parameters,
 	}
 	connectionString, err := sf.DSN(cfg)
@@ -127,7 +427,30 @@ func NewSnowflake(ctx context.Context, config *SnowflakeConfig, s3Config *S3Conf
 		return nil, err
 	}
 
-	return &Snowflake{ctx: ctx, config: config, s3Config: s3Config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToSnowflake)}, nil
+	//0 values keep database/sql defaults
+	if config.MaxOpenConns > 0 {
+		dataSource.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		dataSource.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetimeSec > 0 {
+		dataSource.SetConnMaxLifetime(time.Duration(config.ConnMaxLifetimeSec) * time.Second)
+	}
+
+	snowflake := &Snowflake{ctx: ctx, config: config, s3Config: s3Config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, config.ColumnTypesMapping())}
+
+	//check that ddl_role and ddl_warehouse exist and are granted
+	if config.DDLRole != "" || config.DDLWarehouse != "" {
+		_, release, err := snowflake.ddlConn()
+		if err != nil {
+			dataSource.Close()
+			return nil, err
+		}
+		release()
+	}
+
+	return snowflake, nil
 }
 
 func (Snowflake) Type() string {
@@ -146,10 +469,11 @@ func (s *Snowflake) OpenTx() (*Transaction, error) {
 
 //CreateDbSchema create database schema instance if doesn't exist
 func (s *Snowflake) CreateDbSchema(dbSchemaName string) error {
-	wrappedTx, err := s.OpenTx()
+	wrappedTx, release, err := s.openDDLTx()
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	return createDbSchemaInTransaction(s.ctx, wrappedTx, createSFDbSchemaIfNotExistsTemplate,
 		dbSchemaName, s.queryLogger)
@@ -157,10 +481,11 @@ func (s *Snowflake) CreateDbSchema(dbSchemaName string) error {
 
 //CreateTable runs createTableInTransaction
 func (s *Snowflake) CreateTable(tableSchema *Table) error {
-	wrappedTx, err := s.OpenTx()
+	wrappedTx, release, err := s.openDDLTx()
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	if err = s.createTableInTransaction(wrappedTx, tableSchema); err != nil {
 		wrappedTx.Rollback(err)
@@ -171,10 +496,11 @@ func (s *Snowflake) CreateTable(tableSchema *Table) error {
 
 //PatchTableSchema add new columns(from provided Table) to existing table
 func (s *Snowflake) PatchTableSchema(patchSchema *Table) error {
-	wrappedTx, err := s.OpenTx()
+	wrappedTx, release, err := s.openDDLTx()
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	for columnName, column := range patchSchema.Columns {
 		columnDDL := s.columnDDL(columnName, column)
@@ -198,6 +524,26 @@ func (s *Snowflake) PatchTableSchema(patchSchema *Table) error {
 	return wrappedTx.tx.Commit()
 }
 
+//AlterColumnType changes column data type (e.g. increases VARCHAR length or NUMBER precision)
+func (s *Snowflake) AlterColumnType(tableName, columnName, columnType string) error {
+	query := fmt.Sprintf(alterSFColumnTypeTemplate, s.config.Schema, reformatValue(tableName), reformatValue(columnName), columnType)
+	s.queryLogger.LogDDL(query)
+
+	conn, release, err := s.ddlConn()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := s.queryContext()
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("Error altering %s table '%s' column type to %s: %v", tableName, columnName, columnType, err)
+	}
+
+	return nil
+}
+
 //GetTableSchema returns table (name,columns with name and types) representation wrapped in Table struct
 func (s *Snowflake) GetTableSchema(tableName string) (*Table, error) {
 	table := &Table{Schema: s.config.Schema, Name: tableName, Columns: Columns{}}
@@ -254,31 +600,129 @@ func (s *Snowflake) GetTableSchema(tableName string) (*Table, error) {
 	return table, nil
 }
 
-//Copy transfer data from s3 to Snowflake by passing COPY request to Snowflake
-func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
-	var reformattedHeader []string
-	for _, v := range header {
-		reformattedHeader = append(reformattedHeader, reformatValue(v))
+//GetTableNamesByPrefix returns names of tables (not views) which names start with the prefix
+func (s *Snowflake) GetTableNamesByPrefix(prefix string) ([]string, error) {
+	rows, err := s.dataSource.QueryContext(s.ctx, tablesByPrefixSFQuery, reformatToParam(s.config.Schema), reformatToParam(reformatValue(prefix)))
+	if err != nil {
+		return nil, fmt.Errorf("Error querying tables with [%s] prefix: %v", prefix, err)
 	}
+	defer rows.Close()
 
-	wrappedTx, err := s.OpenTx()
+	var tableNames []string
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("Error scanning table name: %v", err)
+		}
+		tableNames = append(tableNames, tableName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error querying tables with [%s] prefix: %v", prefix, err)
+	}
+
+	return tableNames, nil
+}
+
+//CreateOrReplaceUnionView creates (or replaces) view which selects data from all tables with UNION ALL
+//columns which don't exist in a table are selected as NULL
+func (s *Snowflake) CreateOrReplaceUnionView(viewName string, tables []*Table) error {
+	if len(tables) == 0 {
+		return fmt.Errorf("Error creating view %s: tables are empty", viewName)
+	}
+
+	allColumns := Columns{}
+	for _, table := range tables {
+		for name, column := range table.Columns {
+			if _, ok := allColumns[name]; !ok {
+				allColumns[name] = column
+			}
+		}
+	}
+	columnNames := make([]string, 0, len(allColumns))
+	for name := range allColumns {
+		columnNames = append(columnNames, name)
+	}
+	sort.Strings(columnNames)
+
+	selects := make([]string, 0, len(tables))
+	for _, table := range tables {
+		selectColumns := make([]string, 0, len(columnNames))
+		for _, name := range columnNames {
+			if _, ok := table.Columns[name]; ok {
+				selectColumns = append(selectColumns, reformatValue(name))
+			} else {
+				selectColumns = append(selectColumns, fmt.Sprintf("CAST(NULL AS %s) AS %s", allColumns[name].Type, reformatValue(name)))
+			}
+		}
+		selects = append(selects, fmt.Sprintf("SELECT %s FROM %s.%s", strings.Join(selectColumns, ", "), s.config.Schema, reformatValue(table.Name)))
+	}
+
+	query := fmt.Sprintf(createOrReplaceSFViewTemplate, s.config.Schema, reformatValue(viewName), strings.Join(selects, " UNION ALL "))
+	s.queryLogger.LogDDL(query)
+
+	conn, release, err := s.ddlConn()
 	if err != nil {
 		return err
 	}
+	defer release()
 
-	statement := fmt.Sprintf(`COPY INTO %s.%s (%s) `, s.config.Schema, reformatValue(tableName), strings.Join(reformattedHeader, ","))
-	if s.s3Config != nil {
-		//s3 integration stage
-		if s.s3Config.Folder != "" {
-			fileName = s.s3Config.Folder + "/" + fileName
-		}
-		statement += fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, s.s3Config.AccessKeyID, s.s3Config.SecretKey, copyStatementFileFormat)
-	} else {
-		//gcp integration stage
-		statement += fmt.Sprintf(gcpFrom, s.config.Stage, copyStatementFileFormat, fileName)
+	if _, err := conn.ExecContext(s.ctx, query); err != nil {
+		return fmt.Errorf("Error creating view %s: %v", viewName, err)
 	}
 
-	_, err = wrappedTx.tx.ExecContext(s.ctx, statement)
+	return nil
+}
+
+//Copy transfer data from s3 to Snowflake by passing COPY request to Snowflake
+//header is used only with csv stage format: json and parquet files are mapped by column names
+//COPY is canceled and rolled back if ctx is done (e.g. store timeout is exceeded)
+func (s *Snowflake) Copy(ctx context.Context, fileName, tableName string, header []string) error {
+	return s.copy(ctx, s.buildCopyStatement(fileName, tableName, header, false))
+}
+
+//CopyFromStage transfers data from the s3Config bucket (or from GCP stage if s3Config is nil) to Snowflake
+//it is used if several stage buckets are configured
+func (s *Snowflake) CopyFromStage(ctx context.Context, s3Config *S3Config, fileName, tableName string, header []string) error {
+	return s.copy(ctx, s.buildStageCopyStatement(s3Config, fileName, tableName, header, false))
+}
+
+//CopyPrefix transfers all stage files under the prefix (stage folder) to Snowflake with a single COPY request
+//header is used only with csv stage format: all files must have the same header
+func (s *Snowflake) CopyPrefix(ctx context.Context, prefix, tableName string, header []string) error {
+	return s.copy(ctx, s.buildCopyStatement(prefix, tableName, header, true))
+}
+
+//CopyPrefixFromStage transfers all stage files under the prefix from the s3Config bucket (or from GCP stage if s3Config is nil) to Snowflake
+//it is used if several stage buckets are configured
+func (s *Snowflake) CopyPrefixFromStage(ctx context.Context, s3Config *S3Config, prefix, tableName string, header []string) error {
+	return s.copy(ctx, s.buildStageCopyStatement(s3Config, prefix, tableName, header, true))
+}
+
+//copy runs COPY statement in a transaction
+//the transaction is bound to the query context: if parent is done, the query is canceled and the transaction is rolled back
+//(sql.Tx doesn't commit with the done context)
+func (s *Snowflake) copy(parent context.Context, statement string) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+	if parent.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-parent.Done():
+				cancel()
+			case <-stop:
+			}
+		}()
+	}
+
+	tx, err := s.dataSource.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	wrappedTx := &Transaction{tx: tx, dbType: s.Type()}
+
+	_, err = wrappedTx.tx.ExecContext(ctx, statement)
 	if err != nil {
 		wrappedTx.Rollback(err)
 		return err
@@ -287,8 +731,72 @@ func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
 	return wrappedTx.DirectCommit()
 }
 
+//buildCopyStatement returns COPY statement with the file format and the columns mapping of the configured stage format
+//if isPrefix is true, fileName is a stage folder and all files under it are loaded
+//if copy_purge is enabled, loaded files are deleted from the stage by Snowflake
+func (s *Snowflake) buildCopyStatement(fileName, tableName string, header []string, isPrefix bool) string {
+	return s.buildStageCopyStatement(s.s3Config, fileName, tableName, header, isPrefix)
+}
+
+//buildStageCopyStatement returns COPY statement from the s3Config bucket or from GCP stage if s3Config is nil
+func (s *Snowflake) buildStageCopyStatement(s3Config *S3Config, fileName, tableName string, header []string, isPrefix bool) string {
+	statement := s.buildCopyStatementWithoutOptions(s3Config, fileName, tableName, header, isPrefix)
+	if s.config.CopyPurge {
+		statement += copyPurgeOption
+	}
+	return statement
+}
+
+func (s *Snowflake) buildCopyStatementWithoutOptions(s3Config *S3Config, fileName, tableName string, header []string, isPrefix bool) string {
+	var statement, fileFormat string
+	switch s.config.StageFormat {
+	case StageFormatJSON:
+		statement = fmt.Sprintf(`COPY INTO %s.%s `, s.config.Schema, reformatValue(tableName))
+		fileFormat = copyStatementJSONFormat
+	case StageFormatParquet:
+		statement = fmt.Sprintf(`COPY INTO %s.%s `, s.config.Schema, reformatValue(tableName))
+		fileFormat = copyStatementParquetFormat
+	default:
+		var reformattedHeader []string
+		for _, v := range header {
+			reformattedHeader = append(reformattedHeader, reformatValue(v))
+		}
+		statement = fmt.Sprintf(`COPY INTO %s.%s (%s) `, s.config.Schema, reformatValue(tableName), strings.Join(reformattedHeader, ","))
+		//matches schema.VerticalBarSeparatedMarshallerInstance: values with delimiter chars, quotes or line breaks
+		//are enclosed in double quotes (quotes are doubled), other values are written as is (backslash isn't an escape char)
+		fileFormat = copyStatementFileFormat
+	}
+
+	if s3Config != nil {
+		//s3 integration stage
+		if s3Config.Folder != "" {
+			fileName = s3Config.Folder + "/" + fileName
+		}
+		if isPrefix {
+			fileName += "/"
+		}
+		return statement + fmt.Sprintf(awsS3From, s3Config.Bucket, fileName, s3Config.AccessKeyID, s3Config.SecretKey, fileFormat)
+	}
+
+	//gcp integration stage
+	if isPrefix {
+		fileName += "/.*"
+	}
+	return statement + fmt.Sprintf(gcpFrom, s.config.Stage, fileFormat, fileName)
+}
+
+//SetOnConflict configures Insert behavior for tables with primary key fields (OnConflictInsert, OnConflictUpdate or OnConflictIgnore)
+func (s *Snowflake) SetOnConflict(onConflict string) {
+	s.onConflict = onConflict
+}
+
 // Insert inserts provided object into Snowflake
+//uses single row MERGE if primary key fields and on_conflict (update or ignore) are configured
 func (s *Snowflake) Insert(eventContext *EventContext) error {
+	if len(eventContext.Table.PKFields) > 0 && (s.onConflict == OnConflictUpdate || s.onConflict == OnConflictIgnore) {
+		return s.mergeRow(eventContext)
+	}
+
 	wrappedTx, err := s.OpenTx()
 	if err != nil {
 		return err
@@ -309,7 +817,7 @@ func (s *Snowflake) insertInTransaction(wrappedTx *Transaction, eventContext *Ev
 	for name, value := range eventContext.ProcessedEvent {
 		columnNames = append(columnNames, reformatValue(name))
 
-		castClause := s.getCastClause(name, eventContext.Table.Columns[name])
+		value, castClause := s.timestampArg(value, s.getCastClause(name, eventContext.Table.Columns[name]))
 		placeholders = append(placeholders, "?"+castClause)
 		values = append(values, value)
 	}
@@ -328,6 +836,82 @@ func (s *Snowflake) insertInTransaction(wrappedTx *Transaction, eventContext *Ev
 	return nil
 }
 
+//mergeRow inserts provided object or resolves the primary key conflict according to onConflict with single row MERGE
+//sets eventContext.ConflictResolution if the row with the same primary key exists
+func (s *Snowflake) mergeRow(eventContext *EventContext) error {
+	table := eventContext.Table
+	statement, values, withMatchedClause := s.buildMergeRowStatement(table, eventContext.ProcessedEvent)
+	s.queryLogger.LogQueryWithValues(statement, values)
+
+	//MERGE returns number of inserted rows (and number of updated rows if there is WHEN MATCHED clause)
+	var inserted, updated int64
+	row := s.dataSource.QueryRowContext(s.ctx, statement, values...)
+	var err error
+	if withMatchedClause {
+		err = row.Scan(&inserted, &updated)
+	} else {
+		err = row.Scan(&inserted)
+	}
+	if err != nil {
+		return fmt.Errorf("Error merging row in %s table with statement: %s values: %v: %v", table.Name, statement, values, err)
+	}
+
+	if inserted == 0 {
+		if updated > 0 {
+			eventContext.ConflictResolution = OnConflictUpdate
+		} else {
+			eventContext.ConflictResolution = OnConflictIgnore
+		}
+	}
+
+	return nil
+}
+
+//buildMergeRowStatement returns single row MERGE statement with values and true if the statement has WHEN MATCHED clause
+//columns are sorted so the statement doesn't depend on the fields order
+func (s *Snowflake) buildMergeRowStatement(table *Table, object map[string]interface{}) (string, []interface{}, bool) {
+	tableName := reformatValue(table.Name)
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var columnNames, sourceColumns, sourceValues, updateSet []string
+	var values []interface{}
+	for _, name := range names {
+		columnName := reformatValue(name)
+		columnNames = append(columnNames, columnName)
+		castClause := s.getCastClause(name, table.Columns[name])
+		sourceColumns = append(sourceColumns, fmt.Sprintf("?%s AS %s", castClause, columnName))
+		sourceValues = append(sourceValues, fmt.Sprintf("%s.%s", sfMergeRowSource, columnName))
+		if !table.PKFields[name] {
+			updateSet = append(updateSet, fmt.Sprintf("%s.%s = %s.%s", tableName, columnName, sfMergeRowSource, columnName))
+		}
+		values = append(values, object[name])
+	}
+
+	pkFields := make([]string, 0, len(table.PKFields))
+	for pkField := range table.PKFields {
+		pkFields = append(pkFields, pkField)
+	}
+	sort.Strings(pkFields)
+
+	var joinConditions []string
+	for _, pkField := range pkFields {
+		joinConditions = append(joinConditions, fmt.Sprintf("%s.%s = %s.%s", tableName, reformatValue(pkField), sfMergeRowSource, reformatValue(pkField)))
+	}
+
+	var matchedClause string
+	if s.onConflict == OnConflictUpdate && len(updateSet) > 0 {
+		matchedClause = " WHEN MATCHED THEN UPDATE SET " + strings.Join(updateSet, ", ")
+	}
+
+	statement := fmt.Sprintf(sfMergeRowStatement, s.config.Schema, tableName, strings.Join(sourceColumns, ", "), sfMergeRowSource,
+		strings.Join(joinConditions, " AND "), matchedClause, strings.Join(columnNames, ", "), strings.Join(sourceValues, ", "))
+	return statement, values, matchedClause != ""
+}
+
 //BulkInsert runs bulkInsertInTransaction
 //returns error if occurred
 func (s *Snowflake) BulkInsert(table *Table, objects []map[string]interface{}) error {
@@ -397,10 +981,32 @@ func (s *Snowflake) Truncate(tableName string) error {
 		queryLogger: s.queryLogger,
 		ctx:         s.ctx,
 	}
-	statement := fmt.Sprintf(truncateSFTableTemplate, s.config.Db, tableName)
+	statement := fmt.Sprintf(truncateSFTableTemplate, s.config.Db, reformatValue(tableName))
 	return sqlParams.commonTruncate(tableName, statement)
 }
 
+//DeleteOlderThan deletes all records of tableName table with column value less than before
+//returns amount of deleted rows
+func (s *Snowflake) DeleteOlderThan(tableName, column string, before time.Time) (int64, error) {
+	statement := fmt.Sprintf(deleteOlderThanSFTemplate, s.config.Schema, reformatValue(tableName), reformatValue(column))
+	s.queryLogger.LogQueryWithValues(statement, []interface{}{before})
+
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	result, err := s.dataSource.ExecContext(ctx, statement, before)
+	if err != nil {
+		return 0, fmt.Errorf("Error deleting rows older than %s from %s table with statement: %s: %v", before.Format(time.RFC3339), tableName, statement, err)
+	}
+
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Error getting deleted rows count from %s table: %v", tableName, err)
+	}
+
+	return rowsDeleted, nil
+}
+
 //Update one record in Snowflake
 func (s *Snowflake) Update(table *Table, object map[string]interface{}, whereKey string, whereValue interface{}) error {
 	columnNames := make([]string, len(object), len(object))
@@ -420,7 +1026,10 @@ func (s *Snowflake) Update(table *Table, object map[string]interface{}, whereKey
 	statement := fmt.Sprintf(updateSFTemplate, s.config.Schema, reformatValue(table.Name), header, reformatValue(whereKey))
 	s.queryLogger.LogQueryWithValues(statement, values)
 
-	_, err := s.dataSource.ExecContext(s.ctx, statement, values...)
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	_, err := s.dataSource.ExecContext(ctx, statement, values...)
 	if err != nil {
 		return fmt.Errorf("Error updating in %s table with statement: %s values: %v: %v", table.Name, header, values, err)
 	}
@@ -428,6 +1037,151 @@ func (s *Snowflake) Update(table *Table, object map[string]interface{}, whereKey
 	return nil
 }
 
+//UpdateBatch updates records with whereValues of whereKey column with one MERGE statement
+//all objects must have the same columns. whereValues[i] is the whereKey value of objects[i]
+//if there are several objects with the same whereKey value, only the last one is applied (Snowflake fails on nondeterministic MERGE)
+func (s *Snowflake) UpdateBatch(table *Table, objects []map[string]interface{}, whereKey string, whereValues []interface{}) error {
+	if len(objects) == 0 || len(objects[0]) == 0 {
+		return nil
+	}
+
+	statement, values, rows := s.buildMergeUpdateStatement(table, objects, whereKey, whereValues)
+	s.queryLogger.LogQueryWithValues(statement, values)
+
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	if _, err := s.dataSource.ExecContext(ctx, statement, values...); err != nil {
+		return fmt.Errorf("Error updating %d rows in %s table with statement: %s: %v", rows, table.Name, statement, err)
+	}
+
+	return nil
+}
+
+//buildMergeUpdateStatement returns multi-row MERGE statement with values and the number of updated rows (objects with unique whereKey values)
+func (s *Snowflake) buildMergeUpdateStatement(table *Table, objects []map[string]interface{}, whereKey string, whereValues []interface{}) (string, []interface{}, int) {
+	columns := make([]string, 0, len(objects[0]))
+	for name := range objects[0] {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	//the last object wins
+	rowPerKey := map[string]int{}
+	for i := range objects {
+		rowPerKey[fmt.Sprint(whereValues[i])] = i
+	}
+
+	tableName := reformatValue(table.Name)
+	var selects []string
+	var values []interface{}
+	for i, object := range objects {
+		if rowPerKey[fmt.Sprint(whereValues[i])] != i {
+			continue
+		}
+
+		sourceColumns := make([]string, 0, len(columns)+1)
+		for _, name := range columns {
+			sourceColumns = append(sourceColumns, fmt.Sprintf("?%s AS %s", s.getCastClause(name, table.Columns[name]), reformatValue(name)))
+			values = append(values, object[name])
+		}
+		sourceColumns = append(sourceColumns, "? AS "+sfMergeUpdateKey)
+		values = append(values, whereValues[i])
+		selects = append(selects, "SELECT "+strings.Join(sourceColumns, ", "))
+	}
+
+	updateSet := make([]string, 0, len(columns))
+	for _, name := range columns {
+		columnName := reformatValue(name)
+		updateSet = append(updateSet, fmt.Sprintf("%s.%s = %s.%s", tableName, columnName, sfMergeRowSource, columnName))
+	}
+
+	statement := fmt.Sprintf(sfMergeUpdateStatement, s.config.Schema, tableName, strings.Join(selects, " UNION ALL "), sfMergeRowSource,
+		tableName, reformatValue(whereKey), sfMergeRowSource, sfMergeUpdateKey, strings.Join(updateSet, ", "))
+	return statement, values, len(selects)
+}
+
+//openDDLTx opens transaction on the connection from ddlConn
+//returned func must be called after the transaction is finished
+func (s *Snowflake) openDDLTx() (*Transaction, func(), error) {
+	conn, release, err := s.ddlConn()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := conn.BeginTx(s.ctx, nil)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	return &Transaction{tx: tx, dbType: s.Type()}, release, nil
+}
+
+//ddlConn returns a dedicated connection with ddl_role and ddl_warehouse (if configured) and func which
+//restores the connection role and warehouse and returns it into the pool
+func (s *Snowflake) ddlConn() (*sql.Conn, func(), error) {
+	conn, err := s.dataSource.Conn(s.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.config.DDLRole == "" && s.config.DDLWarehouse == "" {
+		return conn, func() { conn.Close() }, nil
+	}
+
+	var role, warehouse sql.NullString
+	if err := conn.QueryRowContext(s.ctx, currentSFSessionQuery).Scan(&role, &warehouse); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("Error getting Snowflake current role and warehouse: %v", err)
+	}
+
+	release := func() {
+		err := s.useRoleAndWarehouse(conn, quoteSFIdentifier(role), quoteSFIdentifier(warehouse))
+		if err == nil && (s.config.DDLRole != "" && !role.Valid || s.config.DDLWarehouse != "" && !warehouse.Valid) {
+			err = errors.New("session didn't have role or warehouse before DDL")
+		}
+		if err != nil {
+			logging.Errorf("Error restoring Snowflake role [%s] and warehouse [%s] after DDL: %v", role.String, warehouse.String, err)
+			//the connection with DDL role and warehouse mustn't be reused
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	if err := s.useRoleAndWarehouse(conn, s.config.DDLRole, s.config.DDLWarehouse); err != nil {
+		release()
+		return nil, nil, fmt.Errorf("Error switching to Snowflake ddl_role [%s] and ddl_warehouse [%s]: %v", s.config.DDLRole, s.config.DDLWarehouse, err)
+	}
+
+	return conn, release, nil
+}
+
+//useRoleAndWarehouse sets the connection session role and warehouse (empty values are skipped)
+func (s *Snowflake) useRoleAndWarehouse(conn *sql.Conn, role, warehouse string) error {
+	if role != "" {
+		if _, err := conn.ExecContext(s.ctx, fmt.Sprintf(useSFRoleTemplate, role)); err != nil {
+			return err
+		}
+	}
+	if warehouse != "" {
+		if _, err := conn.ExecContext(s.ctx, fmt.Sprintf(useSFWarehouseTemplate, warehouse)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//queryContext returns context with configured query timeout (or adapter context if the timeout isn't configured)
+func (s *Snowflake) queryContext() (context.Context, context.CancelFunc) {
+	if s.config.QueryTimeoutSec > 0 {
+		return context.WithTimeout(s.ctx, time.Duration(s.config.QueryTimeoutSec)*time.Second)
+	}
+
+	return s.ctx, func() {}
+}
+
 //createTableInTransaction creates database table with name,columns provided in Table representation
 func (s *Snowflake) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
 	var columnsDDL []string
@@ -478,9 +1232,8 @@ func (s *Snowflake) bulkInsertInTransaction(wrappedTx *Transaction, table *Table
 		}
 
 		for i, column := range unformattedColumnNames {
-			value, _ := row[column]
+			value, castClause := s.timestampArg(row[column], s.getCastClause(column, table.Columns[column]))
 			valueArgs = append(valueArgs, value)
-			castClause := s.getCastClause(column, table.Columns[column])
 
 			_, err = placeholdersBuilder.WriteString("?" + castClause)
 			if err != nil {
@@ -537,17 +1290,17 @@ func (s *Snowflake) bulkMergeInTransaction(wrappedTx *Transaction, table *Table,
 	for name := range table.Columns {
 		reformattedColumnName := reformatValue(name)
 		unformattedColumnNames = append(unformattedColumnNames, name)
-		formattedColumnNames = append(formattedColumnNames, reformatDefault(reformattedColumnName))
-		updateSet = append(updateSet, fmt.Sprintf("%s.%s = %s.%s", table.Name, reformattedColumnName, tmpTable.Name, reformattedColumnName))
+		formattedColumnNames = append(formattedColumnNames, reformattedColumnName)
+		updateSet = append(updateSet, fmt.Sprintf("%s.%s = %s.%s", reformatValue(table.Name), reformattedColumnName, tmpTable.Name, reformattedColumnName))
 		tmpPreffixColumnNames = append(tmpPreffixColumnNames, fmt.Sprintf("%s.%s", tmpTable.Name, reformattedColumnName))
 	}
 
 	var joinConditions []string
 	for pkField := range table.PKFields {
-		joinConditions = append(joinConditions, fmt.Sprintf("%s.%s = %s.%s", table.Name, pkField, tmpTable.Name, pkField))
+		joinConditions = append(joinConditions, fmt.Sprintf("%s.%s = %s.%s", reformatValue(table.Name), reformatValue(pkField), tmpTable.Name, reformatValue(pkField)))
 	}
 
-	insertFromSelectStatement := fmt.Sprintf(sfMergeStatement, s.config.Schema, table.Name, strings.Join(formattedColumnNames, ", "), s.config.Schema, tmpTable.Name,
+	insertFromSelectStatement := fmt.Sprintf(sfMergeStatement, s.config.Schema, reformatValue(table.Name), strings.Join(formattedColumnNames, ", "), s.config.Schema, tmpTable.Name,
 		tmpTable.Name, strings.Join(joinConditions, " AND "), strings.Join(updateSet, ", "), strings.Join(formattedColumnNames, ", "), strings.Join(tmpPreffixColumnNames, ", "))
 
 	s.queryLogger.LogQuery(insertFromSelectStatement)
@@ -562,7 +1315,7 @@ func (s *Snowflake) bulkMergeInTransaction(wrappedTx *Transaction, table *Table,
 
 //dropTableInTransaction drops a table in transaction
 func (s *Snowflake) dropTableInTransaction(wrappedTx *Transaction, table *Table) error {
-	query := fmt.Sprintf(dropSFTableTemplate, s.config.Schema, table.Name)
+	query := fmt.Sprintf(dropSFTableTemplate, s.config.Schema, reformatValue(table.Name))
 	s.queryLogger.LogDDL(query)
 
 	_, err := wrappedTx.tx.ExecContext(s.ctx, query)
@@ -581,7 +1334,7 @@ func (s *Snowflake) executeInsert(wrappedTx *Transaction, table *Table, headerWi
 		quotedHeader = append(quotedHeader, reformatValue(columnName))
 	}
 
-	statement := fmt.Sprintf(insertSFTemplate, s.config.Schema, table.Name, strings.Join(quotedHeader, ", "), placeholders)
+	statement := fmt.Sprintf(insertSFTemplate, s.config.Schema, reformatValue(table.Name), strings.Join(quotedHeader, ", "), placeholders)
 
 	s.queryLogger.LogQueryWithValues(statement, valueArgs)
 
@@ -611,7 +1364,7 @@ func (s *Snowflake) toDeleteQuery(conditions *DeleteConditions) (string, []inter
 	var values []interface{}
 
 	for _, condition := range conditions.Conditions {
-		conditionString := condition.Field + " " + condition.Clause + " ?"
+		conditionString := reformatValue(condition.Field) + " " + condition.Clause + " ?"
 		queryConditions = append(queryConditions, conditionString)
 		values = append(values, condition.Value)
 	}
@@ -619,6 +1372,52 @@ func (s *Snowflake) toDeleteQuery(conditions *DeleteConditions) (string, []inter
 	return strings.Join(queryConditions, conditions.JoinCondition), values
 }
 
+//Ping checks the connection to Snowflake
+func (s *Snowflake) Ping() error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	return s.dataSource.PingContext(ctx)
+}
+
+//TestQuery runs a trivial query which checks that the user can execute statements
+func (s *Snowflake) TestQuery() error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	s.queryLogger.LogQuery(sfTestQuery)
+	_, err := s.dataSource.ExecContext(ctx, sfTestQuery)
+	return err
+}
+
+//Execute runs the statement as is (e.g. the post-load hook)
+func (s *Snowflake) Execute(statement string) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	s.queryLogger.LogQuery(statement)
+	if _, err := s.dataSource.ExecContext(ctx, statement); err != nil {
+		return fmt.Errorf("Error executing statement: %s: %v", statement, err)
+	}
+
+	return nil
+}
+
+//FileLoaded returns true if the stage file has been loaded into the table since the time according to the COPY load history
+//it is used for checking the outcome of the interrupted COPY
+func (s *Snowflake) FileLoaded(tableName, fileName string, since time.Time) (bool, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	s.queryLogger.LogQueryWithValues(sfCopyHistoryQuery, []interface{}{s.config.Schema + "." + reformatValue(tableName), since, fileName})
+	var loaded int
+	if err := s.dataSource.QueryRowContext(ctx, sfCopyHistoryQuery, s.config.Schema+"."+reformatValue(tableName), since, fileName).Scan(&loaded); err != nil {
+		return false, fmt.Errorf("Error checking load history of file %s in %s table: %v", fileName, tableName, err)
+	}
+
+	return loaded > 0, nil
+}
+
 //Close underlying sql.DB
 func (s *Snowflake) Close() (multiErr error) {
 	return s.dataSource.Close()
@@ -639,6 +1438,24 @@ func (s *Snowflake) getCastClause(name string, column typing.SQLColumn) string {
 	return ""
 }
 
+//timestampArg returns time value as RFC3339 string with the timestamp_type cast clause (if there is no other cast clause)
+//the driver binds time values as TIMESTAMP_NTZ and the offset would be lost before casting into TIMESTAMP_TZ column
+//other values and all values without configured timestamp_type are returned as is
+func (s *Snowflake) timestampArg(value interface{}, castClause string) (interface{}, string) {
+	if s.config.TimestampType == "" {
+		return value, castClause
+	}
+	t, ok := NormalizeSnowflakeTimestamp(s.config.TimestampType, value).(time.Time)
+	if !ok {
+		return value, castClause
+	}
+	if castClause == "" {
+		castClause = "::" + s.config.TimestampType
+	}
+
+	return t.Format(time.RFC3339Nano), castClause
+}
+
 //columnDDL returns column DDL (column name, mapped sql type)
 func (s *Snowflake) columnDDL(name string, column typing.SQLColumn) string {
 	sqlColumnTypeDDL := column.DDLType()
@@ -664,9 +1481,15 @@ func reformatToParam(value string) string {
 //Snowflake accepts names (identifiers) started with '_' or letter
 //also names can contain only '_', letters, numbers, '$'
 //otherwise double quote them
+//reserved keywords are double quoted in upper case (as Snowflake stores unquoted identifiers) e.g. select -> "SELECT"
 //https://docs.snowflake.com/en/sql-reference/identifiers-syntax.html#unquoted-identifiers
 func reformatValue(value string) string {
 	if len(value) > 0 {
+		upperValue := strings.ToUpper(value)
+		if _, ok := sfReservedKeywords[upperValue]; ok {
+			return `"` + upperValue + `"`
+		}
+
 		//must begin with a letter or underscore, or enclose in double quotes
 		firstSymbol := value[0]
 
@@ -685,14 +1508,13 @@ func reformatValue(value string) string {
 	return value
 }
 
-//Snowflake doesn't accept names (identifiers) like: default
-//it should be reformatted to "DEFAULT"
-func reformatDefault(value string) string {
-	if value == "default" {
-		return `"DEFAULT"`
+//quoteSFIdentifier returns double quoted identifier (as is with case) or empty string if the value is NULL or empty
+func quoteSFIdentifier(value sql.NullString) string {
+	if !value.Valid || value.String == "" {
+		return ""
 	}
 
-	return value
+	return `"` + strings.ReplaceAll(value.String, `"`, `""`) + `"`
 }
 
 //_: 95
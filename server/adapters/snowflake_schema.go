@@ -0,0 +1,133 @@
+package adapters
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const snowflakeMaxVarcharLength = 16777216
+
+var snowflakeTypeRegexp = regexp.MustCompile(`^([A-Z][A-Z0-9_ ]*?)\s*(?:\((\d+)(?:\s*,\s*(\d+))?\))?$`)
+
+//SchemaRepair is a result of Snowflake table schema reconciliation
+type SchemaRepair struct {
+	Table string `json:"table"`
+	//Added is a list of added columns DDL
+	Added []string `json:"added,omitempty"`
+	//Widened is a list of columns with widened types DDL
+	Widened []string `json:"widened,omitempty"`
+	//Skipped is a list of changes which can't be made safely (narrowing, incompatible types, drops)
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+//snowflakeType is a normalized Snowflake data type e.g. VARCHAR(256) or NUMBER(38,0)
+type snowflakeType struct {
+	base      string
+	precision int
+	scale     int
+}
+
+func (st snowflakeType) String() string {
+	switch st.base {
+	case "VARCHAR", "TIMESTAMP_NTZ", "TIMESTAMP_LTZ", "TIMESTAMP_TZ", "TIME":
+		return fmt.Sprintf("%s(%d)", st.base, st.precision)
+	case "NUMBER":
+		return fmt.Sprintf("%s(%d,%d)", st.base, st.precision, st.scale)
+	default:
+		return st.base
+	}
+}
+
+//parseSnowflakeType parses Snowflake data type (or its synonym) into the normalized form
+func parseSnowflakeType(value string) snowflakeType {
+	parts := snowflakeTypeRegexp.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if len(parts) != 4 {
+		return snowflakeType{base: strings.ToUpper(strings.TrimSpace(value))}
+	}
+
+	st := snowflakeType{base: parts[1]}
+	precision, precisionErr := strconv.Atoi(parts[2])
+	scale, _ := strconv.Atoi(parts[3])
+	switch st.base {
+	case "VARCHAR", "TEXT", "STRING", "CHARACTER VARYING", "NVARCHAR", "NVARCHAR2", "CHAR VARYING", "NCHAR VARYING":
+		st.base = "VARCHAR"
+		st.precision = snowflakeMaxVarcharLength
+	case "NUMBER", "DECIMAL", "NUMERIC":
+		st.base = "NUMBER"
+		st.precision = 38
+	case "INT", "INTEGER", "BIGINT", "SMALLINT", "TINYINT", "BYTEINT":
+		return snowflakeType{base: "NUMBER", precision: 38}
+	case "FLOAT", "FLOAT4", "FLOAT8", "DOUBLE", "DOUBLE PRECISION", "REAL":
+		return snowflakeType{base: "FLOAT"}
+	case "TIMESTAMP", "DATETIME", "TIMESTAMP_NTZ", "TIMESTAMP WITHOUT TIME ZONE":
+		st.base = "TIMESTAMP_NTZ"
+		st.precision = 9
+	case "TIMESTAMP_LTZ", "TIMESTAMP_TZ", "TIME":
+		st.precision = 9
+	case "BOOL", "BOOLEAN":
+		return snowflakeType{base: "BOOLEAN"}
+	}
+
+	if precisionErr == nil {
+		st.precision = precision
+		st.scale = scale
+	}
+
+	return st
+}
+
+//PlanSnowflakeSchemaRepair compares the live table schema with the expected one and returns:
+//columns to add, columns with types to widen (column name -> new type) and changes which can't be made safely
+//Only VARCHAR length and NUMBER precision (with the same scale) increasing are considered safe
+func PlanSnowflakeSchemaRepair(live, expected *Table) (Columns, map[string]string, []string) {
+	toAdd := Columns{}
+	toWiden := map[string]string{}
+	var skipped []string
+
+	for name, column := range expected.Columns {
+		liveColumn, ok := live.Columns[strings.ToLower(name)]
+		if !ok {
+			toAdd[name] = column
+			continue
+		}
+
+		liveType := parseSnowflakeType(liveColumn.Type)
+		expectedType := parseSnowflakeType(column.DDLType())
+		if liveType == expectedType {
+			continue
+		}
+
+		if liveType.base != expectedType.base || liveType.scale != expectedType.scale {
+			skipped = append(skipped, fmt.Sprintf("column %s: type %s can't be changed to %s", name, liveType, expectedType))
+			continue
+		}
+
+		if expectedType.precision < liveType.precision {
+			skipped = append(skipped, fmt.Sprintf("column %s: type %s can't be narrowed to %s", name, liveType, expectedType))
+			continue
+		}
+
+		if liveType.base != "VARCHAR" && liveType.base != "NUMBER" {
+			skipped = append(skipped, fmt.Sprintf("column %s: type %s can't be changed to %s", name, liveType, expectedType))
+			continue
+		}
+
+		toWiden[name] = expectedType.String()
+	}
+
+	expectedNames := make(map[string]bool, len(expected.Columns))
+	for name := range expected.Columns {
+		expectedNames[strings.ToLower(name)] = true
+	}
+	for name := range live.Columns {
+		if !expectedNames[strings.ToLower(name)] {
+			skipped = append(skipped, fmt.Sprintf("column %s: doesn't exist in the expected schema and won't be dropped", name))
+		}
+	}
+
+	sort.Strings(skipped)
+	return toAdd, toWiden, skipped
+}
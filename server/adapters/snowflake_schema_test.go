@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanSnowflakeSchemaRepair(t *testing.T) {
+	live := &Table{Name: "events", Columns: Columns{
+		"id":         typing.SQLColumn{Type: "NUMBER(38,0)"},
+		"name":       typing.SQLColumn{Type: "VARCHAR(256)"},
+		"amount":     typing.SQLColumn{Type: "NUMBER(10,2)"},
+		"code":       typing.SQLColumn{Type: "VARCHAR(16777216)"},
+		"created_at": typing.SQLColumn{Type: "TIMESTAMP_NTZ(6)"},
+		"flag":       typing.SQLColumn{Type: "VARCHAR(16777216)"},
+		"legacy":     typing.SQLColumn{Type: "FLOAT"},
+	}}
+	expected := &Table{Name: "events", Columns: Columns{
+		"id":         typing.SQLColumn{Type: "bigint"},
+		"name":       typing.SQLColumn{Type: "text"},
+		"amount":     typing.SQLColumn{Type: "number(18,2)"},
+		"code":       typing.SQLColumn{Type: "varchar(10)"},
+		"created_at": typing.SQLColumn{Type: "timestamp(6)"},
+		"flag":       typing.SQLColumn{Type: "boolean"},
+		"new_column": typing.SQLColumn{Type: "double precision"},
+	}}
+
+	toAdd, toWiden, skipped := PlanSnowflakeSchemaRepair(live, expected)
+	require.Equal(t, Columns{"new_column": typing.SQLColumn{Type: "double precision"}}, toAdd)
+	require.Equal(t, map[string]string{"name": "VARCHAR(16777216)", "amount": "NUMBER(18,2)"}, toWiden)
+	require.Equal(t, []string{
+		"column code: type VARCHAR(16777216) can't be narrowed to VARCHAR(10)",
+		"column flag: type VARCHAR(16777216) can't be changed to BOOLEAN",
+		"column legacy: doesn't exist in the expected schema and won't be dropped",
+	}, skipped)
+}
@@ -2,15 +2,19 @@ package adapters
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/typing"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/stretchr/testify/require"
 	"math/rand"
 	"os"
 	"testing"
+	"time"
 )
 
 const (
@@ -63,6 +67,21 @@ func TestReformatValue(t *testing.T) {
 			`abc bbb`,
 			`"abc bbb"`,
 		},
+		{
+			"reserved keyword",
+			`select`,
+			`"SELECT"`,
+		},
+		{
+			"reserved keyword in upper case",
+			`ORDER`,
+			`"ORDER"`,
+		},
+		{
+			"contains reserved keyword",
+			`order_id`,
+			`order_id`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -71,6 +90,77 @@ func TestReformatValue(t *testing.T) {
 	}
 }
 
+func TestBuildCopyStatement(t *testing.T) {
+	tests := []struct {
+		name        string
+		stageFormat string
+		contains    []string
+		notContains []string
+	}{
+		{
+			"csv",
+			StageFormatCSV,
+			[]string{`COPY INTO PUBLIC.events (id,"1col","SELECT") `, `TYPE= 'CSV'`, `FIELD_OPTIONALLY_ENCLOSED_BY = '"'`, "ESCAPE_UNENCLOSED_FIELD = NONE"},
+			[]string{"MATCH_BY_COLUMN_NAME"},
+		},
+		{
+			"json",
+			StageFormatJSON,
+			[]string{`COPY INTO PUBLIC.events FROM @stage`, `TYPE= 'JSON'`, "MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE"},
+			[]string{"id,"},
+		},
+		{
+			"parquet",
+			StageFormatParquet,
+			[]string{`COPY INTO PUBLIC.events FROM @stage`, `TYPE= 'PARQUET'`, "MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE"},
+			[]string{"id,"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := &Snowflake{config: &SnowflakeConfig{Schema: "PUBLIC", Stage: "stage", StageFormat: tt.stageFormat}}
+			statement := sf.buildCopyStatement("file1", "events", []string{"id", "1col", "select"}, false)
+			for _, expected := range tt.contains {
+				require.Contains(t, statement, expected)
+			}
+			for _, unexpected := range tt.notContains {
+				require.NotContains(t, statement, unexpected)
+			}
+		})
+	}
+}
+
+func TestBuildCopyStatementMatchByColumnName(t *testing.T) {
+	sf := &Snowflake{config: &SnowflakeConfig{Schema: "PUBLIC", Stage: "stage", StageFormat: StageFormatJSON}}
+	//json stage files are mapped by column names: the statement doesn't depend on the fields order
+	statement := sf.buildCopyStatement("file1", "events", []string{"id", "user", "select"}, false)
+	require.Equal(t, statement, sf.buildCopyStatement("file1", "events", []string{"select", "id"}, false))
+	require.Contains(t, statement, "MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE")
+
+	//csv stage files are mapped by the columns positions
+	sf.config.StageFormat = StageFormatCSV
+	require.NotEqual(t, sf.buildCopyStatement("file1", "events", []string{"id", "user"}, false),
+		sf.buildCopyStatement("file1", "events", []string{"user", "id"}, false))
+}
+
+func TestBuildCopyStatementPrefix(t *testing.T) {
+	gcs := &Snowflake{config: &SnowflakeConfig{Schema: "PUBLIC", Stage: "stage", StageFormat: StageFormatCSV}}
+	require.Contains(t, gcs.buildCopyStatement("batches/events/1", "events", []string{"id"}, true), "PATTERN = 'batches/events/1/.*'")
+
+	s3 := &Snowflake{config: &SnowflakeConfig{Schema: "PUBLIC", StageFormat: StageFormatCSV},
+		s3Config: &S3Config{Bucket: "bucket", Folder: "folder"}}
+	require.Contains(t, s3.buildCopyStatement("batches/events/1", "events", []string{"id"}, true), "FROM 's3://bucket/folder/batches/events/1/'")
+}
+
+func TestBuildCopyStatementPurge(t *testing.T) {
+	sf := &Snowflake{config: &SnowflakeConfig{Schema: "PUBLIC", Stage: "stage", StageFormat: StageFormatJSON}}
+	require.NotContains(t, sf.buildCopyStatement("file1", "events", nil, false), "PURGE")
+
+	sf.config.CopyPurge = true
+	require.Contains(t, sf.buildCopyStatement("file1", "events", nil, false), "PATTERN = 'file1' PURGE = TRUE")
+	require.NotContains(t, sf.buildCopyStatement("file1", "events", nil, false), "FORCE", "already loaded files must be skipped by Snowflake load metadata")
+}
+
 func TestReformatToParam(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -100,6 +190,35 @@ func TestReformatToParam(t *testing.T) {
 	}
 }
 
+func TestQuoteSFIdentifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    sql.NullString
+		expected string
+	}{
+		{
+			"null",
+			sql.NullString{},
+			``,
+		},
+		{
+			"upper case",
+			sql.NullString{String: `SYSADMIN`, Valid: true},
+			`"SYSADMIN"`,
+		},
+		{
+			"mixed case with quote",
+			sql.NullString{String: `My"Role`, Valid: true},
+			`"My""Role"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, quoteSFIdentifier(tt.input), "Quoted identifiers aren't equal")
+		})
+	}
+}
+
 func TestSFBulkInsert(t *testing.T) {
 	sfConfig, skip := readSFConfig(t)
 	if skip {
@@ -185,6 +304,89 @@ func TestSFBulkUpdate(t *testing.T) {
 	require.Equal(t, count, 5)
 }
 
+func TestSFReservedKeywordColumns(t *testing.T) {
+	sfConfig, skip := readSFConfig(t)
+	if skip {
+		return
+	}
+
+	sf, err := NewSnowflake(context.Background(), sfConfig, nil, &logging.QueryLogger{}, typing.SQLTypes{})
+	require.NoError(t, err)
+	defer sf.Close()
+
+	table := &Table{
+		Name:     "test_sf_reserved_" + uuid.NewLettersNumbers(),
+		Columns:  Columns{"id": typing.SQLColumn{Type: "text"}, "select": typing.SQLColumn{Type: "text"}, "order": typing.SQLColumn{Type: "bigint"}},
+		PKFields: map[string]bool{"order": true},
+	}
+	require.NoError(t, sf.CreateTable(table))
+	defer sf.DropTable(table)
+
+	dbTable, err := sf.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	for name := range table.Columns {
+		require.Contains(t, dbTable.Columns, name)
+	}
+
+	require.NoError(t, sf.BulkInsert(table, []map[string]interface{}{{"id": "1", "select": "inserted", "order": 1}}))
+	require.NoError(t, sf.BulkUpdate(table, []map[string]interface{}{{"id": "2", "select": "merged", "order": 2}}, nil))
+	require.NoError(t, sf.Update(table, map[string]interface{}{"select": "updated"}, "id", "1"))
+
+	var value string
+	require.NoError(t, sf.dataSource.QueryRow(fmt.Sprintf(`SELECT "SELECT" FROM %s WHERE id = '1'`, table.Name)).Scan(&value))
+	require.Equal(t, "updated", value)
+}
+
+func TestSFLookupEnrichment(t *testing.T) {
+	sfConfig, skip := readSFConfig(t)
+	if skip {
+		return
+	}
+	require.NoError(t, sfConfig.Validate())
+
+	rule, err := enrichment.NewRule(&enrichment.RuleConfig{Name: enrichment.Lookup, From: "/country_code", To: "/country",
+		Lookup: &enrichment.LookupConfig{Data: map[string]interface{}{"DE": map[string]interface{}{"name": "Germany"}}}}, nil, "")
+	require.NoError(t, err)
+
+	var objects []map[string]interface{}
+	for i, countryCode := range []string{"DE", "FR"} {
+		event := map[string]interface{}{"id": fmt.Sprint(i), "country_code": countryCode}
+		rule.Execute(event)
+		flattened, err := schema.NewFlattener().FlattenObject(event)
+		require.NoError(t, err)
+		objects = append(objects, flattened)
+	}
+
+	sf, err := NewSnowflake(context.Background(), sfConfig, nil, &logging.QueryLogger{}, typing.SQLTypes{})
+	require.NoError(t, err)
+	defer sf.Close()
+
+	table := &Table{
+		Name:    "test_sf_lookup_enrichment_" + uuid.NewLettersNumbers(),
+		Columns: Columns{"id": typing.SQLColumn{Type: "text"}, "country_code": typing.SQLColumn{Type: "text"}, "country_name": typing.SQLColumn{Type: "text"}},
+	}
+	require.NoError(t, sf.CreateTable(table))
+	defer func() {
+		tx, _ := sf.OpenTx()
+		sf.dropTableInTransaction(tx, table)
+		tx.Commit()
+	}()
+	require.NoError(t, sf.BulkInsert(table, objects))
+
+	rows, err := sf.dataSource.Query(fmt.Sprintf("SELECT country_code, country_name FROM %s.%s ORDER BY id", sfConfig.Schema, table.Name))
+	require.NoError(t, err)
+	defer rows.Close()
+	actual := map[string]sql.NullString{}
+	for rows.Next() {
+		var countryCode string
+		var countryName sql.NullString
+		require.NoError(t, rows.Scan(&countryCode, &countryName))
+		actual[countryCode] = countryName
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, map[string]sql.NullString{"DE": {String: "Germany", Valid: true}, "FR": {}}, actual, "enriched column must be loaded (missing keys as nulls)")
+}
+
 func readSFConfig(t *testing.T) (*SnowflakeConfig, bool) {
 	sfConfigJSON := os.Getenv(testSFConfigVar)
 	if sfConfigJSON == "" {
@@ -210,3 +412,273 @@ func createObjectsWithFields(fields []string, num int) []map[string]interface{}
 	}
 	return objects
 }
+
+func TestSnowflakeTimestampType(t *testing.T) {
+	sfConfig := &SnowflakeConfig{Account: "account", Db: "db", Username: "user", Warehouse: "wh", TimestampType: "TIMESTAMP_WTF"}
+	require.Error(t, sfConfig.Validate())
+
+	sfConfig.TimestampType = ""
+	require.NoError(t, sfConfig.Validate())
+	require.Equal(t, SchemaToSnowflake, sfConfig.ColumnTypesMapping(), "default mapping must be kept")
+
+	sfConfig.TimestampType = TimestampTZ
+	require.NoError(t, sfConfig.Validate())
+	mapping := sfConfig.ColumnTypesMapping()
+	require.Equal(t, "TIMESTAMP_TZ(6)", mapping[typing.TIMESTAMP])
+	require.Equal(t, "timestamp(6)", SchemaToSnowflake[typing.TIMESTAMP], "global mapping mustn't be changed")
+
+	berlin := time.FixedZone("CEST", 2*60*60)
+	eventTime := time.Date(2022, 6, 1, 10, 30, 0, 123456000, berlin)
+
+	sf := &Snowflake{config: sfConfig}
+	value, cast := sf.timestampArg(eventTime, "")
+	require.Equal(t, "2022-06-01T10:30:00.123456+02:00", value)
+	require.Equal(t, "::TIMESTAMP_TZ", cast)
+	value, cast = sf.timestampArg("not a time", "")
+	require.Equal(t, "not a time", value)
+	require.Equal(t, "", cast)
+
+	sfConfig.TimestampType = TimestampNTZ
+	value, cast = sf.timestampArg(eventTime, "")
+	require.Equal(t, "2022-06-01T08:30:00.123456Z", value)
+	require.Equal(t, "::TIMESTAMP_NTZ", cast)
+
+	//without timestamp_type time values are bound by the driver as is
+	sfConfig.TimestampType = ""
+	value, cast = sf.timestampArg(eventTime, "")
+	require.Equal(t, eventTime, value)
+	require.Equal(t, "", cast)
+}
+
+func TestSFTimestampTZRoundTrip(t *testing.T) {
+	sfConfig, skip := readSFConfig(t)
+	if skip {
+		return
+	}
+	sfConfig.TimestampType = TimestampTZ
+	require.NoError(t, sfConfig.Validate())
+
+	sf, err := NewSnowflake(context.Background(), sfConfig, nil, &logging.QueryLogger{}, typing.SQLTypes{})
+	require.NoError(t, err)
+	defer sf.Close()
+
+	table := &Table{
+		Name:    "test_sf_timestamp_tz_" + uuid.NewLettersNumbers(),
+		Columns: Columns{"id": typing.SQLColumn{Type: "text"}, "event_time": typing.SQLColumn{Type: sfConfig.ColumnTypesMapping()[typing.TIMESTAMP]}},
+	}
+	require.NoError(t, sf.CreateTable(table))
+	defer func() {
+		tx, _ := sf.OpenTx()
+		sf.dropTableInTransaction(tx, table)
+		tx.Commit()
+	}()
+
+	eventTime := time.Date(2022, 6, 1, 10, 30, 0, 123456000, time.FixedZone("CEST", 2*60*60))
+	require.NoError(t, sf.BulkInsert(table, []map[string]interface{}{{"id": "1", "event_time": eventTime}}))
+
+	var offsetMinutes int
+	var loaded time.Time
+	row := sf.dataSource.QueryRow(fmt.Sprintf("SELECT event_time, EXTRACT(TIMEZONE_HOUR FROM event_time) * 60 + EXTRACT(TIMEZONE_MINUTE FROM event_time) FROM %s.%s", sfConfig.Schema, table.Name))
+	require.NoError(t, row.Scan(&loaded, &offsetMinutes))
+	require.True(t, eventTime.Equal(loaded), "expected %s, got %s", eventTime, loaded)
+	require.Equal(t, 120, offsetMinutes, "timezone offset must be kept")
+}
+
+func TestSnowflakeConnectionSettings(t *testing.T) {
+	tests := []struct {
+		name             string
+		modify           func(config *SnowflakeConfig)
+		expectedErr      string
+		expectedDeadline bool
+	}{
+		{
+			name:   "defaults: database/sql pool and no query timeout",
+			modify: func(config *SnowflakeConfig) {},
+		},
+		{
+			name: "pool and query timeout",
+			modify: func(config *SnowflakeConfig) {
+				config.MaxOpenConns = 10
+				config.MaxIdleConns = 2
+				config.ConnMaxLifetimeSec = 300
+				config.QueryTimeoutSec = 60
+			},
+			expectedDeadline: true,
+		},
+		{
+			name:        "negative max_open_conns",
+			modify:      func(config *SnowflakeConfig) { config.MaxOpenConns = -1 },
+			expectedErr: "Snowflake max_open_conns, max_idle_conns, conn_max_lifetime_sec and query_timeout_sec must be positive",
+		},
+		{
+			name:        "negative query_timeout_sec",
+			modify:      func(config *SnowflakeConfig) { config.QueryTimeoutSec = -1 },
+			expectedErr: "Snowflake max_open_conns, max_idle_conns, conn_max_lifetime_sec and query_timeout_sec must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SnowflakeConfig{Account: "account", Db: "db", Username: "user", Warehouse: "warehouse"}
+			tt.modify(config)
+			err := config.Validate()
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			s := &Snowflake{ctx: context.Background(), config: config}
+			ctx, cancel := s.queryContext()
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			require.Equal(t, tt.expectedDeadline, ok)
+			if ok {
+				require.WithinDuration(t, time.Now().Add(time.Duration(config.QueryTimeoutSec)*time.Second), deadline, time.Second)
+			}
+		})
+	}
+}
+
+func TestBuildMergeRowStatement(t *testing.T) {
+	object := map[string]interface{}{"title": "title1", "id": 1, "tenant": "t1"}
+	tests := []struct {
+		name                      string
+		onConflict                string
+		pkFields                  map[string]bool
+		object                    map[string]interface{}
+		expectedStatement         string
+		expectedValues            []interface{}
+		expectedWithMatchedClause bool
+	}{
+		{
+			"update",
+			OnConflictUpdate,
+			map[string]bool{"id": true},
+			object,
+			"MERGE INTO PUBLIC.events USING (SELECT ? AS id, ? AS tenant, ?::text AS title) jitsu_src ON events.id = jitsu_src.id" +
+				" WHEN MATCHED THEN UPDATE SET events.tenant = jitsu_src.tenant, events.title = jitsu_src.title" +
+				" WHEN NOT MATCHED THEN INSERT (id, tenant, title) VALUES (jitsu_src.id, jitsu_src.tenant, jitsu_src.title)",
+			[]interface{}{1, "t1", "title1"},
+			true,
+		},
+		{
+			"ignore",
+			OnConflictIgnore,
+			map[string]bool{"id": true},
+			object,
+			"MERGE INTO PUBLIC.events USING (SELECT ? AS id, ? AS tenant, ?::text AS title) jitsu_src ON events.id = jitsu_src.id" +
+				" WHEN NOT MATCHED THEN INSERT (id, tenant, title) VALUES (jitsu_src.id, jitsu_src.tenant, jitsu_src.title)",
+			[]interface{}{1, "t1", "title1"},
+			false,
+		},
+		{
+			"update composite primary key",
+			OnConflictUpdate,
+			map[string]bool{"tenant": true, "id": true},
+			object,
+			"MERGE INTO PUBLIC.events USING (SELECT ? AS id, ? AS tenant, ?::text AS title) jitsu_src ON events.id = jitsu_src.id AND events.tenant = jitsu_src.tenant" +
+				" WHEN MATCHED THEN UPDATE SET events.title = jitsu_src.title" +
+				" WHEN NOT MATCHED THEN INSERT (id, tenant, title) VALUES (jitsu_src.id, jitsu_src.tenant, jitsu_src.title)",
+			[]interface{}{1, "t1", "title1"},
+			true,
+		},
+		{
+			"update only primary key fields",
+			OnConflictUpdate,
+			map[string]bool{"id": true},
+			map[string]interface{}{"id": 1},
+			"MERGE INTO PUBLIC.events USING (SELECT ? AS id) jitsu_src ON events.id = jitsu_src.id" +
+				" WHEN NOT MATCHED THEN INSERT (id) VALUES (jitsu_src.id)",
+			[]interface{}{1},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := &Snowflake{config: &SnowflakeConfig{Schema: "PUBLIC"}, sqlTypes: typing.SQLTypes{"title": typing.SQLColumn{Type: "text"}}}
+			sf.SetOnConflict(tt.onConflict)
+
+			statement, values, withMatchedClause := sf.buildMergeRowStatement(&Table{Name: "events", PKFields: tt.pkFields}, tt.object)
+			require.Equal(t, tt.expectedStatement, statement)
+			require.Equal(t, tt.expectedValues, values)
+			require.Equal(t, tt.expectedWithMatchedClause, withMatchedClause)
+		})
+	}
+}
+
+func TestBuildMergeUpdateStatement(t *testing.T) {
+	tests := []struct {
+		name              string
+		objects           []map[string]interface{}
+		whereValues       []interface{}
+		expectedStatement string
+		expectedValues    []interface{}
+		expectedRows      int
+	}{
+		{
+			"one row",
+			[]map[string]interface{}{{"name": "a", "tenant": "t1"}},
+			[]interface{}{1},
+			"MERGE INTO PUBLIC.users USING (SELECT ? AS name, ?::text AS tenant, ? AS jitsu_update_key) jitsu_src ON users.id = jitsu_src.jitsu_update_key" +
+				" WHEN MATCHED THEN UPDATE SET users.name = jitsu_src.name, users.tenant = jitsu_src.tenant",
+			[]interface{}{"a", "t1", 1},
+			1,
+		},
+		{
+			"many rows",
+			[]map[string]interface{}{{"name": "a", "tenant": "t1"}, {"name": "b", "tenant": "t2"}},
+			[]interface{}{1, 2},
+			"MERGE INTO PUBLIC.users USING (SELECT ? AS name, ?::text AS tenant, ? AS jitsu_update_key UNION ALL SELECT ? AS name, ?::text AS tenant, ? AS jitsu_update_key) jitsu_src" +
+				" ON users.id = jitsu_src.jitsu_update_key WHEN MATCHED THEN UPDATE SET users.name = jitsu_src.name, users.tenant = jitsu_src.tenant",
+			[]interface{}{"a", "t1", 1, "b", "t2", 2},
+			2,
+		},
+		{
+			"the last object with the same key wins",
+			[]map[string]interface{}{{"name": "a", "tenant": "t1"}, {"name": "b", "tenant": "t2"}, {"name": "c", "tenant": "t3"}},
+			[]interface{}{1, 2, 1},
+			"MERGE INTO PUBLIC.users USING (SELECT ? AS name, ?::text AS tenant, ? AS jitsu_update_key UNION ALL SELECT ? AS name, ?::text AS tenant, ? AS jitsu_update_key) jitsu_src" +
+				" ON users.id = jitsu_src.jitsu_update_key WHEN MATCHED THEN UPDATE SET users.name = jitsu_src.name, users.tenant = jitsu_src.tenant",
+			[]interface{}{"b", "t2", 2, "c", "t3", 1},
+			2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := &Snowflake{config: &SnowflakeConfig{Schema: "PUBLIC"}, sqlTypes: typing.SQLTypes{"tenant": typing.SQLColumn{Type: "text"}}}
+
+			statement, values, rows := sf.buildMergeUpdateStatement(&Table{Name: "users"}, tt.objects, "id", tt.whereValues)
+			require.Equal(t, tt.expectedStatement, statement)
+			require.Equal(t, tt.expectedValues, values)
+			require.Equal(t, tt.expectedRows, rows)
+		})
+	}
+}
+
+func TestSnowflakeUpdateBatchSize(t *testing.T) {
+	tests := []struct {
+		name            string
+		updateBatchSize int
+		expected        int
+		expectedErr     string
+	}{
+		{"default", 0, defaultUpdateBatchSize, ""},
+		{"configured", 500, 500, ""},
+		{"one UPDATE statement per record", 1, 1, ""},
+		{"negative", -1, 0, "Snowflake update_batch_size must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SnowflakeConfig{Account: "account", Db: "db", Username: "user", Warehouse: "warehouse", UpdateBatchSize: tt.updateBatchSize}
+			err := config.Validate()
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, config.UpdateBatchSize)
+		})
+	}
+}
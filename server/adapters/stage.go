@@ -1,6 +1,9 @@
 package adapters
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 //Stage is an intermediate layer (for BQ, Snowflake, Redshift, etc)
 type Stage interface {
@@ -8,3 +11,16 @@ type Stage interface {
 	UploadBytes(fileName string, fileBytes []byte) error
 	DeleteObject(key string) error
 }
+
+//ListableStage is a Stage which can list files under a prefix
+type ListableStage interface {
+	Stage
+	//ListObjects returns files under the prefix with keys as they are passed to UploadBytes
+	ListObjects(prefix string) ([]*StageObject, error)
+}
+
+//StageObject is a stage file key with the last modification time
+type StageObject struct {
+	Key          string
+	LastModified time.Time
+}
@@ -0,0 +1,93 @@
+package adapters
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//isStageTransportConfigured returns true if any of proxy_url, ca_bundle or tls_skip_verify is set
+//otherwise the default HTTP transport of the stage client is used
+func isStageTransportConfigured(proxyURL, caBundle string, skipVerify bool) bool {
+	return proxyURL != "" || caBundle != "" || skipVerify
+}
+
+//validateStageTransport returns err if proxy_url is malformed or ca_bundle file can't be loaded
+func validateStageTransport(section, proxyURL, caBundle string) error {
+	if proxyURL != "" {
+		if _, err := parseProxyURL(proxyURL); err != nil {
+			return fmt.Errorf("%s proxy_url is malformed: %v", section, err)
+		}
+	}
+	if caBundle != "" {
+		if _, err := loadCABundle(caBundle); err != nil {
+			return fmt.Errorf("%s ca_bundle: %v", section, err)
+		}
+	}
+
+	return nil
+}
+
+//newStageTransport returns HTTP transport for stage adapters (S3/GCS) with proxy and custom CA bundle
+//tls_skip_verify disables server certificate verification and is discouraged
+func newStageTransport(section, proxyURL, caBundle string, skipVerify bool) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		parsed, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s proxy_url is malformed: %v", section, err)
+		}
+		transport.Proxy = http.ProxyURL(parsed)
+	}
+
+	tlsConfig := &tls.Config{}
+	if caBundle != "" {
+		caCertPool, err := loadCABundle(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("%s ca_bundle: %v", section, err)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+	if skipVerify {
+		logging.Warnf("%s tls_skip_verify is enabled: server certificates aren't verified. Use ca_bundle instead", section)
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("'%s' must be an absolute URL e.g. http://proxy:3128", proxyURL)
+	}
+
+	return parsed, nil
+}
+
+//loadCABundle returns system cert pool with appended PEM certificates from the file
+func loadCABundle(filePath string) (*x509.CertPool, error) {
+	payload, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %v", filePath, err)
+	}
+
+	caCertPool, err := x509.SystemCertPool()
+	if err != nil || caCertPool == nil {
+		caCertPool = x509.NewCertPool()
+	}
+	if ok := caCertPool.AppendCertsFromPEM(payload); !ok {
+		return nil, fmt.Errorf("error parsing file %s: PEM certificates are expected", filePath)
+	}
+
+	return caCertPool, nil
+}
@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStageTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stage_transport")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	notPEM := filepath.Join(dir, "not_pem.crt")
+	require.NoError(t, ioutil.WriteFile(notPEM, []byte("not a certificate"), 0600))
+
+	tests := []struct {
+		name     string
+		proxyURL string
+		caBundle string
+		wantErr  bool
+	}{
+		{"empty", "", "", false},
+		{"proxy", "http://proxy.local:3128", "", false},
+		{"relative proxy", "proxy.local", "", true},
+		{"missing ca_bundle", "", filepath.Join(dir, "missing.crt"), true},
+		{"malformed ca_bundle", "", notPEM, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStageTransport("S3", tt.proxyURL, tt.caBundle)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewStageTransport(t *testing.T) {
+	transport, err := newStageTransport("S3", "http://proxy.local:3128", "", true)
+	require.NoError(t, err)
+	require.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+
+	request, err := http.NewRequest(http.MethodPut, "https://bucket.s3.amazonaws.com/file", nil)
+	require.NoError(t, err)
+	proxy, err := transport.Proxy(request)
+	require.NoError(t, err)
+	require.Equal(t, "proxy.local:3128", proxy.Host)
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/schema"
 	"io"
+	"io/ioutil"
+	"os"
 )
 
 const (
@@ -19,6 +22,24 @@ type asynchronousParser struct {
 	dataConsumer          base.CLIDataConsumer
 	streamsRepresentation map[string]*base.StreamRepresentation
 	logger                logging.TaskLogger
+	//configPath is a connector config file path which is rewritten with the config from CONTROL messages
+	configPath string
+	//configListener is notified about connector config updates (optional)
+	configListener func(config map[string]interface{})
+	//sourceID and sourceTap are metrics labels. Per-stream metrics aren't written if sourceID is empty
+	sourceID  string
+	sourceTap string
+	//streamsStats is a per-stream amount of records and bytes which haven't been written into metrics yet
+	streamsStats map[string]*streamStats
+	//traceError is the last error TRACE message: it is the failure reason if the read fails
+	traceError *TraceErrorRow
+	//normalization is NormalizationRaw or NormalizationBasic (arrays of objects are written into child streams)
+	normalization string
+}
+
+type streamStats struct {
+	records int
+	bytes   int
 }
 
 //Parse reads from stdout and:
@@ -46,6 +67,8 @@ func (ap *asynchronousParser) parse(stdout io.Reader) error {
 	scanner.Buffer(buf, 1024*1024)
 
 	records := 0
+	//stateChanged is true if the state has been emitted after the last persisted batch
+	stateChanged := false
 	for scanner.Scan() {
 		lineBytes := scanner.Bytes()
 
@@ -56,7 +79,17 @@ func (ap *asynchronousParser) parse(stdout io.Reader) error {
 			continue
 		}
 
-		if row.Type != RecordType || row.Record == nil {
+		if row.Type == ControlType {
+			ap.control(row.Control)
+			continue
+		}
+
+		if row.Type == TraceType {
+			ap.trace(row.Trace)
+			continue
+		}
+
+		if row.Type != StateType && (row.Type != RecordType || row.Record == nil) {
 			ap.logger.LOG(string(lineBytes), airbyteSystem, logging.DEBUG)
 			continue
 		}
@@ -82,6 +115,8 @@ func (ap *asynchronousParser) parse(stdout io.Reader) error {
 			}
 
 			output.State = row.State.Data
+			stateChanged = true
+			ap.flushStreamsStats()
 		case RecordType:
 			records++
 			if row.Record == nil || row.Record.Data == nil {
@@ -89,6 +124,10 @@ func (ap *asynchronousParser) parse(stdout io.Reader) error {
 			}
 
 			output.Streams[row.Record.Stream].Objects = append(output.Streams[row.Record.Stream].Objects, row.Record.Data)
+			if ap.normalization == NormalizationBasic {
+				ap.normalize(output, row.Record.Stream, row.Record.Data)
+			}
+			ap.countRecord(row.Record.Stream, len(lineBytes))
 		default:
 			msg := fmt.Sprintf("Unknown airbyte output line type: %s [%s]", row.Type, string(lineBytes))
 			logging.Error(msg)
@@ -97,6 +136,7 @@ func (ap *asynchronousParser) parse(stdout io.Reader) error {
 
 		//persist batch and recreate variables
 		if records >= Instance.batchSize {
+			ap.flushStreamsStats()
 			err := ap.dataConsumer.Consume(output)
 			if err != nil {
 				return err
@@ -109,11 +149,15 @@ func (ap *asynchronousParser) parse(stdout io.Reader) error {
 				stream.NeedClean = false
 			}
 			records = 0
+			stateChanged = false
 		}
 	}
 
-	//persist last batch
-	if records > 0 {
+	ap.flushStreamsStats()
+
+	//persist last batch and the latest state (even if there are no records after it)
+	//the state persisting error must fail the sync: otherwise the next sync will re-read data from the previous state
+	if records > 0 || stateChanged {
 		err := ap.dataConsumer.Consume(output)
 		if err != nil {
 			return err
@@ -127,3 +171,104 @@ func (ap *asynchronousParser) parse(stdout io.Reader) error {
 
 	return nil
 }
+
+//normalize moves arrays of objects from the record into child streams records (see normalizeRecord)
+//child streams are created on the first record: they are cleaned together with the parent stream in full refresh mode
+func (ap *asynchronousParser) normalize(output *base.CLIOutputRepresentation, stream string, record map[string]interface{}) {
+	childRecords := map[string][]map[string]interface{}{}
+	normalizeRecord(stream, record, childRecords)
+
+	for childStream, objects := range childRecords {
+		representation, ok := output.Streams[childStream]
+		if !ok {
+			representation = &base.StreamRepresentation{
+				StreamName:   childStream,
+				BatchHeader:  &schema.BatchHeader{TableName: childStream, Fields: schema.Fields{}},
+				Objects:      []map[string]interface{}{},
+				ParentStream: stream,
+			}
+			if parent, ok := ap.streamsRepresentation[stream]; ok {
+				representation.NeedClean = parent.NeedClean
+			}
+			output.Streams[childStream] = representation
+		}
+		representation.Objects = append(representation.Objects, objects...)
+	}
+}
+
+//countRecord accumulates the stream record and its raw message size until the next flushStreamsStats call
+func (ap *asynchronousParser) countRecord(stream string, bytes int) {
+	if ap.sourceID == "" {
+		return
+	}
+
+	if ap.streamsStats == nil {
+		ap.streamsStats = map[string]*streamStats{}
+	}
+	stats, ok := ap.streamsStats[stream]
+	if !ok {
+		stats = &streamStats{}
+		ap.streamsStats[stream] = stats
+	}
+	stats.records++
+	stats.bytes += bytes
+}
+
+//flushStreamsStats writes accumulated per-stream records and bytes into metrics and resets them
+func (ap *asynchronousParser) flushStreamsStats() {
+	for stream, stats := range ap.streamsStats {
+		if stats.records > 0 {
+			metrics.SourceStreamRecords(base.AirbyteType, ap.sourceTap, ap.sourceID, stream, stats.records, stats.bytes)
+		}
+	}
+	ap.streamsStats = nil
+}
+
+//trace keeps the last error TRACE message and writes it into the task log. Other trace types are ignored
+func (ap *asynchronousParser) trace(traceRow *TraceRow) {
+	if traceRow == nil || traceRow.Type != ErrorTraceType || traceRow.Error == nil {
+		return
+	}
+
+	ap.traceError = traceRow.Error
+	ap.logger.LOG("%s (failure type: %s, internal message: %s)", airbyteSystem, logging.ERROR, traceRow.Error.Message, traceRow.Error.FailureType, traceRow.Error.InternalMessage)
+}
+
+//control persists the updated connector config (e.g. refreshed OAuth tokens) from CONNECTOR_CONFIG control message
+//so the next syncs use it. Errors are only logged because the current sync isn't affected
+func (ap *asynchronousParser) control(controlRow *ControlRow) {
+	if controlRow == nil || controlRow.Type != ConnectorConfigControlType {
+		return
+	}
+	if controlRow.ConnectorConfig == nil || controlRow.ConnectorConfig.Config == nil {
+		ap.logger.WARN("Airbyte connector config control message doesn't contain 'config'")
+		return
+	}
+
+	if ap.configPath != "" {
+		if err := writeConfig(ap.configPath, controlRow.ConnectorConfig.Config); err != nil {
+			ap.logger.ERROR("Error saving updated Airbyte connector config: %v", err)
+			return
+		}
+	}
+	if ap.configListener != nil {
+		ap.configListener(controlRow.ConnectorConfig.Config)
+	}
+
+	ap.logger.INFO("Airbyte connector config has been updated by the connector")
+}
+
+//writeConfig writes config into a temporary file and renames it so the config file is never partially written
+func writeConfig(configPath string, config map[string]interface{}) error {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("Malformed config: %v", err)
+	}
+
+	tmpPath := configPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, configPath)
+}
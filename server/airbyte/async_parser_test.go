@@ -0,0 +1,222 @@
+package airbyte
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+type testTaskLogger struct{}
+
+func (ttl *testTaskLogger) INFO(format string, v ...interface{})                             {}
+func (ttl *testTaskLogger) ERROR(format string, v ...interface{})                            {}
+func (ttl *testTaskLogger) WARN(format string, v ...interface{})                             {}
+func (ttl *testTaskLogger) LOG(format, system string, level logging.Level, v ...interface{}) {}
+func (ttl *testTaskLogger) Write(p []byte) (n int, err error)                                { return len(p), nil }
+
+type testDataConsumer struct {
+	objects int
+	//state is the latest successfully persisted state
+	state interface{}
+	//stateErr is returned from Consume if the state is passed
+	stateErr error
+}
+
+func (tdc *testDataConsumer) Consume(representation *base.CLIOutputRepresentation) error {
+	for _, stream := range representation.Streams {
+		tdc.objects += len(stream.Objects)
+	}
+
+	if representation.State != nil {
+		if tdc.stateErr != nil {
+			return tdc.stateErr
+		}
+		tdc.state = representation.State
+	}
+	return nil
+}
+
+func TestParseControlMessage(t *testing.T) {
+	Instance = &Bridge{batchSize: 10}
+	defer func() { Instance = nil }()
+
+	dir, err := ioutil.TempDir("", "airbyte_control")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, base.ConfigFileName)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`{"access_token":"expired","refresh_token":"rt"}`), 0644))
+
+	stdout := strings.Join([]string{
+		`{"type":"RECORD","record":{"stream":"users","data":{"id":1}}}`,
+		`{"type":"CONTROL","control":{"type":"CONNECTOR_CONFIG","emitted_at":1672531200000,"connectorConfig":{"config":{"access_token":"refreshed","refresh_token":"rt"}}}}`,
+		`{"type":"RECORD","record":{"stream":"users","data":{"id":2}}}`,
+	}, "\n")
+
+	var notified map[string]interface{}
+	consumer := &testDataConsumer{}
+	parser := &asynchronousParser{
+		dataConsumer: consumer,
+		streamsRepresentation: map[string]*base.StreamRepresentation{
+			"users": {BatchHeader: &schema.BatchHeader{TableName: "users", Fields: schema.Fields{}}},
+		},
+		logger:         &testTaskLogger{},
+		configPath:     configPath,
+		configListener: func(config map[string]interface{}) { notified = config },
+	}
+	require.NoError(t, parser.parse(strings.NewReader(stdout)))
+	require.Equal(t, 2, consumer.objects)
+
+	b, err := ioutil.ReadFile(configPath)
+	require.NoError(t, err)
+	actual := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(b, &actual), fmt.Sprintf("config file: %s", string(b)))
+	expected := map[string]interface{}{"access_token": "refreshed", "refresh_token": "rt"}
+	require.Equal(t, expected, actual)
+	require.Equal(t, expected, notified)
+}
+
+func TestParseState(t *testing.T) {
+	Instance = &Bridge{batchSize: 2}
+	defer func() { Instance = nil }()
+
+	stdout := strings.Join([]string{
+		`{"type":"RECORD","record":{"stream":"users","data":{"id":1}}}`,
+		`{"type":"RECORD","record":{"stream":"users","data":{"id":2}}}`,
+		`{"type":"STATE","state":{"data":{"cursor":2}}}`,
+	}, "\n")
+
+	tests := []struct {
+		name          string
+		stateErr      error
+		expectedErr   string
+		expectedState interface{}
+	}{
+		{
+			"state after the last batch is persisted",
+			nil,
+			"",
+			map[string]interface{}{"cursor": float64(2)},
+		},
+		{
+			"state persisting failure fails the sync",
+			errors.New("meta storage is unavailable"),
+			"meta storage is unavailable",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &testDataConsumer{stateErr: tt.stateErr}
+			parser := &asynchronousParser{
+				dataConsumer: consumer,
+				streamsRepresentation: map[string]*base.StreamRepresentation{
+					"users": {BatchHeader: &schema.BatchHeader{TableName: "users", Fields: schema.Fields{}}},
+				},
+				logger: &testTaskLogger{},
+			}
+
+			err := parser.parse(strings.NewReader(stdout))
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, 2, consumer.objects)
+			require.Equal(t, tt.expectedState, consumer.state)
+		})
+	}
+}
+
+func TestParseTraceError(t *testing.T) {
+	Instance = &Bridge{batchSize: 10}
+	defer func() { Instance = nil }()
+
+	stdout := strings.Join([]string{
+		`{"type":"RECORD","record":{"stream":"users","data":{"id":1}}}`,
+		`{"type":"TRACE","trace":{"type":"ESTIMATE","emitted_at":1672531200000}}`,
+		`{"type":"TRACE","trace":{"type":"ERROR","emitted_at":1672531200000,"error":{"message":"Something went wrong","failure_type":"system_error"}}}`,
+		`{"type":"TRACE","trace":{"type":"ERROR","emitted_at":1672531201000,"error":{"message":"authentication failed","internal_message":"401 Client Error: Unauthorized","stack_trace":"Traceback...","failure_type":"config_error"}}}`,
+	}, "\n")
+
+	consumer := &testDataConsumer{}
+	parser := &asynchronousParser{
+		dataConsumer: consumer,
+		streamsRepresentation: map[string]*base.StreamRepresentation{
+			"users": {BatchHeader: &schema.BatchHeader{TableName: "users", Fields: schema.Fields{}}},
+		},
+		logger: &testTaskLogger{},
+	}
+	require.NoError(t, parser.parse(strings.NewReader(stdout)))
+	require.Equal(t, 1, consumer.objects)
+
+	//the last error trace is the failure reason
+	require.NotNil(t, parser.traceError)
+	exitErr := errors.New("exit status 1")
+	err := withTraceError(parser.traceError, exitErr)
+	require.Equal(t, "Airbyte source: authentication failed (failure type: config_error, internal message: 401 Client Error: Unauthorized): exit status 1", err.Error())
+	require.True(t, errors.Is(err, exitErr))
+	var traceErr *TraceError
+	require.True(t, errors.As(err, &traceErr))
+	require.Equal(t, "config_error", traceErr.Trace.FailureType)
+
+	//successful read isn't failed by the trace
+	require.NoError(t, withTraceError(parser.traceError, nil))
+	require.Equal(t, exitErr, withTraceError(nil, exitErr))
+}
+
+func TestCountStreamRecords(t *testing.T) {
+	type record struct {
+		stream string
+		bytes  int
+	}
+	tests := []struct {
+		name     string
+		sourceID string
+		records  []record
+		expected map[string]*streamStats
+	}{
+		{
+			"Without source ID",
+			"",
+			[]record{{"users", 10}},
+			nil,
+		},
+		{
+			"Without records",
+			"source1",
+			nil,
+			nil,
+		},
+		{
+			"Per stream records and bytes",
+			"source1",
+			[]record{{"users", 10}, {"orders", 25}, {"users", 15}},
+			map[string]*streamStats{
+				"users":  {records: 2, bytes: 25},
+				"orders": {records: 1, bytes: 25},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ap := &asynchronousParser{sourceID: tt.sourceID, sourceTap: "airbyte/source-test"}
+			for _, r := range tt.records {
+				ap.countRecord(r.stream, r.bytes)
+			}
+			require.Equal(t, tt.expected, ap.streamsStats)
+
+			ap.flushStreamsStats()
+			require.Nil(t, ap.streamsStats, "stats must be reset after flush")
+		})
+	}
+}
@@ -2,6 +2,7 @@ package airbyte
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
@@ -10,6 +11,7 @@ import (
 	"github.com/jitsucom/jitsu/server/safego"
 	"io"
 	"io/ioutil"
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -34,29 +36,53 @@ type Bridge struct {
 	WorkspaceVolume string
 
 	batchSize int
+	//limits concurrent containers of all commands
+	containers *containersLimiter
 	//spec loading
-	imageMutex    *sync.RWMutex
+	imageMutex *sync.RWMutex
+	//pullingImages is a map of in-flight pulls: docker image -> *imagePull
 	pullingImages *sync.Map
 	pulledImages  map[string]bool
+	//pullFunc executes docker pull (bridge.pullImage)
+	pullFunc func(dockerVersionedImage string) error
+	//configDirs tracks used per source config directories
+	configDirs *configDirs
+	//catalogs keeps discovered catalogs
+	catalogs *catalogCache
+	//checks keeps successful connection tests
+	checks    *checkCache
+	startedAt time.Time
 }
 
 //Init initializes airbyte Bridge
-func Init(ctx context.Context, configDir, workspaceVolume string, batchSize int, logWriter io.Writer) error {
-	logging.Infof("Initializing Airbyte bridge. Batch size: %d", batchSize)
+//maxConcurrentContainers limits the number of concurrently running Airbyte containers (0 - unlimited)
+//discovered catalogs are cached for catalogCacheTTL (DefaultCatalogCacheTTL if it isn't positive)
+//successful connection tests are cached for checkCacheTTL (disabled if it isn't positive)
+func Init(ctx context.Context, configDir, workspaceVolume string, batchSize, maxConcurrentContainers int, catalogCacheTTL, checkCacheTTL time.Duration, logWriter io.Writer) error {
+	logging.Infof("Initializing Airbyte bridge. Batch size: %d, max concurrent containers: %d", batchSize, maxConcurrentContainers)
 
 	if logWriter == nil {
 		logWriter = ioutil.Discard
 	}
+	if catalogCacheTTL <= 0 {
+		catalogCacheTTL = DefaultCatalogCacheTTL
+	}
 	Instance = &Bridge{
 		LogWriter:       logWriter,
 		ConfigDir:       configDir,
 		WorkspaceVolume: workspaceVolume,
 
 		batchSize:     batchSize,
+		containers:    newContainersLimiter(maxConcurrentContainers),
 		imageMutex:    &sync.RWMutex{},
 		pullingImages: &sync.Map{},
 		pulledImages:  map[string]bool{},
+		configDirs:    newConfigDirs(configDir),
+		catalogs:      newCatalogCache(path.Join(configDir, catalogsDirName), catalogCacheTTL),
+		checks:        newCheckCache(checkCacheTTL),
+		startedAt:     time.Now(),
 	}
+	Instance.pullFunc = Instance.pullImage
 
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
@@ -81,6 +107,63 @@ func Init(ctx context.Context, configDir, workspaceVolume string, batchSize int,
 	return nil
 }
 
+//AcquireConfigDir marks the source config directory as used (by the driver or the running sync)
+//the directory isn't removed until it is released by all users
+func (b *Bridge) AcquireConfigDir(dir string) {
+	b.configDirs.acquire(dir)
+}
+
+//ReleaseConfigDir marks the source config directory as unused by one user.
+//The directory is removed if RemoveConfigDir has been called and there are no other users
+func (b *Bridge) ReleaseConfigDir(dir string) {
+	b.configDirs.release(dir)
+}
+
+//RemoveConfigDir removes the source config directory (e.g. on the source removal or recreation) right away if it isn't used
+//otherwise after the last release
+func (b *Bridge) RemoveConfigDir(dir string) {
+	b.configDirs.remove(dir)
+}
+
+//PruneConfigDirs removes config directories which have been left by previous runs and don't belong to sourceIDs
+//and directories of docker images which aren't used by existing sources anymore
+func (b *Bridge) PruneConfigDirs(sourceIDs map[string]bool) {
+	for _, dir := range b.configDirs.prune(sourceIDs, b.startedAt) {
+		logging.Infof("Orphaned airbyte config dir [%s] has been removed", dir)
+	}
+}
+
+//CachedCatalog returns the catalog which has been discovered with the configuration hash (see CatalogHash)
+//returns nil if it isn't cached or has been expired
+func (b *Bridge) CachedCatalog(hash string) *CatalogRow {
+	return b.catalogs.get(hash)
+}
+
+//CacheCatalog caches the discovered catalog by the configuration hash (see CatalogHash)
+func (b *Bridge) CacheCatalog(hash, dockerImage string, catalog *CatalogRow) error {
+	return b.catalogs.put(hash, dockerImage, catalog)
+}
+
+//RemoveCachedCatalog removes the cached catalog by the configuration hash
+func (b *Bridge) RemoveCachedCatalog(hash string) error {
+	return b.catalogs.remove(hash)
+}
+
+//EvictCachedCatalogs removes cached catalogs of all versions of the docker image. Returns number of evicted catalogs
+func (b *Bridge) EvictCachedCatalogs(dockerImage string) int {
+	return b.catalogs.evict(dockerImage)
+}
+
+//IsCheckSucceeded returns true if the connection test with the configuration hash has succeeded recently
+func (b *Bridge) IsCheckSucceeded(hash string) bool {
+	return b.checks.isSucceeded(hash)
+}
+
+//CheckSucceeded caches successful connection test result by the configuration hash
+func (b *Bridge) CheckSucceeded(hash string) {
+	b.checks.succeed(hash)
+}
+
 //IsImagePulled returns true if the image is pulled or start pulling the image asynchronously and returns false
 func (b *Bridge) IsImagePulled(dockerRepoImage, version string) bool {
 	dockerVersionedImage := fmt.Sprintf("%s:%s", dockerRepoImage, version)
@@ -92,33 +175,86 @@ func (b *Bridge) IsImagePulled(dockerRepoImage, version string) bool {
 	}
 
 	//or do pull
-	if _, exists := b.pullingImages.LoadOrStore(dockerVersionedImage, true); !exists {
-		safego.Run(func() {
-			b.pullImage(dockerVersionedImage)
-		})
+	b.startPull(dockerVersionedImage)
+	return false
+}
+
+//PullImage pulls the image synchronously if it isn't pulled yet. Concurrent callers wait for the same pull
+//returns actionable error if the image can't be pulled (e.g. not found or access denied)
+func (b *Bridge) PullImage(dockerRepoImage, version string) error {
+	dockerVersionedImage := fmt.Sprintf("%s:%s", dockerRepoImage, version)
+	b.imageMutex.RLock()
+	_, exist := b.pulledImages[dockerVersionedImage]
+	b.imageMutex.RUnlock()
+	if exist {
+		return nil
 	}
 
-	return false
+	pull := b.startPull(dockerVersionedImage)
+	<-pull.done
+	return pull.err
 }
 
-//pullImage executes docker pull
-func (b *Bridge) pullImage(dockerVersionedImage string) {
-	defer b.pullingImages.Delete(dockerVersionedImage)
+//imagePull is an in-flight docker pull which is shared by all callers
+type imagePull struct {
+	done chan struct{}
+	//err is set before closing done
+	err error
+}
 
+//startPull returns the in-flight pull of the image or starts pulling the image asynchronously
+func (b *Bridge) startPull(dockerVersionedImage string) *imagePull {
+	pull := &imagePull{done: make(chan struct{})}
+	actual, loaded := b.pullingImages.LoadOrStore(dockerVersionedImage, pull)
+	if loaded {
+		return actual.(*imagePull)
+	}
+
+	safego.Run(func() {
+		defer close(pull.done)
+		defer b.pullingImages.Delete(dockerVersionedImage)
+		pull.err = b.pullFunc(dockerVersionedImage)
+	})
+
+	return pull
+}
+
+//pullImage executes docker pull and writes pull progress into airbyte logs
+func (b *Bridge) pullImage(dockerVersionedImage string) error {
 	pullImgOutWriter := logging.NewStringWriter()
 	pullImgErrWriter := logging.NewStringWriter()
+	progressWriter := logging.NewPrefixDateTimeProxy(fmt.Sprintf("[pull %s]", dockerVersionedImage), b.LogWriter)
 
+	logging.Infof("[Airbyte] pulling docker image: %s", dockerVersionedImage)
 	//pull last image
-	if err := runner.ExecCmd(BridgeType, DockerCommand, pullImgOutWriter, pullImgErrWriter, time.Minute*30, "pull", dockerVersionedImage); err != nil {
+	if err := runner.ExecCmd(BridgeType, DockerCommand, logging.Dual{FileWriter: pullImgOutWriter, Stdout: progressWriter},
+		logging.Dual{FileWriter: pullImgErrWriter, Stdout: progressWriter}, time.Minute*30, "pull", dockerVersionedImage); err != nil {
 		errMsg := b.BuildMsg("Error pulling airbyte image:", pullImgOutWriter, pullImgErrWriter, err)
 		logging.SystemError(errMsg)
 
-		return
+		return pullImageError(dockerVersionedImage, pullImgErrWriter.String(), errMsg)
 	}
 
+	logging.Infof("[Airbyte] docker image has been pulled: %s", dockerVersionedImage)
+
 	b.imageMutex.Lock()
 	b.pulledImages[dockerVersionedImage] = true
 	b.imageMutex.Unlock()
+
+	return nil
+}
+
+//pullImageError returns error with a hint based on docker pull stderr output
+func pullImageError(dockerVersionedImage, stderr, errMsg string) error {
+	lowerStderr := strings.ToLower(stderr)
+	switch {
+	case strings.Contains(lowerStderr, "manifest unknown") || strings.Contains(lowerStderr, "not found"):
+		return fmt.Errorf("docker image %s not found. Please check docker_image and image_version configuration parameters", dockerVersionedImage)
+	case strings.Contains(lowerStderr, "denied") || strings.Contains(lowerStderr, "unauthorized"):
+		return fmt.Errorf("access to docker image %s has been denied: the repository doesn't exist or requires 'docker login'", dockerVersionedImage)
+	default:
+		return errors.New(errMsg)
+	}
 }
 
 //BuildMsg returns formatted error
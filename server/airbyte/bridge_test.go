@@ -0,0 +1,76 @@
+package airbyte
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestPullImageDeduplication(t *testing.T) {
+	tests := []struct {
+		name    string
+		pullErr error
+	}{
+		{
+			name: "successful pull",
+		},
+		{
+			name:    "failed pull",
+			pullErr: errors.New("docker image airbyte/source-x:latest not found"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pulls := atomic.NewInt32(0)
+			release := make(chan struct{})
+			b := &Bridge{
+				imageMutex:    &sync.RWMutex{},
+				pullingImages: &sync.Map{},
+				pulledImages:  map[string]bool{},
+			}
+			b.pullFunc = func(dockerVersionedImage string) error {
+				pulls.Inc()
+				<-release
+				if tt.pullErr != nil {
+					return tt.pullErr
+				}
+
+				b.imageMutex.Lock()
+				b.pulledImages[dockerVersionedImage] = true
+				b.imageMutex.Unlock()
+				return nil
+			}
+
+			//the driver checks readiness while other drivers pull the same image
+			require.False(t, b.IsImagePulled("airbyte/source-x", "latest"))
+			pull := b.startPull("airbyte/source-x:latest")
+			for i := 0; i < 3; i++ {
+				require.Same(t, pull, b.startPull("airbyte/source-x:latest"), "concurrent callers must share the in-flight pull")
+			}
+
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			var err error
+			go func() {
+				defer wg.Done()
+				err = b.PullImage("airbyte/source-x", "latest")
+			}()
+			close(release)
+			wg.Wait()
+			<-pull.done
+
+			require.Equal(t, tt.pullErr, err)
+			require.Equal(t, tt.pullErr, pull.err)
+			require.Equal(t, tt.pullErr == nil, b.IsImagePulled("airbyte/source-x", "latest"))
+			if tt.pullErr == nil {
+				require.Equal(t, int32(1), pulls.Load())
+			} else {
+				//failed pull isn't cached: the next call pulls again
+				require.Equal(t, tt.pullErr, b.PullImage("airbyte/source-x", "latest"))
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package airbyte
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	//catalogsDirName is a directory in the airbyte config dir with cached discovered catalogs
+	catalogsDirName = "catalogs"
+	//DefaultCatalogCacheTTL is used if airbyte-bridge.catalog_cache_ttl_sec isn't configured
+	DefaultCatalogCacheTTL = 24 * time.Hour
+)
+
+//cachedCatalog is a discovered catalog with the docker image which it has been discovered with
+type cachedCatalog struct {
+	DockerImage string      `json:"docker_image"`
+	Catalog     *CatalogRow `json:"catalog"`
+}
+
+//catalogCache keeps discovered catalogs on the file system by the configuration hash (see CatalogHash).
+//Catalogs are reused by drivers after restarts and by catalog API requests. Catalogs expire after ttl.
+//Expired catalogs are removed on every put so the cache doesn't grow with configurations which aren't used anymore
+type catalogCache struct {
+	mutex *sync.Mutex
+	dir   string
+	ttl   time.Duration
+}
+
+func newCatalogCache(dir string, ttl time.Duration) *catalogCache {
+	return &catalogCache{mutex: &sync.Mutex{}, dir: dir, ttl: ttl}
+}
+
+//CatalogHash returns hash of the connector config, docker image, image version, env variables and docker network
+//the cached catalog is reused only if the hash is the same
+func CatalogHash(connectorConfig interface{}, dockerImage, imageVersion string, env map[string]string, dockerNetwork string) string {
+	configBytes, _ := json.Marshal(connectorConfig)
+	envBytes, _ := json.Marshal(env)
+	return uuid.GetHash(map[string]interface{}{
+		"config":         string(configBytes),
+		"docker_image":   trimImagePrefix(dockerImage),
+		"image_version":  imageVersion,
+		"env":            string(envBytes),
+		"docker_network": dockerNetwork,
+	})
+}
+
+//get returns the cached catalog or nil if it doesn't exist or has been expired
+func (cc *catalogCache) get(hash string) *CatalogRow {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	filePath := cc.path(hash)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warnf("Error reading cached airbyte catalog [%s]: %v", filePath, err)
+		}
+		return nil
+	}
+
+	if cc.expired(info.ModTime()) {
+		cc.removeFile(filePath)
+		return nil
+	}
+
+	cached, err := readCachedCatalog(filePath)
+	if err != nil {
+		logging.Warnf("%v", err)
+		return nil
+	}
+
+	return cached.Catalog
+}
+
+//put writes the catalog into the cache and removes expired catalogs
+func (cc *catalogCache) put(hash, dockerImage string, catalog *CatalogRow) error {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if err := logging.EnsureDir(cc.dir); err != nil {
+		return fmt.Errorf("Error creating cached catalogs dir: %v", err)
+	}
+
+	cc.sweep(func(filePath string, modTime time.Time) bool {
+		return cc.expired(modTime)
+	})
+
+	b, err := json.Marshal(&cachedCatalog{DockerImage: trimImagePrefix(dockerImage), Catalog: catalog})
+	if err != nil {
+		return fmt.Errorf("Error marshalling cached catalog: %v", err)
+	}
+
+	if err := ioutil.WriteFile(cc.path(hash), b, 0644); err != nil {
+		return fmt.Errorf("Error writing cached catalog file: %v", err)
+	}
+
+	return nil
+}
+
+//remove removes the cached catalog by the hash
+func (cc *catalogCache) remove(hash string) error {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if err := os.Remove(cc.path(hash)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Error removing cached catalog file: %v", err)
+	}
+
+	return nil
+}
+
+//evict removes all cached catalogs (of all versions and configurations) of the docker image
+//returns number of evicted catalogs
+func (cc *catalogCache) evict(dockerImage string) int {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	dockerImage = trimImagePrefix(dockerImage)
+	return cc.sweep(func(filePath string, modTime time.Time) bool {
+		cached, err := readCachedCatalog(filePath)
+		//unreadable files are removed as well
+		return err != nil || cached.DockerImage == dockerImage
+	})
+}
+
+//sweep removes cached catalog files which match the condition. Returns number of removed files
+//must be called under the lock
+func (cc *catalogCache) sweep(condition func(filePath string, modTime time.Time) bool) int {
+	entries, err := ioutil.ReadDir(cc.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warnf("Error reading cached airbyte catalogs dir [%s]: %v", cc.dir, err)
+		}
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		filePath := path.Join(cc.dir, entry.Name())
+		if entry.IsDir() || !condition(filePath, entry.ModTime()) {
+			continue
+		}
+
+		if cc.removeFile(filePath) {
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func (cc *catalogCache) expired(modTime time.Time) bool {
+	return time.Since(modTime) >= cc.ttl
+}
+
+func (cc *catalogCache) removeFile(filePath string) bool {
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		logging.Warnf("Error removing cached airbyte catalog [%s]: %v", filePath, err)
+		return false
+	}
+
+	return true
+}
+
+func (cc *catalogCache) path(hash string) string {
+	return path.Join(cc.dir, hash+".json")
+}
+
+func readCachedCatalog(filePath string) (*cachedCatalog, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading cached airbyte catalog [%s]: %v", filePath, err)
+	}
+
+	cached := &cachedCatalog{}
+	if err := json.Unmarshal(b, cached); err != nil {
+		return nil, fmt.Errorf("Error parsing cached airbyte catalog [%s]: %v", filePath, err)
+	}
+
+	return cached, nil
+}
+
+func trimImagePrefix(dockerImage string) string {
+	return strings.TrimPrefix(dockerImage, DockerImageRepositoryPrefix)
+}
@@ -0,0 +1,80 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte_catalog_cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cc := newCatalogCache(path.Join(dir, catalogsDirName), time.Hour)
+	hash := CatalogHash(map[string]interface{}{"host": "localhost", "port": 5432}, "source-postgres", "0.4.0", nil, "")
+	require.Nil(t, cc.get(hash), "catalog isn't cached yet")
+
+	catalog := &CatalogRow{Streams: []*Stream{{Name: "users", Namespace: "public", SupportedSyncModes: []string{"full_refresh"}}}}
+	require.NoError(t, cc.put(hash, "airbyte/source-postgres", catalog))
+	require.Equal(t, catalog, cc.get(hash))
+
+	require.Equal(t, hash, CatalogHash(map[string]interface{}{"port": 5432, "host": "localhost"}, "airbyte/source-postgres", "0.4.0", nil, ""),
+		"hash doesn't depend on keys order and airbyte prefix")
+	for _, changed := range []string{
+		CatalogHash(map[string]interface{}{"host": "remote", "port": 5432}, "source-postgres", "0.4.0", nil, ""),
+		CatalogHash(map[string]interface{}{"host": "localhost", "port": 5432}, "source-postgres", "0.4.1", nil, ""),
+		CatalogHash(map[string]interface{}{"host": "localhost", "port": 5432}, "source-postgres", "0.4.0", map[string]string{"JAVA_OPTS": "-Xmx1g"}, ""),
+		CatalogHash(map[string]interface{}{"host": "localhost", "port": 5432}, "source-postgres", "0.4.0", nil, "db_network"),
+	} {
+		require.NotEqual(t, hash, changed)
+		require.Nil(t, cc.get(changed), "catalog must be invalidated if configuration is changed")
+	}
+
+	require.NoError(t, cc.remove(hash))
+	require.Nil(t, cc.get(hash))
+	require.NoError(t, cc.remove(hash), "removing of not existing catalog isn't an error")
+}
+
+func TestCatalogCacheExpiration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte_catalog_cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cc := newCatalogCache(dir, time.Hour)
+	catalog := &CatalogRow{Streams: []*Stream{{Name: "users"}}}
+	require.NoError(t, cc.put("expired", "source-postgres", catalog))
+	require.NoError(t, cc.put("unused_expired", "source-github", catalog))
+	expiredAt := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(cc.path("expired"), expiredAt, expiredAt))
+	require.NoError(t, os.Chtimes(cc.path("unused_expired"), expiredAt, expiredAt))
+
+	require.Nil(t, cc.get("expired"))
+	require.NoFileExists(t, cc.path("expired"), "expired catalog must be removed on get")
+
+	require.NoError(t, cc.put("actual", "source-postgres", catalog))
+	require.NoFileExists(t, cc.path("unused_expired"), "expired catalogs must be removed on put")
+	require.Equal(t, catalog, cc.get("actual"))
+}
+
+func TestCatalogCacheEvict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte_catalog_cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cc := newCatalogCache(dir, time.Hour)
+	catalog := &CatalogRow{Streams: []*Stream{{Name: "users"}}}
+	require.NoError(t, cc.put("github_1", "airbyte/source-github", catalog))
+	require.NoError(t, cc.put("github_2", "source-github", catalog))
+	require.NoError(t, cc.put("postgres", "source-postgres", catalog))
+
+	require.Equal(t, 2, cc.evict("airbyte/source-github"))
+	require.Nil(t, cc.get("github_1"))
+	require.Nil(t, cc.get("github_2"))
+	require.Equal(t, catalog, cc.get("postgres"), "other images mustn't be evicted")
+	require.Equal(t, 0, cc.evict("source-github"))
+}
@@ -0,0 +1,59 @@
+package airbyte
+
+import (
+	"sync"
+	"time"
+)
+
+//checkCache keeps the time of successful connection tests by the configuration hash
+//so rapid repeated identical tests (e.g. test-on-keystroke in UI) don't launch new containers.
+//Failed tests aren't cached. Caching is disabled if ttl isn't positive
+type checkCache struct {
+	mutex     *sync.Mutex
+	ttl       time.Duration
+	succeeded map[string]time.Time
+}
+
+func newCheckCache(ttl time.Duration) *checkCache {
+	return &checkCache{mutex: &sync.Mutex{}, ttl: ttl, succeeded: map[string]time.Time{}}
+}
+
+//isSucceeded returns true if the test with the hash has succeeded less than ttl ago
+func (cc *checkCache) isSucceeded(hash string) bool {
+	if cc.ttl <= 0 {
+		return false
+	}
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	succeededAt, ok := cc.succeeded[hash]
+	if !ok {
+		return false
+	}
+
+	if time.Since(succeededAt) >= cc.ttl {
+		delete(cc.succeeded, hash)
+		return false
+	}
+
+	return true
+}
+
+//succeed saves successful test result and removes expired ones
+func (cc *checkCache) succeed(hash string) {
+	if cc.ttl <= 0 {
+		return
+	}
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	now := time.Now()
+	for key, succeededAt := range cc.succeeded {
+		if now.Sub(succeededAt) >= cc.ttl {
+			delete(cc.succeeded, key)
+		}
+	}
+	cc.succeeded[hash] = now
+}
@@ -0,0 +1,46 @@
+package airbyte
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCache(t *testing.T) {
+	tests := []struct {
+		name              string
+		ttl               time.Duration
+		expectedSucceeded bool
+	}{
+		{"enabled", time.Minute, true},
+		{"disabled", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bridge{checks: newCheckCache(tt.ttl)}
+			require.False(t, b.IsCheckSucceeded("hash1"))
+
+			b.CheckSucceeded("hash1")
+			require.Equal(t, tt.expectedSucceeded, b.IsCheckSucceeded("hash1"))
+			require.False(t, b.IsCheckSucceeded("hash2"))
+		})
+	}
+}
+
+func TestCheckCacheExpiration(t *testing.T) {
+	cache := newCheckCache(50 * time.Millisecond)
+	cache.succeed("hash")
+	require.True(t, cache.isSucceeded("hash"))
+
+	time.Sleep(60 * time.Millisecond)
+	require.False(t, cache.isSucceeded("hash"))
+	require.Empty(t, cache.succeeded)
+
+	//expired results are removed on saving new ones
+	cache.succeed("hash1")
+	time.Sleep(60 * time.Millisecond)
+	cache.succeed("hash2")
+	require.Len(t, cache.succeeded, 1)
+	require.True(t, cache.isSucceeded("hash2"))
+}
@@ -0,0 +1,168 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//configDirs tracks per source config directories (config, catalog and state files) which are used by drivers and running syncs
+//directories are removed only after the last user releases them: active syncs aren't affected by the source removal or recreation
+type configDirs struct {
+	mutex *sync.Mutex
+	root  string
+	refs  map[string]int
+	//removing is a set of directories which are removed after the last release
+	removing map[string]bool
+}
+
+func newConfigDirs(root string) *configDirs {
+	return &configDirs{
+		mutex:    &sync.Mutex{},
+		root:     path.Clean(root),
+		refs:     map[string]int{},
+		removing: map[string]bool{},
+	}
+}
+
+//acquire marks the directory as used. The scheduled removal of the directory and its parents is canceled (e.g. the source has been recreated)
+func (cd *configDirs) acquire(dir string) {
+	dir = path.Clean(dir)
+
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	cd.refs[dir]++
+	for removing := range cd.removing {
+		if isSubdir(dir, removing) {
+			delete(cd.removing, removing)
+		}
+	}
+}
+
+//release marks the directory as unused by one user and removes it if the removal has been scheduled and there are no other users
+func (cd *configDirs) release(dir string) {
+	dir = path.Clean(dir)
+
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	cd.refs[dir]--
+	if cd.refs[dir] > 0 {
+		return
+	}
+
+	delete(cd.refs, dir)
+	for removing := range cd.removing {
+		if isSubdir(dir, removing) && !cd.inUse(removing) {
+			delete(cd.removing, removing)
+			cd.removeDir(removing)
+		}
+	}
+}
+
+//remove removes the directory right away if it isn't used otherwise the directory is removed after the last release
+func (cd *configDirs) remove(dir string) {
+	dir = path.Clean(dir)
+
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	if cd.inUse(dir) {
+		cd.removing[dir] = true
+		return
+	}
+
+	cd.removeDir(dir)
+}
+
+//prune removes directories which were left by previous runs (modified before 'before'):
+//1. directories of sources which don't exist (sourceIDs) and generated configs of check/discover commands (except cached catalogs)
+//2. directories of docker images which aren't used by existing sources anymore
+//returns removed directories. Used directories are never removed
+func (cd *configDirs) prune(sourceIDs map[string]bool, before time.Time) []string {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	var removed []string
+	//source dirs are in use while any of their image dirs is used so they are walked regardless of staleSubdirs(cd.root)
+	for sourceID := range sourceIDs {
+		for _, imageDir := range cd.staleSubdirs(path.Join(cd.root, sourceID), before) {
+			cd.removeDir(imageDir)
+			removed = append(removed, imageDir)
+		}
+	}
+
+	for _, dir := range cd.staleSubdirs(cd.root, before) {
+		//cached catalogs expire on their own
+		if path.Base(dir) == catalogsDirName || sourceIDs[path.Base(dir)] {
+			continue
+		}
+
+		cd.removeDir(dir)
+		removed = append(removed, dir)
+	}
+
+	return removed
+}
+
+//staleSubdirs returns subdirectories of the dir which aren't used and have been modified before 'before'
+//must be called under the lock
+func (cd *configDirs) staleSubdirs(dir string, before time.Time) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warnf("Error reading airbyte config dir [%s]: %v", dir, err)
+		}
+		return nil
+	}
+
+	var result []string
+	for _, entry := range entries {
+		subdir := path.Join(dir, entry.Name())
+		if !entry.IsDir() || !entry.ModTime().Before(before) || cd.inUse(subdir) {
+			continue
+		}
+		result = append(result, subdir)
+	}
+
+	return result
+}
+
+//inUse returns true if the directory or any nested directory is used
+//must be called under the lock
+func (cd *configDirs) inUse(dir string) bool {
+	for used := range cd.refs {
+		if isSubdir(used, dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//isSubdir returns true if the dir is the parent dir or nested into it
+func isSubdir(dir, parent string) bool {
+	return dir == parent || strings.HasPrefix(dir, parent+"/")
+}
+
+//removeDir removes the directory and its empty parents up to the root
+//must be called under the lock
+func (cd *configDirs) removeDir(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		logging.SystemErrorf("Error removing airbyte config dir [%s]: %v", dir, err)
+		return
+	}
+
+	for parent := path.Dir(dir); parent != cd.root && strings.HasPrefix(parent, cd.root+"/") && !cd.inUse(parent); parent = path.Dir(parent) {
+		//fails if the parent isn't empty
+		if err := os.Remove(parent); err != nil {
+			return
+		}
+	}
+}
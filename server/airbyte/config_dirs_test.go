@@ -0,0 +1,117 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigDirsRemoveAfterRelease(t *testing.T) {
+	root, err := ioutil.TempDir("", "airbyte_config_dirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	cd := newConfigDirs(root)
+	dir := path.Join(root, "source_1", "source-github")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	//driver and running sync
+	cd.acquire(dir)
+	cd.acquire(dir)
+
+	//the source is removed: the dir is kept until the sync finishes
+	cd.release(dir)
+	cd.remove(dir)
+	require.DirExists(t, dir)
+
+	cd.release(dir)
+	require.NoDirExists(t, dir)
+	require.NoDirExists(t, path.Join(root, "source_1"), "empty source dir must be removed")
+	require.DirExists(t, root)
+}
+
+func TestConfigDirsRecreationCancelsRemoval(t *testing.T) {
+	root, err := ioutil.TempDir("", "airbyte_config_dirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	cd := newConfigDirs(root)
+	dir := path.Join(root, "source_1", "source-github")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	//old driver with running sync is closed and removed
+	cd.acquire(dir)
+	cd.acquire(dir)
+	cd.release(dir)
+	cd.remove(dir)
+
+	//new driver uses the same dir
+	cd.acquire(dir)
+	cd.release(dir)
+	require.DirExists(t, dir)
+}
+
+func TestConfigDirsRemoveParentAfterNestedRelease(t *testing.T) {
+	root, err := ioutil.TempDir("", "airbyte_config_dirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	cd := newConfigDirs(root)
+	dir := path.Join(root, "source_1", "custom_org", "source-x")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	//running sync of the previous docker image
+	cd.acquire(dir)
+	cd.remove(path.Join(root, "source_1", "custom_org"))
+	require.DirExists(t, dir)
+
+	cd.release(dir)
+	require.NoDirExists(t, path.Join(root, "source_1"))
+}
+
+func TestConfigDirsPrune(t *testing.T) {
+	root, err := ioutil.TempDir("", "airbyte_config_dirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	dirs := []string{
+		path.Join(root, "existing", "source-github"),
+		path.Join(root, "existing", "source-old-image"),
+		path.Join(root, "existing", "custom", "source-x"),
+		path.Join(root, "removed", "source-github"),
+		path.Join(root, "generatedconfig"),
+		path.Join(root, "removed_but_syncing", "source-github"),
+		path.Join(root, catalogsDirName),
+	}
+	for _, dir := range dirs {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+	}
+
+	cd := newConfigDirs(root)
+	cd.acquire(path.Join(root, "existing", "source-github"))
+	cd.acquire(path.Join(root, "existing", "custom", "source-x"))
+	cd.acquire(path.Join(root, "removed_but_syncing", "source-github"))
+
+	removed := cd.prune(map[string]bool{"existing": true}, time.Now().Add(time.Minute))
+	require.ElementsMatch(t, []string{
+		path.Join(root, "existing", "source-old-image"),
+		path.Join(root, "removed"),
+		path.Join(root, "generatedconfig"),
+	}, removed)
+
+	require.DirExists(t, path.Join(root, "existing", "source-github"))
+	require.DirExists(t, path.Join(root, "existing", "custom", "source-x"))
+	require.DirExists(t, path.Join(root, "removed_but_syncing", "source-github"))
+	require.DirExists(t, path.Join(root, catalogsDirName), "cached catalogs mustn't be pruned")
+	require.NoDirExists(t, path.Join(root, "existing", "source-old-image"))
+	require.NoDirExists(t, path.Join(root, "removed"))
+	require.NoDirExists(t, path.Join(root, "generatedconfig"))
+
+	//dirs which are created after the start aren't pruned (e.g. generated configs of running check commands)
+	require.NoError(t, os.MkdirAll(path.Join(root, "new"), 0755))
+	require.Empty(t, cd.prune(map[string]bool{}, time.Now().Add(-time.Minute)))
+}
@@ -0,0 +1,83 @@
+package airbyte
+
+import (
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/metrics"
+)
+
+//containersLimiter limits the number of concurrently running Airbyte containers across all commands (spec, check, discover, read)
+//and exposes the number of running containers as a metric
+type containersLimiter struct {
+	//slots is nil if the number of containers isn't limited
+	slots chan struct{}
+
+	mutex   *sync.Mutex
+	running map[string]int64
+}
+
+//newContainersLimiter returns containersLimiter. maxConcurrent <= 0 means unlimited
+func newContainersLimiter(maxConcurrent int) *containersLimiter {
+	cl := &containersLimiter{
+		mutex:   &sync.Mutex{},
+		running: map[string]int64{},
+	}
+	if maxConcurrent > 0 {
+		cl.slots = make(chan struct{}, maxConcurrent)
+	}
+
+	return cl
+}
+
+//acquire takes a container slot for the command. If all slots are taken:
+//  wait = false - returns false immediately
+//  wait = true - waits for a free slot and returns false if cancel has been closed while waiting
+func (cl *containersLimiter) acquire(command string, wait bool, cancel <-chan struct{}) bool {
+	if cl.slots != nil {
+		if wait {
+			select {
+			case cl.slots <- struct{}{}:
+			case <-cancel:
+				return false
+			}
+		} else {
+			select {
+			case cl.slots <- struct{}{}:
+			default:
+				return false
+			}
+		}
+	}
+
+	cl.mutex.Lock()
+	cl.running[command]++
+	metrics.RunningAirbyteContainers(command, cl.running[command])
+	cl.mutex.Unlock()
+
+	return true
+}
+
+//release frees the container slot of the command
+func (cl *containersLimiter) release(command string) {
+	cl.mutex.Lock()
+	cl.running[command]--
+	metrics.RunningAirbyteContainers(command, cl.running[command])
+	cl.mutex.Unlock()
+
+	if cl.slots != nil {
+		<-cl.slots
+	}
+}
+
+//runningCount returns the number of running containers of all commands
+func (cl *containersLimiter) runningCount() int64 {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	var count int64
+	for _, running := range cl.running {
+		count += running
+	}
+
+	return count
+}
@@ -0,0 +1,55 @@
+package airbyte
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainersLimiter(t *testing.T) {
+	limiter := newContainersLimiter(2)
+	cancel := make(chan struct{})
+
+	require.True(t, limiter.acquire("read", true, cancel))
+	require.True(t, limiter.acquire("discover", false, cancel))
+	require.Equal(t, int64(2), limiter.runningCount())
+
+	//spec/check/discover don't wait for a free slot
+	require.False(t, limiter.acquire("spec", false, cancel))
+
+	//read waits for a free slot
+	acquired := make(chan bool)
+	go func() {
+		acquired <- limiter.acquire("read", true, cancel)
+	}()
+	select {
+	case <-acquired:
+		require.Fail(t, "read must wait for a free slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	limiter.release("discover")
+	require.True(t, <-acquired)
+	require.Equal(t, int64(2), limiter.runningCount())
+
+	//waiting is stopped when the runner is closed
+	go func() {
+		acquired <- limiter.acquire("read", true, cancel)
+	}()
+	close(cancel)
+	require.False(t, <-acquired)
+	require.Equal(t, int64(2), limiter.runningCount())
+
+	limiter.release("read")
+	limiter.release("read")
+	require.Equal(t, int64(0), limiter.runningCount())
+}
+
+func TestContainersLimiterUnlimited(t *testing.T) {
+	limiter := newContainersLimiter(0)
+	for i := 0; i < 100; i++ {
+		require.True(t, limiter.acquire("spec", false, nil))
+	}
+	require.Equal(t, int64(100), limiter.runningCount())
+}
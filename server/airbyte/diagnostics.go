@@ -0,0 +1,85 @@
+package airbyte
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/hashicorp/go-multierror"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+const (
+	diagnoseTimeout = 10 * time.Second
+	//diagnoseMinFreeDiskBytes is a min free disk space in ConfigDir (configs, catalogs and state files are written there)
+	diagnoseMinFreeDiskBytes = 512 * 1024 * 1024
+)
+
+//Diagnose checks that the bridge is able to run sources: docker daemon is reachable,
+//ConfigDir is writable and has enough free disk space. Returns error with all failed checks
+func (b *Bridge) Diagnose() error {
+	var multiErr error
+	if err := b.diagnoseDocker(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+	if err := b.diagnoseConfigDir(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+	if err := b.diagnoseDisk(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+
+	return multiErr
+}
+
+//diagnoseDocker pings docker daemon
+func (b *Bridge) diagnoseDocker() error {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf("error creating docker client: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnoseTimeout)
+	defer cancel()
+
+	if _, err := cli.Ping(ctx); err != nil {
+		return fmt.Errorf("docker daemon isn't reachable: %v", err)
+	}
+
+	return nil
+}
+
+//diagnoseConfigDir writes and removes a temporary file in ConfigDir
+func (b *Bridge) diagnoseConfigDir() error {
+	file, err := ioutil.TempFile(b.ConfigDir, "diagnose-*")
+	if err != nil {
+		return fmt.Errorf("config dir [%s] isn't writable: %v", b.ConfigDir, err)
+	}
+
+	_, writeErr := file.Write([]byte("{}"))
+	file.Close()
+	os.Remove(file.Name())
+	if writeErr != nil {
+		return fmt.Errorf("error writing into config dir [%s]: %v", b.ConfigDir, writeErr)
+	}
+
+	return nil
+}
+
+//diagnoseDisk checks free disk space of ConfigDir
+func (b *Bridge) diagnoseDisk() error {
+	usage, err := disk.Usage(b.ConfigDir)
+	if err != nil {
+		return fmt.Errorf("error getting config dir [%s] disk usage: %v", b.ConfigDir, err)
+	}
+
+	if usage.Free < diagnoseMinFreeDiskBytes {
+		return fmt.Errorf("config dir [%s] disk has only %d MB free space (min %d MB)", b.ConfigDir, usage.Free/1024/1024, diagnoseMinFreeDiskBytes/1024/1024)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnoseConfigDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte-diagnose")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name          string
+		configDir     string
+		expectedError string
+	}{
+		{
+			"Writable config dir",
+			dir,
+			"",
+		},
+		{
+			"Not existing config dir",
+			path.Join(dir, "not_existing"),
+			"config dir [" + path.Join(dir, "not_existing") + "] isn't writable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bridge{ConfigDir: tt.configDir}
+
+			err := b.diagnoseConfigDir()
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+				files, err := ioutil.ReadDir(tt.configDir)
+				require.NoError(t, err)
+				require.Empty(t, files, "temporary file must be removed")
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestDiagnoseDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte-diagnose")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name          string
+		configDir     string
+		expectedError string
+	}{
+		{
+			"Existing config dir",
+			dir,
+			"",
+		},
+		{
+			"Not existing config dir",
+			path.Join(dir, "not_existing"),
+			"error getting config dir [" + path.Join(dir, "not_existing") + "] disk usage",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bridge{ConfigDir: tt.configDir}
+
+			err := b.diagnoseDisk()
+			if tt.expectedError == "" {
+				//CI machines might have less free space than diagnoseMinFreeDiskBytes
+				if err != nil {
+					require.Contains(t, err.Error(), "MB free space")
+				}
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
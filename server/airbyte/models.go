@@ -9,6 +9,13 @@ const (
 	RecordType           = "RECORD"
 	CatalogType          = "CATALOG"
 	SpecType             = "SPEC"
+	ControlType          = "CONTROL"
+	TraceType            = "TRACE"
+
+	//ConnectorConfigControlType is a CONTROL message type with the updated connector config (e.g. refreshed OAuth tokens)
+	ConnectorConfigControlType = "CONNECTOR_CONFIG"
+	//ErrorTraceType is a TRACE message type with the structured connector failure reason
+	ErrorTraceType = "ERROR"
 )
 
 //Row is a dto for airbyte output row representation
@@ -20,6 +27,8 @@ type Row struct {
 	Record           *RecordRow             `json:"record,omitempty"`
 	Catalog          *CatalogRow            `json:"catalog,omitempty"`
 	Spec             map[string]interface{} `json:"spec,omitempty"`
+	Control          *ControlRow            `json:"control,omitempty"`
+	Trace            *TraceRow              `json:"trace,omitempty"`
 }
 
 //LogRow is a dto for airbyte logs serialization
@@ -39,6 +48,34 @@ type StateRow struct {
 	Data map[string]interface{} `json:"data,omitempty"`
 }
 
+//ControlRow is a dto for airbyte control message serialization
+type ControlRow struct {
+	Type            string              `json:"type,omitempty"`
+	EmittedAt       float64             `json:"emitted_at,omitempty"`
+	ConnectorConfig *ConnectorConfigRow `json:"connectorConfig,omitempty"`
+}
+
+//ConnectorConfigRow is a dto for airbyte updated connector config serialization
+type ConnectorConfigRow struct {
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+//TraceRow is a dto for airbyte trace message serialization
+type TraceRow struct {
+	Type      string         `json:"type,omitempty"`
+	EmittedAt float64        `json:"emitted_at,omitempty"`
+	Error     *TraceErrorRow `json:"error,omitempty"`
+}
+
+//TraceErrorRow is a dto for airbyte error trace serialization
+type TraceErrorRow struct {
+	Message         string `json:"message,omitempty"`
+	InternalMessage string `json:"internal_message,omitempty"`
+	StackTrace      string `json:"stack_trace,omitempty"`
+	//FailureType is system_error or config_error (e.g. invalid credentials)
+	FailureType string `json:"failure_type,omitempty"`
+}
+
 //RecordRow is a dto for airbyte record serialization
 type RecordRow struct {
 	Stream string                 `json:"stream,omitempty"`
@@ -0,0 +1,31 @@
+package airbyte
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// dockerNetworkRegex matches docker network names (including host, bridge and none)
+var dockerNetworkRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateNetwork returns err if network isn't a valid docker network name
+func ValidateNetwork(network string) error {
+	if network == "" {
+		return nil
+	}
+
+	if !dockerNetworkRegex.MatchString(network) {
+		return fmt.Errorf("docker network name [%s] must start with a letter or a digit and contain only letters, digits, '_', '.' and '-'", network)
+	}
+
+	return nil
+}
+
+// networkArgs returns docker run '--network' flag or nothing if network isn't set (default bridge network)
+func networkArgs(network string) []string {
+	if network == "" {
+		return nil
+	}
+
+	return []string{"--network", network}
+}
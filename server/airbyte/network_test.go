@@ -0,0 +1,63 @@
+package airbyte
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateNetwork(t *testing.T) {
+	tests := []struct {
+		name        string
+		network     string
+		expectedErr string
+	}{
+		{
+			"default network",
+			"",
+			"",
+		},
+		{
+			"host network",
+			"host",
+			"",
+		},
+		{
+			"named network",
+			"internal_db-net.1",
+			"",
+		},
+		{
+			"flags injection",
+			"--privileged",
+			"docker network name [--privileged] must start with a letter or a digit and contain only letters, digits, '_', '.' and '-'",
+		},
+		{
+			"spaces",
+			"my net",
+			"docker network name [my net] must start with a letter or a digit and contain only letters, digits, '_', '.' and '-'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNetwork(tt.network)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDockerRunArgsNetwork(t *testing.T) {
+	Instance = &Bridge{WorkspaceVolume: "jitsu_workspace"}
+	defer func() { Instance = nil }()
+
+	args := NewRunner("source-postgres", "latest", "container", nil, "internal_db").dockerRunArgs("container", true)
+	require.Equal(t, []string{"run", "--rm", "--init", "-i", "--name", "container", "--log-driver", "none", "--network", "internal_db",
+		"-v", "jitsu_workspace:/tmp/airbyte/", "airbyte/source-postgres:latest"}, args)
+
+	args = NewRunner("source-postgres", "latest", "container", nil, "").dockerRunArgs("container", false)
+	require.NotContains(t, args, "--network")
+}
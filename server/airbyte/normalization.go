@@ -0,0 +1,79 @@
+package airbyte
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	//NormalizationRaw stores records as is: arrays are written as JSON values (default)
+	NormalizationRaw = "raw"
+	//NormalizationBasic expands arrays of objects into child streams like Airbyte basic normalization does
+	NormalizationBasic = "basic"
+)
+
+//ValidateNormalization returns error if the normalization isn't supported
+func ValidateNormalization(normalization string) error {
+	switch normalization {
+	case "", NormalizationRaw, NormalizationBasic:
+		return nil
+	default:
+		return fmt.Errorf("must be one of [%s, %s]", NormalizationRaw, NormalizationBasic)
+	}
+}
+
+//HashIDField returns the name of the field which links records of the stream with records of its child streams
+func HashIDField(stream string) string {
+	return "_airbyte_" + stream + "_hashid"
+}
+
+//normalizeRecord moves arrays of objects from the stream record into records of child streams <stream>_<field> (recursively).
+//The record and its child records are linked by HashIDField(stream) field with the hash of the original record.
+//Nested objects aren't moved: they are flattened into the record columns as usual.
+//Child records are appended into childRecords (child stream name -> records)
+func normalizeRecord(stream string, record map[string]interface{}, childRecords map[string][]map[string]interface{}) {
+	var hashID string
+	for field, value := range record {
+		objects, ok := arrayOfObjects(value)
+		if !ok {
+			continue
+		}
+
+		//hash of the original record (before any array is moved)
+		if hashID == "" {
+			hashID = uuid.GetHash(record)
+		}
+		delete(record, field)
+
+		childStream := stream + "_" + field
+		for _, object := range objects {
+			object[HashIDField(stream)] = hashID
+			normalizeRecord(childStream, object, childRecords)
+			childRecords[childStream] = append(childRecords[childStream], object)
+		}
+	}
+
+	if hashID != "" {
+		record[HashIDField(stream)] = hashID
+	}
+}
+
+//arrayOfObjects returns objects and true if the value is a non-empty array of JSON objects
+func arrayOfObjects(value interface{}) ([]map[string]interface{}, bool) {
+	array, ok := value.([]interface{})
+	if !ok || len(array) == 0 {
+		return nil, false
+	}
+
+	objects := make([]map[string]interface{}, 0, len(array))
+	for _, element := range array {
+		object, ok := element.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, true
+}
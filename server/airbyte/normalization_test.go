@@ -0,0 +1,105 @@
+package airbyte
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateNormalization(t *testing.T) {
+	require.NoError(t, ValidateNormalization(""))
+	require.NoError(t, ValidateNormalization(NormalizationRaw))
+	require.NoError(t, ValidateNormalization(NormalizationBasic))
+	require.Error(t, ValidateNormalization("dbt"))
+}
+
+func TestNormalizeRecord(t *testing.T) {
+	record := map[string]interface{}{
+		"id":      1.0,
+		"tags":    []interface{}{"a", "b"},
+		"address": map[string]interface{}{"city": "Berlin"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "s1", "discounts": []interface{}{map[string]interface{}{"code": "d1"}}},
+			map[string]interface{}{"sku": "s2"},
+		},
+	}
+
+	childRecords := map[string][]map[string]interface{}{}
+	normalizeRecord("orders", record, childRecords)
+
+	hashID, ok := record["_airbyte_orders_hashid"]
+	require.True(t, ok)
+	require.NotEmpty(t, hashID)
+	require.NotContains(t, record, "items")
+	//arrays of scalars and nested objects aren't moved
+	require.Equal(t, []interface{}{"a", "b"}, record["tags"])
+	require.Equal(t, map[string]interface{}{"city": "Berlin"}, record["address"])
+
+	items := childRecords["orders_items"]
+	require.Len(t, items, 2)
+	require.Equal(t, hashID, items[0]["_airbyte_orders_hashid"])
+	require.Equal(t, hashID, items[1]["_airbyte_orders_hashid"])
+	require.NotContains(t, items[0], "discounts")
+	require.NotContains(t, items[1], "_airbyte_orders_items_hashid")
+
+	discounts := childRecords["orders_items_discounts"]
+	require.Len(t, discounts, 1)
+	require.Equal(t, "d1", discounts[0]["code"])
+	require.Equal(t, items[0]["_airbyte_orders_items_hashid"], discounts[0]["_airbyte_orders_items_hashid"])
+}
+
+func TestNormalizeRecordWithoutArrays(t *testing.T) {
+	record := map[string]interface{}{"id": 1.0, "empty": []interface{}{}}
+	childRecords := map[string][]map[string]interface{}{}
+	normalizeRecord("orders", record, childRecords)
+
+	require.Equal(t, map[string]interface{}{"id": 1.0, "empty": []interface{}{}}, record)
+	require.Empty(t, childRecords)
+}
+
+type streamsDataConsumer struct {
+	streams map[string]*base.StreamRepresentation
+}
+
+func (sdc *streamsDataConsumer) Consume(representation *base.CLIOutputRepresentation) error {
+	for name, stream := range representation.Streams {
+		sdc.streams[name] = &base.StreamRepresentation{
+			Objects:      append([]map[string]interface{}{}, stream.Objects...),
+			NeedClean:    stream.NeedClean,
+			ParentStream: stream.ParentStream,
+		}
+	}
+	return nil
+}
+
+func TestParseBasicNormalization(t *testing.T) {
+	Instance = &Bridge{batchSize: 10}
+	defer func() { Instance = nil }()
+
+	stdout := strings.Join([]string{
+		`{"type":"RECORD","record":{"stream":"orders","data":{"id":1,"items":[{"sku":"s1"},{"sku":"s2"}]}}}`,
+		`{"type":"RECORD","record":{"stream":"orders","data":{"id":2,"items":[]}}}`,
+	}, "\n")
+
+	consumer := &streamsDataConsumer{streams: map[string]*base.StreamRepresentation{}}
+	parser := &asynchronousParser{
+		dataConsumer: consumer,
+		streamsRepresentation: map[string]*base.StreamRepresentation{
+			"orders": {BatchHeader: &schema.BatchHeader{TableName: "orders", Fields: schema.Fields{}}, NeedClean: true},
+		},
+		logger:        &testTaskLogger{},
+		normalization: NormalizationBasic,
+	}
+	require.NoError(t, parser.parse(strings.NewReader(stdout)))
+
+	require.Len(t, consumer.streams["orders"].Objects, 2)
+	items := consumer.streams["orders_items"]
+	require.NotNil(t, items)
+	require.Len(t, items.Objects, 2)
+	require.Equal(t, "orders", items.ParentStream)
+	require.True(t, items.NeedClean)
+	require.Equal(t, consumer.streams["orders"].Objects[0]["_airbyte_orders_hashid"], items.Objects[0]["_airbyte_orders_hashid"])
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jitsucom/jitsu/server/runner"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/uuid"
+	"go.uber.org/atomic"
 	"io"
 	"os"
 	"os/exec"
@@ -33,13 +34,17 @@ type Runner struct {
 	Version     string
 
 	identifier string
+	env        map[string]string
+	network    string
 	closed     chan struct{}
+	timedOut   *atomic.Bool
 
 	command *exec.Cmd
 }
 
 //NewRunner returns configured Airbyte Runner
-func NewRunner(dockerImage, imageVersion, identifier string) *Runner {
+//env is passed into docker container as '-e' flags, network (if set) as '--network' flag
+func NewRunner(dockerImage, imageVersion, identifier string, env map[string]string, network string) *Runner {
 	if identifier == "" {
 		identifier = fmt.Sprintf("%s-%s-%s", dockerImage, imageVersion, uuid.New())
 	}
@@ -47,17 +52,32 @@ func NewRunner(dockerImage, imageVersion, identifier string) *Runner {
 		DockerImage: dockerImage,
 		Version:     imageVersion,
 		identifier:  identifier,
+		env:         env,
+		network:     network,
 		closed:      make(chan struct{}),
+		timedOut:    atomic.NewBool(false),
 	}
 }
 
-//String returns exec command string
+//String returns exec command string with masked secret env variables
 func (r *Runner) String() string {
 	if r.command == nil {
 		return ""
 	}
 
-	return r.command.String()
+	return strings.Join(append([]string{r.command.Path}, maskArgs(r.command.Args[1:])...), " ")
+}
+
+//dockerRunArgs returns docker run args with docker network, mounted workspace volume (if mountWorkspace is true), env variables and versioned image
+func (r *Runner) dockerRunArgs(containerName string, mountWorkspace bool) []string {
+	args := []string{"run", "--rm", "--init", "-i", "--name", containerName, "--log-driver", "none"}
+	args = append(args, networkArgs(r.network)...)
+	if mountWorkspace {
+		args = append(args, "-v", fmt.Sprintf("%s:%s", Instance.WorkspaceVolume, VolumeAlias))
+	}
+	args = append(args, envArgs(r.env)...)
+
+	return append(args, fmt.Sprintf("%s:%s", Instance.AddAirbytePrefix(r.DockerImage), r.Version))
 }
 
 //Spec runs airbyte docker spec command and returns spec and err if occurred
@@ -65,7 +85,7 @@ func (r *Runner) Spec() (interface{}, error) {
 	resultParser := &synchronousParser{desiredRowType: SpecType}
 	errWriter := logging.NewStringWriter()
 
-	err := r.run(resultParser.parse, copyTo(errWriter), time.Minute*3, "run", "--rm", "--init", "-i", "--name", r.identifier, "--log-driver", "none", fmt.Sprintf("%s:%s", Instance.AddAirbytePrefix(r.DockerImage), r.Version), "spec")
+	err := r.run("spec", resultParser.parse, copyTo(errWriter), time.Minute*3, append(r.dockerRunArgs(r.identifier, false), "spec")...)
 	if err != nil {
 		if err == runner.ErrNotReady {
 			return nil, err
@@ -93,8 +113,8 @@ func (r *Runner) Check(airbyteSourceConfig interface{}) error {
 		}
 	}()
 
-	err = r.run(resultParser.parse, copyTo(errWriter), time.Minute*3,
-		"run", "--rm", "--init", "-i", "--name", r.identifier, "--log-driver", "none", "-v", fmt.Sprintf("%s:%s", Instance.WorkspaceVolume, VolumeAlias), fmt.Sprintf("%s:%s", Instance.AddAirbytePrefix(r.DockerImage), r.Version), "check", "--config", path.Join(VolumeAlias, relatedFilePath))
+	err = r.run("check", resultParser.parse, copyTo(errWriter), time.Minute*3,
+		append(r.dockerRunArgs(r.identifier, true), "check", "--config", path.Join(VolumeAlias, relatedFilePath))...)
 	if err != nil {
 		if err == runner.ErrNotReady {
 			return err
@@ -131,12 +151,15 @@ func (r *Runner) Discover(airbyteSourceConfig interface{}, timeout time.Duration
 		}
 	}()
 
-	err = r.run(resultParser.parse, copyTo(dualStdErrWriter), timeout,
-		"run", "--rm", "--init", "-i", "--name", r.identifier, "--log-driver", "none", "-v", fmt.Sprintf("%s:%s", Instance.WorkspaceVolume, VolumeAlias), fmt.Sprintf("%s:%s", Instance.AddAirbytePrefix(r.DockerImage), r.Version), "discover", "--config", path.Join(VolumeAlias, relatedFilePath))
+	err = r.run("discover", resultParser.parse, copyTo(dualStdErrWriter), timeout,
+		append(r.dockerRunArgs(r.identifier, true), "discover", "--config", path.Join(VolumeAlias, relatedFilePath))...)
 	if err != nil {
 		if err == runner.ErrNotReady {
 			return nil, err
 		}
+		if r.timedOut.Load() {
+			return nil, runner.ErrTimeout
+		}
 
 		errMsg := Instance.BuildMsg("Error loading airbyte catalog:", resultParser.output, errStrWriter, err)
 		logging.Error(errMsg)
@@ -146,11 +169,20 @@ func (r *Runner) Discover(airbyteSourceConfig interface{}, timeout time.Duration
 	return resultParser.parsedRow.Catalog, nil
 }
 
-func (r *Runner) Read(dataConsumer base.CLIDataConsumer, streamsRepresentation map[string]*base.StreamRepresentation, taskLogger logging.TaskLogger, taskCloser base.CLITaskCloser, sourceID, statePath string) error {
+//Read runs airbyte read command and passes data to dataConsumer
+//configDir is a directory (relative to Instance.ConfigDir) with config, catalog and state files
+//configListener is notified when the connector updates its config with CONTROL message (the config file is rewritten)
+func (r *Runner) Read(dataConsumer base.CLIDataConsumer, streamsRepresentation map[string]*base.StreamRepresentation, taskLogger logging.TaskLogger, taskCloser base.CLITaskCloser, sourceID, configDir, statePath, normalization string,
+	configListener func(config map[string]interface{})) error {
 	asyncParser := &asynchronousParser{
 		dataConsumer:          dataConsumer,
 		streamsRepresentation: streamsRepresentation,
 		logger:                taskLogger,
+		configPath:            path.Join(Instance.ConfigDir, configDir, base.ConfigFileName),
+		configListener:        configListener,
+		sourceID:              sourceID,
+		sourceTap:             r.DockerImage,
+		normalization:         normalization,
 	}
 
 	stdoutHandler := func(stdout io.Reader) error {
@@ -182,14 +214,16 @@ func (r *Runner) Read(dataConsumer base.CLIDataConsumer, streamsRepresentation m
 
 	dualStdErrWriter := logging.Dual{FileWriter: taskLogger, Stdout: logging.NewPrefixDateTimeProxy(fmt.Sprintf("[%s]", sourceID), Instance.LogWriter)}
 
-	args := []string{"run", "--rm", "--init", "-i", "--name", taskCloser.TaskID(), "--log-driver", "none", "-v", fmt.Sprintf("%s:%s", Instance.WorkspaceVolume, VolumeAlias), fmt.Sprintf("%s:%s", Instance.AddAirbytePrefix(r.DockerImage), r.Version), "read", "--config", path.Join(VolumeAlias, sourceID, r.DockerImage, base.ConfigFileName), "--catalog", path.Join(VolumeAlias, sourceID, r.DockerImage, base.CatalogFileName)}
+	args := append(r.dockerRunArgs(taskCloser.TaskID(), true), "read", "--config", path.Join(VolumeAlias, configDir, base.ConfigFileName), "--catalog", path.Join(VolumeAlias, configDir, base.CatalogFileName))
 
 	if statePath != "" {
-		args = append(args, "--state", path.Join(VolumeAlias, sourceID, r.DockerImage, base.StateFileName))
+		args = append(args, "--state", path.Join(VolumeAlias, configDir, base.StateFileName))
 	}
 
-	taskLogger.INFO("ID [%s] exec: %s %s", r.identifier, DockerCommand, strings.Join(args, " "))
-	return r.run(stdoutHandler, copyTo(dualStdErrWriter), time.Hour*24, args...)
+	taskLogger.INFO("ID [%s] exec: %s %s", r.identifier, DockerCommand, strings.Join(maskArgs(args), " "))
+	err := r.run("read", stdoutHandler, copyTo(dualStdErrWriter), time.Hour*24, args...)
+	//the connector failure reason instead of the generic exit code error
+	return withTraceError(asyncParser.traceError, err)
 }
 
 func (r *Runner) Close() error {
@@ -204,7 +238,7 @@ func (r *Runner) Close() error {
 			return
 		case <-ticker.C:
 			logging.Errorf("%s airbyte runner closing timeout. Killing.", r.identifier)
-			_ = r.command.Process.Kill()
+			r.kill()
 		}
 		ticker.Stop()
 	})
@@ -213,12 +247,19 @@ func (r *Runner) Close() error {
 	close(r.closed)
 	if err != nil {
 		logging.Errorf("%s airbyte runner closing failed. Killing. Closing error: %v", r.identifier, err)
-		_ = r.command.Process.Kill()
+		r.kill()
 		return err
 	}
 	return nil
 }
 
+//kill kills the process if it has been started (the runner might be closed while waiting for a container slot)
+func (r *Runner) kill() {
+	if r.command != nil && r.command.Process != nil {
+		_ = r.command.Process.Kill()
+	}
+}
+
 func (r *Runner) terminated() bool {
 	select {
 	case <-r.closed:
@@ -228,7 +269,9 @@ func (r *Runner) terminated() bool {
 	}
 }
 
-func (r *Runner) run(stdoutHandler, stderrHandler func(io.Reader) error, timeout time.Duration, args ...string) error {
+//run launches the container of the command if there is a free container slot (see airbyte-bridge.max_concurrent_containers):
+//read waits for a free slot, spec/check/discover return runner.ErrNotReady (pending) immediately
+func (r *Runner) run(command string, stdoutHandler, stderrHandler func(io.Reader) error, timeout time.Duration, args ...string) error {
 	if r.terminated() {
 		return runner.ErrAirbyteAlreadyTerminated
 	}
@@ -237,6 +280,16 @@ func (r *Runner) run(stdoutHandler, stderrHandler func(io.Reader) error, timeout
 		return runner.ErrNotReady
 	}
 
+	if !Instance.containers.acquire(command, command == "read", r.closed) {
+		if r.terminated() {
+			return runner.ErrAirbyteAlreadyTerminated
+		}
+
+		logging.Debugf("[%s] Airbyte %s is pending: max concurrent containers limit has been reached. Running containers: %d", r.identifier, command, Instance.containers.runningCount())
+		return runner.ErrNotReady
+	}
+	defer Instance.containers.release(command)
+
 	//self closed
 	safego.Run(func() {
 		ticker := time.NewTicker(timeout)
@@ -246,6 +299,7 @@ func (r *Runner) run(stdoutHandler, stderrHandler func(io.Reader) error, timeout
 				return
 			case <-ticker.C:
 				logging.Warnf("[%s] Airbyte run timeout after [%s]", r.identifier, timeout.String())
+				r.timedOut.Store(true)
 				if err := r.Close(); err != nil {
 					if err != runner.ErrAirbyteAlreadyTerminated {
 						logging.SystemErrorf("Error terminating Airbyte runner [%s:%s] after timeout: %v", r.DockerImage, r.Version, err)
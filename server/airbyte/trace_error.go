@@ -0,0 +1,49 @@
+package airbyte
+
+import (
+	"fmt"
+	"strings"
+)
+
+//TraceError is a connector failure with the reason from the last Airbyte error TRACE message
+//e.g. 'Airbyte source: authentication failed' instead of the generic non-zero exit code error
+type TraceError struct {
+	Trace *TraceErrorRow
+	//Err is the original runner error (e.g. exit status 1)
+	Err error
+}
+
+//Error returns the connector failure reason with failure type and internal message
+func (te *TraceError) Error() string {
+	msg := "Airbyte source: " + te.Trace.Message
+	var details []string
+	if te.Trace.FailureType != "" {
+		details = append(details, "failure type: "+te.Trace.FailureType)
+	}
+	if te.Trace.InternalMessage != "" && te.Trace.InternalMessage != te.Trace.Message {
+		details = append(details, "internal message: "+te.Trace.InternalMessage)
+	}
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, ", ") + ")"
+	}
+	if te.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, te.Err)
+	}
+
+	return msg
+}
+
+//Unwrap returns the original runner error
+func (te *TraceError) Unwrap() error {
+	return te.Err
+}
+
+//withTraceError returns TraceError with the runner error if the connector has emitted an error TRACE message
+//otherwise returns err as is
+func withTraceError(trace *TraceErrorRow, err error) error {
+	if err == nil || trace == nil {
+		return err
+	}
+
+	return &TraceError{Trace: trace, Err: err}
+}
@@ -7,9 +7,12 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/authorization"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/samples"
 	"github.com/jitsucom/jitsu/server/useragent"
 	"github.com/spf13/viper"
 )
@@ -26,6 +29,10 @@ type AppConfig struct {
 	ConfiguratorToken string
 
 	DisableSkipEventsWarn bool
+	//StreamingMaxRetries is a max count of transient insert errors per event in streaming mode
+	StreamingMaxRetries int
+	//MaxEventBytes is a default max event size: bigger events are skipped by destinations (0 - unlimited)
+	MaxEventBytes int
 
 	EmptyGIFPixelOnexOne []byte
 
@@ -62,21 +69,32 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("server.auth_reload_sec", 1)
 	viper.SetDefault("server.api_keys_reload_sec", 1)
 	viper.SetDefault("server.destinations_reload_sec", 1)
+	viper.SetDefault("server.destinations_reload.allow_empty", false)
+	viper.SetDefault("server.destinations_reload.max_removed_percent", 0)
 	viper.SetDefault("server.sources_reload_sec", 1)
 	viper.SetDefault("server.geo_resolvers_reload_sec", 1)
+	viper.SetDefault("server.max_reload_backoff_sec", 60)
+	viper.SetDefault("server.reload_failures_threshold", 10)
+	viper.SetDefault("server.debug_samples.max_per_destination", 100)
+	viper.SetDefault("server.debug_samples.ttl_minutes", 60)
+	viper.SetDefault("server.streaming.max_retries", 10)
 	viper.SetDefault("server.sync_tasks.pool.size", 16)
 	viper.SetDefault("server.sync_tasks.stalled.last_heartbeat_threshold_seconds", 60)
 	viper.SetDefault("server.sync_tasks.stalled.last_activity_threshold_minutes", 10)
 	viper.SetDefault("server.sync_tasks.stalled.observe_stalled_every_seconds", 20)
 	viper.SetDefault("server.sync_tasks.store_logs.last_runs", -1)
+	viper.SetDefault("server.sync_tasks.state_save_retries", 3)
+	viper.SetDefault("server.sync_tasks.store_concurrency", 1)
 	viper.SetDefault("server.disable_version_reminder", false)
 	viper.SetDefault("server.disable_skip_events_warn", false)
 	viper.SetDefault("server.cache.enabled", true)
 	viper.SetDefault("server.cache.events.size", 100)
 	viper.SetDefault("server.cache.events.trim_interval_ms", 500)
 	viper.SetDefault("server.cache.pool.size", 10)
+	viper.SetDefault("server.cache.timeout_ms", 1000)
 	viper.SetDefault("server.strict_auth_tokens", false)
 	viper.SetDefault("server.max_columns", 100)
+	viper.SetDefault("server.max_event_bytes", 16*1024*1024)
 	viper.SetDefault("server.configurator_urn", "/configurator")
 	//unique IDs
 	viper.SetDefault("server.fields_configuration.unique_id_field", "/eventn_ctx/event_id||/eventn_ctx_event_id||/event_id")
@@ -116,6 +134,17 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("airbyte-bridge.log.rotation_min", "1440")
 	viper.SetDefault("airbyte-bridge.log.max_backups", "30") //30 days = 1440 min * 30
 	viper.SetDefault("airbyte-bridge.batch_size", 10_000)
+	viper.SetDefault("airbyte-bridge.discover_timeout_sec", 180)
+	//0 - unlimited
+	viper.SetDefault("airbyte-bridge.max_concurrent_containers", 0)
+	//successful connection tests are cached (0 - disabled)
+	viper.SetDefault("airbyte-bridge.check_cache_ttl_sec", 30)
+	//discovered catalogs are cached by the configuration hash
+	viper.SetDefault("airbyte-bridge.catalog_cache_ttl_sec", 86400)
+	//connector configs are written only into sync run directories which are removed after syncs
+	viper.SetDefault("airbyte-bridge.ephemeral_configs", false)
+	//config dirs of removed sources are removed on the startup
+	viper.SetDefault("airbyte-bridge.prune_config_dirs", true)
 
 	viper.SetDefault("server.volumes.workspace", "jitsu_workspace")
 
@@ -268,6 +297,7 @@ func Init(containerized bool, dockerHubID string) error {
 	if err != nil {
 		return err
 	}
+	logging.AddSensitiveKeys(viper.GetStringSlice("server.log.sensitive_keys"))
 
 	logWelcomeBanner(RawVersion)
 	logDeprecatedImageUsage(dockerHubID)
@@ -345,6 +375,14 @@ func Init(containerized bool, dockerHubID string) error {
 	port := viper.GetString("server.port")
 	appConfig.Authority = "0.0.0.0:" + port
 
+	//resources reloading backoff (must be configured before any resources.Watch)
+	resources.MaxReloadBackoff = time.Duration(viper.GetInt("server.max_reload_backoff_sec")) * time.Second
+	resources.FailuresThreshold = viper.GetInt("server.reload_failures_threshold")
+
+	//debug samples of processed objects (destinations with debug_sample_rate)
+	samples.Instance = samples.NewSink(viper.GetInt("server.debug_samples.max_per_destination"),
+		time.Duration(viper.GetInt("server.debug_samples.ttl_minutes"))*time.Minute)
+
 	authService, err := authorization.NewService(appConfig.ConfiguratorURL, appConfig.ConfiguratorToken)
 	if err != nil {
 		return err
@@ -358,7 +396,14 @@ func Init(containerized bool, dockerHubID string) error {
 	appConfig.AuthorizationService = authService
 	appConfig.UaResolver = useragent.NewResolver()
 	appConfig.DisableSkipEventsWarn = viper.GetBool("server.disable_skip_events_warn")
-	appConfig.GlobalUniqueIDField = identifiers.NewUniqueID(uniqueIDField)
+	appConfig.StreamingMaxRetries = viper.GetInt("server.streaming.max_retries")
+	appConfig.MaxEventBytes = viper.GetInt("server.max_event_bytes")
+	appConfig.GlobalUniqueIDField, err = identifiers.NewUniqueID(uniqueIDField).WithFallback(
+		viper.GetStringSlice("server.fields_configuration.unique_id_fallback_fields"),
+		viper.GetString("server.fields_configuration.unique_id_fallback_strategy"))
+	if err != nil {
+		return fmt.Errorf("Error configuring server.fields_configuration.unique_id_fallback_strategy: %v", err)
+	}
 
 	Instance = &appConfig
 	return nil
@@ -2,11 +2,13 @@ package appconfig
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/resources"
 	"github.com/spf13/viper"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
@@ -14,10 +16,10 @@ import (
 
 const notsetDefaultValue = "__NOTSET_DEFAULT_VALUE__"
 
-var templateVariablePattern = regexp.MustCompile(`\$\{env\.[\w_]+(?:\|[^\}]*)?\}`)
+var templateVariablePattern = regexp.MustCompile(`\$\{(?:env\.[\w_]+|file\.[^\|\}]+)(?:\|[^\}]*)?\}`)
 
 //Read reads config from configSourceStr that might be (HTTP URL or path to YAML/JSON file or plain JSON string)
-//replaces all ${env.VAR} placeholders with OS variables
+//replaces all ${env.VAR} and ${file./path} placeholders with OS variables and files content
 //configSourceStr might be overridden by "config_location" ENV variable
 //returns err if occurred
 func Read(configSourceStr string, containerizedRun bool, configNotFoundErrMsg string, appName string) error {
@@ -84,47 +86,100 @@ func Read(configSourceStr string, containerizedRun bool, configNotFoundErrMsg st
 
 func enrichWithResolvedPlaceholders(key string, value string, result map[string]interface{}) {
 	if templateVariablePattern.MatchString(value) {
-		res := templateVariablePattern.ReplaceAllStringFunc(value, func(value string) string {
-			envExpression := strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}")
-
-			var varsNotFound []string
-			//alternatives in case of ${env.VAR1|env.VAR2|default_value}
-			expressionValues := strings.Split(envExpression, "|")
-			for _, expressionValue := range expressionValues {
-				if strings.HasPrefix(expressionValue, "env.") {
-					//from env
-					envVarName := strings.TrimPrefix(expressionValue, "env.")
-					if envVarValue := os.Getenv(envVarName); envVarValue != "" {
-						return envVarValue
-					}
-
-					//not found
-					varsNotFound = append(varsNotFound, envVarName)
-				} else {
-					//constant
-					return expressionValue
-				}
-			}
-
-			//not found
-			if len(varsNotFound) == 1 {
-				logging.Fatalf("Mandatory env variable was not found: %s", varsNotFound[0])
-			} else {
-				logging.Fatalf("No one of env variables [%s] were not found. Please set any", strings.Join(varsNotFound, " or "))
-			}
-
-			return ""
-		})
+		res, err := resolvePlaceholders(value, nil)
+		if err != nil {
+			logging.Fatal(err)
+		}
 
 		//set value
 		valuePath := jsonutils.NewJSONPath(strings.ReplaceAll(key, ".", "/"))
-		err := valuePath.Set(result, res)
+		err = valuePath.Set(result, res)
 		if err != nil {
 			logging.Fatalf("Unable to set value in %s config path", key)
 		}
 	}
 }
 
+//ResolveJSONPlaceholders replaces all ${env.VAR} and ${file./path} placeholders in JSON payload
+//with OS variables and files content. Resolved values are escaped as JSON string content
+//returns err if a placeholder can't be resolved
+func ResolveJSONPlaceholders(payload []byte) ([]byte, error) {
+	if !templateVariablePattern.Match(payload) {
+		return payload, nil
+	}
+
+	res, err := resolvePlaceholders(string(payload), func(value string) string {
+		b, _ := json.Marshal(value)
+		return string(b[1 : len(b)-1])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(res), nil
+}
+
+//resolvePlaceholders replaces all placeholders in value. Alternatives are supported: ${env.VAR1|file./path|default_value}
+//escape func (if set) is applied to resolved values
+func resolvePlaceholders(value string, escape func(string) string) (string, error) {
+	var resolveErr error
+	res := templateVariablePattern.ReplaceAllStringFunc(value, func(value string) string {
+		expression := strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}")
+
+		resolved, err := resolvePlaceholderExpression(expression)
+		if err != nil {
+			if resolveErr == nil {
+				resolveErr = err
+			}
+			return ""
+		}
+
+		if escape != nil {
+			return escape(resolved)
+		}
+		return resolved
+	})
+
+	return res, resolveErr
+}
+
+func resolvePlaceholderExpression(expression string) (string, error) {
+	var varsNotFound []string
+	//alternatives in case of ${env.VAR1|env.VAR2|default_value}
+	expressionValues := strings.Split(expression, "|")
+	for _, expressionValue := range expressionValues {
+		if strings.HasPrefix(expressionValue, "env.") {
+			//from env
+			envVarName := strings.TrimPrefix(expressionValue, "env.")
+			if envVarValue := os.Getenv(envVarName); envVarValue != "" {
+				return envVarValue, nil
+			}
+
+			//not found
+			varsNotFound = append(varsNotFound, envVarName)
+		} else if strings.HasPrefix(expressionValue, "file.") {
+			//from file (e.g. docker/k8s secrets)
+			filePath := strings.TrimPrefix(expressionValue, "file.")
+			if content, err := ioutil.ReadFile(filePath); err == nil {
+				return strings.TrimRight(string(content), "\r\n"), nil
+			}
+
+			//not found
+			varsNotFound = append(varsNotFound, filePath)
+		} else {
+			//constant
+			return expressionValue, nil
+		}
+	}
+
+	//not found
+	if len(varsNotFound) == 1 {
+		return "", fmt.Errorf("Mandatory env variable or file was not found: %s", varsNotFound[0])
+	}
+
+	return "", fmt.Errorf("No one of env variables or files [%s] were not found. Please set any", strings.Join(varsNotFound, " or "))
+}
+
 //handleConfigErr returns err only if application can't start without config
 //otherwise log error and return nil
 func handleConfigErr(err error, containerizedRun bool, configNotFoundErrMsg string) error {
@@ -0,0 +1,71 @@
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveJSONPlaceholders(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_RESOLVE_PASSWORD", `pa"ss`))
+	defer os.Unsetenv("TEST_RESOLVE_PASSWORD")
+
+	dir, err := ioutil.TempDir("", "placeholders")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "secret")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("file_secret\n"), 0644))
+
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr string
+	}{
+		{
+			"without placeholders",
+			`{"password": "${$.event_type}"}`,
+			`{"password": "${$.event_type}"}`,
+			"",
+		},
+		{
+			"env variable is escaped",
+			`{"password": "${env.TEST_RESOLVE_PASSWORD}"}`,
+			`{"password": "pa\"ss"}`,
+			"",
+		},
+		{
+			"file",
+			`{"password": "${file.` + secretFile + `}"}`,
+			`{"password": "file_secret"}`,
+			"",
+		},
+		{
+			"default value",
+			`{"user": "${env.TEST_RESOLVE_UNSET|default_user}"}`,
+			`{"user": "default_user"}`,
+			"",
+		},
+		{
+			"unset variable",
+			`{"password": "${env.TEST_RESOLVE_UNSET}"}`,
+			"",
+			"Mandatory env variable or file was not found: TEST_RESOLVE_UNSET",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ResolveJSONPlaceholders([]byte(tt.input))
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(actual))
+		})
+	}
+}
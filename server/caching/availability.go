@@ -0,0 +1,126 @@
+package caching
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"go.uber.org/atomic"
+)
+
+const (
+	//unavailableFailuresThreshold is an amount of consecutive failed operations after which the storage is considered unavailable
+	unavailableFailuresThreshold = 5
+	//unavailableCooldown is a period during which all operations are skipped after the storage has become unavailable
+	unavailableCooldown = 10 * time.Second
+	//failuresLogInterval is a minimal interval between failure logs
+	failuresLogInterval = time.Minute
+)
+
+var errOperationTimeout = errors.New("operation timeout")
+
+//availability guards events cache storage operations: every operation is limited with timeout and
+//after unavailableFailuresThreshold consecutive failures all operations are skipped for unavailableCooldown
+//so Redis outage doesn't block cache workers and doesn't flood logs. Failures are logged at a throttled rate
+type availability struct {
+	timeout time.Duration
+
+	consecutiveFailures atomic.Int64
+	//unix nanoseconds, 0 - available
+	unavailableUntil atomic.Int64
+
+	mutex *sync.Mutex
+	//lastLog is the last failure log time, suppressed is an amount of failures which haven't been logged since lastLog
+	lastLog    time.Time
+	suppressed int
+}
+
+//newAvailability returns availability with operation timeout. Timeout isn't applied if it isn't positive
+func newAvailability(timeout time.Duration) *availability {
+	metrics.SetEventsCacheAvailable(true)
+	return &availability{timeout: timeout, mutex: &sync.Mutex{}}
+}
+
+//allow returns false if the storage is unavailable and operations should be skipped
+func (a *availability) allow() bool {
+	return timestamp.Now().UnixNano() >= a.unavailableUntil.Load()
+}
+
+//do runs the storage operation if the storage is available and waits for the result not longer than timeout
+//returns false if the operation has been skipped, timed out or failed
+func (a *availability) do(operation string, f func() error) bool {
+	if !a.allow() {
+		metrics.EventsCacheFailure(operation, "unavailable")
+		return false
+	}
+
+	err := a.runWithTimeout(f)
+	if err != nil {
+		a.failed(operation, err)
+		return false
+	}
+
+	a.succeeded()
+	return true
+}
+
+//runWithTimeout returns errOperationTimeout if f isn't finished in timeout.
+//f isn't interrupted: it is finished in background (e.g. on Redis read timeout)
+func (a *availability) runWithTimeout(f func() error) error {
+	if a.timeout <= 0 {
+		return f()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- f()
+	}()
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return errOperationTimeout
+	}
+}
+
+func (a *availability) succeeded() {
+	if a.consecutiveFailures.Swap(0) >= unavailableFailuresThreshold {
+		a.unavailableUntil.Store(0)
+		metrics.SetEventsCacheAvailable(true)
+		logging.Infof("[events cache] storage is available again")
+	}
+}
+
+func (a *availability) failed(operation string, err error) {
+	reason := "error"
+	if err == errOperationTimeout {
+		reason = "timeout"
+	}
+	metrics.EventsCacheFailure(operation, reason)
+
+	if a.consecutiveFailures.Inc() >= unavailableFailuresThreshold {
+		a.unavailableUntil.Store(timestamp.Now().Add(unavailableCooldown).UnixNano())
+		metrics.SetEventsCacheAvailable(false)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := timestamp.Now()
+	if now.Sub(a.lastLog) < failuresLogInterval {
+		a.suppressed++
+		return
+	}
+
+	logging.Errorf("[events cache] %s operation failed: %v. Live Events UI may show inaccurate results. Failures since the last log: %d. Consecutive failures: %d",
+		operation, err, a.suppressed+1, a.consecutiveFailures.Load())
+	a.lastLog = now
+	a.suppressed = 0
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"math/rand"
@@ -21,11 +22,13 @@ type EventsCache struct {
 	poolSize               int
 	trimIntervalMs         time.Duration
 	lastDestinations       sync.Map
+	availability           *availability
 	done                   chan struct{}
 }
 
 //NewEventsCache returns EventsCache and start goroutine for async operations
-func NewEventsCache(enabled bool, storage meta.Storage, capacityPerDestination, poolSize, trimIntervalMs int) *EventsCache {
+//every storage operation is limited with timeoutMs so Redis outage never blocks the cache workers
+func NewEventsCache(enabled bool, storage meta.Storage, capacityPerDestination, poolSize, trimIntervalMs, timeoutMs int) *EventsCache {
 	if !enabled {
 		logging.Warnf("Events cache is disabled.")
 		done := make(chan struct{})
@@ -50,6 +53,7 @@ func NewEventsCache(enabled bool, storage meta.Storage, capacityPerDestination,
 		lastDestinations:       sync.Map{},
 		poolSize:               poolSize,
 		trimIntervalMs:         time.Duration(trimIntervalMs),
+		availability:           newAvailability(time.Duration(timeoutMs) * time.Millisecond),
 
 		done: make(chan struct{}),
 	}
@@ -74,6 +78,12 @@ func (ec *EventsCache) start() {
 				ec.error(cf.destinationID, cf.eventID, cf.error)
 			case "skip":
 				ec.skip(cf.destinationID, cf.eventID, cf.error)
+			case "succeed_batch":
+				ec.succeedBatch(cf.eventContexts)
+			case "error_batch":
+				ec.errorBatch(cf.destinationID, meta.EventStatusError, cf.eventErrors)
+			case "skip_batch":
+				ec.errorBatch(cf.destinationID, meta.EventStatusSkip, cf.eventErrors)
 			}
 		}
 	})
@@ -90,10 +100,9 @@ func (ec *EventsCache) startTrimmer() {
 			case <-ticker.C:
 				ec.lastDestinations.Range(func(key interface{}, value interface{}) bool {
 					ec.lastDestinations.Delete(key)
-					err := ec.storage.TrimEvents(key.(string), ec.capacityPerDestination)
-					if err != nil {
-						logging.Warnf("failed to trim events cache events for %s: %v", key, err)
-					}
+					ec.availability.do("trim", func() error {
+						return ec.storage.TrimEvents(key.(string), ec.capacityPerDestination)
+					})
 					return true
 				})
 			}
@@ -153,6 +162,55 @@ func (ec *EventsCache) Skip(disabled bool, destinationID, eventID string, errMsg
 	}
 }
 
+//SucceedBatch puts all values into channel as one item which will be read and updated in storage with one call
+//is used in batch mode instead of Succeed
+func (ec *EventsCache) SucceedBatch(eventContexts []*adapters.EventContext) {
+	var enabled []*adapters.EventContext
+	for _, eventContext := range eventContexts {
+		if !eventContext.CacheDisabled {
+			enabled = append(enabled, eventContext)
+		}
+	}
+
+	if len(enabled) > 0 && ec.isActive() {
+		select {
+		case ec.eventsChannel <- &statusEvent{eventType: "succeed_batch", eventContexts: enabled}:
+		default:
+			if rand.Int31n(10) == 0 {
+				logging.Warnf("[events cache] queue overflow. Live Events UI may show inaccurate results. Consider increasing config variable: server.cache.pool.size (current value: %d)", ec.poolSize)
+			}
+		}
+	}
+}
+
+//ErrorBatch puts all values into channel as one item which will be read and updated in storage with one call
+//is used in batch mode instead of Error
+func (ec *EventsCache) ErrorBatch(disabled bool, destinationID string, eventErrors []*EventError) {
+	if !disabled && len(eventErrors) > 0 && ec.isActive() {
+		select {
+		case ec.eventsChannel <- &statusEvent{eventType: "error_batch", destinationID: destinationID, eventErrors: eventErrors}:
+		default:
+			if rand.Int31n(10) == 0 {
+				logging.Warnf("[events cache] queue overflow. Live Events UI may show inaccurate results. Consider increasing config variable: server.cache.pool.size (current value: %d)", ec.poolSize)
+			}
+		}
+	}
+}
+
+//SkipBatch puts all values into channel as one item which will be read and updated in storage with one call
+//is used in batch mode instead of Skip
+func (ec *EventsCache) SkipBatch(disabled bool, destinationID string, eventErrors []*EventError) {
+	if !disabled && len(eventErrors) > 0 && ec.isActive() {
+		select {
+		case ec.eventsChannel <- &statusEvent{eventType: "skip_batch", destinationID: destinationID, eventErrors: eventErrors}:
+		default:
+			if rand.Int31n(10) == 0 {
+				logging.Warnf("[events cache] queue overflow. Live Events UI may show inaccurate results. Consider increasing config variable: server.cache.pool.size (current value: %d)", ec.poolSize)
+			}
+		}
+	}
+}
+
 //put creates new event in storage
 func (ec *EventsCache) put(destinationID, eventID string, serializedPayload []byte) {
 	if eventID == "" {
@@ -160,9 +218,10 @@ func (ec *EventsCache) put(destinationID, eventID string, serializedPayload []by
 		return
 	}
 
-	err := ec.storage.AddEvent(destinationID, eventID, string(serializedPayload), timestamp.Now().UTC())
-	if err != nil {
-		logging.SystemErrorf("[%s] Error saving event %v in cache: %v", destinationID, string(serializedPayload), err)
+	ok := ec.availability.do("put", func() error {
+		return ec.storage.AddEvent(destinationID, eventID, string(serializedPayload), timestamp.Now().UTC())
+	})
+	if !ok {
 		return
 	}
 	ec.lastDestinations.LoadOrStore(destinationID, true)
@@ -170,9 +229,67 @@ func (ec *EventsCache) put(destinationID, eventID string, serializedPayload []by
 
 //succeed serializes and update processed event in storage
 func (ec *EventsCache) succeed(eventContext *adapters.EventContext) {
+	eventID, serialized, ok := ec.serializeSucceed(eventContext)
+	if !ok {
+		return
+	}
+
+	ec.availability.do("succeed", func() error {
+		return ec.storage.UpdateSucceedEvent(eventContext.DestinationID, eventID, serialized)
+	})
+}
+
+//succeedBatch serializes processed events and updates them in storage with one call per destination
+func (ec *EventsCache) succeedBatch(eventContexts []*adapters.EventContext) {
+	updatesPerDestination := map[string][]*meta.EventUpdate{}
+	for _, eventContext := range eventContexts {
+		eventID, serialized, ok := ec.serializeSucceed(eventContext)
+		if !ok {
+			continue
+		}
+
+		updatesPerDestination[eventContext.DestinationID] = append(updatesPerDestination[eventContext.DestinationID], &meta.EventUpdate{EventID: eventID, Status: meta.EventStatusSuccess, Value: serialized})
+	}
+
+	for destinationID, updates := range updatesPerDestination {
+		ec.updateBatch(destinationID, meta.EventStatusSuccess, updates)
+	}
+}
+
+//errorBatch writes errors into event error or skip fields (depends on status) in storage with one call
+func (ec *EventsCache) errorBatch(destinationID, status string, eventErrors []*EventError) {
+	updates := make([]*meta.EventUpdate, 0, len(eventErrors))
+	for _, eventError := range eventErrors {
+		if eventError.EventID == "" {
+			logging.SystemErrorf("[EventsCache] %sBatch(): Event id can't be empty. Destination [%s]", status, destinationID)
+			continue
+		}
+
+		updates = append(updates, &meta.EventUpdate{EventID: eventError.EventID, Status: status, Value: eventError.Error})
+	}
+
+	ec.updateBatch(destinationID, status, updates)
+}
+
+//updateBatch applies updates in storage and observes batch size metric
+func (ec *EventsCache) updateBatch(destinationID, status string, updates []*meta.EventUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+
+	metrics.EventsCacheBatchSize(status, len(updates))
+
+	ec.availability.do(status+"_batch", func() error {
+		return ec.storage.UpdateEvents(destinationID, updates)
+	})
+}
+
+//serializeSucceed returns event ID and serialized succeed event entity (HTTP or database)
+//returns false if event can't be serialized
+func (ec *EventsCache) serializeSucceed(eventContext *adapters.EventContext) (string, string, bool) {
 	if eventContext.EventID == "" {
 		logging.SystemErrorf("[EventsCache] Succeed(): Event id can't be empty. Destination [%s] event %s", eventContext.DestinationID, eventContext.ProcessedEvent.Serialize())
-		return
+		return "", "", false
 	}
 	eventId := eventContext.EventID
 	if processedEventId := appconfig.Instance.GlobalUniqueIDField.Extract(eventContext.ProcessedEvent); processedEventId != "" {
@@ -224,14 +341,10 @@ func (ec *EventsCache) succeed(eventContext *adapters.EventContext) {
 	b, err := json.Marshal(eventEntity)
 	if err != nil {
 		logging.SystemErrorf("[%s] Error marshalling succeed event [%v] before update: %v", eventContext.DestinationID, eventEntity, err)
-		return
+		return "", "", false
 	}
 
-	err = ec.storage.UpdateSucceedEvent(eventContext.DestinationID, eventId, string(b))
-	if err != nil {
-		logging.SystemErrorf("[%s] Error updating success event %s in cache: %v", eventContext.DestinationID, eventContext.ProcessedEvent.Serialize(), err)
-		return
-	}
+	return eventId, string(b), true
 }
 
 //error writes error into event field in storage
@@ -241,11 +354,9 @@ func (ec *EventsCache) error(destinationID, eventID string, errMsg string) {
 		return
 	}
 
-	err := ec.storage.UpdateErrorEvent(destinationID, eventID, errMsg)
-	if err != nil {
-		logging.SystemErrorf("[%s] Error updating error event [%s] in cache: %v", destinationID, eventID, err)
-		return
-	}
+	ec.availability.do("error", func() error {
+		return ec.storage.UpdateErrorEvent(destinationID, eventID, errMsg)
+	})
 }
 
 //skip writes skipped error into event skip field in storage
@@ -255,18 +366,20 @@ func (ec *EventsCache) skip(destinationID, eventID string, errMsg string) {
 		return
 	}
 
-	err := ec.storage.UpdateSkipEvent(destinationID, eventID, errMsg)
-	if err != nil {
-		logging.SystemErrorf("[%s] Error updating skipped event [%s] in cache: %v", destinationID, eventID, err)
-		return
-	}
+	ec.availability.do("skip", func() error {
+		return ec.storage.UpdateSkipEvent(destinationID, eventID, errMsg)
+	})
 }
 
 //GetN returns at most n facts by key
+//returns empty slice if the storage is unavailable
 func (ec *EventsCache) GetN(destinationID string, start, end time.Time, n int) []meta.Event {
-	facts, err := ec.storage.GetEvents(destinationID, start, end, n)
-	if err != nil {
-		logging.SystemErrorf("Error getting %d cached events for [%s] destination: %v", n, destinationID, err)
+	var facts []meta.Event
+	ok := ec.availability.do("get", func() (err error) {
+		facts, err = ec.storage.GetEvents(destinationID, start, end, n)
+		return err
+	})
+	if !ok {
 		return []meta.Event{}
 	}
 
@@ -274,10 +387,14 @@ func (ec *EventsCache) GetN(destinationID string, start, end time.Time, n int) [
 }
 
 //GetTotal returns total amount of destination events in storage
+//returns 0 if the storage is unavailable
 func (ec *EventsCache) GetTotal(destinationID string) int {
-	total, err := ec.storage.GetTotalEvents(destinationID)
-	if err != nil {
-		logging.SystemErrorf("Error getting total events for [%s] destination: %v", destinationID, err)
+	var total int
+	ok := ec.availability.do("get_total", func() (err error) {
+		total, err = ec.storage.GetTotalEvents(destinationID)
+		return err
+	})
+	if !ok {
 		return 0
 	}
 
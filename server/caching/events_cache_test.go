@@ -0,0 +1,144 @@
+package caching
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+//testUpdatesStorage is a meta.Storage which records UpdateEvents calls
+type testUpdatesStorage struct {
+	*meta.Dummy
+
+	mutex   sync.Mutex
+	calls   int
+	updates map[string][]*meta.EventUpdate
+}
+
+func (tus *testUpdatesStorage) UpdateEvents(destinationID string, updates []*meta.EventUpdate) error {
+	tus.mutex.Lock()
+	defer tus.mutex.Unlock()
+
+	tus.calls++
+	tus.updates[destinationID] = append(tus.updates[destinationID], updates...)
+	return nil
+}
+
+func newTestEventsCache() (*EventsCache, *testUpdatesStorage) {
+	storage := &testUpdatesStorage{Dummy: &meta.Dummy{}, updates: map[string][]*meta.EventUpdate{}}
+	return &EventsCache{storage: storage, availability: newAvailability(0)}, storage
+}
+
+func TestErrorBatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          string
+		eventErrors     []*EventError
+		expectedCalls   int
+		expectedUpdates []*meta.EventUpdate
+	}{
+		{
+			"Empty batch",
+			meta.EventStatusError,
+			[]*EventError{},
+			0,
+			nil,
+		},
+		{
+			"Only empty event ids",
+			meta.EventStatusError,
+			[]*EventError{{EventID: "", Error: "error1"}},
+			0,
+			nil,
+		},
+		{
+			"Errors without empty event ids",
+			meta.EventStatusError,
+			[]*EventError{{EventID: "id1", Error: "error1"}, {EventID: "", Error: "error2"}, {EventID: "id3", Error: "error3"}},
+			1,
+			[]*meta.EventUpdate{
+				{EventID: "id1", Status: meta.EventStatusError, Value: "error1"},
+				{EventID: "id3", Status: meta.EventStatusError, Value: "error3"},
+			},
+		},
+		{
+			"Skips",
+			meta.EventStatusSkip,
+			[]*EventError{{EventID: "id1", Error: "skipped"}},
+			1,
+			[]*meta.EventUpdate{{EventID: "id1", Status: meta.EventStatusSkip, Value: "skipped"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventsCache, storage := newTestEventsCache()
+
+			eventsCache.errorBatch("dest1", tt.status, tt.eventErrors)
+
+			require.Equal(t, tt.expectedCalls, storage.calls)
+			require.Equal(t, tt.expectedUpdates, storage.updates["dest1"])
+		})
+	}
+}
+
+func TestSucceedBatch(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	tests := []struct {
+		name             string
+		eventContexts    []*adapters.EventContext
+		expectedCalls    int
+		expectedEventIDs map[string][]string
+	}{
+		{
+			"Empty batch",
+			[]*adapters.EventContext{},
+			0,
+			map[string][]string{},
+		},
+		{
+			"Empty event id is skipped",
+			[]*adapters.EventContext{
+				{DestinationID: "dest1", EventID: "", ProcessedEvent: events.Event{"field1": "value1"}},
+				{DestinationID: "dest1", EventID: "id2", ProcessedEvent: events.Event{"field1": "value2"}},
+			},
+			1,
+			map[string][]string{"dest1": {"id2"}},
+		},
+		{
+			"One call per destination",
+			[]*adapters.EventContext{
+				{DestinationID: "dest1", EventID: "id1", ProcessedEvent: events.Event{"field1": "value1"}},
+				{DestinationID: "dest2", EventID: "id2", ProcessedEvent: events.Event{"field1": "value2"}},
+				{DestinationID: "dest1", EventID: "id3", HTTPRequest: &adapters.Request{URL: "https://test.com", Method: "POST"}},
+			},
+			2,
+			map[string][]string{"dest1": {"id1", "id3"}, "dest2": {"id2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventsCache, storage := newTestEventsCache()
+
+			eventsCache.succeedBatch(tt.eventContexts)
+
+			require.Equal(t, tt.expectedCalls, storage.calls)
+			actualEventIDs := map[string][]string{}
+			for destinationID, updates := range storage.updates {
+				for _, update := range updates {
+					require.Equal(t, meta.EventStatusSuccess, update.Status)
+					require.NotEmpty(t, update.Value)
+					actualEventIDs[destinationID] = append(actualEventIDs[destinationID], update.EventID)
+				}
+			}
+			require.Equal(t, tt.expectedEventIDs, actualEventIDs)
+		})
+	}
+}
@@ -21,6 +21,12 @@ type SucceedHTTPEvent struct {
 	Body    string            `json:"body,omitempty"`
 }
 
+//EventError is an event identifier with error message for batch error/skip updates
+type EventError struct {
+	EventID string
+	Error   string
+}
+
 //channel dto
 type statusEvent struct {
 	eventType         string
@@ -29,4 +35,8 @@ type statusEvent struct {
 	serializedPayload []byte
 	eventContext      *adapters.EventContext
 	error             string
+
+	//batch updates
+	eventContexts []*adapters.EventContext
+	eventErrors   []*EventError
 }
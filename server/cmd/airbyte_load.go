@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"github.com/jitsucom/jitsu/server/airbyte"
+	"github.com/jitsucom/jitsu/server/drivers"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/atomic"
+)
+
+const airbyteLoadTaskID = "airbyte-load"
+
+var (
+	//airbyte-load command flags
+	sourceConfigPath, sourceID, inputStatePath, recordsOutputPath, stateOutputPath, airbyteConfigDir string
+	airbyteBatchSize                                                                                 int
+)
+
+//airbyteLoadCmd runs a single Airbyte source sync without the server
+var airbyteLoadCmd = &cobra.Command{
+	Use:   "airbyte-load --source <source config file> [flags]",
+	Short: "CLI for running a single Airbyte source sync locally with file-based state",
+	Long: `Jitsu CLI tool for running a single Airbyte source sync without the server. Records are written as JSON lines into --output (stdout by default),
+the latest state is written into --state-output after every batch. Common use case: deterministic integration tests of connectors`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return airbyteLoad()
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Version:       version,
+}
+
+func init() {
+	rootCmd.AddCommand(airbyteLoadCmd)
+
+	airbyteLoadCmd.Flags().StringVar(&sourceConfigPath, "source", "", "(required) a path to YAML or JSON file with the source configuration (the same as in 'sources' section of the server configuration)")
+	airbyteLoadCmd.Flags().StringVar(&sourceID, "source-id", "airbyte_load", "(optional) source id. It is used in table names and config directory names")
+	airbyteLoadCmd.Flags().StringVar(&inputStatePath, "state", "", "(optional) a path to file with the state JSON which the sync is started with. If missing, initial_state from the source configuration is used")
+	airbyteLoadCmd.Flags().StringVar(&recordsOutputPath, "output", "-", "(optional) a path to file where records will be written as JSON lines: {\"stream\": ..., \"data\": {...}}. Default value '-' means stdout")
+	airbyteLoadCmd.Flags().StringVar(&stateOutputPath, "state-output", "", "(optional) a path to file where the latest state will be written. If missing, the --state file is overwritten")
+	airbyteLoadCmd.Flags().StringVar(&airbyteConfigDir, "config-dir", "./airbyte_config", "(optional) a directory where Airbyte configs, catalogs and state files are written. It is mounted into connector containers")
+	airbyteLoadCmd.Flags().IntVar(&airbyteBatchSize, "batch-size", 10_000, "(optional) max records amount in one batch. The state is written after every batch")
+
+	airbyteLoadCmd.MarkFlagRequired("source")
+}
+
+//airbyteLoad is a command main function:
+//creates Airbyte driver from the source configuration file, runs Load and writes records and the latest state into files
+//returns err if occurred
+func airbyteLoad() error {
+	if stateOutputPath == "" {
+		stateOutputPath = inputStatePath
+	}
+
+	sourceConfig, err := readSourceConfig(sourceConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read source configuration: %v", err)
+	}
+	if sourceConfig.Type != base.AirbyteType {
+		return fmt.Errorf("source type must be [%s]. Got: [%s]", base.AirbyteType, sourceConfig.Type)
+	}
+	sourceConfig.SourceID = sourceID
+
+	state := ""
+	if inputStatePath != "" {
+		b, err := ioutil.ReadFile(inputStatePath)
+		if err != nil {
+			return fmt.Errorf("failed to read state file: %v", err)
+		}
+		state = string(b)
+	}
+
+	absConfigDir, err := filepath.Abs(airbyteConfigDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute config dir path: %v", err)
+	}
+	if err := logging.EnsureDir(absConfigDir); err != nil {
+		return fmt.Errorf("failed to create config dir: %v", err)
+	}
+
+	ctx := context.Background()
+	//configs are mounted into connector containers from the local directory (not from the docker volume as on the server)
+	if err := airbyte.Init(ctx, absConfigDir, absConfigDir, airbyteBatchSize, 0, airbyte.DefaultCatalogCacheTTL, 0, os.Stderr); err != nil {
+		return fmt.Errorf("failed to initialize Airbyte bridge: %v", err)
+	}
+
+	collections, err := drivers.ParseCollections(sourceConfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse source collections: %v", err)
+	}
+	driver, err := base.DriverConstructors[base.AirbyteType](ctx, sourceConfig, collections[0])
+	if err != nil {
+		return fmt.Errorf("failed to create Airbyte driver: %v", err)
+	}
+	defer driver.Close()
+
+	cliDriver, ok := driver.(base.CLIDriver)
+	if !ok {
+		return fmt.Errorf("%s driver doesn't support Load", base.AirbyteType)
+	}
+
+	recordsWriter, closeRecordsWriter, err := openRecordsOutput(recordsOutputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open records output: %v", err)
+	}
+
+	consumer := &fileDataConsumer{recordsWriter: recordsWriter, statePath: stateOutputPath}
+	taskCloser := newLocalTaskCloser()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			fmt.Fprintln(os.Stderr, "Canceling the sync..")
+			taskCloser.cancel()
+		}
+	}()
+
+	loadErr := cliDriver.Load("", state, &stderrTaskLogger{}, consumer, taskCloser)
+	if err := closeRecordsWriter(); err != nil && loadErr == nil {
+		loadErr = fmt.Errorf("failed to write records: %v", err)
+	}
+	if loadErr != nil {
+		return loadErr
+	}
+
+	fmt.Fprintf(os.Stderr, "Sync has been finished: %d records\n", consumer.records)
+	return nil
+}
+
+//readSourceConfig reads YAML or JSON source configuration file
+func readSourceConfig(configPath string) (*base.SourceConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	sourceConfig := &base.SourceConfig{}
+	if err := v.Unmarshal(sourceConfig); err != nil {
+		return nil, err
+	}
+
+	return sourceConfig, nil
+}
+
+//openRecordsOutput returns buffered writer into stdout (if output path is '-') or into the file and close func
+func openRecordsOutput(outputPath string) (*bufio.Writer, func() error, error) {
+	if outputPath == "-" || outputPath == "" {
+		w := bufio.NewWriter(os.Stdout)
+		return w, w.Flush, nil
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := bufio.NewWriter(file)
+	return w, func() error {
+		if err := w.Flush(); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}, nil
+}
+
+//fileDataConsumer writes records as JSON lines and persists the latest state into the file
+type fileDataConsumer struct {
+	recordsWriter *bufio.Writer
+	statePath     string
+	records       int
+}
+
+//outputRecord is a records output line
+type outputRecord struct {
+	Stream string                 `json:"stream"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+//Consume writes batch records (streams are sorted by name for deterministic output) and the state
+func (fdc *fileDataConsumer) Consume(representation *base.CLIOutputRepresentation) error {
+	streamNames := make([]string, 0, len(representation.Streams))
+	for streamName := range representation.Streams {
+		streamNames = append(streamNames, streamName)
+	}
+	sort.Strings(streamNames)
+
+	encoder := json.NewEncoder(fdc.recordsWriter)
+	for _, streamName := range streamNames {
+		for _, object := range representation.Streams[streamName].Objects {
+			if err := encoder.Encode(outputRecord{Stream: streamName, Data: object}); err != nil {
+				return fmt.Errorf("error writing record: %v", err)
+			}
+			fdc.records++
+		}
+	}
+
+	if representation.State == nil || fdc.statePath == "" {
+		return nil
+	}
+
+	//records must be written before the state: otherwise records of the batch might be lost on the next run
+	if err := fdc.recordsWriter.Flush(); err != nil {
+		return fmt.Errorf("error writing records: %v", err)
+	}
+
+	b, err := json.Marshal(representation.State)
+	if err != nil {
+		return fmt.Errorf("error marshalling state: %v", err)
+	}
+
+	//state is written into a temporary file and renamed so the state file is never partially written
+	tmpPath := fdc.statePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, 0644); err != nil {
+		return fmt.Errorf("error writing state: %v", err)
+	}
+
+	return os.Rename(tmpPath, fdc.statePath)
+}
+
+//localTaskCloser is a base.CLITaskCloser of the local sync. The sync is canceled by signal
+type localTaskCloser struct {
+	canceled *atomic.Bool
+}
+
+func newLocalTaskCloser() *localTaskCloser {
+	return &localTaskCloser{canceled: atomic.NewBool(false)}
+}
+
+func (ltc *localTaskCloser) TaskID() string {
+	return airbyteLoadTaskID
+}
+
+func (ltc *localTaskCloser) CloseWithError(msg string, systemErr bool) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+}
+
+//HandleCanceling returns error if the sync has been canceled
+func (ltc *localTaskCloser) HandleCanceling() error {
+	if ltc.canceled.Load() {
+		return errors.New("sync has been canceled")
+	}
+
+	return nil
+}
+
+func (ltc *localTaskCloser) cancel() {
+	ltc.canceled.Store(true)
+}
+
+//stderrTaskLogger writes sync logs into stderr so records output into stdout isn't mixed with logs
+type stderrTaskLogger struct{}
+
+func (stl *stderrTaskLogger) INFO(format string, v ...interface{}) {
+	stl.LOG(format, "", logging.INFO, v...)
+}
+
+func (stl *stderrTaskLogger) ERROR(format string, v ...interface{}) {
+	stl.LOG(format, "", logging.ERROR, v...)
+}
+
+func (stl *stderrTaskLogger) WARN(format string, v ...interface{}) {
+	stl.LOG(format, "", logging.WARN, v...)
+}
+
+func (stl *stderrTaskLogger) LOG(format, system string, level logging.Level, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if system != "" {
+		msg = fmt.Sprintf("[%s] %s", system, msg)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s\n", level.String(), msg)
+}
+
+func (stl *stderrTaskLogger) Write(p []byte) (n int, err error) {
+	return os.Stderr.Write(p)
+}
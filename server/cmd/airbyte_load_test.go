@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileDataConsumer(t *testing.T) {
+	tests := []struct {
+		name            string
+		batches         []*base.CLIOutputRepresentation
+		withStatePath   bool
+		expectedRecords string
+		expectedState   string
+	}{
+		{
+			"Records are written sorted by stream name",
+			[]*base.CLIOutputRepresentation{
+				{Streams: map[string]*base.StreamRepresentation{
+					"users":  {Objects: []map[string]interface{}{{"id": 1}, {"id": 2}}},
+					"orders": {Objects: []map[string]interface{}{{"id": 10}}},
+				}},
+			},
+			true,
+			`{"stream":"orders","data":{"id":10}}` + "\n" + `{"stream":"users","data":{"id":1}}` + "\n" + `{"stream":"users","data":{"id":2}}` + "\n",
+			"",
+		},
+		{
+			"The latest state is written",
+			[]*base.CLIOutputRepresentation{
+				{State: map[string]interface{}{"cursor": 1}, Streams: map[string]*base.StreamRepresentation{"users": {Objects: []map[string]interface{}{{"id": 1}}}}},
+				{State: map[string]interface{}{"cursor": 2}, Streams: map[string]*base.StreamRepresentation{}},
+			},
+			true,
+			`{"stream":"users","data":{"id":1}}` + "\n",
+			`{"cursor":2}`,
+		},
+		{
+			"State isn't written without state path",
+			[]*base.CLIOutputRepresentation{
+				{State: map[string]interface{}{"cursor": 1}, Streams: map[string]*base.StreamRepresentation{"users": {Objects: []map[string]interface{}{{"id": 1}}}}},
+			},
+			false,
+			`{"stream":"users","data":{"id":1}}` + "\n",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "airbyte-load")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			statePath := ""
+			if tt.withStatePath {
+				statePath = filepath.Join(dir, "state.json")
+			}
+			buf := &bytes.Buffer{}
+			consumer := &fileDataConsumer{recordsWriter: bufio.NewWriter(buf), statePath: statePath}
+
+			expectedRecordsCount := 0
+			for _, batch := range tt.batches {
+				require.NoError(t, consumer.Consume(batch))
+				for _, stream := range batch.Streams {
+					expectedRecordsCount += len(stream.Objects)
+				}
+			}
+			require.NoError(t, consumer.recordsWriter.Flush())
+
+			require.Equal(t, tt.expectedRecords, buf.String())
+			require.Equal(t, expectedRecordsCount, consumer.records)
+
+			if tt.expectedState == "" {
+				files, err := ioutil.ReadDir(dir)
+				require.NoError(t, err)
+				require.Empty(t, files)
+			} else {
+				state, err := ioutil.ReadFile(statePath)
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedState, string(state))
+				_, err = os.Stat(statePath + ".tmp")
+				require.True(t, os.IsNotExist(err), "temporary state file must be renamed")
+			}
+		})
+	}
+}
+
+func TestReadSourceConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte-load")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name         string
+		fileName     string
+		content      string
+		expectedType string
+		expectedErr  bool
+	}{
+		{
+			"YAML",
+			"source.yaml",
+			"type: airbyte\nconfig:\n  docker_image: source-test\n",
+			base.AirbyteType,
+			false,
+		},
+		{
+			"JSON",
+			"source.json",
+			`{"type": "airbyte", "config": {"docker_image": "source-test"}}`,
+			base.AirbyteType,
+			false,
+		},
+		{
+			"Malformed",
+			"malformed.json",
+			`{"type": `,
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, tt.fileName)
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(tt.content), 0644))
+
+			sourceConfig, err := readSourceConfig(configPath)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedType, sourceConfig.Type)
+			require.Equal(t, "source-test", sourceConfig.Config["docker_image"])
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -26,6 +27,16 @@ type DestinationConfig struct {
 	CachingConfiguration   *CachingConfiguration    `mapstructure:"caching" json:"caching,omitempty" yaml:"caching,omitempty"`
 	PostHandleDestinations []string                 `mapstructure:"post_handle_destinations,omitempty" json:"post_handle_destinations,omitempty" yaml:"post_handle_destinations,omitempty"`
 	GeoDataResolverID      string                   `mapstructure:"geo_data_resolver_id" json:"geo_data_resolver_id,omitempty" yaml:"geo_data_resolver_id,omitempty"`
+	Deduplication          *DeduplicationConfig     `mapstructure:"deduplication" json:"deduplication,omitempty" yaml:"deduplication,omitempty"`
+	Filter                 string                   `mapstructure:"filter" json:"filter,omitempty" yaml:"filter,omitempty"`
+	FieldMasking           map[string]string        `mapstructure:"field_masking" json:"field_masking,omitempty" yaml:"field_masking,omitempty"`
+	Ordering               *OrderingConfig          `mapstructure:"ordering" json:"ordering,omitempty" yaml:"ordering,omitempty"`
+	TimestampBounds        *TimestampBoundsConfig   `mapstructure:"timestamp_bounds" json:"timestamp_bounds,omitempty" yaml:"timestamp_bounds,omitempty"`
+	TableAllowlist         []string                 `mapstructure:"table_allowlist" json:"table_allowlist,omitempty" yaml:"table_allowlist,omitempty"`
+	TableDenylist          []string                 `mapstructure:"table_denylist" json:"table_denylist,omitempty" yaml:"table_denylist,omitempty"`
+	LazyInit               bool                     `mapstructure:"lazy_init" json:"lazy_init,omitempty" yaml:"lazy_init,omitempty"`
+	DebugSampleRate        float64                  `mapstructure:"debug_sample_rate" json:"debug_sample_rate,omitempty" yaml:"debug_sample_rate,omitempty"`
+	MaxStoreLatencyMs      int                      `mapstructure:"max_store_latency_ms" json:"max_store_latency_ms,omitempty" yaml:"max_store_latency_ms,omitempty"`
 
 	//Deprecated
 	DataSource map[string]interface{} `mapstructure:"datasource,omitempty" json:"datasource,omitempty" yaml:"datasource,omitempty"`
@@ -98,9 +109,32 @@ type DataLayout struct {
 	//Deprecated
 	Mappings          *Mapping `mapstructure:"mappings" json:"mappings,omitempty" yaml:"mappings,omitempty"`
 	MaxColumns        int      `mapstructure:"max_columns" json:"max_columns,omitempty" yaml:"max_columns,omitempty"`
+	OnMaxColumns      string   `mapstructure:"on_max_columns" json:"on_max_columns,omitempty" yaml:"on_max_columns,omitempty"`
+	MaxEventBytes     int      `mapstructure:"max_event_bytes" json:"max_event_bytes,omitempty" yaml:"max_event_bytes,omitempty"`
 	TableNameTemplate string   `mapstructure:"table_name_template" json:"table_name_template,omitempty" yaml:"table_name_template,omitempty"`
 	PrimaryKeyFields  []string `mapstructure:"primary_key_fields" json:"primary_key_fields,omitempty" yaml:"primary_key_fields,omitempty"`
+	OnConflict        string   `mapstructure:"on_conflict" json:"on_conflict,omitempty" yaml:"on_conflict,omitempty"`
 	UniqueIDField     string   `mapstructure:"unique_id_field" json:"unique_id_field,omitempty" yaml:"unique_id_field,omitempty"`
+
+	//MaxFlattenDepth is a max nesting level of flattened objects (0 - unlimited). Deeper objects are written as JSON strings
+	MaxFlattenDepth int `mapstructure:"max_flatten_depth" json:"max_flatten_depth,omitempty" yaml:"max_flatten_depth,omitempty"`
+
+	//ColumnRules is a table name ('*' - all tables) -> flat column name -> rule mapping
+	ColumnRules map[string]map[string]ColumnRule `mapstructure:"column_rules" json:"column_rules,omitempty" yaml:"column_rules,omitempty"`
+
+	//SchemaInferenceSample is a number of the first events of a new table which are buffered in stream mode
+	//to create the table with their union schema (0 - disabled). SchemaInferenceMaxWaitSec is a max buffering time
+	SchemaInferenceSample     int `mapstructure:"schema_inference_sample" json:"schema_inference_sample,omitempty" yaml:"schema_inference_sample,omitempty"`
+	SchemaInferenceMaxWaitSec int `mapstructure:"schema_inference_max_wait_sec" json:"schema_inference_max_wait_sec,omitempty" yaml:"schema_inference_max_wait_sec,omitempty"`
+
+	//TablePrefix and TableSuffix are added to names of all destination tables (e.g. prod_ for sharing a warehouse across environments)
+	TablePrefix string `mapstructure:"table_prefix" json:"table_prefix,omitempty" yaml:"table_prefix,omitempty"`
+	TableSuffix string `mapstructure:"table_suffix" json:"table_suffix,omitempty" yaml:"table_suffix,omitempty"`
+
+	//IdentifierTruncation is a strategy of shortening over-length column and table names: cut (default) or hash
+	//MaxIdentifierLength overrides the destination type max length of identifiers
+	IdentifierTruncation string `mapstructure:"identifier_truncation" json:"identifier_truncation,omitempty" yaml:"identifier_truncation,omitempty"`
+	MaxIdentifierLength  int    `mapstructure:"max_identifier_length" json:"max_identifier_length,omitempty" yaml:"max_identifier_length,omitempty"`
 }
 
 //UsersRecognition is a model for Users recognition module configuration
@@ -114,8 +148,75 @@ type UsersRecognition struct {
 }
 
 //CachingConfiguration is a configuration for disabling caching
+//SkipEvents is a list of event types which aren't written into events cache (but are stored)
+//values with '*' suffix are matched as event types prefixes (e.g. heartbeat_*)
 type CachingConfiguration struct {
-	Disabled bool `mapstructure:"disabled" json:"disabled" yaml:"disabled"`
+	Disabled   bool     `mapstructure:"disabled" json:"disabled" yaml:"disabled"`
+	SkipEvents []string `mapstructure:"cache_skip_events" json:"cache_skip_events,omitempty" yaml:"cache_skip_events,omitempty"`
+}
+
+//IsEventSkipped returns true if events with the event type shouldn't be written into events cache
+func (cc *CachingConfiguration) IsEventSkipped(eventType string) bool {
+	if cc == nil || eventType == "" {
+		return false
+	}
+
+	for _, skipEvent := range cc.SkipEvents {
+		if strings.HasSuffix(skipEvent, "*") {
+			if strings.HasPrefix(eventType, strings.TrimSuffix(skipEvent, "*")) {
+				return true
+			}
+		} else if skipEvent == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+//DeduplicationConfig is a configuration for skipping recently seen events (by unique ID) in stream mode
+type DeduplicationConfig struct {
+	Enabled       bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	WindowSeconds int  `mapstructure:"window_seconds" json:"window_seconds,omitempty" yaml:"window_seconds,omitempty"`
+	MaxSize       int  `mapstructure:"max_size" json:"max_size,omitempty" yaml:"max_size,omitempty"`
+}
+
+//IsEnabled returns true if not nil and enabled
+func (dc *DeduplicationConfig) IsEnabled() bool {
+	return dc != nil && dc.Enabled
+}
+
+//OrderingConfig is a configuration for in-order processing of events with the same key in stream mode
+//events are partitioned by the key (unique ID field by default) and every partition is processed sequentially
+type OrderingConfig struct {
+	Enabled      bool   `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Partitions   int    `mapstructure:"partitions" json:"partitions,omitempty" yaml:"partitions,omitempty"`
+	PartitionKey string `mapstructure:"partition_key" json:"partition_key,omitempty" yaml:"partition_key,omitempty"`
+}
+
+//IsEnabled returns true if not nil and enabled
+func (oc *OrderingConfig) IsEnabled() bool {
+	return oc != nil && oc.Enabled
+}
+
+//ColumnRule is a configuration of the column value processing before storing:
+//values are coerced to Type (string, integer, double, boolean, timestamp) and missing (or null) values are replaced with Default
+type ColumnRule struct {
+	Type    string      `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Default interface{} `mapstructure:"default" json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+//TimestampBoundsConfig is a configuration of events _timestamp validation
+//events with _timestamp later than now + max_future_skew_sec or earlier than now - max_past_age_sec are skipped or clamped
+type TimestampBoundsConfig struct {
+	MaxFutureSkewSec int    `mapstructure:"max_future_skew_sec" json:"max_future_skew_sec,omitempty" yaml:"max_future_skew_sec,omitempty"`
+	MaxPastAgeSec    int    `mapstructure:"max_past_age_sec" json:"max_past_age_sec,omitempty" yaml:"max_past_age_sec,omitempty"`
+	OnOutOfBounds    string `mapstructure:"on_out_of_bounds" json:"on_out_of_bounds,omitempty" yaml:"on_out_of_bounds,omitempty"`
+}
+
+//IsEnabled returns true if not nil and at least one bound is configured
+func (tbc *TimestampBoundsConfig) IsEnabled() bool {
+	return tbc != nil && (tbc.MaxFutureSkewSec > 0 || tbc.MaxPastAgeSec > 0)
 }
 
 //IsEnabled returns true if enabled
@@ -0,0 +1,110 @@
+package destinations
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/spf13/viper"
+)
+
+const (
+	s3SourcePrefix  = "s3://"
+	gcsSourcePrefix = "gs://"
+
+	//workloadIdentityKeyFile makes google client use default application credentials
+	workloadIdentityKeyFile = "workload_identity"
+)
+
+//isObjectStorageSource returns true if destinations source is s3://bucket/key or gs://bucket/key
+func isObjectStorageSource(source string) bool {
+	return strings.HasPrefix(source, s3SourcePrefix) || strings.HasPrefix(source, gcsSourcePrefix)
+}
+
+//parseObjectStorageSource returns bucket and object key from s3://bucket/key or gs://bucket/key
+func parseObjectStorageSource(source string) (string, string, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", "", fmt.Errorf("Error parsing destinations source %s: %v", source, err)
+	}
+
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return "", "", fmt.Errorf("Destinations source must be in format %sbucket/key or %sbucket/key: %s", s3SourcePrefix, gcsSourcePrefix, source)
+	}
+
+	return u.Host, key, nil
+}
+
+//newObjectStorageLoadFunc returns resources.Watch load func which downloads destinations config from S3 or Google Cloud Storage
+//credentials are taken from server.destinations_storage.s3 or server.destinations_storage.google configuration sections.
+//ETag (S3) or generation (GCS) is used as the last modified value: unchanged objects aren't downloaded
+func newObjectStorageLoadFunc(source string, storageConfig *viper.Viper) (func(string, string) (*resources.ResponsePayload, error), error) {
+	bucket, key, err := parseObjectStorageSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if storageConfig == nil {
+		storageConfig = viper.New()
+	}
+
+	if strings.HasPrefix(source, s3SourcePrefix) {
+		s3Config := &adapters.S3Config{}
+		if err := storageConfig.UnmarshalKey("s3", s3Config); err != nil {
+			return nil, fmt.Errorf("Error parsing server.destinations_storage.s3 config: %v", err)
+		}
+		s3Config.Bucket = bucket
+		s3Config.Folder = ""
+		s3, err := adapters.NewS3(s3Config)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating S3 client for destinations source: %v", err)
+		}
+		appconfig.Instance.ScheduleClosing(s3)
+
+		return func(_, eTag string) (*resources.ResponsePayload, error) {
+			b, newETag, err := s3.GetObject(key, eTag)
+			return objectPayload(b, newETag, err)
+		}, nil
+	}
+
+	googleConfig := &adapters.GoogleConfig{}
+	if err := storageConfig.UnmarshalKey("google", googleConfig); err != nil {
+		return nil, fmt.Errorf("Error parsing server.destinations_storage.google config: %v", err)
+	}
+	googleConfig.Bucket = bucket
+	if googleConfig.KeyFile == nil || googleConfig.KeyFile == "" {
+		googleConfig.KeyFile = workloadIdentityKeyFile
+	}
+	if err := googleConfig.Validate(); err != nil {
+		return nil, err
+	}
+	gcs, err := adapters.NewGoogleCloudStorage(context.Background(), googleConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating Google Cloud Storage client for destinations source: %v", err)
+	}
+	appconfig.Instance.ScheduleClosing(gcs)
+
+	return func(_, generation string) (*resources.ResponsePayload, error) {
+		current, _ := strconv.ParseInt(generation, 10, 64)
+		b, newGeneration, err := gcs.GetObject(key, current)
+		return objectPayload(b, strconv.FormatInt(newGeneration, 10), err)
+	}, nil
+}
+
+//objectPayload converts object storage GetObject result into resources.ResponsePayload
+func objectPayload(content []byte, version string, err error) (*resources.ResponsePayload, error) {
+	if err == adapters.ErrObjectNotModified {
+		return nil, resources.ErrNoModified
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &resources.ResponsePayload{Content: content, LastModified: version}, nil
+}
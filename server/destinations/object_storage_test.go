@@ -0,0 +1,54 @@
+package destinations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseObjectStorageSource(t *testing.T) {
+	tests := []struct {
+		name           string
+		source         string
+		expectedBucket string
+		expectedKey    string
+		expectedErr    string
+	}{
+		{
+			"s3",
+			"s3://my-bucket/jitsu/destinations.json",
+			"my-bucket",
+			"jitsu/destinations.json",
+			"",
+		},
+		{
+			"gcs",
+			"gs://my-bucket/destinations.yaml",
+			"my-bucket",
+			"destinations.yaml",
+			"",
+		},
+		{
+			"without key",
+			"s3://my-bucket/",
+			"",
+			"",
+			"Destinations source must be in format s3://bucket/key or gs://bucket/key: s3://my-bucket/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.True(t, isObjectStorageSource(tt.source))
+
+			bucket, key, err := parseObjectStorageSource(tt.source)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedBucket, bucket)
+			require.Equal(t, tt.expectedKey, key)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package destinations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+//reloadGate is a sanity check of the reloaded destinations config. It is applied before the current destinations are replaced
+//so a broken config push (e.g. a typo which removes all destinations) doesn't drop traffic
+type reloadGate struct {
+	//allowEmpty permits a config without destinations to replace non-empty one (server.destinations_reload.allow_empty)
+	allowEmpty bool
+	//maxRemovedPercent is a max percent of the current destinations which can be removed by one reload (0 - unlimited)
+	maxRemovedPercent int
+}
+
+//validate returns error if the new config mustn't be applied:
+//1. any destination config is invalid (unknown type, missing required fields)
+//2. all current destinations are removed (unless allowEmpty)
+//3. more than maxRemovedPercent of current destinations are removed
+//The first loaded config isn't checked: there are no destinations to keep and invalid ones are skipped by init()
+func (rg *reloadGate) validate(currentIDs []string, dc map[string]config.DestinationConfig) error {
+	if len(currentIDs) == 0 {
+		return nil
+	}
+
+	var invalid []string
+	for id, d := range dc {
+		destinationConfig := d
+		if errs := storages.ValidateDestination(id, &destinationConfig); len(errs) > 0 {
+			invalid = append(invalid, fmt.Sprintf("[%s] %v", id, errs))
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("invalid destinations configs: %s", strings.Join(invalid, ", "))
+	}
+
+	if len(dc) == 0 {
+		if rg.allowEmpty {
+			return nil
+		}
+		return fmt.Errorf("all %d destinations would be removed. Set server.destinations_reload.allow_empty: true if it is expected", len(currentIDs))
+	}
+
+	removed := 0
+	for _, id := range currentIDs {
+		if _, ok := dc[id]; !ok {
+			removed++
+		}
+	}
+	if rg.maxRemovedPercent > 0 && removed*100 > rg.maxRemovedPercent*len(currentIDs) {
+		return fmt.Errorf("%d of %d destinations would be removed: it exceeds server.destinations_reload.max_removed_percent (%d%%)", removed, len(currentIDs), rg.maxRemovedPercent)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package destinations
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadGate(t *testing.T) {
+	valid := func(ids ...string) map[string]config.DestinationConfig {
+		dc := map[string]config.DestinationConfig{}
+		for _, id := range ids {
+			dc[id] = config.DestinationConfig{Type: "postgres", DataSource: map[string]interface{}{"host": id, "db": "db", "username": "user"}}
+		}
+		return dc
+	}
+	current := []string{"pg_1", "pg_2", "pg_3", "pg_4"}
+
+	tests := []struct {
+		name        string
+		gate        *reloadGate
+		currentIDs  []string
+		dc          map[string]config.DestinationConfig
+		expectedErr string
+	}{
+		{
+			"first load isn't checked",
+			&reloadGate{},
+			nil,
+			map[string]config.DestinationConfig{"pg_1": {Type: "postgres"}},
+			"",
+		},
+		{
+			"valid config",
+			&reloadGate{},
+			current,
+			valid("pg_1", "pg_5"),
+			"",
+		},
+		{
+			"invalid destination",
+			&reloadGate{},
+			current,
+			map[string]config.DestinationConfig{"pg_1": valid("pg_1")["pg_1"], "pg_2": {Type: "postgres", DataSource: map[string]interface{}{"host": "pg_2"}}},
+			"invalid destinations configs: [pg_2] datasource.db: is required; datasource.username: is required",
+		},
+		{
+			"unknown type",
+			&reloadGate{},
+			current,
+			map[string]config.DestinationConfig{"pg_1": {Type: "postgress"}},
+			"invalid destinations configs: [pg_1] type: ",
+		},
+		{
+			"all destinations removed",
+			&reloadGate{},
+			current,
+			map[string]config.DestinationConfig{},
+			"all 4 destinations would be removed",
+		},
+		{
+			"all destinations removed is allowed",
+			&reloadGate{allowEmpty: true},
+			current,
+			map[string]config.DestinationConfig{},
+			"",
+		},
+		{
+			"removed less than max percent",
+			&reloadGate{maxRemovedPercent: 50},
+			current,
+			valid("pg_1", "pg_2"),
+			"",
+		},
+		{
+			"removed more than max percent",
+			&reloadGate{maxRemovedPercent: 50},
+			current,
+			valid("pg_1"),
+			"3 of 4 destinations would be removed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.gate.validate(tt.currentIDs, tt.dc)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+			}
+		})
+	}
+}
@@ -10,16 +10,25 @@ import (
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logevents"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/spf13/viper"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
-const serviceName = "destinations"
+const (
+	serviceName = "destinations"
+	//queueDepthSamplingPeriod is a period of destinations events queues depth metrics sampling
+	queueDepthSamplingPeriod = 10 * time.Second
+)
+
 const marshallingErrorMsg = `Error initializing destinations: wrong config format: each destination must contains one key and config as a value(see https://docs.eventnative.dev/configuration) e.g. 
 destinations:  
   custom_name:
@@ -54,6 +63,15 @@ type Service struct {
 	queueConsumerByDestinationID map[string]events.Consumer
 
 	strictAuth bool
+	closed     chan struct{}
+
+	//reloadMutex guards reloading and pendingPayload: only one reload runs at a time
+	reloadMutex sync.Mutex
+	reloading   bool
+	//pendingPayload is the latest config received during the reload. It is applied once after the current reload
+	pendingPayload []byte
+	//reloadGate rejects invalid reloaded configs: the current destinations are kept
+	reloadGate *reloadGate
 }
 
 //NewTestService returns test instance. It is used only for tests
@@ -66,10 +84,12 @@ func NewTestService(unitsByID map[string]*Unit, consumersByTokenID TokenizedCons
 		batchStoragesByTokenID:       storagesByTokenID,
 		destinationsIDByTokenID:      destinationsIDByTokenID,
 		queueConsumerByDestinationID: queueConsumerByDestinationID,
+		reloadGate:                   &reloadGate{},
 	}
 }
 
-//NewService returns loaded Service instance and call resources.Watcher() if destinations source is http url or file path
+//NewService returns loaded Service instance and call resources.Watcher() if destinations source is http url, file path
+//or object storage (s3://bucket/key, gs://bucket/key) path
 func NewService(destinations *viper.Viper, destinationsSource string, storageFactory storages.Factory, loggerFactory *logevents.Factory, strictAuth bool) (*Service, error) {
 	service := &Service{
 		mutex: &sync.RWMutex{},
@@ -87,6 +107,12 @@ func NewService(destinations *viper.Viper, destinationsSource string, storageFac
 		queueConsumerByDestinationID: map[string]events.Consumer{},
 
 		strictAuth: strictAuth,
+		closed:     make(chan struct{}),
+
+		reloadGate: &reloadGate{
+			allowEmpty:        viper.GetBool("server.destinations_reload.allow_empty"),
+			maxRemovedPercent: viper.GetInt("server.destinations_reload.max_removed_percent"),
+		},
 	}
 
 	reloadSec := viper.GetInt("server.destinations_reload_sec")
@@ -94,6 +120,8 @@ func NewService(destinations *viper.Viper, destinationsSource string, storageFac
 		return nil, errors.New("server.destinations_reload_sec can't be empty")
 	}
 
+	safego.RunWithRestart(service.startQueueDepthMonitor)
+
 	if destinations != nil {
 		dc := map[string]config.DestinationConfig{}
 		if err := destinations.Unmarshal(&dc); err != nil {
@@ -101,7 +129,8 @@ func NewService(destinations *viper.Viper, destinationsSource string, storageFac
 			return service, nil
 		}
 
-		service.init(dc)
+		StatusInstance.startReloading()
+		StatusInstance.finishReloading(service.init(dc))
 
 		if len(service.unitsByID) == 0 {
 			logging.Info("Destinations are empty")
@@ -112,6 +141,12 @@ func NewService(destinations *viper.Viper, destinationsSource string, storageFac
 			appconfig.Instance.AuthorizationService.DestinationsForceReload = resources.Watch(serviceName, destinationsSource, resources.LoadFromHTTP, service.updateDestinations, time.Duration(reloadSec)*time.Second)
 		} else if strings.Contains(destinationsSource, "file://") || strings.HasPrefix(destinationsSource, "/") {
 			appconfig.Instance.AuthorizationService.DestinationsForceReload = resources.Watch(serviceName, strings.Replace(destinationsSource, "file://", "", 1), resources.LoadFromFile, service.updateDestinations, time.Duration(reloadSec)*time.Second)
+		} else if isObjectStorageSource(destinationsSource) {
+			loadFunc, err := newObjectStorageLoadFunc(destinationsSource, viper.Sub("server.destinations_storage"))
+			if err != nil {
+				return nil, err
+			}
+			appconfig.Instance.AuthorizationService.DestinationsForceReload = resources.Watch(serviceName, destinationsSource, loadFunc, service.updateDestinations, time.Duration(reloadSec)*time.Second)
 		} else if strings.HasPrefix(destinationsSource, "{") && strings.HasSuffix(destinationsSource, "}") {
 			service.updateDestinations([]byte(destinationsSource))
 		} else {
@@ -191,24 +226,226 @@ func (s *Service) GetEventsConsumerByDestinationID(destinationID string) (events
 
 }
 
+//GetStorages returns a snapshot of all storage proxies per destination ID
+func (s *Service) GetStorages() map[string]storages.StorageProxy {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	proxies := make(map[string]storages.StorageProxy, len(s.unitsByID))
+	for destinationID, unit := range s.unitsByID {
+		proxies[destinationID] = unit.storage
+	}
+
+	return proxies
+}
+
+//Stats returns lifetime counters per destination ID. Destinations which aren't initialized yet are skipped
+func (s *Service) Stats() map[string]storages.StorageStats {
+	proxies := s.GetStorages()
+	result := make(map[string]storages.StorageStats, len(proxies))
+	for destinationID, proxy := range proxies {
+		if storage, ok := proxy.Get(); ok {
+			result[destinationID] = storage.Stats()
+		}
+	}
+
+	return result
+}
+
+//Health returns health check result (nil if healthy) per destination ID
+//health checks are run without service lock because they might be slow
+func (s *Service) Health() map[string]error {
+	proxies := s.GetStorages()
+	result := make(map[string]error, len(proxies))
+	for destinationID, proxy := range proxies {
+		result[destinationID] = proxy.Health()
+	}
+
+	return result
+}
+
+//DumpRouting returns a read-locked snapshot of the routing tables for debugging: token ID -> destinations IDs,
+//token ID -> batch storages (id, type), token ID -> consumers, loggers usage per token ID and destinations.
+//Only identifiers and types are returned: destinations configurations (credentials) aren't included
+func (s *Service) DumpRouting() map[string]interface{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	destinationsByToken := map[string][]string{}
+	for tokenID, ids := range s.destinationsIDByTokenID {
+		destinationIDs := make([]string, 0, len(ids))
+		for id := range ids {
+			destinationIDs = append(destinationIDs, id)
+		}
+		sort.Strings(destinationIDs)
+		destinationsByToken[tokenID] = destinationIDs
+	}
+
+	storagesByToken := map[string][]map[string]string{}
+	for tokenID, proxies := range s.batchStoragesByTokenID {
+		storagesInfo := make([]map[string]string, 0, len(proxies))
+		for _, proxy := range proxies {
+			storagesInfo = append(storagesInfo, map[string]string{"id": proxy.ID(), "type": proxy.Type()})
+		}
+		sort.Slice(storagesInfo, func(i, j int) bool { return storagesInfo[i]["id"] < storagesInfo[j]["id"] })
+		storagesByToken[tokenID] = storagesInfo
+	}
+
+	consumersByToken := map[string][]string{}
+	for tokenID, consumers := range s.consumersByTokenID {
+		names := make([]string, 0, len(consumers))
+		for name := range consumers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		consumersByToken[tokenID] = names
+	}
+
+	loggersUsage := map[string]int{}
+	for tokenID, loggerUsage := range s.loggersUsageByTokenID {
+		loggersUsage[tokenID] = loggerUsage.usage
+	}
+
+	destinationsInfo := map[string]interface{}{}
+	for destinationID, unit := range s.unitsByID {
+		_, queueConsumer := s.queueConsumerByDestinationID[destinationID]
+		destinationsInfo[destinationID] = map[string]interface{}{
+			"type":           unit.destinationType,
+			"tokens":         unit.tokenIDs,
+			"paused":         unit.storage.IsPaused(),
+			"queue_consumer": queueConsumer,
+		}
+	}
+
+	return map[string]interface{}{
+		"destinations_by_token": destinationsByToken,
+		"storages_by_token":     storagesByToken,
+		"consumers_by_token":    consumersByToken,
+		"loggers_usage":         loggersUsage,
+		"destinations":          destinationsInfo,
+	}
+}
+
+//PauseDestination stops writes into the destination without its recreation: stream mode events are kept in the queue
+//and batch files are kept on disk until ResumeDestination is called. Paused state is kept on reloads if the destination
+//config isn't changed. Returns false if the destination doesn't exist
+func (s *Service) PauseDestination(id string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	unit, ok := s.unitsByID[id]
+	if !ok {
+		return false
+	}
+
+	unit.storage.Pause()
+	StatusInstance.setPaused(id, true)
+	logging.Infof("[%s] destination has been paused", id)
+	return true
+}
+
+//ResumeDestination resumes writes into the paused destination. Returns false if the destination doesn't exist
+func (s *Service) ResumeDestination(id string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	unit, ok := s.unitsByID[id]
+	if !ok {
+		return false
+	}
+
+	unit.storage.Resume()
+	StatusInstance.setPaused(id, false)
+	logging.Infof("[%s] destination has been resumed", id)
+	return true
+}
+
+//updateDestinations reloads destinations with the payload. Reloads don't overlap: if the previous reload is still running,
+//the payload is kept as pending and only the latest pending payload is applied once after the current reload
 func (s *Service) updateDestinations(payload []byte) {
+	s.reloadMutex.Lock()
+	if s.reloading {
+		s.pendingPayload = payload
+		s.reloadMutex.Unlock()
+		logging.Infof("Destinations are being reloaded: the latest config will be applied after the current reloading")
+		return
+	}
+	s.reloading = true
+	StatusInstance.startReloading()
+	s.reloadMutex.Unlock()
+
+	for {
+		destinationsCount, err := s.reload(payload)
+
+		s.reloadMutex.Lock()
+		if s.pendingPayload == nil {
+			s.reloading = false
+			//status is finished under the lock: a new reload can't start before
+			StatusInstance.finishReloading(destinationsCount, err)
+			s.reloadMutex.Unlock()
+			return
+		}
+		payload = s.pendingPayload
+		s.pendingPayload = nil
+		s.reloadMutex.Unlock()
+	}
+}
+
+//reload parses the payload and applies it with init()
+//returns destinations count and the last error (the current destinations are kept if the payload is invalid)
+func (s *Service) reload(payload []byte) (int, error) {
+	//resolve ${env.VAR} and ${file./path} placeholders (e.g. credentials) before parsing
+	payload, err := appconfig.ResolveJSONPlaceholders(payload)
+	if err != nil {
+		logging.Errorf("Error reloading destinations: %v. Current destinations will be kept", err)
+		return s.destinationsCount(), fmt.Errorf("Error resolving destinations config placeholders: %v", err)
+	}
+
 	dc, err := parseFromBytes(payload)
 	if err != nil {
 		logging.Error(marshallingErrorMsg, err)
-		return
+		return s.destinationsCount(), fmt.Errorf("Error parsing destinations config: %v", err)
 	}
 
-	s.init(dc)
+	//validate before swap: a broken config mustn't replace working destinations
+	if err := s.reloadGate.validate(s.destinationIDs(), dc); err != nil {
+		logging.SystemErrorf("Destinations config has been rejected: %v. Current destinations will be kept", err)
+		return s.destinationsCount(), fmt.Errorf("Destinations config has been rejected: %v", err)
+	}
+
+	destinationsCount, err := s.init(dc)
 
-	if len(s.unitsByID) == 0 {
+	if destinationsCount == 0 {
 		logging.Info("Destinations are empty")
 	}
+
+	return destinationsCount, err
+}
+
+func (s *Service) destinationsCount() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.unitsByID)
+}
+
+func (s *Service) destinationIDs() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ids := make([]string, 0, len(s.unitsByID))
+	for id := range s.unitsByID {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 //1. close and remove all destinations which don't exist in new config
 //2. recreate/create changed/new destinations
-func (s *Service) init(dc map[string]config.DestinationConfig) {
-	StatusInstance.Reloading = true
+//returns destinations count and the last destination initialization error
+func (s *Service) init(dc map[string]config.DestinationConfig) (int, error) {
+	//the last destination initialization error
+	var lastErr error
 
 	//close and remove non-existent (in new config)
 	toDelete := map[string]*Unit{}
@@ -245,22 +482,40 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 			destinationConfig.OnlyTokens = appconfig.Instance.AuthorizationService.GetAllTokenIDs()
 		}
 
+		//token ids don't affect the storage: hash is calculated without them
+		//so token membership changes are applied without the destination recreation
+		tokenIDs := destinationConfig.OnlyTokens
+		destinationConfig.OnlyTokens = nil
 		hash, err := resources.GetHash(destinationConfig)
+		destinationConfig.OnlyTokens = tokenIDs
 		if err != nil {
 			logging.SystemErrorf("Error getting hash from [%s] destination: %v. Destination will be skipped!", id, err)
+			lastErr = fmt.Errorf("[%s] Error getting hash: %v", id, err)
 			continue
 		}
 
 		unit, ok := s.unitsByID[id]
-		if ok {
-			if unit.hash == hash {
-				//destination wasn't changed
-				continue
-			}
-			//remove old (for recreation)
+		if ok && unit.hash == hash {
+			//destination wasn't changed or only token ids were changed
 			s.mutex.Lock()
-			s.removeAndClose(id, unit)
+			s.reassignTokens(id, unit, &destinationConfig)
 			s.mutex.Unlock()
+			continue
+		}
+
+		//invalid config must fail destination initialization with all field errors at once
+		//config and filter are validated before the recreation so the current destination is kept
+		if errs := storages.ValidateDestination(id, &destinationConfig); len(errs) > 0 {
+			lastErr = fmt.Errorf("[%s] Error initializing destination of type %s: invalid config: %v", id, destinationConfig.Type, errs)
+			logging.Error(lastErr)
+			continue
+		}
+
+		//invalid filter must fail destination initialization rather than every event processing
+		if err := schema.ValidateFilter(id, destinationConfig.Type, destinationConfig.Filter); err != nil {
+			lastErr = fmt.Errorf("[%s] Error initializing destination of type %s: invalid filter: %v", id, destinationConfig.Type, err)
+			logging.Error(lastErr)
+			continue
 		}
 
 		if !s.strictAuth && len(destinationConfig.OnlyTokens) == 0 {
@@ -273,18 +528,28 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 		//create new
 		newStorageProxy, eventQueue, err := s.storageFactory.Create(id, destinationConfig)
 		if err != nil {
-			logging.Errorf("[%s] Error initializing destination of type %s: %v", id, destinationConfig.Type, err)
+			lastErr = fmt.Errorf("[%s] Error initializing destination of type %s: %v", id, destinationConfig.Type, logging.RedactError(err, destinationConfig))
+			logging.Error(lastErr)
 			continue
 		}
 		appconfig.Instance.ScheduleEventsConsumerClosing(eventQueue)
 
+		if ok {
+			//remove old (for recreation) only after the new one has been created
+			s.mutex.Lock()
+			s.removeAndClose(id, unit)
+			s.mutex.Unlock()
+		}
+
 		queueConsumerByDestinationID[id] = eventQueue
-		s.unitsByID[id] = &Unit{
-			eventQueue: eventQueue,
-			storage:    newStorageProxy,
-			tokenIDs:   destinationConfig.OnlyTokens,
-			hash:       hash,
+		newUnit := &Unit{
+			eventQueue:      eventQueue,
+			storage:         newStorageProxy,
+			destinationType: destinationConfig.Type,
+			tokenIDs:        destinationConfig.OnlyTokens,
+			hash:            hash,
 		}
+		s.unitsByID[id] = newUnit
 
 		//create:
 		//  1 logger per token id
@@ -299,28 +564,7 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 				logging.Warnf("[%s] Skipping consumer creation for staged destination", id)
 				continue
 			}
-			newIDs.Add(tokenID, id)
-			if destinationConfig.Mode == storages.StreamMode {
-				newConsumers.Add(tokenID, id, eventQueue)
-			} else {
-				//get or create new logger
-				loggerUsage, ok := s.loggersUsageByTokenID[tokenID]
-				if !ok {
-					incomeLogger := s.loggerFactory.CreateIncomingLogger(tokenID)
-					appconfig.Instance.ScheduleEventsConsumerClosing(incomeLogger)
-					loggerUsage = &LoggerUsage{logger: incomeLogger, usage: 0}
-					s.loggersUsageByTokenID[tokenID] = loggerUsage
-				}
-
-				if loggerUsage != nil {
-					loggerUsage.usage += 1
-					//2 destinations with only 1 logger can be under 1 tokenID
-					newConsumers.Add(tokenID, tokenID, loggerUsage.logger)
-				}
-
-				//add storage only if batch mode
-				newStorages.Add(tokenID, id, newStorageProxy)
-			}
+			s.linkToken(tokenID, id, &destinationConfig, newUnit, newConsumers, newStorages, newIDs)
 		}
 	}
 
@@ -332,9 +576,114 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 	for destinationID, eventsQueueConsumer := range queueConsumerByDestinationID {
 		s.queueConsumerByDestinationID[destinationID] = eventsQueueConsumer
 	}
+	destinationsCount := len(s.unitsByID)
 	s.mutex.Unlock()
 
-	StatusInstance.Reloading = false
+	return destinationsCount, lastErr
+}
+
+//linkToken adds destination consumer (events queue or token logger), storage (only batch mode) and id
+//into the input token collections
+func (s *Service) linkToken(tokenID, destinationID string, destinationConfig *config.DestinationConfig, unit *Unit,
+	consumers TokenizedConsumers, batchStorages TokenizedStorages, ids TokenizedIDs) {
+	ids.Add(tokenID, destinationID)
+	if destinationConfig.Mode == storages.StreamMode {
+		consumers.Add(tokenID, destinationID, unit.eventQueue)
+		return
+	}
+
+	//get or create new logger
+	loggerUsage, ok := s.loggersUsageByTokenID[tokenID]
+	if !ok {
+		incomeLogger := s.loggerFactory.CreateIncomingLogger(tokenID)
+		appconfig.Instance.ScheduleEventsConsumerClosing(incomeLogger)
+		loggerUsage = &LoggerUsage{logger: incomeLogger, usage: 0}
+		s.loggersUsageByTokenID[tokenID] = loggerUsage
+	}
+
+	if loggerUsage != nil {
+		loggerUsage.usage += 1
+		//2 destinations with only 1 logger can be under 1 tokenID
+		consumers.Add(tokenID, tokenID, loggerUsage.logger)
+	}
+
+	//add storage only if batch mode
+	batchStorages.Add(tokenID, destinationID, unit.storage)
+}
+
+//unlinkToken removes destination consumer, storage and id from token collections. Closes token logger if it isn't used anymore
+//method must be called with locks
+func (s *Service) unlinkToken(tokenID, destinationID string, destinationConfig *config.DestinationConfig) {
+	consumers := s.consumersByTokenID[tokenID]
+	if destinationConfig.Mode == storages.StreamMode {
+		delete(consumers, destinationID)
+	} else if loggerUsage, ok := s.loggersUsageByTokenID[tokenID]; ok {
+		loggerUsage.usage -= 1
+		if loggerUsage.usage == 0 {
+			delete(consumers, tokenID)
+			delete(s.loggersUsageByTokenID, tokenID)
+			loggerUsage.logger.Close()
+		}
+	}
+	if len(consumers) == 0 {
+		delete(s.consumersByTokenID, tokenID)
+	}
+
+	if batchStorages, ok := s.batchStoragesByTokenID[tokenID]; ok {
+		delete(batchStorages, destinationID)
+		if len(batchStorages) == 0 {
+			delete(s.batchStoragesByTokenID, tokenID)
+		}
+	}
+
+	if ids, ok := s.destinationsIDByTokenID[tokenID]; ok {
+		delete(ids, destinationID)
+		if len(ids) == 0 {
+			delete(s.destinationsIDByTokenID, tokenID)
+		}
+	}
+}
+
+//reassignTokens updates token collections in place according to the new destination token ids
+//without the destination storage and events queue recreation. New tokens are linked before old ones are unlinked
+//so the destination doesn't miss events during reloading
+//method must be called with locks
+func (s *Service) reassignTokens(destinationID string, unit *Unit, destinationConfig *config.DestinationConfig) {
+	newTokenIDs := map[string]bool{}
+	for _, tokenID := range destinationConfig.OnlyTokens {
+		newTokenIDs[tokenID] = true
+	}
+	oldTokenIDs := map[string]bool{}
+	for _, tokenID := range unit.tokenIDs {
+		oldTokenIDs[tokenID] = true
+	}
+
+	var added, removed []string
+	for _, tokenID := range destinationConfig.OnlyTokens {
+		if !oldTokenIDs[tokenID] {
+			added = append(added, tokenID)
+		}
+	}
+	for _, tokenID := range unit.tokenIDs {
+		if !newTokenIDs[tokenID] {
+			removed = append(removed, tokenID)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	if !destinationConfig.Staged {
+		for _, tokenID := range added {
+			s.linkToken(tokenID, destinationID, destinationConfig, unit, s.consumersByTokenID, s.batchStoragesByTokenID, s.destinationsIDByTokenID)
+		}
+		for _, tokenID := range removed {
+			s.unlinkToken(tokenID, destinationID, destinationConfig)
+		}
+	}
+
+	unit.tokenIDs = destinationConfig.OnlyTokens
+	logging.Infof("[%s] destination token ids have been reassigned: added %v, removed %v", destinationID, added, removed)
 }
 
 //removeAndClose removes and closes destination from all collections and close it
@@ -387,15 +736,55 @@ func (s *Service) removeAndClose(destinationID string, unit *Unit) {
 	}
 
 	delete(s.unitsByID, destinationID)
+	StatusInstance.setPaused(destinationID, false)
+	metrics.RemoveDestinationQueueDepth(unit.destinationType, destinationID)
 	logging.Infof("[%s] destination has been removed!", destinationID)
 }
 
+//startQueueDepthMonitor periodically samples destinations events queues depth into metrics
+func (s *Service) startQueueDepthMonitor() {
+	ticker := time.NewTicker(queueDepthSamplingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			s.sampleQueueDepth()
+		}
+	}
+}
+
+//sampleQueueDepth gets queues size without locks because it might be a network call (e.g. Redis queue)
+func (s *Service) sampleQueueDepth() {
+	s.mutex.RLock()
+	units := make(map[string]*Unit, len(s.unitsByID))
+	for destinationID, unit := range s.unitsByID {
+		units[destinationID] = unit
+	}
+	s.mutex.RUnlock()
+
+	for destinationID, unit := range units {
+		if unit.eventQueue != nil {
+			metrics.SetDestinationQueueDepth(unit.destinationType, destinationID, unit.eventQueue.Size())
+		}
+	}
+}
+
 func (s *Service) GetFactory() storages.Factory {
 	return s.storageFactory
 }
 
 //Close closes destination storages
 func (s *Service) Close() (multiErr error) {
+	if s.closed != nil {
+		select {
+		case <-s.closed:
+		default:
+			close(s.closed)
+		}
+	}
+
 	for id, unit := range s.unitsByID {
 		if err := unit.CloseStorage(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing destination unit storage: %v", id, err))
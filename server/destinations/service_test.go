@@ -1,14 +1,19 @@
 package destinations
 
 import (
+	"fmt"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logevents"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 )
@@ -61,7 +66,9 @@ func TestServiceInit(t *testing.T) {
         "token2"
       ],
       "datasource": {
-        "host": "host_redshift_1"
+        "host": "host_redshift_1",
+        "db": "db",
+        "username": "user"
       }
     },
     "pg_1": {
@@ -71,7 +78,9 @@ func TestServiceInit(t *testing.T) {
         "token3"
       ],
       "datasource": {
-        "host": "host_pg_1"
+        "host": "host_pg_1",
+        "db": "db",
+        "username": "user"
       }
     },
     "pg_2": {
@@ -81,7 +90,9 @@ func TestServiceInit(t *testing.T) {
         "token3"
       ],
       "datasource": {
-        "host": "host_pg_2"
+        "host": "host_pg_2",
+        "db": "db",
+        "username": "user"
       }
     },
 	"pg_with_unknown_token": {
@@ -91,7 +102,9 @@ func TestServiceInit(t *testing.T) {
         "token5"
       ],
       "datasource": {
-        "host": "host_pg_with_unknown_token"
+        "host": "host_pg_with_unknown_token",
+        "db": "db",
+        "username": "user"
       }
     }
   }
@@ -101,7 +114,10 @@ func TestServiceInit(t *testing.T) {
 
 	loggerFactory := logevents.NewFactory("/tmp", 5, false, nil, nil, false, 1)
 	destinationsMockFactory := storages.NewMockFactory()
+	//the test removes all destinations on purpose
+	viper.Set("server.destinations_reload.allow_empty", true)
 	service, err := NewService(nil, mockDestinationsServer.URL, destinationsMockFactory, loggerFactory, false)
+	viper.Set("server.destinations_reload.allow_empty", false)
 	require.NoError(t, err)
 	require.NotNil(t, service)
 
@@ -109,6 +125,7 @@ func TestServiceInit(t *testing.T) {
 	//wasn't changed
 	time.Sleep(1 * time.Second)
 	initialConfigAsserts(t, service)
+	pg1Unit := service.unitsByID["pg_1"]
 
 	//change
 	changedDestinations := `{
@@ -121,7 +138,9 @@ func TestServiceInit(t *testing.T) {
         "token4"
       ],
       "datasource": {
-        "host": "host_pg_1"
+        "host": "host_pg_1",
+        "db": "db",
+        "username": "user"
       }
     },
     "pg_2": {
@@ -131,7 +150,9 @@ func TestServiceInit(t *testing.T) {
         "token3"
       ],
       "datasource": {
-        "host": "host_pg_2"
+        "host": "host_pg_2",
+        "db": "db",
+        "username": "user"
       }
     },
     "pg_3": {
@@ -141,7 +162,9 @@ func TestServiceInit(t *testing.T) {
         "token4"
       ],
       "datasource": {
-        "host": "host_pg_3"
+        "host": "host_pg_3",
+        "db": "db",
+        "username": "user"
       }
     },
     "pg_4": {
@@ -150,7 +173,9 @@ func TestServiceInit(t *testing.T) {
         "token3"
       ],
       "datasource": {
-        "host": "host_pg_4"
+        "host": "host_pg_4",
+        "db": "db",
+        "username": "user"
       }
     },
     "pg_5": {
@@ -160,7 +185,9 @@ func TestServiceInit(t *testing.T) {
         "token3"
       ],
       "datasource": {
-        "host": "host_pg_5"
+        "host": "host_pg_5",
+        "db": "db",
+        "username": "user"
       }
     }
   }
@@ -168,6 +195,8 @@ func TestServiceInit(t *testing.T) {
 	payload.payload = []byte(changedDestinations)
 	time.Sleep(2 * time.Second)
 	changedConfigAsserts(t, service)
+	//only token ids were changed
+	require.True(t, pg1Unit == service.unitsByID["pg_1"], "pg_1 destination must not be recreated")
 
 	//add new token to authorization
 	fullAuth := `{
@@ -211,6 +240,117 @@ func TestServiceInit(t *testing.T) {
 	emptyConfigAsserts(t, service)
 }
 
+//slowMockFactory creates mock storages slowly and tracks concurrent creations
+type slowMockFactory struct {
+	storages.Factory
+
+	mutex         sync.Mutex
+	running       int
+	maxConcurrent int
+}
+
+func (smf *slowMockFactory) Create(id string, destination config.DestinationConfig) (storages.StorageProxy, events.Queue, error) {
+	smf.mutex.Lock()
+	smf.running++
+	if smf.running > smf.maxConcurrent {
+		smf.maxConcurrent = smf.running
+	}
+	smf.mutex.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	smf.mutex.Lock()
+	smf.running--
+	smf.mutex.Unlock()
+	return smf.Factory.Create(id, destination)
+}
+
+func TestServiceOverlappingReloads(t *testing.T) {
+	viper.Set("server.destinations_reload_sec", 1)
+	viper.Set("server.log.path", "")
+	appconfig.Init(false, "")
+
+	factory := &slowMockFactory{Factory: storages.NewMockFactory()}
+	service, err := NewService(nil, "", factory, logevents.NewFactory("/tmp", 5, false, nil, nil, false, 1), true)
+	require.NoError(t, err)
+
+	destinationsPayload := func(ids ...string) []byte {
+		payload := `{"destinations": {`
+		for i, id := range ids {
+			if i > 0 {
+				payload += ","
+			}
+			payload += fmt.Sprintf(`"%s": {"type": "postgres", "datasource": {"host": "%s", "db": "db", "username": "user"}}`, id, id)
+		}
+		return []byte(payload + `}}`)
+	}
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		close(started)
+		service.updateDestinations(destinationsPayload("pg_1", "pg_2", "pg_3", "pg_4", "pg_5"))
+		close(finished)
+	}()
+	isReloading := func() bool {
+		service.reloadMutex.Lock()
+		defer service.reloadMutex.Unlock()
+		return service.reloading
+	}
+	<-started
+	require.Eventually(t, isReloading, time.Second, time.Millisecond)
+
+	//configs received during the reloading don't start overlapping reloads: only the latest one is applied
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			service.updateDestinations(destinationsPayload(fmt.Sprintf("pg_intermediate_%d", i)))
+		}(i)
+	}
+	wg.Wait()
+	service.updateDestinations(destinationsPayload("pg_1", "pg_latest"))
+	require.True(t, isReloading(), "the first reloading must still be running")
+
+	<-finished
+	require.False(t, isReloading())
+	require.Nil(t, service.pendingPayload)
+	require.Equal(t, 1, factory.maxConcurrent, "reloads mustn't overlap")
+
+	ids := map[string]bool{}
+	for id := range service.GetStorages() {
+		ids[id] = true
+	}
+	require.Equal(t, map[string]bool{"pg_1": true, "pg_latest": true}, ids)
+}
+
+func TestServiceRejectedReloadKeepsDestinations(t *testing.T) {
+	viper.Set("server.destinations_reload_sec", 1)
+	viper.Set("server.log.path", "")
+	appconfig.Init(false, "")
+
+	service, err := NewService(nil, "", storages.NewMockFactory(), logevents.NewFactory("/tmp", 5, false, nil, nil, false, 1), true)
+	require.NoError(t, err)
+
+	count, err := service.reload([]byte(`{"destinations": {"pg_1": {"type": "postgres", "datasource": {"host": "pg_1", "db": "db", "username": "user"}}}}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	pg1Unit := service.unitsByID["pg_1"]
+
+	//a typo in the type
+	count, err = service.reload([]byte(`{"destinations": {"pg_1": {"type": "postgress", "datasource": {"host": "pg_1", "db": "db", "username": "user"}}}}`))
+	require.Error(t, err)
+	require.Equal(t, 1, count)
+	require.True(t, pg1Unit == service.unitsByID["pg_1"], "pg_1 destination must be kept")
+
+	//a typo in the root key removes all destinations
+	count, err = service.reload([]byte(`{"destination": {"pg_1": {"type": "postgres", "datasource": {"host": "pg_1", "db": "db", "username": "user"}}}}`))
+	require.Error(t, err)
+	require.Equal(t, 1, count)
+	require.True(t, pg1Unit == service.unitsByID["pg_1"], "pg_1 destination must be kept")
+}
+
 func initialConfigAsserts(t *testing.T, service *Service) {
 	require.Equal(t, 3, len(service.batchStoragesByTokenID))
 	require.Equal(t, 3, len(service.consumersByTokenID))
@@ -286,3 +426,223 @@ func startTestServer(ph *payloadHolder) *httptest.Server {
 			w.Write(ph.payload)
 		}))
 }
+
+func TestReloadStatus(t *testing.T) {
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+	now := timestamp.ToISOFormat(timestamp.Now().UTC())
+
+	tests := []struct {
+		name     string
+		update   func(s *Status)
+		expected ReloadStatus
+	}{
+		{
+			"never reloaded",
+			func(s *Status) {},
+			ReloadStatus{},
+		},
+		{
+			"reloading",
+			func(s *Status) { s.startReloading() },
+			ReloadStatus{Reloading: true, StartedAt: now},
+		},
+		{
+			"reloaded",
+			func(s *Status) {
+				s.startReloading()
+				s.finishReloading(3, nil)
+			},
+			ReloadStatus{StartedAt: now, FinishedAt: now, DestinationsCount: 3},
+		},
+		{
+			"reloaded with error",
+			func(s *Status) {
+				s.startReloading()
+				s.finishReloading(2, fmt.Errorf("destination [pg] init error"))
+			},
+			ReloadStatus{StartedAt: now, FinishedAt: now, DestinationsCount: 2, LastError: "destination [pg] init error"},
+		},
+		{
+			"error is cleared by the next successful reload",
+			func(s *Status) {
+				s.startReloading()
+				s.finishReloading(2, fmt.Errorf("destination [pg] init error"))
+				s.startReloading()
+				s.finishReloading(3, nil)
+			},
+			ReloadStatus{StartedAt: now, FinishedAt: now, DestinationsCount: 3},
+		},
+		{
+			"stuck reloading keeps the previous result",
+			func(s *Status) {
+				s.startReloading()
+				s.finishReloading(3, nil)
+				s.startReloading()
+			},
+			ReloadStatus{Reloading: true, StartedAt: now, FinishedAt: now, DestinationsCount: 3},
+		},
+		{
+			"paused destinations are sorted",
+			func(s *Status) {
+				s.setPaused("sf", true)
+				s.setPaused("bq", true)
+				s.setPaused("pg", true)
+				s.setPaused("pg", false)
+			},
+			ReloadStatus{PausedDestinations: []string{"bq", "sf"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Status{mutex: &sync.RWMutex{}, paused: map[string]bool{}}
+			tt.update(s)
+			require.Equal(t, tt.expected, s.Get())
+			require.Equal(t, tt.expected.Reloading, s.IsReloading())
+		})
+	}
+
+	//duration of the last finished reload
+	s := &Status{mutex: &sync.RWMutex{}, paused: map[string]bool{}}
+	s.startedAt = time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.finishedAt = s.startedAt.Add(1500 * time.Millisecond)
+	require.Equal(t, 1.5, s.Get().DurationSeconds)
+}
+
+//testSizedQueue counts Size calls. Size takes the service lock for checking that queues are sampled without the lock
+type testSizedQueue struct {
+	events.Queue
+
+	service   *Service
+	size      int64
+	sizeCalls int
+}
+
+func (tsq *testSizedQueue) Size() int64 {
+	tsq.service.mutex.Lock()
+	tsq.sizeCalls++
+	tsq.service.mutex.Unlock()
+	return tsq.size
+}
+
+func TestSampleQueueDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		units map[string]bool
+	}{
+		{"no destinations", map[string]bool{}},
+		{"stream destinations", map[string]bool{"pg": true, "sf": true}},
+		{"batch destination without queue", map[string]bool{"pg": true, "s3": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewTestService(map[string]*Unit{}, nil, nil, nil, nil)
+			queues := map[string]*testSizedQueue{}
+			for destinationID, withQueue := range tt.units {
+				unit := &Unit{destinationType: "postgres"}
+				if withQueue {
+					queues[destinationID] = &testSizedQueue{service: service, size: 10}
+					unit.eventQueue = queues[destinationID]
+				}
+				service.unitsByID[destinationID] = unit
+			}
+
+			done := make(chan struct{})
+			go func() {
+				service.sampleQueueDepth()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("queues depth must be sampled without holding the service lock")
+			}
+
+			for destinationID, queue := range queues {
+				require.Equal(t, 1, queue.sizeCalls, destinationID)
+			}
+		})
+	}
+}
+
+//testRoutingProxy is a storage proxy with identifiers and paused state
+type testRoutingProxy struct {
+	storages.StorageProxy
+
+	id              string
+	destinationType string
+	paused          bool
+}
+
+func (trp *testRoutingProxy) ID() string     { return trp.id }
+func (trp *testRoutingProxy) Type() string   { return trp.destinationType }
+func (trp *testRoutingProxy) IsPaused() bool { return trp.paused }
+
+func TestDumpRouting(t *testing.T) {
+	pg := &testRoutingProxy{id: "pg", destinationType: "postgres"}
+	s3 := &testRoutingProxy{id: "s3", destinationType: "s3", paused: true}
+	sf := &testRoutingProxy{id: "sf", destinationType: "snowflake"}
+
+	tests := []struct {
+		name     string
+		service  *Service
+		expected map[string]interface{}
+	}{
+		{
+			"Empty",
+			NewTestService(map[string]*Unit{}, TokenizedConsumers{}, TokenizedStorages{}, TokenizedIDs{}, map[string]events.Consumer{}),
+			map[string]interface{}{
+				"destinations_by_token": map[string][]string{},
+				"storages_by_token":     map[string][]map[string]string{},
+				"consumers_by_token":    map[string][]string{},
+				"loggers_usage":         map[string]int{},
+				"destinations":          map[string]interface{}{},
+			},
+		},
+		{
+			"Destinations per token",
+			&Service{
+				mutex: &sync.RWMutex{},
+				unitsByID: map[string]*Unit{
+					"pg": {storage: pg, destinationType: "postgres", tokenIDs: []string{"token1", "token2"}},
+					"s3": {storage: s3, destinationType: "s3", tokenIDs: []string{"token1"}},
+					"sf": {storage: sf, destinationType: "snowflake", tokenIDs: []string{"token2"}},
+				},
+				loggersUsageByTokenID: map[string]*LoggerUsage{"token1": {usage: 2}},
+				consumersByTokenID:    TokenizedConsumers{"token1": {"pg": nil}, "token2": {"sf": nil, "pg": nil}},
+				batchStoragesByTokenID: TokenizedStorages{
+					"token1": {"s3": s3, "sf": sf},
+				},
+				destinationsIDByTokenID: TokenizedIDs{
+					"token1": {"s3": true, "pg": true},
+					"token2": {"sf": true, "pg": true},
+				},
+				queueConsumerByDestinationID: map[string]events.Consumer{"pg": nil, "sf": nil},
+			},
+			map[string]interface{}{
+				"destinations_by_token": map[string][]string{
+					"token1": {"pg", "s3"},
+					"token2": {"pg", "sf"},
+				},
+				"storages_by_token": map[string][]map[string]string{
+					"token1": {{"id": "s3", "type": "s3"}, {"id": "sf", "type": "snowflake"}},
+				},
+				"consumers_by_token": map[string][]string{
+					"token1": {"pg"},
+					"token2": {"pg", "sf"},
+				},
+				"loggers_usage": map[string]int{"token1": 2},
+				"destinations": map[string]interface{}{
+					"pg": map[string]interface{}{"type": "postgres", "tokens": []string{"token1", "token2"}, "paused": false, "queue_consumer": true},
+					"s3": map[string]interface{}{"type": "s3", "tokens": []string{"token1"}, "paused": true, "queue_consumer": false},
+					"sf": map[string]interface{}{"type": "snowflake", "tokens": []string{"token2"}, "paused": false, "queue_consumer": true},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.service.DumpRouting())
+		})
+	}
+}
@@ -1,9 +1,108 @@
 package destinations
 
-var StatusInstance = &Status{Reloading: false}
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+var StatusInstance = &Status{mutex: &sync.RWMutex{}, paused: map[string]bool{}}
 
 //Status is a singleton struct for storing destinations reloading state.
-//Uploader checks this flag and doesn't upload batch files if Reloading = true
+//Uploader checks this flag and doesn't upload batch files if IsReloading() = true
 type Status struct {
-	Reloading bool
+	mutex *sync.RWMutex
+
+	reloading         bool
+	startedAt         time.Time
+	finishedAt        time.Time
+	destinationsCount int
+	lastError         string
+	//paused is a set of paused destinations IDs
+	paused map[string]bool
+}
+
+//ReloadStatus is a dto for destinations reloading state
+//Reloading = true with old started_at means that the reloading is stuck
+type ReloadStatus struct {
+	Reloading         bool    `json:"reloading"`
+	StartedAt         string  `json:"last_reload_started_at,omitempty"`
+	FinishedAt        string  `json:"last_reload_finished_at,omitempty"`
+	DurationSeconds   float64 `json:"last_reload_duration_seconds"`
+	DestinationsCount int     `json:"destinations_count"`
+	LastError         string  `json:"last_reload_error,omitempty"`
+	//PausedDestinations is a sorted list of paused destinations IDs
+	PausedDestinations []string `json:"paused_destinations,omitempty"`
+}
+
+//IsReloading returns true if destinations are being reloaded right now
+func (s *Status) IsReloading() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.reloading
+}
+
+//Get returns a snapshot of the current reloading state
+func (s *Status) Get() ReloadStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rs := ReloadStatus{
+		Reloading:         s.reloading,
+		DestinationsCount: s.destinationsCount,
+		LastError:         s.lastError,
+	}
+	if !s.startedAt.IsZero() {
+		rs.StartedAt = timestamp.ToISOFormat(s.startedAt)
+	}
+	for destinationID := range s.paused {
+		rs.PausedDestinations = append(rs.PausedDestinations, destinationID)
+	}
+	sort.Strings(rs.PausedDestinations)
+	if !s.finishedAt.IsZero() {
+		rs.FinishedAt = timestamp.ToISOFormat(s.finishedAt)
+		if !s.finishedAt.Before(s.startedAt) {
+			rs.DurationSeconds = s.finishedAt.Sub(s.startedAt).Seconds()
+		}
+	}
+
+	return rs
+}
+
+func (s *Status) startReloading() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.reloading = true
+	s.startedAt = timestamp.Now().UTC()
+}
+
+//finishReloading writes reloading result. err is the last error occurred during reloading (nil if there were no errors)
+func (s *Status) finishReloading(destinationsCount int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.reloading = false
+	s.finishedAt = timestamp.Now().UTC()
+	s.destinationsCount = destinationsCount
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		s.lastError = ""
+	}
+}
+
+//setPaused adds or removes the destination from the paused set
+func (s *Status) setPaused(destinationID string, paused bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if paused {
+		s.paused[destinationID] = true
+	} else {
+		delete(s.paused, destinationID)
+	}
 }
@@ -9,8 +9,9 @@ import (
 
 //Unit holds storage bundle for closing at once
 type Unit struct {
-	eventQueue events.Queue
-	storage    storages.StorageProxy
+	eventQueue      events.Queue
+	storage         storages.StorageProxy
+	destinationType string
 
 	tokenIDs []string
 	hash     uint64
@@ -0,0 +1,37 @@
+package dlq
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//Failure classifications
+const (
+	//ClassificationTransient is a temporary error (e.g. connection problems)
+	ClassificationTransient = "transient"
+	//ClassificationBadData is an error caused by data (e.g. values which don't match column types)
+	ClassificationBadData = "bad_data"
+	//ClassificationConfig is an error caused by broken authorization or configuration
+	ClassificationConfig = "config"
+	//ClassificationProcessing is an error of events processing (mappings, transformations, table name templates)
+	ClassificationProcessing = "processing"
+	//ClassificationMalformed is an error of parsing malformed (not valid JSON) events
+	ClassificationMalformed = "malformed"
+	//ClassificationUnknown is used if an error isn't classified
+	ClassificationUnknown = "unknown"
+)
+
+//Record is a structured dead-letter queue record of an event which failed to be stored
+type Record struct {
+	Event          json.RawMessage `json:"event,omitempty"`
+	MalformedEvent string          `json:"malformed_event,omitempty"`
+	EventID        string          `json:"event_id,omitempty"`
+	DestinationID  string          `json:"destination_id"`
+	TableName      string          `json:"table_name,omitempty"`
+	Error          string          `json:"error"`
+	Classification string          `json:"classification"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Attempt        int             `json:"attempt"`
+	//CorrelationID is a batch file name or an event ID which prefixes the pipeline log messages (see logging.Context)
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
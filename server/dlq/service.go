@@ -0,0 +1,131 @@
+package dlq
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logevents"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	fileMask     = "dlq.dst=*.log"
+	defaultLimit = 100
+	//maxLineSize is a max DLQ record size (events might be large)
+	maxLineSize = 10 * 1024 * 1024
+)
+
+//Filter is a DLQ records filter. Empty values aren't applied
+type Filter struct {
+	DestinationIDs map[string]bool
+	TableName      string
+	Classification string
+	//Reason is a case-insensitive substring of the error
+	Reason string
+	Start  time.Time
+	End    time.Time
+	Limit  int
+}
+
+//match returns true if the record matches all filter conditions
+func (f *Filter) match(record *Record) bool {
+	if len(f.DestinationIDs) > 0 && !f.DestinationIDs[record.DestinationID] {
+		return false
+	}
+	if f.TableName != "" && f.TableName != record.TableName {
+		return false
+	}
+	if f.Classification != "" && f.Classification != record.Classification {
+		return false
+	}
+	if f.Reason != "" && !strings.Contains(strings.ToLower(record.Error), strings.ToLower(f.Reason)) {
+		return false
+	}
+	if !f.Start.IsZero() && record.Timestamp.Before(f.Start) {
+		return false
+	}
+	if !f.End.IsZero() && record.Timestamp.After(f.End) {
+		return false
+	}
+	return true
+}
+
+//Service reads dead-letter queue records from the DLQ sink (logs/events/dlq dir)
+type Service struct {
+	dir string
+}
+
+//NewService returns configured Service
+func NewService(logEventPath string) *Service {
+	return &Service{dir: path.Join(logEventPath, logevents.DLQDir)}
+}
+
+//List returns DLQ records which match the filter sorted by timestamp (newest first)
+//returns not more than filter.Limit records (100 by default)
+func (s *Service) List(filter *Filter) ([]*Record, error) {
+	files, err := filepath.Glob(path.Join(s.dir, fileMask))
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*Record{}
+	for _, filePath := range files {
+		fileRecords, err := readFile(filePath, filter)
+		if err != nil {
+			logging.Errorf("Error reading DLQ file [%s]: %v", filePath, err)
+			continue
+		}
+		records = append(records, fileRecords...)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.After(records[j].Timestamp)
+	})
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+//readFile returns all file records which match the filter. Malformed lines are skipped
+func readFile(filePath string, filter *Filter) ([]*Record, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		record := &Record{}
+		if err := json.Unmarshal(line, record); err != nil {
+			logging.Warnf("Malformed DLQ record in file [%s]: %v", filePath, err)
+			continue
+		}
+
+		if filter.match(record) {
+			records = append(records, record)
+		}
+	}
+
+	return records, scanner.Err()
+}
@@ -0,0 +1,69 @@
+package dlq
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logevents"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	logEventPath, err := ioutil.TempDir("", "dlq")
+	require.NoError(t, err)
+	defer os.RemoveAll(logEventPath)
+
+	dir := path.Join(logEventPath, logevents.DLQDir)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	t1 := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	writeRecords(t, path.Join(dir, "dlq.dst=pg.log"),
+		&Record{EventID: "1", DestinationID: "pg", TableName: "events", Error: "Numeric value 'abc' is not recognized", Classification: ClassificationBadData, Timestamp: t1, Attempt: 1},
+		&Record{EventID: "2", DestinationID: "pg", TableName: "pageviews", Error: "connection refused", Classification: ClassificationTransient, Timestamp: t1.Add(time.Hour), Attempt: 1},
+	)
+	writeRecords(t, path.Join(dir, "dlq.dst=sf-2021-10-01T10-00-00.000.log"),
+		&Record{EventID: "3", DestinationID: "sf", TableName: "events", Error: "Numeric value 'xyz' is not recognized", Classification: ClassificationBadData, Timestamp: t1.Add(2 * time.Hour), Attempt: 1},
+	)
+
+	tests := []struct {
+		name        string
+		filter      *Filter
+		expectedIDs []string
+	}{
+		{"all newest first", &Filter{}, []string{"3", "2", "1"}},
+		{"by destination", &Filter{DestinationIDs: map[string]bool{"pg": true}}, []string{"2", "1"}},
+		{"by classification", &Filter{Classification: ClassificationBadData}, []string{"3", "1"}},
+		{"by reason", &Filter{Reason: "NUMERIC VALUE"}, []string{"3", "1"}},
+		{"by table", &Filter{TableName: "pageviews"}, []string{"2"}},
+		{"by time", &Filter{Start: t1.Add(30 * time.Minute), End: t1.Add(90 * time.Minute)}, []string{"2"}},
+		{"limit", &Filter{Limit: 1}, []string{"3"}},
+	}
+	service := NewService(logEventPath)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := service.List(tt.filter)
+			require.NoError(t, err)
+
+			actualIDs := []string{}
+			for _, record := range records {
+				actualIDs = append(actualIDs, record.EventID)
+			}
+			require.Equal(t, tt.expectedIDs, actualIDs, "DLQ records aren't equal")
+		})
+	}
+}
+
+func writeRecords(t *testing.T, filePath string, records ...*Record) {
+	var b []byte
+	for _, record := range records {
+		recordBytes, err := json.Marshal(record)
+		require.NoError(t, err)
+		b = append(b, recordBytes...)
+		b = append(b, '\n')
+	}
+	require.NoError(t, ioutil.WriteFile(filePath, b, 0644))
+}
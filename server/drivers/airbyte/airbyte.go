@@ -10,17 +10,25 @@ import (
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/parsers"
 	"github.com/jitsucom/jitsu/server/runner"
 	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/secrets"
+	"github.com/jitsucom/jitsu/server/tracing"
 	"github.com/jitsucom/jitsu/server/utils"
+	"github.com/spf13/viper"
 	"go.uber.org/atomic"
+	"os"
 	"path"
 	"strings"
 	"sync"
 	"time"
 )
 
+//ErrSyncAlreadyInProgress is returned from Load when the source already has max_concurrent_syncs active syncs
+var ErrSyncAlreadyInProgress = errors.New("sync already in progress")
+
 //Airbyte is an Airbyte CLI driver
 type Airbyte struct {
 	mutex *sync.RWMutex
@@ -33,7 +41,12 @@ type Airbyte struct {
 	pathToConfigs                string
 	streamsRepresentation        map[string]*base.StreamRepresentation
 	catalogDiscovered            *atomic.Bool
-	discoverCatalogLastError     error
+	//discoverCatalogLastError and pullImageLastError are guarded by the mutex
+	discoverCatalogLastError error
+	pullImageLastError       error
+
+	//ephemeralConfigs: the connector config is written only into the sync run directory which is removed after the sync
+	ephemeralConfigs bool
 
 	closed chan struct{}
 }
@@ -65,18 +78,42 @@ func NewAirbyte(ctx context.Context, sourceConfig *base.SourceConfig, collection
 	if config.ImageVersion == "" {
 		config.ImageVersion = airbyte.LatestVersion
 	}
+	config.Config, err = resolveConfigSecrets(config.Config)
+	if err != nil {
+		return nil, logging.RedactError(err, sourceConfig.Config)
+	}
 	base.FillPreconfiguredOauth(config.DockerImage, config.Config)
 
 	pathToConfigs := path.Join(airbyte.Instance.ConfigDir, sourceConfig.SourceID, config.DockerImage)
 
+	//the config dir isn't removed while it is used by the driver (released in Close())
+	airbyte.Instance.AcquireConfigDir(pathToConfigs)
+	initialized := false
+	defer func() {
+		if !initialized {
+			airbyte.Instance.ReleaseConfigDir(pathToConfigs)
+		}
+	}()
+
 	if err := logging.EnsureDir(pathToConfigs); err != nil {
 		return nil, fmt.Errorf("Error creating airbyte config dir: %v", err)
 	}
+	removeStaleImageDirs(sourceConfig.SourceID, config.DockerImage)
 
-	//parse airbyte config as file path
-	configPath, err := parsers.ParseJSONAsFile(path.Join(pathToConfigs, base.ConfigFileName), config.Config)
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing airbyte config [%v]: %v", config.Config, err)
+	ephemeralConfigs := viper.GetBool("airbyte-bridge.ephemeral_configs")
+	var configPath string
+	if ephemeralConfigs {
+		//config might have been left by the previous run without ephemeral configs
+		if err := os.Remove(path.Join(pathToConfigs, base.ConfigFileName)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("Error removing airbyte config file: %v", err)
+		}
+	} else {
+		//parse airbyte config as file path
+		configPath, err = parsers.ParseJSONAsFile(path.Join(pathToConfigs, base.ConfigFileName), config.Config)
+		if err != nil {
+			//config isn't logged: it might contain resolved secrets
+			return nil, fmt.Errorf("Error parsing airbyte config: %v", logging.RedactError(err, config.Config))
+		}
 	}
 
 	//parse airbyte catalog as file path
@@ -109,8 +146,9 @@ func NewAirbyte(ctx context.Context, sourceConfig *base.SourceConfig, collection
 			return nil, fmt.Errorf("Error parse formatted catalog: %v", err)
 		}
 
-		for streamName := range streamsRepresentation {
-			streamTableNameMapping[streamName] = config.StreamTableNamesPrefix + streamName
+		streamTableNameMapping, err = streamTableNames(config, config.StreamTableNamesPrefix, streamsRepresentation)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -122,6 +160,7 @@ func NewAirbyte(ctx context.Context, sourceConfig *base.SourceConfig, collection
 		config:                       config,
 		selectedStreamsWithNamespace: selectedStreamsWithNamespace(config),
 		pathToConfigs:                pathToConfigs,
+		ephemeralConfigs:             ephemeralConfigs,
 		catalogDiscovered:            catalogDiscovered,
 		streamsRepresentation:        streamsRepresentation,
 		closed:                       make(chan struct{}),
@@ -129,12 +168,36 @@ func NewAirbyte(ctx context.Context, sourceConfig *base.SourceConfig, collection
 	s.AbstractCLIDriver = *abstract
 	s.AbstractCLIDriver.SetStreamTableNameMappingIfNotExists(streamTableNameMapping)
 
+	safego.Run(s.ensureImage)
 	safego.Run(s.EnsureCatalog)
 
+	initialized = true
 	return s, nil
 }
 
+//registerCommand adds the sync command into active commands
+//returns false if the source already has max_concurrent_syncs active syncs
+func (a *Airbyte) registerCommand(taskID string, syncCommand *base.SyncCommand) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if len(a.activeCommands) >= a.config.MaxConcurrentSyncs {
+		return false
+	}
+
+	a.activeCommands[taskID] = syncCommand
+	return true
+}
+
+//unregisterCommand removes the finished sync command from active commands
+func (a *Airbyte) unregisterCommand(taskID string) {
+	a.mutex.Lock()
+	delete(a.activeCommands, taskID)
+	a.mutex.Unlock()
+}
+
 //TestAirbyte tests airbyte connection (runs check) if docker has been ready otherwise returns errNotReady
+//successful results are cached for airbyte-bridge.check_cache_ttl_sec (unless sourceConfig.ForceTest is set)
 func TestAirbyte(sourceConfig *base.SourceConfig) error {
 	config := &Config{}
 	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
@@ -149,15 +212,42 @@ func TestAirbyte(sourceConfig *base.SourceConfig) error {
 	if config.ImageVersion == "" {
 		config.ImageVersion = airbyte.LatestVersion
 	}
+	resolvedConfig, err := resolveConfigSecrets(config.Config)
+	if err != nil {
+		return err
+	}
+	config.Config = resolvedConfig
 	base.FillPreconfiguredOauth(config.DockerImage, config.Config)
-	airbyteRunner := airbyte.NewRunner(config.DockerImage, config.ImageVersion, "")
+
+	if airbyte.Instance == nil {
+		return errors.New("airbyte-bridge must be configured")
+	}
+
+	hash := checkHash(config)
+	if !sourceConfig.ForceTest && airbyte.Instance.IsCheckSucceeded(hash) {
+		logging.Debugf("Airbyte [%s:%s] connection test result is taken from the cache", config.DockerImage, config.ImageVersion)
+		return nil
+	}
+
+	if err := checkAirbyte(config); err != nil {
+		return err
+	}
+
+	airbyte.Instance.CheckSucceeded(hash)
+	return nil
+}
+
+//checkAirbyte runs check command and discovers catalog if selected streams are configured
+//returns error if any selected stream is unavailable
+func checkAirbyte(config *Config) error {
+	airbyteRunner := airbyte.NewRunner(config.DockerImage, config.ImageVersion, "", config.Env, config.DockerNetwork)
 	err := airbyteRunner.Check(config.Config)
 	if err != nil {
 		return err
 	}
 	selectedStreamsWithNamespace := selectedStreamsWithNamespace(config)
 	if len(selectedStreamsWithNamespace) > 0 {
-		airbyteRunner = airbyte.NewRunner(config.DockerImage, config.ImageVersion, "")
+		airbyteRunner = airbyte.NewRunner(config.DockerImage, config.ImageVersion, "", config.Env, config.DockerNetwork)
 		catalog, err := airbyteRunner.Discover(config.Config, time.Minute*3)
 		if err != nil {
 			return err
@@ -182,6 +272,26 @@ func TestAirbyte(sourceConfig *base.SourceConfig) error {
 	return nil
 }
 
+//ensureImage pulls docker image and surfaces pull errors through pullImageLastError
+func (a *Airbyte) ensureImage() {
+	retry := 0
+	for !a.IsClosed() {
+		err := airbyte.Instance.PullImage(airbyte.Instance.AddAirbytePrefix(a.GetTap()), a.config.ImageVersion)
+		a.setPullImageLastError(err)
+		if err == nil {
+			return
+		}
+
+		retry++
+		logging.Errorf("[%s] Error pulling airbyte image: %v. Scheduled next try after: %d minutes", a.ID(), err, retry)
+		select {
+		case <-a.closed:
+			return
+		case <-time.After(time.Duration(retry) * time.Minute):
+		}
+	}
+}
+
 //EnsureCatalog does discover if catalog wasn't provided
 func (a *Airbyte) EnsureCatalog() {
 	retry := 0
@@ -200,6 +310,8 @@ func (a *Airbyte) EnsureCatalog() {
 				time.Sleep(time.Second)
 				continue
 			}
+			//connector output might contain config values
+			err = logging.RedactError(err, a.config.Config)
 
 			a.mutex.Lock()
 			a.discoverCatalogLastError = err
@@ -212,9 +324,14 @@ func (a *Airbyte) EnsureCatalog() {
 			continue
 		}
 
-		streamTableNameMapping := map[string]string{}
-		for streamName := range streamsRepresentation {
-			streamTableNameMapping[streamName] = a.GetTableNamePrefix() + streamName
+		streamTableNameMapping, err := streamTableNames(a.config, a.GetTableNamePrefix(), streamsRepresentation)
+		if err != nil {
+			a.mutex.Lock()
+			a.discoverCatalogLastError = err
+			a.mutex.Unlock()
+
+			logging.Errorf("[%s] Error configuring airbyte: %v", a.ID(), err)
+			return
 		}
 
 		a.mutex.Lock()
@@ -229,11 +346,25 @@ func (a *Airbyte) EnsureCatalog() {
 	}
 }
 
+//setPullImageLastError sets the last docker pull result
+func (a *Airbyte) setPullImageLastError(err error) {
+	a.mutex.Lock()
+	a.pullImageLastError = err
+	a.mutex.Unlock()
+}
+
 //Ready returns true if catalog is discovered
 func (a *Airbyte) Ready() (bool, error) {
 	//check if docker image isn't pulled
 	ready := airbyte.Instance.IsImagePulled(airbyte.Instance.AddAirbytePrefix(a.GetTap()), a.config.ImageVersion)
 	if !ready {
+		//surface image pulling error
+		a.mutex.RLock()
+		defer a.mutex.RUnlock()
+		if a.pullImageLastError != nil {
+			return false, runner.NewCompositeNotReadyError(a.pullImageLastError.Error())
+		}
+
 		return false, runner.ErrNotReady
 	}
 
@@ -252,7 +383,16 @@ func (a *Airbyte) Ready() (bool, error) {
 	return false, runner.NewCompositeNotReadyError(msg)
 }
 
-func (a *Airbyte) Load(config string, state string, taskLogger logging.TaskLogger, dataConsumer base.CLIDataConsumer, taskCloser base.CLITaskCloser) error {
+func (a *Airbyte) Load(config string, state string, taskLogger logging.TaskLogger, dataConsumer base.CLIDataConsumer, taskCloser base.CLITaskCloser) (err error) {
+	ctx, span := tracing.StartSpan(context.Background(), "airbyte.Load", tracing.SourceID(a.ID()), tracing.DockerImage(a.GetTap()))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	//the config dir isn't removed during the sync even if the source is removed or recreated
+	airbyte.Instance.AcquireConfigDir(a.pathToConfigs)
+	defer airbyte.Instance.ReleaseConfigDir(a.pathToConfigs)
+
 	if a.IsClosed() {
 		return fmt.Errorf("%s has already been closed", a.Type())
 	}
@@ -262,33 +402,30 @@ func (a *Airbyte) Load(config string, state string, taskLogger logging.TaskLogge
 	}
 
 	//waiting when airbyte is ready
-	ready, readyErr := base.WaitReadiness(a, taskLogger)
+	_, waitSpan := tracing.StartSpan(ctx, "airbyte.WaitReadiness", tracing.SourceID(a.ID()), tracing.DockerImage(a.GetTap()))
+	ready, readyErr := base.WaitReadinessWithTimeout(a, taskLogger, time.Duration(a.config.ReadinessTimeoutSec)*time.Second)
+	tracing.EndSpan(waitSpan, readyErr)
 	if !ready {
 		return readyErr
 	}
 
-	statePath, err := a.GetStateFilePath(state)
-	if err != nil {
-		return err
-	}
-
-	airbyteRunner := airbyte.NewRunner(a.GetTap(), a.config.ImageVersion, taskCloser.TaskID())
+	airbyteRunner := airbyte.NewRunner(a.GetTap(), a.config.ImageVersion, taskCloser.TaskID(), a.config.Env, a.config.DockerNetwork)
 
 	syncCommand := &base.SyncCommand{
 		Cmd:        airbyteRunner,
 		TaskCloser: taskCloser,
 	}
-	a.mutex.Lock()
-	a.activeCommands[taskCloser.TaskID()] = syncCommand
-	a.mutex.Unlock()
+	if !a.registerCommand(taskCloser.TaskID(), syncCommand) {
+		taskLogger.WARN("Source [%s] has reached max_concurrent_syncs limit: %d", a.ID(), a.config.MaxConcurrentSyncs)
+		return ErrSyncAlreadyInProgress
+	}
+	deregister := base.ActiveSyncs.Register(a.ID(), a.Type(), syncCommand)
 
 	loadDone := make(chan struct{})
 	defer func() {
 		close(loadDone)
-
-		a.mutex.Lock()
-		delete(a.activeCommands, taskCloser.TaskID())
-		a.mutex.Unlock()
+		deregister()
+		a.unregisterCommand(taskCloser.TaskID())
 	}()
 
 	safego.Run(func() {
@@ -308,7 +445,125 @@ func (a *Airbyte) Load(config string, state string, taskLogger logging.TaskLogge
 		}
 	})
 
-	return airbyteRunner.Read(dataConsumer, a.streamsRepresentation, taskLogger, taskCloser, a.ID(), statePath)
+	statePath, err := a.GetStateFilePath(state)
+	if err != nil {
+		return err
+	}
+
+	//config persisted in meta storage contains the latest connector updates (e.g. refreshed OAuth tokens)
+	if config != "" {
+		if err := a.applyPersistedConfig(config); err != nil {
+			return err
+		}
+	}
+
+	configDir, err := a.syncConfigDir(statePath)
+	if err != nil {
+		return err
+	}
+	if a.ephemeralConfigs {
+		defer a.removeRunDir(configDir)
+	}
+
+	_, readSpan := tracing.StartSpan(ctx, "airbyte.Read", tracing.SourceID(a.ID()), tracing.DockerImage(a.GetTap()))
+	readStart := time.Now()
+	err = airbyteRunner.Read(dataConsumer, a.streamsRepresentation, taskLogger, taskCloser, a.ID(), configDir, statePath, a.config.Normalization, func(config map[string]interface{}) {
+		a.updateConfig(config, dataConsumer)
+	})
+	if err != nil && syncCommand.IsStopped() {
+		err = base.ErrSyncTaskCanceled
+	}
+	syncStatus := "success"
+	if err != nil {
+		syncStatus = "error"
+	}
+	metrics.SourceSyncDuration(base.AirbyteType, a.GetTap(), a.ID(), syncStatus, time.Since(readStart))
+	tracing.EndSpan(readSpan, err)
+	return err
+}
+
+//resolveConfigSecrets returns the connector config with resolved secret placeholders (e.g. ${vault:path#key} or ${aws-sm:name})
+//raw JSON config is parsed before resolving, config file path is returned as is
+func resolveConfigSecrets(connectorConfig interface{}) (interface{}, error) {
+	if raw, ok := connectorConfig.(string); ok {
+		if !strings.HasPrefix(raw, "{") {
+			return connectorConfig, nil
+		}
+
+		parsed := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			return nil, fmt.Errorf("Error parsing airbyte config: %v", err)
+		}
+		connectorConfig = parsed
+	}
+
+	resolved, err := secrets.Resolve(connectorConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error resolving airbyte config secrets: %v", err)
+	}
+
+	return resolved, nil
+}
+
+//applyPersistedConfig writes the connector config loaded from meta storage into the config file
+func (a *Airbyte) applyPersistedConfig(config string) error {
+	persisted := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(config), &persisted); err != nil {
+		return fmt.Errorf("Error parsing airbyte config loaded from meta storage: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	//ephemeral config is written into the sync run directory
+	if !a.ephemeralConfigs {
+		if _, err := parsers.ParseJSONAsFile(a.GetConfigPath(), persisted); err != nil {
+			return fmt.Errorf("Failed to write airbyte config loaded from meta storage: %v", err)
+		}
+	}
+	a.config.Config = persisted
+
+	return nil
+}
+
+//updateConfig applies connector config which has been updated by the connector (e.g. refreshed OAuth tokens)
+//the config file has been already rewritten by the runner. The config is persisted with dataConsumer (into meta storage)
+//so it is used by the next syncs after the source reload and on other cluster nodes
+func (a *Airbyte) updateConfig(config map[string]interface{}, dataConsumer base.CLIDataConsumer) {
+	a.mutex.Lock()
+	a.config.Config = config
+	a.mutex.Unlock()
+
+	logging.Infof("[%s] airbyte connector config has been updated by the connector", a.ID())
+
+	configConsumer, ok := dataConsumer.(base.CLIConfigConsumer)
+	if !ok {
+		return
+	}
+	if err := configConsumer.ConsumeConfig(config); err != nil {
+		logging.SystemErrorf("[%s] updated airbyte connector config hasn't been persisted: %v", a.ID(), err)
+	}
+}
+
+//CancelTask kills only the container of the active sync with the task id. The interrupted Load returns base.ErrSyncTaskCanceled
+//returns base.ErrSyncTaskNotFound if there is no such active sync or it has already finished
+func (a *Airbyte) CancelTask(taskID string) error {
+	a.mutex.RLock()
+	syncCommand, ok := a.activeCommands[taskID]
+	a.mutex.RUnlock()
+	if !ok {
+		return base.ErrSyncTaskNotFound
+	}
+
+	logging.Infof("[%s] canceling task [%s] process: %s", a.ID(), taskID, syncCommand.Cmd.String())
+	if err := syncCommand.Stop(); err != nil {
+		if err == runner.ErrAirbyteAlreadyTerminated {
+			return base.ErrSyncTaskNotFound
+		}
+
+		return err
+	}
+
+	return nil
 }
 
 //GetDriversInfo returns telemetry information about the driver
@@ -343,19 +598,57 @@ func (a *Airbyte) Close() (multiErr error) {
 
 	a.mutex.Unlock()
 
+	airbyte.Instance.ReleaseConfigDir(a.pathToConfigs)
+
 	return multiErr
 }
 
+//RefreshCatalog removes the cached catalog and runs discover again (if the catalog isn't configured explicitly)
+//the source isn't ready until the catalog is discovered
+func (a *Airbyte) RefreshCatalog() error {
+	if a.config.Catalog != nil && a.config.Catalog != "" {
+		return nil
+	}
+
+	a.mutex.RLock()
+	hash := airbyte.CatalogHash(a.config.Config, a.GetTap(), a.config.ImageVersion, a.config.Env, a.config.DockerNetwork)
+	a.mutex.RUnlock()
+	if err := airbyte.Instance.RemoveCachedCatalog(hash); err != nil {
+		return err
+	}
+
+	if a.catalogDiscovered.CAS(true, false) {
+		safego.Run(a.EnsureCatalog)
+	}
+
+	return nil
+}
+
 //loadCatalog:
-//1. discovers source catalog
+//1. discovers source catalog (or takes the cached one if it has been discovered with the same configuration)
 //2. applies selected streams
 //3. reformat catalog to airbyte format and writes it to the file system
 //returns catalog
 func (a *Airbyte) loadCatalog() (string, map[string]*base.StreamRepresentation, error) {
-	airbyteRunner := airbyte.NewRunner(a.GetTap(), a.config.ImageVersion, "")
-	rawCatalog, err := airbyteRunner.Discover(a.config.Config, 5*time.Minute)
-	if err != nil {
-		return "", nil, err
+	a.mutex.RLock()
+	connectorConfig := a.config.Config
+	a.mutex.RUnlock()
+
+	hash := airbyte.CatalogHash(connectorConfig, a.GetTap(), a.config.ImageVersion, a.config.Env, a.config.DockerNetwork)
+	rawCatalog := airbyte.Instance.CachedCatalog(hash)
+	if rawCatalog != nil {
+		logging.Infof("[%s] uses cached airbyte catalog: configuration hasn't been changed", a.ID())
+	} else {
+		airbyteRunner := airbyte.NewRunner(a.GetTap(), a.config.ImageVersion, "", a.config.Env, a.config.DockerNetwork)
+		var err error
+		rawCatalog, err = airbyteRunner.Discover(connectorConfig, 5*time.Minute)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if err := airbyte.Instance.CacheCatalog(hash, a.GetTap(), rawCatalog); err != nil {
+			logging.Warnf("[%s] discovered airbyte catalog won't be reused after restart: %v", a.ID(), err)
+		}
 	}
 
 	//apply only selected streams
@@ -387,6 +680,26 @@ func (a *Airbyte) loadCatalog() (string, map[string]*base.StreamRepresentation,
 	return catalogPath, streamsRepresentation, nil
 }
 
+//streamTableNames returns stream - table name mapping according to stream_table_name_template or the prefix
+//explicit stream_table_names mapping has priority and is applied by SetStreamTableNameMappingIfNotExists
+//returns err if several streams are written into the same table
+func streamTableNames(config *Config, prefix string, streamsRepresentation map[string]*base.StreamRepresentation) (map[string]string, error) {
+	streamTableNameMapping := map[string]string{}
+	for streamName, representation := range streamsRepresentation {
+		tableName, err := config.streamTableName(prefix, streamName, representation.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("Error building table name of stream [%s] with stream_table_name_template: %v", streamName, err)
+		}
+		streamTableNameMapping[streamName] = tableName
+	}
+
+	if err := config.resolveTableNameCollisions(streamTableNameMapping, streamsRepresentation); err != nil {
+		return nil, err
+	}
+
+	return streamTableNameMapping, nil
+}
+
 func selectedStreamsWithNamespace(config *Config) map[string]base.StreamConfiguration {
 	var selectedStreamsWithNamespace map[string]base.StreamConfiguration
 	if len(config.SelectedStreams) > 0 {
@@ -0,0 +1,23 @@
+package airbyte
+
+import (
+	"encoding/json"
+
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+//checkHash returns hash of the resolved connector config, docker image, image version, env, network and selected streams
+//any configuration change results in a new hash. Successful connection tests are cached by the hash in the airbyte bridge
+func checkHash(config *Config) string {
+	configBytes, _ := json.Marshal(config.Config)
+	envBytes, _ := json.Marshal(config.Env)
+	streamsBytes, _ := json.Marshal(config.SelectedStreams)
+	return uuid.GetHash(map[string]interface{}{
+		"config":           string(configBytes),
+		"docker_image":     config.DockerImage,
+		"image_version":    config.ImageVersion,
+		"env":              string(envBytes),
+		"docker_network":   config.DockerNetwork,
+		"selected_streams": string(streamsBytes),
+	})
+}
@@ -0,0 +1,29 @@
+package airbyte
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHash(t *testing.T) {
+	config := &Config{DockerImage: "source-postgres", ImageVersion: "latest", Config: map[string]interface{}{"host": "db1"}}
+	hash := checkHash(config)
+	require.Equal(t, hash, checkHash(&Config{DockerImage: "source-postgres", ImageVersion: "latest", Config: map[string]interface{}{"host": "db1"}}))
+
+	//any config change busts the cache
+	tests := []struct {
+		name    string
+		changed *Config
+	}{
+		{"connector config", &Config{DockerImage: "source-postgres", ImageVersion: "latest", Config: map[string]interface{}{"host": "db2"}}},
+		{"image version", &Config{DockerImage: "source-postgres", ImageVersion: "0.4.1", Config: map[string]interface{}{"host": "db1"}}},
+		{"env", &Config{DockerImage: "source-postgres", ImageVersion: "latest", Config: map[string]interface{}{"host": "db1"}, Env: map[string]string{"TZ": "UTC"}}},
+		{"docker network", &Config{DockerImage: "source-postgres", ImageVersion: "latest", Config: map[string]interface{}{"host": "db1"}, DockerNetwork: "host"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NotEqual(t, hash, checkHash(tt.changed))
+		})
+	}
+}
@@ -1,23 +1,46 @@
 package airbyte
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/airbyte"
 	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/schema"
+	"sort"
+	"strings"
+	"text/template"
 )
 
-//Config is a dto for airbyte configuration serialization
+// defaultMaxConcurrentSyncs doesn't allow overlapping syncs of the same source (they share the same state file)
+const defaultMaxConcurrentSyncs = 1
+
+// Config is a dto for airbyte configuration serialization
 type Config struct {
-	DockerImage            string                     `mapstructure:"docker_image" json:"docker_image,omitempty" yaml:"docker_image,omitempty"`
-	ImageVersion           string                     `mapstructure:"image_version" json:"image_version,omitempty" yaml:"image_version,omitempty"`
-	Config                 interface{}                `mapstructure:"config" json:"config,omitempty" yaml:"config,omitempty"`
-	Catalog                interface{}                `mapstructure:"catalog" json:"catalog,omitempty" yaml:"catalog,omitempty"`
-	InitialState           interface{}                `mapstructure:"initial_state" json:"initial_state,omitempty" yaml:"initial_state,omitempty"`
-	StreamTableNames       map[string]string          `mapstructure:"stream_table_names" json:"stream_table_names,omitempty" yaml:"stream_table_names,omitempty"`
-	StreamTableNamesPrefix string                     `mapstructure:"stream_table_name_prefix" json:"stream_table_name_prefix,omitempty" yaml:"stream_table_name_prefix,omitempty"`
-	SelectedStreams        []base.StreamConfiguration `mapstructure:"selected_streams" json:"selected_streams,omitempty" yaml:"selected_streams,omitempty"`
+	DockerImage             string                     `mapstructure:"docker_image" json:"docker_image,omitempty" yaml:"docker_image,omitempty"`
+	ImageVersion            string                     `mapstructure:"image_version" json:"image_version,omitempty" yaml:"image_version,omitempty"`
+	Config                  interface{}                `mapstructure:"config" json:"config,omitempty" yaml:"config,omitempty"`
+	Catalog                 interface{}                `mapstructure:"catalog" json:"catalog,omitempty" yaml:"catalog,omitempty"`
+	InitialState            interface{}                `mapstructure:"initial_state" json:"initial_state,omitempty" yaml:"initial_state,omitempty"`
+	StreamTableNames        map[string]string          `mapstructure:"stream_table_names" json:"stream_table_names,omitempty" yaml:"stream_table_names,omitempty"`
+	StreamTableNamesPrefix  string                     `mapstructure:"stream_table_name_prefix" json:"stream_table_name_prefix,omitempty" yaml:"stream_table_name_prefix,omitempty"`
+	StreamTableNameTemplate string                     `mapstructure:"stream_table_name_template" json:"stream_table_name_template,omitempty" yaml:"stream_table_name_template,omitempty"`
+	SelectedStreams         []base.StreamConfiguration `mapstructure:"selected_streams" json:"selected_streams,omitempty" yaml:"selected_streams,omitempty"`
+	MaxConcurrentSyncs      int                        `mapstructure:"max_concurrent_syncs" json:"max_concurrent_syncs,omitempty" yaml:"max_concurrent_syncs,omitempty"`
+	Env                     map[string]string          `mapstructure:"env" json:"env,omitempty" yaml:"env,omitempty"`
+	// ReadinessTimeoutSec is a max time of waiting for the source readiness (docker image pulling and catalog discovering) before sync
+	ReadinessTimeoutSec int `mapstructure:"readiness_timeout_sec" json:"readiness_timeout_sec,omitempty" yaml:"readiness_timeout_sec,omitempty"`
+	// DockerNetwork is a docker network of connector containers (e.g. host or a named network with internal databases)
+	DockerNetwork string `mapstructure:"docker_network" json:"docker_network,omitempty" yaml:"docker_network,omitempty"`
+	// DisambiguateStreamTableNames appends stream namespace to table names of streams which are written into the same table
+	DisambiguateStreamTableNames bool `mapstructure:"disambiguate_stream_table_names" json:"disambiguate_stream_table_names,omitempty" yaml:"disambiguate_stream_table_names,omitempty"`
+	// Normalization is raw (default) or basic: arrays of objects are written into child tables
+	Normalization string `mapstructure:"normalization" json:"normalization,omitempty" yaml:"normalization,omitempty"`
+
+	tableNameTemplate *template.Template
 }
 
-//Validate returns err if configuration is invalid
+// Validate returns err if configuration is invalid
 func (ac *Config) Validate() error {
 	if ac == nil {
 		return errors.New("Airbyte config is required. Please read docs https://jitsu.com/docs/sources-configuration/airbyte")
@@ -35,5 +58,123 @@ func (ac *Config) Validate() error {
 		ac.StreamTableNames = map[string]string{}
 	}
 
+	if ac.MaxConcurrentSyncs < 0 {
+		return errors.New("Airbyte max_concurrent_syncs must be positive")
+	}
+
+	if ac.MaxConcurrentSyncs == 0 {
+		ac.MaxConcurrentSyncs = defaultMaxConcurrentSyncs
+	}
+
+	if ac.ReadinessTimeoutSec < 0 {
+		return errors.New("Airbyte readiness_timeout_sec must be positive")
+	}
+
+	if err := airbyte.ValidateEnv(ac.Env); err != nil {
+		return fmt.Errorf("Airbyte env is invalid: %v", err)
+	}
+
+	if err := airbyte.ValidateNetwork(ac.DockerNetwork); err != nil {
+		return fmt.Errorf("Airbyte docker_network is invalid: %v", err)
+	}
+
+	if err := airbyte.ValidateNormalization(ac.Normalization); err != nil {
+		return fmt.Errorf("Airbyte normalization is invalid: %v", err)
+	}
+
+	if ac.StreamTableNameTemplate != "" {
+		tmpl, err := template.New("stream_table_name_template").Option("missingkey=error").Parse(ac.StreamTableNameTemplate)
+		if err != nil {
+			return fmt.Errorf("Airbyte stream_table_name_template is invalid: %v", err)
+		}
+		ac.tableNameTemplate = tmpl
+
+		if _, err := ac.executeTableNameTemplate("stream", "namespace"); err != nil {
+			return fmt.Errorf("Airbyte stream_table_name_template is invalid: %v", err)
+		}
+	}
+
 	return nil
 }
+
+// streamTableName returns table name of the stream:
+// stream_table_name_template result if the template is configured otherwise prefix + stream name
+func (ac *Config) streamTableName(prefix, streamName, namespace string) (string, error) {
+	if ac.tableNameTemplate == nil {
+		return prefix + streamName, nil
+	}
+
+	return ac.executeTableNameTemplate(streamName, namespace)
+}
+
+func (ac *Config) executeTableNameTemplate(streamName, namespace string) (string, error) {
+	var buf bytes.Buffer
+	if err := ac.tableNameTemplate.Execute(&buf, map[string]string{"stream": streamName, "namespace": namespace}); err != nil {
+		return "", err
+	}
+
+	tableName := strings.TrimSpace(buf.String())
+	if tableName == "" {
+		return "", fmt.Errorf("template result of stream [%s] is empty", streamName)
+	}
+
+	return tableName, nil
+}
+
+// resolveTableNameCollisions returns error if several streams are written into the same table (after table name reformatting)
+// streams which are explicitly mapped into the same table with stream_table_names aren't collisions.
+// If disambiguate_stream_table_names is enabled, namespace suffix is appended to not explicitly mapped table names of colliding streams
+func (ac *Config) resolveTableNameCollisions(streamTableNameMapping map[string]string, streamsRepresentation map[string]*base.StreamRepresentation) error {
+	if ac.DisambiguateStreamTableNames {
+		for _, streamNames := range ac.tableNameCollisions(streamTableNameMapping) {
+			for _, streamName := range streamNames {
+				if _, explicit := ac.StreamTableNames[streamName]; explicit {
+					continue
+				}
+				if representation, ok := streamsRepresentation[streamName]; ok && representation.Namespace != "" {
+					streamTableNameMapping[streamName] += "_" + representation.Namespace
+				}
+			}
+		}
+	}
+
+	collisions := ac.tableNameCollisions(streamTableNameMapping)
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	var descriptions []string
+	for tableName, streamNames := range collisions {
+		descriptions = append(descriptions, fmt.Sprintf("[%s] streams: [%s]", tableName, strings.Join(streamNames, ", ")))
+	}
+	sort.Strings(descriptions)
+	return fmt.Errorf("Several airbyte streams are written into the same table: %s. Please configure stream_table_names or enable disambiguate_stream_table_names", strings.Join(descriptions, "; "))
+}
+
+// tableNameCollisions returns reformatted table name -> sorted stream names of the streams which are written into the same table
+// explicit stream_table_names mapping has priority over streamTableNameMapping
+func (ac *Config) tableNameCollisions(streamTableNameMapping map[string]string) map[string][]string {
+	streamsPerTable := map[string][]string{}
+	derivedTables := map[string]bool{}
+	for streamName, tableName := range streamTableNameMapping {
+		explicitTableName, explicit := ac.StreamTableNames[streamName]
+		if explicit {
+			tableName = explicitTableName
+		}
+		tableName = schema.Reformat(tableName)
+		streamsPerTable[tableName] = append(streamsPerTable[tableName], streamName)
+		if !explicit {
+			derivedTables[tableName] = true
+		}
+	}
+
+	collisions := map[string][]string{}
+	for tableName, streamNames := range streamsPerTable {
+		if len(streamNames) > 1 && derivedTables[tableName] {
+			sort.Strings(streamNames)
+			collisions[tableName] = streamNames
+		}
+	}
+
+	return collisions
+}
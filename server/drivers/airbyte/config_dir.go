@@ -0,0 +1,105 @@
+package airbyte
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/airbyte"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+//runDirPrefix is a prefix of ephemeral sync run directories in the source config dir
+const runDirPrefix = "run-"
+
+//syncConfigDir returns the directory (relative to airbyte.Instance.ConfigDir) with config, catalog and state files for the sync
+//with ephemeral configs the files are written into a new run directory which must be removed after the sync with removeRunDir()
+func (a *Airbyte) syncConfigDir(statePath string) (string, error) {
+	relativeConfigDir := path.Join(a.ID(), a.GetTap())
+	if !a.ephemeralConfigs {
+		return relativeConfigDir, nil
+	}
+
+	runDir := runDirPrefix + uuid.NewLettersNumbers()
+	absoluteRunDir := path.Join(a.pathToConfigs, runDir)
+	if err := logging.EnsureDir(absoluteRunDir); err != nil {
+		return "", fmt.Errorf("Error creating airbyte sync run dir: %v", err)
+	}
+
+	a.mutex.RLock()
+	connectorConfig := a.config.Config
+	a.mutex.RUnlock()
+
+	err := writeRunFile(path.Join(absoluteRunDir, base.ConfigFileName), connectorConfig)
+	if err == nil {
+		err = writeRunFile(path.Join(absoluteRunDir, base.CatalogFileName), a.GetCatalogPath())
+	}
+	if err == nil && statePath != "" {
+		err = writeRunFile(path.Join(absoluteRunDir, base.StateFileName), statePath)
+	}
+	if err != nil {
+		a.removeRunDir(path.Join(relativeConfigDir, runDir))
+		//config isn't logged: it might contain resolved secrets
+		return "", fmt.Errorf("Error writing airbyte sync run files: %v", logging.RedactError(err, connectorConfig))
+	}
+
+	return path.Join(relativeConfigDir, runDir), nil
+}
+
+//removeRunDir removes the ephemeral sync run directory (relative to airbyte.Instance.ConfigDir)
+func (a *Airbyte) removeRunDir(runDir string) {
+	absoluteRunDir := path.Join(airbyte.Instance.ConfigDir, runDir)
+	if err := os.RemoveAll(absoluteRunDir); err != nil {
+		logging.SystemErrorf("[%s] Error removing airbyte sync run dir [%s]: %v", a.ID(), absoluteRunDir, err)
+	}
+}
+
+//CleanWorkDir removes the source config dir (configs, catalogs and state files) when the source is removed or recreated
+//the dir is removed after the end of running syncs
+func (a *Airbyte) CleanWorkDir() {
+	airbyte.Instance.RemoveConfigDir(a.pathToConfigs)
+}
+
+//removeStaleImageDirs removes config dirs of other docker images of the source (e.g. the source has been recreated with a new image)
+//the dirs are removed after the end of their running syncs
+func removeStaleImageDirs(sourceID, dockerImage string) {
+	sourceDir := path.Join(airbyte.Instance.ConfigDir, sourceID)
+	entries, err := ioutil.ReadDir(sourceDir)
+	if err != nil {
+		logging.Warnf("[%s] Error reading airbyte source config dir [%s]: %v", sourceID, sourceDir, err)
+		return
+	}
+
+	//docker image might contain '/' (e.g. custom_org/source-x)
+	imageDir := strings.SplitN(dockerImage, "/", 2)[0]
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != imageDir {
+			airbyte.Instance.RemoveConfigDir(path.Join(sourceDir, entry.Name()))
+		}
+	}
+}
+
+//writeRunFile writes value (raw JSON, JSON object or a path to the JSON file) into the file
+func writeRunFile(filePath string, value interface{}) error {
+	writtenPath, err := parsers.ParseJSONAsFile(filePath, value)
+	if err != nil {
+		return err
+	}
+
+	if writtenPath == "" || writtenPath == filePath {
+		return nil
+	}
+
+	//value is a path to the existing file
+	b, err := ioutil.ReadFile(writtenPath)
+	if err != nil {
+		return fmt.Errorf("Error reading file [%s]: %v", writtenPath, err)
+	}
+
+	return ioutil.WriteFile(filePath, b, 0644)
+}
@@ -0,0 +1,43 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRunFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte_run_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	existingFile := path.Join(dir, "existing.json")
+	require.NoError(t, ioutil.WriteFile(existingFile, []byte(`{"from":"file"}`), 0644))
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"json object", map[string]interface{}{"from": "object"}, `{"from":"object"}`},
+		{"raw json", `{"from":"raw"}`, `{"from":"raw"}`},
+		{"file path", existingFile, `{"from":"file"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := path.Join(dir, "config.json")
+			require.NoError(t, writeRunFile(filePath, tt.value))
+
+			b, err := ioutil.ReadFile(filePath)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expected, string(b))
+		})
+	}
+
+	//empty value isn't written
+	require.NoError(t, writeRunFile(path.Join(dir, "state.json"), ""))
+	require.NoFileExists(t, path.Join(dir, "state.json"))
+}
@@ -0,0 +1,166 @@
+package airbyte
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamTableName(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		namespace   string
+		expected    string
+		expectedErr string
+	}{
+		{
+			"without template",
+			"",
+			"public",
+			"prefix_orders",
+			"",
+		},
+		{
+			"with namespace",
+			"{{.namespace}}_{{.stream}}",
+			"public",
+			"public_orders",
+			"",
+		},
+		{
+			"unknown placeholder",
+			"{{.streem}}",
+			"",
+			"",
+			`Airbyte stream_table_name_template is invalid: template: stream_table_name_template:1:2: executing "stream_table_name_template" at <.streem>: map has no entry for key "streem"`,
+		},
+		{
+			"malformed template",
+			"{{.stream",
+			"",
+			"",
+			"Airbyte stream_table_name_template is invalid: template: stream_table_name_template:1: unclosed action",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{DockerImage: "source-shopify", Config: map[string]interface{}{}, StreamTableNameTemplate: tt.template}
+			err := config.Validate()
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			actual, err := config.streamTableName("prefix_", "orders", tt.namespace)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestResolveTableNameCollisions(t *testing.T) {
+	streams := map[string]*base.StreamRepresentation{
+		"Orders":   {Namespace: "sales"},
+		"orders":   {Namespace: "crm"},
+		"products": {Namespace: "sales"},
+	}
+	tests := []struct {
+		name             string
+		streamTableNames map[string]string
+		disambiguate     bool
+		expected         map[string]string
+		expectedErr      string
+	}{
+		{
+			"collision",
+			map[string]string{},
+			false,
+			nil,
+			"Several airbyte streams are written into the same table: [src_orders] streams: [Orders, orders]. Please configure stream_table_names or enable disambiguate_stream_table_names",
+		},
+		{
+			"collision resolved by explicit mapping",
+			map[string]string{"Orders": "sales_orders"},
+			false,
+			map[string]string{"Orders": "src_Orders", "orders": "src_orders", "products": "src_products"},
+			"",
+		},
+		{
+			"collision with explicit mapping",
+			map[string]string{"products": "src_orders"},
+			false,
+			nil,
+			"Several airbyte streams are written into the same table: [src_orders] streams: [Orders, orders, products]. Please configure stream_table_names or enable disambiguate_stream_table_names",
+		},
+		{
+			"explicit mapping into the same table isn't a collision",
+			map[string]string{"Orders": "all_orders", "orders": "all_orders"},
+			false,
+			map[string]string{"Orders": "src_Orders", "orders": "src_orders", "products": "src_products"},
+			"",
+		},
+		{
+			"disambiguated with namespace suffix",
+			map[string]string{},
+			true,
+			map[string]string{"Orders": "src_Orders_sales", "orders": "src_orders_crm", "products": "src_products"},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{DockerImage: "source-postgres", Config: map[string]interface{}{}, StreamTableNames: tt.streamTableNames, DisambiguateStreamTableNames: tt.disambiguate}
+			require.NoError(t, config.Validate())
+
+			actual, err := streamTableNames(config, "src_", streams)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestMaxConcurrentSyncs(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxConcurrentSyncs int
+		expectedErr        string
+		expectedLimit      int
+	}{
+		{"default: overlapping syncs aren't allowed", 0, "", 1},
+		{"configured", 3, "", 3},
+		{"negative", -1, "Airbyte max_concurrent_syncs must be positive", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{DockerImage: "source-shopify", Config: map[string]interface{}{}, MaxConcurrentSyncs: tt.maxConcurrentSyncs}
+			err := config.Validate()
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedLimit, config.MaxConcurrentSyncs)
+
+			a := &Airbyte{mutex: &sync.RWMutex{}, activeCommands: map[string]*base.SyncCommand{}, config: config}
+			for i := 0; i < tt.expectedLimit; i++ {
+				require.True(t, a.registerCommand(fmt.Sprintf("task_%d", i), &base.SyncCommand{}))
+			}
+			require.False(t, a.registerCommand("task_over_limit", &base.SyncCommand{}), "sync over max_concurrent_syncs must be rejected")
+			require.NotContains(t, a.activeCommands, "task_over_limit")
+
+			//finished sync frees the slot
+			a.unregisterCommand("task_0")
+			require.True(t, a.registerCommand("task_next", &base.SyncCommand{}))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfigConsumer struct {
+	configs []map[string]interface{}
+}
+
+func (tcc *testConfigConsumer) Consume(representation *base.CLIOutputRepresentation) error {
+	return nil
+}
+
+func (tcc *testConfigConsumer) ConsumeConfig(config map[string]interface{}) error {
+	tcc.configs = append(tcc.configs, config)
+	return nil
+}
+
+func TestUpdateConfigPersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte_config_update")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configPath := path.Join(dir, base.ConfigFileName)
+	a := &Airbyte{
+		mutex:             &sync.RWMutex{},
+		AbstractCLIDriver: *base.NewAbstractCLIDriver("source", "source-hubspot", configPath, "", "", "", "", dir, nil),
+		config:            &Config{Config: map[string]interface{}{"access_token": "original"}},
+	}
+
+	consumer := &testConfigConsumer{}
+	refreshed := map[string]interface{}{"access_token": "refreshed"}
+	a.updateConfig(refreshed, consumer)
+	require.Equal(t, []map[string]interface{}{refreshed}, consumer.configs, "updated config should be persisted")
+	require.Equal(t, refreshed, a.config.Config)
+
+	//source reload: the driver is created with the original config and the next sync gets the persisted one
+	a.config.Config = map[string]interface{}{"access_token": "original"}
+	require.NoError(t, a.applyPersistedConfig(`{"access_token":"refreshed"}`))
+	require.Equal(t, refreshed, a.config.Config)
+	b, err := ioutil.ReadFile(configPath)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"access_token":"refreshed"}`, string(b))
+
+	require.Error(t, a.applyPersistedConfig("malformed"))
+}
@@ -48,6 +48,9 @@ func reformatCatalog(dockerImage string, rawCatalog *airbyte.CatalogRow) ([]byte
 			}
 		}
 
+		if existing, ok := streamsRepresentation[stream.Name]; ok {
+			return nil, nil, duplicatedStreamError(stream.Name, existing.Namespace, stream.Namespace)
+		}
 		streamsRepresentation[stream.Name] = &base.StreamRepresentation{
 			Namespace:  stream.Namespace,
 			StreamName: stream.Name,
@@ -88,6 +91,9 @@ func parseFormattedCatalog(catalogIface interface{}) (map[string]*base.StreamRep
 		streamSchema := schema.Fields{}
 		base.ParseProperties(base.AirbyteType, "", stream.Stream.JsonSchema.Properties, streamSchema)
 
+		if existing, ok := streamsRepresentation[stream.Stream.Name]; ok {
+			return nil, duplicatedStreamError(stream.Stream.Name, existing.Namespace, stream.Stream.Namespace)
+		}
 		streamsRepresentation[stream.Stream.Name] = &base.StreamRepresentation{
 			Namespace:  stream.Stream.Namespace,
 			StreamName: stream.Stream.Name,
@@ -105,6 +111,12 @@ func parseFormattedCatalog(catalogIface interface{}) (map[string]*base.StreamRep
 	return streamsRepresentation, nil
 }
 
+//duplicatedStreamError returns error about streams with the same name in different namespaces
+//they can't be synchronized together because Airbyte records are identified only by the stream name
+func duplicatedStreamError(streamName, namespace, otherNamespace string) error {
+	return fmt.Errorf("Airbyte catalog contains several streams with the same name [%s] (namespaces: [%s] and [%s]): please select only one of them with selected_streams", streamName, namespace, otherNamespace)
+}
+
 //getSyncMode returns incremental if supported
 //otherwise returns first
 //for DB source returns not incremental
@@ -0,0 +1,83 @@
+package airbyte
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+//lastStateFileName is a file for keeping the latest emitted state when meta storage isn't configured
+const lastStateFileName = "last_state.json"
+
+//LoadLocalState returns the latest state from the local file or empty string if it doesn't exist.
+//If there is no local state file, returns the state file which was left by the previous versions (legacy state)
+func (a *Airbyte) LoadLocalState() (string, error) {
+	state, err := readStateFile(a.lastStatePath())
+	if err != nil || state != "" {
+		return state, err
+	}
+
+	legacyStatePath := a.legacyStatePath()
+	if legacyStatePath == "" {
+		return "", nil
+	}
+
+	return readStateFile(legacyStatePath)
+}
+
+//SaveLocalState writes the state into the local file
+func (a *Airbyte) SaveLocalState(state string) error {
+	if err := ioutil.WriteFile(a.lastStatePath(), []byte(state), 0644); err != nil {
+		return fmt.Errorf("Error writing local state file: %v", err)
+	}
+
+	return nil
+}
+
+//ClearLocalState removes the local state file and the legacy state file
+func (a *Airbyte) ClearLocalState() error {
+	statePaths := []string{a.lastStatePath()}
+	if legacyStatePath := a.legacyStatePath(); legacyStatePath != "" {
+		statePaths = append(statePaths, legacyStatePath)
+	}
+
+	for _, statePath := range statePaths {
+		if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Error removing local state file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (a *Airbyte) lastStatePath() string {
+	return path.Join(a.pathToConfigs, lastStateFileName)
+}
+
+//legacyStatePath returns the path of the state file which was written before each sync by the previous versions.
+//It contains the state of the latest sync. Returns empty string if the initial state is configured:
+//the file is overwritten by the initial state on the driver creation
+func (a *Airbyte) legacyStatePath() string {
+	if a.config != nil && a.config.InitialState != nil {
+		return ""
+	}
+
+	return path.Join(a.pathToConfigs, base.StateFileName)
+}
+
+//readStateFile returns the file content or empty string if the file doesn't exist
+func readStateFile(statePath string) (string, error) {
+	b, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("Error reading local state file: %v", err)
+	}
+
+	return string(b), nil
+}
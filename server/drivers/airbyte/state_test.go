@@ -0,0 +1,75 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalState(t *testing.T) {
+	tests := []struct {
+		name          string
+		initialState  interface{}
+		legacyState   string
+		lastState     string
+		expectedState string
+	}{
+		{
+			name:          "no state",
+			expectedState: "",
+		},
+		{
+			name:          "legacy state of the previous versions is migrated",
+			legacyState:   `{"cursor":1}`,
+			expectedState: `{"cursor":1}`,
+		},
+		{
+			name:          "last state takes precedence over legacy one",
+			legacyState:   `{"cursor":1}`,
+			lastState:     `{"cursor":2}`,
+			expectedState: `{"cursor":2}`,
+		},
+		{
+			name:          "legacy file contains the initial state",
+			initialState:  map[string]interface{}{"cursor": 0},
+			legacyState:   `{"cursor":0}`,
+			expectedState: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "airbyte_state")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			if tt.legacyState != "" {
+				require.NoError(t, ioutil.WriteFile(path.Join(dir, base.StateFileName), []byte(tt.legacyState), 0644))
+			}
+			if tt.lastState != "" {
+				require.NoError(t, ioutil.WriteFile(path.Join(dir, lastStateFileName), []byte(tt.lastState), 0644))
+			}
+
+			a := &Airbyte{pathToConfigs: dir, config: &Config{InitialState: tt.initialState}}
+			state, err := a.LoadLocalState()
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedState, state)
+
+			require.NoError(t, a.SaveLocalState(`{"cursor":3}`))
+			state, err = a.LoadLocalState()
+			require.NoError(t, err)
+			require.Equal(t, `{"cursor":3}`, state)
+
+			require.NoError(t, a.ClearLocalState())
+			state, err = a.LoadLocalState()
+			require.NoError(t, err)
+			require.Empty(t, state)
+			if tt.initialState != nil {
+				require.FileExists(t, path.Join(dir, base.StateFileName), "initial state file mustn't be removed")
+			}
+		})
+	}
+}
@@ -20,6 +20,8 @@ type StreamConfiguration struct {
 //SourceConfig is a dto for api connector source config serialization
 type SourceConfig struct {
 	SourceID string `json:"source_id" yaml:"-"`
+	//ForceTest is true if the connection test must ignore cached results (?force=true)
+	ForceTest bool `json:"-" yaml:"-"`
 
 	Type                   string   `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
 	Destinations           []string `mapstructure:"destinations" json:"destinations,omitempty" yaml:"destinations,omitempty"`
@@ -2,6 +2,7 @@ package base
 
 import (
 	"github.com/jitsucom/jitsu/server/runner"
+	"go.uber.org/atomic"
 )
 
 type ExecCommand interface {
@@ -13,6 +14,8 @@ type ExecCommand interface {
 type SyncCommand struct {
 	Cmd        ExecCommand
 	TaskCloser CLITaskCloser
+
+	stopped atomic.Bool
 }
 
 //Cancel uses Kill() under the hood
@@ -34,3 +37,15 @@ func (sc *SyncCommand) Kill(msg string) error {
 
 	return nil
 }
+
+//Stop closes runner without closing the task: the task status is changed by the caller (e.g. the task has been canceled by a user)
+//returns runner.ErrAirbyteAlreadyTerminated if the command has already finished
+func (sc *SyncCommand) Stop() error {
+	sc.stopped.Store(true)
+	return sc.Cmd.Close()
+}
+
+//IsStopped returns true if the command has been stopped with Stop()
+func (sc *SyncCommand) IsStopped() bool {
+	return sc.stopped.Load()
+}
@@ -0,0 +1,163 @@
+package base
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//ActiveSyncs is a registry of all running syncs of all CLI drivers on this node
+var ActiveSyncs = NewSyncRegistry()
+
+//ActiveSync is a running sync representation
+type ActiveSync struct {
+	SourceID  string    `json:"source_id"`
+	TaskID    string    `json:"task_id"`
+	Driver    string    `json:"driver"`
+	StartedAt time.Time `json:"started_at"`
+	Command   string    `json:"command"`
+}
+
+//registeredSync is a registry entry. Entries are compared by pointer on deregistration
+//so a finished sync can't remove a newer sync with the same task ID
+type registeredSync struct {
+	sourceID    string
+	driver      string
+	startedAt   time.Time
+	syncCommand *SyncCommand
+
+	//mutex guards finished and canceled: a finished sync is never killed and a sync is killed only once
+	mutex    sync.Mutex
+	finished bool
+	canceled bool
+}
+
+//cancel calls beforeCancel and cancels the sync command if the sync isn't finished or canceled yet
+//returns false if the sync has been skipped
+func (rs *registeredSync) cancel(taskID string, beforeCancel func(taskID string) error) (bool, error) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	if rs.finished || rs.canceled {
+		return false, nil
+	}
+
+	if beforeCancel != nil {
+		if err := beforeCancel(taskID); err != nil {
+			return false, err
+		}
+	}
+
+	rs.canceled = true
+	if err := rs.syncCommand.Cancel(); err != nil {
+		return false, fmt.Errorf("error canceling sync command: %v", err)
+	}
+
+	return true, nil
+}
+
+//finish marks the sync as finished. It waits for the cancel in progress
+func (rs *registeredSync) finish() {
+	rs.mutex.Lock()
+	rs.finished = true
+	rs.mutex.Unlock()
+}
+
+//SyncRegistry keeps running syncs per task ID
+type SyncRegistry struct {
+	mutex *sync.RWMutex
+	syncs map[string]*registeredSync
+}
+
+//NewSyncRegistry returns empty SyncRegistry
+func NewSyncRegistry() *SyncRegistry {
+	return &SyncRegistry{
+		mutex: &sync.RWMutex{},
+		syncs: map[string]*registeredSync{},
+	}
+}
+
+//Register adds the sync command into the registry and returns deregistration func which must be called
+//when the sync is finished. The func removes only this registration and is safe to call more than once
+func (sr *SyncRegistry) Register(sourceID, driver string, syncCommand *SyncCommand) func() {
+	entry := &registeredSync{
+		sourceID:    sourceID,
+		driver:      driver,
+		startedAt:   timestamp.Now().UTC(),
+		syncCommand: syncCommand,
+	}
+	taskID := syncCommand.TaskCloser.TaskID()
+
+	sr.mutex.Lock()
+	sr.syncs[taskID] = entry
+	sr.mutex.Unlock()
+
+	return func() {
+		entry.finish()
+
+		sr.mutex.Lock()
+		if sr.syncs[taskID] == entry {
+			delete(sr.syncs, taskID)
+		}
+		sr.mutex.Unlock()
+	}
+}
+
+//List returns all running syncs sorted by start time
+func (sr *SyncRegistry) List() []ActiveSync {
+	sr.mutex.RLock()
+	result := make([]ActiveSync, 0, len(sr.syncs))
+	for taskID, entry := range sr.syncs {
+		result = append(result, ActiveSync{
+			SourceID:  entry.sourceID,
+			TaskID:    taskID,
+			Driver:    entry.driver,
+			StartedAt: entry.startedAt,
+			Command:   entry.syncCommand.Cmd.String(),
+		})
+	}
+	sr.mutex.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].TaskID < result[j].TaskID
+		}
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+
+	return result
+}
+
+//CancelAll cancels all running syncs. beforeCancel is called with every task ID before the sync command is killed
+//(e.g. for marking the task as canceled so it won't be marked as failed). If beforeCancel returns error, the sync isn't canceled
+//Syncs which are finished concurrently are skipped. Returns canceled task IDs and errors of syncs which haven't been canceled
+func (sr *SyncRegistry) CancelAll(beforeCancel func(taskID string) error) ([]string, error) {
+	sr.mutex.RLock()
+	entries := make(map[string]*registeredSync, len(sr.syncs))
+	for taskID, entry := range sr.syncs {
+		entries[taskID] = entry
+	}
+	sr.mutex.RUnlock()
+
+	//commands are killed without the lock: killing might be slow and finished syncs deregister themselves concurrently
+	canceled := make([]string, 0, len(entries))
+	var multiErr error
+	for taskID, entry := range entries {
+		ok, err := entry.cancel(taskID, beforeCancel)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] %v", taskID, err))
+			continue
+		}
+
+		if ok {
+			canceled = append(canceled, taskID)
+		}
+	}
+
+	sort.Strings(canceled)
+	return canceled, multiErr
+}
@@ -0,0 +1,202 @@
+package base
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+type testExecCommand struct {
+	closed   int
+	closeErr error
+}
+
+func (tec *testExecCommand) String() string { return "docker run source" }
+func (tec *testExecCommand) Close() error {
+	tec.closed++
+	return tec.closeErr
+}
+
+type testCLITaskCloser struct {
+	taskID string
+	closed bool
+}
+
+func (ttc *testCLITaskCloser) TaskID() string                            { return ttc.taskID }
+func (ttc *testCLITaskCloser) CloseWithError(msg string, systemErr bool) { ttc.closed = true }
+func (ttc *testCLITaskCloser) HandleCanceling() error                    { return nil }
+
+func newTestSyncCommand(taskID string, closeErr error) *SyncCommand {
+	return &SyncCommand{Cmd: &testExecCommand{closeErr: closeErr}, TaskCloser: &testCLITaskCloser{taskID: taskID}}
+}
+
+func TestSyncRegistryCancelAll(t *testing.T) {
+	tests := []struct {
+		name             string
+		taskIDs          []string
+		finished         map[string]bool
+		closeErrs        map[string]error
+		beforeCancelErrs map[string]error
+		expectedCanceled []string
+		expectedClosed   map[string]bool
+		expectedErr      string
+	}{
+		{
+			"No active syncs",
+			nil,
+			nil,
+			nil,
+			nil,
+			[]string{},
+			map[string]bool{},
+			"",
+		},
+		{
+			"All syncs are canceled",
+			[]string{"task2", "task1"},
+			nil,
+			nil,
+			nil,
+			[]string{"task1", "task2"},
+			map[string]bool{"task1": true, "task2": true},
+			"",
+		},
+		{
+			"Finished syncs are skipped",
+			[]string{"task1", "task2"},
+			map[string]bool{"task1": true},
+			nil,
+			nil,
+			[]string{"task2"},
+			map[string]bool{"task2": true},
+			"",
+		},
+		{
+			"Sync isn't canceled if beforeCancel fails",
+			[]string{"task1", "task2"},
+			nil,
+			nil,
+			map[string]error{"task2": errors.New("task not found")},
+			[]string{"task1"},
+			map[string]bool{"task1": true},
+			"[task2] task not found",
+		},
+		{
+			"Command close error",
+			[]string{"task1"},
+			nil,
+			map[string]error{"task1": errors.New("no such container")},
+			nil,
+			[]string{},
+			map[string]bool{"task1": true},
+			"[task1] error canceling sync command: no such container",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewSyncRegistry()
+			commands := map[string]*SyncCommand{}
+			for _, taskID := range tt.taskIDs {
+				commands[taskID] = newTestSyncCommand(taskID, tt.closeErrs[taskID])
+				deregister := registry.Register("source1", "airbyte", commands[taskID])
+				if tt.finished[taskID] {
+					deregister()
+				}
+			}
+
+			canceled, err := registry.CancelAll(func(taskID string) error {
+				return tt.beforeCancelErrs[taskID]
+			})
+			require.Equal(t, tt.expectedCanceled, canceled)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+			}
+
+			for taskID, command := range commands {
+				expectedClosed := 0
+				if tt.expectedClosed[taskID] {
+					expectedClosed = 1
+				}
+				require.Equal(t, expectedClosed, command.Cmd.(*testExecCommand).closed, taskID)
+				require.Equal(t, tt.expectedClosed[taskID], command.TaskCloser.(*testCLITaskCloser).closed, taskID)
+			}
+
+			//syncs are canceled only once: only syncs with failed beforeCancel are canceled again
+			canceled, _ = registry.CancelAll(nil)
+			for _, taskID := range canceled {
+				require.Error(t, tt.beforeCancelErrs[taskID], "sync [%s] must be canceled only once", taskID)
+			}
+		})
+	}
+}
+
+func TestSyncRegistryList(t *testing.T) {
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	tests := []struct {
+		name            string
+		taskIDs         []string
+		deregistered    map[string]bool
+		expectedTaskIDs []string
+	}{
+		{
+			"Empty",
+			nil,
+			nil,
+			[]string{},
+		},
+		{
+			"Sorted by start time and task ID",
+			[]string{"task3", "task1", "task2"},
+			nil,
+			[]string{"task1", "task2", "task3"},
+		},
+		{
+			"Deregistered syncs aren't listed",
+			[]string{"task1", "task2"},
+			map[string]bool{"task1": true},
+			[]string{"task2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewSyncRegistry()
+			for _, taskID := range tt.taskIDs {
+				deregister := registry.Register("source1", "airbyte", newTestSyncCommand(taskID, nil))
+				if tt.deregistered[taskID] {
+					deregister()
+				}
+			}
+
+			actualTaskIDs := []string{}
+			for _, activeSync := range registry.List() {
+				require.Equal(t, "source1", activeSync.SourceID)
+				require.Equal(t, "airbyte", activeSync.Driver)
+				require.Equal(t, "docker run source", activeSync.Command)
+				require.Equal(t, timestamp.Now().UTC(), activeSync.StartedAt)
+				actualTaskIDs = append(actualTaskIDs, activeSync.TaskID)
+			}
+			require.Equal(t, tt.expectedTaskIDs, actualTaskIDs)
+		})
+	}
+}
+
+func TestSyncRegistryStaleDeregistration(t *testing.T) {
+	registry := NewSyncRegistry()
+	deregisterOld := registry.Register("source1", "airbyte", newTestSyncCommand("task1", nil))
+	deregisterOld()
+	deregisterNew := registry.Register("source1", "airbyte", newTestSyncCommand("task1", nil))
+
+	//the finished sync can't remove the newer sync with the same task ID
+	deregisterOld()
+	require.Len(t, registry.List(), 1)
+
+	deregisterNew()
+	require.Empty(t, registry.List())
+}
@@ -31,6 +31,9 @@ const (
 	GoogleOAuthAuthorizationType = "OAuth"
 
 	DefaultDaysBackToLoad = 365
+
+	//DefaultReadinessTimeout is a max time of waiting for CLI driver readiness before sync
+	DefaultReadinessTimeout = 90 * time.Second
 )
 
 var (
@@ -38,6 +41,14 @@ var (
 	DriverTestConnectionFuncs = make(map[string]func(config *SourceConfig) error)
 
 	errAccountKeyConfiguration = errors.New("service_account_key must be an object, JSON file path or JSON content string")
+
+	//ErrSyncTaskNotFound is returned from TaskCanceler when the driver doesn't have an active sync with the task id
+	ErrSyncTaskNotFound = errors.New("active sync task not found")
+	//ErrSyncTaskCanceled is returned from CLIDriver.Load when the sync has been stopped by TaskCanceler
+	ErrSyncTaskCanceled = errors.New("active sync task has been canceled")
+
+	//readinessPollInterval is an interval of driver readiness checks in WaitReadinessWithTimeout
+	readinessPollInterval = 10 * time.Second
 )
 
 type GoogleAuthConfig struct {
@@ -177,11 +188,41 @@ type CLIDriver interface {
 	GetConfigPath() string
 }
 
+//TaskCanceler is implemented by CLI drivers which can cancel a certain active sync without affecting other ones
+type TaskCanceler interface {
+	//CancelTask kills the active sync command or returns ErrSyncTaskNotFound. The task status isn't changed:
+	//the interrupted Load returns ErrSyncTaskCanceled
+	CancelTask(taskID string) error
+}
+
+//LocalStateDriver is implemented by CLI drivers which can keep the latest state in the local file system.
+//Local state is used when meta storage isn't configured and is migrated into meta storage once it has been configured
+type LocalStateDriver interface {
+	//LoadLocalState returns the latest state from the local file or empty string
+	LoadLocalState() (string, error)
+	//SaveLocalState writes the state into the local file
+	SaveLocalState(state string) error
+	//ClearLocalState removes the local state
+	ClearLocalState() error
+}
+
+//CatalogRefresher is implemented by CLI drivers which cache discovered catalog
+type CatalogRefresher interface {
+	//RefreshCatalog removes the cached catalog and runs discover again
+	RefreshCatalog() error
+}
+
 //CLIDataConsumer is used for consuming CLI drivers output
 type CLIDataConsumer interface {
 	Consume(representation *CLIOutputRepresentation) error
 }
 
+//CLIConfigConsumer is implemented by CLI data consumers which persist connector configs
+//updated by the connector during the sync (e.g. refreshed OAuth tokens)
+type CLIConfigConsumer interface {
+	ConsumeConfig(config map[string]interface{}) error
+}
+
 //CLITaskCloser is used for closing tasks
 type CLITaskCloser interface {
 	TaskID() string
@@ -204,6 +245,9 @@ type StreamRepresentation struct {
 	KeyFields   []string
 	Objects     []map[string]interface{}
 	NeedClean   bool
+	//ParentStream is a source stream of a child stream (e.g. Airbyte basic normalization): the child stream table name
+	//is the parent stream table name with the child stream name suffix
+	ParentStream string
 }
 
 //DriversInfo is a dto for sharing information about the driver into telemetry
@@ -225,29 +269,59 @@ func RegisterTestConnectionFunc(driverType string, testConnectionFunc func(confi
 	DriverTestConnectionFuncs[driverType] = testConnectionFunc
 }
 
-//WaitReadiness waits 90 sec until driver is ready or returns false and notReadyError
-func WaitReadiness(driver CLIDriver, taskLogger logging.TaskLogger) (bool, error) {
+//WorkDirCleaner is a driver which keeps files on disk (e.g. connector configs with secrets)
+type WorkDirCleaner interface {
+	//CleanWorkDir removes the driver files when the source is removed or recreated (after the end of running syncs)
+	CleanWorkDir()
+}
+
+//ReadinessChecker is a driver which might be not ready right after creation (e.g. catalog discovering)
+type ReadinessChecker interface {
+	//IsClosed returns true if the driver is already closed
+	IsClosed() bool
+	//Ready returns true if the driver is ready otherwise returns ErrNotReady
+	Ready() (bool, error)
+	Type() string
+}
+
+//WaitReadiness waits DefaultReadinessTimeout until driver is ready or returns false and notReadyError
+func WaitReadiness(driver ReadinessChecker, taskLogger logging.TaskLogger) (bool, error) {
+	return WaitReadinessWithTimeout(driver, taskLogger, DefaultReadinessTimeout)
+}
+
+//WaitReadinessWithTimeout waits until driver is ready. If driver isn't ready after timeout (DefaultReadinessTimeout if 0)
+//returns false and error with the latest driver not ready error (e.g. catalog discovering error)
+func WaitReadinessWithTimeout(driver ReadinessChecker, taskLogger logging.TaskLogger, timeout time.Duration) (bool, error) {
 	ready, _ := driver.Ready()
 
 	if ready {
 		return true, nil
 	}
 
-	seconds := 0
-	for seconds < 90 {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
 		if driver.IsClosed() {
 			return false, fmt.Errorf("%s already has been closed", driver.Type())
 		}
 
-		ready, _ := driver.Ready()
+		ready, readyErr := driver.Ready()
 		if ready {
 			return true, nil
 		}
 
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false, fmt.Errorf("%s source driver isn't ready after %s: %v", driver.Type(), timeout, readyErr)
+		}
+
 		taskLogger.WARN("waiting for source driver being ready..")
-		time.Sleep(10 * time.Second)
-		seconds += 10
+		if remaining > readinessPollInterval {
+			remaining = readinessPollInterval
+		}
+		time.Sleep(remaining)
 	}
-
-	return driver.Ready()
 }
@@ -0,0 +1,90 @@
+package base
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type testTaskLogger struct{}
+
+func (ttl *testTaskLogger) INFO(format string, v ...interface{})                             {}
+func (ttl *testTaskLogger) ERROR(format string, v ...interface{})                            {}
+func (ttl *testTaskLogger) WARN(format string, v ...interface{})                             {}
+func (ttl *testTaskLogger) LOG(format, system string, level logging.Level, v ...interface{}) {}
+func (ttl *testTaskLogger) Write(p []byte) (n int, err error)                                { return len(p), nil }
+
+type testReadinessChecker struct {
+	readyAfter time.Time
+	closed     bool
+}
+
+func (trc *testReadinessChecker) IsClosed() bool {
+	return trc.closed
+}
+
+func (trc *testReadinessChecker) Ready() (bool, error) {
+	if !trc.readyAfter.IsZero() && time.Now().After(trc.readyAfter) {
+		return true, nil
+	}
+
+	return false, errors.New("not ready: Error discovering catalog: connection refused")
+}
+
+func (trc *testReadinessChecker) Type() string {
+	return AirbyteType
+}
+
+func TestWaitReadinessWithTimeout(t *testing.T) {
+	defaultPollInterval := readinessPollInterval
+	readinessPollInterval = 10 * time.Millisecond
+	defer func() {
+		readinessPollInterval = defaultPollInterval
+	}()
+
+	tests := []struct {
+		name          string
+		driver        *testReadinessChecker
+		timeout       time.Duration
+		expectedReady bool
+		expectedErr   string
+	}{
+		{
+			"never ready",
+			&testReadinessChecker{},
+			100 * time.Millisecond,
+			false,
+			"airbyte source driver isn't ready after 100ms: not ready: Error discovering catalog: connection refused",
+		},
+		{
+			"ready before timeout",
+			&testReadinessChecker{readyAfter: time.Now().Add(50 * time.Millisecond)},
+			time.Second,
+			true,
+			"",
+		},
+		{
+			"closed",
+			&testReadinessChecker{closed: true},
+			time.Second,
+			false,
+			"airbyte already has been closed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			ready, err := WaitReadinessWithTimeout(tt.driver, &testTaskLogger{}, tt.timeout)
+			require.Equal(t, tt.expectedReady, ready)
+			require.Less(t, int64(time.Since(start)), int64(tt.timeout+time.Second), "timeout must fire")
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -425,6 +425,8 @@ func (s *Singer) loadAndParse(taskLogger logging.TaskLogger, cliParser base.CLIP
 	if err != nil {
 		return err
 	}
+	//registered only after start: the command process doesn't exist before
+	defer base.ActiveSyncs.Register(s.ID(), s.Type(), syncCommand)()
 
 	var wg sync.WaitGroup
 	var parsingErr error
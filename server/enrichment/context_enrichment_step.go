@@ -30,6 +30,10 @@ func ContextEnrichmentStep(payload events.Event, token string, reqContext *event
 	//3. unique ID field
 	//extract 1.0 format -> 1.0 flat format -> 2.0 format
 	eventID := uniqueIDField.ExtractAndRemove(payload)
+	if eventID == "" {
+		//candidate fields or fallback strategy value is assigned once before the event is multiplexed to destinations
+		eventID = uniqueIDField.Fallback(payload)
+	}
 	if eventID == "" {
 		eventID = uuid.New()
 	}
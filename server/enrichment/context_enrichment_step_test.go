@@ -3,6 +3,7 @@ package enrichment
 import (
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/spf13/viper"
@@ -146,6 +147,28 @@ func TestWithAPIPreprocess(t *testing.T) {
 	}
 }
 
+func TestUniqueIDFallback(t *testing.T) {
+	SetTestDefaultParams()
+	uuid.InitMock()
+
+	require.NoError(t, appconfig.Init(false, ""))
+	defer appconfig.Instance.Close()
+	defer appconfig.Instance.CloseEventsConsumers()
+
+	uniqueIDField, err := identifiers.NewUniqueID("/eventn_ctx/event_id").WithFallback([]string{"/message_id"}, identifiers.FallbackStrategyHash)
+	require.NoError(t, err)
+
+	withCandidate := map[string]interface{}{"message_id": "msg1"}
+	ContextEnrichmentStep(withCandidate, "token", &events.RequestContext{}, events.NewAPIProcessor(), uniqueIDField)
+	require.Equal(t, map[string]interface{}{"event_id": "msg1"}, withCandidate["eventn_ctx"])
+
+	withoutCandidate := map[string]interface{}{"field": "value"}
+	ContextEnrichmentStep(withoutCandidate, "token", &events.RequestContext{}, events.NewAPIProcessor(), uniqueIDField)
+	eventID := uniqueIDField.Extract(withoutCandidate)
+	require.NotEmpty(t, eventID)
+	require.NotEqual(t, "mockeduuid", eventID, "hash fallback strategy must be used")
+}
+
 func SetTestDefaultParams() {
 	viper.Set("log.path", "")
 	viper.Set("server.auth", `{"tokens":[{"id":"id1","client_secret":"c2stoken","server_secret":"s2stoken","origins":["whiteorigin*"]}]}`)
@@ -0,0 +1,441 @@
+package enrichment
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/maputils"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/safego"
+)
+
+const (
+	Lookup = "lookup"
+
+	//LookupMissingNull sets nulls into derived columns if the key doesn't exist in the lookup table
+	LookupMissingNull = "null"
+	//LookupMissingSkip passes the event as is if the key doesn't exist in the lookup table
+	LookupMissingSkip = "skip"
+
+	defaultLookupReloadSec = 300
+)
+
+//lookup tables (CSV and SQL) are shared by rules with the same source: every source is loaded and reloaded once
+var (
+	lookupTablesMutex = &sync.Mutex{}
+	lookupTables      = map[string]*lookupTable{}
+)
+
+//LookupConfig is a lookup table configuration of the lookup rule. Only one of Data, CSV, SQL must be set
+type LookupConfig struct {
+	//Data is a static lookup table: key -> value (a string or an object with several columns)
+	Data map[string]interface{} `mapstructure:"data" json:"data,omitempty" yaml:"data,omitempty"`
+	//CSV is a path to a local CSV file or http(s) URL. The first line is a header
+	CSV string `mapstructure:"csv" json:"csv,omitempty" yaml:"csv,omitempty"`
+	//SQL is a query of a small table
+	SQL *LookupSQLConfig `mapstructure:"sql" json:"sql,omitempty" yaml:"sql,omitempty"`
+	//KeyColumn is a CSV or SQL column with keys (the first column by default). Other columns are added into the event
+	KeyColumn string `mapstructure:"key_column" json:"key_column,omitempty" yaml:"key_column,omitempty"`
+	//ReloadSec is a reload interval of CSV and SQL lookup tables
+	ReloadSec int `mapstructure:"reload_sec" json:"reload_sec,omitempty" yaml:"reload_sec,omitempty"`
+	//Missing is LookupMissingNull (default) or LookupMissingSkip
+	Missing string `mapstructure:"missing" json:"missing,omitempty" yaml:"missing,omitempty"`
+}
+
+//LookupSQLConfig is a lookup table query configuration
+type LookupSQLConfig struct {
+	//Driver is one of postgres, mysql, clickhouse, snowflake
+	Driver string `mapstructure:"driver" json:"driver,omitempty" yaml:"driver,omitempty"`
+	DSN    string `mapstructure:"dsn" json:"dsn,omitempty" yaml:"dsn,omitempty"`
+	Query  string `mapstructure:"query" json:"query,omitempty" yaml:"query,omitempty"`
+}
+
+//Validate returns err if the lookup configuration is invalid and sets default values
+func (lc *LookupConfig) Validate() error {
+	if lc == nil {
+		return errors.New("'lookup' is required lookup rule parameter")
+	}
+
+	sources := 0
+	if len(lc.Data) > 0 {
+		sources++
+	}
+	if lc.CSV != "" {
+		sources++
+	}
+	if lc.SQL != nil {
+		sources++
+		switch lc.SQL.Driver {
+		case "postgres", "mysql", "clickhouse", "snowflake":
+		default:
+			return fmt.Errorf("lookup sql.driver must be one of [postgres, mysql, clickhouse, snowflake], got: %q", lc.SQL.Driver)
+		}
+		if lc.SQL.DSN == "" || lc.SQL.Query == "" {
+			return errors.New("lookup sql.dsn and sql.query are required")
+		}
+	}
+	if sources != 1 {
+		return errors.New("lookup must have one of 'data', 'csv' or 'sql'")
+	}
+
+	if lc.Missing == "" {
+		lc.Missing = LookupMissingNull
+	}
+	if lc.Missing != LookupMissingNull && lc.Missing != LookupMissingSkip {
+		return fmt.Errorf("lookup missing must be one of [%s, %s], got: %q", LookupMissingNull, LookupMissingSkip, lc.Missing)
+	}
+
+	if lc.ReloadSec < 0 {
+		return errors.New("lookup reload_sec must be positive")
+	}
+	if lc.ReloadSec == 0 {
+		lc.ReloadSec = defaultLookupReloadSec
+	}
+
+	return nil
+}
+
+//LookupRule joins events with the lookup table by the key from the 'from' path
+//and sets the found row into the 'to' path (derived columns)
+type LookupRule struct {
+	source      jsonutils.JSONPath
+	destination jsonutils.JSONPath
+	table       *lookupTable
+	skipMissing bool
+}
+
+//NewLookupRule returns LookupRule with loaded lookup table
+func NewLookupRule(source, destination jsonutils.JSONPath, lookupConfig *LookupConfig) (*LookupRule, error) {
+	if err := lookupConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	table, err := getLookupTable(lookupConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LookupRule{
+		source:      source,
+		destination: destination,
+		table:       table,
+		skipMissing: lookupConfig.Missing == LookupMissingSkip,
+	}, nil
+}
+
+//Execute sets the lookup table row by the event key. Missing keys are counted and set as nulls (unless skipMissing)
+//existing values aren't overwritten
+func (lr *LookupRule) Execute(event map[string]interface{}) {
+	keyIface, ok := lr.source.Get(event)
+	if !ok || keyIface == nil {
+		return
+	}
+
+	row, ok := lr.table.get(fmt.Sprint(keyIface))
+	if !ok {
+		metrics.LookupEnrichmentEvents(lr.destination.String(), metrics.LookupMiss)
+		if lr.skipMissing {
+			return
+		}
+		row = lr.table.emptyRow()
+	} else {
+		metrics.LookupEnrichmentEvents(lr.destination.String(), metrics.LookupHit)
+	}
+
+	if object, ok := row.(map[string]interface{}); ok {
+		row = maputils.CopyMap(object)
+	}
+
+	if err := lr.destination.SetIfNotExist(event, row); err != nil {
+		logging.SystemErrorf("Lookup enrichment data wasn't set: %v", err)
+	}
+}
+
+func (lr *LookupRule) Name() string {
+	return Lookup
+}
+
+//lookupTable is a reloadable key -> row cache
+type lookupTable struct {
+	mutex *sync.RWMutex
+	rows  map[string]interface{}
+	//columns is a union of object rows columns. Missing keys are set as objects with these columns with null values
+	columns []string
+	//caseInsensitive is true for static tables: keys of YAML configs are lowercased
+	caseInsensitive bool
+}
+
+func newLookupTable() *lookupTable {
+	return &lookupTable{mutex: &sync.RWMutex{}, rows: map[string]interface{}{}}
+}
+
+func (lt *lookupTable) get(key string) (interface{}, bool) {
+	lt.mutex.RLock()
+	defer lt.mutex.RUnlock()
+
+	if lt.caseInsensitive {
+		key = strings.ToLower(key)
+	}
+	row, ok := lt.rows[key]
+	return row, ok
+}
+
+//emptyRow returns nil or an object with all columns with null values
+func (lt *lookupTable) emptyRow() interface{} {
+	lt.mutex.RLock()
+	defer lt.mutex.RUnlock()
+
+	if len(lt.columns) == 0 {
+		return nil
+	}
+
+	row := make(map[string]interface{}, len(lt.columns))
+	for _, column := range lt.columns {
+		row[column] = nil
+	}
+	return row
+}
+
+//set replaces all rows
+func (lt *lookupTable) set(rows map[string]interface{}) {
+	uniqueColumns := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		if object, ok := row.(map[string]interface{}); ok {
+			for column := range object {
+				if !uniqueColumns[column] {
+					uniqueColumns[column] = true
+					columns = append(columns, column)
+				}
+			}
+		}
+	}
+
+	lt.mutex.Lock()
+	lt.rows = rows
+	lt.columns = columns
+	lt.mutex.Unlock()
+}
+
+//getLookupTable returns the static table or the shared loaded table which is reloaded in background
+func getLookupTable(lookupConfig *LookupConfig) (*lookupTable, error) {
+	if len(lookupConfig.Data) > 0 {
+		rows := make(map[string]interface{}, len(lookupConfig.Data))
+		for key, row := range lookupConfig.Data {
+			rows[strings.ToLower(key)] = row
+		}
+		table := newLookupTable()
+		table.caseInsensitive = true
+		table.set(rows)
+		return table, nil
+	}
+
+	reloadEvery := time.Duration(lookupConfig.ReloadSec) * time.Second
+	var key string
+	if lookupConfig.CSV != "" {
+		key = "csv:" + lookupConfig.CSV
+	} else {
+		key = "sql:" + lookupConfig.SQL.Driver + lookupConfig.SQL.DSN + lookupConfig.SQL.Query
+	}
+	key += "|" + lookupConfig.KeyColumn + "|" + reloadEvery.String()
+
+	lookupTablesMutex.Lock()
+	defer lookupTablesMutex.Unlock()
+
+	if table, ok := lookupTables[key]; ok {
+		return table, nil
+	}
+
+	table := newLookupTable()
+	if lookupConfig.CSV != "" {
+		if err := watchCSVLookupTable(table, lookupConfig.CSV, lookupConfig.KeyColumn, reloadEvery); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := watchSQLLookupTable(table, lookupConfig.SQL, lookupConfig.KeyColumn, reloadEvery); err != nil {
+			return nil, err
+		}
+	}
+
+	lookupTables[key] = table
+	return table, nil
+}
+
+//watchCSVLookupTable loads the CSV file (or URL) into the table and reloads it if it has been changed
+func watchCSVLookupTable(table *lookupTable, source, keyColumn string, reloadEvery time.Duration) error {
+	loadFunc := loadFile
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		loadFunc = resources.LoadFromHTTP
+	}
+
+	payload, err := loadFunc(source, "")
+	if err != nil {
+		return fmt.Errorf("Error loading lookup CSV: %v", err)
+	}
+	rows, err := parseCSVLookupTable(payload.Content, keyColumn)
+	if err != nil {
+		return err
+	}
+	table.set(rows)
+
+	resources.Watch("lookup_"+resources.GetBytesHash([]byte(source)), source, loadFunc, func(content []byte) {
+		rows, err := parseCSVLookupTable(content, keyColumn)
+		if err != nil {
+			logging.Errorf("Error reloading lookup CSV: %v. The previous lookup table will be used", err)
+			return
+		}
+		table.set(rows)
+	}, reloadEvery)
+
+	return nil
+}
+
+//parseCSVLookupTable returns key -> row (other columns) from CSV with header
+func parseCSVLookupTable(content []byte, keyColumn string) (map[string]interface{}, error) {
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing lookup CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("lookup CSV must have a header")
+	}
+
+	header := records[0]
+	keyIndex, err := keyColumnIndex(header, keyColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[string]interface{}, len(records)-1)
+	for _, record := range records[1:] {
+		row := map[string]interface{}{}
+		for i, value := range record {
+			if i != keyIndex {
+				row[header[i]] = value
+			}
+		}
+		rows[record[keyIndex]] = row
+	}
+
+	return rows, nil
+}
+
+//watchSQLLookupTable loads the query result into the table and reloads it every reloadEvery
+func watchSQLLookupTable(table *lookupTable, sqlConfig *LookupSQLConfig, keyColumn string, reloadEvery time.Duration) error {
+	dataSource, err := sql.Open(sqlConfig.Driver, sqlConfig.DSN)
+	if err != nil {
+		return fmt.Errorf("Error opening lookup %s connection: %v", sqlConfig.Driver, redactDSN(err, sqlConfig.DSN))
+	}
+	dataSource.SetMaxOpenConns(1)
+
+	rows, err := querySQLLookupTable(dataSource, sqlConfig.Query, keyColumn)
+	if err != nil {
+		dataSource.Close()
+		return fmt.Errorf("Error loading lookup %s table: %v", sqlConfig.Driver, redactDSN(err, sqlConfig.DSN))
+	}
+	table.set(rows)
+
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(reloadEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			rows, err := querySQLLookupTable(dataSource, sqlConfig.Query, keyColumn)
+			if err != nil {
+				logging.Errorf("Error reloading lookup %s table: %v. The previous lookup table will be used", sqlConfig.Driver, redactDSN(err, sqlConfig.DSN))
+				continue
+			}
+			table.set(rows)
+		}
+	})
+
+	return nil
+}
+
+//querySQLLookupTable returns key -> row (other columns) from the query result
+func querySQLLookupTable(dataSource *sql.DB, query, keyColumn string) (map[string]interface{}, error) {
+	sqlRows, err := dataSource.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer sqlRows.Close()
+
+	columns, err := sqlRows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	keyIndex, err := keyColumnIndex(columns, keyColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := map[string]interface{}{}
+	for sqlRows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := sqlRows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		for i, value := range values {
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if values[keyIndex] == nil {
+			continue
+		}
+
+		row := map[string]interface{}{}
+		for i, value := range values {
+			if i != keyIndex {
+				row[strings.ToLower(columns[i])] = value
+			}
+		}
+		rows[fmt.Sprint(values[keyIndex])] = row
+	}
+
+	return rows, sqlRows.Err()
+}
+
+//keyColumnIndex returns index of the key column (the first column if keyColumn is empty)
+func keyColumnIndex(columns []string, keyColumn string) (int, error) {
+	if keyColumn == "" {
+		return 0, nil
+	}
+
+	for i, column := range columns {
+		if strings.EqualFold(column, keyColumn) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("lookup key column %q doesn't exist in %v", keyColumn, columns)
+}
+
+//redactDSN returns the error message without the DSN (it contains credentials)
+func redactDSN(err error, dsn string) string {
+	return strings.ReplaceAll(err.Error(), dsn, logging.RedactedValue)
+}
+
+//loadFile returns the file content. Lookup CSV files don't have last-modified logic: the content hash is compared by the watcher
+func loadFile(filePath, lastModified string) (*resources.ResponsePayload, error) {
+	b, err := ioutil.ReadFile(strings.TrimPrefix(filePath, "file://"))
+	if err != nil {
+		return nil, fmt.Errorf("Error loading file %s: %v", filePath, err)
+	}
+
+	return &resources.ResponsePayload{Content: b}, nil
+}
@@ -0,0 +1,116 @@
+package enrichment
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupRuleStaticData(t *testing.T) {
+	countries := map[string]interface{}{
+		"de": map[string]interface{}{"name": "Germany", "region": "Europe"},
+		"us": map[string]interface{}{"name": "United States", "region": "North America"},
+	}
+
+	tests := []struct {
+		name     string
+		missing  string
+		input    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			"found key",
+			"",
+			map[string]interface{}{"country_code": "DE"},
+			map[string]interface{}{"country_code": "DE", "country": map[string]interface{}{"name": "Germany", "region": "Europe"}},
+		},
+		{
+			"missing key with nulls",
+			LookupMissingNull,
+			map[string]interface{}{"country_code": "FR"},
+			map[string]interface{}{"country_code": "FR", "country": map[string]interface{}{"name": nil, "region": nil}},
+		},
+		{
+			"missing key is skipped",
+			LookupMissingSkip,
+			map[string]interface{}{"country_code": "FR"},
+			map[string]interface{}{"country_code": "FR"},
+		},
+		{
+			"event without key",
+			"",
+			map[string]interface{}{"field": "value"},
+			map[string]interface{}{"field": "value"},
+		},
+		{
+			"existing value isn't overwritten",
+			"",
+			map[string]interface{}{"country_code": "US", "country": "custom"},
+			map[string]interface{}{"country_code": "US", "country": "custom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewRule(&RuleConfig{Name: Lookup, From: "/country_code", To: "/country",
+				Lookup: &LookupConfig{Data: countries, Missing: tt.missing}}, nil, "")
+			require.NoError(t, err)
+
+			rule.Execute(tt.input)
+			require.Equal(t, tt.expected, tt.input)
+		})
+	}
+}
+
+func TestLookupRuleCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup_csv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	csvPath := path.Join(dir, "countries.csv")
+	require.NoError(t, ioutil.WriteFile(csvPath, []byte("name,code\nGermany,DE\nUnited States,US\n"), 0644))
+
+	rule, err := NewLookupRule(jsonutils.NewJSONPath("/location/country"), jsonutils.NewJSONPath("/country"), &LookupConfig{CSV: csvPath, KeyColumn: "code"})
+	require.NoError(t, err)
+
+	event := map[string]interface{}{"location": map[string]interface{}{"country": "US"}}
+	rule.Execute(event)
+	require.Equal(t, map[string]interface{}{"name": "United States"}, event["country"])
+
+	//keys of CSV tables are case-sensitive
+	event = map[string]interface{}{"location": map[string]interface{}{"country": "us"}}
+	rule.Execute(event)
+	require.Equal(t, map[string]interface{}{"name": nil}, event["country"])
+
+	_, err = NewLookupRule(jsonutils.NewJSONPath("/country_code"), jsonutils.NewJSONPath("/country"), &LookupConfig{CSV: csvPath, KeyColumn: "iso"})
+	require.Error(t, err)
+}
+
+func TestLookupConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *LookupConfig
+		expectedErr string
+	}{
+		{"empty", &LookupConfig{}, "lookup must have one of 'data', 'csv' or 'sql'"},
+		{"several sources", &LookupConfig{Data: map[string]interface{}{"de": "Germany"}, CSV: "/countries.csv"}, "lookup must have one of 'data', 'csv' or 'sql'"},
+		{"unknown sql driver", &LookupConfig{SQL: &LookupSQLConfig{Driver: "oracle", DSN: "dsn", Query: "SELECT 1"}}, `lookup sql.driver must be one of [postgres, mysql, clickhouse, snowflake], got: "oracle"`},
+		{"unknown missing", &LookupConfig{Data: map[string]interface{}{"de": "Germany"}, Missing: "drop"}, `lookup missing must be one of [null, skip], got: "drop"`},
+		{"valid", &LookupConfig{Data: map[string]interface{}{"de": "Germany"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				require.Equal(t, LookupMissingNull, tt.config.Missing)
+				require.Equal(t, defaultLookupReloadSec, tt.config.ReloadSec)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
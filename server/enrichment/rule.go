@@ -33,6 +33,8 @@ func NewRule(ruleConfig *RuleConfig, geoService *geo.Service, geoResolverID stri
 		return NewIPLookupRule(source, destination, geoService, geoResolverID)
 	case UserAgentParse:
 		return NewUserAgentParseRule(source, destination)
+	case Lookup:
+		return NewLookupRule(source, destination, ruleConfig.Lookup)
 	default:
 		return nil, fmt.Errorf("Unsupported enrichment rule type: %s", ruleConfig.Name)
 	}
@@ -43,6 +45,8 @@ type RuleConfig struct {
 	Name string `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty"`
 	From string `mapstructure:"from" json:"from,omitempty" yaml:"from,omitempty"`
 	To   string `mapstructure:"to" json:"to,omitempty" yaml:"to,omitempty"`
+	//Lookup is used only by the lookup rule
+	Lookup *LookupConfig `mapstructure:"lookup" json:"lookup,omitempty" yaml:"lookup,omitempty"`
 }
 
 func (r *RuleConfig) Validate() error {
@@ -92,6 +92,11 @@ func (dbq *DQueBasedQueue) DequeueBlock() (Event, time.Time, string, error) {
 	return fact, wrappedFact.DequeuedTime, wrappedFact.TokenID, nil
 }
 
+//Size returns the number of enqueued events
+func (dbq *DQueBasedQueue) Size() int64 {
+	return int64(dbq.queue.Size())
+}
+
 //Close closes underlying queue and returns err if occurred
 // *Note: dque.ErrQueueClosed will be ignored
 func (dbq *DQueBasedQueue) Close() error {
@@ -33,6 +33,10 @@ type FailedEvent struct {
 	Event          json.RawMessage `json:"event,omitempty"`
 	Error          string          `json:"error,omitempty"`
 	EventID        string          `json:"event_id,omitempty"`
+	TableName      string          `json:"table_name,omitempty"`
+	Classification string          `json:"classification,omitempty"`
+	Attempt        int             `json:"attempt,omitempty"`
+	CorrelationID  string          `json:"correlation_id,omitempty"`
 }
 
 //FailedEvents is a dto for keeping fallback events per src
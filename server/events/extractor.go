@@ -15,3 +15,17 @@ func ExtractSrc(event Event) string {
 
 	return ""
 }
+
+//ExtractEventType returns 'event_type' field from input event or an empty string
+func ExtractEventType(event Event) string {
+	if event == nil {
+		return ""
+	}
+
+	eventType, ok := event[EventType]
+	if ok {
+		return fmt.Sprint(eventType)
+	}
+
+	return ""
+}
@@ -71,6 +71,11 @@ func (ldq *LevelDBQueue) DequeueBlock() (Event, time.Time, string, error) {
 	return fact, qe.DequeuedTime, qe.TokenID, nil
 }
 
+//Size returns the number of enqueued events
+func (ldq *LevelDBQueue) Size() int64 {
+	return int64(ldq.queue.Size())
+}
+
 //Close closes underlying queue
 func (ldq *LevelDBQueue) Close() error {
 	return ldq.queue.Close()
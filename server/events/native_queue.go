@@ -106,6 +106,11 @@ func (q *NativeQueue) DequeueBlock() (Event, time.Time, string, error) {
 	return te.Payload, te.DequeuedTime, te.TokenID, nil
 }
 
+//Size returns the number of enqueued events
+func (q *NativeQueue) Size() int64 {
+	return q.queue.Size()
+}
+
 //Close closes underlying queue
 func (q *NativeQueue) Close() error {
 	select {
@@ -22,6 +22,7 @@ type Queue interface {
 	Consume(f map[string]interface{}, tokenID string)
 	ConsumeTimed(f map[string]interface{}, t time.Time, tokenID string)
 	DequeueBlock() (Event, time.Time, string, error)
+	Size() int64
 }
 
 type QueueFactory struct {
@@ -0,0 +1,193 @@
+package fallback
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	//streamingArchiveFileMask is a mask of rotated streaming archive files: plain (not archived yet) and gzipped (in date folders)
+	streamingArchiveFileMask = "streaming-archive.dst=*-20*.log*"
+	//rotationTimeLayout is a layout of the rotation time (UTC) in rotated log file names
+	rotationTimeLayout = "2006-01-02T15-04-05.000"
+
+	archiveReplayChunkSize = 1000
+)
+
+var streamingArchiveFileRegexp = regexp.MustCompile(`^streaming-archive\.dst=(.*)-(\d\d\d\d-\d\d-\d\dT\d\d-\d\d-\d\d\.\d\d\d)\.log(\.gz)?$`)
+
+//ArchiveReplayResult is a result of replaying archived streaming events
+type ArchiveReplayResult struct {
+	Files    int `json:"files"`
+	Replayed int `json:"replayed"`
+	//Skipped is the number of events with already replayed unique ID
+	Skipped int `json:"skipped"`
+}
+
+//archiveFile is a rotated streaming archive file
+type archiveFile struct {
+	path      string
+	rotatedAt time.Time
+}
+
+//ReplayArchive stores streaming events of the sourceDestinationID archive (destinationID archive if empty) which were received within [from, to]
+//into the destinationID destination with SyncStore: the destination mapping and transformation are applied again.
+//Events with the same unique ID are stored only once. Only rotated archive files are read
+func (s *Service) ReplayArchive(destinationID, sourceDestinationID string, from, to time.Time) (*ArchiveReplayResult, error) {
+	if destinationID == "" {
+		return nil, errors.New("destination_id can't be empty")
+	}
+	if sourceDestinationID == "" {
+		sourceDestinationID = destinationID
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("start [%s] must be before end [%s]", timestamp.ToISOFormat(from), timestamp.ToISOFormat(to))
+	}
+
+	lockKey := "archive:" + destinationID
+	if _, loaded := s.locks.LoadOrStore(lockKey, true); loaded {
+		return nil, fmt.Errorf("Archive is being replayed into destination [%s]", destinationID)
+	}
+	defer s.locks.Delete(lockKey)
+
+	storageProxy, ok := s.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] wasn't found", destinationID)
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] hasn't been initialized yet", destinationID)
+	}
+	if storage.IsStaging() {
+		return nil, fmt.Errorf("Error replaying archive into destination [%s] in staged mode, "+
+			"cannot be used to store data (only available for dry-run)", destinationID)
+	}
+
+	files, err := findArchiveFiles(s.archiveDir, sourceDestinationID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.replayArchiveFiles(files, storage.GetUniqueIDField(), from, to, func(objects []map[string]interface{}) error {
+		return storage.SyncStore(nil, objects, "", false)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	logging.Infof("[%s] Archive of [%s] from %s to %s has been replayed: %d files, %d events replayed, %d duplicates skipped", destinationID,
+		sourceDestinationID, timestamp.ToISOFormat(from), timestamp.ToISOFormat(to), result.Files, result.Replayed, result.Skipped)
+	return result, nil
+}
+
+//replayArchiveFiles passes events within [from, to] from files to syncStore by chunks skipping events with already passed unique ID
+//returns the result with counters of already stored chunks on error
+func (s *Service) replayArchiveFiles(files []*archiveFile, uniqueIDField *identifiers.UniqueID, from, to time.Time,
+	syncStore func(objects []map[string]interface{}) error) (*ArchiveReplayResult, error) {
+	result := &ArchiveReplayResult{}
+	replayedIDs := map[string]bool{}
+	var chunk []map[string]interface{}
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := syncStore(chunk); err != nil {
+			return fmt.Errorf("Error storing replayed events: %v", err)
+		}
+
+		result.Replayed += len(chunk)
+		chunk = nil
+		return nil
+	}
+
+	for _, file := range files {
+		b, err := s.readFileBytes(file.path)
+		if err != nil {
+			return result, err
+		}
+
+		objects, parseErrors, err := parsers.ParseJSONFileWithFuncFallback(b, parsers.ParseJSON)
+		if err != nil {
+			return result, fmt.Errorf("Error parsing archive file %s: %v", file.path, err)
+		}
+		if len(parseErrors) > 0 {
+			logging.Warnf("%d malformed events of archive file %s will be skipped", len(parseErrors), file.path)
+		}
+		result.Files++
+
+		for _, object := range objects {
+			eventTime, ok := timestamp.FromField(object, timestamp.Key)
+			if !ok || eventTime.Before(from) || eventTime.After(to) {
+				continue
+			}
+
+			//events without unique ID can't be deduplicated
+			if eventID := uniqueIDField.Extract(object); eventID != "" {
+				if replayedIDs[eventID] {
+					result.Skipped++
+					continue
+				}
+				replayedIDs[eventID] = true
+			}
+
+			chunk = append(chunk, object)
+			if len(chunk) >= archiveReplayChunkSize {
+				if err := flush(); err != nil {
+					return result, err
+				}
+			}
+		}
+	}
+
+	return result, flush()
+}
+
+//findArchiveFiles returns rotated streaming archive files of the destination which might contain events received after from
+//sorted by the rotation time
+func findArchiveFiles(archiveDir, destinationID string, from time.Time) ([]*archiveFile, error) {
+	var paths []string
+	for _, mask := range []string{path.Join(archiveDir, streamingArchiveFileMask), path.Join(archiveDir, "*", streamingArchiveFileMask)} {
+		matched, err := filepath.Glob(mask)
+		if err != nil {
+			return nil, fmt.Errorf("Error finding archive files by mask [%s]: %v", mask, err)
+		}
+		paths = append(paths, matched...)
+	}
+
+	var files []*archiveFile
+	for _, filePath := range paths {
+		regexResult := streamingArchiveFileRegexp.FindStringSubmatch(filepath.Base(filePath))
+		if len(regexResult) < 3 || regexResult[1] != destinationID {
+			continue
+		}
+
+		rotatedAt, err := time.Parse(rotationTimeLayout, regexResult[2])
+		if err != nil {
+			logging.Warnf("Archive file %s will be skipped: malformed rotation time: %v", filePath, err)
+			continue
+		}
+		//all file events have been received before the rotation
+		if rotatedAt.Before(from) {
+			continue
+		}
+
+		files = append(files, &archiveFile{path: filePath, rotatedAt: rotatedAt})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].rotatedAt.Before(files[j].rotatedAt)
+	})
+
+	return files, nil
+}
@@ -0,0 +1,79 @@
+package fallback
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/stretchr/testify/require"
+)
+
+func writeArchiveFile(t *testing.T, filePath string, lines ...string) {
+	require.NoError(t, os.MkdirAll(path.Dir(filePath), 0744))
+	b := []byte{}
+	for _, line := range lines {
+		b = append(b, []byte(line+"\n")...)
+	}
+
+	if path.Ext(filePath) == ".gz" {
+		buf := bytes.Buffer{}
+		gzw := gzip.NewWriter(&buf)
+		_, err := gzw.Write(b)
+		require.NoError(t, err)
+		require.NoError(t, gzw.Close())
+		b = buf.Bytes()
+	}
+
+	require.NoError(t, ioutil.WriteFile(filePath, b, 0644))
+}
+
+func TestReplayArchive(t *testing.T) {
+	archiveDir, err := ioutil.TempDir("", "archive_replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(archiveDir)
+
+	//rotated before the range start
+	writeArchiveFile(t, path.Join(archiveDir, "2021-10-01", "streaming-archive.dst=dst1-2021-10-01T09-00-00.000.log.gz"),
+		`{"eventn_ctx": {"event_id": "0"}, "_timestamp": "2021-10-01T08:30:00.000000Z"}`)
+	writeArchiveFile(t, path.Join(archiveDir, "2021-10-01", "streaming-archive.dst=dst1-2021-10-01T10-00-00.000.log.gz"),
+		`{"eventn_ctx": {"event_id": "1"}, "_timestamp": "2021-10-01T09:10:00.000000Z"}`,
+		`{"eventn_ctx": {"event_id": "2"}, "_timestamp": "2021-10-01T09:30:00.000000Z"}`,
+		`{"eventn_ctx": {"event_id": "3"}, "_timestamp": "2021-10-01T09:50:00.000000Z"}`)
+	//another destination
+	writeArchiveFile(t, path.Join(archiveDir, "2021-10-01", "streaming-archive.dst=dst1-copy-2021-10-01T10-00-00.000.log.gz"),
+		`{"eventn_ctx": {"event_id": "5"}, "_timestamp": "2021-10-01T09:30:00.000000Z"}`)
+	//rotated but not archived yet
+	writeArchiveFile(t, path.Join(archiveDir, "streaming-archive.dst=dst1-2021-10-01T11-30-00.000.log"),
+		`{"eventn_ctx": {"event_id": "3"}, "_timestamp": "2021-10-01T09:50:00.000000Z"}`,
+		`malformed`,
+		`{"eventn_ctx": {"event_id": "4"}, "_timestamp": "2021-10-01T10:30:00.000000Z"}`,
+		`{"eventn_ctx": {"event_id": "6"}, "_timestamp": "2021-10-01T11:10:00.000000Z"}`)
+	//current file
+	writeArchiveFile(t, path.Join(archiveDir, "streaming-archive.dst=dst1.log"),
+		`{"eventn_ctx": {"event_id": "7"}, "_timestamp": "2021-10-01T10:40:00.000000Z"}`)
+
+	from := time.Date(2021, 10, 1, 9, 15, 0, 0, time.UTC)
+	to := time.Date(2021, 10, 1, 11, 0, 0, 0, time.UTC)
+	files, err := findArchiveFiles(archiveDir, "dst1", from)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, "streaming-archive.dst=dst1-2021-10-01T10-00-00.000.log.gz", path.Base(files[0].path))
+	require.Equal(t, "streaming-archive.dst=dst1-2021-10-01T11-30-00.000.log", path.Base(files[1].path))
+
+	var replayedIDs []string
+	uniqueIDField := identifiers.NewUniqueID("/eventn_ctx/event_id")
+	result, err := NewTestService().replayArchiveFiles(files, uniqueIDField, from, to, func(objects []map[string]interface{}) error {
+		for _, object := range objects {
+			replayedIDs = append(replayedIDs, uniqueIDField.Extract(object))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2", "3", "4"}, replayedIDs)
+	require.Equal(t, &ArchiveReplayResult{Files: 2, Replayed: 3, Skipped: 1}, result)
+}
@@ -32,6 +32,7 @@ var destinationIDExtractRegexp = regexp.MustCompile("failed.dst=(.*)-\\d\\d\\d\\
 //Service stores and processes fallback files
 type Service struct {
 	fallbackDir        string
+	archiveDir         string
 	fileMask           string
 	statusManager      *logfiles.StatusManager
 	destinationService *destinations.Service
@@ -56,6 +57,7 @@ func NewService(logEventsPath string, destinationService *destinations.Service,
 	}
 	return &Service{
 		fallbackDir:        fallbackPath,
+		archiveDir:         logArchiveEventPath,
 		statusManager:      statusManager,
 		fileMask:           path.Join(fallbackPath, fallbackFileMaskPostfix),
 		destinationService: destinationService,
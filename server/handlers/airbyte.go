@@ -12,18 +12,27 @@ import (
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/oauth"
 	"github.com/jitsucom/jitsu/server/runner"
+	"github.com/jitsucom/jitsu/server/sources"
+	"github.com/jitsucom/jitsu/server/synchronization"
 	"github.com/jitsucom/jitsu/server/utils"
 	"github.com/spf13/viper"
 	"io/ioutil"
 	"net/http"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	dockerHubURLTemplate = "https://hub.docker.com/v2/repositories/%s/%s/tags?page_size=1000"
 	defaultTimeout       = 40 * time.Second
+	//defaultDiscoverTimeout is used if airbyte-bridge.discover_timeout_sec isn't configured
+	defaultDiscoverTimeout = 3 * time.Minute
+
+	//batchVersionsConcurrency is a max number of concurrent DockerHub requests in BatchVersionsHandler
+	batchVersionsConcurrency = 5
+	batchVersionsMaxImages   = 100
 )
 
 //DockerHubResponse is a DockerHub tags response dto
@@ -42,6 +51,24 @@ type VersionsResponse struct {
 	Versions []string `json:"versions"`
 }
 
+//BatchVersionsRequest is a dto for requesting versions of several docker images
+type BatchVersionsRequest struct {
+	DockerImages []string `json:"docker_images"`
+}
+
+//ImageVersions is a dto for versions of one docker image or error (if versions can't be got)
+type ImageVersions struct {
+	Versions []string `json:"versions,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+//BatchVersionsResponse is a dto with versions per docker image name
+type BatchVersionsResponse struct {
+	middleware.StatusResponse
+
+	Versions map[string]*ImageVersions `json:"versions"`
+}
+
 type SpecResponse struct {
 	middleware.StatusResponse
 
@@ -54,12 +81,59 @@ type CatalogResponse struct {
 	Catalog interface{} `json:"catalog"`
 }
 
+//CatalogSummaryResponse is a dto for the catalog summary (without streams schemas)
+type CatalogSummaryResponse struct {
+	middleware.StatusResponse
+
+	StreamsCount int              `json:"streams_count"`
+	Streams      []*StreamSummary `json:"streams"`
+}
+
+//StreamSummary is a dto for the stream name and fields count
+type StreamSummary struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace,omitempty"`
+	FieldsCount int    `json:"fields_count"`
+}
+
+type EvictCacheResponse struct {
+	middleware.StatusResponse
+
+	Evicted int `json:"evicted"`
+}
+
+//airbyteTaskService is a part of synchronization.TaskService which is used by AirbyteHandler
+type airbyteTaskService interface {
+	GetTask(id string) (*synchronization.TaskDto, error)
+	CancelTask(taskID string) error
+}
+
+//airbyteSourcesService is a part of sources.Service which is used by AirbyteHandler
+type airbyteSourcesService interface {
+	GetSource(sourceID string) (*sources.Unit, error)
+}
+
 type AirbyteHandler struct {
-	httpClient *http.Client
+	httpClient      *http.Client
+	discoverTimeout time.Duration
+	taskService     airbyteTaskService
+	sourcesService  airbyteSourcesService
+	cache           *airbyteCache
 }
 
-func NewAirbyteHandler() *AirbyteHandler {
-	return &AirbyteHandler{httpClient: &http.Client{Timeout: defaultTimeout}}
+func NewAirbyteHandler(taskService *synchronization.TaskService, sourcesService *sources.Service) *AirbyteHandler {
+	discoverTimeout := time.Duration(viper.GetInt("airbyte-bridge.discover_timeout_sec")) * time.Second
+	if discoverTimeout <= 0 {
+		discoverTimeout = defaultDiscoverTimeout
+	}
+
+	return &AirbyteHandler{
+		httpClient:      &http.Client{Timeout: defaultTimeout},
+		discoverTimeout: discoverTimeout,
+		taskService:     taskService,
+		sourcesService:  sourcesService,
+		cache:           newAirbyteCache(),
+	}
 }
 
 //VersionsHandler requests available docker version from DockerHub and returns them by docker image name
@@ -70,22 +144,87 @@ func (ah *AirbyteHandler) VersionsHandler(c *gin.Context) {
 		return
 	}
 
-	sortedAvailableTagsVersions, err := ah.getAvailableDockerVersions(dockerImage)
+	versions, err := ah.getVersions(dockerImage)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("error getting available docker image [%s] versions from DockerHub: %v", dockerImage, err), nil))
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
 		return
 	}
 
-	if len(sortedAvailableTagsVersions) == 0 {
-		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Docker Image %s doesn't have availabe tag on hub.docker.com", dockerImage), nil))
+	c.JSON(http.StatusOK, VersionsResponse{
+		Versions: versions,
+	})
+}
+
+//BatchVersionsHandler returns available docker versions of several docker images
+//versions are requested from DockerHub concurrently. Per image errors are returned in the response
+func (ah *AirbyteHandler) BatchVersionsHandler(c *gin.Context) {
+	req := &BatchVersionsRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+	if len(req.DockerImages) == 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("docker_images is required field", nil))
+		return
+	}
+	if len(req.DockerImages) > batchVersionsMaxImages {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("docker_images can't contain more than %d images", batchVersionsMaxImages), nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, VersionsResponse{
-		Versions: sortedAvailableTagsVersions,
+	result := map[string]*ImageVersions{}
+	for _, dockerImage := range req.DockerImages {
+		if dockerImage != "" {
+			result[dockerImage] = &ImageVersions{}
+		}
+	}
+
+	wg := &sync.WaitGroup{}
+	semaphore := make(chan struct{}, batchVersionsConcurrency)
+	for dockerImage, imageVersions := range result {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(dockerImage string, imageVersions *ImageVersions) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			versions, err := ah.getVersions(dockerImage)
+			if err != nil {
+				imageVersions.Error = err.Error()
+			} else {
+				imageVersions.Versions = versions
+			}
+		}(dockerImage, imageVersions)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, BatchVersionsResponse{
+		StatusResponse: middleware.OKResponse(),
+		Versions:       result,
 	})
 }
 
+//getVersions returns cached or requested from DockerHub available docker image versions
+func (ah *AirbyteHandler) getVersions(dockerImage string) ([]string, error) {
+	if cached, ok := ah.cache.get(versionsCacheKind, dockerImage, "").([]string); ok {
+		return cached, nil
+	}
+
+	sortedAvailableTagsVersions, err := ah.getAvailableDockerVersions(dockerImage)
+	if err != nil {
+		return nil, fmt.Errorf("error getting available docker image [%s] versions from DockerHub: %v", dockerImage, err)
+	}
+
+	if len(sortedAvailableTagsVersions) == 0 {
+		return nil, fmt.Errorf("Docker Image %s doesn't have availabe tag on hub.docker.com", dockerImage)
+	}
+
+	ah.cache.put(versionsCacheKind, dockerImage, "", sortedAvailableTagsVersions, airbyteVersionsCacheTTL)
+	return sortedAvailableTagsVersions, nil
+}
+
 //SpecHandler returns airbyte spec by docker name
 func (ah *AirbyteHandler) SpecHandler(c *gin.Context) {
 	dockerImage := c.Param("dockerImageName")
@@ -99,7 +238,15 @@ func (ah *AirbyteHandler) SpecHandler(c *gin.Context) {
 		imageVersion = airbyte.LatestVersion
 	}
 
-	airbyteRunner := airbyte.NewRunner(dockerImage, imageVersion, "")
+	if cached := ah.cache.get(specCacheKind, dockerImage, imageVersion); cached != nil {
+		c.JSON(http.StatusOK, SpecResponse{
+			StatusResponse: middleware.OKResponse(),
+			Spec:           cached,
+		})
+		return
+	}
+
+	airbyteRunner := airbyte.NewRunner(dockerImage, imageVersion, "", nil, "")
 	spec, err := airbyteRunner.Spec()
 	if err != nil {
 		if err == runner.ErrNotReady {
@@ -116,6 +263,7 @@ func (ah *AirbyteHandler) SpecHandler(c *gin.Context) {
 	} else {
 		enrichOathFields(dockerImage, row.Spec)
 	}
+	ah.cache.put(specCacheKind, dockerImage, imageVersion, spec, airbyteSpecCacheTTL)
 	c.JSON(http.StatusOK, SpecResponse{
 		StatusResponse: middleware.OKResponse(),
 		Spec:           spec,
@@ -177,6 +325,11 @@ func (ah *AirbyteHandler) CatalogHandler(c *gin.Context) {
 		return
 	}
 
+	if airbyte.Instance == nil {
+		c.JSON(http.StatusServiceUnavailable, middleware.ErrResponse("Airbyte bridge isn't initialized", nil))
+		return
+	}
+
 	airbyteSourceConnectorConfig := map[string]interface{}{}
 	if err := c.BindJSON(&airbyteSourceConnectorConfig); err != nil {
 		logging.Errorf("Error parsing airbyte source connector body: %v", err)
@@ -190,24 +343,153 @@ func (ah *AirbyteHandler) CatalogHandler(c *gin.Context) {
 		imageVersion = airbyte.LatestVersion
 	}
 
-	airbyteRunner := airbyte.NewRunner(dockerImage, imageVersion, "")
-	catalogRow, err := airbyteRunner.Discover(airbyteSourceConnectorConfig, time.Minute * 3)
+	//e.g. the source database is accessible only in the docker network
+	dockerNetwork := c.Query("docker_network")
+	if err := airbyte.ValidateNetwork(dockerNetwork); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+		return
+	}
+
+	//catalog depends on the connector config. Catalogs are cached with the same hash as catalogs of Airbyte sources
+	catalogHash := airbyte.CatalogHash(airbyteSourceConnectorConfig, dockerImage, imageVersion, nil, dockerNetwork)
+	summary := c.Query("summary") == "true"
+	if cached := airbyte.Instance.CachedCatalog(catalogHash); cached != nil {
+		writeCatalog(c, cached, summary)
+		return
+	}
+
+	airbyteRunner := airbyte.NewRunner(dockerImage, imageVersion, "", nil, dockerNetwork)
+	catalogRow, err := airbyteRunner.Discover(airbyteSourceConnectorConfig, ah.discoverTimeout)
 	if err != nil {
 		if err == runner.ErrNotReady {
 			c.JSON(http.StatusOK, middleware.PendingResponse())
 			return
 		}
+		if err == runner.ErrTimeout {
+			c.JSON(http.StatusGatewayTimeout, middleware.ErrResponse(fmt.Sprintf("Airbyte discover timeout [%s] has been reached. It can be increased with airbyte-bridge.discover_timeout_sec", ah.discoverTimeout.String()), nil))
+			return
+		}
 
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, CatalogResponse{
+	if err := airbyte.Instance.CacheCatalog(catalogHash, dockerImage, catalogRow); err != nil {
+		logging.Warnf("Discovered catalog of [%s] won't be cached: %v", dockerImage, err)
+	}
+	writeCatalog(c, catalogRow, summary)
+}
+
+//writeCatalog writes the full catalog or only streams names and fields counts if summary is true
+//the summary keeps responses of large sources (thousands of streams) small
+func writeCatalog(c *gin.Context, catalog *airbyte.CatalogRow, summary bool) {
+	if !summary {
+		c.JSON(http.StatusOK, CatalogResponse{
+			StatusResponse: middleware.OKResponse(),
+			Catalog:        catalog,
+		})
+		return
+	}
+
+	streams := make([]*StreamSummary, 0, len(catalog.Streams))
+	for _, stream := range catalog.Streams {
+		fieldsCount := 0
+		if stream.JsonSchema != nil {
+			fieldsCount = len(stream.JsonSchema.Properties)
+		}
+		streams = append(streams, &StreamSummary{Name: stream.Name, Namespace: stream.Namespace, FieldsCount: fieldsCount})
+	}
+
+	c.JSON(http.StatusOK, CatalogSummaryResponse{
+		StatusResponse: middleware.OKResponse(),
+		StreamsCount:   len(streams),
+		Streams:        streams,
+	})
+}
+
+//EvictCacheHandler evicts all cached spec/catalog/versions responses of all versions of the docker image
+//it is useful after re-pushing a mutable tag (e.g. latest)
+func (ah *AirbyteHandler) EvictCacheHandler(c *gin.Context) {
+	dockerImage := c.Param("dockerImageName")
+	if dockerImage == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("docker image name is required path parameter", nil))
+		return
+	}
+
+	evicted := ah.cache.evict(strings.TrimPrefix(dockerImage, airbyte.DockerImageRepositoryPrefix))
+	if airbyte.Instance != nil {
+		evicted += airbyte.Instance.EvictCachedCatalogs(dockerImage)
+	}
+	logging.Infof("[Airbyte] %d cached entries of docker image [%s] have been evicted", evicted, dockerImage)
+
+	c.JSON(http.StatusOK, EvictCacheResponse{
 		StatusResponse: middleware.OKResponse(),
-		Catalog:        catalogRow,
+		Evicted:        evicted,
 	})
 }
 
+//DiagnosticsHandler checks Airbyte bridge health (docker daemon, config dir, disk space)
+//returns 503 with all failed checks if the bridge can't run sources
+func (ah *AirbyteHandler) DiagnosticsHandler(c *gin.Context) {
+	if airbyte.Instance == nil {
+		c.JSON(http.StatusServiceUnavailable, middleware.ErrResponse("Airbyte bridge isn't initialized", nil))
+		return
+	}
+
+	if err := airbyte.Instance.Diagnose(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, middleware.ErrResponse("Airbyte bridge is unhealthy", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
+
+//CancelTaskHandler kills the container of the active Airbyte sync task and marks the task as canceled.
+//Other syncs of the same source aren't affected
+func (ah *AirbyteHandler) CancelTaskHandler(c *gin.Context) {
+	taskID := c.Param("taskID")
+	if taskID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("'task_id' is required path parameter", nil))
+		return
+	}
+
+	task, err := ah.taskService.GetTask(taskID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error getting task", err))
+		return
+	}
+
+	source, err := ah.sourcesService.GetSource(task.Source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error getting source by id", err))
+		return
+	}
+
+	taskCanceler, ok := source.DriverPerCollection[task.Collection].(base.TaskCanceler)
+	if !ok || task.SourceType != base.AirbyteType {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("task [%s] isn't an Airbyte sync task", taskID), nil))
+		return
+	}
+
+	//the task status is changed only after the sync has been stopped on this node
+	if err := taskCanceler.CancelTask(taskID); err != nil {
+		if err == base.ErrSyncTaskNotFound {
+			c.JSON(http.StatusNotFound, middleware.ErrResponse(fmt.Sprintf("task [%s] isn't running on this node", taskID), nil))
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error canceling task", err))
+		return
+	}
+
+	if err := ah.taskService.CancelTask(taskID); err != nil {
+		c.JSON(http.StatusInternalServerError, middleware.ErrResponse(fmt.Sprintf("task [%s] sync has been stopped but the task status hasn't been updated", taskID), err))
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
+
 func (ah *AirbyteHandler) getAvailableDockerVersions(dockerImageName string) ([]string, error) {
 	var tags []*DockerHubTag
 	nextURL := fmt.Sprintf(dockerHubURLTemplate, "airbyte", dockerImageName)
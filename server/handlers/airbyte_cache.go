@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	airbyteSpecCacheTTL     = time.Hour
+	airbyteVersionsCacheTTL = 10 * time.Minute
+
+	specCacheKind     = "spec"
+	versionsCacheKind = "versions"
+)
+
+type airbyteCacheEntry struct {
+	dockerImage string
+	value       interface{}
+	expiresAt   time.Time
+}
+
+//airbyteCache is an in-memory cache of Airbyte spec/versions responses. Expired entries are removed on every put
+//discovered catalogs are cached by airbyte.Bridge
+type airbyteCache struct {
+	mutex   *sync.RWMutex
+	entries map[string]*airbyteCacheEntry
+}
+
+func newAirbyteCache() *airbyteCache {
+	return &airbyteCache{
+		mutex:   &sync.RWMutex{},
+		entries: map[string]*airbyteCacheEntry{},
+	}
+}
+
+//get returns cached value or nil if it doesn't exist or has been expired
+func (ac *airbyteCache) get(kind, dockerImage, key string) interface{} {
+	ac.mutex.RLock()
+	entry, ok := ac.entries[cacheKey(kind, dockerImage, key)]
+	ac.mutex.RUnlock()
+	if !ok || timestamp.Now().After(entry.expiresAt) {
+		return nil
+	}
+
+	return entry.value
+}
+
+//put caches the value and removes expired entries
+func (ac *airbyteCache) put(kind, dockerImage, key string, value interface{}, ttl time.Duration) {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	now := timestamp.Now()
+	for entryKey, entry := range ac.entries {
+		if now.After(entry.expiresAt) {
+			delete(ac.entries, entryKey)
+		}
+	}
+
+	ac.entries[cacheKey(kind, dockerImage, key)] = &airbyteCacheEntry{
+		dockerImage: dockerImage,
+		value:       value,
+		expiresAt:   now.Add(ttl),
+	}
+}
+
+//evict removes all cached entries (of all versions) of the docker image
+//returns number of evicted entries
+func (ac *airbyteCache) evict(dockerImage string) int {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	evicted := 0
+	for key, entry := range ac.entries {
+		if entry.dockerImage == dockerImage {
+			delete(ac.entries, key)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+func cacheKey(kind, dockerImage, key string) string {
+	return fmt.Sprintf("%s:%s:%s", kind, dockerImage, key)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAirbyteCacheTTL(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		expected interface{}
+	}{
+		{
+			name:     "actual entry",
+			ttl:      time.Hour,
+			expected: "spec",
+		},
+		{
+			name:     "expired entry",
+			ttl:      -time.Second,
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := newAirbyteCache()
+			ac.put(specCacheKind, "source-github", "0.2.0", "spec", tt.ttl)
+			require.Equal(t, tt.expected, ac.get(specCacheKind, "source-github", "0.2.0"))
+		})
+	}
+}
+
+func TestAirbyteCacheSweepsExpiredEntriesOnPut(t *testing.T) {
+	ac := newAirbyteCache()
+	ac.put(specCacheKind, "source-github", "0.1.0", "expired spec", -time.Second)
+	ac.put(versionsCacheKind, "source-postgres", "", []string{"0.1.0"}, -time.Second)
+	require.Len(t, ac.entries, 1, "expired entries must be removed on put")
+
+	ac.put(specCacheKind, "source-github", "0.2.0", "spec", time.Hour)
+	require.Len(t, ac.entries, 1)
+	require.Equal(t, "spec", ac.get(specCacheKind, "source-github", "0.2.0"))
+}
+
+func TestAirbyteCacheEvict(t *testing.T) {
+	ac := newAirbyteCache()
+	ac.put(specCacheKind, "source-github", "0.1.0", "spec 0.1.0", time.Hour)
+	ac.put(specCacheKind, "source-github", "0.2.0", "spec 0.2.0", time.Hour)
+	ac.put(versionsCacheKind, "source-github", "", []string{"0.1.0", "0.2.0"}, time.Hour)
+	ac.put(specCacheKind, "source-postgres", "0.1.0", "postgres spec", time.Hour)
+
+	require.Equal(t, 3, ac.evict("source-github"))
+	require.Nil(t, ac.get(specCacheKind, "source-github", "0.1.0"))
+	require.Nil(t, ac.get(versionsCacheKind, "source-github", ""))
+	require.Equal(t, "postgres spec", ac.get(specCacheKind, "source-postgres", "0.1.0"), "other images mustn't be evicted")
+	require.Equal(t, 0, ac.evict("source-github"))
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/airbyte"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/sources"
+	"github.com/jitsucom/jitsu/server/synchronization"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+type testTaskService struct {
+	task      *synchronization.TaskDto
+	cancelErr error
+	canceled  []string
+}
+
+func (tts *testTaskService) GetTask(id string) (*synchronization.TaskDto, error) {
+	if tts.task == nil || tts.task.ID != id {
+		return nil, errors.New("task not found")
+	}
+
+	return tts.task, nil
+}
+
+func (tts *testTaskService) CancelTask(taskID string) error {
+	if tts.cancelErr != nil {
+		return tts.cancelErr
+	}
+
+	tts.canceled = append(tts.canceled, taskID)
+	return nil
+}
+
+type testSourcesService struct {
+	sourceID string
+	unit     *sources.Unit
+}
+
+func (tss *testSourcesService) GetSource(sourceID string) (*sources.Unit, error) {
+	if tss.sourceID != sourceID {
+		return nil, errors.New("source not found")
+	}
+
+	return tss.unit, nil
+}
+
+//testTaskCancelerDriver is a driver with active syncs. Other Driver methods mustn't be called
+type testTaskCancelerDriver struct {
+	base.Driver
+
+	stopErr error
+	stopped []string
+	//taskService is used for checking that the task status isn't changed before stopping
+	taskService *testTaskService
+}
+
+func (ttcd *testTaskCancelerDriver) CancelTask(taskID string) error {
+	if len(ttcd.taskService.canceled) > 0 {
+		return errors.New("task status has been changed before stopping the sync")
+	}
+	if ttcd.stopErr != nil {
+		return ttcd.stopErr
+	}
+
+	ttcd.stopped = append(ttcd.stopped, taskID)
+	return nil
+}
+
+func TestCancelTaskHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		taskID           string
+		sourceType       string
+		stopErr          error
+		statusErr        error
+		expectedCode     int
+		expectedStopped  []string
+		expectedCanceled []string
+	}{
+		{
+			name:             "running sync",
+			taskID:           "task_1",
+			sourceType:       base.AirbyteType,
+			expectedCode:     http.StatusOK,
+			expectedStopped:  []string{"task_1"},
+			expectedCanceled: []string{"task_1"},
+		},
+		{
+			name:         "unknown task",
+			taskID:       "task_2",
+			sourceType:   base.AirbyteType,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "not airbyte task",
+			taskID:       "task_1",
+			sourceType:   base.SingerType,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "sync isn't running on this node",
+			taskID:       "task_1",
+			sourceType:   base.AirbyteType,
+			stopErr:      base.ErrSyncTaskNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "sync stopping error",
+			taskID:       "task_1",
+			sourceType:   base.AirbyteType,
+			stopErr:      errors.New("docker stop error"),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:            "task status updating error",
+			taskID:          "task_1",
+			sourceType:      base.AirbyteType,
+			statusErr:       errors.New("redis error"),
+			expectedCode:    http.StatusInternalServerError,
+			expectedStopped: []string{"task_1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskService := &testTaskService{
+				task:      &synchronization.TaskDto{ID: "task_1", SourceType: tt.sourceType, Source: "source_1", Collection: "collection_1"},
+				cancelErr: tt.statusErr,
+			}
+			driver := &testTaskCancelerDriver{stopErr: tt.stopErr, taskService: taskService}
+			ah := &AirbyteHandler{
+				taskService: taskService,
+				sourcesService: &testSourcesService{
+					sourceID: "source_1",
+					unit:     &sources.Unit{DriverPerCollection: map[string]base.Driver{"collection_1": driver}},
+				},
+			}
+
+			gin.SetMode(gin.TestMode)
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/airbyte/tasks/"+tt.taskID+"/cancel", nil)
+			c.Params = gin.Params{{Key: "taskID", Value: tt.taskID}}
+
+			ah.CancelTaskHandler(c)
+
+			require.Equal(t, tt.expectedCode, recorder.Code, recorder.Body.String())
+			require.Equal(t, tt.expectedStopped, driver.stopped)
+			require.Equal(t, tt.expectedCanceled, taskService.canceled, "task status must be changed only after stopping the sync")
+		})
+	}
+}
+
+//testDockerHubTransport responds with tags of known images and 404 for other ones. Tracks max concurrent requests
+type testDockerHubTransport struct {
+	mutex         sync.Mutex
+	tags          map[string][]string
+	running       int
+	maxConcurrent int
+	requested     []string
+}
+
+func (tdht *testDockerHubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	//path: /v2/repositories/airbyte/<image>/tags
+	image := strings.Split(strings.TrimPrefix(req.URL.Path, "/v2/repositories/airbyte/"), "/")[0]
+
+	tdht.mutex.Lock()
+	tdht.running++
+	if tdht.running > tdht.maxConcurrent {
+		tdht.maxConcurrent = tdht.running
+	}
+	tdht.requested = append(tdht.requested, image)
+	tags, ok := tdht.tags[image]
+	tdht.mutex.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	tdht.mutex.Lock()
+	tdht.running--
+	tdht.mutex.Unlock()
+
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader("not found")), Request: req}, nil
+	}
+
+	response := &DockerHubResponse{}
+	for _, tag := range tags {
+		response.Results = append(response.Results, &DockerHubTag{Name: tag})
+	}
+	b, _ := json.Marshal(response)
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(string(b))), Request: req}, nil
+}
+
+func TestBatchVersionsHandler(t *testing.T) {
+	manyImages := make([]string, 0, 2*batchVersionsConcurrency)
+	tooManyImages := make([]string, 0, batchVersionsMaxImages+1)
+	for i := 0; i <= batchVersionsMaxImages; i++ {
+		if i < 2*batchVersionsConcurrency {
+			manyImages = append(manyImages, fmt.Sprintf("source-%d", i))
+		}
+		tooManyImages = append(tooManyImages, fmt.Sprintf("source-%d", i))
+	}
+	manyImagesVersions := map[string]*ImageVersions{}
+	for _, image := range manyImages {
+		manyImagesVersions[image] = &ImageVersions{Versions: []string{"0.1.0"}}
+	}
+
+	tests := []struct {
+		name              string
+		body              string
+		expectedCode      int
+		expectedVersions  map[string]*ImageVersions
+		expectedRequested []string
+	}{
+		{
+			name:         "empty images",
+			body:         `{"docker_images":[]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "malformed body",
+			body:         `{"docker_images":`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "too many images",
+			body:         `{"docker_images":["` + strings.Join(tooManyImages, `","`) + `"]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "per image results and errors",
+			body:         `{"docker_images":["source-postgres","source-cached","source-unknown","source-postgres",""]}`,
+			expectedCode: http.StatusOK,
+			expectedVersions: map[string]*ImageVersions{
+				"source-postgres": {Versions: []string{"0.3.0"}},
+				"source-cached":   {Versions: []string{"1.0.0"}},
+				"source-unknown":  {Error: "error getting available docker image [source-unknown] versions from DockerHub: HTTP code = 404, body: not found"},
+			},
+			expectedRequested: []string{"source-postgres", "source-unknown"},
+		},
+		{
+			name:              "concurrent requests are limited",
+			body:              `{"docker_images":["` + strings.Join(manyImages, `","`) + `"]}`,
+			expectedCode:      http.StatusOK,
+			expectedVersions:  manyImagesVersions,
+			expectedRequested: manyImages,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := map[string][]string{"source-postgres": {"0.3.0", "latest"}}
+			for _, image := range manyImages {
+				tags[image] = []string{"0.1.0"}
+			}
+			transport := &testDockerHubTransport{tags: tags}
+			ah := &AirbyteHandler{httpClient: &http.Client{Transport: transport}, cache: newAirbyteCache()}
+			ah.cache.put(versionsCacheKind, "source-cached", "", []string{"1.0.0"}, time.Minute)
+
+			gin.SetMode(gin.TestMode)
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/airbyte/versions", strings.NewReader(tt.body))
+
+			ah.BatchVersionsHandler(c)
+
+			require.Equal(t, tt.expectedCode, recorder.Code, recorder.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				require.Empty(t, transport.requested)
+				return
+			}
+
+			response := &BatchVersionsResponse{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), response))
+			require.Equal(t, tt.expectedVersions, response.Versions)
+			require.ElementsMatch(t, tt.expectedRequested, transport.requested, "cached and duplicated images mustn't be requested")
+			require.LessOrEqual(t, transport.maxConcurrent, batchVersionsConcurrency)
+		})
+	}
+}
+
+func TestDiscoverTimeout(t *testing.T) {
+	defer viper.Set("airbyte-bridge.discover_timeout_sec", nil)
+
+	tests := []struct {
+		name       string
+		timeoutSec int
+		expected   time.Duration
+	}{
+		{"configured", 600, 10 * time.Minute},
+		{"not configured", 0, defaultDiscoverTimeout},
+		{"negative", -1, defaultDiscoverTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("airbyte-bridge.discover_timeout_sec", tt.timeoutSec)
+			require.Equal(t, tt.expected, NewAirbyteHandler(nil, nil).discoverTimeout)
+		})
+	}
+}
+
+func TestWriteCatalog(t *testing.T) {
+	catalog := &airbyte.CatalogRow{Streams: []*airbyte.Stream{
+		{Name: "users", Namespace: "public", JsonSchema: &airbyte.Schema{Properties: map[string]*base.Property{"id": {}, "email": {}}}},
+		{Name: "events"},
+	}}
+
+	tests := []struct {
+		name         string
+		catalog      *airbyte.CatalogRow
+		summary      bool
+		expectedJSON string
+	}{
+		{
+			"full catalog",
+			catalog,
+			false,
+			`{"status":"ok","catalog":{"streams":[{"name":"users","json_schema":{"properties":{"email":{},"id":{}}},"source_defined_cursor":false,"namespace":"public"},{"name":"events","source_defined_cursor":false}]}}`,
+		},
+		{
+			"summary without streams schemas",
+			catalog,
+			true,
+			`{"status":"ok","streams_count":2,"streams":[{"name":"users","namespace":"public","fields_count":2},{"name":"events","fields_count":0}]}`,
+		},
+		{
+			"summary of empty catalog",
+			&airbyte.CatalogRow{},
+			true,
+			`{"status":"ok","streams_count":0,"streams":[]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+
+			writeCatalog(c, tt.catalog, tt.summary)
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.JSONEq(t, tt.expectedJSON, recorder.Body.String())
+		})
+	}
+}
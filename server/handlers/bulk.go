@@ -65,7 +65,7 @@ func (bh *BulkHandler) BulkLoadingHandler(c *gin.Context) {
 	for _, storageProxy := range storageProxies {
 		if err := bh.upload(storageProxy, eventObjects); err != nil {
 
-			metrics.ErrorTokenEvents(tokenID, storageProxy.Type(), storageProxy.ID(), rowsCount)
+			metrics.ErrorTokenEvents(tokenID, storageProxy.Type(), storageProxy.ID(), metrics.BatchMode, rowsCount)
 			metrics.ErrorTokenObjects(tokenID, rowsCount)
 			telemetry.Error(tokenID, storageProxy.ID(), events.SrcBulk, "", rowsCount)
 			counters.ErrorPushDestinationEvents(storageProxy.ID(), int64(rowsCount))
@@ -74,7 +74,7 @@ func (bh *BulkHandler) BulkLoadingHandler(c *gin.Context) {
 			return
 		}
 
-		metrics.SuccessTokenEvents(tokenID, storageProxy.Type(), storageProxy.ID(), rowsCount)
+		metrics.SuccessTokenEvents(tokenID, storageProxy.Type(), storageProxy.ID(), metrics.BatchMode, rowsCount)
 		metrics.SuccessTokenObjects(tokenID, rowsCount)
 		telemetry.Event(tokenID, storageProxy.ID(), events.SrcBulk, "", rowsCount)
 		counters.SuccessPushDestinationEvents(storageProxy.ID(), int64(rowsCount))
@@ -11,11 +11,13 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/plugins"
 	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/samples"
 	"github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/templates"
 	"github.com/jitsucom/jitsu/server/timestamp"
@@ -54,6 +56,43 @@ func DestinationsHandler(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+//DestinationsValidateHandler validates destination config against the destination type schema without creating
+//the destination. Returns 200 if the config is valid or 400 with the list of field errors in the payload
+func DestinationsValidateHandler(c *gin.Context) {
+	destinationConfig := &config.DestinationConfig{}
+	if err := c.BindJSON(destinationConfig); err != nil {
+		logging.Errorf("Error parsing destinations body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	if errs := storages.ValidateDestination(c.Query("destination_id"), destinationConfig); len(errs) > 0 {
+		response := middleware.ErrResponse("Invalid destination config", nil)
+		response.Payload = errs
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
+
+//DestinationsStatusHandler returns destinations reloading state: start/end time and duration of the last reloading,
+//destinations count and the last reloading error
+func DestinationsStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, destinations.StatusInstance.Get())
+}
+
+//SamplesResponse is a response dto for debug samples of processed objects
+type SamplesResponse struct {
+	Samples []*samples.Record `json:"samples"`
+}
+
+//DestinationsSamplesHandler returns debug samples of processed objects (the newest first)
+//filtered by destination_id query parameter (optional)
+func DestinationsSamplesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, SamplesResponse{Samples: samples.Instance.Get(c.Query("destination_id"))})
+}
+
 //testDestinationConnection creates default table with 2 fields (eventn_ctx key and timestamp)
 //depends on the destination type calls destination test connection func
 //returns err if has occurred
@@ -161,6 +200,23 @@ func testDestinationConnection(config *config.DestinationConfig) error {
 		}
 		defer s3Adapter.Close()
 		return s3Adapter.ValidateWritePermission()
+	case storages.HTTPType:
+		cfg := &adapters.HTTPBatchConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		httpAdapter, err := adapters.NewHTTPBatch(cfg, nil)
+		if err != nil {
+			return err
+		}
+		defer httpAdapter.Close()
+		return nil
+	case storages.FileType:
+		cfg := &storages.FileConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		return logging.EnsureDir(cfg.Path)
 	case storages.NpmType:
 		plugin, err := plugins.DownloadPlugin(config.Package)
 		if err != nil {
@@ -511,6 +567,8 @@ func testSnowflake(config *config.DestinationConfig, eventContext *adapters.Even
 	if snowflakeConfig.Schema == "" {
 		snowflakeConfig.Schema = "PUBLIC"
 	}
+	//test event is uploaded to the stage as a json object regardless of the configured stage format
+	snowflakeConfig.StageFormat = adapters.StageFormatJSON
 
 	timeout := "6"
 	snowflakeConfig.Parameters["statement_timeout_in_seconds"] = &timeout
@@ -587,7 +645,7 @@ func testSnowflake(config *config.DestinationConfig, eventContext *adapters.Even
 			return err
 		}
 
-		if err = snowflake.Copy(eventContext.Table.Name, eventContext.Table.Name, header); err != nil {
+		if err = snowflake.Copy(context.Background(), eventContext.Table.Name, eventContext.Table.Name, header); err != nil {
 			return err
 		}
 	} else {
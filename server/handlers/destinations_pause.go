@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/middleware"
+)
+
+//DestinationsPauseHandler pauses and resumes writes into destinations at runtime (e.g. for a maintenance window)
+type DestinationsPauseHandler struct {
+	destinationService *destinations.Service
+}
+
+//NewDestinationsPauseHandler returns configured DestinationsPauseHandler
+func NewDestinationsPauseHandler(destinationService *destinations.Service) *DestinationsPauseHandler {
+	return &DestinationsPauseHandler{destinationService: destinationService}
+}
+
+//PauseHandler pauses writes into the destination_id destination
+func (dph *DestinationsPauseHandler) PauseHandler(c *gin.Context) {
+	dph.handle(c, dph.destinationService.PauseDestination)
+}
+
+//ResumeHandler resumes writes into the paused destination_id destination
+func (dph *DestinationsPauseHandler) ResumeHandler(c *gin.Context) {
+	dph.handle(c, dph.destinationService.ResumeDestination)
+}
+
+func (dph *DestinationsPauseHandler) handle(c *gin.Context, action func(id string) bool) {
+	destinationID := c.Query("destination_id")
+	if destinationID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[destination_id] query parameter is required", nil))
+		return
+	}
+
+	if !action(destinationID) {
+		c.JSON(http.StatusNotFound, middleware.ErrResponse(fmt.Sprintf("Destination [%s] doesn't exist", destinationID), nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
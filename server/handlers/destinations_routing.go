@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/destinations"
+)
+
+//DestinationsRoutingHandler returns the current routing tables snapshot (which destinations receive events of which token)
+type DestinationsRoutingHandler struct {
+	destinationService *destinations.Service
+}
+
+//NewDestinationsRoutingHandler returns configured DestinationsRoutingHandler
+func NewDestinationsRoutingHandler(destinationService *destinations.Service) *DestinationsRoutingHandler {
+	return &DestinationsRoutingHandler{destinationService: destinationService}
+}
+
+//Handler returns destinations.Service routing tables snapshot
+func (drh *DestinationsRoutingHandler) Handler(c *gin.Context) {
+	c.JSON(http.StatusOK, drh.destinationService.DumpRouting())
+}
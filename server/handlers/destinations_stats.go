@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/destinations"
+)
+
+//DestinationsStatsHandler returns destinations lifetime counters (rows stored, bytes staged, failures, last success/failure time)
+type DestinationsStatsHandler struct {
+	destinationService *destinations.Service
+}
+
+//NewDestinationsStatsHandler returns configured DestinationsStatsHandler
+func NewDestinationsStatsHandler(destinationService *destinations.Service) *DestinationsStatsHandler {
+	return &DestinationsStatsHandler{destinationService: destinationService}
+}
+
+//Handler returns destinations.Service stats per destination ID
+func (dsh *DestinationsStatsHandler) Handler(c *gin.Context) {
+	c.JSON(http.StatusOK, dsh.destinationService.Stats())
+}
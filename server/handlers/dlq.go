@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/dlq"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+)
+
+//DLQResponse is a response dto for dead-letter queue records
+type DLQResponse struct {
+	Records []*dlq.Record `json:"records"`
+}
+
+//DLQHandler is a handler for enumerating and filtering dead-letter queue records
+type DLQHandler struct {
+	dlqService *dlq.Service
+}
+
+//NewDLQHandler returns configured DLQHandler
+func NewDLQHandler(dlqService *dlq.Service) *DLQHandler {
+	return &DLQHandler{dlqService: dlqService}
+}
+
+//GetHandler returns DLQ records filtered by destination_ids, table, classification, reason (error substring)
+//and start, end (RFC3339) query parameters. Not more than limit (default 100) the newest records are returned
+func (dh *DLQHandler) GetHandler(c *gin.Context) {
+	filter := &dlq.Filter{
+		DestinationIDs: map[string]bool{},
+		TableName:      c.Query("table"),
+		Classification: c.Query("classification"),
+		Reason:         c.Query("reason"),
+	}
+
+	destinationIDs := c.Query("destination_ids")
+	if destinationIDs != "" {
+		for _, destinationID := range strings.Split(destinationIDs, ",") {
+			filter.DestinationIDs[destinationID] = true
+		}
+	}
+
+	var err error
+	if startStr := c.Query("start"); startStr != "" {
+		filter.Start, err = time.Parse(time.RFC3339Nano, startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [start] query parameter", err))
+			return
+		}
+	}
+	if endStr := c.Query("end"); endStr != "" {
+		filter.End, err = time.Parse(time.RFC3339Nano, endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [end] query parameter", err))
+			return
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		filter.Limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("limit must be int", nil))
+			return
+		}
+	}
+
+	records, err := dh.dlqService.List(filter)
+	if err != nil {
+		logging.Errorf("Error listing DLQ records: %v", err)
+		c.JSON(http.StatusInternalServerError, middleware.ErrResponse("Failed to list DLQ records", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, DLQResponse{Records: records})
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/server/fallback"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"net/http"
 	"strings"
+	"time"
 )
 
 const rawJSONFormat = "raw_json"
@@ -22,6 +25,21 @@ type ReplayRequest struct {
 	SkipMalformed bool   `json:"skip_malformed"`
 }
 
+//ArchiveReplayRequest is a request for replaying archived streaming events received within [start, end] (or the last hours)
+type ArchiveReplayRequest struct {
+	DestinationID       string `json:"destination_id"`
+	SourceDestinationID string `json:"source_destination_id"`
+	Start               string `json:"start"`
+	End                 string `json:"end"`
+	Hours               int    `json:"hours"`
+}
+
+//ArchiveReplayResponse is a response with replayed and skipped events counters
+type ArchiveReplayResponse struct {
+	middleware.StatusResponse
+	*fallback.ArchiveReplayResult
+}
+
 type FallbackHandler struct {
 	fallbackService *fallback.Service
 }
@@ -61,3 +79,51 @@ func (fh *FallbackHandler) ReplayHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, middleware.OKResponse())
 }
+
+//ArchiveReplayHandler replays archived streaming events within the time range into the destination
+func (fh *FallbackHandler) ArchiveReplayHandler(c *gin.Context) {
+	req := &ArchiveReplayRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing archive replay body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	end := timestamp.Now().UTC()
+	if req.End != "" {
+		t, ok := timestamp.ParseValue(req.End)
+		if !ok {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Malformed end: %s. ISO format is expected", req.End), nil))
+			return
+		}
+		end = t
+	}
+
+	var start time.Time
+	switch {
+	case req.Start != "":
+		t, ok := timestamp.ParseValue(req.Start)
+		if !ok {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Malformed start: %s. ISO format is expected", req.Start), nil))
+			return
+		}
+		start = t
+	case req.Hours > 0:
+		start = end.Add(-time.Duration(req.Hours) * time.Hour)
+	default:
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("start or hours is required", nil))
+		return
+	}
+
+	result, err := fh.fallbackService.ReplayArchive(req.DestinationID, req.SourceDestinationID, start, end)
+	if err != nil {
+		if result != nil {
+			err = fmt.Errorf("%v. Already replayed: %d events", err, result.Replayed)
+		}
+		logging.Errorf("Error replaying archive into destination [%s]: %v", req.DestinationID, err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to replay archive", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, ArchiveReplayResponse{StatusResponse: middleware.OKResponse(), ArchiveReplayResult: result})
+}
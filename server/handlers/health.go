@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/sources"
+)
+
+//Health statuses
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+	HealthStatusFailed   = "failed"
+)
+
+//ComponentHealth is a health check result of a destination or a source driver
+//Error is returned only to requests with admin token
+type ComponentHealth struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
+
+//HealthResponse is a response dto for /healthz and /readyz endpoints
+//Status is failed if at least one critical component is failed, degraded if at least one non-critical component is failed
+type HealthResponse struct {
+	Status     string             `json:"status"`
+	Components []*ComponentHealth `json:"components"`
+}
+
+//HealthHandler aggregates destinations health checks and source drivers readiness
+type HealthHandler struct {
+	destinationService *destinations.Service
+	sourceService      *sources.Service
+	adminToken         *middleware.AdminToken
+	//critical is a list of components names (or prefixes with '*' suffix) which fail readiness
+	critical []string
+}
+
+//NewHealthHandler returns configured HealthHandler
+func NewHealthHandler(destinationService *destinations.Service, sourceService *sources.Service, adminToken *middleware.AdminToken, critical []string) *HealthHandler {
+	return &HealthHandler{
+		destinationService: destinationService,
+		sourceService:      sourceService,
+		adminToken:         adminToken,
+		critical:           critical,
+	}
+}
+
+//HealthzHandler is a liveness probe: always returns 200 with components health breakdown
+func (hh *HealthHandler) HealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, hh.check(hh.adminToken.IsAdmin(c)))
+}
+
+//ReadyzHandler is a readiness probe: returns 503 if at least one critical component is failed or the server is shutting down
+func (hh *HealthHandler) ReadyzHandler(c *gin.Context) {
+	response := hh.check(hh.adminToken.IsAdmin(c))
+	if response.Status == HealthStatusFailed {
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (hh *HealthHandler) check(withErrors bool) *HealthResponse {
+	response := &HealthResponse{Status: HealthStatusOK, Components: []*ComponentHealth{}}
+	if appstatus.Instance.Idle.Load() {
+		response.Status = HealthStatusFailed
+		response.Components = append(response.Components, &ComponentHealth{Name: "server", Status: HealthStatusFailed, Critical: true, Error: "server is shutting down"})
+		return response
+	}
+
+	results := map[string]error{}
+	if hh.destinationService != nil {
+		for destinationID, err := range hh.destinationService.Health() {
+			results["destinations."+destinationID] = err
+		}
+	}
+	if hh.sourceService != nil {
+		for sourceCollection, err := range hh.sourceService.Health() {
+			results["sources."+sourceCollection] = err
+		}
+	}
+
+	return hh.aggregate(results, withErrors)
+}
+
+//aggregate builds response from health check results per component name
+func (hh *HealthHandler) aggregate(results map[string]error, withErrors bool) *HealthResponse {
+	response := &HealthResponse{Status: HealthStatusOK, Components: []*ComponentHealth{}}
+	for name, err := range results {
+		component := &ComponentHealth{Name: name, Status: HealthStatusOK, Critical: hh.isCritical(name)}
+		if err != nil {
+			component.Status = HealthStatusFailed
+			if withErrors {
+				component.Error = err.Error()
+			}
+
+			if component.Critical {
+				response.Status = HealthStatusFailed
+			} else if response.Status == HealthStatusOK {
+				response.Status = HealthStatusDegraded
+			}
+		}
+		response.Components = append(response.Components, component)
+	}
+
+	sort.Slice(response.Components, func(i, j int) bool {
+		return response.Components[i].Name < response.Components[j].Name
+	})
+
+	return response
+}
+
+//isCritical returns true if the component name matches server.health.critical configuration
+func (hh *HealthHandler) isCritical(name string) bool {
+	for _, critical := range hh.critical {
+		if strings.HasSuffix(critical, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(critical, "*")) {
+				return true
+			}
+		} else if critical == name {
+			return true
+		}
+	}
+
+	return false
+}
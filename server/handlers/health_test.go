@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthAggregate(t *testing.T) {
+	tests := []struct {
+		name               string
+		critical           []string
+		results            map[string]error
+		withErrors         bool
+		expectedStatus     string
+		expectedComponents []*ComponentHealth
+	}{
+		{
+			"Empty",
+			nil,
+			map[string]error{},
+			true,
+			HealthStatusOK,
+			[]*ComponentHealth{},
+		},
+		{
+			"All healthy",
+			[]string{"destinations.dest1"},
+			map[string]error{"destinations.dest1": nil, "sources.source1.users": nil},
+			true,
+			HealthStatusOK,
+			[]*ComponentHealth{
+				{Name: "destinations.dest1", Status: HealthStatusOK, Critical: true},
+				{Name: "sources.source1.users", Status: HealthStatusOK},
+			},
+		},
+		{
+			"Non critical failed",
+			[]string{"destinations.dest1"},
+			map[string]error{"destinations.dest1": nil, "destinations.dest2": errors.New("connection refused")},
+			true,
+			HealthStatusDegraded,
+			[]*ComponentHealth{
+				{Name: "destinations.dest1", Status: HealthStatusOK, Critical: true},
+				{Name: "destinations.dest2", Status: HealthStatusFailed, Error: "connection refused"},
+			},
+		},
+		{
+			"Critical prefix failed",
+			[]string{"destinations.*"},
+			map[string]error{"destinations.dest1": errors.New("connection refused"), "sources.source1.users": errors.New("not ready")},
+			true,
+			HealthStatusFailed,
+			[]*ComponentHealth{
+				{Name: "destinations.dest1", Status: HealthStatusFailed, Critical: true, Error: "connection refused"},
+				{Name: "sources.source1.users", Status: HealthStatusFailed, Error: "not ready"},
+			},
+		},
+		{
+			"Errors are hidden without admin token",
+			[]string{"destinations.dest1"},
+			map[string]error{"destinations.dest1": errors.New("connection refused")},
+			false,
+			HealthStatusFailed,
+			[]*ComponentHealth{
+				{Name: "destinations.dest1", Status: HealthStatusFailed, Critical: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hh := NewHealthHandler(nil, nil, &middleware.AdminToken{}, tt.critical)
+
+			response := hh.aggregate(tt.results, tt.withErrors)
+
+			require.Equal(t, tt.expectedStatus, response.Status)
+			require.Equal(t, tt.expectedComponents, response.Components)
+		})
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		idle           bool
+		expectedCode   int
+		expectedStatus string
+	}{
+		{
+			"Ready",
+			false,
+			http.StatusOK,
+			HealthStatusOK,
+		},
+		{
+			"Shutting down",
+			true,
+			http.StatusServiceUnavailable,
+			HealthStatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appstatus.Instance.Idle.Store(tt.idle)
+			defer appstatus.Instance.Idle.Store(false)
+
+			hh := NewHealthHandler(nil, nil, &middleware.AdminToken{}, nil)
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			hh.ReadyzHandler(c)
+
+			require.Equal(t, tt.expectedCode, recorder.Code)
+			response := &HealthResponse{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), response))
+			require.Equal(t, tt.expectedStatus, response.Status)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/mitchellh/mapstructure"
+)
+
+const defaultPreviewDestinationID = "preview"
+
+//PreviewRequest is a request dto for processing raw events with destination config without storing
+type PreviewRequest struct {
+	DestinationID string                   `json:"destination_id,omitempty"`
+	Config        map[string]interface{}   `json:"config,omitempty"`
+	Events        []map[string]interface{} `json:"events,omitempty"`
+}
+
+//Validate returns err if invalid
+func (pr *PreviewRequest) Validate() error {
+	if pr.Config == nil {
+		return errors.New("'config' is required field")
+	}
+	if len(pr.Events) == 0 {
+		return errors.New("'events' is required field")
+	}
+
+	return nil
+}
+
+//PreviewHandler is a handler for previewing processed objects and table schemas without writing to destinations
+type PreviewHandler struct {
+	factory storages.Factory
+}
+
+//NewPreviewHandler returns configured PreviewHandler
+func NewPreviewHandler(factory storages.Factory) *PreviewHandler {
+	return &PreviewHandler{factory: factory}
+}
+
+//Handler processes events with the destination config and returns processed objects, table schemas,
+//failed and skipped events
+func (ph *PreviewHandler) Handler(c *gin.Context) {
+	req := &PreviewRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing preview body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+		return
+	}
+
+	destinationConfig := config.DestinationConfig{}
+	if err := mapstructure.Decode(req.Config, &destinationConfig); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse destination config", err))
+		return
+	}
+
+	destinationID := req.DestinationID
+	if destinationID == "" {
+		destinationID = defaultPreviewDestinationID
+	}
+
+	result, err := ph.factory.Preview(destinationID, destinationConfig, req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to process events", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//RetentionHandler deletes destination table rows which are older than the retention age
+type RetentionHandler struct {
+	destinationService *destinations.Service
+}
+
+//NewRetentionHandler returns configured RetentionHandler
+func NewRetentionHandler(destinationService *destinations.Service) *RetentionHandler {
+	return &RetentionHandler{destinationService: destinationService}
+}
+
+//Handler deletes rows of the destination_id destination table with column (default _timestamp) value older than age_days
+//deleted rows are reported in logs and metrics
+func (rh *RetentionHandler) Handler(c *gin.Context) {
+	destinationID := c.Query("destination_id")
+	if destinationID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[destination_id] query parameter is required", nil))
+		return
+	}
+
+	table := c.Query("table")
+	if table == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[table] query parameter is required", nil))
+		return
+	}
+
+	ageDays, err := strconv.Atoi(c.Query("age_days"))
+	if err != nil || ageDays <= 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[age_days] query parameter must be a positive integer", nil))
+		return
+	}
+
+	column := c.Query("column")
+	if column == "" {
+		column = timestamp.Key
+	}
+
+	storageProxy, ok := rh.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Destination [%s] doesn't exist", destinationID), nil))
+		return
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Destination [%s] isn't initialized", destinationID), nil))
+		return
+	}
+
+	if err := storage.CleanOlderThan(table, column, time.Duration(ageDays)*24*time.Hour); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Error deleting rows older than %d days from %s table", ageDays, table), err))
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+//SchemaRepairResponse is a response dto for destination tables schema repair
+type SchemaRepairResponse struct {
+	Tables []*adapters.SchemaRepair `json:"tables"`
+}
+
+//SchemaRepairHandler reconciles destination tables schema after manual warehouse changes
+type SchemaRepairHandler struct {
+	destinationService *destinations.Service
+}
+
+//NewSchemaRepairHandler returns configured SchemaRepairHandler
+func NewSchemaRepairHandler(destinationService *destinations.Service) *SchemaRepairHandler {
+	return &SchemaRepairHandler{destinationService: destinationService}
+}
+
+//Handler repairs schema of the destination_id destination table (or all known tables if table query parameter isn't set)
+//only Snowflake destinations are supported
+func (srh *SchemaRepairHandler) Handler(c *gin.Context) {
+	destinationID := c.Query("destination_id")
+	if destinationID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[destination_id] query parameter is required", nil))
+		return
+	}
+
+	storageProxy, ok := srh.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Destination [%s] doesn't exist", destinationID), nil))
+		return
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Destination [%s] isn't initialized", destinationID), nil))
+		return
+	}
+
+	snowflake, ok := storage.(*storages.Snowflake)
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Schema repair isn't supported for destination type %s. Supported types: [%s]", storage.Type(), storages.SnowflakeType), nil))
+		return
+	}
+
+	repairs, err := snowflake.RepairSchemas(c.Query("table"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error repairing schema", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, SchemaRepairResponse{Tables: repairs})
+}
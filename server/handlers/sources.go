@@ -41,6 +41,7 @@ func NewSourcesHandler(sourcesService *sources.Service, metaStorage meta.Storage
 //ClearCacheHandler deletes source state (signature) from meta.Storage
 func (sh *SourcesHandler) ClearCacheHandler(c *gin.Context) {
 	shouldCleanWarehouse := c.DefaultQuery("delete_warehouse_data", "false") == "true"
+	shouldRefreshCatalog := c.DefaultQuery("refresh_catalog", "false") == "true"
 	req := &ClearCacheRequest{}
 	if err := c.BindJSON(req); err != nil {
 		logging.Errorf("Error parsing clear cache request: %v", err)
@@ -78,6 +79,18 @@ func (sh *SourcesHandler) ClearCacheHandler(c *gin.Context) {
 			logging.Error(msg)
 			multiErr = multierror.Append(multiErr, err)
 		}
+		if localStateDriver, ok := driver.(driversbase.LocalStateDriver); ok {
+			if err := localStateDriver.ClearLocalState(); err != nil {
+				logging.Errorf("Error clearing local state for source: [%s] collection: [%s]: %v", req.Source, collection, err)
+				multiErr = multierror.Append(multiErr, err)
+			}
+		}
+		if catalogRefresher, ok := driver.(driversbase.CatalogRefresher); ok && shouldRefreshCatalog {
+			if err := catalogRefresher.RefreshCatalog(); err != nil {
+				logging.Errorf("Error refreshing catalog for source: [%s] collection: [%s]: %v", req.Source, collection, err)
+				multiErr = multierror.Append(multiErr, err)
+			}
+		}
 		if shouldCleanWarehouse {
 			multiErr = sh.cleanWarehouse(driver, source.DestinationIDs, req.Source, collection, multiErr)
 		}
@@ -146,6 +159,7 @@ func (sh *SourcesHandler) getTableNames(driver driversbase.Driver) ([]string, er
 //  200 with status pending if source isn't ready
 //  200 with status pending and error in body if source isn't ready and has previous error
 //  400 with error if a connection failed
+//?force=true bypasses cached connection test results
 func (sh *SourcesHandler) TestSourcesHandler(c *gin.Context) {
 	sourceConfig := &driversbase.SourceConfig{}
 	if err := c.BindJSON(sourceConfig); err != nil {
@@ -153,6 +167,7 @@ func (sh *SourcesHandler) TestSourcesHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
 		return
 	}
+	sourceConfig.ForceTest = c.Query("force") == "true"
 	err := testSourceConnection(sourceConfig)
 	if err != nil {
 		if err == runner.ErrNotReady {
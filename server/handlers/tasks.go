@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/server/drivers"
 	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
@@ -26,6 +27,14 @@ type TaskLogsResponse struct {
 	Logs []synchronization.LogRecordDto `json:"logs"`
 }
 
+type ActiveSyncsResponse struct {
+	Syncs []driversbase.ActiveSync `json:"syncs"`
+}
+
+type CanceledSyncsResponse struct {
+	Canceled []string `json:"canceled"`
+}
+
 type TaskHandler struct {
 	taskService   *synchronization.TaskService
 	sourceService *sources.Service
@@ -240,6 +249,24 @@ func (sh *TaskHandler) TaskCancelHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, middleware.OKResponse())
 }
 
+//ActiveSyncsHandler returns all running syncs of all CLI drivers (Airbyte, Singer) on this node
+func (sh *TaskHandler) ActiveSyncsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, ActiveSyncsResponse{Syncs: driversbase.ActiveSyncs.List()})
+}
+
+//CancelActiveSyncsHandler cancels all running syncs on this node: tasks are marked as canceled and sync commands are killed
+func (sh *TaskHandler) CancelActiveSyncsHandler(c *gin.Context) {
+	canceled, err := driversbase.ActiveSyncs.CancelAll(sh.taskService.CancelTask)
+	if err != nil {
+		logging.Errorf("Error canceling active syncs (canceled: %v): %v", canceled, err)
+		c.JSON(http.StatusInternalServerError, middleware.ErrResponse(fmt.Sprintf("Error canceling active syncs. Canceled tasks: %v", canceled), err))
+		return
+	}
+
+	logging.Infof("Active syncs have been canceled: %v", canceled)
+	c.JSON(http.StatusOK, CanceledSyncsResponse{Canceled: canceled})
+}
+
 func extractCollectionID(sourceType string, c *gin.Context) string {
 	if sourceType == driversbase.SingerType || sourceType == driversbase.AirbyteType {
 		return drivers.DefaultCollection
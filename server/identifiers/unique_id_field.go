@@ -3,11 +3,24 @@ package identifiers
 import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	//FallbackStrategyUUID generates random UUID for objects without unique ID
+	FallbackStrategyUUID = "uuid"
+	//FallbackStrategyHash uses hash of the object payload as unique ID
+	FallbackStrategyHash = "hash"
 )
 
 //UniqueID is a struct for extracting unique ID from objects
 type UniqueID struct {
 	jsonPath jsonutils.JSONPath
+
+	//fallbackPaths are ordered candidate fields which are used if the object doesn't have unique ID field
+	fallbackPaths []jsonutils.JSONPath
+	//fallbackStrategy generates unique ID if there are no candidate fields in the object (empty - disabled)
+	fallbackStrategy string
 }
 
 //NewUniqueID returns new UniqueID instance
@@ -15,23 +28,81 @@ func NewUniqueID(uniqueIDField string) *UniqueID {
 	return &UniqueID{jsonPath: jsonutils.NewJSONPath(uniqueIDField)}
 }
 
-//Extract returns extracted global unique ID from input object
+//WithFallback returns UniqueID copy with ordered candidate fields and final strategy (uuid or hash)
+//which are applied to objects without unique ID field
+func (uid *UniqueID) WithFallback(fallbackFields []string, fallbackStrategy string) (*UniqueID, error) {
+	switch fallbackStrategy {
+	case "", FallbackStrategyUUID, FallbackStrategyHash:
+	default:
+		return nil, fmt.Errorf("Unknown unique ID fallback strategy: %s. Available values: [%s, %s]", fallbackStrategy, FallbackStrategyUUID, FallbackStrategyHash)
+	}
+
+	var fallbackPaths []jsonutils.JSONPath
+	for _, field := range fallbackFields {
+		fallbackPaths = append(fallbackPaths, jsonutils.NewJSONPath(field))
+	}
+
+	return &UniqueID{jsonPath: uid.jsonPath, fallbackPaths: fallbackPaths, fallbackStrategy: fallbackStrategy}, nil
+}
+
+//WithField returns UniqueID copy with another unique ID field and the same fallback configuration
+func (uid *UniqueID) WithField(uniqueIDField string) *UniqueID {
+	return &UniqueID{jsonPath: jsonutils.NewJSONPath(uniqueIDField), fallbackPaths: uid.fallbackPaths, fallbackStrategy: uid.fallbackStrategy}
+}
+
+//Extract returns extracted global unique ID from input object (the object isn't changed)
+//if the object doesn't have unique ID field, candidate fields are used. Fallback strategy isn't applied:
+//generated IDs are put into the unique ID field on the ingest (see Fallback)
 func (uid *UniqueID) Extract(obj map[string]interface{}) string {
 	if obj == nil {
 		return ""
 	}
 
-	value, ok := uid.jsonPath.Get(obj)
+	if value, ok := extract(uid.jsonPath, obj); ok {
+		return value
+	}
+
+	for _, path := range uid.fallbackPaths {
+		if value, ok := extract(path, obj); ok {
+			return value
+		}
+	}
+
+	return ""
+}
+
+//Fallback returns the first candidate field value or the value generated with fallback strategy (empty if it isn't configured)
+//it is used on the ingest for objects without unique ID field before the object is shared between destinations
+func (uid *UniqueID) Fallback(obj map[string]interface{}) string {
+	for _, path := range uid.fallbackPaths {
+		if value, ok := extract(path, obj); ok {
+			return value
+		}
+	}
+
+	switch uid.fallbackStrategy {
+	case FallbackStrategyUUID:
+		return uuid.New()
+	case FallbackStrategyHash:
+		return uuid.GetHash(obj)
+	default:
+		return ""
+	}
+}
+
+//extract returns value by JSON path or by flat field name (for flattened objects)
+func extract(path jsonutils.JSONPath, obj map[string]interface{}) (string, bool) {
+	value, ok := path.Get(obj)
 	if ok {
-		return fmt.Sprint(value)
+		return fmt.Sprint(value), true
 	}
 
-	value, ok = obj[uid.GetFlatFieldName()]
+	value, ok = obj[path.FieldName()]
 	if ok {
-		return fmt.Sprint(value)
+		return fmt.Sprint(value), true
 	}
 
-	return ""
+	return "", false
 }
 
 //ExtractAndRemove returns extracted global unique ID from input object and remove it from the objects
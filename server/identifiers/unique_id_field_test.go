@@ -0,0 +1,99 @@
+package identifiers
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniqueIDExtractFallback(t *testing.T) {
+	uuid.InitMock()
+
+	tests := []struct {
+		name             string
+		fallbackFields   []string
+		fallbackStrategy string
+		input            map[string]interface{}
+		expectedID       string
+		expectedFallback string
+	}{
+		{
+			"primary field",
+			[]string{"/message_id"},
+			FallbackStrategyUUID,
+			map[string]interface{}{"eventn_ctx": map[string]interface{}{"event_id": "id1"}, "message_id": "id2"},
+			"id1",
+			"id2",
+		},
+		{
+			"flat primary field",
+			nil,
+			"",
+			map[string]interface{}{"eventn_ctx_event_id": "id1"},
+			"id1",
+			"",
+		},
+		{
+			"missing primary field without fallback",
+			nil,
+			"",
+			map[string]interface{}{"field": "value"},
+			"",
+			"",
+		},
+		{
+			"candidate field",
+			[]string{"/message_id", "/context/request_id"},
+			FallbackStrategyUUID,
+			map[string]interface{}{"context": map[string]interface{}{"request_id": "req1"}},
+			"req1",
+			"req1",
+		},
+		{
+			"flat candidate field",
+			[]string{"/context/request_id"},
+			"",
+			map[string]interface{}{"context_request_id": "req1"},
+			"req1",
+			"req1",
+		},
+		{
+			"uuid strategy",
+			[]string{"/message_id"},
+			FallbackStrategyUUID,
+			map[string]interface{}{"field": "value"},
+			"",
+			"mockeduuid",
+		},
+		{
+			"hash strategy",
+			nil,
+			FallbackStrategyHash,
+			map[string]interface{}{"field": "value"},
+			"",
+			uuid.GetHash(map[string]interface{}{"field": "value"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, err := NewUniqueID("/eventn_ctx/event_id").WithFallback(tt.fallbackFields, tt.fallbackStrategy)
+			require.NoError(t, err)
+
+			expectedObject := map[string]interface{}{}
+			for k, v := range tt.input {
+				expectedObject[k] = v
+			}
+
+			require.Equal(t, tt.expectedID, uid.Extract(tt.input))
+			require.Equal(t, expectedObject, tt.input, "Extract mustn't change the object")
+			require.Equal(t, tt.expectedFallback, uid.Fallback(tt.input))
+			require.Equal(t, expectedObject, tt.input, "Fallback mustn't change the object")
+		})
+	}
+}
+
+func TestUniqueIDWithFallbackUnknownStrategy(t *testing.T) {
+	_, err := NewUniqueID("/eventn_ctx/event_id").WithFallback(nil, "random")
+	require.EqualError(t, err, "Unknown unique ID fallback strategy: random. Available values: [uuid, hash]")
+}
@@ -10,6 +10,7 @@ const (
 	ArchiveDir  = "archive"
 	FailedDir   = "failed"
 	IncomingDir = "incoming"
+	DLQDir      = "dlq"
 )
 
 type Factory struct {
@@ -97,6 +98,21 @@ func (f *Factory) CreateFailedLogger(destinationName string) logging.ObjectLogge
 	return NewSyncLogger(failedEventWriter, false)
 }
 
+//CreateDLQLogger returns logger for structured dead-letter queue records of the destination
+func (f *Factory) CreateDLQLogger(destinationName string) logging.ObjectLogger {
+	dlqWriter := logging.NewRollingWriter(&logging.Config{
+		FileName:      "dlq.dst=" + destinationName,
+		FileDir:       path.Join(f.logEventPath, DLQDir),
+		RotationMin:   f.logRotationMin,
+		RotateOnClose: true,
+	})
+
+	if f.asyncLoggers {
+		return NewAsyncLogger(dlqWriter, false, f.asyncLoggerPoolSize)
+	}
+	return NewSyncLogger(dlqWriter, false)
+}
+
 func (f *Factory) CreateSQLQueryLogger(destinationName string) *logging.QueryLogger {
 	return logging.NewQueryLogger(destinationName, f.ddlLogsWriter, f.queryLogsWriter)
 }
@@ -0,0 +1,105 @@
+package logfiles
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	//storeLatencySmoothing is a weight of the latest latency in the rolling (exponentially weighted) average
+	storeLatencySmoothing = 0.3
+	//maxThrottlingIntervals limits pause duration (in upload intervals)
+	maxThrottlingIntervals = 10
+)
+
+//storeThrottler keeps rolling average of Store() latency per destination and pauses dispatching new batches
+//to a destination when the average exceeds max latency (the destination max_store_latency_ms or the global one).
+//Pause duration is proportional to the excess: (average/maxLatency - 1) upload intervals
+//but not less than one upload interval (the next upload is always skipped) and not more than maxThrottlingIntervals
+type storeThrottler struct {
+	maxLatency     time.Duration
+	uploadInterval time.Duration
+
+	mutex       *sync.Mutex
+	latencies   map[string]time.Duration
+	pausedUntil map[string]time.Time
+}
+
+//newStoreThrottler returns configured storeThrottler. maxLatency is used for destinations without max_store_latency_ms
+//Throttling is disabled if max latency isn't positive
+func newStoreThrottler(maxLatency, uploadInterval time.Duration) *storeThrottler {
+	return &storeThrottler{
+		maxLatency:     maxLatency,
+		uploadInterval: uploadInterval,
+		mutex:          &sync.Mutex{},
+		latencies:      map[string]time.Duration{},
+		pausedUntil:    map[string]time.Time{},
+	}
+}
+
+//destinationMaxLatency returns the destination max latency if it is configured otherwise the global one
+func (st *storeThrottler) destinationMaxLatency(maxLatency time.Duration) time.Duration {
+	if maxLatency > 0 {
+		return maxLatency
+	}
+
+	return st.maxLatency
+}
+
+//allow returns false if dispatching batches to the destination is paused
+//maxLatency is the destination max_store_latency_ms (0 if it isn't configured)
+func (st *storeThrottler) allow(destinationType, destinationID string, maxLatency time.Duration) bool {
+	if st.destinationMaxLatency(maxLatency) <= 0 {
+		return true
+	}
+
+	st.mutex.Lock()
+	pausedUntil, ok := st.pausedUntil[destinationID]
+	st.mutex.Unlock()
+
+	throttled := ok && timestamp.Now().Before(pausedUntil)
+	metrics.SetStoreThrottled(destinationType, destinationID, throttled)
+	return !throttled
+}
+
+//observe updates rolling average latency of the destination and pauses it if the average exceeds max latency
+//maxLatency is the destination max_store_latency_ms (0 if it isn't configured)
+func (st *storeThrottler) observe(destinationType, destinationID string, latency, maxLatency time.Duration) {
+	maxLatency = st.destinationMaxLatency(maxLatency)
+	if maxLatency <= 0 {
+		return
+	}
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	average, ok := st.latencies[destinationID]
+	if ok {
+		average = time.Duration(storeLatencySmoothing*float64(latency) + (1-storeLatencySmoothing)*float64(average))
+	} else {
+		average = latency
+	}
+	st.latencies[destinationID] = average
+	metrics.SetStoreLatency(destinationType, destinationID, average.Milliseconds())
+
+	if average <= maxLatency {
+		delete(st.pausedUntil, destinationID)
+		return
+	}
+
+	excess := float64(average)/float64(maxLatency) - 1
+	if excess < 1 {
+		//a shorter pause expires before the next upload and doesn't throttle anything
+		excess = 1
+	}
+	if excess > maxThrottlingIntervals {
+		excess = maxThrottlingIntervals
+	}
+	pause := time.Duration(excess * float64(st.uploadInterval))
+	st.pausedUntil[destinationID] = timestamp.Now().Add(pause)
+	logging.Warnf("[%s] average store latency %s exceeds max_store_latency_ms %s. Dispatching new batches is paused for %s", destinationID, average, maxLatency, pause)
+}
@@ -0,0 +1,101 @@
+package logfiles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreThrottler(t *testing.T) {
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	st := newStoreThrottler(time.Second, time.Minute)
+
+	st.observe("snowflake", "dest", 500*time.Millisecond, 0)
+	require.True(t, st.allow("snowflake", "dest", 0))
+
+	//average = 0.3 * 11.5s + 0.7 * 0.5s = 3.8s => paused for 2.8 upload intervals
+	st.observe("snowflake", "dest", 11500*time.Millisecond, 0)
+	require.False(t, st.allow("snowflake", "dest", 0))
+	require.Equal(t, timestamp.Now().Add(168*time.Second), st.pausedUntil["dest"])
+
+	//other destinations aren't affected
+	require.True(t, st.allow("postgres", "other", 0))
+
+	//average = 0.7^3 * 3.8s = 1.3s => still paused
+	for i := 0; i < 3; i++ {
+		st.observe("snowflake", "dest", 0, 0)
+	}
+	require.False(t, st.allow("snowflake", "dest", 0))
+
+	//recovered: average = 0.7^4 * 3.8s = 0.9s
+	st.observe("snowflake", "dest", 0, 0)
+	require.True(t, st.allow("snowflake", "dest", 0))
+}
+
+func TestStoreThrottlerPause(t *testing.T) {
+	tests := []struct {
+		name                  string
+		globalMaxLatency      time.Duration
+		destinationMaxLatency time.Duration
+		latency               time.Duration
+		expectedPause         time.Duration
+	}{
+		{
+			name:             "small excess is paused for one upload interval",
+			globalMaxLatency: time.Second,
+			latency:          1100 * time.Millisecond,
+			expectedPause:    time.Minute,
+		},
+		{
+			name:             "pause is proportional to the excess",
+			globalMaxLatency: time.Second,
+			latency:          4 * time.Second,
+			expectedPause:    3 * time.Minute,
+		},
+		{
+			name:             "pause is limited",
+			globalMaxLatency: time.Second,
+			latency:          time.Hour,
+			expectedPause:    maxThrottlingIntervals * time.Minute,
+		},
+		{
+			name:                  "destination max latency overrides the global one",
+			globalMaxLatency:      time.Second,
+			destinationMaxLatency: 10 * time.Second,
+			latency:               5 * time.Second,
+		},
+		{
+			name:                  "destination max latency when the global one is disabled",
+			destinationMaxLatency: 2 * time.Second,
+			latency:               8 * time.Second,
+			expectedPause:         3 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp.FreezeTime()
+			defer timestamp.UnfreezeTime()
+
+			st := newStoreThrottler(tt.globalMaxLatency, time.Minute)
+			st.observe("snowflake", "dest", tt.latency, tt.destinationMaxLatency)
+
+			if tt.expectedPause == 0 {
+				require.True(t, st.allow("snowflake", "dest", tt.destinationMaxLatency))
+				return
+			}
+
+			require.False(t, st.allow("snowflake", "dest", tt.destinationMaxLatency))
+			require.Equal(t, timestamp.Now().Add(tt.expectedPause), st.pausedUntil["dest"])
+		})
+	}
+}
+
+func TestStoreThrottlerDisabled(t *testing.T) {
+	st := newStoreThrottler(0, time.Minute)
+	st.observe("snowflake", "dest", time.Hour, 0)
+	require.True(t, st.allow("snowflake", "dest", 0))
+}
@@ -1,6 +1,7 @@
 package logfiles
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path"
@@ -10,6 +11,7 @@ import (
 	"github.com/jitsucom/jitsu/server/appstatus"
 	"github.com/jitsucom/jitsu/server/counters"
 	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/dlq"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logevents"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -34,23 +36,27 @@ type PeriodicUploader struct {
 	archiver           *Archiver
 	statusManager      *StatusManager
 	destinationService *destinations.Service
+	storeThrottler     *storeThrottler
 }
 
 //NewUploader returns new configured PeriodicUploader instance
-func NewUploader(logEventPath, fileMask string, uploadEveryMin int, destinationService *destinations.Service) (*PeriodicUploader, error) {
+//maxStoreLatencyMs enables throttling of dispatching batches to slow destinations (0 - disabled)
+func NewUploader(logEventPath, fileMask string, uploadEveryMin, maxStoreLatencyMs int, destinationService *destinations.Service) (*PeriodicUploader, error) {
 	logIncomingEventPath := path.Join(logEventPath, logevents.IncomingDir)
 	logArchiveEventPath := path.Join(logEventPath, logevents.ArchiveDir)
 	statusManager, err := NewStatusManager(logIncomingEventPath)
 	if err != nil {
 		return nil, err
 	}
+	uploadEvery := time.Duration(uploadEveryMin) * time.Minute
 	return &PeriodicUploader{
 		logIncomingEventPath: logIncomingEventPath,
 		fileMask:             path.Join(logIncomingEventPath, fileMask),
-		uploadEvery:          time.Duration(uploadEveryMin) * time.Minute,
+		uploadEvery:          uploadEvery,
 		archiver:             NewArchiver(logIncomingEventPath, logArchiveEventPath),
 		statusManager:        statusManager,
 		destinationService:   destinationService,
+		storeThrottler:       newStoreThrottler(time.Duration(maxStoreLatencyMs)*time.Millisecond, uploadEvery),
 	}, nil
 }
 
@@ -64,7 +70,7 @@ func (u *PeriodicUploader) Start() {
 				break
 			}
 
-			if destinations.StatusInstance.Reloading {
+			if destinations.StatusInstance.IsReloading() {
 				time.Sleep(2 * time.Second)
 				continue
 			}
@@ -120,12 +126,24 @@ func (u *PeriodicUploader) Start() {
 				//flag for archiving file if all storages don't have errors while storing this file
 				archiveFile := true
 				for _, storageProxy := range storageProxies {
+					//destination is paused: file will be uploaded after resuming
+					if storageProxy.IsPaused() {
+						archiveFile = false
+						continue
+					}
+
 					storage, ok := storageProxy.Get()
 					if !ok {
 						archiveFile = false
 						continue
 					}
 
+					//destination is too slow: file will be retried on the next upload
+					if !u.storeThrottler.allow(storage.Type(), storage.ID(), storageProxy.GetMaxStoreLatency()) {
+						archiveFile = false
+						continue
+					}
+
 					alreadyUploadedTables := map[string]bool{}
 					tableStatuses := u.statusManager.GetTablesStatuses(fileName, storage.ID())
 					for tableName, status := range tableStatuses {
@@ -134,10 +152,12 @@ func (u *PeriodicUploader) Start() {
 						}
 					}
 
+					storeStartTime := time.Now()
 					resultPerTable, failedEvents, skippedEvents, err := storage.Store(fileName, objects, alreadyUploadedTables)
+					u.storeThrottler.observe(storage.Type(), storage.ID(), time.Since(storeStartTime), storageProxy.GetMaxStoreLatency())
 
 					if !skippedEvents.IsEmpty() {
-						metrics.SkipTokenEvents(tokenID, storage.Type(), storage.ID(), len(skippedEvents.Events))
+						metrics.SkipTokenEvents(tokenID, storage.Type(), storage.ID(), metrics.BatchMode, len(skippedEvents.Events))
 						counters.SkipPushDestinationEvents(storage.ID(), int64(len(skippedEvents.Events)))
 					}
 
@@ -152,7 +172,7 @@ func (u *PeriodicUploader) Start() {
 						}
 
 						errRowsCount := len(objects)
-						metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), errRowsCount)
+						metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), metrics.BatchMode, errRowsCount)
 						counters.ErrorPushDestinationEvents(storage.ID(), int64(errRowsCount))
 
 						telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), eventsSrc)
@@ -168,6 +188,8 @@ func (u *PeriodicUploader) Start() {
 							parsingFailedEvents = append(parsingFailedEvents, &events.FailedEvent{
 								MalformedEvent: string(pe.Original),
 								Error:          pe.Error,
+								Classification: dlq.ClassificationMalformed,
+								CorrelationID:  fileName,
 							})
 						}
 						storage.Fallback(parsingFailedEvents...)
@@ -175,16 +197,39 @@ func (u *PeriodicUploader) Start() {
 					}
 					//events that are failed to be processed
 					if !failedEvents.IsEmpty() {
+						for _, failedEvent := range failedEvents.Events {
+							failedEvent.Classification = dlq.ClassificationProcessing
+							failedEvent.CorrelationID = fileName
+						}
 						storage.Fallback(failedEvents.Events...)
 
 						telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), failedEvents.Src)
 					}
 
 					for tableName, result := range resultPerTable {
+						if errors.Is(result.Err, storages.ErrCopyPending) {
+							//table is uploaded into stage: the file will be stored again to get the batched COPY result
+							logging.Debugf("[%s] Table %s from file %s is waiting for the batched COPY", storage.ID(), tableName, filePath)
+							archiveFile = false
+							delete(resultPerTable, tableName)
+							continue
+						}
+
+						if errors.Is(result.Err, storages.ErrBadData) || errors.Is(result.Err, storages.ErrTimeout) {
+							//retries won't help: the destination has already written table objects into fallback
+							logging.Errorf("[%s] Error storing table %s from file %s: %v. Objects have been written into fallback", storage.ID(), tableName, filePath, result.Err)
+							metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), metrics.BatchMode, result.RowsCount)
+							counters.ErrorPushDestinationEvents(storage.ID(), int64(result.RowsCount))
+
+							telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), result.EventsSrc)
+							u.statusManager.UpdateStatus(fileName, storage.ID(), tableName, nil)
+							continue
+						}
+
 						if result.Err != nil {
 							archiveFile = false
 							logging.Errorf("[%s] Error storing table %s from file %s: %v", storage.ID(), tableName, filePath, result.Err)
-							metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), result.RowsCount)
+							metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), metrics.BatchMode, result.RowsCount)
 							counters.ErrorPushDestinationEvents(storage.ID(), int64(result.RowsCount))
 
 							telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), result.EventsSrc)
@@ -200,7 +245,7 @@ func (u *PeriodicUploader) Start() {
 									mp[storage.ID()] = true
 								}
 							}
-							metrics.SuccessTokenEvents(tokenID, storage.Type(), storage.ID(), result.RowsCount)
+							metrics.SuccessTokenEvents(tokenID, storage.Type(), storage.ID(), metrics.BatchMode, result.RowsCount)
 							counters.SuccessPushDestinationEvents(storage.ID(), int64(result.RowsCount))
 
 							telemetry.PushedEventsPerSrc(tokenID, storage.ID(), result.EventsSrc)
@@ -208,6 +253,16 @@ func (u *PeriodicUploader) Start() {
 
 						u.statusManager.UpdateStatus(fileName, storage.ID(), tableName, result.Err)
 					}
+
+					if len(resultPerTable) > 0 {
+						summary := storages.AggregateStoreResults(resultPerTable)
+						metrics.SetBatchMaxTableLatency(storage.Type(), storage.ID(), summary.MaxLatency.Milliseconds())
+						if summary.FailedTables > 0 {
+							logging.Warnf("[%s] File %s has been stored partially: %s", storage.ID(), filePath, summary)
+						} else {
+							logging.Infof("[%s] File %s has been stored: %s", storage.ID(), filePath, summary)
+						}
+					}
 				}
 
 				if archiveFile {
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+)
+
+type contextKey struct{}
+
+//Context is a logging context with the destination ID and the correlation ID (batch file name or event ID)
+//all messages are prefixed with them: e.g. [destination_id] [cid=batch_file_name] message
+//so a single batch or event can be grepped through the whole pipeline
+type Context struct {
+	destinationID string
+	correlationID string
+	prefix        string
+}
+
+//NewContext returns Context with the destination ID and the correlation ID (empty IDs aren't written)
+func NewContext(destinationID, correlationID string) *Context {
+	prefix := ""
+	if destinationID != "" {
+		prefix += fmt.Sprintf("[%s] ", destinationID)
+	}
+	if correlationID != "" {
+		prefix += fmt.Sprintf("[cid=%s] ", correlationID)
+	}
+
+	return &Context{destinationID: destinationID, correlationID: correlationID, prefix: prefix}
+}
+
+//WithContext returns a copy of ctx with the logging context
+func WithContext(ctx context.Context, logContext *Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, logContext)
+}
+
+//FromContext returns the logging context from ctx or empty Context (messages without prefix) if there is no one
+func FromContext(ctx context.Context) *Context {
+	if logContext, ok := ctx.Value(contextKey{}).(*Context); ok {
+		return logContext
+	}
+
+	return &Context{}
+}
+
+//CorrelationID returns the correlation ID (batch file name or event ID)
+func (c *Context) CorrelationID() string {
+	return c.correlationID
+}
+
+func (c *Context) SystemErrorf(format string, v ...interface{}) {
+	SystemError(c.prefix + fmt.Sprintf(format, v...))
+}
+
+func (c *Context) Errorf(format string, v ...interface{}) {
+	Error(c.prefix + fmt.Sprintf(format, v...))
+}
+
+func (c *Context) Warnf(format string, v ...interface{}) {
+	Warn(c.prefix + fmt.Sprintf(format, v...))
+}
+
+func (c *Context) Infof(format string, v ...interface{}) {
+	Info(c.prefix + fmt.Sprintf(format, v...))
+}
+
+func (c *Context) Debugf(format string, v ...interface{}) {
+	Debug(c.prefix + fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext(t *testing.T) {
+	tests := []struct {
+		name                  string
+		ctx                   context.Context
+		expectedCorrelationID string
+		expectedMessage       string
+	}{
+		{
+			"Without logging context",
+			context.Background(),
+			"",
+			"[ERROR]: batch failed",
+		},
+		{
+			"Destination and correlation IDs",
+			WithContext(context.Background(), NewContext("dest1", "incoming.tok=abc-2021-01-01T00-00-00.000.log")),
+			"incoming.tok=abc-2021-01-01T00-00-00.000.log",
+			"[ERROR]: [dest1] [cid=incoming.tok=abc-2021-01-01T00-00-00.000.log] batch failed",
+		},
+		{
+			"Only destination ID",
+			WithContext(context.Background(), NewContext("dest1", "")),
+			"",
+			"[ERROR]: [dest1] batch failed",
+		},
+		{
+			"Only correlation ID",
+			WithContext(context.Background(), NewContext("", "event1")),
+			"event1",
+			"[ERROR]: [cid=event1] batch failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			log.SetOutput(buf)
+			defer log.SetOutput(os.Stderr)
+
+			logContext := FromContext(tt.ctx)
+			require.Equal(t, tt.expectedCorrelationID, logContext.CorrelationID())
+
+			logContext.Errorf("batch %s", "failed")
+			require.Contains(t, buf.String(), tt.expectedMessage)
+		})
+	}
+}
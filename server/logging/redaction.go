@@ -0,0 +1,189 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//RedactedValue replaces sensitive values in logs
+const RedactedValue = "*****"
+
+//DefaultSensitiveKeys are markers of config keys which values mustn't be written into logs
+//a key is sensitive if it contains any marker (case-insensitive) e.g. password, aws_secret_key, api_token
+var DefaultSensitiveKeys = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "access_key", "access_key_id",
+	"private_key", "credentials", "key_file", "service_account_key"}
+
+var (
+	sensitiveKeysMutex = &sync.RWMutex{}
+	sensitiveKeys      = DefaultSensitiveKeys
+)
+
+//AddSensitiveKeys extends DefaultSensitiveKeys with configured markers (server.log.sensitive_keys)
+func AddSensitiveKeys(keys []string) {
+	markers := append([]string{}, DefaultSensitiveKeys...)
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key != "" {
+			markers = append(markers, key)
+		}
+	}
+
+	sensitiveKeysMutex.Lock()
+	sensitiveKeys = markers
+	sensitiveKeysMutex.Unlock()
+}
+
+//IsSensitiveKey returns true if the key contains any sensitive key marker
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+
+	sensitiveKeysMutex.RLock()
+	defer sensitiveKeysMutex.RUnlock()
+	for _, marker := range sensitiveKeys {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Redact returns a JSON representation of the value (struct, map, slice) where values of sensitive keys are replaced with RedactedValue
+//it is used for logging configs: e.g. logging.Errorf("invalid config: %v", logging.Redact(config))
+func Redact(value interface{}) interface{} {
+	generic, ok := toGeneric(value)
+	if !ok {
+		return RedactedValue
+	}
+
+	return redactGeneric(generic)
+}
+
+//RedactString replaces all values of sensitive keys of configs in the string (e.g. a password in a DSN of a connection error)
+func RedactString(s string, configs ...interface{}) string {
+	for _, secret := range secretValues(configs...) {
+		s = strings.ReplaceAll(s, secret, RedactedValue)
+	}
+
+	return s
+}
+
+//RedactError returns error which message doesn't contain values of sensitive keys of configs
+//the original error is available with errors.Unwrap (errors.Is and errors.As work as usual)
+func RedactError(err error, configs ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	redacted := RedactString(msg, configs...)
+	if redacted == msg {
+		return err
+	}
+
+	return &redactedError{msg: redacted, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (re *redactedError) Error() string {
+	return re.msg
+}
+
+func (re *redactedError) Unwrap() error {
+	return re.err
+}
+
+//secretValues returns values of sensitive keys from configs (and their URL encoded forms) sorted by length desc
+//so longer secrets are replaced before their substrings
+func secretValues(configs ...interface{}) []string {
+	unique := map[string]bool{}
+	for _, config := range configs {
+		generic, ok := toGeneric(config)
+		if !ok {
+			continue
+		}
+
+		collectSecrets(generic, false, unique)
+	}
+
+	values := make([]string, 0, len(unique))
+	for value := range unique {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	return values
+}
+
+func collectSecrets(value interface{}, sensitive bool, result map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			collectSecrets(nested, sensitive || IsSensitiveKey(key), result)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			collectSecrets(nested, sensitive, result)
+		}
+	case string:
+		if sensitive && v != "" {
+			result[v] = true
+			result[url.QueryEscape(v)] = true
+			result[url.PathEscape(v)] = true
+		}
+	}
+}
+
+func redactGeneric(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			if IsSensitiveKey(key) && nested != nil {
+				redacted[key] = RedactedValue
+			} else {
+				redacted[key] = redactGeneric(nested)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, nested := range v {
+			redacted[i] = redactGeneric(nested)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+//toGeneric converts the value into JSON generic types (maps, slices and primitives)
+func toGeneric(value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, false
+	}
+
+	return generic, true
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConnectionConfig struct {
+	Host       string            `json:"host"`
+	Username   string            `json:"username"`
+	Password   string            `json:"password"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+func TestRedact(t *testing.T) {
+	config := &testConnectionConfig{
+		Host:       "db.example.com",
+		Username:   "admin",
+		Password:   "s3cr3t",
+		Parameters: map[string]string{"aws_secret_access_key": "aws-key", "region": "us-east-1"},
+	}
+
+	expected := map[string]interface{}{
+		"host":       "db.example.com",
+		"username":   "admin",
+		"password":   RedactedValue,
+		"parameters": map[string]interface{}{"aws_secret_access_key": RedactedValue, "region": "us-east-1"},
+	}
+	require.Equal(t, expected, Redact(config))
+
+	nested := map[string]interface{}{"credentials": map[string]interface{}{"client_id": "id", "client_secret": "cs"}, "api_key": nil}
+	require.Equal(t, map[string]interface{}{"credentials": RedactedValue, "api_key": nil}, Redact(nested))
+
+	require.Equal(t, RedactedValue, Redact(make(chan int)))
+}
+
+func TestRedactError(t *testing.T) {
+	password := "p@ss/w0rd"
+	config := map[string]interface{}{"account": "acc", "username": "user", "password": password, "s3": map[string]interface{}{"secret_key": "aws-secret"}}
+
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("error connecting user:%s@acc.snowflakecomputing.com (plain: %s, s3: aws-secret): %w", url.QueryEscape(password), password, cause)
+	redacted := RedactError(err, config)
+	require.NotContains(t, redacted.Error(), password)
+	require.NotContains(t, redacted.Error(), url.QueryEscape(password))
+	require.NotContains(t, redacted.Error(), "aws-secret")
+	require.Contains(t, redacted.Error(), "user:"+RedactedValue+"@acc.snowflakecomputing.com")
+	require.True(t, errors.Is(redacted, cause))
+
+	//errors without secrets are returned as is
+	require.Equal(t, cause, RedactError(cause, config))
+	require.Nil(t, RedactError(nil, config))
+}
+
+func TestSensitiveKeys(t *testing.T) {
+	defer AddSensitiveKeys(nil)
+
+	require.True(t, IsSensitiveKey("Password"))
+	require.True(t, IsSensitiveKey("refresh_token"))
+	require.False(t, IsSensitiveKey("connection_string"))
+
+	AddSensitiveKeys([]string{" Connection_String "})
+	require.True(t, IsSensitiveKey("connection_string"))
+	require.True(t, IsSensitiveKey("password"))
+}
+
+func TestRedactedErrorLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	config := &testConnectionConfig{Host: "localhost", Username: "admin", Password: "very-secret-password"}
+	err := fmt.Errorf("dial postgres://admin:%s@localhost:5432: connection refused", config.Password)
+	Errorf("Error connecting with config %v: %v", Redact(config), RedactError(err, config))
+
+	require.Contains(t, buf.String(), "connection refused")
+	require.NotContains(t, buf.String(), config.Password)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/system"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/tracing"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/jitsucom/jitsu/server/wal"
 
@@ -40,6 +41,7 @@ import (
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/counters"
 	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/dlq"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/fallback"
 	"github.com/jitsucom/jitsu/server/logfiles"
@@ -48,6 +50,7 @@ import (
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/notifications"
+	"github.com/jitsucom/jitsu/server/resources"
 	"github.com/jitsucom/jitsu/server/routers"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/scheduling"
@@ -107,7 +110,7 @@ func setAppWorkDir() {
 }
 
 func main() {
-	if len(os.Args) >= 2 && os.Args[1] == "replay" {
+	if len(os.Args) >= 2 && (os.Args[1] == "replay" || os.Args[1] == "airbyte-load") {
 		cmd.Execute(tag)
 		return
 	}
@@ -161,7 +164,9 @@ func main() {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	if err := airbyte.Init(ctx, viper.GetString("airbyte-bridge.config_dir"), viper.GetString("server.volumes.workspace"), viper.GetInt("airbyte-bridge.batch_size"), appconfig.Instance.AirbyteLogsWriter); err != nil {
+	if err := airbyte.Init(ctx, viper.GetString("airbyte-bridge.config_dir"), viper.GetString("server.volumes.workspace"), viper.GetInt("airbyte-bridge.batch_size"),
+		viper.GetInt("airbyte-bridge.max_concurrent_containers"), time.Duration(viper.GetInt("airbyte-bridge.catalog_cache_ttl_sec"))*time.Second,
+		time.Duration(viper.GetInt("airbyte-bridge.check_cache_ttl_sec"))*time.Second, appconfig.Instance.AirbyteLogsWriter); err != nil {
 		logging.Errorf("❌ Airbyte integration is disabled: %v. For using Airbyte run Jitsu with: -v /var/run/docker.sock:/var/run/docker.sock", err)
 	}
 
@@ -186,6 +191,9 @@ func main() {
 		logging.Error(string(debug.Stack()))
 		notifications.SystemErrorf("Panic:\n%s\n%s", value, string(debug.Stack()))
 	}
+	resources.FailuresHandler = func(name string, failures int, err error) {
+		notifications.SystemErrorf("Resource [%s] hasn't been reloaded %d times in a row: %v", name, failures, err)
+	}
 
 	//TELEMETRY
 	telemetryURL := viper.GetString("server.telemetry")
@@ -206,6 +214,15 @@ func main() {
 	systemInfo := runtime.GetInfo()
 	telemetry.EnrichSystemInfo(clusterID, systemInfo)
 
+	//TRACING
+	tracingService, err := tracing.Init(ctx, notifications.ServiceName, viper.Sub("server.tracing"))
+	if err != nil {
+		logging.Fatalf("Error initializing tracing: %v", err)
+	}
+	if tracingService != nil {
+		appconfig.Instance.ScheduleLastClosing(tracingService)
+	}
+
 	metricsExported := viper.GetBool("server.metrics.prometheus.enabled")
 	metricsRelay := metrics.InitRelay(clusterID, viper.Sub("server.metrics.relay"))
 	if metricsExported || metricsRelay != nil {
@@ -320,12 +337,13 @@ func main() {
 	eventsCacheSize := viper.GetInt("server.cache.events.size")
 	eventsCacheTrimIntervalMs := viper.GetInt("server.cache.events.trim_interval_ms")
 	eventsCachePoolSize := viper.GetInt("server.cache.pool.size")
+	eventsCacheTimeoutMs := viper.GetInt("server.cache.timeout_ms")
 	if eventsCachePoolSize == 0 {
 		eventsCachePoolSize = 1
 		logging.Infof("server.cache.pool.size can't be 0. Using default value=1 instead")
 
 	}
-	eventsCache := caching.NewEventsCache(eventsCacheEnabled, metaStorage, eventsCacheSize, eventsCachePoolSize, eventsCacheTrimIntervalMs)
+	eventsCache := caching.NewEventsCache(eventsCacheEnabled, metaStorage, eventsCacheSize, eventsCachePoolSize, eventsCacheTrimIntervalMs, eventsCacheTimeoutMs)
 	appconfig.Instance.ScheduleClosing(eventsCache)
 
 	// ** Retroactive users recognition
@@ -416,9 +434,11 @@ func main() {
 		stalledTasksThresholdSeconds := viper.GetInt("server.sync_tasks.stalled.last_heartbeat_threshold_seconds")
 		stalledLastLogThresholdMinutes := viper.GetInt("server.sync_tasks.stalled.last_activity_threshold_minutes")
 		observeStalledTaskEverySeconds := viper.GetInt("server.sync_tasks.stalled.observe_stalled_every_seconds")
+		stateSaveRetries := viper.GetInt("server.sync_tasks.state_save_retries")
+		storeConcurrency := viper.GetInt("server.sync_tasks.store_concurrency")
 
 		//Create task executor
-		taskExecutor, err := synchronization.NewTaskExecutor(poolSize, stalledTasksThresholdSeconds, stalledLastLogThresholdMinutes, observeStalledTaskEverySeconds, sourceService, destinationsService, metaStorage, coordinationService)
+		taskExecutor, err := synchronization.NewTaskExecutor(poolSize, stalledTasksThresholdSeconds, stalledLastLogThresholdMinutes, observeStalledTaskEverySeconds, stateSaveRetries, storeConcurrency, sourceService, destinationsService, metaStorage, coordinationService)
 		if err != nil {
 			logging.Fatal("Error creating sources sync task executor:", err)
 		}
@@ -430,7 +450,7 @@ func main() {
 	//for now use the same interval as for log rotation
 	uploaderRunInterval := viper.GetInt("log.rotation_min")
 	//Uploader must read event logger directory
-	uploader, err := logfiles.NewUploader(logEventPath, uploaderFileMask, uploaderRunInterval, destinationsService)
+	uploader, err := logfiles.NewUploader(logEventPath, uploaderFileMask, uploaderRunInterval, viper.GetInt("log.max_store_latency_ms"), destinationsService)
 	if err != nil {
 		logging.Fatal("Error while creating file uploader", err)
 	}
@@ -498,7 +518,7 @@ func main() {
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
 	router := routers.SetupRouter(adminToken, metaStorage, destinationsService, sourceService, taskService, fallbackService,
-		coordinationService, eventsCache, systemService, segmentRequestFieldsMapper, segmentCompatRequestFieldsMapper, processorHolder,
+		dlq.NewService(logEventPath), coordinationService, eventsCache, systemService, segmentRequestFieldsMapper, segmentCompatRequestFieldsMapper, processorHolder,
 		multiplexingService, walService, geoService, pluginsRepository)
 
 	telemetry.ServerStart()
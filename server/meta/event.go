@@ -8,6 +8,21 @@ type Event struct {
 	DestinationID string `json:"destination_id,omitempty" redis:"destination_id"`
 }
 
+//Cached event statuses which are used in EventUpdate
+const (
+	EventStatusSuccess = "success"
+	EventStatusError   = "error"
+	EventStatusSkip    = "skip"
+)
+
+//EventUpdate is a cached event status update which is used in batch updates
+//Value is a serialized succeed event or an error message (depends on Status)
+type EventUpdate struct {
+	EventID string
+	Status  string
+	Value   string
+}
+
 type EventsPerTime struct {
 	Key    string `json:"key"`
 	Events int    `json:"events"`
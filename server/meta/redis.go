@@ -263,6 +263,51 @@ func (r *Redis) UpdateSkipEvent(destinationID, eventID, error string) error {
 	return nil
 }
 
+//UpdateEvents updates event records in Redis with success, error or skip fields in one pipeline
+//returns the last error if any of updates has failed
+func (r *Redis) UpdateEvents(destinationID string, updates []*EventUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	lastEventsIndexKey := "last_events_index:destination#" + destinationID
+	now := timestamp.Now().UTC().Unix()
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for _, update := range updates {
+		lastEventsKey := "last_events:destination#" + destinationID + ":id#" + update.EventID
+		originalEventKey := "last_events:destination#" + destinationID + ":id#" + extractOriginalEventId(update.EventID)
+
+		var err error
+		if update.Status == EventStatusError {
+			err = updateTwoFieldsCachedEvent.Send(conn, lastEventsKey, EventStatusError, update.Value, "destination_id", destinationID, lastEventsIndexKey, now, update.EventID, originalEventKey)
+		} else {
+			err = updateThreeFieldsCachedEvent.Send(conn, lastEventsKey, update.Status, update.Value, EventStatusError, "", "destination_id", destinationID, lastEventsIndexKey, now, update.EventID, originalEventKey)
+		}
+		if err != nil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+	}
+
+	if err := conn.Flush(); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	var lastErr error
+	for range updates {
+		if _, err := conn.Receive(); err != nil && err != redis.ErrNil {
+			r.errorMetrics.NoticeError(err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
 //TrimEvents removes events from index that exceed provided capacity Redis
 func (r *Redis) TrimEvents(destinationID string, capacity int) error {
 	conn := r.pool.Get()
@@ -36,6 +36,8 @@ type Storage interface {
 	UpdateSucceedEvent(destinationID, eventID, success string) error
 	UpdateErrorEvent(destinationID, eventID, error string) error
 	UpdateSkipEvent(destinationID, eventID, error string) error
+	//UpdateEvents applies all updates with one pipelined call
+	UpdateEvents(destinationID string, updates []*EventUpdate) error
 	TrimEvents(destinationID string, capacity int) error
 
 	GetEvents(destinationID string, start, end time.Time, n int) ([]Event, error)
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var airbyteContainersLabels = []string{"command"}
+
+var (
+	airbyteRunningContainers *prometheus.GaugeVec
+)
+
+func initAirbyteContainers() {
+	airbyteRunningContainers = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "sources",
+		Name:      "airbyte_running_containers",
+	}, airbyteContainersLabels)
+}
+
+//RunningAirbyteContainers sets the current number of running Airbyte containers of the command (spec, check, discover, read)
+func RunningAirbyteContainers(command string, value int64) {
+	if Enabled() {
+		airbyteRunningContainers.WithLabelValues(command).Set(float64(value))
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var eventLabels = []string{"project_id", "source_type", "source_tap", "source_id", "destination_type", "destination_id"}
+var eventLabels = []string{"project_id", "source_type", "source_tap", "source_id", "destination_type", "destination_id", "mode"}
 
 var (
 	successEvents *prometheus.CounterVec
@@ -30,51 +30,53 @@ func initEvents() {
 	}, eventLabels)
 }
 
-func SuccessTokenEvent(tokenID, destinationType, destinationName string) {
-	SuccessTokenEvents(tokenID, destinationType, destinationName, 1)
+func SuccessTokenEvent(tokenID, destinationType, destinationName, mode string) {
+	SuccessTokenEvents(tokenID, destinationType, destinationName, mode, 1)
 }
 
-func SuccessTokenEvents(tokenID, destinationType, destinationName string, value int) {
+func SuccessTokenEvents(tokenID, destinationType, destinationName, mode string, value int) {
 	if Enabled() {
 		projectID, destinationID := extractLabels(destinationName)
-		successEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID).Add(float64(value))
+		successEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID, mode).Add(float64(value))
 	}
 }
 
-func SkipTokenEvent(tokenID, destinationType, destinationName string) {
-	SkipTokenEvents(tokenID, destinationType, destinationName, 1)
+func SkipTokenEvent(tokenID, destinationType, destinationName, mode string) {
+	SkipTokenEvents(tokenID, destinationType, destinationName, mode, 1)
 }
 
-func ErrorTokenEvent(tokenID, destinationType, destinationName string) {
-	ErrorTokenEvents(tokenID, destinationType, destinationName, 1)
+func ErrorTokenEvent(tokenID, destinationType, destinationName, mode string) {
+	ErrorTokenEvents(tokenID, destinationType, destinationName, mode, 1)
 }
 
-func ErrorTokenEvents(tokenID, destinationType, destinationName string, value int) {
+func ErrorTokenEvents(tokenID, destinationType, destinationName, mode string, value int) {
 	if Enabled() {
 		projectID, destinationID := extractLabels(destinationName)
-		errorsEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID).Add(float64(value))
+		errorsEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID, mode).Add(float64(value))
 	}
 }
 
+//SuccessSourceEvents counts synchronized objects: source syncs are always stored with batches
 func SuccessSourceEvents(sourceType, sourceTap, sourceName, destinationType, destinationName string, value int) {
 	if Enabled() {
 		projectID, destinationID := extractLabels(destinationName)
 		_, sourceID := extractLabels(sourceName)
-		successEvents.WithLabelValues(projectID, sourceType, sourceTap, sourceID, destinationType, destinationID).Add(float64(value))
+		successEvents.WithLabelValues(projectID, sourceType, sourceTap, sourceID, destinationType, destinationID, BatchMode).Add(float64(value))
 	}
 }
 
-func SkipTokenEvents(tokenID, destinationType, destinationName string, value int) {
+func SkipTokenEvents(tokenID, destinationType, destinationName, mode string, value int) {
 	if Enabled() {
 		projectID, destinationID := extractLabels(destinationName)
-		skippedEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID).Add(float64(value))
+		skippedEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID, mode).Add(float64(value))
 	}
 }
 
+//ErrorSourceEvents counts objects which haven't been synchronized: source syncs are always stored with batches
 func ErrorSourceEvents(sourceType, sourceTap, sourceName, destinationType, destinationName string, value int) {
 	if Enabled() {
 		projectID, destinationID := extractLabels(destinationName)
 		_, sourceID := extractLabels(sourceName)
-		errorsEvents.WithLabelValues(projectID, sourceType, sourceTap, sourceID, destinationType, destinationID).Add(float64(value))
+		errorsEvents.WithLabelValues(projectID, sourceType, sourceTap, sourceID, destinationType, destinationID, BatchMode).Add(float64(value))
 	}
 }
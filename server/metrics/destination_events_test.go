@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestinationEventsModeLabel(t *testing.T) {
+	tests := []struct {
+		name           string
+		write          func()
+		expectedMetric string
+		expectedLabels map[string]string
+		expectedValue  float64
+	}{
+		{
+			"Stream success token event",
+			func() { SuccessTokenEvent("token1", "postgres", "project1.dest1", StreamMode) },
+			"eventnative_destinations_events",
+			map[string]string{"project_id": "project1", "source_type": TokenSourceType, "source_tap": EmptySourceTap, "source_id": "token1",
+				"destination_type": "postgres", "destination_id": "dest1", "mode": StreamMode},
+			1,
+		},
+		{
+			"Batch success token events",
+			func() { SuccessTokenEvents("token1", "postgres", "dest1", BatchMode, 10) },
+			"eventnative_destinations_events",
+			map[string]string{"project_id": "-", "source_type": TokenSourceType, "source_tap": EmptySourceTap, "source_id": "token1",
+				"destination_type": "postgres", "destination_id": "dest1", "mode": BatchMode},
+			10,
+		},
+		{
+			"Stream skip token event",
+			func() { SkipTokenEvent("token1", "snowflake", "dest1", StreamMode) },
+			"eventnative_destinations_skips",
+			map[string]string{"project_id": "-", "source_type": TokenSourceType, "source_tap": EmptySourceTap, "source_id": "token1",
+				"destination_type": "snowflake", "destination_id": "dest1", "mode": StreamMode},
+			1,
+		},
+		{
+			"Batch error token events",
+			func() { ErrorTokenEvents("token1", "snowflake", "dest1", BatchMode, 3) },
+			"eventnative_destinations_errors",
+			map[string]string{"project_id": "-", "source_type": TokenSourceType, "source_tap": EmptySourceTap, "source_id": "token1",
+				"destination_type": "snowflake", "destination_id": "dest1", "mode": BatchMode},
+			3,
+		},
+		{
+			"Source events are always batch",
+			func() {
+				SuccessSourceEvents("airbyte", "source-test", "project1.source1", "postgres", "project1.dest1", 5)
+			},
+			"eventnative_destinations_events",
+			map[string]string{"project_id": "project1", "source_type": "airbyte", "source_tap": "source-test", "source_id": "source1",
+				"destination_type": "postgres", "destination_id": "dest1", "mode": BatchMode},
+			5,
+		},
+		{
+			"Source errors are always batch",
+			func() { ErrorSourceEvents("airbyte", "source-test", "source1", "postgres", "dest1", 2) },
+			"eventnative_destinations_errors",
+			map[string]string{"project_id": "-", "source_type": "airbyte", "source_tap": "source-test", "source_id": "source1",
+				"destination_type": "postgres", "destination_id": "dest1", "mode": BatchMode},
+			2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousRegistry := Registry
+			Registry = prometheus.NewRegistry()
+			defer func() { Registry = previousRegistry }()
+			initEvents()
+
+			tt.write()
+
+			families, err := Registry.Gather()
+			require.NoError(t, err)
+			var found bool
+			for _, family := range families {
+				if family.GetName() != tt.expectedMetric {
+					continue
+				}
+
+				require.Len(t, family.GetMetric(), 1)
+				metric := family.GetMetric()[0]
+				labels := map[string]string{}
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				require.Equal(t, tt.expectedLabels, labels)
+				require.Equal(t, tt.expectedValue, metric.GetCounter().GetValue())
+				found = true
+			}
+			require.True(t, found, "metric %s must be written", tt.expectedMetric)
+		})
+	}
+}
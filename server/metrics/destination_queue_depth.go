@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var destinationQueueDepthLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	destinationQueueDepth *prometheus.GaugeVec
+)
+
+func initDestinationQueueDepth() {
+	destinationQueueDepth = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "queue_depth",
+	}, destinationQueueDepthLabels)
+}
+
+//SetDestinationQueueDepth sets the sampled number of events in the destination queue
+func SetDestinationQueueDepth(destinationType, destinationName string, value int64) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		destinationQueueDepth.WithLabelValues(projectID, destinationType, destinationID).Set(float64(value))
+	}
+}
+
+//RemoveDestinationQueueDepth removes the destination queue depth series (e.g. when the destination is removed)
+func RemoveDestinationQueueDepth(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		destinationQueueDepth.DeleteLabelValues(projectID, destinationType, destinationID)
+	}
+}
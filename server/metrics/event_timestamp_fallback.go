@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var eventTimestampFallbackLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var eventTimestampFallback *prometheus.CounterVec
+
+func initEventTimestampFallback() {
+	eventTimestampFallback = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "event_timestamp_fallback_events",
+	}, eventTimestampFallbackLabels)
+}
+
+//EventTimestampFallback increments counter of events which timestamp_field is missing or can't be parsed
+//(ingestion time is used instead)
+func EventTimestampFallback(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		eventTimestampFallback.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var eventsCacheLabels = []string{"status"}
+
+var eventsCacheSkippedLabels = []string{"project_id", "destination_id"}
+
+var eventsCacheFailuresLabels = []string{"operation", "reason"}
+
+var (
+	eventsCacheBatchSize *prometheus.HistogramVec
+	eventsCacheSkipped   *prometheus.CounterVec
+	eventsCacheAvailable *prometheus.GaugeVec
+	eventsCacheFailures  *prometheus.CounterVec
+)
+
+func initEventsCache() {
+	eventsCacheBatchSize = NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eventnative",
+		Subsystem: "events_cache",
+		Name:      "batch_size",
+		Buckets:   []float64{1, 10, 50, 100, 500, 1000, 5000, 10000},
+	}, eventsCacheLabels)
+	eventsCacheSkipped = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "events_cache",
+		Name:      "skipped",
+	}, eventsCacheSkippedLabels)
+	eventsCacheAvailable = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "events_cache",
+		Name:      "available",
+	}, []string{})
+	eventsCacheFailures = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "events_cache",
+		Name:      "failures",
+	}, eventsCacheFailuresLabels)
+}
+
+//EventsCacheBatchSize observes amount of events updated in events cache with one pipelined call
+func EventsCacheBatchSize(status string, size int) {
+	if Enabled() {
+		eventsCacheBatchSize.WithLabelValues(status).Observe(float64(size))
+	}
+}
+
+//EventsCacheSkipped increments counter of stored events which aren't written into events cache according to caching.cache_skip_events
+func EventsCacheSkipped(destinationName string, value int) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		eventsCacheSkipped.WithLabelValues(projectID, destinationID).Add(float64(value))
+	}
+}
+
+//SetEventsCacheAvailable sets 1 if events cache storage is available otherwise 0
+func SetEventsCacheAvailable(available bool) {
+	if Enabled() {
+		var value float64
+		if available {
+			value = 1
+		}
+		eventsCacheAvailable.WithLabelValues().Set(value)
+	}
+}
+
+//EventsCacheFailure increments counter of failed or skipped events cache storage operations
+//reason is one of: error, timeout, unavailable (skipped because the storage is unavailable)
+func EventsCacheFailure(operation, reason string) {
+	if Enabled() {
+		eventsCacheFailures.WithLabelValues(operation, reason).Inc()
+	}
+}
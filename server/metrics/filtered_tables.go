@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//table_allowlist/table_denylist results
+const (
+	TableDenied     = "denied"
+	TableNotAllowed = "not_allowed"
+)
+
+var filteredTablesLabels = []string{"project_id", "destination_type", "destination_id", "table", "result"}
+
+var filteredTableEvents *prometheus.CounterVec
+
+func initFilteredTables() {
+	filteredTableEvents = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "filtered_table_events",
+	}, filteredTablesLabels)
+}
+
+//FilteredTableEvents increments counter of events which are skipped because of table_allowlist/table_denylist
+//result is TableDenied or TableNotAllowed
+func FilteredTableEvents(destinationType, destinationName, table, result string, value int) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		filteredTableEvents.WithLabelValues(projectID, destinationType, destinationID, table, result).Add(float64(value))
+	}
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//lookup enrichment results
+const (
+	LookupHit  = "hit"
+	LookupMiss = "miss"
+)
+
+var lookupEnrichmentLabels = []string{"lookup", "result"}
+
+var lookupEnrichmentEvents *prometheus.CounterVec
+
+func initLookupEnrichment() {
+	lookupEnrichmentEvents = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "enrichment",
+		Name:      "lookup_events",
+	}, lookupEnrichmentLabels)
+}
+
+//LookupEnrichmentEvents increments counter of events which have been enriched from the lookup table
+//result is LookupHit or LookupMiss (the key doesn't exist in the lookup table)
+func LookupEnrichmentEvents(lookup, result string) {
+	if Enabled() {
+		lookupEnrichmentEvents.WithLabelValues(lookup, result).Inc()
+	}
+}
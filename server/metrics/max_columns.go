@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var droppedColumnsLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var droppedColumns *prometheus.CounterVec
+
+func initDroppedColumns() {
+	droppedColumns = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "max_columns_dropped_fields",
+	}, droppedColumnsLabels)
+}
+
+//DropColumns increments counter of event fields which have been dropped because of max_columns limit
+func DropColumns(destinationType, destinationName string, value int) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		droppedColumns.WithLabelValues(projectID, destinationType, destinationID).Add(float64(value))
+	}
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var oversizedEventsLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var oversizedEvents *prometheus.CounterVec
+
+func initOversizedEvents() {
+	oversizedEvents = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "max_event_bytes_skipped_events",
+	}, oversizedEventsLabels)
+}
+
+//OversizedEvents increments counter of events which have been skipped because of max_event_bytes limit
+func OversizedEvents(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		oversizedEvents.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
@@ -15,6 +15,11 @@ import (
 const (
 	TokenSourceType = "token"
 	EmptySourceTap  = ""
+
+	//BatchMode is a mode label value of events which are stored with batches (log files uploader, bulk uploads and source syncs)
+	BatchMode = "batch"
+	//StreamMode is a mode label value of events which are stored one by one by the streaming worker
+	StreamMode = "stream"
 )
 
 var Exported bool
@@ -37,6 +42,12 @@ func NewGaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVe
 	return vec
 }
 
+func NewHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec {
+	vec := prometheus.NewHistogramVec(opts, labels)
+	Registry.MustRegister(vec)
+	return vec
+}
+
 const Unknown = "unknown"
 
 func Init(exported bool) {
@@ -56,6 +67,25 @@ func Init(exported bool) {
 	initUsersRecognitionQueue()
 	initUsersRecognitionRedis()
 	initStreamEventsQueue()
+	initDestinationQueueDepth()
+	initStreamDedup()
+	initStreamRetries()
+	initDroppedColumns()
+	initOversizedEvents()
+	initTimestampBounds()
+	initStoreThrottling()
+	initSyncLoads()
+	initStoreSummary()
+	initStoreTimeouts()
+	initEventsCache()
+	initStreamConflicts()
+	initSourceStreams()
+	initFilteredTables()
+	initUpdateBatches()
+	initRetention()
+	initEventTimestampFallback()
+	initAirbyteContainers()
+	initLookupEnrichment()
 }
 
 func InitRelay(clusterID string, viper *viper.Viper) *Relay {
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var retentionLabels = []string{"project_id", "destination_type", "destination_id", "table"}
+
+var (
+	retentionDeletedRows *prometheus.CounterVec
+)
+
+func initRetention() {
+	retentionDeletedRows = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "retention_deleted_rows",
+	}, retentionLabels)
+}
+
+//RetentionDeletedRows increments the number of rows which were deleted from the table as older than the retention age
+func RetentionDeletedRows(destinationType, destinationName, table string, rows int64) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		retentionDeletedRows.WithLabelValues(projectID, destinationType, destinationID, table).Add(float64(rows))
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var streamLabels = []string{"project_id", "source_type", "source_tap", "source_id", "stream"}
+
+var syncDurationLabels = []string{"project_id", "source_type", "source_tap", "source_id", "status"}
+
+var (
+	streamRecords *prometheus.CounterVec
+	streamBytes   *prometheus.CounterVec
+	syncDuration  *prometheus.HistogramVec
+)
+
+func initSourceStreams() {
+	streamRecords = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "sources",
+		Name:      "stream_records",
+	}, streamLabels)
+	streamBytes = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "sources",
+		Name:      "stream_bytes",
+	}, streamLabels)
+	syncDuration = NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eventnative",
+		Subsystem: "sources",
+		Name:      "sync_duration_seconds",
+		Buckets:   []float64{10, 30, 60, 300, 600, 1800, 3600, 7200, 21600},
+	}, syncDurationLabels)
+}
+
+//SourceStreamRecords increments counters of records and bytes (raw record messages size) read from the source stream
+func SourceStreamRecords(sourceType, sourceTap, sourceName, stream string, records, bytes int) {
+	if Enabled() {
+		projectID, sourceID := extractLabels(sourceName)
+		streamRecords.WithLabelValues(projectID, sourceType, sourceTap, sourceID, stream).Add(float64(records))
+		streamBytes.WithLabelValues(projectID, sourceType, sourceTap, sourceID, stream).Add(float64(bytes))
+	}
+}
+
+//SourceSyncDuration observes the source sync duration with status: success or error
+func SourceSyncDuration(sourceType, sourceTap, sourceName, status string, duration time.Duration) {
+	if Enabled() {
+		projectID, sourceID := extractLabels(sourceName)
+		syncDuration.WithLabelValues(projectID, sourceType, sourceTap, sourceID, status).Observe(duration.Seconds())
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var storeSummaryLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	batchMaxTableLatency *prometheus.GaugeVec
+)
+
+func initStoreSummary() {
+	batchMaxTableLatency = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "batch_max_table_latency_ms",
+	}, storeSummaryLabels)
+}
+
+//SetBatchMaxTableLatency sets max table storing latency of the last stored batch file
+func SetBatchMaxTableLatency(destinationType, destinationName string, latencyMs int64) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		batchMaxTableLatency.WithLabelValues(projectID, destinationType, destinationID).Set(float64(latencyMs))
+	}
+}
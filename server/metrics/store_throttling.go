@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var storeThrottlingLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	storeThrottled *prometheus.GaugeVec
+	storeLatency   *prometheus.GaugeVec
+)
+
+func initStoreThrottling() {
+	storeThrottled = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "store_throttled",
+	}, storeThrottlingLabels)
+	storeLatency = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "store_latency_ms",
+	}, storeThrottlingLabels)
+}
+
+//SetStoreThrottled sets 1 if batches dispatching to the destination is throttled otherwise 0
+func SetStoreThrottled(destinationType, destinationName string, throttled bool) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		var value float64
+		if throttled {
+			value = 1
+		}
+		storeThrottled.WithLabelValues(projectID, destinationType, destinationID).Set(value)
+	}
+}
+
+//SetStoreLatency sets rolling average of batch Store() latency
+func SetStoreLatency(destinationType, destinationName string, latencyMs int64) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		storeLatency.WithLabelValues(projectID, destinationType, destinationID).Set(float64(latencyMs))
+	}
+}
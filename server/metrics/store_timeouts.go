@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var storeTimeoutsLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	storeTimeouts *prometheus.CounterVec
+)
+
+func initStoreTimeouts() {
+	storeTimeouts = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "store_timeouts",
+	}, storeTimeoutsLabels)
+}
+
+//StoreTimeout increments the number of tables stores which exceeded store timeout (and were routed to fallback)
+func StoreTimeout(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		storeTimeouts.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
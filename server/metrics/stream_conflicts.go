@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var streamConflictsLabels = []string{"project_id", "destination_type", "destination_id", "resolution"}
+
+var (
+	streamConflicts *prometheus.CounterVec
+)
+
+func initStreamConflicts() {
+	streamConflicts = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "stream_conflicts",
+	}, streamConflictsLabels)
+}
+
+//StreamConflict increments the number of streamed events with existing primary key which have been resolved
+//with on_conflict strategy (resolution: update or ignore)
+func StreamConflict(destinationType, destinationName, resolution string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		streamConflicts.WithLabelValues(projectID, destinationType, destinationID, resolution).Inc()
+	}
+}
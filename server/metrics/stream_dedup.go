@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var streamDedupLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	streamDedupHits   *prometheus.CounterVec
+	streamDedupMisses *prometheus.CounterVec
+)
+
+func initStreamDedup() {
+	streamDedupHits = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "dedup_hits",
+	}, streamDedupLabels)
+	streamDedupMisses = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "dedup_misses",
+	}, streamDedupLabels)
+}
+
+//DedupHit increments counter of events which have been skipped as duplicates
+func DedupHit(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		streamDedupHits.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
+
+//DedupMiss increments counter of events which haven't been found in the deduplication cache
+func DedupMiss(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		streamDedupMisses.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	streamRetriesLabels     = []string{"project_id", "destination_type", "destination_id"}
+	streamDeadLettersLabels = []string{"project_id", "destination_type", "destination_id", "classification"}
+)
+
+var (
+	streamRetries     *prometheus.CounterVec
+	streamDeadLetters *prometheus.CounterVec
+)
+
+func initStreamRetries() {
+	streamRetries = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "stream_retries",
+	}, streamRetriesLabels)
+	streamDeadLetters = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "stream_dead_letters",
+	}, streamDeadLettersLabels)
+}
+
+//StreamRetry increments counter of streaming events which have been re-queued after a transient error
+func StreamRetry(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		streamRetries.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
+
+//StreamDeadLetter increments counter of streaming events which have been sent to the dead-letter queue
+func StreamDeadLetter(destinationType, destinationName, classification string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		streamDeadLetters.WithLabelValues(projectID, destinationType, destinationID, classification).Inc()
+	}
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var syncLoadsLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	concurrentSyncLoads *prometheus.GaugeVec
+)
+
+func initSyncLoads() {
+	concurrentSyncLoads = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "concurrent_sync_loads",
+	}, syncLoadsLabels)
+}
+
+//IncConcurrentSyncLoads increments the number of running pulled data chunks loads (SyncStore) into the destination
+func IncConcurrentSyncLoads(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		concurrentSyncLoads.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
+
+//DecConcurrentSyncLoads decrements the number of running pulled data chunks loads (SyncStore) into the destination
+func DecConcurrentSyncLoads(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		concurrentSyncLoads.WithLabelValues(projectID, destinationType, destinationID).Dec()
+	}
+}
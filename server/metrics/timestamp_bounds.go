@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//timestamp_bounds results
+const (
+	TimestampRejected = "rejected"
+	TimestampClamped  = "clamped"
+)
+
+var timestampBoundsLabels = []string{"project_id", "destination_type", "destination_id", "result"}
+
+var timestampOutOfBounds *prometheus.CounterVec
+
+func initTimestampBounds() {
+	timestampOutOfBounds = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "timestamp_out_of_bounds_events",
+	}, timestampBoundsLabels)
+}
+
+//TimestampOutOfBounds increments counter of events which _timestamp is out of timestamp_bounds
+//result is TimestampRejected (the event is skipped) or TimestampClamped
+func TimestampOutOfBounds(destinationType, destinationName, result string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		timestampOutOfBounds.WithLabelValues(projectID, destinationType, destinationID, result).Inc()
+	}
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var updateBatchesLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var updateBatchSize *prometheus.HistogramVec
+
+func initUpdateBatches() {
+	updateBatchSize = NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "update_batch_size",
+		Buckets:   []float64{1, 5, 10, 50, 100, 500, 1000},
+	}, updateBatchesLabels)
+}
+
+//UpdateBatchSize observes amount of records updated in the destination with one statement
+func UpdateBatchSize(destinationType, destinationName string, size int) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		updateBatchSize.WithLabelValues(projectID, destinationType, destinationID).Observe(float64(size))
+	}
+}
@@ -21,15 +21,24 @@ func (a *AdminToken) AdminAuth(main gin.HandlerFunc) gin.HandlerFunc {
 			return
 		}
 
-		token := c.Query(TokenName)
-		if token == "" {
-			token = c.GetHeader(AdminTokenKey)
-		}
-
-		if token != a.Token {
+		if !a.IsAdmin(c) {
 			c.JSON(http.StatusUnauthorized, ErrResponse(AdminTokenErr, nil))
 			return
 		}
 		main(c)
 	}
 }
+
+//IsAdmin returns true if the request contains configured admin token (in query parameter or header)
+func (a *AdminToken) IsAdmin(c *gin.Context) bool {
+	if a.Token == "" {
+		return false
+	}
+
+	token := c.Query(TokenName)
+	if token == "" {
+		token = c.GetHeader(AdminTokenKey)
+	}
+
+	return token == a.Token
+}
@@ -55,6 +55,9 @@ func (s *Service) AcceptRequest(processor events.Processor, reqContext *events.R
 		var destinationIDs []string
 		for _, destinationProxy := range destinationStorages {
 			destinationIDs = append(destinationIDs, destinationProxy.ID())
+			if destinationProxy.IsCachingSkipped(payload) {
+				continue
+			}
 			s.eventsCache.Put(destinationProxy.IsCachingDisabled(), destinationProxy.ID(), eventID, serializedPayload)
 		}
 
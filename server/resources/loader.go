@@ -1,9 +1,11 @@
 package resources
 
 import (
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -15,6 +17,9 @@ type ContentType string
 const (
 	lastModifiedHeader    = "Last-Modified"
 	ifModifiedSinceHeader = "If-Modified-Since"
+	acceptEncodingHeader  = "Accept-Encoding"
+	contentEncodingHeader = "Content-Encoding"
+	gzipEncoding          = "gzip"
 
 	JSONContentType    ContentType = "json"
 	YamlContentType    ContentType = "yaml"
@@ -87,6 +92,8 @@ func LoadFromHTTP(fullURL, ifModifiedSinceValue string) (*ResponsePayload, error
 	}
 
 	req.Header.Add(ifModifiedSinceHeader, ifModifiedSinceValue)
+	//explicit header disables transparent decompression of http.Transport: response is decompressed by readBody
+	req.Header.Add(acceptEncodingHeader, gzipEncoding)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("Error loading resource from url %s: %v", fullURL, err)
@@ -101,7 +108,7 @@ func LoadFromHTTP(fullURL, ifModifiedSinceValue string) (*ResponsePayload, error
 		return nil, fmt.Errorf("Error loading resource from url %s: http code isn't 200 [%d]", fullURL, resp.StatusCode)
 	}
 
-	b, err := ioutil.ReadAll(resp.Body)
+	b, err := readBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("Error reading resource from url %s: %v", fullURL, err)
 	}
@@ -124,3 +131,18 @@ func LoadFromHTTP(fullURL, ifModifiedSinceValue string) (*ResponsePayload, error
 		ContentType:  &contentType,
 	}, nil
 }
+
+//readBody returns response body bytes (decompressed if Content-Encoding is gzip)
+func readBody(resp *http.Response) ([]byte, error) {
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(strings.TrimSpace(resp.Header.Get(contentEncodingHeader)), gzipEncoding) {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error creating gzip reader: %v", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,70 @@
+package resources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFromHTTP(t *testing.T) {
+	config := []byte(`{"destinations":{"pg":{"type":"postgres"}}}`)
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, err := gzipWriter.Write(config)
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		expectedErr     string
+	}{
+		{
+			"plain body",
+			"",
+			config,
+			"",
+		},
+		{
+			"gzip encoded body",
+			"gzip",
+			gzipped.Bytes(),
+			"",
+		},
+		{
+			"malformed gzip body",
+			"gzip",
+			config,
+			"error creating gzip reader: gzip: invalid header",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+				w.Header().Set("Content-Type", "application/json")
+				if tt.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", tt.contentEncoding)
+				}
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			payload, err := LoadFromHTTP(server.URL, "")
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, config, payload.Content)
+			require.Equal(t, JSONContentType, *payload.ContentType)
+		})
+	}
+}
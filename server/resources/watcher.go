@@ -4,15 +4,28 @@ import (
 	"github.com/jitsucom/jitsu/server/appstatus"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/utils"
 	"time"
 )
 
+var (
+	//MaxReloadBackoff is a max interval between reloads after consecutive failures
+	MaxReloadBackoff = time.Minute
+	//FailuresThreshold is a count of consecutive reload failures after which FailuresHandler is called
+	FailuresThreshold = 10
+	//FailuresHandler is called on every FailuresThreshold consecutive reload failures (e.g. for alerting)
+	FailuresHandler func(name string, failures int, err error)
+)
+
 type Watcher struct {
 	name         string
 	hash         string
 	source       string
 	lastModified string
 	reloadEvery  time.Duration
+	backoff      *utils.Backoff
+	//delay before the next reload: reloadEvery or increased by backoff after failures
+	delay time.Duration
 
 	loadFunc func(string, string) (*ResponsePayload, error)
 	consumer func([]byte)
@@ -20,6 +33,7 @@ type Watcher struct {
 
 //Watch First loads source then runs goroutine to reload source every 'reloadEvery' duration
 //On every load check if content was changed => run consumer otherwise do nothing
+//On consecutive load failures the interval is increased exponentially up to MaxReloadBackoff
 func Watch(name, source string, loadFunc func(string, string) (*ResponsePayload, error), consumer func([]byte), reloadEvery time.Duration) func() {
 	w := &Watcher{
 		name:         name,
@@ -29,6 +43,8 @@ func Watch(name, source string, loadFunc func(string, string) (*ResponsePayload,
 		loadFunc:     loadFunc,
 		consumer:     consumer,
 		reloadEvery:  reloadEvery,
+		backoff:      utils.NewBackoff(reloadEvery, MaxReloadBackoff),
+		delay:        reloadEvery,
 	}
 	logging.Infof("🔄 Resource [%s] will be loaded every %d seconds", name, int(reloadEvery.Seconds()))
 	w.watch()
@@ -43,7 +59,7 @@ func (w *Watcher) watch() {
 				break
 			}
 
-			time.Sleep(w.reloadEvery)
+			time.Sleep(w.delay)
 
 			w.download()
 		}
@@ -53,14 +69,22 @@ func (w *Watcher) watch() {
 func (w *Watcher) download() {
 	payload, err := w.loadFunc(w.source, w.lastModified)
 	if err == ErrNoModified {
+		w.resetBackoff()
 		return
 	}
 
 	if err != nil {
-		logging.Errorf("Error reloading resource [%s]: %v", w.name, err)
+		w.delay = w.backoff.Next()
+		failures := w.backoff.Attempts()
+		logging.Errorf("Error reloading resource [%s] (%d consecutive failures, next reload in %s): %v", w.name, failures, w.delay, err)
+		if FailuresHandler != nil && FailuresThreshold > 0 && failures%FailuresThreshold == 0 {
+			FailuresHandler(w.name, failures, err)
+		}
 		return
 	}
 
+	w.resetBackoff()
+
 	w.lastModified = payload.LastModified
 
 	newHash := GetBytesHash(payload.Content)
@@ -71,6 +95,14 @@ func (w *Watcher) download() {
 	}
 }
 
+func (w *Watcher) resetBackoff() {
+	if w.backoff.Attempts() > 0 {
+		logging.Infof("Resource [%s] has been reloaded after %d consecutive failures", w.name, w.backoff.Attempts())
+		w.backoff.Reset()
+		w.delay = w.reloadEvery
+	}
+}
+
 func (w *Watcher) forceReload() {
 	w.hash = ""
 	w.lastModified = ""
@@ -10,6 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/dlq"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
 	"github.com/jitsucom/jitsu/server/geo"
@@ -29,7 +30,7 @@ import (
 )
 
 func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *destinations.Service, sourcesService *sources.Service,
-	taskService *synchronization.TaskService, fallbackService *fallback.Service, coordinationService *coordination.Service,
+	taskService *synchronization.TaskService, fallbackService *fallback.Service, dlqService *dlq.Service, coordinationService *coordination.Service,
 	eventsCache *caching.EventsCache, systemService *system.Service, segmentEndpointFieldMapper, segmentCompatEndpointFieldMapper events.Mapper,
 	processorHolder *events.ProcessorHolder, multiplexingService *multiplexing.Service, walService *wal.Service, geoService *geo.Service,
 	pluginsRepository plugins.PluginsRepository) *gin.Engine {
@@ -55,6 +56,11 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		c.String(http.StatusOK, "pong")
 	})
 
+	//health probes are used by Kubernetes without authorization: errors are returned only with admin token
+	healthHandler := handlers.NewHealthHandler(destinations, sourcesService, &middleware.AdminToken{Token: adminToken}, viper.GetStringSlice("server.health.critical"))
+	router.GET("/healthz", healthHandler.HealthzHandler)
+	router.GET("/readyz", healthHandler.ReadyzHandler)
+
 	publicURL := viper.GetString("server.public_url")
 	configuratorURN := viper.GetString("server.configurator_urn")
 
@@ -76,11 +82,12 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 	dryRunHandler := handlers.NewDryRunHandler(destinations, processorHolder.GetJSPreprocessor(), geoService)
 	statisticsHandler := handlers.NewStatisticsHandler(metaStorage)
 
-	airbyteHandler := handlers.NewAirbyteHandler()
+	airbyteHandler := handlers.NewAirbyteHandler(taskService, sourcesService)
 	sourcesHandler := handlers.NewSourcesHandler(sourcesService, metaStorage, destinations)
 	pixelHandler := handlers.NewPixelHandler(multiplexingService, processorHolder.GetPixelPreprocessor(), destinations, geoService)
 
 	bulkHandler := handlers.NewBulkHandler(destinations, processorHolder.GetBulkPreprocessor())
+	destinationsPauseHandler := handlers.NewDestinationsPauseHandler(destinations)
 
 	geoDataResolverHandler := handlers.NewGeoDataResolverHandler(geoService)
 
@@ -110,6 +117,16 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.GET("/geo_data_resolvers/editions", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.EditionsHandler))
 		apiV1.POST("/geo_data_resolvers/test", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.TestHandler))
 		apiV1.POST("/destinations/test", adminTokenMiddleware.AdminAuth(handlers.DestinationsHandler))
+		apiV1.POST("/destinations/validate", adminTokenMiddleware.AdminAuth(handlers.DestinationsValidateHandler))
+		apiV1.GET("/destinations/status", adminTokenMiddleware.AdminAuth(handlers.DestinationsStatusHandler))
+		apiV1.POST("/destinations/pause", adminTokenMiddleware.AdminAuth(destinationsPauseHandler.PauseHandler))
+		apiV1.POST("/destinations/resume", adminTokenMiddleware.AdminAuth(destinationsPauseHandler.ResumeHandler))
+		apiV1.GET("/destinations/samples", adminTokenMiddleware.AdminAuth(handlers.DestinationsSamplesHandler))
+		apiV1.GET("/destinations/routing", adminTokenMiddleware.AdminAuth(handlers.NewDestinationsRoutingHandler(destinations).Handler))
+		apiV1.GET("/destinations/stats", adminTokenMiddleware.AdminAuth(handlers.NewDestinationsStatsHandler(destinations).Handler))
+		apiV1.POST("/destinations/repair_schema", adminTokenMiddleware.AdminAuth(handlers.NewSchemaRepairHandler(destinations).Handler))
+		apiV1.POST("/destinations/retention", adminTokenMiddleware.AdminAuth(handlers.NewRetentionHandler(destinations).Handler))
+		apiV1.POST("/destinations/preview", adminTokenMiddleware.AdminAuth(handlers.NewPreviewHandler(destinations.GetFactory()).Handler))
 		apiV1.POST("/templates/evaluate", adminTokenMiddleware.AdminAuth(handlers.NewEventTemplateHandler(pluginsRepository, destinations.GetFactory()).Handler))
 
 		sourcesRoute := apiV1.Group("/sources")
@@ -129,16 +146,24 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.POST("/tasks", adminTokenMiddleware.AdminAuth(taskHandler.SyncHandler))
 		apiV1.GET("/tasks/:taskID/logs", adminTokenMiddleware.AdminAuth(taskHandler.TaskLogsHandler))
 		apiV1.POST("/tasks/:taskID/cancel", adminTokenMiddleware.AdminAuth(taskHandler.TaskCancelHandler))
+		apiV1.GET("/syncs/active", adminTokenMiddleware.AdminAuth(taskHandler.ActiveSyncsHandler))
+		apiV1.POST("/syncs/active/cancel", adminTokenMiddleware.AdminAuth(taskHandler.CancelActiveSyncsHandler))
 
 		apiV1.GET("/cluster", adminTokenMiddleware.AdminAuth(handlers.NewClusterHandler(coordinationService).Handler))
 		apiV1.GET("/events/cache", adminTokenMiddleware.AdminAuth(jsEventHandler.GetHandler))
 
 		apiV1.GET("/fallback", adminTokenMiddleware.AdminAuth(fallbackHandler.GetHandler))
 		apiV1.POST("/replay", adminTokenMiddleware.AdminAuth(fallbackHandler.ReplayHandler))
+		apiV1.POST("/replay/archive", adminTokenMiddleware.AdminAuth(fallbackHandler.ArchiveReplayHandler))
+		apiV1.GET("/dlq", adminTokenMiddleware.AdminAuth(handlers.NewDLQHandler(dlqService).GetHandler))
 
+		apiV1.GET("/airbyte/diagnostics", adminTokenMiddleware.AdminAuth(airbyteHandler.DiagnosticsHandler))
 		apiV1.GET("/airbyte/:dockerImageName/spec", adminTokenMiddleware.AdminAuth(airbyteHandler.SpecHandler))
 		apiV1.GET("/airbyte/:dockerImageName/versions", adminTokenMiddleware.AdminAuth(airbyteHandler.VersionsHandler))
+		apiV1.POST("/airbyte/versions/batch", adminTokenMiddleware.AdminAuth(airbyteHandler.BatchVersionsHandler))
 		apiV1.POST("/airbyte/:dockerImageName/catalog", adminTokenMiddleware.AdminAuth(airbyteHandler.CatalogHandler))
+		apiV1.POST("/airbyte/tasks/:taskID/cancel", adminTokenMiddleware.AdminAuth(airbyteHandler.CancelTaskHandler))
+		apiV1.DELETE("/airbyte/cache/:dockerImageName", adminTokenMiddleware.AdminAuth(airbyteHandler.EvictCacheHandler))
 
 		apiV1.POST("/singer/:tap/catalog", adminTokenMiddleware.AdminAuth(handlers.NewSingerHandler(metaStorage).CatalogHandler))
 	}
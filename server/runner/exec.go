@@ -13,6 +13,9 @@ import (
 
 var ErrAirbyteAlreadyTerminated = errors.New("Airbyte Runner has been already terminated. You can use it only once.")
 
+//ErrTimeout is returned when the command has been terminated after the timeout
+var ErrTimeout = errors.New("command has been terminated after the timeout")
+
 //ExecCmd executes command with args and uses stdOutWriter and stdErrWriter to pipe the result
 //runs separate goroutine for timeout control
 func ExecCmd(system, cmd string, stdOutWriter, stdErrWriter io.Writer, timeout time.Duration, args ...string) error {
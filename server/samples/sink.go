@@ -0,0 +1,111 @@
+package samples
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	defaultMaxPerDestination = 100
+	defaultTTL               = time.Hour
+)
+
+//Instance is a global debug samples sink. It is configured from server.debug_samples section
+var Instance = NewSink(defaultMaxPerDestination, defaultTTL)
+
+//Record is a sampled processed object which is going to be stored into the destination table
+type Record struct {
+	DestinationID string                 `json:"destination_id"`
+	TableName     string                 `json:"table_name"`
+	Object        map[string]interface{} `json:"object"`
+	Timestamp     time.Time              `json:"timestamp"`
+}
+
+//Sink is an in-memory storage of debug samples
+//keeps not more than maxPerDestination the newest records per destination and not older than ttl
+type Sink struct {
+	mutex             *sync.RWMutex
+	maxPerDestination int
+	ttl               time.Duration
+
+	recordsByDestinationID map[string][]*Record
+}
+
+//NewSink returns configured Sink instance. Default values are used if maxPerDestination or ttl aren't positive
+func NewSink(maxPerDestination int, ttl time.Duration) *Sink {
+	if maxPerDestination <= 0 {
+		maxPerDestination = defaultMaxPerDestination
+	}
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &Sink{
+		mutex:                  &sync.RWMutex{},
+		maxPerDestination:      maxPerDestination,
+		ttl:                    ttl,
+		recordsByDestinationID: map[string][]*Record{},
+	}
+}
+
+//Add puts a copy of the object into the sink. The oldest and expired destination records are removed
+func (s *Sink) Add(destinationID, tableName string, object map[string]interface{}) {
+	objectCopy := make(map[string]interface{}, len(object))
+	for k, v := range object {
+		objectCopy[k] = v
+	}
+
+	now := timestamp.Now().UTC()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records := append(s.actual(s.recordsByDestinationID[destinationID], now), &Record{
+		DestinationID: destinationID,
+		TableName:     tableName,
+		Object:        objectCopy,
+		Timestamp:     now,
+	})
+	if len(records) > s.maxPerDestination {
+		records = records[len(records)-s.maxPerDestination:]
+	}
+	s.recordsByDestinationID[destinationID] = records
+}
+
+//Get returns not expired destination records (the newest first)
+//all destinations records are returned if destinationID is empty
+func (s *Sink) Get(destinationID string) []*Record {
+	now := timestamp.Now().UTC()
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := []*Record{}
+	for id, records := range s.recordsByDestinationID {
+		if destinationID != "" && id != destinationID {
+			continue
+		}
+
+		actual := s.actual(records, now)
+		for i := len(actual) - 1; i >= 0; i-- {
+			result = append(result, actual[i])
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.After(result[j].Timestamp)
+	})
+	return result
+}
+
+//actual returns not expired records (records are sorted by timestamp)
+func (s *Sink) actual(records []*Record, now time.Time) []*Record {
+	for i, record := range records {
+		if now.Sub(record.Timestamp) < s.ttl {
+			return records[i:]
+		}
+	}
+
+	return nil
+}
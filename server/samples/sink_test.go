@@ -0,0 +1,28 @@
+package samples
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSink(t *testing.T) {
+	sink := NewSink(2, time.Minute)
+	sink.Add("dst1", "events", map[string]interface{}{"id": 1})
+	sink.Add("dst1", "events", map[string]interface{}{"id": 2})
+	sink.Add("dst1", "events", map[string]interface{}{"id": 3})
+	sink.Add("dst2", "users", map[string]interface{}{"id": 4})
+
+	records := sink.Get("dst1")
+	require.Equal(t, 2, len(records), "records must be capped")
+	require.Equal(t, 3, records[0].Object["id"])
+	require.Equal(t, 2, records[1].Object["id"])
+	require.Equal(t, 3, len(sink.Get("")))
+
+	//make the oldest record expired
+	sink.recordsByDestinationID["dst1"][0].Timestamp = time.Now().Add(-2 * time.Minute)
+	records = sink.Get("dst1")
+	require.Equal(t, 1, len(records), "expired records must be skipped")
+	require.Equal(t, 3, records[0].Object["id"])
+}
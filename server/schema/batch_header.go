@@ -94,10 +94,12 @@ func NewSQLTypeSuggestion(sqlType typing.SQLColumn, sqlTypePerDestination map[st
 }
 
 //Field is a data type holder with occurrences
+//masking is a field_masking method if the field values are masked (e.g. sha256)
 type Field struct {
 	dataType          *typing.DataType
 	sqlTypeSuggestion *SQLTypeSuggestion
 	typeOccurrence    map[typing.DataType]bool
+	masking           string
 }
 
 //NewField returns Field instance
@@ -134,6 +136,11 @@ func (f Field) GetSuggestedSQLType(destinationType string) (typing.SQLColumn, bo
 	return typing.SQLColumn{}, false
 }
 
+//Masking returns field_masking method (e.g. sha256, redact, truncate:N) or empty string if the field isn't masked
+func (f Field) Masking() string {
+	return f.masking
+}
+
 //GetType get field type based on occurrence in one file
 //lazily get common ancestor type (typing.GetCommonAncestorType)
 func (f Field) GetType() typing.DataType {
@@ -172,4 +179,8 @@ func (f *Field) Merge(anotherField *Field) {
 			f.dataType = nil
 		}
 	}
+
+	if anotherField.masking != "" {
+		f.masking = anotherField.masking
+	}
 }
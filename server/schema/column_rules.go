@@ -0,0 +1,193 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//AllTablesRulesKey is a column_rules key of rules which are applied to all tables
+const AllTablesRulesKey = "*"
+
+//ColumnCoercionError is returned if the column value can't be coerced to the configured type. Such events are written into fallback
+type ColumnCoercionError struct {
+	Table  string
+	Column string
+	Value  interface{}
+	Type   typing.DataType
+	Err    error
+}
+
+func (cce *ColumnCoercionError) Error() string {
+	return fmt.Sprintf("Column [%s] of table [%s]: value [%v] can't be coerced to %s: %v", cce.Column, cce.Table, cce.Value, cce.Type.String(), cce.Err)
+}
+
+type columnRule struct {
+	//dataType is UNKNOWN if the rule doesn't have type
+	dataType     typing.DataType
+	defaultValue interface{}
+}
+
+//ColumnRules applies column_rules to flat objects: sets default values of missing (or null) columns
+//and coerces values to the configured types (e.g. numeric string into integer)
+type ColumnRules struct {
+	//table name -> column name -> rule
+	rules map[string]map[string]*columnRule
+}
+
+//NewColumnRules returns configured ColumnRules instance or nil if rules aren't set
+//returns err if a type is unknown or a default value can't be coerced to the type
+func NewColumnRules(rulesConfig map[string]map[string]config.ColumnRule) (*ColumnRules, error) {
+	if len(rulesConfig) == 0 {
+		return nil, nil
+	}
+
+	rules := make(map[string]map[string]*columnRule, len(rulesConfig))
+	for tableName, columns := range rulesConfig {
+		tableRules := make(map[string]*columnRule, len(columns))
+		for columnName, ruleConfig := range columns {
+			rule := &columnRule{dataType: typing.UNKNOWN, defaultValue: ruleConfig.Default}
+			if ruleConfig.Type != "" {
+				dataType, err := typing.TypeFromString(ruleConfig.Type)
+				if err != nil {
+					return nil, fmt.Errorf("column_rules [%s] column [%s]: %v", tableName, columnName, err)
+				}
+				rule.dataType = dataType
+			}
+
+			if rule.defaultValue != nil && rule.dataType != typing.UNKNOWN {
+				coerced, err := coerceValue(rule.dataType, rule.defaultValue)
+				if err != nil {
+					return nil, fmt.Errorf("column_rules [%s] column [%s]: default value [%v] can't be coerced to %s: %v", tableName, columnName, rule.defaultValue, rule.dataType.String(), err)
+				}
+				rule.defaultValue = coerced
+			}
+
+			tableRules[columnName] = rule
+		}
+		rules[tableName] = tableRules
+	}
+
+	return &ColumnRules{rules: rules}, nil
+}
+
+//Apply sets default values and coerces values of the flat object columns according to the table rules
+//(table rules override '*' rules of the same column). Returns ColumnCoercionError if a value can't be coerced
+func (cr *ColumnRules) Apply(tableName string, object map[string]interface{}) error {
+	for columnName, rule := range cr.rules[tableName] {
+		if err := rule.apply(tableName, columnName, object); err != nil {
+			return err
+		}
+	}
+
+	for columnName, rule := range cr.rules[AllTablesRulesKey] {
+		if _, overridden := cr.rules[tableName][columnName]; overridden {
+			continue
+		}
+
+		if err := rule.apply(tableName, columnName, object); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *columnRule) apply(tableName, columnName string, object map[string]interface{}) error {
+	value, ok := object[columnName]
+	if !ok || value == nil {
+		if r.defaultValue != nil {
+			object[columnName] = r.defaultValue
+		}
+		return nil
+	}
+
+	if r.dataType == typing.UNKNOWN {
+		return nil
+	}
+
+	coerced, err := coerceValue(r.dataType, value)
+	if err != nil {
+		return &ColumnCoercionError{Table: tableName, Column: columnName, Value: value, Type: r.dataType, Err: err}
+	}
+
+	object[columnName] = coerced
+	return nil
+}
+
+//coerceValue returns the value converted into dataType. Besides typing.Convert rules
+//numeric and boolean strings, integral floats (into integer) and 0/1 numbers (into boolean) are converted
+func coerceValue(dataType typing.DataType, value interface{}) (interface{}, error) {
+	value = typing.ReformatValue(value)
+	currentType, err := typing.TypeFromValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentType == dataType {
+		return value, nil
+	}
+
+	switch dataType {
+	case typing.INT64:
+		switch v := value.(type) {
+		case string:
+			trimmed := strings.TrimSpace(v)
+			if intValue, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+				return intValue, nil
+			}
+			floatValue, err := strconv.ParseFloat(trimmed, 64)
+			if err != nil {
+				return nil, fmt.Errorf("not a number")
+			}
+			return floatToIntegral(floatValue)
+		case float32:
+			return floatToIntegral(float64(v))
+		case float64:
+			return floatToIntegral(v)
+		}
+	case typing.FLOAT64:
+		if v, ok := value.(string); ok {
+			floatValue, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("not a number")
+			}
+			return floatValue, nil
+		}
+	case typing.BOOL:
+		switch v := value.(type) {
+		case string:
+			boolValue, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("not a boolean")
+			}
+			return boolValue, nil
+		case int, int8, int16, int32, int64, float32, float64:
+			switch fmt.Sprint(v) {
+			case "0":
+				return false, nil
+			case "1":
+				return true, nil
+			}
+			return nil, fmt.Errorf("only 0 and 1 numbers can be coerced to boolean")
+		}
+	case typing.TIMESTAMP:
+		if v, ok := value.(string); ok {
+			return typing.ParseTimestamp(strings.TrimSpace(v))
+		}
+	}
+
+	return typing.Convert(dataType, value)
+}
+
+func floatToIntegral(value float64) (interface{}, error) {
+	if value != math.Trunc(value) || value > math.MaxInt64 || value < math.MinInt64 {
+		return nil, fmt.Errorf("not an integer")
+	}
+
+	return int64(value), nil
+}
@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnRules(t *testing.T) {
+	rulesConfig := map[string]map[string]config.ColumnRule{
+		"*": {
+			"amount":  {Type: "integer"},
+			"country": {Default: "unknown"},
+		},
+		"orders": {
+			"amount":     {Type: "double", Default: 0},
+			"paid":       {Type: "boolean", Default: false},
+			"created_at": {Type: "timestamp"},
+			"order_id":   {Type: "string"},
+		},
+	}
+	tests := []struct {
+		name        string
+		table       string
+		input       map[string]interface{}
+		expected    map[string]interface{}
+		expectedErr string
+	}{
+		{
+			"all tables rules",
+			"events",
+			map[string]interface{}{"amount": "42", "country": nil},
+			map[string]interface{}{"amount": int64(42), "country": "unknown"},
+			"",
+		},
+		{
+			"integral float and json number into integer",
+			"events",
+			map[string]interface{}{"amount": json.Number("10")},
+			map[string]interface{}{"amount": int64(10), "country": "unknown"},
+			"",
+		},
+		{
+			"table rules override all tables rules",
+			"orders",
+			map[string]interface{}{"amount": " 12.5 ", "paid": "true", "created_at": "2021-06-15T12:30:00.000000Z", "order_id": int64(1001), "country": "US"},
+			map[string]interface{}{"amount": 12.5, "paid": true, "created_at": time.Date(2021, 6, 15, 12, 30, 0, 0, time.UTC), "order_id": "1001", "country": "US"},
+			"",
+		},
+		{
+			"defaults of missing columns",
+			"orders",
+			map[string]interface{}{"order_id": "1002"},
+			map[string]interface{}{"amount": float64(0), "paid": false, "order_id": "1002", "country": "unknown"},
+			"",
+		},
+		{
+			"0 and 1 numbers into boolean",
+			"orders",
+			map[string]interface{}{"paid": 1, "amount": 1, "country": "US"},
+			map[string]interface{}{"paid": true, "amount": float64(1), "country": "US"},
+			"",
+		},
+		{
+			"not integral float",
+			"events",
+			map[string]interface{}{"amount": 1.5},
+			nil,
+			"Column [amount] of table [events]: value [1.5] can't be coerced to INT64: not an integer",
+		},
+		{
+			"not a number",
+			"orders",
+			map[string]interface{}{"amount": "abc"},
+			nil,
+			"Column [amount] of table [orders]: value [abc] can't be coerced to FLOAT64: not a number",
+		},
+	}
+	columnRules, err := NewColumnRules(rulesConfig)
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := columnRules.Apply(tt.table, tt.input)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				require.IsType(t, &ColumnCoercionError{}, err)
+				require.Equal(t, tt.expectedErr, err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, tt.input)
+		})
+	}
+}
+
+func TestColumnRulesConfigErrors(t *testing.T) {
+	_, err := NewColumnRules(map[string]map[string]config.ColumnRule{"events": {"amount": {Type: "decimal"}}})
+	require.EqualError(t, err, "column_rules [events] column [amount]: Unknown casting type: decimal")
+
+	_, err = NewColumnRules(map[string]map[string]config.ColumnRule{"events": {"amount": {Type: "integer", Default: "none"}}})
+	require.EqualError(t, err, "column_rules [events] column [amount]: default value [none] can't be coerced to INT64: not a number")
+
+	columnRules, err := NewColumnRules(nil)
+	require.NoError(t, err)
+	require.Nil(t, columnRules)
+}
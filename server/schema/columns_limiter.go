@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//on_max_columns strategies
+const (
+	//OnMaxColumnsError sends the event to fallback
+	OnMaxColumnsError = "error"
+	//OnMaxColumnsDrop removes excess fields from the event
+	OnMaxColumnsDrop = "drop"
+	//OnMaxColumnsVariant moves excess fields into OverflowColumn JSON column
+	OnMaxColumnsVariant = "variant"
+
+	//OverflowColumn is a column for excess fields (JSON object string) with variant strategy
+	OverflowColumn = "_overflow"
+)
+
+//ColumnsLimiter applies on_max_columns strategy to the events which have columns that don't fit into max_columns table limit
+//it keeps known columns per table: the existing table columns (from tableColumnsFunc) and columns of all accepted events
+type ColumnsLimiter struct {
+	maxColumns int
+	strategy   string
+	//returns existing table columns or nil if they are unknown
+	tableColumnsFunc func(tableName string) map[string]bool
+
+	mutex        *sync.Mutex
+	tableColumns map[string]map[string]bool
+}
+
+//NewColumnsLimiter returns configured ColumnsLimiter instance or nil if strategy or maxColumns isn't set
+//returns err if strategy is unknown
+func NewColumnsLimiter(maxColumns int, strategy string) (*ColumnsLimiter, error) {
+	switch strategy {
+	case "":
+		return nil, nil
+	case OnMaxColumnsError, OnMaxColumnsDrop, OnMaxColumnsVariant:
+	default:
+		return nil, fmt.Errorf("Unknown on_max_columns value: %s. Available values: [%s, %s, %s]", strategy, OnMaxColumnsError, OnMaxColumnsDrop, OnMaxColumnsVariant)
+	}
+	if maxColumns <= 0 {
+		return nil, nil
+	}
+
+	return &ColumnsLimiter{
+		maxColumns:   maxColumns,
+		strategy:     strategy,
+		mutex:        &sync.Mutex{},
+		tableColumns: map[string]map[string]bool{},
+	}, nil
+}
+
+//SetTableColumnsFunc sets func which returns existing table columns (e.g. from the destination)
+func (cl *ColumnsLimiter) SetTableColumnsFunc(tableColumnsFunc func(tableName string) map[string]bool) {
+	cl.tableColumnsFunc = tableColumnsFunc
+}
+
+//Limit checks if header fields fit into max_columns limit of the table. If they don't:
+//returns error with excess columns list (error strategy)
+//or removes excess fields from header and object (drop strategy)
+//or moves excess fields into OverflowColumn (variant strategy)
+//returns dropped (or moved) columns
+func (cl *ColumnsLimiter) Limit(header *BatchHeader, object map[string]interface{}) ([]string, error) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	knownColumns, ok := cl.tableColumns[header.TableName]
+	if !ok {
+		knownColumns = map[string]bool{}
+		if cl.tableColumnsFunc != nil {
+			for column := range cl.tableColumnsFunc(header.TableName) {
+				knownColumns[strings.ToLower(column)] = true
+			}
+		}
+		cl.tableColumns[header.TableName] = knownColumns
+	}
+
+	var newColumns []string
+	for name := range header.Fields {
+		if !knownColumns[strings.ToLower(name)] {
+			newColumns = append(newColumns, name)
+		}
+	}
+	capacity := cl.maxColumns - len(knownColumns)
+	if len(newColumns) <= capacity {
+		cl.accept(knownColumns, newColumns)
+		return nil, nil
+	}
+
+	//overflow column requires the place as well
+	if cl.strategy == OnMaxColumnsVariant && !knownColumns[OverflowColumn] {
+		capacity--
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+
+	sort.Strings(newColumns)
+	accepted, excess := append([]string{}, newColumns[:capacity]...), newColumns[capacity:]
+
+	switch cl.strategy {
+	case OnMaxColumnsError:
+		return excess, fmt.Errorf("Event has %d new columns which exceed max_columns limit (%d) of table %s: [%s]", len(excess), cl.maxColumns, header.TableName, strings.Join(excess, ", "))
+	case OnMaxColumnsDrop:
+		for _, name := range excess {
+			delete(header.Fields, name)
+			delete(object, name)
+		}
+	case OnMaxColumnsVariant:
+		overflow := map[string]interface{}{}
+		for _, name := range excess {
+			if value, ok := object[name]; ok {
+				overflow[name] = value
+			}
+			delete(header.Fields, name)
+			delete(object, name)
+		}
+		b, err := json.Marshal(overflow)
+		if err != nil {
+			return nil, fmt.Errorf("Error marshalling %s column: %v", OverflowColumn, err)
+		}
+		object[OverflowColumn] = string(b)
+		header.Fields[OverflowColumn] = NewField(typing.STRING)
+		accepted = append(accepted, OverflowColumn)
+	}
+
+	cl.accept(knownColumns, accepted)
+	return excess, nil
+}
+
+func (cl *ColumnsLimiter) accept(knownColumns map[string]bool, columns []string) {
+	for _, name := range columns {
+		knownColumns[strings.ToLower(name)] = true
+	}
+}
@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnsLimiter(t *testing.T) {
+	tests := []struct {
+		name           string
+		strategy       string
+		tableColumns   map[string]bool
+		input          map[string]interface{}
+		expected       map[string]interface{}
+		expectedExcess []string
+		expectedErr    string
+	}{
+		{
+			"fits into limit",
+			OnMaxColumnsError,
+			map[string]bool{"id": true},
+			map[string]interface{}{"id": 1, "a": "a", "b": "b"},
+			map[string]interface{}{"id": 1, "a": "a", "b": "b"},
+			nil,
+			"",
+		},
+		{
+			"error",
+			OnMaxColumnsError,
+			map[string]bool{"id": true, "a": true},
+			map[string]interface{}{"id": 1, "a": "a", "b": "b", "c": "c", "d": "d"},
+			map[string]interface{}{"id": 1, "a": "a", "b": "b", "c": "c", "d": "d"},
+			[]string{"c", "d"},
+			"Event has 2 new columns which exceed max_columns limit (3) of table events: [c, d]",
+		},
+		{
+			"drop",
+			OnMaxColumnsDrop,
+			map[string]bool{"ID": true, "a": true},
+			map[string]interface{}{"id": 1, "a": "a", "b": "b", "c": "c", "d": "d"},
+			map[string]interface{}{"id": 1, "a": "a", "b": "b"},
+			[]string{"c", "d"},
+			"",
+		},
+		{
+			"variant",
+			OnMaxColumnsVariant,
+			map[string]bool{"id": true},
+			map[string]interface{}{"id": 1, "a": "a", "b": "b", "c": 3},
+			map[string]interface{}{"id": 1, "a": "a", OverflowColumn: `{"b":"b","c":3}`},
+			[]string{"b", "c"},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := NewColumnsLimiter(3, tt.strategy)
+			require.NoError(t, err)
+			limiter.SetTableColumnsFunc(func(tableName string) map[string]bool { return tt.tableColumns })
+
+			header := &BatchHeader{TableName: "events", Fields: Fields{}}
+			for name := range tt.input {
+				header.Fields[name] = NewField(typing.STRING)
+			}
+
+			excess, err := limiter.Limit(header, tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedExcess, excess)
+			require.Equal(t, tt.expected, tt.input)
+			require.Equal(t, len(tt.expected), len(header.Fields))
+		})
+	}
+}
+
+func TestColumnsLimiterDisabled(t *testing.T) {
+	limiter, err := NewColumnsLimiter(0, OnMaxColumnsError)
+	require.NoError(t, err)
+	require.Nil(t, limiter)
+
+	_, err = NewColumnsLimiter(10, "unknown")
+	require.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jitsucom/jitsu/server/templates"
+)
+
+//EventFilter evaluates destination filter expression (go template or javascript) against every event
+//events are dropped if the expression result is empty string, 'null' or 'false'
+type EventFilter struct {
+	tmpl templates.TemplateExecutor
+}
+
+//NewEventFilter returns configured EventFilter or err if the expression can't be parsed
+func NewEventFilter(filterExpression string, funcMap template.FuncMap) (*EventFilter, error) {
+	tmpl, err := templates.SmartParse("destination filter", filterExpression, funcMap)
+	if err != nil {
+		return nil, fmt.Errorf("filter expression parsing error: %v", err)
+	}
+
+	return &EventFilter{tmpl: tmpl}, nil
+}
+
+//ValidateFilter returns err if the destination filter expression can't be parsed
+func ValidateFilter(destinationID, destinationType, filterExpression string) error {
+	if filterExpression == "" {
+		return nil
+	}
+
+	eventFilter, err := NewEventFilter(filterExpression, templates.EnrichedFuncMap(map[string]interface{}{
+		"destinationId":   destinationID,
+		"destinationType": destinationType,
+	}))
+	if err != nil {
+		return err
+	}
+
+	eventFilter.Close()
+	return nil
+}
+
+//Match returns true if the event matches the filter expression
+func (ef *EventFilter) Match(object map[string]interface{}) (match bool, err error) {
+	//panic handler
+	defer func() {
+		if r := recover(); r != nil {
+			match = false
+			err = fmt.Errorf("error evaluating filter: %v", r)
+		}
+	}()
+
+	resultObject, err := ef.tmpl.ProcessEvent(object)
+	if err != nil {
+		return false, fmt.Errorf("error executing filter: %v", err)
+	}
+
+	result := strings.TrimSpace(templates.ToString(resultObject, false, false, false))
+	switch result {
+	case "", "null", "false", "<no value>":
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+func (ef *EventFilter) Close() {
+	ef.tmpl.Close()
+}
@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/templates"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		input      map[string]interface{}
+		expected   bool
+	}{
+		{
+			"matched go template",
+			`{{if eq .event_type "purchase"}}true{{end}}`,
+			map[string]interface{}{"event_type": "purchase"},
+			true,
+		},
+		{
+			"not matched go template",
+			`{{if eq .event_type "purchase"}}true{{end}}`,
+			map[string]interface{}{"event_type": "pageview"},
+			false,
+		},
+		{
+			"false result",
+			`false`,
+			map[string]interface{}{"event_type": "pageview"},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventFilter, err := NewEventFilter(tt.expression, templates.EnrichedFuncMap(nil))
+			require.NoError(t, err)
+			defer eventFilter.Close()
+
+			match, err := eventFilter.Match(tt.input)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, match, "Filter result isn't equal")
+		})
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	require.NoError(t, ValidateFilter("dest", "postgres", ""))
+	require.Error(t, ValidateFilter("dest", "postgres", "{{if .event_type}}"))
+}
@@ -0,0 +1,152 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+const (
+	//MaskSHA256 replaces value with hex encoded SHA-256 hash
+	MaskSHA256 = "sha256"
+	//MaskRedact replaces string value with RedactedValue and removes not string values
+	MaskRedact = "redact"
+	//MaskTruncatePrefix keeps first N characters of string value (truncate:N)
+	MaskTruncatePrefix = "truncate:"
+
+	//RedactedValue is a replacement of redacted string values
+	RedactedValue = "[REDACTED]"
+)
+
+type maskingRule struct {
+	path        jsonutils.JSONPath
+	flatName    string
+	method      string
+	truncateLen int
+}
+
+//FieldMasker masks configured fields (hashes, redacts or truncates values) before objects are flattened and stored
+type FieldMasker struct {
+	rules []*maskingRule
+}
+
+//NewFieldMasker returns configured FieldMasker or err if masking method is unknown
+//fieldMasking is a map: JSON path (e.g. /user/email) -> masking method (sha256, redact or truncate:N)
+func NewFieldMasker(fieldMasking map[string]string) (*FieldMasker, error) {
+	paths := make([]string, 0, len(fieldMasking))
+	for path := range fieldMasking {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	rules := make([]*maskingRule, 0, len(paths))
+	for _, path := range paths {
+		jsonPath := jsonutils.NewJSONPath(path)
+		if jsonPath.IsEmpty() {
+			return nil, fmt.Errorf("field_masking path can't be empty")
+		}
+
+		method := strings.TrimSpace(fieldMasking[path])
+		rule := &maskingRule{path: jsonPath, flatName: Reformat(jsonPath.FieldName()), method: method}
+		switch {
+		case method == MaskSHA256, method == MaskRedact:
+		case strings.HasPrefix(method, MaskTruncatePrefix):
+			truncateLen, err := strconv.Atoi(strings.TrimPrefix(method, MaskTruncatePrefix))
+			if err != nil || truncateLen < 0 {
+				return nil, fmt.Errorf("field_masking [%s]: truncate length must be a positive integer: %s", path, method)
+			}
+			rule.truncateLen = truncateLen
+		default:
+			return nil, fmt.Errorf("field_masking [%s]: unknown masking method: %s. Available: %s, %s, %sN", path, method, MaskSHA256, MaskRedact, MaskTruncatePrefix)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return &FieldMasker{rules: rules}, nil
+}
+
+//Mask masks configured fields in the object (in place)
+func (fm *FieldMasker) Mask(object map[string]interface{}) error {
+	for _, rule := range fm.rules {
+		value, ok := rule.path.Get(object)
+		if !ok || value == nil {
+			continue
+		}
+
+		masked, err := rule.mask(value)
+		if err != nil {
+			return fmt.Errorf("Error masking field [%s]: %v", rule.path.String(), err)
+		}
+
+		if masked == nil {
+			rule.path.GetAndRemove(object)
+			continue
+		}
+
+		if err := rule.path.Set(object, masked); err != nil {
+			return fmt.Errorf("Error masking field [%s]: %v", rule.path.String(), err)
+		}
+	}
+
+	return nil
+}
+
+//MaskedFields returns flat field names with masking methods
+func (fm *FieldMasker) MaskedFields() map[string]string {
+	fields := map[string]string{}
+	for _, rule := range fm.rules {
+		fields[rule.flatName] = rule.method
+	}
+	return fields
+}
+
+//markFields puts masking methods into the schema fields of masked values (see Field.Masking)
+func (fm *FieldMasker) markFields(fields Fields) {
+	for _, rule := range fm.rules {
+		field, ok := fields[rule.flatName]
+		if !ok {
+			continue
+		}
+		field.masking = rule.method
+		fields[rule.flatName] = field
+	}
+}
+
+//mask returns masked value. Strings are kept strings
+func (mr *maskingRule) mask(value interface{}) (interface{}, error) {
+	switch mr.method {
+	case MaskSHA256:
+		str, ok := value.(string)
+		if !ok {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			str = string(b)
+		}
+		hash := sha256.Sum256([]byte(str))
+		return hex.EncodeToString(hash[:]), nil
+	case MaskRedact:
+		if _, ok := value.(string); ok {
+			return RedactedValue, nil
+		}
+		return nil, nil
+	default:
+		str, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		runes := []rune(str)
+		if len(runes) > mr.truncateLen {
+			return string(runes[:mr.truncateLen]), nil
+		}
+		return str, nil
+	}
+}
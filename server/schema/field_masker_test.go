@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldMasker(t *testing.T) {
+	tests := []struct {
+		name         string
+		fieldMasking map[string]string
+		input        map[string]interface{}
+		expected     map[string]interface{}
+		expectedErr  string
+	}{
+		{
+			"sha256 string",
+			map[string]string{"/user/email": "sha256"},
+			map[string]interface{}{"user": map[string]interface{}{"email": "john@example.com", "id": "1"}},
+			map[string]interface{}{"user": map[string]interface{}{"email": "855f96e983f1f8e8be944692b6f719fd54329826cb62e98015efee8e2e071dd4", "id": "1"}},
+			"",
+		},
+		{
+			"redact",
+			map[string]string{"/phone": "redact", "/age": "redact"},
+			map[string]interface{}{"phone": "+123456789", "age": 33, "event_type": "signup"},
+			map[string]interface{}{"phone": RedactedValue, "event_type": "signup"},
+			"",
+		},
+		{
+			"truncate",
+			map[string]string{"/ip": "truncate:3", "/count": "truncate:1"},
+			map[string]interface{}{"ip": "127.0.0.1", "count": 100},
+			map[string]interface{}{"ip": "127", "count": 100},
+			"",
+		},
+		{
+			"missing field",
+			map[string]string{"/user/email": "sha256"},
+			map[string]interface{}{"event_type": "signup"},
+			map[string]interface{}{"event_type": "signup"},
+			"",
+		},
+		{
+			"unknown method",
+			map[string]string{"/user/email": "md5"},
+			nil,
+			nil,
+			"field_masking [/user/email]: unknown masking method: md5. Available: sha256, redact, truncate:N",
+		},
+		{
+			"wrong truncate length",
+			map[string]string{"/ip": "truncate:abc"},
+			nil,
+			nil,
+			"field_masking [/ip]: truncate length must be a positive integer: truncate:abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldMasker, err := NewFieldMasker(tt.fieldMasking)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			require.NoError(t, fieldMasker.Mask(tt.input))
+			require.Equal(t, tt.expected, tt.input, "Masked objects aren't equal")
+		})
+	}
+}
+
+func TestProcessorFieldMaskingStageBytes(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	destination := &config.DestinationConfig{Type: "snowflake", DataLayout: &config.DataLayout{},
+		FieldMasking: map[string]string{"/user/email": "sha256", "/user/phone": "redact", "/ip": "truncate:3"}}
+	p, err := NewProcessor("test", destination, true, `events`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), identifiers.NewUniqueID("/eventn_ctx/event_id"), 0)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+	defer p.CloseJavaScriptTemplates()
+
+	require.Equal(t, map[string]string{"user_email": "sha256", "user_phone": "redact", "ip": "truncate:3"}, p.MaskedFields())
+
+	objects := []map[string]interface{}{
+		{"eventn_ctx": map[string]interface{}{"event_id": "1"}, "ip": "192.168.1.1", "user": map[string]interface{}{"email": "john@example.com", "phone": "+123456789"}},
+		{"eventn_ctx": map[string]interface{}{"event_id": "2"}, "ip": "10.0.0.15", "user": map[string]interface{}{"email": "jane@example.com"}},
+	}
+	processedFiles, failed, skipped, err := p.ProcessEvents("testfile", objects, map[string]bool{})
+	require.NoError(t, err)
+	require.Empty(t, failed.Events)
+	require.Empty(t, skipped.Events)
+	require.Len(t, processedFiles, 1)
+
+	for _, fdata := range processedFiles {
+		for _, marshaller := range []Marshaller{VerticalBarSeparatedMarshallerInstance, JSONMarshallerInstance} {
+			stageBytes, _ := fdata.GetPayloadBytesWithHeader(marshaller)
+			for _, raw := range []string{"john@example.com", "jane@example.com", "+123456789", "192.168.1.1", "10.0.0.15"} {
+				require.False(t, bytes.Contains(stageBytes, []byte(raw)), "raw value %s is in stage bytes", raw)
+			}
+			require.True(t, bytes.Contains(stageBytes, []byte(RedactedValue)))
+		}
+		require.Equal(t, typing.STRING, fdata.BatchHeader.Fields["user_email"].GetType())
+
+		//masking methods are in the table schema
+		maskedFields := map[string]string{}
+		for name, field := range fdata.BatchHeader.Fields {
+			if field.Masking() != "" {
+				maskedFields[name] = field.Masking()
+			}
+		}
+		require.Equal(t, map[string]string{"user_email": "sha256", "user_phone": "redact", "ip": "truncate:3"}, maskedFields)
+	}
+
+	//source objects aren't changed
+	require.Equal(t, "john@example.com", objects[0]["user"].(map[string]interface{})["email"])
+}
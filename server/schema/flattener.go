@@ -13,6 +13,9 @@ type Flattener interface {
 
 type FlattenerImpl struct {
 	omitNilValues bool
+	//maxDepth is a max nesting level of flattened objects (0 - unlimited)
+	//deeper objects are serialized into JSON string columns
+	maxDepth int
 }
 
 func NewFlattener() Flattener {
@@ -21,13 +24,22 @@ func NewFlattener() Flattener {
 	}
 }
 
+//NewDepthLimitedFlattener returns Flattener which flattens objects only up to maxDepth nesting level
+//e.g. with maxDepth = 1 {"key1":{"key2":{"key3":1}}} is flattened to {"key1":"{\"key2\":{\"key3\":1}}"}
+func NewDepthLimitedFlattener(maxDepth int) Flattener {
+	return &FlattenerImpl{
+		omitNilValues: true,
+		maxDepth:      maxDepth,
+	}
+}
+
 //FlattenObject flatten object e.g. from {"key1":{"key2":123}} to {"key1_key2":123}
 //from {"$key1":1} to {"_key1":1}
 //from {"(key1)":1} to {"_key1_":1}
 func (f *FlattenerImpl) FlattenObject(json map[string]interface{}) (map[string]interface{}, error) {
 	flattenMap := make(map[string]interface{})
 
-	err := f.flatten("", json, flattenMap)
+	err := f.flatten("", json, flattenMap, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -36,8 +48,8 @@ func (f *FlattenerImpl) FlattenObject(json map[string]interface{}) (map[string]i
 }
 
 //recursive function for flatten key (if value is inner object -> recursion call)
-//Reformat key
-func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[string]interface{}) error {
+//Reformat key. depth is a nesting level of the value (0 - the root object)
+func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[string]interface{}, depth int) error {
 	key = Reformat(key)
 	t := reflect.ValueOf(value)
 	switch t.Kind() {
@@ -54,12 +66,20 @@ func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[s
 		destination[key] = string(b)
 	case reflect.Map:
 		unboxed := value.(map[string]interface{})
+		if f.maxDepth > 0 && depth >= f.maxDepth && len(unboxed) > 0 {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("Error marshaling object with key %s: %v", key, err)
+			}
+			destination[key] = string(b)
+			return nil
+		}
 		for k, v := range unboxed {
 			newKey := k
 			if key != "" {
 				newKey = key + "_" + newKey
 			}
-			if err := f.flatten(newKey, v, destination); err != nil {
+			if err := f.flatten(newKey, v, destination, depth+1); err != nil {
 				return err
 			}
 		}
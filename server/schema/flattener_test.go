@@ -1,8 +1,11 @@
 package schema
 
 import (
+	"encoding/json"
 	"github.com/jitsucom/jitsu/server/test"
 	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -68,3 +71,110 @@ func TestFlattenObject(t *testing.T) {
 		})
 	}
 }
+
+func TestFlattenObjectWithMaxDepth(t *testing.T) {
+	//l1 -> l2 -> ... -> l10 -> value
+	deep := map[string]interface{}{"value": 1}
+	for i := 10; i >= 1; i-- {
+		deep = map[string]interface{}{"l" + strconv.Itoa(i): deep}
+	}
+	deep["id"] = "event1"
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		expected map[string]interface{}
+	}{
+		{
+			"unlimited",
+			0,
+			map[string]interface{}{"id": "event1", "l1_l2_l3_l4_l5_l6_l7_l8_l9_l10_value": 1},
+		},
+		{
+			"depth 1",
+			1,
+			map[string]interface{}{"id": "event1", "l1": `{"l2":{"l3":{"l4":{"l5":{"l6":{"l7":{"l8":{"l9":{"l10":{"value":1}}}}}}}}}}`},
+		},
+		{
+			"depth 3",
+			3,
+			map[string]interface{}{"id": "event1", "l1_l2_l3": `{"l4":{"l5":{"l6":{"l7":{"l8":{"l9":{"l10":{"value":1}}}}}}}}`},
+		},
+		{
+			"depth 10",
+			10,
+			map[string]interface{}{"id": "event1", "l1_l2_l3_l4_l5_l6_l7_l8_l9_l10": `{"value":1}`},
+		},
+		{
+			"depth 11",
+			11,
+			map[string]interface{}{"id": "event1", "l1_l2_l3_l4_l5_l6_l7_l8_l9_l10_value": 1},
+		},
+		{
+			"depth greater than the object depth",
+			50,
+			map[string]interface{}{"id": "event1", "l1_l2_l3_l4_l5_l6_l7_l8_l9_l10_value": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := NewDepthLimitedFlattener(tt.maxDepth).FlattenObject(deep)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual, "Flattened objects aren't equal")
+		})
+	}
+}
+
+//nestedObject returns {"l<first>":{"l<first+1>":...{"l<last>":{"value":1,"list":[1,2]}}}} and flattened keys of its levels
+func nestedObject(first, last int) (map[string]interface{}, []string) {
+	object := map[string]interface{}{"value": 1, "list": []interface{}{1, 2}}
+	keys := make([]string, last-first+1)
+	for i := last; i >= first; i-- {
+		keys[i-first] = "l" + strconv.Itoa(i)
+		object = map[string]interface{}{keys[i-first]: object}
+	}
+	return object, keys
+}
+
+func TestFlattenObjectMaxDepthCutOver(t *testing.T) {
+	const maxDepth = 10
+	tests := []struct {
+		name   string
+		levels int
+	}{
+		{"object is shallower than max depth", maxDepth - 1},
+		{"object leaves are at max depth", maxDepth},
+		{"object is 1 level deeper than max depth", maxDepth + 1},
+		{"object is 2 levels deeper than max depth", maxDepth + 2},
+		{"object is much deeper than max depth", 3 * maxDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, keys := nestedObject(1, tt.levels)
+			object["id"] = "event1"
+
+			actual, err := NewDepthLimitedFlattener(maxDepth).FlattenObject(object)
+			require.NoError(t, err)
+			require.Equal(t, "event1", actual["id"])
+
+			if tt.levels < maxDepth {
+				//fully flattened
+				prefix := strings.Join(keys, "_")
+				require.Equal(t, map[string]interface{}{"id": "event1", prefix + "_value": 1, prefix + "_list": "[1,2]"}, actual)
+				return
+			}
+
+			//objects below max_flatten_depth are cut over into a JSON string column named by the first maxDepth levels
+			cutOverKey := strings.Join(keys[:maxDepth], "_")
+			require.Len(t, actual, 2, "only id and the cut-over column are expected: %v", actual)
+			cutOverValue, ok := actual[cutOverKey].(string)
+			require.True(t, ok, "%s column must be a JSON string: %v", cutOverKey, actual)
+
+			//the JSON string keeps the rest of the nested object as is
+			expectedRest, _ := nestedObject(maxDepth+1, tt.levels)
+			expectedJSON, err := json.Marshal(expectedRest)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expectedJSON), cutOverValue)
+		})
+	}
+}
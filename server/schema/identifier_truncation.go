@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	//IdentifierTruncationCut shortens over-length column names by abbreviating name parts (default)
+	//different names might be shortened to the same column name
+	IdentifierTruncationCut = "cut"
+	//IdentifierTruncationHash truncates over-length column and table names and appends a short hash of the original name
+	IdentifierTruncationHash = "hash"
+
+	identifierHashLength = 8
+)
+
+//IdentifierTruncator truncates over-length identifiers (see TruncateIdentifier) and logs every original -> truncated mapping once
+type IdentifierTruncator struct {
+	identifier string
+	logged     sync.Map
+}
+
+//NewIdentifierTruncator returns IdentifierTruncator or error if the strategy isn't supported
+//returns nil if the strategy is empty or IdentifierTruncationCut
+func NewIdentifierTruncator(identifier, strategy string) (*IdentifierTruncator, error) {
+	switch strategy {
+	case "", IdentifierTruncationCut:
+		return nil, nil
+	case IdentifierTruncationHash:
+		return &IdentifierTruncator{identifier: identifier}, nil
+	default:
+		return nil, fmt.Errorf("Unknown identifier_truncation: %s. Supported values: [%s, %s]", strategy, IdentifierTruncationCut, IdentifierTruncationHash)
+	}
+}
+
+//Truncate returns TruncateIdentifier result. Logs the mapping when the name is truncated for the first time
+func (it *IdentifierTruncator) Truncate(name string, maxLength int) string {
+	truncated := TruncateIdentifier(name, maxLength)
+	if truncated != name {
+		if _, logged := it.logged.LoadOrStore(name, true); !logged {
+			logging.Infof("[%s] identifier %s exceeds %d characters and is truncated to %s", it.identifier, name, maxLength, truncated)
+		}
+	}
+
+	return truncated
+}
+
+//TruncateIdentifier returns name as is if it doesn't exceed maxLength (or maxLength <= 0)
+//otherwise returns the name beginning with '_' and the hash of the full name e.g. very_long_na_1a2b3c4d
+//The result depends only on the name so it is the same across restarts and different names stay distinct
+func TruncateIdentifier(name string, maxLength int) string {
+	if maxLength <= 0 || len(name) <= maxLength {
+		return name
+	}
+
+	sum := sha1.Sum([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:identifierHashLength]
+
+	cut := maxLength - identifierHashLength - 1
+	if cut <= 0 {
+		return hash
+	}
+	//don't split multi-byte characters
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+
+	return name[:cut] + "_" + hash
+}
@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateIdentifier(t *testing.T) {
+	//near the limit
+	require.Equal(t, strings.Repeat("a", 20), TruncateIdentifier(strings.Repeat("a", 20), 20))
+	truncated := TruncateIdentifier(strings.Repeat("a", 21), 20)
+	require.Len(t, truncated, 20)
+	require.True(t, strings.HasPrefix(truncated, strings.Repeat("a", 11)+"_"), truncated)
+
+	//unlimited
+	require.Equal(t, strings.Repeat("a", 300), TruncateIdentifier(strings.Repeat("a", 300), 0))
+
+	//names with the same beginning stay distinct
+	a := TruncateIdentifier("properties_very_long_nested_object_name_a", 30)
+	b := TruncateIdentifier("properties_very_long_nested_object_name_b", 30)
+	require.Len(t, a, 30)
+	require.Len(t, b, 30)
+	require.NotEqual(t, a, b)
+	require.Equal(t, a[:21], b[:21])
+
+	//stable
+	require.Equal(t, a, TruncateIdentifier("properties_very_long_nested_object_name_a", 30))
+
+	//multi-byte characters aren't split
+	multiByte := TruncateIdentifier("ключ_"+strings.Repeat("я", 20), 20)
+	require.LessOrEqual(t, len(multiByte), 20)
+	require.True(t, strings.HasPrefix(multiByte, "ключ_"), multiByte)
+}
+
+func TestNewIdentifierTruncator(t *testing.T) {
+	truncator, err := NewIdentifierTruncator("test", "")
+	require.NoError(t, err)
+	require.Nil(t, truncator)
+
+	truncator, err = NewIdentifierTruncator("test", IdentifierTruncationCut)
+	require.NoError(t, err)
+	require.Nil(t, truncator)
+
+	truncator, err = NewIdentifierTruncator("test", IdentifierTruncationHash)
+	require.NoError(t, err)
+	require.NotNil(t, truncator)
+
+	_, err = NewIdentifierTruncator("test", "md5")
+	require.Error(t, err)
+}
@@ -3,14 +3,19 @@ package schema
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 )
 
-const quotaByteValue = 34
+const (
+	quotaByteValue = 34
+	quote          = `"`
+)
 
 var (
-	JSONMarshallerInstance                 = JSONMarshaller{}
-	CSVMarshallerInstance                  = CSVMarshaller{delimiter: ","}
-	VerticalBarSeparatedMarshallerInstance = CSVMarshaller{delimiter: "||"}
+	JSONMarshallerInstance = JSONMarshaller{}
+	CSVMarshallerInstance  = CSVMarshaller{delimiter: ","}
+	//VerticalBarSeparatedMarshallerInstance is used for Snowflake stage files: values are quoted (see NewQuotingCSVMarshaller)
+	VerticalBarSeparatedMarshallerInstance = NewQuotingCSVMarshaller("||")
 )
 
 type Marshaller interface {
@@ -32,6 +37,15 @@ func (jm JSONMarshaller) NeedHeader() bool {
 
 type CSVMarshaller struct {
 	delimiter string
+	//quoting enables RFC 4180 style values quoting
+	quoting bool
+}
+
+//NewQuotingCSVMarshaller returns CSVMarshaller which writes string values as is and encloses values
+//which contain any delimiter char, double quote or line break in double quotes (double quotes inside are doubled).
+//The file must be loaded with '"' optional enclosing char and without backslash escaping
+func NewQuotingCSVMarshaller(delimiter string) CSVMarshaller {
+	return CSVMarshaller{delimiter: delimiter, quoting: true}
 }
 
 //Marshal marshals input object as csv values string with delimiter
@@ -42,14 +56,10 @@ func (cm CSVMarshaller) Marshal(fields []string, object map[string]interface{})
 	for _, field := range fields {
 		v, ok := object[field]
 		if ok {
-			//helper function for value serialization
-			b, _ := json.Marshal(v)
-			//don't write begin and end quotas
-			lastIndex := len(b) - 1
-			if len(b) >= 2 && b[0] == quotaByteValue && b[lastIndex] == quotaByteValue {
-				buf.Write(b[1:lastIndex])
+			if cm.quoting {
+				buf.WriteString(cm.quote(formatCSVValue(v)))
 			} else {
-				buf.Write(b)
+				buf.Write(formatJSONValue(v))
 			}
 		}
 		//don't write delimiter after last element
@@ -64,3 +74,32 @@ func (cm CSVMarshaller) Marshal(fields []string, object map[string]interface{})
 func (cm CSVMarshaller) NeedHeader() bool {
 	return true
 }
+
+//quote encloses the value in double quotes if it contains any delimiter char, double quote or line break
+func (cm CSVMarshaller) quote(value string) string {
+	if !strings.ContainsAny(value, cm.delimiter+quote+"\r\n") {
+		return value
+	}
+
+	return quote + strings.ReplaceAll(value, quote, quote+quote) + quote
+}
+
+//formatCSVValue returns strings as is and other values serialized with JSON (without begin and end quotas)
+func formatCSVValue(v interface{}) string {
+	if str, ok := v.(string); ok {
+		return str
+	}
+
+	return string(formatJSONValue(v))
+}
+
+//formatJSONValue serializes the value with JSON and doesn't write begin and end quotas
+func formatJSONValue(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	lastIndex := len(b) - 1
+	if len(b) >= 2 && b[0] == quotaByteValue && b[lastIndex] == quotaByteValue {
+		return b[1:lastIndex]
+	}
+
+	return b
+}
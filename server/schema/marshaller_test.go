@@ -4,6 +4,7 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 	"time"
 )
@@ -79,6 +80,18 @@ func TestVerticalBarSeparatedMarshal(t *testing.T) {
 			[]string{"key6", "key2", "key3", "key4", "key5", "key1"},
 			[]byte(`222.5||2||2020-07-02T18:23:59.757719Z||||||value1`),
 		},
+		{
+			"Values with delimiter, quotes and line breaks",
+			map[string]interface{}{
+				"key1": "a|b",
+				"key2": `say "hi"`,
+				"key3": "line1\nline2",
+				"key4": `C:\temp <tag> & more`,
+				"key5": map[string]interface{}{"k": "v"},
+			},
+			[]string{"key1", "key2", "key3", "key4", "key5"},
+			[]byte("\"a|b\"||\"say \"\"hi\"\"\"||\"line1\nline2\"||C:\\temp <tag> & more||\"{\"\"k\"\":\"\"v\"\"}\""),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -89,3 +102,57 @@ func TestVerticalBarSeparatedMarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestVerticalBarSeparatedMarshalRoundTrip(t *testing.T) {
+	header := []string{"id", "pipes", "quotes", "lines", "backslashes"}
+	objects := []map[string]interface{}{
+		{"id": "1", "pipes": "a||b|", "quotes": `"quoted"`, "lines": "first\nsecond\r\nthird", "backslashes": `\n is not a line break`},
+		{"id": "2", "pipes": "|", "quotes": `"`, "lines": "\n", "backslashes": `\`},
+		{"id": "3", "pipes": "plain", "quotes": "plain", "lines": "plain", "backslashes": "plain"},
+	}
+
+	var rows []string
+	for _, object := range objects {
+		b, err := VerticalBarSeparatedMarshallerInstance.Marshal(header, object)
+		require.NoError(t, err)
+		rows = append(rows, string(b))
+	}
+
+	records := parseQuotedRecords(strings.Join(rows, "\n"), "||")
+	require.Len(t, records, len(objects))
+	for i, object := range objects {
+		require.Len(t, records[i], len(header))
+		for j, field := range header {
+			require.Equal(t, object[field], records[i][j], "row %d field %s", i, field)
+		}
+	}
+}
+
+//parseQuotedRecords parses RFC 4180 style records with the delimiter (as Snowflake does with FIELD_OPTIONALLY_ENCLOSED_BY = '"')
+func parseQuotedRecords(data, delimiter string) [][]string {
+	var records [][]string
+	var record []string
+	var value strings.Builder
+	quoted := false
+	for i := 0; i < len(data); i++ {
+		switch {
+		case quoted && data[i] == '"' && i+1 < len(data) && data[i+1] == '"':
+			value.WriteByte('"')
+			i++
+		case data[i] == '"' && (quoted || value.Len() == 0):
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(data[i:], delimiter):
+			record = append(record, value.String())
+			value.Reset()
+			i += len(delimiter) - 1
+		case !quoted && data[i] == '\n':
+			records = append(records, append(record, value.String()))
+			record = nil
+			value.Reset()
+		default:
+			value.WriteByte(data[i])
+		}
+	}
+
+	return append(records, append(record, value.String()))
+}
@@ -2,11 +2,14 @@ package schema
 
 import (
 	"bytes"
+	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
 	"strings"
 )
 
 //ProcessedFile collect data in payload and return it in two formats
+//payload objects are kept in the order events were received within a batch (ProcessEvents and SplitByTableName append objects)
+//all payload getters and marshallers preserve this order: e.g. rows in a stage file follow the events order
 type ProcessedFile struct {
 	FileName    string
 	BatchHeader *BatchHeader
@@ -39,6 +42,7 @@ func (pf *ProcessedFile) GetPayloadUsingStronglyTypedMarshaller(stm StronglyType
 }
 
 //GetPayloadBytesWithHeader returns marshaling by marshaller func, joined with \n,  bytes
+//rows are written in the payload order (the order events were received). Objects which can't be marshalled are skipped
 //assume that payload can't be empty
 func (pf *ProcessedFile) GetPayloadBytesWithHeader(marshaller Marshaller) ([]byte, []string) {
 	var buf *bytes.Buffer
@@ -76,3 +80,60 @@ func (pf *ProcessedFile) GetEventsPerSrc() map[string]int {
 
 	return result
 }
+
+//SplitByTableName splits payload into ProcessedFile per table name which is returned by tableNameFunc
+//all parts have the same FileName and the whole BatchHeader fields. Returns the current file if there is only one part with the same table name
+func (pf *ProcessedFile) SplitByTableName(tableNameFunc func(object map[string]interface{}) string) map[string]*ProcessedFile {
+	parts := map[string]*ProcessedFile{}
+	for _, object := range pf.payload {
+		tableName := tableNameFunc(object)
+		part, ok := parts[tableName]
+		if !ok {
+			part = &ProcessedFile{
+				FileName:    pf.FileName,
+				BatchHeader: &BatchHeader{TableName: tableName, Fields: pf.BatchHeader.Fields},
+				eventsSrc:   map[string]int{},
+			}
+			parts[tableName] = part
+		}
+		part.payload = append(part.payload, object)
+		part.eventsSrc[events.ExtractSrc(object)]++
+	}
+
+	if len(parts) == 1 {
+		if _, ok := parts[pf.BatchHeader.TableName]; ok {
+			return map[string]*ProcessedFile{pf.BatchHeader.TableName: pf}
+		}
+	}
+
+	return parts
+}
+
+//SplitByRows splits payload into ProcessedFile chunks with at most maxRows objects in the payload order
+//all chunks have the same FileName and BatchHeader. Returns the current file if it doesn't exceed maxRows
+func (pf *ProcessedFile) SplitByRows(maxRows int) []*ProcessedFile {
+	if maxRows <= 0 || len(pf.payload) <= maxRows {
+		return []*ProcessedFile{pf}
+	}
+
+	var chunks []*ProcessedFile
+	for start := 0; start < len(pf.payload); start += maxRows {
+		end := start + maxRows
+		if end > len(pf.payload) {
+			end = len(pf.payload)
+		}
+
+		chunk := &ProcessedFile{
+			FileName:    pf.FileName,
+			BatchHeader: pf.BatchHeader,
+			payload:     pf.payload[start:end],
+			eventsSrc:   map[string]int{},
+		}
+		for _, object := range chunk.payload {
+			chunk.eventsSrc[events.ExtractSrc(object)]++
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
@@ -0,0 +1,32 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitByRows(t *testing.T) {
+	pf := &ProcessedFile{FileName: "testfile", BatchHeader: &BatchHeader{TableName: "events"}, eventsSrc: map[string]int{}}
+	for i := 0; i < 7; i++ {
+		pf.payload = append(pf.payload, map[string]interface{}{"seq": i, "src": "api"})
+		pf.eventsSrc["api"]++
+	}
+
+	require.Equal(t, []*ProcessedFile{pf}, pf.SplitByRows(0))
+	require.Equal(t, []*ProcessedFile{pf}, pf.SplitByRows(7))
+
+	chunks := pf.SplitByRows(3)
+	require.Len(t, chunks, 3)
+	var seq []interface{}
+	for _, chunk := range chunks {
+		require.Equal(t, pf.FileName, chunk.FileName)
+		require.Equal(t, pf.BatchHeader, chunk.BatchHeader)
+		for _, object := range chunk.GetPayload() {
+			seq = append(seq, object["seq"])
+		}
+	}
+	require.Equal(t, []interface{}{0, 1, 2, 3, 4, 5, 6}, seq, "chunks must keep the payload order")
+	require.Equal(t, 1, chunks[2].GetPayloadLen())
+	require.Equal(t, map[string]int{"api": 1}, chunks[2].eventsSrc)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/maputils"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/templates"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/uuid"
@@ -21,6 +22,26 @@ import (
 
 var ErrSkipObject = errors.New("Transform or table name filter marked object to be skipped. This object will be skipped.")
 
+//OversizedEventError is returned if the event exceeds max_event_bytes limit. Such events are skipped
+type OversizedEventError struct {
+	Size  int
+	Limit int
+}
+
+func (oee *OversizedEventError) Error() string {
+	return fmt.Sprintf("Event size %d bytes exceeds max_event_bytes limit %d bytes. This object will be skipped.", oee.Size, oee.Limit)
+}
+
+//IsSkipError returns true if the error means that the event should be skipped (not written into fallback)
+func IsSkipError(err error) bool {
+	switch err.(type) {
+	case *OversizedEventError, *OutOfBoundsTimestampError, *FilteredTableError:
+		return true
+	default:
+		return err == ErrSkipObject
+	}
+}
+
 //go:embed segment.js
 var segmentTransform string
 
@@ -34,6 +55,12 @@ type Processor struct {
 	destinationConfig       *config.DestinationConfig
 	isSQLType               bool
 	tableNameExtractor      *TableNameExtractor
+	eventFilter             *EventFilter
+	fieldMasker             *FieldMasker
+	timestampBounds         *TimestampBounds
+	tableFilter             *TableFilter
+	columnRules             *ColumnRules
+	columnsLimiter          *ColumnsLimiter
 	lookupEnrichmentStep    *enrichment.LookupEnrichmentStep
 	transformer             *templates.V8TemplateExecutor
 	builtinTransformer      *templates.V8TemplateExecutor
@@ -44,6 +71,8 @@ type Processor struct {
 	breakOnError            bool
 	uniqueIDField           *identifiers.UniqueID
 	maxColumnNameLen        int
+	identifierTruncator     *IdentifierTruncator
+	maxEventBytes           int
 	tableNameFuncExpression string
 	defaultUserTransform    string
 	javaScripts             []string
@@ -53,6 +82,33 @@ type Processor struct {
 }
 
 func NewProcessor(destinationID string, destinationConfig *config.DestinationConfig, isSQLType bool, tableNameFuncExpression string, fieldMapper events.Mapper, enrichmentRules []enrichment.Rule, flattener Flattener, typeResolver TypeResolver, uniqueIDField *identifiers.UniqueID, maxColumnNameLen int) (*Processor, error) {
+	var fieldMasker *FieldMasker
+	if len(destinationConfig.FieldMasking) > 0 {
+		var err error
+		fieldMasker, err = NewFieldMasker(destinationConfig.FieldMasking)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	timestampBounds, err := NewTimestampBounds(destinationConfig.TimestampBounds)
+	if err != nil {
+		return nil, err
+	}
+
+	tableFilter, err := NewTableFilter(destinationConfig.TableAllowlist, destinationConfig.TableDenylist)
+	if err != nil {
+		return nil, err
+	}
+
+	var columnRules *ColumnRules
+	if destinationConfig.DataLayout != nil {
+		columnRules, err = NewColumnRules(destinationConfig.DataLayout.ColumnRules)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Processor{
 		identifier:              destinationID,
 		destinationConfig:       destinationConfig,
@@ -62,6 +118,10 @@ func NewProcessor(destinationID string, destinationConfig *config.DestinationCon
 		pulledEventsfieldMapper: &DummyMapper{},
 		typeResolver:            typeResolver,
 		flattener:               flattener,
+		fieldMasker:             fieldMasker,
+		timestampBounds:         timestampBounds,
+		tableFilter:             tableFilter,
+		columnRules:             columnRules,
 		breakOnError:            destinationConfig.BreakOnError,
 		uniqueIDField:           uniqueIDField,
 		maxColumnNameLen:        maxColumnNameLen,
@@ -83,6 +143,7 @@ func (p *Processor) ProcessEvent(event map[string]interface{}) ([]Envelope, erro
 //ProcessEvents processes events objects
 //returns array of processed objects per table like {"table1": []objects, "table2": []objects},
 //All failed events are moved to separate collection for sending to fallback
+//processed objects of every table are kept in the input objects order
 func (p *Processor) ProcessEvents(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*ProcessedFile, *events.FailedEvents, *events.SkippedEvents, error) {
 	if !p.transformInitialized {
 		err := fmt.Errorf("Destination: %s Attempt to use processor without running InitJavaScriptTemplates first", p.identifier)
@@ -96,13 +157,13 @@ func (p *Processor) ProcessEvents(fileName string, objects []map[string]interfac
 		envelops, err := p.processObject(event, alreadyUploadedTables)
 		if err != nil {
 			//handle skip object functionality
-			if err == ErrSkipObject {
+			if IsSkipError(err) {
 				eventID := p.uniqueIDField.Extract(event)
 				if !appconfig.Instance.DisableSkipEventsWarn {
 					logging.Warnf("[%s] Event [%s]: %v", p.identifier, eventID, err)
 				}
 
-				skippedEvents.Events = append(skippedEvents.Events, &events.SkippedEvent{EventID: eventID, Error: ErrSkipObject.Error()})
+				skippedEvents.Events = append(skippedEvents.Events, &events.SkippedEvent{EventID: eventID, Error: err.Error()})
 			} else if p.breakOnError {
 				return nil, nil, nil, err
 			} else {
@@ -151,20 +212,37 @@ func (p *Processor) ProcessPulledEvents(tableName string, objects []map[string]i
 		err := fmt.Errorf("Destination: %s Attempt to use processor without running InitJavaScriptTemplates first", p.identifier)
 		return nil, err
 	}
+	if err := p.checkTable(tableName, len(objects)); err != nil {
+		logging.Warnf("[%s] %d pulled objects: %v", p.identifier, len(objects), err)
+		return map[string]*ProcessedFile{}, nil
+	}
 	var pf *ProcessedFile
 	for _, event := range objects {
+		if err := p.checkEventSize(event); err != nil {
+			logging.Warnf("[%s] pulled object of %s table will be skipped: %v", p.identifier, tableName, err)
+			continue
+		}
+
 		processedObject, err := p.pulledEventsfieldMapper.Map(event)
 		if err != nil {
 			return nil, fmt.Errorf("Error mapping object: %v", err)
 		}
+		processedObject, err = p.maskFields(processedObject)
+		if err != nil {
+			return nil, err
+		}
 		flatObject, err := p.flattener.FlattenObject(processedObject)
 		if err != nil {
 			return nil, err
 		}
+		if err := p.applyColumnRules(tableName, flatObject); err != nil {
+			return nil, err
+		}
 		fields, err := p.typeResolver.Resolve(flatObject)
 		if err != nil {
 			return nil, err
 		}
+		p.markMaskedFields(fields)
 		batchHeader := &BatchHeader{TableName: tableName, Fields: fields}
 
 		//don't process empty and skipped object
@@ -192,13 +270,31 @@ func (p *Processor) ProcessPulledEvents(tableName string, objects []map[string]i
 }
 
 //processObject checks if table name in skipTables => return empty Table for skipping or
-//skips object if tableNameExtractor returns empty string, 'null' or 'false'
+//skips object if destination filter doesn't match or tableNameExtractor returns empty string, 'null' or 'false'
 //returns table representation of object and flatten, mapped object
-//1. extract table name
-//2. execute enrichment.LookupEnrichmentStep and Mapping
+//1. apply destination filter
+//2. extract table name
+//3. execute enrichment.LookupEnrichmentStep and Mapping
 //or ErrSkipObject/another error
 func (p *Processor) processObject(object map[string]interface{}, alreadyUploadedTables map[string]bool) ([]Envelope, error) {
+	if err := p.checkEventSize(object); err != nil {
+		return nil, err
+	}
+
 	objectCopy := maputils.CopyMap(object)
+	if err := p.checkTimestampBounds(objectCopy); err != nil {
+		return nil, err
+	}
+
+	if p.eventFilter != nil {
+		match, err := p.eventFilter.Match(objectCopy)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			return nil, ErrSkipObject
+		}
+	}
 	tableName, err := p.tableNameExtractor.Extract(objectCopy)
 	if err != nil {
 		return nil, err
@@ -259,6 +355,8 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 		return nil, fmt.Errorf("javascript transform result of incorrect type: %T Expected map[string]interface{}.", transformed)
 	}
 	envelops := make([]Envelope, 0, len(toProcess))
+	//filteredErr is returned if all objects are skipped because of table_allowlist/table_denylist
+	var filteredErr error
 
 	for i, prObject := range toProcess {
 		newUniqueId := p.uniqueIDField.Extract(object)
@@ -287,27 +385,204 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 		if ok {
 			continue
 		}
+		if err := p.checkTable(newTableName, 1); err != nil {
+			filteredErr = err
+			continue
+		}
+		prObject, err = p.maskFields(prObject)
+		if err != nil {
+			return nil, err
+		}
 		flatObject, err := p.flattener.FlattenObject(prObject)
 		if err != nil {
 			return nil, err
 		}
+		if err := p.applyColumnRules(newTableName, flatObject); err != nil {
+			return nil, err
+		}
 		fields, err := p.typeResolver.Resolve(flatObject)
 		if err != nil {
 			return nil, err
 		}
+		p.markMaskedFields(fields)
 		ClearTypeMetaFields(flatObject)
 		bh, obj, err := p.foldLongFields(&BatchHeader{newTableName, fields}, flatObject)
 		if err != nil {
 			return nil, fmt.Errorf("failed to process long fields: %v", err)
 		}
+		if err := p.limitColumns(bh, obj); err != nil {
+			return nil, err
+		}
 		envelops = append(envelops, Envelope{bh, obj})
 	}
 
+	if len(envelops) == 0 && filteredErr != nil {
+		return nil, filteredErr
+	}
+
 	return envelops, nil
 }
 
+//maskFields returns a copy of the object with masked fields (if field masking is configured)
+//the copy is required because pulled objects might be shared between destinations
+func (p *Processor) maskFields(object map[string]interface{}) (map[string]interface{}, error) {
+	if p.fieldMasker == nil {
+		return object, nil
+	}
+	maskedObject := maputils.CopyMap(object)
+	if err := p.fieldMasker.Mask(maskedObject); err != nil {
+		return nil, err
+	}
+	return maskedObject, nil
+}
+
+//MaskedFields returns flat field names with masking methods or empty map if field masking isn't configured
+func (p *Processor) MaskedFields() map[string]string {
+	if p.fieldMasker == nil {
+		return map[string]string{}
+	}
+	return p.fieldMasker.MaskedFields()
+}
+
+//markMaskedFields puts field_masking methods into the schema fields (see Field.Masking)
+//so masked columns are visible in the table schema
+func (p *Processor) markMaskedFields(fields Fields) {
+	if p.fieldMasker != nil {
+		p.fieldMasker.markFields(fields)
+	}
+}
+
+//SetColumnsLimit applies on_max_columns strategy to SQL destinations events if maxColumns is set
+func (p *Processor) SetColumnsLimit(maxColumns int, strategy string) error {
+	columnsLimiter, err := NewColumnsLimiter(maxColumns, strategy)
+	if err != nil {
+		return err
+	}
+	if columnsLimiter != nil && !p.isSQLType {
+		logging.Warnf("[%s] on_max_columns setting is supported only in SQL destinations", p.identifier)
+		return nil
+	}
+
+	p.columnsLimiter = columnsLimiter
+	return nil
+}
+
+//SetTableColumnsFunc sets func which returns existing table columns for max_columns limit checking
+func (p *Processor) SetTableColumnsFunc(tableColumnsFunc func(tableName string) map[string]bool) {
+	if p.columnsLimiter != nil {
+		p.columnsLimiter.SetTableColumnsFunc(tableColumnsFunc)
+	}
+}
+
+//limitColumns applies on_max_columns strategy (if configured) to the flat object
+func (p *Processor) limitColumns(header *BatchHeader, object map[string]interface{}) error {
+	if p.columnsLimiter == nil {
+		return nil
+	}
+
+	excess, err := p.columnsLimiter.Limit(header, object)
+	if err != nil {
+		return err
+	}
+	if len(excess) > 0 {
+		logging.Debugf("[%s] fields %v exceed max_columns limit of table %s: %s", p.identifier, excess, header.TableName, p.columnsLimiter.strategy)
+		if p.columnsLimiter.strategy == OnMaxColumnsDrop {
+			metrics.DropColumns(p.destinationConfig.Type, p.identifier, len(excess))
+		}
+	}
+	return nil
+}
+
+//SetIdentifierTruncator enables truncating over-length column names with the hash of the original name (instead of cutName)
+func (p *Processor) SetIdentifierTruncator(truncator *IdentifierTruncator) {
+	p.identifierTruncator = truncator
+}
+
+//IdentifierTruncator returns configured IdentifierTruncator or nil
+func (p *Processor) IdentifierTruncator() *IdentifierTruncator {
+	return p.identifierTruncator
+}
+
+//MaxColumnNameLength returns the max length of column names (0 - unlimited)
+func (p *Processor) MaxColumnNameLength() int {
+	return p.maxColumnNameLen
+}
+
+//SetMaxEventBytes sets max event size: bigger events are skipped with OversizedEventError (0 - unlimited)
+func (p *Processor) SetMaxEventBytes(maxEventBytes int) {
+	p.maxEventBytes = maxEventBytes
+}
+
+//checkEventSize returns OversizedEventError if the event size exceeds max_event_bytes limit
+func (p *Processor) checkEventSize(object map[string]interface{}) error {
+	if p.maxEventBytes <= 0 {
+		return nil
+	}
+
+	//size of the JSON serialized event
+	b, err := json.Marshal(object)
+	if err != nil {
+		logging.Debugf("[%s] Error serializing event for the size check: %v", p.identifier, err)
+		return nil
+	}
+
+	if len(b) > p.maxEventBytes {
+		metrics.OversizedEvents(p.destinationConfig.Type, p.identifier)
+		return &OversizedEventError{Size: len(b), Limit: p.maxEventBytes}
+	}
+
+	return nil
+}
+
+//checkTimestampBounds returns OutOfBoundsTimestampError or clamps _timestamp value if timestamp_bounds are configured
+func (p *Processor) checkTimestampBounds(object map[string]interface{}) error {
+	if p.timestampBounds == nil {
+		return nil
+	}
+
+	clamped, err := p.timestampBounds.Check(object, timestamp.Now().UTC())
+	if err != nil {
+		metrics.TimestampOutOfBounds(p.destinationConfig.Type, p.identifier, metrics.TimestampRejected)
+		return err
+	}
+	if clamped {
+		metrics.TimestampOutOfBounds(p.destinationConfig.Type, p.identifier, metrics.TimestampClamped)
+	}
+
+	return nil
+}
+
+//checkTable returns FilteredTableError if table_allowlist/table_denylist are configured and the table isn't allowed
+//filtered events are counted in metrics
+func (p *Processor) checkTable(tableName string, eventsCount int) error {
+	if p.tableFilter == nil {
+		return nil
+	}
+
+	err := p.tableFilter.Check(tableName)
+	if filteredErr, ok := err.(*FilteredTableError); ok {
+		result := metrics.TableNotAllowed
+		if filteredErr.Pattern != "" {
+			result = metrics.TableDenied
+		}
+		metrics.FilteredTableEvents(p.destinationConfig.Type, p.identifier, tableName, result, eventsCount)
+	}
+
+	return err
+}
+
+//applyColumnRules sets default values and coerces values of the flat object according to column_rules (if configured)
+//must be called before type resolving so the resolved column types match coerced values
+func (p *Processor) applyColumnRules(tableName string, flatObject map[string]interface{}) error {
+	if p.columnRules == nil {
+		return nil
+	}
+
+	return p.columnRules.Apply(tableName, flatObject)
+}
+
 //foldLongFields replace all column names with truncated values if they exceed the limit
-//uses cutName under the hood
+//uses cutName or IdentifierTruncator (if identifier_truncation is hash) under the hood
 func (p *Processor) foldLongFields(header *BatchHeader, object map[string]interface{}) (*BatchHeader, map[string]interface{}, error) {
 	if p.maxColumnNameLen <= 0 {
 		return header, object, nil
@@ -316,7 +591,12 @@ func (p *Processor) foldLongFields(header *BatchHeader, object map[string]interf
 	changes := map[string]string{}
 	for name := range header.Fields {
 		if len(name) > p.maxColumnNameLen {
-			newName := cutName(name, p.maxColumnNameLen)
+			var newName string
+			if p.identifierTruncator != nil {
+				newName = p.identifierTruncator.Truncate(name, p.maxColumnNameLen)
+			} else {
+				newName = cutName(name, p.maxColumnNameLen)
+			}
 			if name != newName {
 				changes[name] = newName
 			}
@@ -381,6 +661,13 @@ func (p *Processor) InitJavaScriptTemplates() (err error) {
 		return err
 	}
 	p.tableNameExtractor = tableNameExtractor
+	if p.destinationConfig.Filter != "" {
+		eventFilter, err := NewEventFilter(p.destinationConfig.Filter, templateVariables)
+		if err != nil {
+			return err
+		}
+		p.eventFilter = eventFilter
+	}
 	p.AddJavaScriptVariables(templateVariables)
 
 	transformDisabled := false
@@ -438,6 +725,9 @@ func (p *Processor) CloseJavaScriptTemplates() {
 	if p.tableNameExtractor != nil {
 		p.tableNameExtractor.Close()
 	}
+	if p.eventFilter != nil {
+		p.eventFilter.Close()
+	}
 	if p.transformer != nil {
 		p.transformer.Close()
 	}
@@ -507,3 +797,4 @@ func ClearTypeMetaFields(object map[string]interface{}) {
 		}
 	}
 }
+
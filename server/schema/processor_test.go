@@ -5,6 +5,7 @@ import (
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/spf13/viper"
 	"io/ioutil"
+	"strings"
 	"testing"
 	"time"
 
@@ -478,6 +479,92 @@ switch ($.event_type) {
 	}
 }
 
+func TestProcessOversizedEvents(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	destination := &config.DestinationConfig{Type: "postgres", DataLayout: &config.DataLayout{}}
+	p, err := NewProcessor("test", destination, false, `events`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), identifiers.NewUniqueID("/event_id"), 0)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+	p.SetMaxEventBytes(100)
+
+	objects := []map[string]interface{}{
+		{"event_id": "small", "value": "abc"},
+		{"event_id": "huge", "nested": map[string]interface{}{"value": strings.Repeat("a", 200)}},
+	}
+	actual, failed, skipped, err := p.ProcessEvents("testfile", objects, map[string]bool{})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(failed.Events))
+	require.Equal(t, 1, actual["events"].GetPayloadLen())
+	require.Equal(t, 1, len(skipped.Events))
+	require.Equal(t, "huge", skipped.Events[0].EventID)
+	require.Equal(t, "Event size 241 bytes exceeds max_event_bytes limit 100 bytes. This object will be skipped.", skipped.Events[0].Error)
+
+	_, err = p.ProcessEvent(objects[1])
+	require.IsType(t, &OversizedEventError{}, err)
+
+	//numbers are counted by their serialized length
+	_, err = p.ProcessEvent(map[string]interface{}{"event_id": "numbers", "values": []interface{}{1234567890123, 1234567890123, 1234567890123, 1234567890123, 1234567890123, 1234567890123}})
+	require.IsType(t, &OversizedEventError{}, err)
+
+	pulled, err := p.ProcessPulledEvents("pulled", objects)
+	require.NoError(t, err)
+	require.Equal(t, 1, pulled["pulled"].GetPayloadLen(), "oversized pulled object must be skipped")
+}
+
+func TestProcessOutOfBoundsTimestampEvents(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	destination := &config.DestinationConfig{Type: "postgres", DataLayout: &config.DataLayout{},
+		TimestampBounds: &config.TimestampBoundsConfig{MaxFutureSkewSec: 3600}}
+	p, err := NewProcessor("test", destination, false, `events`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), identifiers.NewUniqueID("/event_id"), 0)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+
+	objects := []map[string]interface{}{
+		{"event_id": "now", timestamp.Key: timestamp.NowUTC()},
+		{"event_id": "future", timestamp.Key: timestamp.ToISOFormat(timestamp.Now().Add(24 * time.Hour))},
+	}
+	actual, failed, skipped, err := p.ProcessEvents("testfile", objects, map[string]bool{})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(failed.Events))
+	require.Equal(t, 1, actual["events"].GetPayloadLen())
+	require.Equal(t, 1, len(skipped.Events))
+	require.Equal(t, "future", skipped.Events[0].EventID)
+
+	_, err = p.ProcessEvent(objects[1])
+	require.IsType(t, &OutOfBoundsTimestampError{}, err)
+}
+
+func TestProcessLongFieldsWithHash(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	destination := &config.DestinationConfig{Type: "snowflake", DataLayout: &config.DataLayout{}}
+	p, err := NewProcessor("test", destination, true, `events`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), identifiers.NewUniqueID("/event_id"), 30)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+	truncator, err := NewIdentifierTruncator("test", IdentifierTruncationHash)
+	require.NoError(t, err)
+	p.SetIdentifierTruncator(truncator)
+
+	object := map[string]interface{}{"event_id": "1", "properties": map[string]interface{}{"very_long_nested_name_a": "a", "very_long_nested_name_b": "b"}}
+	envelops, err := p.ProcessEvent(object)
+	require.NoError(t, err)
+	require.Len(t, envelops, 1)
+
+	columnA := TruncateIdentifier("properties_very_long_nested_name_a", 30)
+	columnB := TruncateIdentifier("properties_very_long_nested_name_b", 30)
+	require.NotEqual(t, columnA, columnB, "cutName would shorten both names to the same column")
+	require.Equal(t, "a", envelops[0].Event[columnA])
+	require.Equal(t, "b", envelops[0].Event[columnB])
+	require.Contains(t, envelops[0].Header.Fields, columnA)
+	require.Contains(t, envelops[0].Header.Fields, columnB)
+	require.Contains(t, envelops[0].Header.Fields, "event_id")
+}
+
 func TestCutName(t *testing.T) {
 	require.Equal(t, "ountry", cutName("firstnamelastnamemiddlenamecountry", 6))
 	require.Equal(t, "test", cutName("test", 12))
@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"fmt"
+	"path"
+)
+
+//FilteredTableError is returned if the event table isn't allowed by table_allowlist or is denied by table_denylist. Such events are skipped
+type FilteredTableError struct {
+	Table string
+	//Pattern is the matched table_denylist pattern (empty if the table doesn't match table_allowlist)
+	Pattern string
+}
+
+func (fte *FilteredTableError) Error() string {
+	if fte.Pattern != "" {
+		return fmt.Sprintf("Table [%s] matches table_denylist pattern [%s]. This object will be skipped.", fte.Table, fte.Pattern)
+	}
+
+	return fmt.Sprintf("Table [%s] doesn't match any table_allowlist pattern. This object will be skipped.", fte.Table)
+}
+
+//TableFilter checks table names against table_allowlist and table_denylist glob patterns (e.g. events_*)
+//so new tables aren't created in the destination without permission
+type TableFilter struct {
+	allowlist []string
+	denylist  []string
+}
+
+//NewTableFilter returns configured TableFilter instance or nil if both lists are empty
+//returns err if a pattern is malformed
+func NewTableFilter(allowlist, denylist []string) (*TableFilter, error) {
+	if len(allowlist) == 0 && len(denylist) == 0 {
+		return nil, nil
+	}
+
+	for _, pattern := range append(append([]string{}, allowlist...), denylist...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("malformed table_allowlist/table_denylist pattern [%s]: %v", pattern, err)
+		}
+	}
+
+	return &TableFilter{allowlist: allowlist, denylist: denylist}, nil
+}
+
+//Check returns FilteredTableError if the table matches table_denylist pattern (denylist has priority)
+//or table_allowlist is configured and the table doesn't match any of its patterns
+func (tf *TableFilter) Check(tableName string) error {
+	for _, pattern := range tf.denylist {
+		if matchTable(pattern, tableName) {
+			return &FilteredTableError{Table: tableName, Pattern: pattern}
+		}
+	}
+
+	if len(tf.allowlist) == 0 {
+		return nil
+	}
+
+	for _, pattern := range tf.allowlist {
+		if matchTable(pattern, tableName) {
+			return nil
+		}
+	}
+
+	return &FilteredTableError{Table: tableName}
+}
+
+//matchTable returns true if the table name matches glob pattern. Patterns are validated in NewTableFilter
+func matchTable(pattern, tableName string) bool {
+	matched, _ := path.Match(pattern, tableName)
+	return matched
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowlist   []string
+		denylist    []string
+		table       string
+		expectedErr string
+	}{
+		{
+			"allowed by pattern",
+			[]string{"events", "events_*"},
+			nil,
+			"events_2021",
+			"",
+		},
+		{
+			"not allowed",
+			[]string{"events", "events_*"},
+			nil,
+			"orders",
+			"Table [orders] doesn't match any table_allowlist pattern. This object will be skipped.",
+		},
+		{
+			"denied",
+			nil,
+			[]string{"tmp_*", "debug"},
+			"tmp_test",
+			"Table [tmp_test] matches table_denylist pattern [tmp_*]. This object will be skipped.",
+		},
+		{
+			"not denied",
+			nil,
+			[]string{"tmp_*", "debug"},
+			"events",
+			"",
+		},
+		{
+			"denylist has priority",
+			[]string{"events_*"},
+			[]string{"events_debug?"},
+			"events_debug1",
+			"Table [events_debug1] matches table_denylist pattern [events_debug?]. This object will be skipped.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableFilter, err := NewTableFilter(tt.allowlist, tt.denylist)
+			require.NoError(t, err)
+
+			err = tableFilter.Check(tt.table)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				require.True(t, IsSkipError(err))
+				require.Equal(t, tt.expectedErr, err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTableFilterConfig(t *testing.T) {
+	tableFilter, err := NewTableFilter(nil, []string{})
+	require.NoError(t, err)
+	require.Nil(t, tableFilter)
+
+	_, err = NewTableFilter([]string{"events_["}, nil)
+	require.EqualError(t, err, "malformed table_allowlist/table_denylist pattern [events_[]: syntax error in pattern")
+}
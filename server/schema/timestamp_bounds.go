@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//on_out_of_bounds strategies
+const (
+	//OnOutOfBoundsSkip skips the event with OutOfBoundsTimestampError
+	OnOutOfBoundsSkip = "skip"
+	//OnOutOfBoundsClamp replaces the event _timestamp with the nearest bound
+	OnOutOfBoundsClamp = "clamp"
+)
+
+//OutOfBoundsTimestampError is returned if the event _timestamp is out of timestamp_bounds. Such events are skipped
+type OutOfBoundsTimestampError struct {
+	Timestamp time.Time
+	Reason    string
+}
+
+func (oobe *OutOfBoundsTimestampError) Error() string {
+	return fmt.Sprintf("Event %s %s is %s. This object will be skipped.", timestamp.Key, timestamp.ToISOFormat(oobe.Timestamp), oobe.Reason)
+}
+
+//TimestampBounds checks events _timestamp against max_future_skew_sec and max_past_age_sec bounds
+//events without _timestamp or with unparsable values aren't checked
+type TimestampBounds struct {
+	maxFutureSkew time.Duration
+	maxPastAge    time.Duration
+	strategy      string
+}
+
+//NewTimestampBounds returns configured TimestampBounds instance or nil if bounds aren't set
+//returns err if strategy is unknown or bounds are negative
+func NewTimestampBounds(boundsConfig *config.TimestampBoundsConfig) (*TimestampBounds, error) {
+	if boundsConfig == nil {
+		return nil, nil
+	}
+
+	if boundsConfig.MaxFutureSkewSec < 0 || boundsConfig.MaxPastAgeSec < 0 {
+		return nil, fmt.Errorf("timestamp_bounds max_future_skew_sec and max_past_age_sec must be positive")
+	}
+
+	strategy := boundsConfig.OnOutOfBounds
+	switch strategy {
+	case "":
+		strategy = OnOutOfBoundsSkip
+	case OnOutOfBoundsSkip, OnOutOfBoundsClamp:
+	default:
+		return nil, fmt.Errorf("Unknown timestamp_bounds on_out_of_bounds value: %s. Available values: [%s, %s]", strategy, OnOutOfBoundsSkip, OnOutOfBoundsClamp)
+	}
+
+	if !boundsConfig.IsEnabled() {
+		return nil, nil
+	}
+
+	return &TimestampBounds{
+		maxFutureSkew: time.Duration(boundsConfig.MaxFutureSkewSec) * time.Second,
+		maxPastAge:    time.Duration(boundsConfig.MaxPastAgeSec) * time.Second,
+		strategy:      strategy,
+	}, nil
+}
+
+//Check returns OutOfBoundsTimestampError (skip strategy) or replaces _timestamp with the nearest bound (clamp strategy)
+//if the object _timestamp is out of bounds relative to now. Returns true if the value has been clamped
+func (tb *TimestampBounds) Check(object map[string]interface{}, now time.Time) (bool, error) {
+	rawTimestamp, ok := object[timestamp.Key]
+	if !ok {
+		return false, nil
+	}
+
+	eventTime, err := typing.ParseTimestamp(rawTimestamp)
+	if err != nil {
+		return false, nil
+	}
+
+	var bound time.Time
+	var reason string
+	if tb.maxFutureSkew > 0 && eventTime.After(now.Add(tb.maxFutureSkew)) {
+		bound = now.Add(tb.maxFutureSkew)
+		reason = fmt.Sprintf("later than max_future_skew_sec (%s) bound", tb.maxFutureSkew)
+	} else if tb.maxPastAge > 0 && eventTime.Before(now.Add(-tb.maxPastAge)) {
+		bound = now.Add(-tb.maxPastAge)
+		reason = fmt.Sprintf("earlier than max_past_age_sec (%s) bound", tb.maxPastAge)
+	} else {
+		return false, nil
+	}
+
+	if tb.strategy == OnOutOfBoundsSkip {
+		return false, &OutOfBoundsTimestampError{Timestamp: eventTime, Reason: reason}
+	}
+
+	//keep the value type
+	if _, ok := rawTimestamp.(string); ok {
+		object[timestamp.Key] = timestamp.ToISOFormat(bound)
+	} else {
+		object[timestamp.Key] = bound
+	}
+
+	return true, nil
+}
@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampBounds(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		boundsConfig      *config.TimestampBoundsConfig
+		input             map[string]interface{}
+		expected          map[string]interface{}
+		expectedClamped   bool
+		expectedErr       string
+		expectedConfigErr string
+	}{
+		{
+			"within bounds",
+			&config.TimestampBoundsConfig{MaxFutureSkewSec: 3600, MaxPastAgeSec: 86400},
+			map[string]interface{}{timestamp.Key: "2021-06-15T12:30:00.000000Z"},
+			map[string]interface{}{timestamp.Key: "2021-06-15T12:30:00.000000Z"},
+			false,
+			"",
+			"",
+		},
+		{
+			"without timestamp",
+			&config.TimestampBoundsConfig{MaxFutureSkewSec: 3600},
+			map[string]interface{}{"field": "value"},
+			map[string]interface{}{"field": "value"},
+			false,
+			"",
+			"",
+		},
+		{
+			"future skip",
+			&config.TimestampBoundsConfig{MaxFutureSkewSec: 3600},
+			map[string]interface{}{timestamp.Key: "2024-06-15T12:00:00.000000Z"},
+			nil,
+			false,
+			"Event _timestamp 2024-06-15T12:00:00.000000Z is later than max_future_skew_sec (1h0m0s) bound. This object will be skipped.",
+			"",
+		},
+		{
+			"past skip",
+			&config.TimestampBoundsConfig{MaxPastAgeSec: 86400, OnOutOfBounds: OnOutOfBoundsSkip},
+			map[string]interface{}{timestamp.Key: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+			nil,
+			false,
+			"Event _timestamp 2020-01-01T00:00:00.000000Z is earlier than max_past_age_sec (24h0m0s) bound. This object will be skipped.",
+			"",
+		},
+		{
+			"future clamp string",
+			&config.TimestampBoundsConfig{MaxFutureSkewSec: 3600, OnOutOfBounds: OnOutOfBoundsClamp},
+			map[string]interface{}{timestamp.Key: "2024-06-15T12:00:00.000000Z"},
+			map[string]interface{}{timestamp.Key: "2021-06-15T13:00:00.000000Z"},
+			true,
+			"",
+			"",
+		},
+		{
+			"past clamp time",
+			&config.TimestampBoundsConfig{MaxPastAgeSec: 86400, OnOutOfBounds: OnOutOfBoundsClamp},
+			map[string]interface{}{timestamp.Key: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+			map[string]interface{}{timestamp.Key: time.Date(2021, 6, 14, 12, 0, 0, 0, time.UTC)},
+			true,
+			"",
+			"",
+		},
+		{
+			"unknown strategy",
+			&config.TimestampBoundsConfig{MaxPastAgeSec: 86400, OnOutOfBounds: "drop"},
+			nil,
+			nil,
+			false,
+			"",
+			"Unknown timestamp_bounds on_out_of_bounds value: drop. Available values: [skip, clamp]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounds, err := NewTimestampBounds(tt.boundsConfig)
+			if tt.expectedConfigErr != "" {
+				require.EqualError(t, err, tt.expectedConfigErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, bounds)
+
+			clamped, err := bounds.Check(tt.input, now)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				require.True(t, IsSkipError(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedClamped, clamped)
+			require.Equal(t, tt.expected, tt.input)
+		})
+	}
+
+	bounds, err := NewTimestampBounds(&config.TimestampBoundsConfig{OnOutOfBounds: OnOutOfBoundsClamp})
+	require.NoError(t, err)
+	require.Nil(t, bounds, "bounds without limits must be disabled")
+}
@@ -0,0 +1,54 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+//AWSSecretsManagerScheme is a placeholder scheme of AWS Secrets Manager secrets: ${aws-sm:name} or ${aws-sm:name#key} for JSON secrets
+const AWSSecretsManagerScheme = "aws-sm"
+
+//AWSSecretsManagerResolver reads secrets from AWS Secrets Manager
+//credentials are taken from the default AWS credentials chain (env variables, shared config, instance role),
+//region from secrets.aws.region configuration parameter or AWS_REGION env variable
+type AWSSecretsManagerResolver struct{}
+
+//Resolve returns the secret string (or the key value of JSON secret). Reference format: name or name#key
+func (asmr *AWSSecretsManagerResolver) Resolve(reference string) (string, error) {
+	name, key := splitReference(reference)
+
+	awsSession, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return "", fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	awsConfig := aws.NewConfig()
+	if region := configValue("secrets.aws.region", "AWS_REGION"); region != "" {
+		awsConfig.WithRegion(region)
+	}
+
+	output, err := secretsmanager.New(awsSession, awsConfig).GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(name)})
+	if err != nil {
+		return "", fmt.Errorf("error getting AWS Secrets Manager secret: %v", err)
+	}
+
+	secret := aws.StringValue(output.SecretString)
+	if output.SecretString == nil {
+		secret = string(output.SecretBinary)
+	}
+
+	if key == "" {
+		return secret, nil
+	}
+
+	values := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("secret isn't a JSON object: key [%s] can't be extracted", key)
+	}
+
+	return extractKey(values, key)
+}
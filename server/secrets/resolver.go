@@ -0,0 +1,129 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//Resolver returns the secret value by the reference: the part of ${scheme:reference} placeholder after the scheme
+type Resolver interface {
+	Resolve(reference string) (string, error)
+}
+
+var (
+	placeholderRegex = regexp.MustCompile(`\$\{([a-z0-9-]+):([^}]+)\}`)
+
+	mutex     = &sync.RWMutex{}
+	resolvers = map[string]Resolver{
+		VaultScheme:             NewVaultResolver(),
+		AWSSecretsManagerScheme: &AWSSecretsManagerResolver{},
+	}
+)
+
+//RegisterResolver adds (or replaces) the resolver of placeholders with the scheme
+func RegisterResolver(scheme string, resolver Resolver) {
+	mutex.Lock()
+	resolvers[scheme] = resolver
+	mutex.Unlock()
+}
+
+func getResolver(scheme string) (Resolver, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	resolver, ok := resolvers[scheme]
+	return resolver, ok
+}
+
+//Resolve returns a copy of the value (maps, slices and strings) with ${scheme:reference} placeholders
+//replaced by the secret values. Placeholders with unknown schemes are kept as is.
+//Returned errors contain only placeholder references (secret values are never included)
+func Resolve(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := Resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = resolved
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			resolved, err := Resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, resolved)
+		}
+		return result, nil
+	case string:
+		return resolveString(v)
+	default:
+		return value, nil
+	}
+}
+
+//resolveString replaces all placeholders with registered schemes in the string
+func resolveString(value string) (string, error) {
+	var resolveErr error
+	result := placeholderRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+		if resolveErr != nil {
+			return placeholder
+		}
+
+		groups := placeholderRegex.FindStringSubmatch(placeholder)
+		scheme, reference := groups[1], groups[2]
+		resolver, ok := getResolver(scheme)
+		if !ok {
+			return placeholder
+		}
+
+		secret, err := resolver.Resolve(reference)
+		if err != nil {
+			resolveErr = fmt.Errorf("error resolving secret [%s:%s]: %v", scheme, reference, err)
+			return placeholder
+		}
+
+		return secret
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	return result, nil
+}
+
+//splitReference splits reference into the secret path (name) and the key: path#key
+func splitReference(reference string) (string, string) {
+	if i := strings.LastIndex(reference, "#"); i >= 0 {
+		return reference[:i], reference[i+1:]
+	}
+
+	return reference, ""
+}
+
+//extractKey returns the string value of the key from secret values
+//non-string values are returned as JSON
+func extractKey(values map[string]interface{}, key string) (string, error) {
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("key [%s] doesn't exist in the secret", key)
+	}
+
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("error serializing key [%s] value: %v", key, err)
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+type testResolver struct {
+	secrets map[string]string
+}
+
+func (tr *testResolver) Resolve(reference string) (string, error) {
+	secret, ok := tr.secrets[reference]
+	if !ok {
+		return "", errors.New("not found")
+	}
+
+	return secret, nil
+}
+
+func TestResolve(t *testing.T) {
+	RegisterResolver("test", &testResolver{secrets: map[string]string{"shopify#api_key": "abc123", "db": "p@ss"}})
+
+	tests := []struct {
+		name        string
+		input       interface{}
+		expected    interface{}
+		expectedErr string
+	}{
+		{
+			"nested placeholders",
+			map[string]interface{}{
+				"api_key": "${test:shopify#api_key}",
+				"credentials": map[string]interface{}{
+					"dsn":   "postgres://user:${test:db}@host/db",
+					"hosts": []interface{}{"${test:db}", "plain"},
+				},
+				"port": 5432,
+			},
+			map[string]interface{}{
+				"api_key": "abc123",
+				"credentials": map[string]interface{}{
+					"dsn":   "postgres://user:p@ss@host/db",
+					"hosts": []interface{}{"p@ss", "plain"},
+				},
+				"port": 5432,
+			},
+			"",
+		},
+		{
+			"unknown scheme",
+			map[string]interface{}{"template": "${unknown:value}"},
+			map[string]interface{}{"template": "${unknown:value}"},
+			"",
+		},
+		{
+			"resolving error",
+			map[string]interface{}{"api_key": "${test:missing}"},
+			nil,
+			"error resolving secret [test:missing]: not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := Resolve(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestVaultResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(vaultTokenHeader) != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/shopify":
+			w.Write([]byte(`{"data":{"data":{"api_key":"abc123"},"metadata":{"version":1}}}`))
+		case "/v1/kv/shopify":
+			w.Write([]byte(`{"data":{"api_key":"def456"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	viper.Set("secrets.vault.address", server.URL)
+	viper.Set("secrets.vault.token", "test-token")
+	defer viper.Set("secrets.vault.address", "")
+	defer viper.Set("secrets.vault.token", "")
+
+	resolver := NewVaultResolver()
+
+	secret, err := resolver.Resolve("secret/data/shopify#api_key")
+	require.NoError(t, err)
+	require.Equal(t, "abc123", secret, "KV v2 secret")
+
+	secret, err = resolver.Resolve("kv/shopify#api_key")
+	require.NoError(t, err)
+	require.Equal(t, "def456", secret, "KV v1 secret")
+
+	_, err = resolver.Resolve("kv/shopify#unknown")
+	require.EqualError(t, err, "key [unknown] doesn't exist in the secret")
+
+	_, err = resolver.Resolve("kv/missing#api_key")
+	require.EqualError(t, err, "Vault responded with HTTP code 404")
+
+	_, err = resolver.Resolve("kv/shopify")
+	require.EqualError(t, err, "key is required: use path#key format")
+}
@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	//VaultScheme is a placeholder scheme of HashiCorp Vault secrets: ${vault:secret/data/path#key}
+	VaultScheme = "vault"
+
+	vaultTokenHeader = "X-Vault-Token"
+)
+
+//VaultResolver reads secrets from HashiCorp Vault KV (v1 and v2) secrets engines
+//Vault address and token are taken from secrets.vault.address and secrets.vault.token configuration parameters
+//or VAULT_ADDR and VAULT_TOKEN env variables
+type VaultResolver struct {
+	client *http.Client
+}
+
+//vaultResponse is a dto for Vault read secret response
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+//NewVaultResolver returns configured VaultResolver
+func NewVaultResolver() *VaultResolver {
+	return &VaultResolver{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+//Resolve reads the secret path and returns the key value. Reference format: path#key
+func (vr *VaultResolver) Resolve(reference string) (string, error) {
+	secretPath, key := splitReference(reference)
+	if key == "" {
+		return "", errors.New("key is required: use path#key format")
+	}
+
+	address := configValue("secrets.vault.address", "VAULT_ADDR")
+	if address == "" {
+		return "", errors.New("Vault address isn't configured: set secrets.vault.address or VAULT_ADDR")
+	}
+	token := configValue("secrets.vault.token", "VAULT_TOKEN")
+	if token == "" {
+		return "", errors.New("Vault token isn't configured: set secrets.vault.token or VAULT_TOKEN")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(address, "/")+"/v1/"+strings.TrimPrefix(secretPath, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating Vault request: %v", err)
+	}
+	req.Header.Set(vaultTokenHeader, token)
+
+	resp, err := vr.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error reading Vault secret: %v", err)
+	}
+	defer resp.Body.Close()
+
+	response := &vaultResponse{}
+	decodeErr := json.NewDecoder(resp.Body).Decode(response)
+	if resp.StatusCode != http.StatusOK {
+		if len(response.Errors) > 0 {
+			return "", fmt.Errorf("Vault responded with HTTP code %d: %s", resp.StatusCode, strings.Join(response.Errors, ", "))
+		}
+		return "", fmt.Errorf("Vault responded with HTTP code %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("error decoding Vault response: %v", decodeErr)
+	}
+
+	data := response.Data
+	//KV v2 secrets engine wraps values into data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	return extractKey(data, key)
+}
+
+//configValue returns the configuration parameter value or the env variable value
+func configValue(viperKey, envName string) string {
+	if value := viper.GetString(viperKey); value != "" {
+		return value
+	}
+
+	return os.Getenv(envName)
+}
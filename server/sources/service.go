@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/airbyte"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/drivers"
 	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/runner"
 	"github.com/jitsucom/jitsu/server/scheduling"
 	"github.com/jitsucom/jitsu/server/telemetry"
 	"github.com/spf13/viper"
@@ -39,6 +41,8 @@ type Service struct {
 	cronScheduler       *scheduling.CronScheduler
 
 	configured bool
+	//configDirsPruned is true after orphaned airbyte config dirs have been removed (on the first sources loading)
+	configDirsPruned bool
 }
 
 //NewTestService is used only for tests
@@ -133,6 +137,8 @@ func (s *Service) init(sc map[string]driversbase.SourceConfig) {
 		s.Lock()
 		for sourceIDToDel, unit := range toDelete {
 			s.remove(sourceIDToDel, unit)
+			//recreated sources reuse their files
+			unit.CleanWorkDirs()
 		}
 		s.Unlock()
 	}
@@ -165,7 +171,7 @@ func (s *Service) init(sc map[string]driversbase.SourceConfig) {
 
 		driverPerCollection, err := drivers.Create(s.ctx, name, &sourceConfig, s.cronScheduler)
 		if err != nil {
-			logging.Errorf("[%s] Error initializing source of type %s: %v", name, sourceConfig.Type, err)
+			logging.Errorf("[%s] Error initializing source of type %s: %v", name, sourceConfig.Type, logging.RedactError(err, sourceConfig.Config))
 			continue
 		}
 
@@ -193,6 +199,15 @@ func (s *Service) init(sc map[string]driversbase.SourceConfig) {
 
 		telemetry.Source(name, sourceType, connectorOrigin, connectorVersion, sourceConfig.Schedule, streams)
 	}
+
+	if !s.configDirsPruned && airbyte.Instance != nil && viper.GetBool("airbyte-bridge.prune_config_dirs") {
+		s.configDirsPruned = true
+		sourceIDs := map[string]bool{}
+		for sourceID := range sc {
+			sourceIDs[sourceID] = true
+		}
+		airbyte.Instance.PruneConfigDirs(sourceIDs)
+	}
 }
 
 func (s *Service) IsConfigured() bool {
@@ -228,6 +243,34 @@ func (s *Service) GetCollections(sourceID string) ([]string, error) {
 	return collections, nil
 }
 
+//Health returns CLI drivers (Singer, Airbyte) readiness check result (nil if ready) per sourceID.collection
+func (s *Service) Health() map[string]error {
+	s.RLock()
+	defer s.RUnlock()
+
+	result := map[string]error{}
+	for sourceID, unit := range s.sources {
+		for collection, driver := range unit.DriverPerCollection {
+			cliDriver, ok := driver.(driversbase.CLIDriver)
+			if !ok {
+				continue
+			}
+
+			ready, err := cliDriver.Ready()
+			switch {
+			case ready:
+				result[sourceID+"."+collection] = nil
+			case err != nil:
+				result[sourceID+"."+collection] = err
+			default:
+				result[sourceID+"."+collection] = runner.ErrNotReady
+			}
+		}
+	}
+
+	return result
+}
+
 //remove closes and removes source instance from Service
 //method must be called with locks
 func (s *Service) remove(sourceID string, unit *Unit) {
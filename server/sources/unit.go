@@ -24,3 +24,12 @@ func (u *Unit) Close() (multiErr error) {
 
 	return
 }
+
+//CleanWorkDirs removes drivers files from disk (e.g. connector configs with secrets)
+func (u *Unit) CleanWorkDirs() {
+	for _, driver := range u.DriverPerCollection {
+		if cleaner, ok := driver.(driversbase.WorkDirCleaner); ok {
+			cleaner.CleanWorkDir()
+		}
+	}
+}
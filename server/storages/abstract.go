@@ -3,6 +3,7 @@ package storages
 import (
 	"fmt"
 	"math/rand"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -13,10 +14,13 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/dlq"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/samples"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/telemetry"
+	"github.com/jitsucom/jitsu/server/timestamp"
 )
 
 //Abstract is an Abstract destination storage
@@ -25,6 +29,7 @@ import (
 type Abstract struct {
 	destinationID  string
 	fallbackLogger logging.ObjectLogger
+	dlqLogger      logging.ObjectLogger
 	eventsCache    *caching.EventsCache
 	processor      *schema.Processor
 
@@ -36,6 +41,11 @@ type Abstract struct {
 	cachingConfiguration *config.CachingConfiguration
 
 	archiveLogger logging.ObjectLogger
+
+	//debugSampleRate is a share of processed objects which are written into debug samples sink (0 - disabled)
+	debugSampleRate float64
+
+	stats storageStats
 }
 
 //ID returns destination ID
@@ -62,6 +72,29 @@ func (a *Abstract) IsCachingDisabled() bool {
 	return a.cachingConfiguration != nil && a.cachingConfiguration.Disabled
 }
 
+//IsCachingSkipped returns true if the event type is configured in caching.cache_skip_events
+func (a *Abstract) IsCachingSkipped(event events.Event) bool {
+	return a.cachingConfiguration.IsEventSkipped(events.ExtractEventType(event))
+}
+
+//Stats returns a snapshot of the destination lifetime counters
+func (a *Abstract) Stats() StorageStats {
+	return a.stats.snapshot()
+}
+
+//Health pings all SQL adapters which support it
+func (a *Abstract) Health() error {
+	for _, sqlAdapter := range a.sqlAdapters {
+		if pinger, ok := sqlAdapter.(adapters.Pinger); ok {
+			if err := pinger.Ping(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (a *Abstract) DryRun(payload events.Event) ([][]adapters.TableField, error) {
 	_, tableHelper := a.getAdapters()
 	return dryRun(payload, a.processor, tableHelper)
@@ -69,7 +102,7 @@ func (a *Abstract) DryRun(payload events.Event) ([][]adapters.TableField, error)
 
 //ErrorEvent writes error to metrics/counters/telemetry/events cache
 func (a *Abstract) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, err error) {
-	metrics.ErrorTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
+	metrics.ErrorTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID, metrics.StreamMode)
 	counters.ErrorPushDestinationEvents(a.destinationID, 1)
 	telemetry.Error(eventCtx.TokenID, a.destinationID, eventCtx.Src, "", 1)
 
@@ -77,10 +110,17 @@ func (a *Abstract) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, er
 	a.eventsCache.Error(eventCtx.CacheDisabled, a.destinationID, eventCtx.EventID, err.Error())
 
 	if fallback {
+		var tableName string
+		if eventCtx.Table != nil {
+			tableName = eventCtx.Table.Name
+		}
 		a.Fallback(&events.FailedEvent{
-			Event:   []byte(eventCtx.RawEvent.Serialize()),
-			Error:   err.Error(),
-			EventID: eventCtx.EventID,
+			Event:          []byte(eventCtx.RawEvent.Serialize()),
+			Error:          err.Error(),
+			EventID:        eventCtx.EventID,
+			TableName:      tableName,
+			Classification: ClassifyError(err),
+			CorrelationID:  eventCtx.EventID,
 		})
 	}
 }
@@ -89,25 +129,122 @@ func (a *Abstract) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, er
 func (a *Abstract) SuccessEvent(eventCtx *adapters.EventContext) {
 	counters.SuccessPushDestinationEvents(a.destinationID, 1)
 	telemetry.Event(eventCtx.TokenID, a.destinationID, eventCtx.Src, "", 1)
-	metrics.SuccessTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
+	metrics.SuccessTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID, metrics.StreamMode)
 
 	//cache
+	if a.IsCachingSkipped(eventCtx.ProcessedEvent) {
+		metrics.EventsCacheSkipped(a.destinationID, 1)
+		return
+	}
 	a.eventsCache.Succeed(eventCtx)
 }
 
 //SkipEvent writes skip to metrics/counters/telemetry and error to events cache
 func (a *Abstract) SkipEvent(eventCtx *adapters.EventContext, err error) {
 	counters.SkipPushDestinationEvents(a.destinationID, 1)
-	metrics.SkipTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
+	metrics.SkipTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID, metrics.StreamMode)
 
 	//cache
 	a.eventsCache.Skip(eventCtx.CacheDisabled, a.destinationID, eventCtx.EventID, err.Error())
 }
 
+//cacheProcessingResults writes failed and skipped events errors to events cache with batch updates
+func (a *Abstract) cacheProcessingResults(failedEvents *events.FailedEvents, skippedEvents *events.SkippedEvents) {
+	failed := make([]*caching.EventError, 0, len(failedEvents.Events))
+	for _, failedEvent := range failedEvents.Events {
+		failed = append(failed, &caching.EventError{EventID: failedEvent.EventID, Error: failedEvent.Error})
+	}
+	a.eventsCache.ErrorBatch(a.IsCachingDisabled(), a.destinationID, failed)
+
+	skipped := make([]*caching.EventError, 0, len(skippedEvents.Events))
+	for _, skipEvent := range skippedEvents.Events {
+		skipped = append(skipped, &caching.EventError{EventID: skipEvent.EventID, Error: skipEvent.Error})
+	}
+	a.eventsCache.SkipBatch(a.IsCachingDisabled(), a.destinationID, skipped)
+}
+
+//cacheStoreResult writes objects success (or error if err isn't nil) to events cache with one batch update
+func (a *Abstract) cacheStoreResult(objects []map[string]interface{}, table *adapters.Table, err error) {
+	if err != nil {
+		a.stats.failed()
+		eventErrors := make([]*caching.EventError, 0, len(objects))
+		for _, object := range objects {
+			eventErrors = append(eventErrors, &caching.EventError{EventID: a.uniqueIDField.Extract(object), Error: err.Error()})
+		}
+		a.eventsCache.ErrorBatch(a.IsCachingDisabled(), a.destinationID, eventErrors)
+		return
+	}
+
+	eventContexts := make([]*adapters.EventContext, 0, len(objects))
+	skipped := 0
+	for _, object := range objects {
+		if a.IsCachingSkipped(object) {
+			skipped++
+			continue
+		}
+
+		eventContexts = append(eventContexts, &adapters.EventContext{
+			CacheDisabled:  a.IsCachingDisabled(),
+			DestinationID:  a.destinationID,
+			EventID:        a.uniqueIDField.Extract(object),
+			ProcessedEvent: object,
+			Table:          table,
+		})
+	}
+	if skipped > 0 {
+		metrics.EventsCacheSkipped(a.destinationID, skipped)
+	}
+	a.eventsCache.SucceedBatch(eventContexts)
+	a.stats.stored(len(objects))
+}
+
 //Fallback logs event with error to fallback logger
 func (a *Abstract) Fallback(failedEvents ...*events.FailedEvent) {
 	for _, failedEvent := range failedEvents {
 		a.fallbackLogger.ConsumeAny(failedEvent)
+		a.deadLetter(failedEvent)
+	}
+}
+
+//deadLetter writes structured DLQ record of the failed event into the DLQ sink
+func (a *Abstract) deadLetter(failedEvent *events.FailedEvent) {
+	if a.dlqLogger == nil {
+		return
+	}
+
+	classification := failedEvent.Classification
+	if classification == "" {
+		classification = dlq.ClassificationUnknown
+	}
+	attempt := failedEvent.Attempt
+	if attempt == 0 {
+		attempt = 1
+	}
+
+	a.dlqLogger.ConsumeAny(&dlq.Record{
+		Event:          failedEvent.Event,
+		MalformedEvent: failedEvent.MalformedEvent,
+		EventID:        failedEvent.EventID,
+		DestinationID:  a.destinationID,
+		TableName:      failedEvent.TableName,
+		Error:          failedEvent.Error,
+		Classification: classification,
+		Timestamp:      timestamp.Now().UTC(),
+		Attempt:        attempt,
+		CorrelationID:  failedEvent.CorrelationID,
+	})
+}
+
+//sample writes random objects (according to debugSampleRate) into debug samples sink
+func (a *Abstract) sample(tableName string, objects []map[string]interface{}) {
+	if a.debugSampleRate <= 0 {
+		return
+	}
+
+	for _, object := range objects {
+		if rand.Float64() < a.debugSampleRate {
+			samples.Instance.Add(a.destinationID, tableName, object)
+		}
 	}
 }
 
@@ -170,12 +307,14 @@ func (a *Abstract) retryInsert(sqlAdapter adapters.SQLAdapter, tableHelper *Tabl
 //AccountResult checks input error and calls ErrorEvent or SuccessEvent
 func (a *Abstract) AccountResult(eventContext *adapters.EventContext, err error) {
 	if err != nil {
-		if IsConnectionError(err) {
+		a.stats.failed()
+		if IsTransientError(err) {
 			a.ErrorEvent(false, eventContext, err)
 		} else {
 			a.ErrorEvent(true, eventContext, err)
 		}
 	} else {
+		a.stats.stored(1)
 		a.SuccessEvent(eventContext)
 	}
 }
@@ -185,12 +324,22 @@ func (a *Abstract) Clean(tableName string) error {
 	return nil
 }
 
+//CleanOlderThan returns ErrRetentionNotSupported by default
+func (a *Abstract) CleanOlderThan(tableName, column string, age time.Duration) error {
+	return ErrRetentionNotSupported
+}
+
 func (a *Abstract) close() (multiErr error) {
 	if a.fallbackLogger != nil {
 		if err := a.fallbackLogger.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", a.ID(), err))
 		}
 	}
+	if a.dlqLogger != nil {
+		if err := a.dlqLogger.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing DLQ logger: %v", a.ID(), err))
+		}
+	}
 	if a.archiveLogger != nil {
 		if err := a.archiveLogger.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing archive logger: %v", a.ID(), err))
@@ -0,0 +1,73 @@
+package storages
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCachingSkipped(t *testing.T) {
+	tests := []struct {
+		name                 string
+		cachingConfiguration *config.CachingConfiguration
+		event                events.Event
+		expected             bool
+	}{
+		{
+			"Nil caching configuration",
+			nil,
+			events.Event{"event_type": "pageview"},
+			false,
+		},
+		{
+			"Empty skip events",
+			&config.CachingConfiguration{},
+			events.Event{"event_type": "pageview"},
+			false,
+		},
+		{
+			"Exact match",
+			&config.CachingConfiguration{SkipEvents: []string{"identify", "pageview"}},
+			events.Event{"event_type": "pageview"},
+			true,
+		},
+		{
+			"Exact match doesn't match prefix",
+			&config.CachingConfiguration{SkipEvents: []string{"page"}},
+			events.Event{"event_type": "pageview"},
+			false,
+		},
+		{
+			"Prefix match",
+			&config.CachingConfiguration{SkipEvents: []string{"heartbeat_*"}},
+			events.Event{"event_type": "heartbeat_ping"},
+			true,
+		},
+		{
+			"Prefix doesn't match",
+			&config.CachingConfiguration{SkipEvents: []string{"heartbeat_*"}},
+			events.Event{"event_type": "heartbeat"},
+			false,
+		},
+		{
+			"Event without event type",
+			&config.CachingConfiguration{SkipEvents: []string{"*"}},
+			events.Event{"field1": "value1"},
+			false,
+		},
+		{
+			"Non string event type",
+			&config.CachingConfiguration{SkipEvents: []string{"1"}},
+			events.Event{"event_type": 1},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Abstract{cachingConfiguration: tt.cachingConfiguration}
+			require.Equal(t, tt.expected, a.IsCachingSkipped(tt.event))
+		})
+	}
+}
@@ -16,7 +16,7 @@ type Amplitude struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: AmplitudeType, createFunc: NewAmplitude, isSQL: false})
+	RegisterStorage(StorageType{typeName: AmplitudeType, createFunc: NewAmplitude, isSQL: false, configSchema: amplitudeSchema})
 }
 
 //NewAmplitude returns configured Amplitude destination
@@ -63,6 +63,7 @@ func NewAmplitude(config *Config) (Storage, error) {
 	a.destinationID = config.destinationID
 	a.processor = config.processor
 	a.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	a.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	a.eventsCache = config.eventsCache
 	a.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	a.uniqueIDField = config.uniqueIDField
@@ -70,7 +71,7 @@ func NewAmplitude(config *Config) (Storage, error) {
 	a.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	a.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, a, tableHelper)
+	a.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, a, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
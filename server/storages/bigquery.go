@@ -10,6 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/uuid"
+	"time"
 )
 
 var disabledRecognitionConfiguration = &UserRecognitionConfiguration{enabled: false}
@@ -26,7 +27,7 @@ type BigQuery struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: BigQueryType, createFunc: NewBigQuery, isSQL: true})
+	RegisterStorage(StorageType{typeName: BigQueryType, createFunc: NewBigQuery, isSQL: true, configSchema: bigQuerySchema})
 }
 
 //NewBigQuery returns BigQuery configured instance
@@ -76,6 +77,8 @@ func NewBigQuery(config *Config) (Storage, error) {
 	}
 
 	tableHelper := NewTableHelper("", bigQueryAdapter, config.coordinationService, config.pkFields, adapters.SchemaToBigQueryString, config.maxColumns, BigQueryType)
+	tableHelper.SetTableNameAffixes(config.tablePrefix, config.tableSuffix)
+	tableHelper.SetIdentifierTruncator(config.processor.IdentifierTruncator(), config.processor.MaxColumnNameLength())
 
 	bq := &BigQuery{
 		gcsAdapter: gcsAdapter,
@@ -86,8 +89,11 @@ func NewBigQuery(config *Config) (Storage, error) {
 	bq.destinationID = config.destinationID
 	bq.processor = config.processor
 	bq.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	bq.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	bq.debugSampleRate = config.destination.DebugSampleRate
 	bq.eventsCache = config.eventsCache
 	bq.tableHelpers = []*TableHelper{tableHelper}
+	config.processor.SetTableColumnsFunc(tableHelper.TableColumns)
 	bq.sqlAdapters = []adapters.SQLAdapter{bigQueryAdapter}
 	bq.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	bq.uniqueIDField = config.uniqueIDField
@@ -95,7 +101,7 @@ func NewBigQuery(config *Config) (Storage, error) {
 	bq.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	bq.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, bq, tableHelper)
+	bq.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, bq, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
@@ -113,39 +119,22 @@ func (bq *BigQuery) Store(fileName string, objects []map[string]interface{}, alr
 		return nil, nil, nil, err
 	}
 
-	//update cache with failed events
-	for _, failedEvent := range failedEvents.Events {
-		bq.eventsCache.Error(bq.IsCachingDisabled(), bq.ID(), failedEvent.EventID, failedEvent.Error)
-	}
-	//update cache and counter with skipped events
-	for _, skipEvent := range skippedEvents.Events {
-		bq.eventsCache.Skip(bq.IsCachingDisabled(), bq.ID(), skipEvent.EventID, skipEvent.Error)
-	}
+	//update cache with failed and skipped events
+	bq.cacheProcessingResults(failedEvents, skippedEvents)
 
 	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		start := time.Now()
 		err := bq.storeTable(fdata, table)
-		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
 		if err != nil {
 			storeFailedEvents = false
 		}
 
 		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				bq.eventsCache.Error(bq.IsCachingDisabled(), bq.ID(), bq.uniqueIDField.Extract(object), err.Error())
-			} else {
-				bq.eventsCache.Succeed(&adapters.EventContext{
-					CacheDisabled:  bq.IsCachingDisabled(),
-					DestinationID:  bq.ID(),
-					EventID:        bq.uniqueIDField.Extract(object),
-					ProcessedEvent: object,
-					Table:          table,
-				})
-			}
-		}
+		bq.cacheStoreResult(fdata.GetPayload(), table, err)
 	}
 
 	//store failed events to fallback only if other events have been inserted ok
@@ -159,6 +148,7 @@ func (bq *BigQuery) Store(fileName string, objects []map[string]interface{}, alr
 //storeTable checks table schema
 //stores data into one table via google cloud storage (if batch BQ) or uses streaming if stream mode
 func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	bq.sample(table.Name, fdata.GetPayload())
 	_, tableHelper := bq.getAdapters()
 	dbTable, err := tableHelper.EnsureTableWithoutCaching(bq.ID(), table)
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/schema"
+	"time"
 )
 
 //ClickHouse stores files to ClickHouse in two modes:
@@ -21,7 +22,7 @@ type ClickHouse struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: ClickHouseType, createFunc: NewClickHouse, isSQL: true})
+	RegisterStorage(StorageType{typeName: ClickHouseType, createFunc: NewClickHouse, isSQL: true, configSchema: clickHouseSchema})
 }
 
 //NewClickHouse returns configured ClickHouse instance
@@ -63,7 +64,10 @@ func NewClickHouse(config *Config) (Storage, error) {
 
 		chAdapters = append(chAdapters, adapter)
 		sqlAdapters = append(sqlAdapters, adapter)
-		chTableHelpers = append(chTableHelpers, NewTableHelper("", adapter, config.coordinationService, config.pkFields, adapters.SchemaToClickhouse, config.maxColumns, ClickHouseType))
+		tableHelper := NewTableHelper("", adapter, config.coordinationService, config.pkFields, adapters.SchemaToClickhouse, config.maxColumns, ClickHouseType)
+		tableHelper.SetTableNameAffixes(config.tablePrefix, config.tableSuffix)
+		tableHelper.SetIdentifierTruncator(config.processor.IdentifierTruncator(), config.processor.MaxColumnNameLength())
+		chTableHelpers = append(chTableHelpers, tableHelper)
 	}
 
 	ch := &ClickHouse{
@@ -76,8 +80,11 @@ func NewClickHouse(config *Config) (Storage, error) {
 	ch.destinationID = config.destinationID
 	ch.processor = config.processor
 	ch.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	ch.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	ch.debugSampleRate = config.destination.DebugSampleRate
 	ch.eventsCache = config.eventsCache
 	ch.tableHelpers = chTableHelpers
+	config.processor.SetTableColumnsFunc(chTableHelpers[0].TableColumns)
 	ch.sqlAdapters = sqlAdapters
 	ch.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	ch.uniqueIDField = config.uniqueIDField
@@ -95,7 +102,7 @@ func NewClickHouse(config *Config) (Storage, error) {
 	}
 
 	//streaming worker (queue reading)
-	ch.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, ch, chTableHelpers...)
+	ch.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, ch, config.dedupCache, config.eventPartitioner, config.schemaSampler, chTableHelpers...)
 	if err != nil {
 		return nil, err
 	}
@@ -117,40 +124,23 @@ func (ch *ClickHouse) Store(fileName string, objects []map[string]interface{}, a
 		return nil, nil, nil, err
 	}
 
-	//update cache with failed events
-	for _, failedEvent := range failedEvents.Events {
-		ch.eventsCache.Error(ch.IsCachingDisabled(), ch.ID(), failedEvent.EventID, failedEvent.Error)
-	}
-	//update cache and counter with skipped events
-	for _, skipEvent := range skippedEvents.Events {
-		ch.eventsCache.Skip(ch.IsCachingDisabled(), ch.ID(), skipEvent.EventID, skipEvent.Error)
-	}
+	//update cache with failed and skipped events
+	ch.cacheProcessingResults(failedEvents, skippedEvents)
 
 	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		adapter, tableHelper := ch.getAdapters()
 		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		start := time.Now()
 		err := ch.storeTable(adapter, tableHelper, fdata, table)
-		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
 		if err != nil {
 			storeFailedEvents = false
 		}
 
 		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				ch.eventsCache.Error(ch.IsCachingDisabled(), ch.ID(), ch.uniqueIDField.Extract(object), err.Error())
-			} else {
-				ch.eventsCache.Succeed(&adapters.EventContext{
-					CacheDisabled:  ch.IsCachingDisabled(),
-					DestinationID:  ch.ID(),
-					EventID:        ch.uniqueIDField.Extract(object),
-					ProcessedEvent: object,
-					Table:          table,
-				})
-			}
-		}
+		ch.cacheStoreResult(fdata.GetPayload(), table, err)
 	}
 
 	//store failed events to fallback only if other events have been inserted ok
@@ -164,6 +154,7 @@ func (ch *ClickHouse) Store(fileName string, objects []map[string]interface{}, a
 //check table schema
 //and store data into one table
 func (ch *ClickHouse) storeTable(adapter adapters.SQLAdapter, tableHelper *TableHelper, fdata *schema.ProcessedFile, table *adapters.Table) error {
+	ch.sample(table.Name, fdata.GetPayload())
 	dbSchema, err := tableHelper.EnsureTableWithoutCaching(ch.ID(), table)
 	if err != nil {
 		return err
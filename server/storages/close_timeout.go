@@ -0,0 +1,32 @@
+package storages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/safego"
+)
+
+//closeWithTimeout runs closeFunc and returns its error or timeout error if closeFunc hasn't finished in timeout
+//(closeFunc keeps running in the background: shutdown isn't blocked by a hung resource)
+//closeFunc is run without timeout if timeout isn't positive
+func closeWithTimeout(name string, timeout time.Duration, closeFunc func() error) error {
+	if timeout <= 0 {
+		return closeFunc()
+	}
+
+	result := make(chan error, 1)
+	safego.Run(func() {
+		result <- closeFunc()
+	})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%s hasn't been closed in %s", name, timeout)
+	}
+}
@@ -0,0 +1,24 @@
+package storages
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseWithTimeout(t *testing.T) {
+	require.NoError(t, closeWithTimeout("stage", time.Second, func() error { return nil }))
+	require.EqualError(t, closeWithTimeout("stage", time.Second, func() error { return errors.New("close error") }), "close error")
+
+	hung := make(chan struct{})
+	defer close(hung)
+	start := time.Now()
+	err := closeWithTimeout("stage", 50*time.Millisecond, func() error {
+		<-hung
+		return nil
+	})
+	require.EqualError(t, err, "stage hasn't been closed in 50ms")
+	require.Less(t, time.Since(start), 5*time.Second, "hung close shouldn't block")
+}
@@ -0,0 +1,273 @@
+package storages
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+const configSection = "config"
+
+//FieldError is a field level destination config validation error
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+//ValidationErrors is a list of field level destination config validation errors
+type ValidationErrors []*FieldError
+
+//Error returns all field errors joined in one message
+func (ve ValidationErrors) Error() string {
+	var messages []string
+	for _, fe := range ve {
+		messages = append(messages, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+//ConfigSchema is a declared schema of the destination type configuration (required fields and value ranges)
+//which is checked before the destination creation
+type ConfigSchema struct {
+	//section is a deprecated destination config section name (e.g. datasource) which is used if 'config' isn't set
+	section       string
+	compatibility func(destination *config.DestinationConfig) map[string]interface{}
+	fields        []*fieldSchema
+}
+
+//fieldSchema is a declared constraint of the destination config field
+type fieldSchema struct {
+	name     string
+	required bool
+	//min and max are checked only if ranged is true
+	ranged bool
+	min    float64
+	max    float64
+	//values is a list of allowed string values (if not empty)
+	values []string
+}
+
+func required(name string) *fieldSchema {
+	return &fieldSchema{name: name, required: true}
+}
+
+func ranged(name string, min, max float64) *fieldSchema {
+	return &fieldSchema{name: name, ranged: true, min: min, max: max}
+}
+
+func positive(name string) *fieldSchema {
+	return ranged(name, 0, math.MaxInt32)
+}
+
+func oneOf(name string, values ...string) *fieldSchema {
+	return &fieldSchema{name: name, values: values}
+}
+
+var (
+	dataSourceSchema = &ConfigSchema{
+		section:       "datasource",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.DataSource },
+		fields:        []*fieldSchema{required("host"), required("db"), required("username"), ranged("port", 0, 65535)},
+	}
+	clickHouseSchema = &ConfigSchema{
+		section:       "clickhouse",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.ClickHouse },
+		fields:        []*fieldSchema{required("dsns"), required("db")},
+	}
+	snowflakeSchema = &ConfigSchema{
+		section:       "snowflake",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.Snowflake },
+		fields: []*fieldSchema{required("account"), required("db"), required("username"), required("warehouse"),
+			ranged("port", 0, 65535),
+			oneOf("stage_format", adapters.StageFormatCSV, adapters.StageFormatJSON, adapters.StageFormatParquet),
+			oneOf("keep_stage_files", adapters.KeepStageFilesNever, adapters.KeepStageFilesOnError, adapters.KeepStageFilesAlways),
+			oneOf("table_sharding", adapters.TableShardingDaily, adapters.TableShardingMonthly),
+			oneOf("stage_selection", adapters.StageSelectionHash, adapters.StageSelectionRegion),
+			oneOf("timestamp_type", adapters.TimestampNTZ, adapters.TimestampTZ, adapters.TimestampLTZ),
+			positive("stage_files_ttl_hours"), positive("max_open_conns"), positive("max_idle_conns"),
+			positive("conn_max_lifetime_sec"), positive("query_timeout_sec"),
+			positive("copy_flush_rows"), positive("copy_flush_interval"), positive("update_batch_size"), positive("close_timeout_sec"),
+			positive("stage_file_max_rows"), positive("stage_upload_concurrency"),
+			positive("retention_days"), positive("retention_interval_hours"), positive("post_load_min_interval_sec")},
+	}
+	s3Schema = &ConfigSchema{
+		section:       "s3",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.S3 },
+		fields: []*fieldSchema{required("access_key_id"), required("secret_access_key"), required("bucket"), required("region"),
+			oneOf("format", string(adapters.S3FormatFlatJSON), string(adapters.S3FormatJSON), string(adapters.S3FormatCSV), string(adapters.S3FormatParquet)),
+			oneOf("compression", string(adapters.S3CompressionGZIP))},
+	}
+	bigQuerySchema = &ConfigSchema{
+		section:       "google",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.Google },
+		fields:        []*fieldSchema{required("key_file")},
+	}
+	webHookSchema = &ConfigSchema{
+		section:       "webhook",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.WebHook },
+		fields:        []*fieldSchema{required("url")},
+	}
+	amplitudeSchema = &ConfigSchema{
+		section:       "amplitude",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.Amplitude },
+		fields:        []*fieldSchema{required("api_key")},
+	}
+	facebookSchema = &ConfigSchema{
+		section:       "facebook",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.Facebook },
+		fields:        []*fieldSchema{required("pixel_id"), required("access_token")},
+	}
+	googleAnalyticsSchema = &ConfigSchema{
+		section:       "google_analytics",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.GoogleAnalytics },
+		fields:        []*fieldSchema{required("tracking_id")},
+	}
+	hubSpotSchema = &ConfigSchema{
+		section:       "hubspot",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.HubSpot },
+		fields:        []*fieldSchema{required("api_key"), required("hub_id")},
+	}
+	dbtCloudSchema = &ConfigSchema{
+		section:       "dbtcloud",
+		compatibility: func(destination *config.DestinationConfig) map[string]interface{} { return destination.DbtCloud },
+		fields:        []*fieldSchema{required("account_id"), required("job_id"), required("cause"), required("token")},
+	}
+)
+
+//ValidateDestination checks common destination config fields and the destination type config against
+//the declared ConfigSchema. Returns all field level errors (empty if the config is valid)
+func ValidateDestination(destinationID string, destination *config.DestinationConfig) ValidationErrors {
+	errs := ValidationErrors{}
+
+	destinationType := destination.Type
+	if destinationType == "" {
+		destinationType = destinationID
+	}
+	storageType, ok := StorageTypes[destinationType]
+	if !ok {
+		errs = append(errs, &FieldError{Field: "type", Message: fmt.Sprintf("%s: %s", ErrUnknownDestination.Error(), destinationType)})
+	}
+	if destination.Mode != "" && destination.Mode != BatchMode && destination.Mode != StreamMode {
+		errs = append(errs, &FieldError{Field: "mode", Message: fmt.Sprintf("must be one of [%s, %s]", BatchMode, StreamMode)})
+	}
+	if destination.DebugSampleRate < 0 || destination.DebugSampleRate > 1 {
+		errs = append(errs, &FieldError{Field: "debug_sample_rate", Message: "must be in range [0, 1]"})
+	}
+	if destination.MaxStoreLatencyMs < 0 {
+		errs = append(errs, &FieldError{Field: "max_store_latency_ms", Message: "must be positive"})
+	}
+	if destination.DataLayout != nil && destination.DataLayout.MaxColumns < 0 {
+		errs = append(errs, &FieldError{Field: "data_layout.max_columns", Message: "must be positive"})
+	}
+	if destination.DataLayout != nil {
+		if destination.DataLayout.MaxIdentifierLength < 0 {
+			errs = append(errs, &FieldError{Field: "data_layout.max_identifier_length", Message: "must be positive"})
+		}
+		if _, err := schema.NewIdentifierTruncator(destinationID, destination.DataLayout.IdentifierTruncation); err != nil {
+			errs = append(errs, &FieldError{Field: "data_layout.identifier_truncation", Message: fmt.Sprintf("must be one of [%s, %s]", schema.IdentifierTruncationCut, schema.IdentifierTruncationHash)})
+		}
+	}
+	if ok && destination.DataLayout != nil && (destination.DataLayout.TablePrefix != "" || destination.DataLayout.TableSuffix != "") && !storageType.isSQLType(destination) {
+		errs = append(errs, &FieldError{Field: "data_layout.table_prefix", Message: "table_prefix and table_suffix are supported only by SQL destinations"})
+	}
+
+	if !ok || storageType.configSchema == nil {
+		return errs
+	}
+
+	return append(errs, storageType.configSchema.validate(destination)...)
+}
+
+//validate checks the destination type config (inline 'config' or the deprecated section) against the schema fields
+func (cs *ConfigSchema) validate(destination *config.DestinationConfig) ValidationErrors {
+	section := configSection
+	values := destination.Config
+	if values == nil && cs.compatibility != nil {
+		section = cs.section
+		values = cs.compatibility(destination)
+	}
+
+	errs := ValidationErrors{}
+	for _, field := range cs.fields {
+		if err := field.validate(values[field.name]); err != "" {
+			errs = append(errs, &FieldError{Field: section + "." + field.name, Message: err})
+		}
+	}
+
+	return errs
+}
+
+//validate returns error message if the value doesn't match the field constraints
+func (fs *fieldSchema) validate(value interface{}) string {
+	if isEmptyValue(value) {
+		if fs.required {
+			return "is required"
+		}
+		return ""
+	}
+
+	if fs.ranged {
+		number, ok := toNumber(value)
+		if !ok {
+			return fmt.Sprintf("must be a number, got: %v", value)
+		}
+		if number < fs.min || number > fs.max {
+			return fmt.Sprintf("must be in range [%s, %s]", strconv.FormatFloat(fs.min, 'f', -1, 64), strconv.FormatFloat(fs.max, 'f', -1, 64))
+		}
+	}
+
+	if len(fs.values) > 0 {
+		str := fmt.Sprint(value)
+		for _, v := range fs.values {
+			if str == v {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s]", strings.Join(fs.values, ", "))
+	}
+
+	return ""
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+//toNumber converts numeric values (including numbers as strings for backward compatibility) into float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,82 @@
+package storages
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDestination(t *testing.T) {
+	tests := []struct {
+		name          string
+		destinationID string
+		destination   *config.DestinationConfig
+		expected      ValidationErrors
+	}{
+		{
+			"valid inline config",
+			"pg",
+			&config.DestinationConfig{Type: PostgresType, Config: map[string]interface{}{"host": "localhost", "db": "db", "username": "user", "port": 5432}},
+			ValidationErrors{},
+		},
+		{
+			"valid deprecated section with port as string",
+			"pg",
+			&config.DestinationConfig{Type: PostgresType, DataSource: map[string]interface{}{"host": "localhost", "db": "db", "username": "user", "port": "5432"}},
+			ValidationErrors{},
+		},
+		{
+			"type from destination ID",
+			WebHookType,
+			&config.DestinationConfig{WebHook: map[string]interface{}{"url": "https://jitsu.com"}},
+			ValidationErrors{},
+		},
+		{
+			"unknown type and mode",
+			"dest",
+			&config.DestinationConfig{Type: "unknown", Mode: "realtime"},
+			ValidationErrors{
+				{Field: "type", Message: "Unknown destination type: unknown"},
+				{Field: "mode", Message: "must be one of [batch, stream]"},
+			},
+		},
+		{
+			"missing fields and out of range values",
+			"pg",
+			&config.DestinationConfig{Type: PostgresType, DebugSampleRate: 2, MaxStoreLatencyMs: -1, DataSource: map[string]interface{}{"host": "localhost", "port": 70000}},
+			ValidationErrors{
+				{Field: "debug_sample_rate", Message: "must be in range [0, 1]"},
+				{Field: "max_store_latency_ms", Message: "must be positive"},
+				{Field: "datasource.db", Message: "is required"},
+				{Field: "datasource.username", Message: "is required"},
+				{Field: "datasource.port", Message: "must be in range [0, 65535]"},
+			},
+		},
+		{
+			"identifier truncation",
+			"pg",
+			&config.DestinationConfig{Type: PostgresType, DataLayout: &config.DataLayout{IdentifierTruncation: "md5", MaxIdentifierLength: -1},
+				Config: map[string]interface{}{"host": "localhost", "db": "db", "username": "user"}},
+			ValidationErrors{
+				{Field: "data_layout.max_identifier_length", Message: "must be positive"},
+				{Field: "data_layout.identifier_truncation", Message: "must be one of [cut, hash]"},
+			},
+		},
+		{
+			"not allowed values",
+			"sf",
+			&config.DestinationConfig{Type: SnowflakeType, Config: map[string]interface{}{"account": "a", "db": "db", "username": "user",
+				"warehouse": "w", "stage_format": "avro", "query_timeout_sec": -1}},
+			ValidationErrors{
+				{Field: "config.stage_format", Message: "must be one of [csv, json, parquet]"},
+				{Field: "config.query_timeout_sec", Message: "must be in range [0, 2147483647]"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, ValidateDestination(tt.destinationID, tt.destination))
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package storages
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+//copyBatchesFolder is a stage folder where accumulated files are uploaded (every batch has own subfolder)
+const copyBatchesFolder = "jitsu_copy_batches"
+
+//ErrCopyPending is returned for tables which have been uploaded into stage and are waiting for the batched COPY:
+//the batch file must be stored again later (it isn't archived) to get the COPY result
+var ErrCopyPending = errors.New("table is waiting for the batched COPY")
+
+//errCopyBatchDiscarded is a result of tables from batches which have been discarded on Close
+var errCopyBatchDiscarded = errors.New("batched COPY has been discarded on the destination close")
+
+//copyBatch is a group of stage files of the same table (and csv header) under a common stage prefix
+//which are loaded with a single COPY
+type copyBatch struct {
+	prefix string
+	table  *adapters.Table
+	header []string
+	files  []*stagedFile
+	rows   int
+
+	//uploads is a number of uploads into the batch prefix which are in progress
+	uploads sync.WaitGroup
+	//done is closed when the batch has been flushed (err is the COPY result) or discarded
+	done chan struct{}
+	err  error
+}
+
+//stagedFile is an uploaded stage file with source data (is used for errors attribution)
+type stagedFile struct {
+	key   string
+	fdata *schema.ProcessedFile
+}
+
+//copyBatcher accumulates small stage files and flushes them with flushFunc
+//when a batch has flushRows rows or every flushInterval
+//tables are reported as stored only after the batch COPY has been committed: add registers the table as pending
+//and result returns ErrCopyPending until the batch is flushed. On Close not flushed batches are passed to discardFunc
+//because their source files are stored again (into a new batch) after the restart
+type copyBatcher struct {
+	flushRows     int
+	flushInterval time.Duration
+	flushFunc     func(batch *copyBatch) error
+	discardFunc   func(batch *copyBatch)
+
+	mutex   *sync.Mutex
+	batches map[string]*copyBatch
+	//pending is a batch per stored table (file name and table name)
+	pending map[string]*copyBatch
+
+	closed  chan struct{}
+	stopped chan struct{}
+}
+
+//newCopyBatcher returns configured copyBatcher and starts background goroutine for flushing by time
+func newCopyBatcher(flushRows int, flushInterval time.Duration, flushFunc func(batch *copyBatch) error, discardFunc func(batch *copyBatch)) *copyBatcher {
+	cb := &copyBatcher{
+		flushRows:     flushRows,
+		flushInterval: flushInterval,
+		flushFunc:     flushFunc,
+		discardFunc:   discardFunc,
+		mutex:         &sync.Mutex{},
+		batches:       map[string]*copyBatch{},
+		pending:       map[string]*copyBatch{},
+		closed:        make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	cb.start()
+	return cb
+}
+
+func (cb *copyBatcher) start() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(cb.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-cb.closed:
+				close(cb.stopped)
+				return
+			case <-ticker.C:
+				cb.flushAll()
+			}
+		}
+	})
+}
+
+//result returns true and the batch COPY result of the table from the file if the table has been added into a batch:
+//ErrCopyPending if the batch hasn't been flushed yet, nil if COPY has been committed or COPY error
+//the table is unregistered when the result is returned
+func (cb *copyBatcher) result(fdata *schema.ProcessedFile) (bool, error) {
+	key := pendingKey(fdata)
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	batch, ok := cb.pending[key]
+	if !ok {
+		return false, nil
+	}
+
+	select {
+	case <-batch.done:
+		delete(cb.pending, key)
+		return true, batch.err
+	default:
+		return true, ErrCopyPending
+	}
+}
+
+//add uploads the file with uploadFunc under the current batch prefix and adds it into the batch (the upload is run without the lock)
+//the table is registered as pending (see result) and the batch is flushed if it contains flushRows or more rows
+//returns uploadFunc error
+func (cb *copyBatcher) add(table *adapters.Table, header []string, fdata *schema.ProcessedFile, uploadFunc func(key string) error) error {
+	batchKey := table.Name + "|" + strings.Join(header, ",")
+
+	cb.mutex.Lock()
+	batch, ok := cb.batches[batchKey]
+	if !ok {
+		batch = &copyBatch{prefix: copyBatchesFolder + "/" + table.Name + "/" + uuid.New(), header: header, done: make(chan struct{})}
+		cb.batches[batchKey] = batch
+	}
+	//batch isn't flushed until the upload is finished
+	batch.uploads.Add(1)
+	cb.mutex.Unlock()
+
+	key := batch.prefix + "/" + fdata.FileName
+	err := uploadFunc(key)
+
+	cb.mutex.Lock()
+	var full *copyBatch
+	if err == nil {
+		//table might have new columns
+		batch.table = table
+		batch.files = append(batch.files, &stagedFile{key: key, fdata: fdata})
+		batch.rows += fdata.GetPayloadLen()
+		cb.pending[pendingKey(fdata)] = batch
+
+		if batch.rows >= cb.flushRows && cb.batches[batchKey] == batch {
+			full = batch
+			delete(cb.batches, batchKey)
+		}
+	}
+	batch.uploads.Done()
+	cb.mutex.Unlock()
+
+	if full != nil {
+		cb.flush(full)
+	}
+
+	return err
+}
+
+//flush waits for the batch uploads and runs flushFunc. Batch without files (all uploads have failed) isn't flushed
+func (cb *copyBatcher) flush(batch *copyBatch) {
+	batch.uploads.Wait()
+	if len(batch.files) > 0 {
+		batch.err = cb.flushFunc(batch)
+	}
+	close(batch.done)
+}
+
+//flushAll flushes all accumulated batches
+func (cb *copyBatcher) flushAll() {
+	for _, batch := range cb.takeAll() {
+		cb.flush(batch)
+	}
+}
+
+//takeAll removes all accumulated batches from the batcher and returns them
+func (cb *copyBatcher) takeAll() map[string]*copyBatch {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	batches := cb.batches
+	cb.batches = map[string]*copyBatch{}
+	return batches
+}
+
+//Close stops background goroutine and discards all accumulated batches: their tables are still pending,
+//source files haven't been archived and will be stored again
+func (cb *copyBatcher) Close() {
+	close(cb.closed)
+	<-cb.stopped
+
+	for _, batch := range cb.takeAll() {
+		batch.uploads.Wait()
+		if len(batch.files) > 0 {
+			cb.discardFunc(batch)
+		}
+		batch.err = NewStoreError(ErrTransient, "", errCopyBatchDiscarded)
+		close(batch.done)
+	}
+}
+
+//pendingKey returns registration key of the table from the batch file
+func pendingKey(fdata *schema.ProcessedFile) string {
+	return fdata.FileName + "|" + fdata.BatchHeader.TableName
+}
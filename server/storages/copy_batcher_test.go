@@ -0,0 +1,131 @@
+package storages
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyBatcher(t *testing.T) {
+	var flushed []*copyBatch
+	batcher := newCopyBatcher(1, time.Hour, func(batch *copyBatch) error {
+		flushed = append(flushed, batch)
+		return nil
+	}, func(batch *copyBatch) {})
+	defer batcher.Close()
+
+	var uploaded []string
+	upload := func(key string) error {
+		uploaded = append(uploaded, key)
+		return nil
+	}
+
+	table := &adapters.Table{Name: "events"}
+	require.NoError(t, batcher.add(table, []string{"id"}, testBatchFile("file1", "events"), upload))
+	require.NoError(t, batcher.add(table, []string{"id"}, testBatchFile("file2", "events"), upload))
+	require.NoError(t, batcher.add(table, []string{"id", "name"}, testBatchFile("file3", "events"), upload))
+	require.EqualError(t, batcher.add(table, []string{"id"}, testBatchFile("file4", "events"), func(key string) error {
+		return errors.New("upload error")
+	}), "upload error")
+	require.Equal(t, 0, len(flushed), "batches without enough rows shouldn't be flushed")
+
+	registered, err := batcher.result(testBatchFile("file1", "events"))
+	require.True(t, registered)
+	require.Equal(t, ErrCopyPending, err)
+	registered, _ = batcher.result(testBatchFile("file4", "events"))
+	require.False(t, registered, "file which hasn't been uploaded shouldn't be pending")
+
+	batcher.flushAll()
+	require.Equal(t, 2, len(flushed))
+
+	filesPerHeader := map[string]int{}
+	for _, batch := range flushed {
+		filesPerHeader[strings.Join(batch.header, ",")] = len(batch.files)
+		for _, file := range batch.files {
+			require.True(t, strings.HasPrefix(file.key, batch.prefix+"/"), "file must be under the batch prefix")
+		}
+	}
+	require.Equal(t, map[string]int{"id": 2, "id,name": 1}, filesPerHeader)
+	require.Equal(t, 3, len(uploaded))
+
+	registered, err = batcher.result(testBatchFile("file1", "events"))
+	require.True(t, registered)
+	require.NoError(t, err, "table should be reported as stored after the batch COPY")
+	registered, _ = batcher.result(testBatchFile("file1", "events"))
+	require.False(t, registered, "result should be returned only once")
+}
+
+func TestCopyBatcherFlushError(t *testing.T) {
+	batcher := newCopyBatcher(1, time.Hour, func(batch *copyBatch) error {
+		return errors.New("copy error")
+	}, func(batch *copyBatch) {})
+	defer batcher.Close()
+
+	table := &adapters.Table{Name: "events"}
+	fdata := testBatchFile("file1", "events")
+	require.NoError(t, batcher.add(table, []string{"id"}, fdata, func(key string) error { return nil }))
+	batcher.flushAll()
+
+	registered, err := batcher.result(fdata)
+	require.True(t, registered)
+	require.EqualError(t, err, "copy error", "COPY error should be returned as the table result")
+}
+
+func TestCopyBatcherUploadWithoutLock(t *testing.T) {
+	batcher := newCopyBatcher(100, time.Hour, func(batch *copyBatch) error { return nil }, func(batch *copyBatch) {})
+	defer batcher.Close()
+
+	table := &adapters.Table{Name: "events"}
+	uploading := make(chan struct{})
+	release := make(chan struct{})
+	slowUpload := make(chan error, 1)
+	go func() {
+		slowUpload <- batcher.add(table, []string{"id"}, testBatchFile("slow", "events"), func(key string) error {
+			close(uploading)
+			<-release
+			return nil
+		})
+	}()
+	<-uploading
+
+	fastUpload := make(chan error, 1)
+	go func() {
+		fastUpload <- batcher.add(table, []string{"id"}, testBatchFile("fast", "events"), func(key string) error { return nil })
+	}()
+
+	select {
+	case err := <-fastUpload:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload shouldn't be blocked by another upload")
+	}
+
+	close(release)
+	require.NoError(t, <-slowUpload)
+}
+
+func TestCopyBatcherClose(t *testing.T) {
+	var discarded []*copyBatch
+	batcher := newCopyBatcher(100, time.Hour, func(batch *copyBatch) error {
+		t.Fatal("batch shouldn't be flushed on close")
+		return nil
+	}, func(batch *copyBatch) { discarded = append(discarded, batch) })
+
+	fdata := testBatchFile("file1", "events")
+	require.NoError(t, batcher.add(&adapters.Table{Name: "events"}, []string{"id"}, fdata, func(key string) error { return nil }))
+	batcher.Close()
+
+	require.Equal(t, 1, len(discarded))
+	registered, err := batcher.result(fdata)
+	require.True(t, registered)
+	require.True(t, IsTransientError(err), "discarded table should be stored again")
+}
+
+func testBatchFile(fileName, tableName string) *schema.ProcessedFile {
+	return &schema.ProcessedFile{FileName: fileName, BatchHeader: &schema.BatchHeader{TableName: tableName}}
+}
@@ -23,7 +23,7 @@ type DbtCloud struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: DbtCloudType, createFunc: NewDbtCloud, defaultTableName: dbtCloudTableNameFilter, isSQL: false})
+	RegisterStorage(StorageType{typeName: DbtCloudType, createFunc: NewDbtCloud, defaultTableName: dbtCloudTableNameFilter, isSQL: false, configSchema: dbtCloudSchema})
 }
 
 //NewDbtCloud returns configured DbtCloud destination
@@ -62,6 +62,7 @@ func NewDbtCloud(config *Config) (Storage, error) {
 	dbt.destinationID = config.destinationID
 	dbt.processor = config.processor
 	dbt.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	dbt.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	dbt.eventsCache = config.eventsCache
 	dbt.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	dbt.uniqueIDField = config.uniqueIDField
@@ -69,7 +70,7 @@ func NewDbtCloud(config *Config) (Storage, error) {
 	dbt.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	dbt.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, dbt, tableHelper)
+	dbt.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, dbt, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,97 @@
+package storages
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	defaultDedupWindow  = 5 * time.Minute
+	defaultDedupMaxSize = 100_000
+)
+
+type dedupEntry struct {
+	eventID string
+	seenAt  time.Time
+}
+
+//dedupCache is an in-memory LRU cache of recently stored event IDs
+//entries are expired after window or evicted (the least recently seen) when maxSize is reached
+type dedupCache struct {
+	destinationType string
+	destinationID   string
+	window          time.Duration
+	maxSize         int
+
+	mutex   *sync.Mutex
+	entries map[string]*list.Element
+	//front is the most recently seen
+	order *list.List
+}
+
+//newDedupCache returns configured dedupCache. Default values are used for not positive window and maxSize
+func newDedupCache(destinationType, destinationID string, window time.Duration, maxSize int) *dedupCache {
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	if maxSize <= 0 {
+		maxSize = defaultDedupMaxSize
+	}
+
+	return &dedupCache{
+		destinationType: destinationType,
+		destinationID:   destinationID,
+		window:          window,
+		maxSize:         maxSize,
+		mutex:           &sync.Mutex{},
+		entries:         map[string]*list.Element{},
+		order:           list.New(),
+	}
+}
+
+//contains returns true if eventID has been seen within the window and writes hit/miss metrics
+func (dc *dedupCache) contains(eventID string) bool {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	element, ok := dc.entries[eventID]
+	if ok && timestamp.Now().Sub(element.Value.(*dedupEntry).seenAt) >= dc.window {
+		dc.remove(element)
+		ok = false
+	}
+
+	if ok {
+		metrics.DedupHit(dc.destinationType, dc.destinationID)
+	} else {
+		metrics.DedupMiss(dc.destinationType, dc.destinationID)
+	}
+
+	return ok
+}
+
+//add puts eventID into the cache and evicts the least recently seen entries if maxSize is reached
+func (dc *dedupCache) add(eventID string) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	now := timestamp.Now()
+	if element, ok := dc.entries[eventID]; ok {
+		element.Value.(*dedupEntry).seenAt = now
+		dc.order.MoveToFront(element)
+		return
+	}
+
+	dc.entries[eventID] = dc.order.PushFront(&dedupEntry{eventID: eventID, seenAt: now})
+	for dc.order.Len() > dc.maxSize {
+		dc.remove(dc.order.Back())
+	}
+}
+
+func (dc *dedupCache) remove(element *list.Element) {
+	dc.order.Remove(element)
+	delete(dc.entries, element.Value.(*dedupEntry).eventID)
+}
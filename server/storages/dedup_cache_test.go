@@ -0,0 +1,29 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupCache(t *testing.T) {
+	cache := newDedupCache("postgres", "test", time.Minute, 2)
+
+	require.False(t, cache.contains("id1"))
+	cache.add("id1")
+	require.True(t, cache.contains("id1"))
+
+	//the least recently seen is evicted
+	cache.add("id2")
+	cache.add("id3")
+	require.False(t, cache.contains("id1"))
+	require.True(t, cache.contains("id2"))
+	require.True(t, cache.contains("id3"))
+
+	//expired after window
+	cache.entries["id2"].Value.(*dedupEntry).seenAt = time.Now().Add(-2 * time.Minute)
+	require.False(t, cache.contains("id2"))
+	require.Len(t, cache.entries, 1)
+	require.Equal(t, 1, cache.order.Len())
+}
@@ -0,0 +1,89 @@
+package storages
+
+import (
+	"errors"
+
+	"github.com/jitsucom/jitsu/server/dlq"
+)
+
+var (
+	//ErrTransient is a kind of temporary store errors (e.g. connection problems, timeouts, expired sessions)
+	//data should be retried later
+	ErrTransient = errors.New("transient store error")
+	//ErrBadData is a kind of store errors caused by data (e.g. values which don't match column types)
+	//retries won't help: data should be routed to fallback
+	ErrBadData = errors.New("bad data store error")
+	//ErrConfig is a kind of store errors caused by broken authorization or configuration
+	//(e.g. wrong credentials, not existing objects or missing permissions)
+	ErrConfig = errors.New("configuration store error")
+	//ErrTimeout is a kind of store errors caused by exceeded store timeout (e.g. a stuck warehouse)
+	//data is routed to fallback and can be replayed when the destination is available again
+	ErrTimeout = errors.New("timeout store error")
+	//ErrRetentionNotSupported is returned by CleanOlderThan of storages which can't delete rows by age
+	ErrRetentionNotSupported = errors.New("deleting rows older than the retention age isn't supported by the destination")
+)
+
+//StoreError is a classified store error. The kind is matched with errors.Is(err, ErrTransient|ErrBadData|ErrConfig|ErrTimeout)
+//and the original error is available with errors.Unwrap or errors.As
+type StoreError struct {
+	kind error
+	msg  string
+	err  error
+}
+
+//NewStoreError returns StoreError with the kind and the original error or nil if err is nil
+//msg is used as the error message prefix if it isn't empty
+func NewStoreError(kind error, msg string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &StoreError{kind: kind, msg: msg, err: err}
+}
+
+//Error returns error message
+func (se *StoreError) Error() string {
+	if se.msg == "" {
+		return se.err.Error()
+	}
+
+	return se.msg + ": " + se.err.Error()
+}
+
+//Unwrap returns the original error
+func (se *StoreError) Unwrap() error {
+	return se.err
+}
+
+//Is returns true if target is the kind of the error
+func (se *StoreError) Is(target error) bool {
+	return se.kind == target
+}
+
+//Kind returns ErrTransient, ErrBadData, ErrConfig or ErrTimeout
+func (se *StoreError) Kind() error {
+	return se.kind
+}
+
+//IsTransientError returns true if err is classified as ErrTransient or is a connection error
+func IsTransientError(err error) bool {
+	return err != nil && (errors.Is(err, ErrTransient) || IsConnectionError(err))
+}
+
+//ClassifyError returns DLQ classification of the store error
+func ClassifyError(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrTimeout):
+		return dlq.ClassificationTransient
+	case errors.Is(err, ErrBadData):
+		return dlq.ClassificationBadData
+	case errors.Is(err, ErrConfig):
+		return dlq.ClassificationConfig
+	case IsTransientError(err):
+		return dlq.ClassificationTransient
+	default:
+		return dlq.ClassificationUnknown
+	}
+}
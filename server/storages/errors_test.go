@@ -0,0 +1,36 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type originalTestError struct {
+	code int
+}
+
+func (ote *originalTestError) Error() string {
+	return fmt.Sprintf("code: %d", ote.code)
+}
+
+func TestStoreError(t *testing.T) {
+	require.NoError(t, NewStoreError(ErrTransient, "msg", nil))
+
+	err := NewStoreError(ErrBadData, "Error copying file [file1]", &originalTestError{code: 100038})
+	require.EqualError(t, err, "Error copying file [file1]: code: 100038")
+	require.True(t, errors.Is(err, ErrBadData))
+	require.False(t, errors.Is(err, ErrTransient))
+	require.False(t, errors.Is(err, ErrConfig))
+	require.False(t, IsTransientError(err))
+
+	var original *originalTestError
+	require.True(t, errors.As(err, &original))
+	require.Equal(t, 100038, original.code)
+
+	require.True(t, IsTransientError(NewStoreError(ErrTransient, "", errors.New("session expired"))))
+	require.True(t, IsTransientError(errors.New("dial tcp: connection refused")))
+	require.False(t, IsTransientError(nil))
+}
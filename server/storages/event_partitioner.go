@@ -0,0 +1,64 @@
+package storages
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+const (
+	defaultOrderingPartitions = 4
+	//partitionQueueSize is a max count of dispatched but not processed events per partition
+	partitionQueueSize = 100
+)
+
+//eventPartitioner chooses streaming partition by the event key: events with the same key
+//are always dispatched to the same partition
+type eventPartitioner struct {
+	partitions    int
+	partitionKey  jsonutils.JSONPath
+	uniqueIDField *identifiers.UniqueID
+}
+
+//newEventPartitioner returns configured eventPartitioner. Default value is used for not positive partitions
+//partitionKey is a JSON path of the key. The unique ID field is used if partitionKey is empty
+func newEventPartitioner(partitions int, partitionKey string, uniqueIDField *identifiers.UniqueID) *eventPartitioner {
+	if partitions <= 0 {
+		partitions = defaultOrderingPartitions
+	}
+
+	ep := &eventPartitioner{partitions: partitions, uniqueIDField: uniqueIDField}
+	if partitionKey != "" {
+		ep.partitionKey = jsonutils.NewJSONPath(partitionKey)
+	}
+	return ep
+}
+
+//partition returns partition number of the event
+//events without key are dispatched to a random partition
+func (ep *eventPartitioner) partition(event events.Event) int {
+	key := ep.key(event)
+	if key == "" {
+		return rand.Intn(ep.partitions)
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(ep.partitions))
+}
+
+func (ep *eventPartitioner) key(event events.Event) string {
+	if ep.partitionKey == nil {
+		return ep.uniqueIDField.Extract(event)
+	}
+
+	value, ok := ep.partitionKey.Get(event)
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
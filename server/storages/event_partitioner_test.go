@@ -0,0 +1,31 @@
+package storages
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventPartitioner(t *testing.T) {
+	uniqueIDField := identifiers.NewUniqueID("/eventn_ctx/event_id")
+
+	byUniqueID := newEventPartitioner(0, "", uniqueIDField)
+	require.Equal(t, defaultOrderingPartitions, byUniqueID.partitions)
+	for i := 0; i < 10; i++ {
+		require.Equal(t, byUniqueID.partition(events.Event{"eventn_ctx": map[string]interface{}{"event_id": "id1"}, "n": i}),
+			byUniqueID.partition(events.Event{"eventn_ctx": map[string]interface{}{"event_id": "id1"}}), "Events with the same key must be in the same partition")
+	}
+
+	byKey := newEventPartitioner(16, "/user/id", uniqueIDField)
+	partitions := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		partition := byKey.partition(events.Event{"user": map[string]interface{}{"id": i}, "eventn_ctx": map[string]interface{}{"event_id": "id1"}})
+		require.True(t, partition >= 0 && partition < 16)
+		partitions[partition] = true
+	}
+	require.True(t, len(partitions) > 1, "Events with different keys must be distributed between partitions")
+
+	require.Equal(t, byKey.partition(events.Event{"user": map[string]interface{}{"id": 42}}), byKey.partition(events.Event{"user": map[string]interface{}{"id": 42}, "event_type": "update"}))
+}
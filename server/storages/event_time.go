@@ -0,0 +1,55 @@
+package storages
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//eventTimeResolver returns the event time from the configured timestamp_field of processed (flattened) objects
+//ingestion time (_timestamp or the current time) is used if the field is missing or can't be parsed
+type eventTimeResolver struct {
+	destinationType string
+	destinationID   string
+	field           string
+}
+
+//newEventTimeResolver returns eventTimeResolver of the field (_timestamp if empty)
+func newEventTimeResolver(destinationType, destinationID, field string) *eventTimeResolver {
+	if field == "" {
+		field = timestamp.Key
+	}
+
+	return &eventTimeResolver{destinationType: destinationType, destinationID: destinationID, field: field}
+}
+
+//resolve returns UTC event time and false if ingestion time has been used instead of timestamp_field value
+func (etr *eventTimeResolver) resolve(object map[string]interface{}) (time.Time, bool) {
+	if t, ok := timestamp.FromField(object, etr.field); ok {
+		return t, true
+	}
+
+	if etr.field != timestamp.Key {
+		if t, ok := timestamp.FromField(object, timestamp.Key); ok {
+			return t, false
+		}
+	}
+
+	return timestamp.Now().UTC(), false
+}
+
+//eventTime returns UTC event time (see resolve) and counts ingestion time fallbacks in the metric
+func (etr *eventTimeResolver) eventTime(object map[string]interface{}) time.Time {
+	t, ok := etr.resolve(object)
+	if !ok {
+		etr.fallback()
+	}
+
+	return t
+}
+
+//fallback counts the event which timestamp_field value hasn't been used
+func (etr *eventTimeResolver) fallback() {
+	metrics.EventTimestampFallback(etr.destinationType, etr.destinationID)
+}
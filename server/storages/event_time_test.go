@@ -0,0 +1,40 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventTimeResolver(t *testing.T) {
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	ingestedAt := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)
+	resolver := newEventTimeResolver(SnowflakeType, "test", "created_at")
+
+	eventTime, ok := resolver.resolve(map[string]interface{}{"created_at": "2023-12-31 23:00:00", timestamp.Key: ingestedAt})
+	require.True(t, ok)
+	require.Equal(t, createdAt, eventTime)
+
+	eventTime, ok = resolver.resolve(map[string]interface{}{"created_at": createdAt.UnixMilli()})
+	require.True(t, ok)
+	require.Equal(t, createdAt, eventTime)
+
+	eventTime, ok = resolver.resolve(map[string]interface{}{"created_at": "yesterday", timestamp.Key: ingestedAt})
+	require.False(t, ok, "unparseable field should fall back to ingestion time")
+	require.Equal(t, ingestedAt, eventTime)
+
+	eventTime, ok = resolver.resolve(map[string]interface{}{})
+	require.False(t, ok)
+	require.Equal(t, timestamp.Now().UTC(), eventTime, "current time should be used without ingestion time")
+
+	sharder, err := NewTableSharder(adapters.TableShardingDaily, resolver.eventTime)
+	require.NoError(t, err)
+	require.Equal(t, "events_20231231", sharder.ShardTableName("events", map[string]interface{}{"created_at": createdAt, timestamp.Key: ingestedAt}))
+	require.Equal(t, "events_20240103", sharder.ShardTableName("events", map[string]interface{}{timestamp.Key: ingestedAt}))
+}
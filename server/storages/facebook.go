@@ -16,7 +16,7 @@ type Facebook struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: FacebookType, createFunc: NewFacebook, isSQL: false})
+	RegisterStorage(StorageType{typeName: FacebookType, createFunc: NewFacebook, isSQL: false, configSchema: facebookSchema})
 }
 
 //NewFacebook returns configured Facebook destination
@@ -63,6 +63,7 @@ func NewFacebook(config *Config) (Storage, error) {
 	fb.destinationID = config.destinationID
 	fb.processor = config.processor
 	fb.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	fb.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	fb.eventsCache = config.eventsCache
 	fb.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	fb.uniqueIDField = config.uniqueIDField
@@ -70,7 +71,7 @@ func NewFacebook(config *Config) (Storage, error) {
 	fb.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	fb.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, fb, tableHelper)
+	fb.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, fb, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/caching"
@@ -20,6 +22,7 @@ import (
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/typing"
+	"go.uber.org/atomic"
 )
 
 const (
@@ -66,7 +69,14 @@ type Config struct {
 	uniqueIDField          *identifiers.UniqueID
 	mappingsStyle          string
 	logEventPath           string
+	dedupCache             *dedupCache
+	eventPartitioner       *eventPartitioner
+	schemaSampler          *schemaSampler
+	tablePrefix            string
+	tableSuffix            string
 	PostHandleDestinations []string
+	//paused is shared between the proxy and the events queue: writes are stopped while it is true
+	paused *atomic.Bool
 }
 
 //RegisterStorage registers function to create new storage(destination) instance
@@ -78,6 +88,7 @@ func RegisterStorage(storageType StorageType) {
 type Factory interface {
 	Create(name string, destination config.DestinationConfig) (StorageProxy, events.Queue, error)
 	Configure(destinationID string, destination config.DestinationConfig) (func(config *Config) (Storage, error), *Config, error)
+	Preview(destinationID string, destination config.DestinationConfig, objects []map[string]interface{}) (*PreviewResult, error)
 }
 
 type StorageType struct {
@@ -86,6 +97,8 @@ type StorageType struct {
 	defaultTableName string
 	isSQL            bool
 	isSQLFunc        func(config *config.DestinationConfig) bool
+	//configSchema is used for validation of the destination config before creation (optional)
+	configSchema *ConfigSchema
 }
 
 func (storageType StorageType) isSQLType(destCfg *config.DestinationConfig) bool {
@@ -135,6 +148,11 @@ func (f *FactoryImpl) Create(destinationID string, destination config.Destinatio
 		return nil, nil, err
 	}
 	storageProxy := newProxy(createFunc, config)
+	if destination.LazyInit && config.streamMode {
+		//streaming worker is started with the storage: the storage is created on the first event
+		return storageProxy, &lazyQueue{Queue: config.eventQueue, proxy: storageProxy.(*RetryableProxy)}, nil
+	}
+
 	return storageProxy, config.eventQueue, nil
 }
 
@@ -155,7 +173,9 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 	}
 	pkFields := map[string]bool{}
 	maxColumns := f.maxColumns
+	maxEventBytes := appconfig.Instance.MaxEventBytes
 	uniqueIDField := appconfig.Instance.GlobalUniqueIDField
+	var tablePrefix, tableSuffix string
 	if destination.DataLayout != nil {
 		for _, field := range destination.DataLayout.PrimaryKeyFields {
 			pkFields[field] = true
@@ -164,8 +184,16 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 			maxColumns = destination.DataLayout.MaxColumns
 			logging.Infof("[%s] uses max_columns setting: %d", destinationID, maxColumns)
 		}
+		if destination.DataLayout.MaxEventBytes > 0 {
+			maxEventBytes = destination.DataLayout.MaxEventBytes
+			logging.Infof("[%s] uses max_event_bytes setting: %d", destinationID, maxEventBytes)
+		}
 		if destination.DataLayout.UniqueIDField != "" {
-			uniqueIDField = identifiers.NewUniqueID(destination.DataLayout.UniqueIDField)
+			uniqueIDField = uniqueIDField.WithField(destination.DataLayout.UniqueIDField)
+		}
+		tablePrefix, tableSuffix = destination.DataLayout.TablePrefix, destination.DataLayout.TableSuffix
+		if tablePrefix != "" || tableSuffix != "" {
+			logging.Infof("[%s] table names will be prefixed with [%s] and suffixed with [%s]", destinationID, tablePrefix, tableSuffix)
 		}
 	}
 	if len(pkFields) > 0 {
@@ -184,6 +212,7 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 	if err != nil {
 		return nil, nil, err
 	}
+	paused := atomic.NewBool(false)
 
 	//override debug sql (ddl, queries) loggers from the destination config
 	destinationLoggerFactory := f.globalLoggerFactory
@@ -214,6 +243,55 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 		return nil, nil, err
 	}
 
+	if destination.DataLayout != nil && destination.DataLayout.OnMaxColumns != "" {
+		if err := processor.SetColumnsLimit(maxColumns, destination.DataLayout.OnMaxColumns); err != nil {
+			return nil, nil, err
+		}
+		logging.Infof("[%s] events which exceed max_columns (%d) are handled with on_max_columns strategy: %s", destinationID, maxColumns, destination.DataLayout.OnMaxColumns)
+	}
+	processor.SetMaxEventBytes(maxEventBytes)
+
+	if destination.DataLayout != nil && destination.DataLayout.OnConflict != "" {
+		if err := adapters.ValidateOnConflict(destination.DataLayout.OnConflict); err != nil {
+			return nil, nil, err
+		}
+		if destination.Type != SnowflakeType {
+			return nil, nil, fmt.Errorf("on_conflict is supported only by %s destinations", SnowflakeType)
+		}
+	}
+
+	var streamDedupCache *dedupCache
+	if destination.Deduplication.IsEnabled() {
+		if destination.Mode == StreamMode {
+			streamDedupCache = newDedupCache(destination.Type, destinationID, time.Duration(destination.Deduplication.WindowSeconds)*time.Second, destination.Deduplication.MaxSize)
+			logging.Infof("[%s] events deduplication is enabled with window: %s", destinationID, streamDedupCache.window)
+		} else {
+			logging.Warnf("[%s] events deduplication is supported only in %s mode", destinationID, StreamMode)
+		}
+	}
+
+	var streamEventPartitioner *eventPartitioner
+	if destination.Ordering.IsEnabled() {
+		if destination.Mode == StreamMode {
+			streamEventPartitioner = newEventPartitioner(destination.Ordering.Partitions, destination.Ordering.PartitionKey, uniqueIDField)
+		} else {
+			logging.Warnf("[%s] events ordering is supported only in %s mode", destinationID, StreamMode)
+		}
+	}
+
+	var streamSchemaSampler *schemaSampler
+	if destination.DataLayout != nil && destination.DataLayout.SchemaInferenceSample > 0 {
+		switch {
+		case destination.Mode != StreamMode:
+			logging.Warnf("[%s] schema inference is supported only in %s mode", destinationID, StreamMode)
+		case !storageType.isSQLType(&destination):
+			logging.Warnf("[%s] schema inference is supported only by SQL destinations", destinationID)
+		default:
+			streamSchemaSampler = newSchemaSampler(destination.DataLayout.SchemaInferenceSample, time.Duration(destination.DataLayout.SchemaInferenceMaxWaitSec)*time.Second)
+			logging.Infof("[%s] schema of new tables is inferred from the first %d events (max wait: %s)", destinationID, streamSchemaSampler.sampleSize, streamSchemaSampler.maxWait)
+		}
+	}
+
 	storageConfig := &Config{
 		ctx:                    f.ctx,
 		destinationID:          destinationID,
@@ -223,7 +301,7 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 		streamMode:             destination.Mode == StreamMode,
 		maxColumns:             maxColumns,
 		coordinationService:    f.coordinationService,
-		eventQueue:             eventQueue,
+		eventQueue:             newPausableQueue(eventQueue, paused),
 		eventsCache:            f.eventsCache,
 		loggerFactory:          destinationLoggerFactory,
 		queueFactory:           f.eventsQueueFactory,
@@ -232,7 +310,13 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 		uniqueIDField:          uniqueIDField,
 		mappingsStyle:          mappingsStyle,
 		logEventPath:           f.logEventPath,
+		dedupCache:             streamDedupCache,
+		eventPartitioner:       streamEventPartitioner,
+		schemaSampler:          streamSchemaSampler,
+		tablePrefix:            tablePrefix,
+		tableSuffix:            tableSuffix,
 		PostHandleDestinations: destination.PostHandleDestinations,
+		paused:                 paused,
 	}
 	return storageType.createFunc, storageConfig, nil
 }
@@ -307,7 +391,12 @@ func (f *FactoryImpl) SetupProcessor(destinationID string, destination config.De
 	var flattener schema.Flattener
 	var typeResolver schema.TypeResolver
 	if isSQLType {
-		flattener = schema.NewFlattener()
+		if destination.DataLayout != nil && destination.DataLayout.MaxFlattenDepth > 0 {
+			flattener = schema.NewDepthLimitedFlattener(destination.DataLayout.MaxFlattenDepth)
+			logging.Infof("[%s] objects deeper than max_flatten_depth (%d) are written as JSON strings", destinationID, destination.DataLayout.MaxFlattenDepth)
+		} else {
+			flattener = schema.NewFlattener()
+		}
 		typeResolver = schema.NewTypeResolver()
 	} else {
 		flattener = schema.NewDummyFlattener()
@@ -315,11 +404,28 @@ func (f *FactoryImpl) SetupProcessor(destinationID string, destination config.De
 	}
 
 	maxColumnNameLength, _ := maxColumnNameLengthByDestinationType[destination.Type]
+	var identifierTruncator *schema.IdentifierTruncator
+	if destination.DataLayout != nil && isSQLType {
+		if destination.DataLayout.MaxIdentifierLength > 0 {
+			maxColumnNameLength = destination.DataLayout.MaxIdentifierLength
+		}
+		identifierTruncator, err = schema.NewIdentifierTruncator(destinationID, destination.DataLayout.IdentifierTruncation)
+		if err != nil {
+			return nil, nil, "", err
+		}
+	}
 
 	processor, err = schema.NewProcessor(destinationID, &destination, isSQLType, tableName, fieldMapper, enrichmentRules, flattener, typeResolver, uniqueIDField, maxColumnNameLength)
 	if err != nil {
 		return nil, nil, "", err
 	}
+	if identifierTruncator != nil {
+		processor.SetIdentifierTruncator(identifierTruncator)
+		logging.Infof("[%s] column and table names longer than %d characters are truncated with the hash of the original name", destinationID, maxColumnNameLength)
+	}
+	for field, method := range processor.MaskedFields() {
+		logging.Infof("[%s] field %s is masked with: %s", destinationID, field, method)
+	}
 	//for telemetry
 	if len(oldStyleMappings) > 0 {
 		mappingsStyle = "old"
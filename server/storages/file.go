@@ -0,0 +1,220 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"go.uber.org/atomic"
+)
+
+//FileConfig is a dto for local file destination configuration serialization
+type FileConfig struct {
+	Path string `mapstructure:"path,omitempty" json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+//Validate returns err if invalid
+func (fc *FileConfig) Validate() error {
+	if fc == nil {
+		return errors.New("File config is required")
+	}
+	if fc.Path == "" {
+		return errors.New("File path is required parameter")
+	}
+	return nil
+}
+
+//FileStorage writes events as newline-delimited JSON into local files (one file per table per day)
+//It is designed for local testing, debugging and CI. Stream mode is a no-op: events are read from the queue and dropped
+type FileStorage struct {
+	Abstract
+
+	dir        string
+	eventQueue events.Queue
+	mutex      *sync.Mutex
+	closed     *atomic.Bool
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: FileType, createFunc: NewFileStorage, isSQL: false})
+}
+
+//NewFileStorage returns configured FileStorage
+func NewFileStorage(config *Config) (Storage, error) {
+	fileConfig := &FileConfig{}
+	if err := config.destination.GetDestConfig(nil, fileConfig); err != nil {
+		return nil, err
+	}
+
+	if err := logging.EnsureDir(fileConfig.Path); err != nil {
+		return nil, fmt.Errorf("Error creating dir [%s]: %v", fileConfig.Path, err)
+	}
+
+	fs := &FileStorage{
+		dir:    fileConfig.Path,
+		mutex:  &sync.Mutex{},
+		closed: atomic.NewBool(false),
+	}
+
+	//Abstract (SQLAdapters and tableHelpers and archive logger are omitted)
+	fs.destinationID = config.destinationID
+	fs.processor = config.processor
+	fs.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	fs.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	fs.eventsCache = config.eventsCache
+	fs.uniqueIDField = config.uniqueIDField
+	fs.staged = config.destination.Staged
+	fs.cachingConfiguration = config.destination.CachingConfiguration
+
+	if config.streamMode {
+		logging.Warnf("[%s] File destination doesn't write events in %s mode. Events will be skipped", config.destinationID, StreamMode)
+		fs.eventQueue = config.eventQueue
+		fs.drainQueue()
+	}
+
+	return fs, nil
+}
+
+//drainQueue reads events from the queue without writing them (no-op stream mode)
+func (fs *FileStorage) drainQueue() {
+	safego.RunWithRestart(func() {
+		for !fs.closed.Load() {
+			if _, _, _, err := fs.eventQueue.DequeueBlock(); err != nil {
+				if err == events.ErrQueueClosed {
+					return
+				}
+
+				logging.SystemErrorf("[%s] Error reading event from queue: %v", fs.ID(), err)
+			}
+		}
+	})
+}
+
+func (fs *FileStorage) DryRun(events.Event) ([][]adapters.TableField, error) {
+	return nil, errors.New("File destination does not support dry run functionality")
+}
+
+//Store process events and writes them into table files
+//returns store result per table, failed events (group of events which are failed to process) and err
+func (fs *FileStorage) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
+	processedFiles, failedEvents, skippedEvents, err := fs.processor.ProcessEvents(fileName, objects, alreadyUploadedTables)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	//update cache with failed and skipped events
+	fs.cacheProcessingResults(failedEvents, skippedEvents)
+
+	storeFailedEvents := true
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range processedFiles {
+		start := time.Now()
+		err := fs.writeTable(fdata.BatchHeader.TableName, fdata.GetPayload())
+
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
+		if err != nil {
+			logging.Errorf("[%s] Error storing file %s: %v", fs.ID(), fileName, err)
+			storeFailedEvents = false
+		}
+
+		//events cache
+		fs.cacheStoreResult(fdata.GetPayload(), nil, err)
+	}
+
+	//store failed events to fallback only if other events have been inserted ok
+	if storeFailedEvents {
+		return tableResults, failedEvents, skippedEvents, nil
+	}
+
+	return tableResults, nil, skippedEvents, nil
+}
+
+//SyncStore writes objects into the table file
+func (fs *FileStorage) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string, cacheTable bool) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	if overriddenDataSchema == nil || overriddenDataSchema.TableName == "" {
+		return errors.New("File destination requires table name in sync store")
+	}
+
+	return fs.writeTable(overriddenDataSchema.TableName, objects)
+}
+
+//writeTable appends objects as JSON lines into <dir>/<table>/<day>.log file
+func (fs *FileStorage) writeTable(tableName string, objects []map[string]interface{}) error {
+	var b []byte
+	for _, object := range objects {
+		objectBytes, err := schema.JSONMarshallerInstance.Marshal(nil, object)
+		if err != nil {
+			return fmt.Errorf("marshalling error: %v", err)
+		}
+		b = append(b, objectBytes...)
+		b = append(b, '\n')
+	}
+
+	tableDir := path.Join(fs.dir, tableName)
+	filePath := path.Join(tableDir, timestamp.Now().UTC().Format(timestamp.DashDayLayout)+".log")
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if err := logging.EnsureDir(tableDir); err != nil {
+		return fmt.Errorf("Error creating dir [%s]: %v", tableDir, err)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Error opening file [%s]: %v", filePath, err)
+	}
+
+	if _, err := file.Write(b); err != nil {
+		file.Close()
+		return fmt.Errorf("Error writing file [%s]: %v", filePath, err)
+	}
+
+	return file.Close()
+}
+
+//Update isn't supported
+func (fs *FileStorage) Update(map[string]interface{}) error {
+	return errors.New("File destination doesn't support updates")
+}
+
+//Clean removes all files of the table
+func (fs *FileStorage) Clean(tableName string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	return os.RemoveAll(path.Join(fs.dir, tableName))
+}
+
+//GetUsersRecognition returns disabled users recognition configuration
+func (fs *FileStorage) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+//Type returns File type
+func (fs *FileStorage) Type() string {
+	return FileType
+}
+
+//Close closes fallback logger
+func (fs *FileStorage) Close() (multiErr error) {
+	fs.closed.Store(true)
+	if err := fs.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+	return
+}
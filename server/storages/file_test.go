@@ -0,0 +1,36 @@
+package storages
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestFileStorageWriteAndClean(t *testing.T) {
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	dir, err := ioutil.TempDir("", "file_storage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fs := &FileStorage{dir: dir, mutex: &sync.Mutex{}, closed: atomic.NewBool(false)}
+
+	require.NoError(t, fs.SyncStore(&schema.BatchHeader{TableName: "events"}, []map[string]interface{}{{"id": 1}, {"id": 2}}, "", false))
+	require.NoError(t, fs.SyncStore(&schema.BatchHeader{TableName: "events"}, []map[string]interface{}{{"id": 3, "field": "value"}}, "", false))
+
+	actual, err := ioutil.ReadFile(path.Join(dir, "events", "2020-06-16.log"))
+	require.NoError(t, err)
+	require.Equal(t, "{\"id\":1}\n{\"id\":2}\n{\"field\":\"value\",\"id\":3}\n", string(actual))
+
+	require.NoError(t, fs.Clean("events"))
+	_, err = os.Stat(path.Join(dir, "events"))
+	require.True(t, os.IsNotExist(err), "table dir should be removed")
+}
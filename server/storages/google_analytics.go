@@ -16,7 +16,7 @@ type GoogleAnalytics struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: GoogleAnalyticsType, createFunc: NewGoogleAnalytics, isSQL: false})
+	RegisterStorage(StorageType{typeName: GoogleAnalyticsType, createFunc: NewGoogleAnalytics, isSQL: false, configSchema: googleAnalyticsSchema})
 }
 
 //NewGoogleAnalytics return GoogleAnalytics instance
@@ -64,6 +64,7 @@ func NewGoogleAnalytics(config *Config) (Storage, error) {
 	ga.destinationID = config.destinationID
 	ga.processor = config.processor
 	ga.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	ga.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	ga.eventsCache = config.eventsCache
 	ga.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	ga.uniqueIDField = config.uniqueIDField
@@ -71,7 +72,7 @@ func NewGoogleAnalytics(config *Config) (Storage, error) {
 	ga.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	ga.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, ga, tableHelper)
+	ga.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, ga, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,218 @@
+package storages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//HTTPBatch is a destination that POSTs batches of processed events as JSON arrays to a configurable URL
+//Batches which are failed after all retries are written to the fallback logger
+type HTTPBatch struct {
+	Abstract
+
+	adapter *adapters.HTTPBatch
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: HTTPType, createFunc: NewHTTPBatch, isSQL: false})
+}
+
+//NewHTTPBatch returns configured HTTPBatch destination
+func NewHTTPBatch(config *Config) (Storage, error) {
+	if config.streamMode {
+		if config.eventQueue != nil {
+			config.eventQueue.Close()
+		}
+		return nil, fmt.Errorf("HTTP destination doesn't support %s mode. Please use WebHook destination instead", StreamMode)
+	}
+
+	httpConfig := &adapters.HTTPBatchConfig{}
+	if err := config.destination.GetDestConfig(nil, httpConfig); err != nil {
+		return nil, err
+	}
+
+	adapter, err := adapters.NewHTTPBatch(httpConfig, config.loggerFactory.CreateSQLQueryLogger(config.destinationID))
+	if err != nil {
+		return nil, err
+	}
+
+	hb := &HTTPBatch{adapter: adapter}
+
+	//Abstract (SQLAdapters and tableHelpers and archive logger are omitted)
+	hb.destinationID = config.destinationID
+	hb.processor = config.processor
+	hb.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	hb.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	hb.eventsCache = config.eventsCache
+	hb.uniqueIDField = config.uniqueIDField
+	hb.staged = config.destination.Staged
+	hb.cachingConfiguration = config.destination.CachingConfiguration
+
+	return hb, nil
+}
+
+func (hb *HTTPBatch) DryRun(events.Event) ([][]adapters.TableField, error) {
+	return nil, errors.New("HTTP destination does not support dry run functionality")
+}
+
+//Store process events and sends them with sendTable() func
+//returns store result per table, failed events (group of events which are failed to process) and err
+func (hb *HTTPBatch) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
+	processedFiles, failedEvents, skippedEvents, err := hb.processor.ProcessEvents(fileName, objects, alreadyUploadedTables)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	//update cache with failed and skipped events
+	hb.cacheProcessingResults(failedEvents, skippedEvents)
+
+	storeFailedEvents := true
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range processedFiles {
+		start := time.Now()
+		sentRows, err := hb.sendTable(fdata.GetPayload())
+
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: sentRows, EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
+		if err != nil {
+			logging.Errorf("[%s] Error storing file %s: %v", hb.ID(), fileName, err)
+			storeFailedEvents = false
+		}
+	}
+
+	//store failed events to fallback only if other events have been inserted ok
+	if storeFailedEvents {
+		return tableResults, failedEvents, skippedEvents, nil
+	}
+
+	return tableResults, nil, skippedEvents, nil
+}
+
+//SyncStore sends objects in batches
+func (hb *HTTPBatch) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string, cacheTable bool) error {
+	_, err := hb.sendTable(objects)
+	return err
+}
+
+//sendTable sends objects in batches. If all batches are failed with not ErrBadData errors returns err
+//(objects will be retried with the whole file) otherwise writes failed batches into the fallback logger
+//returns quantity of sent objects
+func (hb *HTTPBatch) sendTable(objects []map[string]interface{}) (int, error) {
+	batchSize := hb.adapter.BatchSize()
+	sentRows := 0
+	var failedBatches [][]map[string]interface{}
+	var failedErrors []error
+	var retryableErr error
+	for start := 0; start < len(objects); start += batchSize {
+		end := start + batchSize
+		if end > len(objects) {
+			end = len(objects)
+		}
+
+		batch := objects[start:end]
+		if err := hb.adapter.Send(batch); err != nil {
+			err = classifyHTTPBatchError(err)
+			failedBatches = append(failedBatches, batch)
+			failedErrors = append(failedErrors, err)
+			if retryableErr == nil && !errors.Is(err, ErrBadData) {
+				retryableErr = err
+			}
+			continue
+		}
+
+		sentRows += len(batch)
+		hb.cacheStoreResult(batch, nil, nil)
+	}
+
+	if len(failedBatches) == 0 {
+		return sentRows, nil
+	}
+
+	if sentRows == 0 && retryableErr != nil {
+		return 0, retryableErr
+	}
+
+	for i, batch := range failedBatches {
+		logging.Errorf("[%s] Error sending batch of %d objects. Objects will be written into fallback: %v", hb.ID(), len(batch), failedErrors[i])
+		hb.cacheStoreResult(batch, nil, failedErrors[i])
+		for _, object := range batch {
+			eventID := hb.uniqueIDField.Extract(object)
+
+			b, _ := json.Marshal(object)
+			hb.Fallback(&events.FailedEvent{
+				Event:          b,
+				Error:          failedErrors[i].Error(),
+				EventID:        eventID,
+				Classification: ClassifyError(failedErrors[i]),
+				Attempt:        hb.adapter.RetryCount() + 1,
+			})
+		}
+	}
+
+	//all batches are rejected by the server: objects have been written into fallback
+	if sentRows == 0 {
+		return 0, failedErrors[0]
+	}
+
+	return sentRows, nil
+}
+
+//classifyHTTPBatchError returns StoreError with the kind according to the HTTP response code:
+//401, 403, 404 and 405 - ErrConfig, other 4xx - ErrBadData, 5xx and 429 - ErrTransient.
+//Network errors are classified as ErrTransient. Other errors are returned as is
+func classifyHTTPBatchError(err error) error {
+	var respErr *adapters.HTTPResponseError
+	if errors.As(err, &respErr) {
+		switch {
+		case respErr.Retryable():
+			return NewStoreError(ErrTransient, "", err)
+		case respErr.StatusCode == http.StatusUnauthorized, respErr.StatusCode == http.StatusForbidden,
+			respErr.StatusCode == http.StatusNotFound, respErr.StatusCode == http.StatusMethodNotAllowed:
+			return NewStoreError(ErrConfig, "", err)
+		default:
+			return NewStoreError(ErrBadData, "", err)
+		}
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || IsConnectionError(err) {
+		return NewStoreError(ErrTransient, "", err)
+	}
+
+	return err
+}
+
+//Update isn't supported
+func (hb *HTTPBatch) Update(map[string]interface{}) error {
+	return errors.New("HTTP destination doesn't support updates")
+}
+
+//GetUsersRecognition returns disabled users recognition configuration
+func (hb *HTTPBatch) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+//Type returns HTTP type
+func (hb *HTTPBatch) Type() string {
+	return HTTPType
+}
+
+//Close closes adapter and fallback logger
+func (hb *HTTPBatch) Close() (multiErr error) {
+	if err := hb.adapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing HTTP adapter: %v", hb.ID(), err))
+	}
+	if err := hb.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package storages
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/dlq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyHTTPBatchError(t *testing.T) {
+	tests := []struct {
+		name                   string
+		statusCode             int
+		expectedKind           error
+		expectedClassification string
+	}{
+		{
+			name:                   "server error",
+			statusCode:             http.StatusBadGateway,
+			expectedKind:           ErrTransient,
+			expectedClassification: dlq.ClassificationTransient,
+		},
+		{
+			name:                   "too many requests",
+			statusCode:             http.StatusTooManyRequests,
+			expectedKind:           ErrTransient,
+			expectedClassification: dlq.ClassificationTransient,
+		},
+		{
+			name:                   "bad request",
+			statusCode:             http.StatusBadRequest,
+			expectedKind:           ErrBadData,
+			expectedClassification: dlq.ClassificationBadData,
+		},
+		{
+			name:                   "request entity too large",
+			statusCode:             http.StatusRequestEntityTooLarge,
+			expectedKind:           ErrBadData,
+			expectedClassification: dlq.ClassificationBadData,
+		},
+		{
+			name:                   "unauthorized",
+			statusCode:             http.StatusUnauthorized,
+			expectedKind:           ErrConfig,
+			expectedClassification: dlq.ClassificationConfig,
+		},
+		{
+			name:                   "not found",
+			statusCode:             http.StatusNotFound,
+			expectedKind:           ErrConfig,
+			expectedClassification: dlq.ClassificationConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			config := &adapters.HTTPBatchConfig{URL: server.URL, RetryCount: 1, RetryDelayMs: 1}
+			require.NoError(t, config.Validate())
+			adapter, err := adapters.NewHTTPBatch(config, nil)
+			require.NoError(t, err)
+			defer adapter.Close()
+
+			err = classifyHTTPBatchError(adapter.Send([]map[string]interface{}{{"id": 1}}))
+			require.True(t, errors.Is(err, tt.expectedKind), err)
+			require.Equal(t, tt.expectedClassification, ClassifyError(err))
+		})
+	}
+
+	//network error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	adapter, err := adapters.NewHTTPBatch(&adapters.HTTPBatchConfig{URL: server.URL, RetryDelayMs: 1}, nil)
+	require.NoError(t, err)
+	err = classifyHTTPBatchError(adapter.Send([]map[string]interface{}{{"id": 1}}))
+	require.True(t, errors.Is(err, ErrTransient), err)
+
+	//not HTTP error
+	notHTTPErr := errors.New("Error marshalling batch")
+	require.Equal(t, notHTTPErr, classifyHTTPBatchError(notHTTPErr))
+}
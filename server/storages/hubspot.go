@@ -11,7 +11,7 @@ type HubSpot struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: HubSpotType, createFunc: NewHubSpot, defaultTableName: "$.user?.email", isSQL: false})
+	RegisterStorage(StorageType{typeName: HubSpotType, createFunc: NewHubSpot, defaultTableName: "$.user?.email", isSQL: false, configSchema: hubSpotSchema})
 }
 
 //NewHubSpot returns configured HubSpot destination
@@ -51,6 +51,7 @@ func NewHubSpot(config *Config) (Storage, error) {
 	h.destinationID = config.destinationID
 	h.processor = config.processor
 	h.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	h.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	h.eventsCache = config.eventsCache
 	h.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	h.uniqueIDField = config.uniqueIDField
@@ -58,7 +59,7 @@ func NewHubSpot(config *Config) (Storage, error) {
 	h.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	h.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, h, tableHelper)
+	h.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, h, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
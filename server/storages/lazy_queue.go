@@ -0,0 +1,26 @@
+package storages
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+)
+
+//lazyQueue is an events.Queue wrapper which starts lazy destination storage creation on the first consumed event
+//events are kept in the underlying queue until the storage (and its streaming worker) is initialized
+type lazyQueue struct {
+	events.Queue
+	proxy *RetryableProxy
+}
+
+//Consume starts lazy storage creation and puts event into the underlying queue
+func (lq *lazyQueue) Consume(f map[string]interface{}, tokenID string) {
+	lq.proxy.lazyStartAsync()
+	lq.Queue.Consume(f, tokenID)
+}
+
+//ConsumeTimed starts lazy storage creation and puts event into the underlying queue
+func (lq *lazyQueue) ConsumeTimed(f map[string]interface{}, t time.Time, tokenID string) {
+	lq.proxy.lazyStartAsync()
+	lq.Queue.ConsumeTimed(f, t, tokenID)
+}
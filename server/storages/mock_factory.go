@@ -2,6 +2,7 @@ package storages
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
@@ -32,12 +33,30 @@ func (tpm *testProxyMock) Close() error { return nil }
 //IsCachingDisabled is a mock func
 func (tpm *testProxyMock) IsCachingDisabled() bool { return false }
 
+//IsCachingSkipped is a mock func
+func (tpm *testProxyMock) IsCachingSkipped(event events.Event) bool { return false }
+
 //GetPostHandleDestinations is a mock func
 func (tpm *testProxyMock) GetPostHandleDestinations() []string { return nil }
 
 //GetGeoResolverID is a mock func
 func (tpm *testProxyMock) GetGeoResolverID() string { return "" }
 
+//GetMaxStoreLatency is a mock func
+func (tpm *testProxyMock) GetMaxStoreLatency() time.Duration { return 0 }
+
+//Pause is a mock func
+func (tpm *testProxyMock) Pause() {}
+
+//Resume is a mock func
+func (tpm *testProxyMock) Resume() {}
+
+//IsPaused is a mock func
+func (tpm *testProxyMock) IsPaused() bool { return false }
+
+//Health is a mock func
+func (tpm *testProxyMock) Health() error { return nil }
+
 //MockFactory is a Mock destinations storages factory
 type MockFactory struct{}
 
@@ -57,3 +76,7 @@ func (mf *MockFactory) Create(id string, destination config.DestinationConfig) (
 func (mf *MockFactory) Configure(_ string, _ config.DestinationConfig) (func(config *Config) (Storage, error), *Config, error) {
 	return nil, nil, fmt.Errorf("Configure method is not implemented for MockFactory")
 }
+
+func (mf *MockFactory) Preview(_ string, _ config.DestinationConfig, _ []map[string]interface{}) (*PreviewResult, error) {
+	return nil, fmt.Errorf("Preview method is not implemented for MockFactory")
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/typing"
+	"time"
 )
 
 //MySQL stores files to MySQL in two modes:
@@ -25,7 +26,7 @@ type MySQL struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: MySQLType, createFunc: NewMySQL, isSQL: true})
+	RegisterStorage(StorageType{typeName: MySQLType, createFunc: NewMySQL, isSQL: true, configSchema: dataSourceSchema})
 }
 
 //NewMySQL returns configured MySQL Destination
@@ -53,6 +54,8 @@ func NewMySQL(config *Config) (Storage, error) {
 	}
 
 	tableHelper := NewTableHelper(mConfig.Schema, adapter, config.coordinationService, config.pkFields, adapters.SchemaToMySQL, config.maxColumns, MySQLType)
+	tableHelper.SetTableNameAffixes(config.tablePrefix, config.tableSuffix)
+	tableHelper.SetIdentifierTruncator(config.processor.IdentifierTruncator(), config.processor.MaxColumnNameLength())
 
 	m := &MySQL{
 		adapter:                       adapter,
@@ -63,8 +66,11 @@ func NewMySQL(config *Config) (Storage, error) {
 	m.destinationID = config.destinationID
 	m.processor = config.processor
 	m.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	m.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	m.debugSampleRate = config.destination.DebugSampleRate
 	m.eventsCache = config.eventsCache
 	m.tableHelpers = []*TableHelper{tableHelper}
+	config.processor.SetTableColumnsFunc(tableHelper.TableColumns)
 	m.sqlAdapters = []adapters.SQLAdapter{adapter}
 	m.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	m.uniqueIDField = config.uniqueIDField
@@ -72,7 +78,7 @@ func NewMySQL(config *Config) (Storage, error) {
 	m.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	m.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, m, tableHelper)
+	m.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, m, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
@@ -132,39 +138,22 @@ func (m *MySQL) Store(fileName string, objects []map[string]interface{}, already
 		return nil, nil, nil, err
 	}
 
-	//update cache with failed events
-	for _, failedEvent := range failedEvents.Events {
-		m.eventsCache.Error(m.IsCachingDisabled(), m.ID(), failedEvent.EventID, failedEvent.Error)
-	}
-	//update cache and counter with skipped events
-	for _, skipEvent := range skippedEvents.Events {
-		m.eventsCache.Skip(m.IsCachingDisabled(), m.ID(), skipEvent.EventID, skipEvent.Error)
-	}
+	//update cache with failed and skipped events
+	m.cacheProcessingResults(failedEvents, skippedEvents)
 
 	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		start := time.Now()
 		err := m.storeTable(fdata, table)
-		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
 		if err != nil {
 			storeFailedEvents = false
 		}
 
 		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				m.eventsCache.Error(m.IsCachingDisabled(), m.ID(), m.uniqueIDField.Extract(object), err.Error())
-			} else {
-				m.eventsCache.Succeed(&adapters.EventContext{
-					CacheDisabled:  m.IsCachingDisabled(),
-					DestinationID:  m.ID(),
-					EventID:        m.uniqueIDField.Extract(object),
-					ProcessedEvent: object,
-					Table:          table,
-				})
-			}
-		}
+		m.cacheStoreResult(fdata.GetPayload(), table, err)
 	}
 
 	//store failed events to fallback only if other events have been inserted ok
@@ -178,6 +167,7 @@ func (m *MySQL) Store(fileName string, objects []map[string]interface{}, already
 //check table schema
 //and store data into one table
 func (m *MySQL) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	m.sample(table.Name, fdata.GetPayload())
 	_, tableHelper := m.getAdapters()
 	dbSchema, err := tableHelper.EnsureTableWithoutCaching(m.ID(), table)
 	if err != nil {
@@ -80,6 +80,7 @@ func NewNpmDestination(config *Config) (Storage, error) {
 	wh.destinationID = config.destinationID
 	wh.processor = config.processor
 	wh.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	wh.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	wh.eventsCache = config.eventsCache
 	wh.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	wh.uniqueIDField = config.uniqueIDField
@@ -87,7 +88,7 @@ func NewNpmDestination(config *Config) (Storage, error) {
 	wh.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	wh.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, &wh, tableHelper)
+	wh.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, &wh, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
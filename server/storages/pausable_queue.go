@@ -0,0 +1,39 @@
+package storages
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"go.uber.org/atomic"
+)
+
+//pausedQueuePollInterval is an interval of checking if the paused destination has been resumed
+const pausedQueuePollInterval = time.Second
+
+//pausableQueue is an events.Queue wrapper which doesn't return events from DequeueBlock while the destination is paused
+//events are kept in the underlying queue until the destination is resumed
+type pausableQueue struct {
+	events.Queue
+	paused *atomic.Bool
+	closed *atomic.Bool
+}
+
+func newPausableQueue(queue events.Queue, paused *atomic.Bool) *pausableQueue {
+	return &pausableQueue{Queue: queue, paused: paused, closed: atomic.NewBool(false)}
+}
+
+//DequeueBlock waits while the destination is paused and then reads event from the underlying queue
+//an event which has been already dequeued before the pause is returned as is
+func (pq *pausableQueue) DequeueBlock() (events.Event, time.Time, string, error) {
+	for pq.paused.Load() && !pq.closed.Load() {
+		time.Sleep(pausedQueuePollInterval)
+	}
+
+	return pq.Queue.DequeueBlock()
+}
+
+//Close stops waiting in DequeueBlock and closes the underlying queue
+func (pq *pausableQueue) Close() error {
+	pq.closed.Store(true)
+	return pq.Queue.Close()
+}
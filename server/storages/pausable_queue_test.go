@@ -0,0 +1,51 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+type testQueue struct {
+	events chan events.Event
+}
+
+func (tq *testQueue) Consume(f map[string]interface{}, tokenID string) { tq.events <- f }
+func (tq *testQueue) ConsumeTimed(f map[string]interface{}, t time.Time, tokenID string) {
+	tq.events <- f
+}
+func (tq *testQueue) DequeueBlock() (events.Event, time.Time, string, error) {
+	return <-tq.events, time.Time{}, "", nil
+}
+func (tq *testQueue) Size() int64  { return int64(len(tq.events)) }
+func (tq *testQueue) Close() error { return nil }
+
+func TestPausableQueue(t *testing.T) {
+	paused := atomic.NewBool(true)
+	queue := newPausableQueue(&testQueue{events: make(chan events.Event, 1)}, paused)
+	queue.Consume(events.Event{"id": 1}, "token")
+
+	dequeued := make(chan events.Event, 1)
+	go func() {
+		event, _, _, _ := queue.DequeueBlock()
+		dequeued <- event
+	}()
+
+	select {
+	case <-dequeued:
+		require.Fail(t, "event must not be dequeued while paused")
+	case <-time.After(2 * pausedQueuePollInterval):
+	}
+	require.Equal(t, int64(1), queue.Size())
+
+	paused.Store(false)
+	select {
+	case event := <-dequeued:
+		require.Equal(t, events.Event{"id": 1}, event)
+	case <-time.After(3 * pausedQueuePollInterval):
+		require.Fail(t, "event must be dequeued after resuming")
+	}
+}
@@ -0,0 +1,78 @@
+package storages
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+)
+
+//postLoadHook executes the configured statement in background after successful loads
+//triggers are coalesced: the statement runs at most once per minInterval and loads during the interval are covered by the next run.
+//Errors are only logged: they don't fail the load
+type postLoadHook struct {
+	destinationID string
+	statement     string
+	minInterval   time.Duration
+	execFunc      func(statement string) error
+
+	triggered chan struct{}
+	closed    chan struct{}
+}
+
+//newPostLoadHook returns configured postLoadHook and starts background goroutine
+func newPostLoadHook(destinationID, statement string, minInterval time.Duration, execFunc func(statement string) error) *postLoadHook {
+	plh := &postLoadHook{
+		destinationID: destinationID,
+		statement:     statement,
+		minInterval:   minInterval,
+		execFunc:      execFunc,
+		triggered:     make(chan struct{}, 1),
+		closed:        make(chan struct{}),
+	}
+	plh.start()
+	return plh
+}
+
+func (plh *postLoadHook) start() {
+	safego.RunWithRestart(func() {
+		for {
+			select {
+			case <-plh.closed:
+				return
+			case <-plh.triggered:
+				plh.run()
+			}
+
+			//rate limit: triggers during the interval are coalesced into one pending run
+			select {
+			case <-plh.closed:
+				return
+			case <-time.After(plh.minInterval):
+			}
+		}
+	})
+}
+
+//trigger schedules the hook run. It doesn't block: the run is skipped if one is already pending
+func (plh *postLoadHook) trigger() {
+	select {
+	case plh.triggered <- struct{}{}:
+	default:
+	}
+}
+
+func (plh *postLoadHook) run() {
+	if err := plh.execFunc(plh.statement); err != nil {
+		logging.Errorf("[%s] Error running post-load hook: %v", plh.destinationID, err)
+		return
+	}
+
+	logging.Debugf("[%s] post-load hook has been executed", plh.destinationID)
+}
+
+//Close stops background goroutine. Pending run is skipped
+func (plh *postLoadHook) Close() error {
+	close(plh.closed)
+	return nil
+}
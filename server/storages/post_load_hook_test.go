@@ -0,0 +1,45 @@
+package storages
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostLoadHookCoalescesTriggers(t *testing.T) {
+	var runs int64
+	hook := newPostLoadHook("test", "EXECUTE TASK downstream", 200*time.Millisecond, func(statement string) error {
+		require.Equal(t, "EXECUTE TASK downstream", statement)
+		atomic.AddInt64(&runs, 1)
+		return nil
+	})
+	defer hook.Close()
+
+	hook.trigger()
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&runs) == 1 }, time.Second, 10*time.Millisecond)
+
+	//triggers during the min interval are covered by one next run
+	for i := 0; i < 10; i++ {
+		hook.trigger()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&runs) == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(300 * time.Millisecond)
+	require.Equal(t, int64(2), atomic.LoadInt64(&runs))
+}
+
+func TestPostLoadHookErrorDoesntStopHook(t *testing.T) {
+	var runs int64
+	hook := newPostLoadHook("test", "SELECT 1", 10*time.Millisecond, func(statement string) error {
+		atomic.AddInt64(&runs, 1)
+		return errors.New("task doesn't exist")
+	})
+	defer hook.Close()
+
+	hook.trigger()
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&runs) == 1 }, time.Second, 5*time.Millisecond)
+	hook.trigger()
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&runs) == 2 }, time.Second, 5*time.Millisecond)
+}
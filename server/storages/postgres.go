@@ -10,6 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"time"
 )
 
 //Postgres stores files to Postgres in two modes:
@@ -24,7 +25,7 @@ type Postgres struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: PostgresType, createFunc: NewPostgres, isSQL: true})
+	RegisterStorage(StorageType{typeName: PostgresType, createFunc: NewPostgres, isSQL: true, configSchema: dataSourceSchema})
 }
 
 //NewPostgres returns configured Postgres Destination
@@ -67,6 +68,8 @@ func NewPostgres(config *Config) (Storage, error) {
 	}
 
 	tableHelper := NewTableHelper(pgConfig.Schema, adapter, config.coordinationService, config.pkFields, adapters.SchemaToPostgres, config.maxColumns, PostgresType)
+	tableHelper.SetTableNameAffixes(config.tablePrefix, config.tableSuffix)
+	tableHelper.SetIdentifierTruncator(config.processor.IdentifierTruncator(), config.processor.MaxColumnNameLength())
 
 	p := &Postgres{
 		adapter:                       adapter,
@@ -77,8 +80,11 @@ func NewPostgres(config *Config) (Storage, error) {
 	p.destinationID = config.destinationID
 	p.processor = config.processor
 	p.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	p.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	p.debugSampleRate = config.destination.DebugSampleRate
 	p.eventsCache = config.eventsCache
 	p.tableHelpers = []*TableHelper{tableHelper}
+	config.processor.SetTableColumnsFunc(tableHelper.TableColumns)
 	p.sqlAdapters = []adapters.SQLAdapter{adapter}
 	p.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	p.uniqueIDField = config.uniqueIDField
@@ -86,7 +92,7 @@ func NewPostgres(config *Config) (Storage, error) {
 	p.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	p.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, p, tableHelper)
+	p.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, p, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
@@ -104,39 +110,22 @@ func (p *Postgres) Store(fileName string, objects []map[string]interface{}, alre
 		return nil, nil, nil, err
 	}
 
-	//update cache with failed events
-	for _, failedEvent := range failedEvents.Events {
-		p.eventsCache.Error(p.IsCachingDisabled(), p.ID(), failedEvent.EventID, failedEvent.Error)
-	}
-	//update cache and counter with skipped events
-	for _, skipEvent := range skippedEvents.Events {
-		p.eventsCache.Skip(p.IsCachingDisabled(), p.ID(), skipEvent.EventID, skipEvent.Error)
-	}
+	//update cache with failed and skipped events
+	p.cacheProcessingResults(failedEvents, skippedEvents)
 
 	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		start := time.Now()
 		err := p.storeTable(fdata, table)
-		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
 		if err != nil {
 			storeFailedEvents = false
 		}
 
 		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				p.eventsCache.Error(p.IsCachingDisabled(), p.ID(), p.uniqueIDField.Extract(object), err.Error())
-			} else {
-				p.eventsCache.Succeed(&adapters.EventContext{
-					CacheDisabled:  p.IsCachingDisabled(),
-					DestinationID:  p.ID(),
-					EventID:        p.uniqueIDField.Extract(object),
-					ProcessedEvent: object,
-					Table:          table,
-				})
-			}
-		}
+		p.cacheStoreResult(fdata.GetPayload(), table, err)
 	}
 
 	//store failed events to fallback only if other events have been inserted ok
@@ -150,6 +139,7 @@ func (p *Postgres) Store(fileName string, objects []map[string]interface{}, alre
 //check table schema
 //and store data into one table
 func (p *Postgres) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	p.sample(table.Name, fdata.GetPayload())
 	_, tableHelper := p.getAdapters()
 	dbSchema, err := tableHelper.EnsureTableWithoutCaching(p.ID(), table)
 	if err != nil {
@@ -0,0 +1,127 @@
+package storages
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+const previewFileName = "preview"
+
+//columnTypesMappingByDestinationType is used for building table schemas without destination instance
+var columnTypesMappingByDestinationType = map[string]map[typing.DataType]string{
+	PostgresType:   adapters.SchemaToPostgres,
+	RedshiftType:   adapters.SchemaToRedshift,
+	SnowflakeType:  adapters.SchemaToSnowflake,
+	BigQueryType:   adapters.SchemaToBigQueryString,
+	ClickHouseType: adapters.SchemaToClickhouse,
+	MySQLType:      adapters.SchemaToMySQL,
+}
+
+//PreviewTable is a dto for table schema and objects which would be stored in the destination
+type PreviewTable struct {
+	Name         string                   `json:"name"`
+	Columns      map[string]string        `json:"columns"`
+	PKFields     []string                 `json:"pk_fields,omitempty"`
+	MaskedFields map[string]string        `json:"masked_fields,omitempty"`
+	Objects      []map[string]interface{} `json:"objects"`
+}
+
+//PreviewResult is a dto for processing results without writing to the destination
+type PreviewResult struct {
+	Tables        []*PreviewTable        `json:"tables"`
+	FailedEvents  []*events.FailedEvent  `json:"failed_events"`
+	SkippedEvents []*events.SkippedEvent `json:"skipped_events"`
+}
+
+//Preview processes objects with the destination processor and maps table schemas with a temporary TableHelper
+//Destination instance isn't created: nothing is written and no connections are opened
+func (f *FactoryImpl) Preview(destinationID string, destination config.DestinationConfig, objects []map[string]interface{}) (*PreviewResult, error) {
+	if destination.Type == "" {
+		return nil, errors.New("destination type is required")
+	}
+	if _, ok := StorageTypes[destination.Type]; !ok {
+		return nil, ErrUnknownDestination
+	}
+
+	processor, sqlTypes, _, err := f.SetupProcessor(destinationID, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := processor.InitJavaScriptTemplates(); err != nil {
+		return nil, err
+	}
+	defer processor.CloseJavaScriptTemplates()
+
+	pkFields := map[string]bool{}
+	if destination.DataLayout != nil {
+		for _, field := range destination.DataLayout.PrimaryKeyFields {
+			pkFields[field] = true
+		}
+	}
+
+	columnTypesMapping, ok := columnTypesMappingByDestinationType[destination.Type]
+	if !ok {
+		columnTypesMapping = adapters.DefaultSchemaTypeMappings
+	}
+	tableHelper := NewTableHelper("", nil, nil, pkFields, columnTypesMapping, 0, destination.Type)
+	if destination.DataLayout != nil {
+		tableHelper.SetTableNameAffixes(destination.DataLayout.TablePrefix, destination.DataLayout.TableSuffix)
+	}
+	tableHelper.SetIdentifierTruncator(processor.IdentifierTruncator(), processor.MaxColumnNameLength())
+
+	processedFiles, failedEvents, skippedEvents, err := processor.ProcessEvents(previewFileName, objects, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PreviewResult{
+		Tables:        []*PreviewTable{},
+		FailedEvents:  []*events.FailedEvent{},
+		SkippedEvents: []*events.SkippedEvent{},
+	}
+	for _, fdata := range processedFiles {
+		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		previewTable := &PreviewTable{
+			Name:    table.Name,
+			Columns: map[string]string{},
+			Objects: fdata.GetPayload(),
+		}
+		for name, column := range table.Columns {
+			//overridden types from mappings
+			if overriddenColumn, ok := sqlTypes[name]; ok {
+				column = overriddenColumn
+			}
+			previewTable.Columns[name] = column.Type
+			if method := fdata.BatchHeader.Fields[name].Masking(); method != "" {
+				if previewTable.MaskedFields == nil {
+					previewTable.MaskedFields = map[string]string{}
+				}
+				previewTable.MaskedFields[name] = method
+			}
+		}
+		for field := range table.PKFields {
+			previewTable.PKFields = append(previewTable.PKFields, field)
+		}
+		sort.Strings(previewTable.PKFields)
+
+		result.Tables = append(result.Tables, previewTable)
+	}
+	sort.Slice(result.Tables, func(i, j int) bool {
+		return result.Tables[i].Name < result.Tables[j].Name
+	})
+
+	if failedEvents != nil {
+		result.FailedEvents = append(result.FailedEvents, failedEvents.Events...)
+	}
+	if skippedEvents != nil {
+		result.SkippedEvents = append(result.SkippedEvents, skippedEvents.Events...)
+	}
+
+	return result, nil
+}
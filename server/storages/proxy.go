@@ -1,6 +1,9 @@
 package storages
 
 import (
+	"errors"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/safego"
@@ -12,6 +15,7 @@ import (
 )
 
 //RetryableProxy creates Storage with retry (if create fails e.g. because of connection issue)
+//In lazy mode the Storage is created on the first Get() call (or on the first event in stream mode)
 type RetryableProxy struct {
 	sync.RWMutex
 	factoryMethod func(*Config) (Storage, error)
@@ -20,68 +24,128 @@ type RetryableProxy struct {
 	storage Storage
 	ready   *atomic.Bool
 	closed  *atomic.Bool
+	//lastErr is the last storage creation error
+	lastErr *atomic.String
+
+	lazy      bool
+	startOnce sync.Once
 }
 
-//newProxy return New RetryableProxy and starts goroutine
+//newProxy return New RetryableProxy and starts goroutine (if destination isn't lazy)
 func newProxy(factoryMethod func(*Config) (Storage, error), config *Config) StorageProxy {
 	rsp := &RetryableProxy{
 		factoryMethod: factoryMethod,
 		config:        config,
 		ready:         atomic.NewBool(false),
 		closed:        atomic.NewBool(false),
+		lastErr:       atomic.NewString(""),
+		lazy:          config.destination.LazyInit,
+	}
+	if !rsp.lazy {
+		rsp.startOnce.Do(func() { rsp.start(false) })
 	}
-	rsp.start()
 	return rsp
 }
 
 //start runs a new goroutine for calling factoryMethod 1 time per 1 minute
-func (rsp *RetryableProxy) start() {
+//if delayed is true the first call is after 1 minute
+func (rsp *RetryableProxy) start(delayed bool) {
 	safego.RunWithRestart(func() {
 		var lastErr string
 		for {
+			if delayed {
+				time.Sleep(time.Minute)
+			}
+			delayed = true
+
 			if rsp.closed.Load() {
 				return
 			}
 
-			storage, err := rsp.factoryMethod(rsp.config)
-			if err == nil {
-				err = storage.Processor().InitJavaScriptTemplates()
-			}
-			if err != nil {
+			if err := rsp.create(); err != nil {
 				//write logs only if new error or write every 20th
 				if err.Error() != lastErr || rand.Int31n(20) == 0 {
 					logging.Errorf("[%s] Error initializing destination of type %s: %v. Retry after 1 minute", rsp.config.destinationID, rsp.config.destination.Type, err)
 				}
-				time.Sleep(time.Minute)
+				lastErr = err.Error()
 				continue
 			}
 
-			rsp.Lock()
-			//double check if closed
-			if rsp.closed.Load() {
-				if err := storage.Close(); err != nil {
-					logging.Errorf("[%s] error closing storage in proxy: %v", rsp.config.destinationID, err)
-				}
+			break
+		}
+	}).WithRestartTimeout(1 * time.Minute)
+}
 
-				rsp.Unlock()
-				return
-			}
+//lazyStart creates the storage in the current goroutine if the proxy is lazy and hasn't been started yet
+//if creation fails, retries are continued in background
+func (rsp *RetryableProxy) lazyStart() {
+	if !rsp.lazy || rsp.ready.Load() || rsp.closed.Load() {
+		return
+	}
 
-			rsp.storage = storage
-			rsp.ready.Store(true)
-			rsp.Unlock()
+	rsp.startOnce.Do(func() {
+		logging.Infof("[%s] initializing lazy destination on the first usage", rsp.config.destinationID)
+		if err := rsp.create(); err != nil {
+			logging.Errorf("[%s] Error initializing destination of type %s: %v. Retry after 1 minute", rsp.config.destinationID, rsp.config.destination.Type, err)
+			rsp.start(true)
+		}
+	})
+}
 
-			logging.Infof("[%s] destination has been initialized!", rsp.config.destinationID)
-			telemetry.Destination(rsp.config.destinationID, rsp.config.destination.Type, rsp.config.destination.Mode,
-				rsp.config.mappingsStyle, len(rsp.config.pkFields) > 0, rsp.storage.GetUsersRecognition().IsEnabled())
+//lazyStartAsync starts the storage creation in background if the proxy is lazy and hasn't been started yet
+func (rsp *RetryableProxy) lazyStartAsync() {
+	if !rsp.lazy || rsp.ready.Load() || rsp.closed.Load() {
+		return
+	}
 
-			break
+	rsp.startOnce.Do(func() {
+		logging.Infof("[%s] initializing lazy destination on the first event", rsp.config.destinationID)
+		rsp.start(false)
+	})
+}
+
+//create creates storage and makes proxy ready
+//returns err if storage creation failed
+func (rsp *RetryableProxy) create() error {
+	storage, err := rsp.factoryMethod(rsp.config)
+	if err == nil {
+		err = storage.Processor().InitJavaScriptTemplates()
+	}
+	if err != nil {
+		//the error is logged and returned in API: it mustn't contain credentials
+		err = logging.RedactError(err, rsp.config.destination)
+		rsp.lastErr.Store(err.Error())
+		return err
+	}
+
+	rsp.Lock()
+	//double check if closed
+	if rsp.closed.Load() {
+		if err := storage.Close(); err != nil {
+			logging.Errorf("[%s] error closing storage in proxy: %v", rsp.config.destinationID, err)
 		}
-	}).WithRestartTimeout(1 * time.Minute)
+
+		rsp.Unlock()
+		return nil
+	}
+
+	rsp.storage = storage
+	rsp.ready.Store(true)
+	rsp.lastErr.Store("")
+	rsp.Unlock()
+
+	logging.Infof("[%s] destination has been initialized!", rsp.config.destinationID)
+	telemetry.Destination(rsp.config.destinationID, rsp.config.destination.Type, rsp.config.destination.Mode,
+		rsp.config.mappingsStyle, len(rsp.config.pkFields) > 0, storage.GetUsersRecognition().IsEnabled())
+
+	return nil
 }
 
 //Get returns underlying destination storage and ready flag
+//lazy destination storage is created on the first call
 func (rsp *RetryableProxy) Get() (Storage, bool) {
+	rsp.lazyStart()
+
 	rsp.RLock()
 	defer rsp.RUnlock()
 	return rsp.storage, rsp.ready.Load()
@@ -99,6 +163,12 @@ func (rsp *RetryableProxy) ID() string {
 
 //Type returns destination type
 func (rsp *RetryableProxy) Type() string {
+	rsp.RLock()
+	defer rsp.RUnlock()
+	if rsp.storage == nil {
+		return rsp.config.destination.Type
+	}
+
 	return rsp.storage.Type()
 }
 
@@ -108,6 +178,39 @@ func (rsp *RetryableProxy) IsCachingDisabled() bool {
 		rsp.config.destination.CachingConfiguration.Disabled
 }
 
+//Health returns error if the storage isn't initialized or the storage HealthChecker fails
+//lazy storage which hasn't been initialized yet is considered healthy
+func (rsp *RetryableProxy) Health() error {
+	if rsp.closed.Load() {
+		return errors.New("destination has been closed")
+	}
+
+	rsp.RLock()
+	storage, ready := rsp.storage, rsp.ready.Load()
+	rsp.RUnlock()
+
+	if !ready {
+		if lastErr := rsp.lastErr.Load(); lastErr != "" {
+			return fmt.Errorf("destination isn't initialized: %s", lastErr)
+		}
+		if rsp.lazy {
+			return nil
+		}
+		return errors.New("destination is being initialized")
+	}
+
+	if healthChecker, ok := storage.(HealthChecker); ok {
+		return healthChecker.Health()
+	}
+
+	return nil
+}
+
+//IsCachingSkipped returns true if the event type is configured in caching.cache_skip_events
+func (rsp *RetryableProxy) IsCachingSkipped(event events.Event) bool {
+	return rsp.config.destination.CachingConfiguration.IsEventSkipped(events.ExtractEventType(event))
+}
+
 func (rsp *RetryableProxy) GetPostHandleDestinations() []string {
 	return rsp.config.PostHandleDestinations
 }
@@ -116,14 +219,40 @@ func (rsp *RetryableProxy) GetGeoResolverID() string {
 	return rsp.config.destination.GeoDataResolverID
 }
 
+//GetMaxStoreLatency returns max_store_latency_ms of the destination or 0 if it isn't configured
+func (rsp *RetryableProxy) GetMaxStoreLatency() time.Duration {
+	return time.Duration(rsp.config.destination.MaxStoreLatencyMs) * time.Millisecond
+}
+
+//Pause stops writes into the destination: batch files and stream mode events are kept until Resume is called
+func (rsp *RetryableProxy) Pause() {
+	if rsp.config.paused != nil {
+		rsp.config.paused.Store(true)
+	}
+}
+
+//Resume resumes writes into the paused destination
+func (rsp *RetryableProxy) Resume() {
+	if rsp.config.paused != nil {
+		rsp.config.paused.Store(false)
+	}
+}
+
+//IsPaused returns true if writes into the destination are paused
+func (rsp *RetryableProxy) IsPaused() bool {
+	return rsp.config.paused != nil && rsp.config.paused.Load()
+}
+
 //Close stops underlying goroutine and close the storage
+//it is a no-op for lazy destination which has never been used
 func (rsp *RetryableProxy) Close() error {
 	rsp.Lock()
+	defer rsp.Unlock()
+
 	rsp.closed.Store(true)
 	if rsp.storage != nil {
 		return rsp.storage.Close()
 	}
-	rsp.Unlock()
 
 	return nil
 }
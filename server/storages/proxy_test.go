@@ -0,0 +1,61 @@
+package storages
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestLazyProxy(t *testing.T) {
+	calls := atomic.NewInt32(0)
+	factoryMethod := func(*Config) (Storage, error) {
+		calls.Inc()
+		return nil, errors.New("connection refused")
+	}
+	destination := &config.DestinationConfig{Type: SnowflakeType, LazyInit: true}
+
+	//never used
+	unused := newProxy(factoryMethod, &Config{destinationID: "unused", destination: destination})
+	require.Equal(t, SnowflakeType, unused.Type())
+	require.NoError(t, unused.Close())
+	require.Equal(t, int32(0), calls.Load(), "Lazy destination must not be created before the first usage")
+
+	//the first usage
+	used := newProxy(factoryMethod, &Config{destinationID: "used", destination: destination})
+	_, ready := used.Get()
+	require.False(t, ready)
+	require.Equal(t, int32(1), calls.Load(), "Lazy destination must be created on the first Get() call")
+
+	_, ready = used.Get()
+	require.False(t, ready)
+	require.Equal(t, int32(1), calls.Load(), "Lazy destination creation must be retried in background")
+	require.NoError(t, used.Close())
+}
+
+func TestProxyErrorIsRedacted(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	password := "snowflake-password"
+	factoryMethod := func(*Config) (Storage, error) {
+		return nil, fmt.Errorf("error connecting user:%s@account.snowflakecomputing.com: connection refused", password)
+	}
+	destination := &config.DestinationConfig{Type: SnowflakeType, LazyInit: true, Snowflake: map[string]interface{}{"account": "account", "username": "user", "password": password}}
+
+	proxy := newProxy(factoryMethod, &Config{destinationID: "failing", destination: destination})
+	_, ready := proxy.Get()
+	require.False(t, ready)
+	require.NoError(t, proxy.Close())
+
+	require.Contains(t, buf.String(), "connection refused")
+	require.NotContains(t, buf.String(), password)
+	require.NotContains(t, proxy.(*RetryableProxy).lastErr.Load(), password)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"time"
 )
 
 //AwsRedshift stores files to aws RedShift in two modes:
@@ -25,7 +26,7 @@ type AwsRedshift struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: RedshiftType, createFunc: NewAwsRedshift, isSQL: true})
+	RegisterStorage(StorageType{typeName: RedshiftType, createFunc: NewAwsRedshift, isSQL: true, configSchema: dataSourceSchema})
 }
 
 //NewAwsRedshift returns AwsRedshift and start goroutine for aws redshift batch storage or for stream consumer depend on destination mode
@@ -86,6 +87,8 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 	}
 
 	tableHelper := NewTableHelper(redshiftConfig.Schema, redshiftAdapter, config.coordinationService, config.pkFields, adapters.SchemaToRedshift, config.maxColumns, RedshiftType)
+	tableHelper.SetTableNameAffixes(config.tablePrefix, config.tableSuffix)
+	tableHelper.SetIdentifierTruncator(config.processor.IdentifierTruncator(), config.processor.MaxColumnNameLength())
 
 	ar := &AwsRedshift{
 		s3Adapter:                     s3Adapter,
@@ -97,8 +100,11 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 	ar.destinationID = config.destinationID
 	ar.processor = config.processor
 	ar.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	ar.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	ar.debugSampleRate = config.destination.DebugSampleRate
 	ar.eventsCache = config.eventsCache
 	ar.tableHelpers = []*TableHelper{tableHelper}
+	config.processor.SetTableColumnsFunc(tableHelper.TableColumns)
 	ar.sqlAdapters = []adapters.SQLAdapter{redshiftAdapter}
 	ar.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	ar.uniqueIDField = config.uniqueIDField
@@ -106,7 +112,7 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 	ar.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	ar.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, ar, tableHelper)
+	ar.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, ar, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
@@ -124,39 +130,22 @@ func (ar *AwsRedshift) Store(fileName string, objects []map[string]interface{},
 		return nil, nil, nil, err
 	}
 
-	//update cache with failed events
-	for _, failedEvent := range failedEvents.Events {
-		ar.eventsCache.Error(ar.IsCachingDisabled(), ar.ID(), failedEvent.EventID, failedEvent.Error)
-	}
-	//update cache and counter with skipped events
-	for _, skipEvent := range skippedEvents.Events {
-		ar.eventsCache.Skip(ar.IsCachingDisabled(), ar.ID(), skipEvent.EventID, skipEvent.Error)
-	}
+	//update cache with failed and skipped events
+	ar.cacheProcessingResults(failedEvents, skippedEvents)
 
 	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+		start := time.Now()
 		err := ar.storeTable(fdata, table)
-		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
 		if err != nil {
 			storeFailedEvents = false
 		}
 
 		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				ar.eventsCache.Error(ar.IsCachingDisabled(), ar.ID(), ar.uniqueIDField.Extract(object), err.Error())
-			} else {
-				ar.eventsCache.Succeed(&adapters.EventContext{
-					CacheDisabled:  ar.IsCachingDisabled(),
-					DestinationID:  ar.ID(),
-					EventID:        ar.uniqueIDField.Extract(object),
-					ProcessedEvent: object,
-					Table:          table,
-				})
-			}
-		}
+		ar.cacheStoreResult(fdata.GetPayload(), table, err)
 	}
 
 	//store failed events to fallback only if other events have been inserted ok
@@ -170,6 +159,7 @@ func (ar *AwsRedshift) Store(fileName string, objects []map[string]interface{},
 //check table schema
 //and store data into one table via s3
 func (ar *AwsRedshift) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	ar.sample(table.Name, fdata.GetPayload())
 	_, tableHelper := ar.getAdapters()
 	dbTable, err := tableHelper.EnsureTableWithoutCaching(ar.ID(), table)
 	if err != nil {
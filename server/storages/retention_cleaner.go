@@ -0,0 +1,65 @@
+package storages
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+)
+
+//retentionCleaner periodically deletes rows which are older than the retention age from the configured tables
+type retentionCleaner struct {
+	destinationID string
+	storage       Storage
+	tables        []string
+	column        string
+	age           time.Duration
+	interval      time.Duration
+
+	closed chan struct{}
+}
+
+//newRetentionCleaner returns configured retentionCleaner and starts background goroutine
+func newRetentionCleaner(destinationID string, storage Storage, tables []string, column string, age, interval time.Duration) *retentionCleaner {
+	rc := &retentionCleaner{
+		destinationID: destinationID,
+		storage:       storage,
+		tables:        tables,
+		column:        column,
+		age:           age,
+		interval:      interval,
+		closed:        make(chan struct{}),
+	}
+	rc.start()
+	return rc
+}
+
+func (rc *retentionCleaner) start() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(rc.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rc.closed:
+				return
+			case <-ticker.C:
+				rc.clean()
+			}
+		}
+	})
+}
+
+//clean deletes expired rows from all tables. Errors are only logged
+func (rc *retentionCleaner) clean() {
+	for _, table := range rc.tables {
+		if err := rc.storage.CleanOlderThan(table, rc.column, rc.age); err != nil {
+			logging.Errorf("[%s] Error deleting rows older than %s from %s table: %v", rc.destinationID, rc.age, table, err)
+		}
+	}
+}
+
+//Close stops background goroutine
+func (rc *retentionCleaner) Close() error {
+	close(rc.closed)
+	return nil
+}
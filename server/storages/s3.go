@@ -25,8 +25,9 @@ type S3 struct {
 
 func init() {
 	RegisterStorage(StorageType{
-		typeName:   S3Type,
-		createFunc: NewS3,
+		typeName:     S3Type,
+		createFunc:   NewS3,
+		configSchema: s3Schema,
 		//S3 can store SQL data it depends on "format".
 		isSQLFunc: func(config *config.DestinationConfig) bool {
 			mp := utils.NvlMap(config.Config, config.S3)
@@ -59,6 +60,7 @@ func NewS3(config *Config) (Storage, error) {
 	s3.destinationID = config.destinationID
 	s3.processor = config.processor
 	s3.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	s3.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	s3.eventsCache = config.eventsCache
 	s3.uniqueIDField = config.uniqueIDField
 	s3.staged = config.destination.Staged
@@ -79,40 +81,23 @@ func (s3 *S3) Store(fileName string, objects []map[string]interface{}, alreadyUp
 		return nil, nil, nil, err
 	}
 
-	//update cache with failed events
-	for _, failedEvent := range failedEvents.Events {
-		s3.eventsCache.Error(s3.IsCachingDisabled(), s3.ID(), failedEvent.EventID, failedEvent.Error)
-	}
-	//update cache and counter with skipped events
-	for _, skipEvent := range skippedEvents.Events {
-		s3.eventsCache.Skip(s3.IsCachingDisabled(), s3.ID(), skipEvent.EventID, skipEvent.Error)
-	}
+	//update cache with failed and skipped events
+	s3.cacheProcessingResults(failedEvents, skippedEvents)
 
 	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range processedFiles {
+		start := time.Now()
 		err := s3.uploadFile(fdata)
 
-		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
 		if err != nil {
 			logging.Errorf("[%s] Error storing file %s: %v", s3.ID(), fileName, err)
 			storeFailedEvents = false
 		}
 
 		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				s3.eventsCache.Error(s3.IsCachingDisabled(), s3.ID(), s3.uniqueIDField.Extract(object), err.Error())
-			} else {
-				s3.eventsCache.Succeed(&adapters.EventContext{
-					CacheDisabled:  s3.IsCachingDisabled(),
-					DestinationID:  s3.ID(),
-					EventID:        s3.uniqueIDField.Extract(object),
-					ProcessedEvent: object,
-					Table:          nil,
-				})
-			}
-		}
+		s3.cacheStoreResult(fdata.GetPayload(), nil, err)
 	}
 
 	//store failed events to fallback only if other events have been inserted ok
@@ -0,0 +1,136 @@
+package storages
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const defaultSchemaInferenceMaxWait = 10 * time.Second
+
+//schemaSampler buffers the first events of new tables (which don't exist in the destination yet)
+//until sampleSize events or maxWait: the table is created with the union schema of all sampled events
+//so the next events don't cause ALTER TABLE statements for every new field
+type schemaSampler struct {
+	sampleSize int
+	maxWait    time.Duration
+
+	mutex *sync.Mutex
+	//known are tables which exist in the destination (sampling isn't needed)
+	known   map[string]bool
+	samples map[string]*tableSample
+}
+
+//tableSample is buffered events of a new table with the union schema
+type tableSample struct {
+	table   *adapters.Table
+	events  []*sampledEvent
+	started time.Time
+}
+
+//sampledEvent is a buffered processed event which is inserted after the table creation
+type sampledEvent struct {
+	eventContext *adapters.EventContext
+	fact         events.Event
+	tokenID      string
+}
+
+//newSchemaSampler returns configured schemaSampler. Default value is used for not positive maxWait
+func newSchemaSampler(sampleSize int, maxWait time.Duration) *schemaSampler {
+	if maxWait <= 0 {
+		maxWait = defaultSchemaInferenceMaxWait
+	}
+
+	return &schemaSampler{
+		sampleSize: sampleSize,
+		maxWait:    maxWait,
+		mutex:      &sync.Mutex{},
+		known:      map[string]bool{},
+		samples:    map[string]*tableSample{},
+	}
+}
+
+//isKnown returns true if the table exists in the destination or it is being sampled
+func (ss *schemaSampler) isKnown(tableName string) (known bool, sampling bool) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	_, sampling = ss.samples[tableName]
+	return ss.known[tableName], sampling
+}
+
+//markKnown marks the table as existing: its events aren't sampled anymore
+func (ss *schemaSampler) markKnown(tableName string) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.known[tableName] = true
+}
+
+//add buffers the event and merges its columns into the table sample schema
+//returns false if the table is already known (the event must be inserted as is)
+//and the sample if it has reached sampleSize events (it is removed from the sampler and must be flushed)
+func (ss *schemaSampler) add(event *sampledEvent) (bool, *tableSample) {
+	table := event.eventContext.Table
+
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	if ss.known[table.Name] {
+		return false, nil
+	}
+
+	sample, ok := ss.samples[table.Name]
+	if !ok {
+		sample = &tableSample{table: table.Clone(), started: timestamp.Now()}
+		ss.samples[table.Name] = sample
+	} else {
+		//the first seen type of the column is used
+		for name, column := range table.Columns {
+			if _, exists := sample.table.Columns[name]; !exists {
+				sample.table.Columns[name] = column
+			}
+		}
+	}
+	sample.events = append(sample.events, event)
+
+	if len(sample.events) < ss.sampleSize {
+		return true, nil
+	}
+
+	delete(ss.samples, table.Name)
+	return true, sample
+}
+
+//takeExpired removes and returns samples which have been buffered for maxWait or longer
+func (ss *schemaSampler) takeExpired(now time.Time) []*tableSample {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	var expired []*tableSample
+	for name, sample := range ss.samples {
+		if now.Sub(sample.started) >= ss.maxWait {
+			expired = append(expired, sample)
+			delete(ss.samples, name)
+		}
+	}
+
+	return expired
+}
+
+//takeAll removes and returns all samples
+func (ss *schemaSampler) takeAll() []*tableSample {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	all := make([]*tableSample, 0, len(ss.samples))
+	for name, sample := range ss.samples {
+		all = append(all, sample)
+		delete(ss.samples, name)
+	}
+
+	return all
+}
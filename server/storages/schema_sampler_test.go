@@ -0,0 +1,46 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaSampler(t *testing.T) {
+	sampler := newSchemaSampler(3, time.Minute)
+	event := func(columns ...string) *sampledEvent {
+		table := &adapters.Table{Name: "events", Columns: adapters.Columns{}}
+		for _, column := range columns {
+			table.Columns[column] = typing.SQLColumn{Type: "text"}
+		}
+		return &sampledEvent{eventContext: &adapters.EventContext{Table: table}}
+	}
+
+	buffered, full := sampler.add(event("id"))
+	require.True(t, buffered)
+	require.Nil(t, full)
+	buffered, full = sampler.add(event("id", "name"))
+	require.True(t, buffered)
+	require.Nil(t, full)
+	_, sampling := sampler.isKnown("events")
+	require.True(t, sampling)
+
+	buffered, full = sampler.add(event("email"))
+	require.True(t, buffered)
+	require.NotNil(t, full, "sample with enough events should be returned")
+	require.Len(t, full.events, 3)
+	require.Equal(t, adapters.Columns{"id": {Type: "text"}, "name": {Type: "text"}, "email": {Type: "text"}}, full.table.Columns, "table should have the union schema")
+	require.Len(t, full.events[0].eventContext.Table.Columns, 1, "event table schema shouldn't be changed")
+
+	sampler.markKnown("events")
+	buffered, _ = sampler.add(event("id"))
+	require.False(t, buffered, "events of known tables shouldn't be buffered")
+
+	sampler.add(&sampledEvent{eventContext: &adapters.EventContext{Table: &adapters.Table{Name: "pages", Columns: adapters.Columns{}}}})
+	require.Empty(t, sampler.takeExpired(time.Now()))
+	require.Len(t, sampler.takeExpired(time.Now().Add(time.Minute)), 1)
+	require.Empty(t, sampler.takeAll())
+}
@@ -2,18 +2,55 @@ package storages
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/tracing"
 	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/jitsucom/jitsu/server/uuid"
 	sf "github.com/snowflakedb/gosnowflake"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+const (
+	//snowflakeNoActiveWarehouse is 'No active warehouse selected in the current session' error code
+	snowflakeNoActiveWarehouse = 606
+	//snowflakeIncorrectCredentials is 'Incorrect username or password was specified' error code
+	snowflakeIncorrectCredentials = 390100
+	//Snowflake data loading (COPY) error codes are 1xxxxx e.g. 100038 'Numeric value is not recognized'
+	snowflakeDataLoadingErrorsMin = 100000
+	snowflakeDataLoadingErrorsMax = 199999
+	//gosnowflake driver communication error codes are 261xxx-262xxx e.g. failed to post query, heartbeat, get chunk
+	snowflakeDriverErrorsMin = 261000
+	snowflakeDriverErrorsMax = 262999
+
+	//load metadata columns (add_load_metadata)
+	loadedAtColumn      = "_jitsu_loaded_at"
+	sourceFileColumn    = "_jitsu_source_file"
+	destinationIDColumn = "_jitsu_destination_id"
+	eventTimeColumn     = "_jitsu_event_time"
+
+	//copyHistoryClockSkew is subtracted from the COPY start time in the load history check
+	copyHistoryClockSkew = time.Minute
+
+	//stageChunksFolder is a stage folder where chunks of table files are uploaded (every file has own subfolder)
+	stageChunksFolder = "jitsu_stage_chunks"
+)
+
+//errCopyOutcomeUnknown is wrapped by the store error if interrupted COPY outcome can't be checked in the load history
+var errCopyOutcomeUnknown = errors.New("interrupted COPY outcome is unknown")
+
 //Snowflake stores files to Snowflake in two modes:
 //batch: via aws s3 (or gcp) in batch mode (1 file = 1 transaction)
 //stream: via events queue in stream mode (1 object = 1 transaction)
@@ -21,13 +58,39 @@ type Snowflake struct {
 	Abstract
 
 	stageAdapter                  adapters.Stage
+	stageSweeper                  *stageSweeper
+	retentionCleaner              *retentionCleaner
+	postLoadHook                  *postLoadHook
+	copyBatcher                   *copyBatcher
+	keepStageFiles                string
+	copyPurge                     bool
+	storeTimeout                  time.Duration
+	closeTimeout                  time.Duration
+	updateBatchSize               int
+	stageFileMaxRows              int
+	stageUploadConcurrency        int
+	stageFormat                   string
 	snowflakeAdapter              *adapters.Snowflake
 	streamingWorker               *StreamingWorker
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	addLoadMetadata               bool
+	eventTime                     *eventTimeResolver
+
+	//timestampType is the configured timestamp_type: NTZ time values are converted into UTC in stage files
+	timestampType string
+	//columnTypesMapping is SchemaToSnowflake with the configured timestamp_type
+	columnTypesMapping map[typing.DataType]string
+
+	//table sharding
+	sharder           *TableSharder
+	shardingUnionView bool
+	unionViewsMutex   sync.Mutex
+	//shard table name -> columns count (which are already selected by the union view)
+	unionViewShards map[string]int
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: SnowflakeType, createFunc: NewSnowflake, isSQL: true})
+	RegisterStorage(StorageType{typeName: SnowflakeType, createFunc: NewSnowflake, isSQL: true, configSchema: snowflakeSchema})
 }
 
 //NewSnowflake returns Snowflake and start goroutine for Snowflake batch storage or for stream consumer depend on destination mode
@@ -40,52 +103,55 @@ func NewSnowflake(config *Config) (Storage, error) {
 		snowflakeConfig.Schema = "PUBLIC"
 		logging.Warnf("[%s] schema wasn't provided. Will be used default one: %s", config.destinationID, snowflakeConfig.Schema)
 	}
+	if len(config.tablePrefix)+len(config.tableSuffix) >= adapters.SnowflakeMaxIdentifierLength {
+		return nil, fmt.Errorf("data_layout table_prefix and table_suffix must be shorter than Snowflake max identifier length: %d characters", adapters.SnowflakeMaxIdentifierLength)
+	}
 
 	//default client_session_keep_alive
 	if _, ok := snowflakeConfig.Parameters["client_session_keep_alive"]; !ok {
 		t := "true"
 		snowflakeConfig.Parameters["client_session_keep_alive"] = &t
 	}
-	var googleConfig *adapters.GoogleConfig
 	gc, err := config.destination.GetConfig(snowflakeConfig.Google, config.destination.Google, &adapters.GoogleConfig{})
 	if err != nil {
 		return nil, err
 	}
-	googleConfig, googleOk := gc.(*adapters.GoogleConfig)
-	if googleOk {
-		if err := googleConfig.Validate(); err != nil {
-			return nil, err
-		}
-		if !config.streamMode {
-			if err := googleConfig.ValidateBatchMode(); err != nil {
-				return nil, err
-			}
-		}
-
-		//stage is required when gcp integration
-		if snowflakeConfig.Stage == "" {
-			return nil, errors.New("Snowflake stage is required parameter in GCP integration")
-		}
-	}
-
-	var stageAdapter adapters.Stage
-	var s3config *adapters.S3Config
+	googleConfig, _ := gc.(*adapters.GoogleConfig)
 	s3c, err := config.destination.GetConfig(snowflakeConfig.S3, config.destination.S3, &adapters.S3Config{})
 	if err != nil {
 		return nil, err
 	}
-	s3config, s3ok := s3c.(*adapters.S3Config)
+	s3config, _ := s3c.(*adapters.S3Config)
+	normalizeStageConfigs(s3config, googleConfig)
+
+	var stageAdapter adapters.Stage
 	if !config.streamMode {
-		var err error
-		if s3ok {
-			stageAdapter, err = adapters.NewS3(s3config)
+		useS3, err := validateSnowflakeStage(s3config, googleConfig, snowflakeConfig.Stage)
+		if err != nil {
+			return nil, err
+		}
+
+		if useS3 {
+			if len(snowflakeConfig.S3Stages) > 0 {
+				stageAdapter, err = newMultiStage(config.destinationID, s3config, snowflakeConfig.S3Stages, snowflakeConfig.StageSelection, snowflakeConfig.DeploymentRegion)
+			} else {
+				stageAdapter, err = adapters.NewS3(s3config)
+			}
 			if err != nil {
-				return nil, err
+				return nil, logging.RedactError(err, snowflakeConfig, s3config)
 			}
 		} else {
+			if len(snowflakeConfig.S3Stages) > 0 {
+				return nil, errors.New("Snowflake s3_stages can be used only with S3 stage (s3 section)")
+			}
+			if err := googleConfig.Validate(); err != nil {
+				return nil, err
+			}
+			//COPY is run from GCP stage if s3 config is nil
+			s3config = nil
 			stageAdapter, err = adapters.NewGoogleCloudStorage(config.ctx, googleConfig)
 			if err != nil {
-				return nil, err
+				return nil, logging.RedactError(err, snowflakeConfig, googleConfig)
 			}
 		}
 	}
@@ -93,35 +159,123 @@ func NewSnowflake(config *Config) (Storage, error) {
 	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
 	snowflakeAdapter, err := CreateSnowflakeAdapter(config.ctx, s3config, *snowflakeConfig, queryLogger, config.sqlTypes)
 	if err != nil {
+		if stageAdapter != nil {
+			stageAdapter.Close()
+		}
+		//connection errors might contain DSN with the password
+		return nil, logging.RedactError(err, snowflakeConfig, s3config, googleConfig)
+	}
+
+	eventTime := newEventTimeResolver(SnowflakeType, config.destinationID, snowflakeConfig.TimestampField)
+	sharder, err := NewTableSharder(snowflakeConfig.TableSharding, eventTime.eventTime)
+	if err != nil {
+		snowflakeAdapter.Close()
 		if stageAdapter != nil {
 			stageAdapter.Close()
 		}
 		return nil, err
 	}
 
-	tableHelper := NewTableHelper(snowflakeConfig.Schema, snowflakeAdapter, config.coordinationService, config.pkFields, adapters.SchemaToSnowflake, config.maxColumns, SnowflakeType)
+	if snowflakeConfig.Preflight {
+		if err := snowflakePreflight(config.destinationID, stageAdapter, snowflakeAdapter.TestQuery); err != nil {
+			snowflakeAdapter.Close()
+			if stageAdapter != nil {
+				stageAdapter.Close()
+			}
+			return nil, logging.RedactError(err, snowflakeConfig, s3config, googleConfig)
+		}
+	}
+
+	pkFields := config.pkFields
+	if snowflakeConfig.AddLoadMetadata {
+		pkFields = withoutLoadMetadataColumns(config.destinationID, pkFields)
+	}
+
+	if config.streamMode && config.destination.DataLayout != nil && config.destination.DataLayout.OnConflict != "" {
+		if len(pkFields) == 0 {
+			logging.Warnf("[%s] on_conflict: %s is ignored because primary_key_fields aren't configured", config.destinationID, config.destination.DataLayout.OnConflict)
+		} else {
+			logging.Infof("[%s] primary key conflicts of streamed events are handled with on_conflict strategy: %s", config.destinationID, config.destination.DataLayout.OnConflict)
+		}
+		snowflakeAdapter.SetOnConflict(config.destination.DataLayout.OnConflict)
+	}
+
+	columnTypesMapping := snowflakeConfig.ColumnTypesMapping()
+	tableHelper := NewTableHelper(snowflakeConfig.Schema, snowflakeAdapter, config.coordinationService, pkFields, columnTypesMapping, config.maxColumns, SnowflakeType)
+	tableHelper.SetTableSharder(sharder)
+	tableHelper.SetTableNameAffixes(config.tablePrefix, config.tableSuffix)
+	tableHelper.SetIdentifierTruncator(config.processor.IdentifierTruncator(), config.processor.MaxColumnNameLength())
 
 	snowflake := &Snowflake{
 		stageAdapter:                  stageAdapter,
+		keepStageFiles:                snowflakeConfig.KeepStageFiles,
+		copyPurge:                     snowflakeConfig.CopyPurge,
+		storeTimeout:                  time.Duration(snowflakeConfig.StoreTimeoutSec) * time.Second,
+		closeTimeout:                  time.Duration(snowflakeConfig.CloseTimeoutSec) * time.Second,
+		updateBatchSize:               snowflakeConfig.UpdateBatchSize,
+		stageFileMaxRows:              snowflakeConfig.StageFileMaxRows,
+		stageUploadConcurrency:        snowflakeConfig.StageUploadConcurrency,
+		stageFormat:                   snowflakeConfig.StageFormat,
+		timestampType:                 snowflakeConfig.TimestampType,
+		columnTypesMapping:            columnTypesMapping,
 		snowflakeAdapter:              snowflakeAdapter,
 		usersRecognitionConfiguration: config.usersRecognition,
+		addLoadMetadata:               snowflakeConfig.AddLoadMetadata,
+		eventTime:                     eventTime,
+		sharder:                       sharder,
+		shardingUnionView:             snowflakeConfig.ShardingUnionView,
+		unionViewShards:               map[string]int{},
+	}
+
+	if sharder != nil {
+		logging.Infof("[%s] events will be written into %s table shards (union view: %t)", config.destinationID, snowflakeConfig.TableSharding, snowflakeConfig.ShardingUnionView)
+	}
+
+	if listableStage, ok := stageAdapter.(adapters.ListableStage); ok && snowflakeConfig.KeepStageFiles != adapters.KeepStageFilesNever {
+		logging.Infof("[%s] stage files will be kept (%s) for %d hours", config.destinationID, snowflakeConfig.KeepStageFiles, snowflakeConfig.StageFilesTTLHours)
+		snowflake.stageSweeper = newStageSweeper(config.destinationID, listableStage, time.Duration(snowflakeConfig.StageFilesTTLHours)*time.Hour)
+	}
+
+	if stageAdapter != nil && snowflakeConfig.CopyFlushRows > 0 {
+		logging.Infof("[%s] stage files will be loaded with a single COPY per %d rows or every %d seconds", config.destinationID, snowflakeConfig.CopyFlushRows, snowflakeConfig.CopyFlushInterval)
+		snowflake.copyBatcher = newCopyBatcher(snowflakeConfig.CopyFlushRows, time.Duration(snowflakeConfig.CopyFlushInterval)*time.Second, snowflake.flushCopyBatch, snowflake.discardCopyBatch)
 	}
 
 	//Abstract
 	snowflake.destinationID = config.destinationID
 	snowflake.processor = config.processor
 	snowflake.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	snowflake.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
+	snowflake.debugSampleRate = config.destination.DebugSampleRate
 	snowflake.eventsCache = config.eventsCache
 	snowflake.tableHelpers = []*TableHelper{tableHelper}
+	config.processor.SetTableColumnsFunc(tableHelper.TableColumns)
 	snowflake.sqlAdapters = []adapters.SQLAdapter{snowflakeAdapter}
 	snowflake.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	snowflake.uniqueIDField = config.uniqueIDField
 	snowflake.staged = config.destination.Staged
 	snowflake.cachingConfiguration = config.destination.CachingConfiguration
 
+	if snowflakeConfig.RetentionDays > 0 {
+		logging.Infof("[%s] rows of %v tables older than %d days by %s column will be deleted every %d hours", config.destinationID, snowflakeConfig.RetentionTables,
+			snowflakeConfig.RetentionDays, snowflakeConfig.RetentionColumn, snowflakeConfig.RetentionIntervalHours)
+		snowflake.retentionCleaner = newRetentionCleaner(config.destinationID, snowflake, snowflakeConfig.RetentionTables, snowflakeConfig.RetentionColumn,
+			time.Duration(snowflakeConfig.RetentionDays)*24*time.Hour, time.Duration(snowflakeConfig.RetentionIntervalHours)*time.Hour)
+	}
+
+	if statement := snowflakeConfig.PostLoadStatement(); statement != "" {
+		logging.Infof("[%s] post-load hook [%s] will be executed after successful loads at most every %d seconds", config.destinationID, statement, snowflakeConfig.PostLoadMinIntervalSec)
+		snowflake.postLoadHook = newPostLoadHook(config.destinationID, statement, time.Duration(snowflakeConfig.PostLoadMinIntervalSec)*time.Second, snowflakeAdapter.Execute)
+	}
+
 	//streaming worker (queue reading)
-	snowflake.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, snowflake, tableHelper)
+	snowflake.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, snowflake, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
+		//the processor isn't closed: it is reused on the next creation retry
+		snowflake.processor = nil
+		if closeErr := snowflake.Close(); closeErr != nil {
+			logging.Errorf("[%s] Error closing partially created Snowflake: %v", config.destinationID, closeErr)
+		}
 		return nil, err
 	}
 	snowflake.streamingWorker.start()
@@ -129,6 +283,123 @@ func NewSnowflake(config *Config) (Storage, error) {
 	return snowflake, nil
 }
 
+//snowflakePreflight writes and deletes a probe object in the stage (batch mode) and runs a test query
+//returns err with the failed step so permission problems are visible on the destination creation
+func snowflakePreflight(destinationID string, stageAdapter adapters.Stage, testQuery func() error) error {
+	stages := []adapters.Stage{}
+	if ms, ok := stageAdapter.(*multiStage); ok {
+		//every stage bucket is probed
+		for _, stage := range ms.stages {
+			stages = append(stages, stage.adapter)
+		}
+	} else if stageAdapter != nil {
+		stages = append(stages, stageAdapter)
+	}
+
+	for _, stage := range stages {
+		probeFileName := fmt.Sprintf("jitsu_preflight_%d", timestamp.Now().UnixNano())
+		if err := stage.UploadBytes(probeFileName, []byte("jitsu preflight probe")); err != nil {
+			return fmt.Errorf("Snowflake preflight failed: error writing probe object %s into the stage (check stage put permission): %v", probeFileName, err)
+		}
+		if err := stage.DeleteObject(probeFileName); err != nil {
+			return fmt.Errorf("Snowflake preflight failed: error deleting probe object %s from the stage (check stage delete permission): %v", probeFileName, err)
+		}
+	}
+
+	if err := testQuery(); err != nil {
+		return fmt.Errorf("Snowflake preflight failed: error running test query (check user, role and warehouse permissions): %v", err)
+	}
+
+	logging.Infof("[%s] Snowflake preflight has been passed", destinationID)
+	return nil
+}
+
+//validateSnowflakeStage checks that exactly one batch mode stage is fully configured:
+//s3 section or google section with snowflake stage. Returns true if s3 stage should be used
+func validateSnowflakeStage(s3Config *adapters.S3Config, googleConfig *adapters.GoogleConfig, snowflakeStage string) (bool, error) {
+	s3Configured := s3Config != nil && *s3Config != adapters.S3Config{}
+	googleConfigured := googleConfig != nil && (googleConfig.Bucket != "" || !isEmptyKeyFile(googleConfig.KeyFile))
+
+	switch {
+	case s3Configured && googleConfigured:
+		return false, errors.New("Snowflake stage is ambiguous: both s3 and google sections are configured. Please leave only one of them")
+	case s3Configured:
+		var missing []string
+		if s3Config.AccessKeyID == "" {
+			missing = append(missing, "s3.access_key_id")
+		}
+		if s3Config.SecretKey == "" {
+			missing = append(missing, "s3.secret_access_key")
+		}
+		if s3Config.Bucket == "" {
+			missing = append(missing, "s3.bucket")
+		}
+		if s3Config.Region == "" {
+			missing = append(missing, "s3.region")
+		}
+		if len(missing) > 0 {
+			return false, fmt.Errorf("Snowflake S3 stage is misconfigured: required parameters are missing: [%s]", strings.Join(missing, ", "))
+		}
+		if snowflakeStage != "" {
+			logging.Warnf("Snowflake stage [%s] is ignored: S3 stage is configured with s3 section", snowflakeStage)
+		}
+		return true, nil
+	case googleConfigured:
+		var missing []string
+		if googleConfig.Bucket == "" {
+			missing = append(missing, "google.gcs_bucket")
+		}
+		if isEmptyKeyFile(googleConfig.KeyFile) {
+			missing = append(missing, "google.key_file")
+		}
+		if snowflakeStage == "" {
+			missing = append(missing, "snowflake.stage")
+		}
+		if len(missing) > 0 {
+			return false, fmt.Errorf("Snowflake GCS stage is misconfigured: required parameters are missing: [%s]", strings.Join(missing, ", "))
+		}
+		return false, nil
+	default:
+		return false, errors.New("Snowflake stage is required in batch mode: configure either s3 section (access_key_id, secret_access_key, bucket, region) or google section (gcs_bucket, key_file) with snowflake.stage")
+	}
+}
+
+//normalizeStageConfigs removes s3:// (gs://) bucket prefixes and leading/trailing slashes
+//bucket with path (e.g. s3://bucket/path) is split into bucket and folder prefix
+func normalizeStageConfigs(s3Config *adapters.S3Config, googleConfig *adapters.GoogleConfig) {
+	if s3Config != nil {
+		bucket := strings.Trim(strings.TrimPrefix(strings.TrimSpace(s3Config.Bucket), "s3://"), "/")
+		folder := strings.Trim(strings.TrimSpace(s3Config.Folder), "/")
+		if parts := strings.SplitN(bucket, "/", 2); len(parts) == 2 {
+			bucket = parts[0]
+			if folder == "" {
+				folder = parts[1]
+			} else {
+				folder = parts[1] + "/" + folder
+			}
+		}
+		s3Config.Bucket = bucket
+		s3Config.Folder = folder
+	}
+
+	if googleConfig != nil {
+		googleConfig.Bucket = strings.Trim(strings.TrimPrefix(strings.TrimSpace(googleConfig.Bucket), "gs://"), "/")
+	}
+}
+
+func isEmptyKeyFile(keyFile interface{}) bool {
+	switch value := keyFile.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+
+	return false
+}
+
 //CreateSnowflakeAdapter creates snowflake adapter with schema
 //if schema doesn't exist - snowflake returns error. In this case connect without schema and create it
 func CreateSnowflakeAdapter(ctx context.Context, s3Config *adapters.S3Config, config adapters.SnowflakeConfig,
@@ -169,45 +440,57 @@ func CreateSnowflakeAdapter(ctx context.Context, s3Config *adapters.S3Config, co
 //Store process events and stores with storeTable() func
 //returns store result per table, failed events (group of events which are failed to process) and err
 func (s *Snowflake) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
+	ctx, span := tracing.StartSpan(context.Background(), "Store", tracing.DestinationID(s.ID()))
+	defer span.End()
+	//batch file name is the correlation ID of all batch log messages and fallback records
+	ctx = logging.WithContext(ctx, logging.NewContext(s.ID(), fileName))
+
 	_, tableHelper := s.getAdapters()
+	_, processSpan := tracing.StartSpan(ctx, "ProcessEvents", tracing.DestinationID(s.ID()))
 	flatData, failedEvents, skippedEvents, err := s.processor.ProcessEvents(fileName, objects, alreadyUploadedTables)
+	tracing.EndSpan(processSpan, err)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	//update cache with failed events
-	for _, failedEvent := range failedEvents.Events {
-		s.eventsCache.Error(s.IsCachingDisabled(), s.ID(), failedEvent.EventID, failedEvent.Error)
-	}
-	//update cache and counter with skipped events
-	for _, skipEvent := range skippedEvents.Events {
-		s.eventsCache.Skip(s.IsCachingDisabled(), s.ID(), skipEvent.EventID, skipEvent.Error)
-	}
+	//update cache with failed and skipped events
+	s.cacheProcessingResults(failedEvents, skippedEvents)
 
 	storeFailedEvents := true
+	loaded := false
 	tableResults := map[string]*StoreResult{}
-	for _, fdata := range flatData {
+	files, baseTableNames := s.splitByShards(flatData, alreadyUploadedTables)
+	for shardName, fdata := range files {
 		table := tableHelper.MapTableSchema(fdata.BatchHeader)
-		err := s.storeTable(fdata, table)
-		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
-		if err != nil {
+		if baseTableName, ok := baseTableNames[shardName]; ok {
+			//shard suffix is added after table prefix and suffix (the same way as in stream mode)
+			baseHeader := &schema.BatchHeader{TableName: baseTableName, Fields: fdata.BatchHeader.Fields}
+			table = tableHelper.MapObjectTableSchema(baseHeader, fdata.GetPayload()[0])
+		}
+		start := time.Now()
+		err := s.storeTableWithTimeout(ctx, fdata, table)
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc(), Latency: time.Since(start)}
+		if errors.Is(err, ErrCopyPending) {
+			//COPY result will be returned by the next Store of the file
+			storeFailedEvents = false
+			continue
+		}
+		if errors.Is(err, ErrBadData) || errors.Is(err, ErrTimeout) {
+			//retries won't help or the table might block the whole pipeline again
+			s.fallbackTable(ctx, fdata, err)
+		} else if err != nil {
 			storeFailedEvents = false
+		} else {
+			loaded = true
 		}
 
 		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				s.eventsCache.Error(s.IsCachingDisabled(), s.ID(), s.uniqueIDField.Extract(object), err.Error())
-			} else {
-				s.eventsCache.Succeed(&adapters.EventContext{
-					CacheDisabled:  s.IsCachingDisabled(),
-					DestinationID:  s.ID(),
-					EventID:        s.uniqueIDField.Extract(object),
-					ProcessedEvent: object,
-					Table:          table,
-				})
-			}
-		}
+		s.cacheStoreResult(fdata.GetPayload(), table, err)
+	}
+
+	//once per Store (not per table)
+	if loaded {
+		s.triggerPostLoadHook()
 	}
 
 	//store failed events to fallback only if other events have been inserted ok
@@ -218,31 +501,681 @@ func (s *Snowflake) Store(fileName string, objects []map[string]interface{}, alr
 	return tableResults, nil, skippedEvents, nil
 }
 
+//storeTableWithTimeout runs storeTable with store_timeout_sec (if configured)
+//returns ErrTimeout StoreError if the timeout has been exceeded: the stage upload is abandoned, COPY is canceled and rolled back
+//(interrupted COPY is checked in the load history: committed COPY isn't reported as timed out)
+func (s *Snowflake) storeTableWithTimeout(ctx context.Context, fdata *schema.ProcessedFile, table *adapters.Table) error {
+	if s.storeTimeout <= 0 {
+		return s.storeTable(ctx, fdata, table)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.storeTimeout)
+	defer cancel()
+
+	err := s.storeTable(ctx, fdata, table)
+	if err != nil && ctx.Err() == context.DeadlineExceeded && !errors.Is(err, errCopyOutcomeUnknown) {
+		logging.FromContext(ctx).Warnf("Storing table %s has exceeded store_timeout_sec [%s]", table.Name, s.storeTimeout)
+		metrics.StoreTimeout(s.Type(), s.ID())
+		return NewStoreError(ErrTimeout, fmt.Sprintf("Error storing table %s: store_timeout_sec [%s] has been exceeded", table.Name, s.storeTimeout), err)
+	}
+
+	return err
+}
+
+//uploadToStage uploads the file into the stage. If ctx is done before the upload is finished, returns ctx error
+//and releases the file after the upload (it won't be copied)
+func (s *Snowflake) uploadToStage(ctx context.Context, fileName string, b []byte) error {
+	if ctx.Done() == nil {
+		return s.uploadBytes(fileName, b)
+	}
+
+	result := make(chan error, 1)
+	safego.Run(func() {
+		result <- s.stageAdapter.UploadBytes(fileName, b)
+	})
+
+	select {
+	case err := <-result:
+		if err == nil {
+			s.stats.staged(len(b))
+		}
+		return err
+	case <-ctx.Done():
+		safego.Run(func() {
+			if err := <-result; err == nil {
+				s.releaseStageFile(fileName, ctx.Err())
+			}
+		})
+		return ctx.Err()
+	}
+}
+
+//uploadBytes uploads the file into the stage and accounts staged bytes
+func (s *Snowflake) uploadBytes(fileName string, b []byte) error {
+	if err := s.stageAdapter.UploadBytes(fileName, b); err != nil {
+		return err
+	}
+
+	s.stats.staged(len(b))
+	return nil
+}
+
 //check table schema
 //and store data into one table via stage (google cloud storage or s3)
-func (s *Snowflake) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+//returns StoreError (ErrTransient, ErrBadData or ErrConfig) if the error can be classified
+func (s *Snowflake) storeTable(ctx context.Context, fdata *schema.ProcessedFile, table *adapters.Table) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "storeTable", tracing.DestinationID(s.ID()), tracing.Table(table.Name))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	if err = validateSnowflakeTableName(table.Name); err != nil {
+		return err
+	}
+
+	if s.addLoadMetadata {
+		s.addLoadMetadataColumns(fdata, table)
+	}
+
+	//table has been uploaded into stage for batched COPY by the previous Store of the file
+	if s.copyBatcher != nil {
+		if registered, copyErr := s.copyBatcher.result(fdata); registered {
+			return copyErr
+		}
+	}
+
+	s.sample(table.Name, fdata.GetPayload())
 	_, tableHelper := s.getAdapters()
 	dbTable, err := tableHelper.EnsureTableWithoutCaching(s.ID(), table)
 	if err != nil {
+		return classifySnowflakeError("", err)
+	}
+
+	//file will be split into chunks which are uploaded in parallel and loaded with one COPY
+	if s.copyBatcher == nil && s.stageFileMaxRows > 0 && fdata.GetPayloadLen() > s.stageFileMaxRows {
+		return s.storeChunks(ctx, fdata, dbTable)
+	}
+
+	b, header, err := s.marshall(fdata)
+	if err != nil {
+		return NewStoreError(ErrBadData, fmt.Sprintf("Error marshalling %s stage file [%s]", s.stageFormat, fdata.FileName), err)
+	}
+
+	//file will be loaded with other files of the table by flushCopyBatch
+	if s.copyBatcher != nil {
+		err = s.copyBatcher.add(dbTable, header, fdata, func(key string) error {
+			_, uploadSpan := tracing.StartSpan(ctx, "StageUpload", tracing.DestinationID(s.ID()), tracing.Table(table.Name))
+			uploadErr := s.uploadToStage(ctx, key, b)
+			tracing.EndSpan(uploadSpan, uploadErr)
+			return uploadErr
+		})
+		if err != nil {
+			return classifySnowflakeError("", err)
+		}
+		logging.FromContext(ctx).Debugf("%d rows have been uploaded into stage for batched COPY into %s table", fdata.GetPayloadLen(), dbTable.Name)
+		return ErrCopyPending
+	}
+
+	_, uploadSpan := tracing.StartSpan(ctx, "StageUpload", tracing.DestinationID(s.ID()), tracing.Table(table.Name))
+	err = s.uploadToStage(ctx, fdata.FileName, b)
+	tracing.EndSpan(uploadSpan, err)
+	if err != nil {
+		return classifySnowflakeError("", err)
+	}
+
+	_, copySpan := tracing.StartSpan(ctx, "Copy", tracing.DestinationID(s.ID()), tracing.Table(dbTable.Name))
+	copyStarted := timestamp.Now()
+	copyErr := s.copyStageFile(ctx, fdata.FileName, dbTable.Name, header)
+	copyErr = s.checkInterruptedCopy(ctx, dbTable.Name, fdata.FileName, copyStarted, copyErr)
+	tracing.EndSpan(copySpan, copyErr)
+	s.releaseStageFile(fdata.FileName, copyErr)
+	if errors.Is(copyErr, errCopyOutcomeUnknown) {
+		return copyErr
+	}
+	if copyErr != nil {
+		return classifySnowflakeError(fmt.Sprintf("Error copying file [%s] from stage to snowflake", fdata.FileName), copyErr)
+	}
+
+	logging.FromContext(ctx).Debugf("%d rows have been copied into %s table", fdata.GetPayloadLen(), dbTable.Name)
+	s.ensureUnionView(dbTable)
+	return nil
+}
+
+//checkInterruptedCopy checks the load history if COPY of the stage file has been interrupted (ctx is done)
+//because COPY might have been committed right before the interruption. Returns nil if the file has been loaded,
+//copyErr if it hasn't or transient StoreError (errCopyOutcomeUnknown) if the history can't be checked:
+//such table isn't written into fallback because the replay might duplicate rows
+func (s *Snowflake) checkInterruptedCopy(ctx context.Context, tableName, fileName string, copyStarted time.Time, copyErr error) error {
+	if copyErr == nil || ctx.Err() == nil {
+		return copyErr
+	}
+
+	//load history time is the Snowflake server time
+	loaded, err := s.snowflakeAdapter.FileLoaded(tableName, fileName, copyStarted.Add(-copyHistoryClockSkew))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("COPY of file [%s] into %s table has been interrupted and its outcome is unknown: %v", fileName, tableName, err)
+		return NewStoreError(ErrTransient, fmt.Sprintf("COPY of file [%s] into %s table has been interrupted (%v)", fileName, tableName, copyErr),
+			fmt.Errorf("%w: %v", errCopyOutcomeUnknown, err))
+	}
+	if loaded {
+		logging.FromContext(ctx).Warnf("COPY of file [%s] into %s table has been interrupted after the commit: %v", fileName, tableName, copyErr)
+		return nil
+	}
+
+	return copyErr
+}
+
+//copyStageFile runs COPY of the stage file from the bucket which has been used for the file upload
+func (s *Snowflake) copyStageFile(ctx context.Context, fileName, tableName string, header []string) error {
+	if ms, ok := s.stageAdapter.(*multiStage); ok {
+		return s.snowflakeAdapter.CopyFromStage(ctx, ms.S3Config(fileName), fileName, tableName, header)
+	}
+
+	return s.snowflakeAdapter.Copy(ctx, fileName, tableName, header)
+}
+
+//storeChunks splits the table file into stage_file_max_rows chunks, uploads them under a common stage folder
+//with stage_upload_concurrency parallel uploads and loads the folder with one COPY
+func (s *Snowflake) storeChunks(ctx context.Context, fdata *schema.ProcessedFile, dbTable *adapters.Table) error {
+	chunks := fdata.SplitByRows(s.stageFileMaxRows)
+	prefix := stageChunksFolder + "/" + dbTable.Name + "/" + uuid.New()
+	keys := make([]string, len(chunks))
+	payloads := make([][]byte, len(chunks))
+	var header []string
+	for i, chunk := range chunks {
+		b, chunkHeader, err := s.marshall(chunk)
+		if err != nil {
+			return NewStoreError(ErrBadData, fmt.Sprintf("Error marshalling %s stage file [%s] chunk %d", s.stageFormat, fdata.FileName, i), err)
+		}
+		keys[i] = fmt.Sprintf("%s/%s_%d", prefix, fdata.FileName, i)
+		payloads[i] = b
+		header = chunkHeader
+	}
+
+	_, uploadSpan := tracing.StartSpan(ctx, "StageUpload", tracing.DestinationID(s.ID()), tracing.Table(dbTable.Name))
+	err := s.uploadChunks(ctx, keys, payloads)
+	tracing.EndSpan(uploadSpan, err)
+	if err != nil {
+		return classifySnowflakeError(fmt.Sprintf("Error uploading file [%s] chunks into stage", fdata.FileName), err)
+	}
+
+	_, copySpan := tracing.StartSpan(ctx, "Copy", tracing.DestinationID(s.ID()), tracing.Table(dbTable.Name))
+	copyStarted := timestamp.Now()
+	copyErr := s.copyStagePrefix(ctx, prefix, keys[0], dbTable.Name, header)
+	//all chunks are loaded in one COPY transaction: the first one is checked
+	copyErr = s.checkInterruptedCopy(ctx, dbTable.Name, keys[0], copyStarted, copyErr)
+	tracing.EndSpan(copySpan, copyErr)
+	for _, key := range keys {
+		s.releaseStageFile(key, copyErr)
+	}
+	if errors.Is(copyErr, errCopyOutcomeUnknown) {
+		return copyErr
+	}
+	if copyErr != nil {
+		return classifySnowflakeError(fmt.Sprintf("Error copying file [%s] chunks from stage to snowflake", fdata.FileName), copyErr)
+	}
+
+	logging.FromContext(ctx).Debugf("%d rows (%d stage files) have been copied into %s table", fdata.GetPayloadLen(), len(keys), dbTable.Name)
+	s.ensureUnionView(dbTable)
+	return nil
+}
+
+//uploadChunks uploads files into the stage with at most stage_upload_concurrency parallel uploads
+//the first failed upload cancels the remaining ones: already uploaded files are deleted and all upload errors are returned
+func (s *Snowflake) uploadChunks(ctx context.Context, keys []string, payloads [][]byte) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		multiErr error
+		uploaded []string
+	)
+	semaphore := make(chan struct{}, s.stageUploadConcurrency)
+	for i := range keys {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		key, payload := keys[i], payloads[i]
+		wg.Add(1)
+		safego.Run(func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := s.uploadToStage(ctx, key, payload)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch {
+			case err == nil:
+				uploaded = append(uploaded, key)
+			case err == context.Canceled && multiErr != nil:
+				//canceled because of another failed upload
+			default:
+				multiErr = multierror.Append(multiErr, fmt.Errorf("stage file %s: %w", key, err))
+				cancel()
+			}
+		})
+	}
+	wg.Wait()
+
+	//parent context is done before all uploads have been started
+	if multiErr == nil && ctx.Err() != nil {
+		multiErr = ctx.Err()
+	}
+
+	if multiErr != nil {
+		for _, key := range uploaded {
+			if err := s.stageAdapter.DeleteObject(key); err != nil {
+				logging.SystemErrorf("[%s] file %s wasn't deleted from stage: %v", s.ID(), key, err)
+			}
+		}
+	}
+
+	return multiErr
+}
+
+//copyStagePrefix runs COPY of the stage folder from the bucket which has been used for the folder files (fileName is one of them)
+func (s *Snowflake) copyStagePrefix(ctx context.Context, prefix, fileName, tableName string, header []string) error {
+	if ms, ok := s.stageAdapter.(*multiStage); ok {
+		return s.snowflakeAdapter.CopyPrefixFromStage(ctx, ms.S3Config(fileName), prefix, tableName, header)
+	}
+
+	return s.snowflakeAdapter.CopyPrefix(ctx, prefix, tableName, header)
+}
+
+//addLoadMetadataColumns adds load metadata columns into the table schema and the file header (if they don't exist)
+//and writes load time, stage file name, destination ID and event time (by timestamp_field) into every object
+func (s *Snowflake) addLoadMetadataColumns(fdata *schema.ProcessedFile, table *adapters.Table) {
+	fields := map[string]typing.DataType{loadedAtColumn: typing.TIMESTAMP, sourceFileColumn: typing.STRING, destinationIDColumn: typing.STRING, eventTimeColumn: typing.TIMESTAMP}
+	for name, dataType := range fields {
+		if _, ok := table.Columns[name]; !ok {
+			table.Columns[name] = typing.SQLColumn{Type: s.columnTypesMapping[dataType]}
+		}
+		if _, ok := fdata.BatchHeader.Fields[name]; !ok {
+			fdata.BatchHeader.Fields[name] = schema.NewField(dataType)
+		}
+	}
+
+	loadedAt := timestamp.NowUTC()
+	for _, object := range fdata.GetPayload() {
+		object[loadedAtColumn] = loadedAt
+		object[sourceFileColumn] = fdata.FileName
+		object[destinationIDColumn] = s.ID()
+
+		//fallbacks are counted by table sharding if it is enabled
+		eventTime, ok := s.eventTime.resolve(object)
+		if !ok && s.sharder == nil {
+			s.eventTime.fallback()
+		}
+		object[eventTimeColumn] = timestamp.ToISOFormat(eventTime)
+	}
+}
+
+//withoutLoadMetadataColumns returns primary key fields without load metadata columns: they are different in every load
+//and mustn't be used for deduplication
+func withoutLoadMetadataColumns(destinationID string, pkFields map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(pkFields))
+	for field, value := range pkFields {
+		if field == loadedAtColumn || field == sourceFileColumn || field == destinationIDColumn {
+			logging.Warnf("[%s] load metadata column %s is excluded from primary key fields", destinationID, field)
+			continue
+		}
+		result[field] = value
+	}
+
+	return result
+}
+
+//marshall returns stage file payload in the configured stage format and csv header (only for csv format)
+func (s *Snowflake) marshall(fdata *schema.ProcessedFile) ([]byte, []string, error) {
+	if s.timestampType == adapters.TimestampNTZ {
+		for _, object := range fdata.GetPayload() {
+			for name, value := range object {
+				object[name] = adapters.NormalizeSnowflakeTimestamp(s.timestampType, value)
+			}
+		}
+	}
+
+	switch s.stageFormat {
+	case adapters.StageFormatJSON:
+		return fdata.GetPayloadBytes(schema.JSONMarshallerInstance), nil, nil
+	case adapters.StageFormatParquet:
+		b, err := fdata.GetPayloadUsingStronglyTypedMarshaller(schema.NewParquetMarshaller(false))
+		return b, nil, err
+	default:
+		b, header := fdata.GetPayloadBytesWithHeader(schema.VerticalBarSeparatedMarshallerInstance)
+		return b, header, nil
+	}
+}
+
+//RepairSchemas runs RepairSchema for the tableName (or all tables if tableName is empty)
+//expected tables schemas are taken from the in-memory mapped schemas
+func (s *Snowflake) RepairSchemas(tableName string) ([]*adapters.SchemaRepair, error) {
+	_, tableHelper := s.getAdapters()
+	tableNames := tableHelper.CachedTableNames()
+	if tableName != "" {
+		tableNames = []string{tableName}
+	}
+	sort.Strings(tableNames)
+
+	repairs := []*adapters.SchemaRepair{}
+	for _, name := range tableNames {
+		expected, ok := tableHelper.CachedTable(name)
+		if !ok {
+			return nil, fmt.Errorf("Table %s schema is unknown: no events have been stored into the table since the destination initialization", name)
+		}
+
+		repair, err := s.RepairSchema(expected)
+		if err != nil {
+			return nil, fmt.Errorf("Error repairing table %s schema: %v", name, err)
+		}
+		repairs = append(repairs, repair)
+	}
+
+	return repairs, nil
+}
+
+//RepairSchema compares the live table with the expected (mapped) table schema and reconciles them with the minimal DDL:
+//creates the table if it doesn't exist, adds missing columns and widens column types where it is safe
+//changes which can't be made safely (narrowing, incompatible types, drops) are returned in SchemaRepair.Skipped
+func (s *Snowflake) RepairSchema(table *adapters.Table) (*adapters.SchemaRepair, error) {
+	_, tableHelper := s.getAdapters()
+	tableLock, err := tableHelper.lockTable(s.ID(), table.Name, tableHelper.getTableIdentifier(s.ID(), table.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer tableLock.Unlock()
+
+	repair := &adapters.SchemaRepair{Table: table.Name}
+	live, err := s.snowflakeAdapter.GetTableSchema(table.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !live.Exists() {
+		if err := s.snowflakeAdapter.CreateTable(table); err != nil {
+			return nil, err
+		}
+		for name, column := range table.Columns {
+			repair.Added = append(repair.Added, name+" "+column.DDLType())
+		}
+	} else {
+		toAdd, toWiden, skipped := adapters.PlanSnowflakeSchemaRepair(live, table)
+		repair.Skipped = skipped
+		if len(toAdd) > 0 {
+			if err := s.snowflakeAdapter.PatchTableSchema(&adapters.Table{Schema: table.Schema, Name: table.Name, Columns: toAdd}); err != nil {
+				return nil, err
+			}
+			for name, column := range toAdd {
+				repair.Added = append(repair.Added, name+" "+column.DDLType())
+			}
+		}
+		for name, columnType := range toWiden {
+			if err := s.snowflakeAdapter.AlterColumnType(table.Name, name, columnType); err != nil {
+				return nil, err
+			}
+			repair.Widened = append(repair.Widened, name+" "+columnType)
+		}
+	}
+	sort.Strings(repair.Added)
+	sort.Strings(repair.Widened)
+
+	//refresh in-memory schema
+	refreshed, err := s.snowflakeAdapter.GetTableSchema(table.Name)
+	if err != nil {
+		return nil, err
+	}
+	tableHelper.Lock()
+	tableHelper.tables[refreshed.Name] = refreshed
+	tableHelper.Unlock()
+
+	logging.Infof("[%s] table %s schema has been repaired: added %v, widened %v, skipped %v", s.ID(), table.Name, repair.Added, repair.Widened, repair.Skipped)
+	return repair, nil
+}
+
+//splitByShards splits processed files into files per table shard if table sharding is enabled
+//returns files per shard name and base table name per shard name. Already uploaded shards are skipped
+func (s *Snowflake) splitByShards(flatData map[string]*schema.ProcessedFile, alreadyUploadedTables map[string]bool) (map[string]*schema.ProcessedFile, map[string]string) {
+	if s.sharder == nil {
+		return flatData, nil
+	}
+
+	result := map[string]*schema.ProcessedFile{}
+	baseTableNames := map[string]string{}
+	for tableName, fdata := range flatData {
+		shards := fdata.SplitByTableName(func(object map[string]interface{}) string {
+			return s.sharder.ShardTableName(tableName, object)
+		})
+		for shardName, shard := range shards {
+			if alreadyUploadedTables[shardName] {
+				continue
+			}
+			result[shardName] = shard
+			baseTableNames[shardName] = tableName
+		}
+	}
+
+	return result, baseTableNames
+}
+
+//ensureUnionView creates (or replaces) the view with the base table name which selects all table shards
+//if the shard is new or it has new columns. Errors are only logged
+func (s *Snowflake) ensureUnionView(shard *adapters.Table) {
+	if s.sharder == nil || !s.shardingUnionView {
+		return
+	}
+
+	baseTableName, ok := s.sharder.BaseTableName(shard.Name)
+	if !ok {
+		return
+	}
+
+	s.unionViewsMutex.Lock()
+	defer s.unionViewsMutex.Unlock()
+
+	if columnsCount, ok := s.unionViewShards[shard.Name]; ok && columnsCount == len(shard.Columns) {
+		return
+	}
+
+	if err := s.replaceUnionView(baseTableName); err != nil {
+		logging.Errorf("[%s] %v", s.ID(), err)
+		return
+	}
+
+	//table name in the DWH might be in another case
+	s.unionViewShards[shard.Name] = len(shard.Columns)
+}
+
+//replaceUnionView creates (or replaces) the view with the base table name which selects all existing table shards
+//must be called under unionViewsMutex
+func (s *Snowflake) replaceUnionView(baseTableName string) error {
+	tableNames, err := s.snowflakeAdapter.GetTableNamesByPrefix(baseTableName + "_")
+	if err != nil {
+		return fmt.Errorf("Error creating %s union view: %v", baseTableName, err)
+	}
+
+	var shards []*adapters.Table
+	for _, tableName := range tableNames {
+		if !s.sharder.IsShardOf(baseTableName, tableName) {
+			continue
+		}
+		table, err := s.snowflakeAdapter.GetTableSchema(tableName)
+		if err != nil {
+			return fmt.Errorf("Error creating %s union view: %v", baseTableName, err)
+		}
+		shards = append(shards, table)
+	}
+	if len(shards) == 0 {
+		return nil
+	}
+
+	if err := s.snowflakeAdapter.CreateOrReplaceUnionView(baseTableName, shards); err != nil {
 		return err
 	}
 
-	b, header := fdata.GetPayloadBytesWithHeader(schema.VerticalBarSeparatedMarshallerInstance)
-	if err := s.stageAdapter.UploadBytes(fdata.FileName, b); err != nil {
+	for _, table := range shards {
+		s.unionViewShards[table.Name] = len(table.Columns)
+	}
+
+	return nil
+}
+
+//Insert inserts event via Abstract and creates the union view if table sharding is enabled
+//primary key conflicts resolved by on_conflict are written into metrics
+func (s *Snowflake) Insert(eventContext *adapters.EventContext) error {
+	if err := validateSnowflakeTableName(eventContext.Table.Name); err != nil {
+		//metrics/counters/cache/fallback
+		s.AccountResult(eventContext, err)
+		return err
+	}
+
+	if err := s.Abstract.Insert(eventContext); err != nil {
+		return err
+	}
+
+	if eventContext.ConflictResolution != "" {
+		metrics.StreamConflict(s.Type(), s.ID(), eventContext.ConflictResolution)
+	}
+
+	s.ensureUnionView(eventContext.Table)
+	return nil
+}
+
+//classifySnowflakeError returns StoreError with the kind according to sf.SnowflakeError code (or SQL state)
+//connection errors are classified as ErrTransient. Other errors are returned as is (with msg prefix)
+func classifySnowflakeError(msg string, err error) error {
+	var sfErr *sf.SnowflakeError
+	if errors.As(err, &sfErr) {
+		switch {
+		case sfErr.Number >= snowflakeDataLoadingErrorsMin && sfErr.Number <= snowflakeDataLoadingErrorsMax:
+			return NewStoreError(ErrBadData, msg, err)
+		case sfErr.Number == sf.ErrObjectNotExistOrAuthorized, sfErr.Number == sf.ErrRoleNotExist,
+			sfErr.Number == sf.ErrFailedToAuth, sfErr.Number == sf.ErrFailedToAuthSAML, sfErr.Number == sf.ErrFailedToAuthOKTA,
+			sfErr.Number == snowflakeNoActiveWarehouse, sfErr.Number == snowflakeIncorrectCredentials:
+			return NewStoreError(ErrConfig, msg, err)
+		case sfErr.Number == sf.ErrSessionGone,
+			sfErr.Number >= snowflakeDriverErrorsMin && sfErr.Number <= snowflakeDriverErrorsMax:
+			return NewStoreError(ErrTransient, msg, err)
+		}
+
+		//SQL state classes: 22 - data exception, 08 - connection exception, 28 - invalid authorization
+		switch {
+		case strings.HasPrefix(sfErr.SQLState, "22"):
+			return NewStoreError(ErrBadData, msg, err)
+		case strings.HasPrefix(sfErr.SQLState, "08"):
+			return NewStoreError(ErrTransient, msg, err)
+		case strings.HasPrefix(sfErr.SQLState, "28"):
+			return NewStoreError(ErrConfig, msg, err)
+		}
+	}
+
+	if IsConnectionError(err) {
+		return NewStoreError(ErrTransient, msg, err)
+	}
+
+	if msg == "" {
 		return err
 	}
+	return fmt.Errorf("%s: %v", msg, err)
+}
 
-	if err := s.snowflakeAdapter.Copy(fdata.FileName, dbTable.Name, header); err != nil {
-		return fmt.Errorf("Error copying file [%s] from stage to snowflake: %v", fdata.FileName, err)
+//validateSnowflakeTableName returns ErrBadData StoreError if the table name (with table prefix, suffix and shard suffix)
+//exceeds Snowflake identifier length limit
+func validateSnowflakeTableName(tableName string) error {
+	if len(tableName) > adapters.SnowflakeMaxIdentifierLength {
+		return NewStoreError(ErrBadData, "", fmt.Errorf("table name %s exceeds Snowflake max identifier length: %d characters", tableName, adapters.SnowflakeMaxIdentifierLength))
 	}
 
-	if err := s.stageAdapter.DeleteObject(fdata.FileName); err != nil {
-		logging.SystemErrorf("[%s] file %s wasn't deleted from stage: %v", s.ID(), fdata.FileName, err)
+	return nil
+}
+
+//flushCopyBatch loads all batch stage files with a single COPY and deletes (or keeps) exactly these files
+//returns classified COPY error: it is the store result of all batch tables (see copyBatcher.result)
+func (s *Snowflake) flushCopyBatch(batch *copyBatch) error {
+	_, copySpan := tracing.StartSpan(context.Background(), "Copy", tracing.DestinationID(s.ID()), tracing.Table(batch.table.Name))
+	copyErr := s.snowflakeAdapter.CopyPrefix(context.Background(), batch.prefix, batch.table.Name, batch.header)
+	tracing.EndSpan(copySpan, copyErr)
+
+	for _, file := range batch.files {
+		s.releaseStageFile(file.key, copyErr)
 	}
 
+	if copyErr != nil {
+		logging.Errorf("[%s] Error copying %d stage files (%d rows) [%s] into %s table: %v", s.ID(), len(batch.files), batch.rows, batch.prefix, batch.table.Name, copyErr)
+		return classifySnowflakeError(fmt.Sprintf("Error copying %d files [%s] from stage to snowflake", len(batch.files), batch.prefix), copyErr)
+	}
+
+	logging.Debugf("[%s] %d stage files (%d rows) have been copied into %s table", s.ID(), len(batch.files), batch.rows, batch.table.Name)
+	s.ensureUnionView(batch.table)
+	s.triggerPostLoadHook()
 	return nil
 }
 
+//triggerPostLoadHook schedules the post-load hook run (if configured)
+func (s *Snowflake) triggerPostLoadHook() {
+	if s.postLoadHook != nil {
+		s.postLoadHook.trigger()
+	}
+}
+
+//discardCopyBatch deletes stage files of the batch which hasn't been flushed before Close
+//source files haven't been archived: they will be stored again
+func (s *Snowflake) discardCopyBatch(batch *copyBatch) {
+	logging.Warnf("[%s] %d stage files (%d rows) of %s table haven't been copied before close: they will be uploaded again", s.ID(), len(batch.files), batch.rows, batch.table.Name)
+	for _, file := range batch.files {
+		if err := s.stageAdapter.DeleteObject(file.key); err != nil {
+			logging.SystemErrorf("[%s] file %s wasn't deleted from stage: %v", s.ID(), file.key, err)
+		}
+	}
+}
+
+//fallbackTable writes all table objects into the fallback logger
+func (s *Snowflake) fallbackTable(ctx context.Context, fdata *schema.ProcessedFile, err error) {
+	logContext := logging.FromContext(ctx)
+	logContext.Errorf("Error storing table %s: %v. %d objects will be written into fallback", fdata.BatchHeader.TableName, err, fdata.GetPayloadLen())
+	for _, object := range fdata.GetPayload() {
+		b, _ := json.Marshal(object)
+		s.Fallback(&events.FailedEvent{
+			Event:          b,
+			Error:          err.Error(),
+			EventID:        s.uniqueIDField.Extract(object),
+			TableName:      fdata.BatchHeader.TableName,
+			Classification: ClassifyError(err),
+			CorrelationID:  logContext.CorrelationID(),
+		})
+	}
+}
+
+//releaseStageFile deletes stage file or keeps it (and registers in the sweeper) according to keep_stage_files configuration
+//successfully loaded files aren't deleted if copy_purge is enabled: they have been already deleted by COPY
+func (s *Snowflake) releaseStageFile(fileName string, copyErr error) {
+	switch {
+	case s.copyPurge && copyErr == nil:
+		logging.Debugf("[%s] stage file %s has been purged by COPY", s.ID(), fileName)
+		return
+	case s.keepStageFiles == adapters.KeepStageFilesAlways:
+		logging.Debugf("[%s] stage file %s has been kept", s.ID(), fileName)
+	case s.keepStageFiles == adapters.KeepStageFilesOnError && copyErr != nil:
+		logging.Warnf("[%s] stage file %s has been kept for debugging: COPY failed: %v", s.ID(), fileName, copyErr)
+	default:
+		if err := s.stageAdapter.DeleteObject(fileName); err != nil {
+			logging.SystemErrorf("[%s] file %s wasn't deleted from stage: %v", s.ID(), fileName, err)
+		}
+		return
+	}
+
+	if s.stageSweeper != nil {
+		s.stageSweeper.keep(fileName)
+	}
+}
+
 //GetUsersRecognition returns users recognition configuration
 func (s *Snowflake) GetUsersRecognition() *UserRecognitionConfiguration {
 	return s.usersRecognitionConfiguration
@@ -257,8 +1190,109 @@ func (s *Snowflake) Clean(tableName string) error {
 	return cleanImpl(s, tableName)
 }
 
+//CleanOlderThan deletes tableName rows with column value older than age
+//if table sharding is enabled and column is the sharding timestamp, expired shards are dropped
+//and rows are deleted only from the shard which contains the boundary
+//tableName is the table name before adding table prefix and suffix
+func (s *Snowflake) CleanOlderThan(tableName, column string, age time.Duration) error {
+	_, tableHelper := s.getAdapters()
+	tableName = tableHelper.TableName(tableName)
+	before := timestamp.Now().UTC().Add(-age)
+	if s.sharder == nil {
+		return s.deleteOlderThan(tableName, column, before)
+	}
+
+	tableNames, err := s.snowflakeAdapter.GetTableNamesByPrefix(tableName + "_")
+	if err != nil {
+		return err
+	}
+
+	var dropped bool
+	for _, shardName := range tableNames {
+		if !s.sharder.IsShardOf(tableName, shardName) {
+			continue
+		}
+
+		start, end, _ := s.sharder.ShardPeriod(shardName)
+		if column == timestamp.Key {
+			if !start.Before(before) {
+				//all rows are newer
+				continue
+			}
+			if !end.After(before) {
+				if err := s.dropExpiredShard(shardName); err != nil {
+					return err
+				}
+				dropped = true
+				continue
+			}
+		}
+
+		if err := s.deleteOlderThan(shardName, column, before); err != nil {
+			return err
+		}
+	}
+
+	if dropped && s.shardingUnionView {
+		s.unionViewsMutex.Lock()
+		defer s.unionViewsMutex.Unlock()
+		if err := s.replaceUnionView(tableName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//deleteOlderThan deletes tableName rows with column value less than before and reports deleted rows
+func (s *Snowflake) deleteOlderThan(tableName, column string, before time.Time) error {
+	rowsDeleted, err := s.snowflakeAdapter.DeleteOlderThan(tableName, column, before)
+	if err != nil {
+		return err
+	}
+
+	metrics.RetentionDeletedRows(s.Type(), s.ID(), tableName, rowsDeleted)
+	logging.Infof("[%s] %d rows older than %s have been deleted from %s table", s.ID(), rowsDeleted, timestamp.ToISOFormat(before), tableName)
+	return nil
+}
+
+//dropExpiredShard drops the table shard which rows are older than the retention age
+func (s *Snowflake) dropExpiredShard(shardName string) error {
+	if err := s.snowflakeAdapter.DropTable(&adapters.Table{Name: shardName}); err != nil {
+		return fmt.Errorf("Error dropping expired table shard %s: %v", shardName, err)
+	}
+
+	_, tableHelper := s.getAdapters()
+	tableHelper.ClearCachedTable(shardName)
+
+	s.unionViewsMutex.Lock()
+	for name := range s.unionViewShards {
+		//table name in the DWH might be in another case
+		if strings.EqualFold(name, shardName) {
+			delete(s.unionViewShards, name)
+		}
+	}
+	s.unionViewsMutex.Unlock()
+
+	logging.Infof("[%s] expired table shard %s has been dropped", s.ID(), shardName)
+	return nil
+}
+
 //Update updates record in Snowflake
 func (s *Snowflake) Update(object map[string]interface{}) error {
+	return s.update(object, nil)
+}
+
+//Patch updates only changedFields (JSON paths) columns of the record with the object unique ID
+//new columns are added to the table before updating. All columns are updated if changed columns aren't found
+func (s *Snowflake) Patch(object map[string]interface{}, changedFields []string) error {
+	return s.update(object, changedFields)
+}
+
+//update processes object and updates the record with the object unique ID
+//updates all columns if changedFields is nil, otherwise only changed ones
+func (s *Snowflake) update(object map[string]interface{}, changedFields []string) error {
+	logContext := logging.NewContext(s.ID(), s.uniqueIDField.Extract(object))
 	_, tableHelper := s.getAdapters()
 	envelops, err := s.processor.ProcessEvent(object)
 	if err != nil {
@@ -267,24 +1301,164 @@ func (s *Snowflake) Update(object map[string]interface{}) error {
 	for _, envelop := range envelops {
 		batchHeader := envelop.Header
 		processedObject := envelop.Event
-		table := tableHelper.MapTableSchema(batchHeader)
+		if changedFields != nil {
+			if patchHeader, patchObject := patchColumns(batchHeader, processedObject, changedFields); patchHeader != nil {
+				batchHeader, processedObject = patchHeader, patchObject
+			} else {
+				//e.g. fields are renamed with mappings
+				logContext.Debugf("changed fields %v aren't found in the processed object. All columns will be updated", changedFields)
+			}
+		}
+		//shard is chosen by the whole object timestamp
+		table := tableHelper.MapObjectTableSchema(batchHeader, envelop.Event)
 
 		dbSchema, err := tableHelper.EnsureTableWithCaching(s.ID(), table)
 		if err != nil {
 			return err
 		}
+		s.ensureUnionView(dbSchema)
 
 		start := timestamp.Now()
 		if err = s.snowflakeAdapter.Update(dbSchema, processedObject, s.uniqueIDField.GetFlatFieldName(), s.uniqueIDField.Extract(object)); err != nil {
 			return err
 		}
 
-		logging.Debugf("[%s] Updated 1 row in [%.2f] seconds", s.ID(), timestamp.Now().Sub(start).Seconds())
+		logContext.Debugf("Updated 1 row (%d columns) in [%.2f] seconds", len(processedObject), timestamp.Now().Sub(start).Seconds())
+	}
+
+	return nil
+}
+
+//updateGroup is a set of processed objects which are updated in the same table
+type updateGroup struct {
+	//table is a union of all objects columns
+	table       *adapters.Table
+	objects     []map[string]interface{}
+	whereValues []interface{}
+}
+
+//UpdateBatch updates records in Snowflake with one MERGE statement per update_batch_size objects of the same table and columns
+//updates all columns if changedFields is nil, otherwise only changed ones
+func (s *Snowflake) UpdateBatch(objects []map[string]interface{}, changedFields []string) error {
+	_, tableHelper := s.getAdapters()
+	groups := map[string]*updateGroup{}
+	for _, object := range objects {
+		envelops, err := s.processor.ProcessEvent(object)
+		if err != nil {
+			return err
+		}
+		for _, envelop := range envelops {
+			batchHeader := envelop.Header
+			processedObject := envelop.Event
+			if changedFields != nil {
+				if patchHeader, patchObject := patchColumns(batchHeader, processedObject, changedFields); patchHeader != nil {
+					batchHeader, processedObject = patchHeader, patchObject
+				}
+			}
+			//shard is chosen by the whole object timestamp
+			table := tableHelper.MapObjectTableSchema(batchHeader, envelop.Event)
+
+			group, ok := groups[table.Name]
+			if !ok {
+				group = &updateGroup{table: table}
+				groups[table.Name] = group
+			} else {
+				for name, column := range table.Columns {
+					if _, ok := group.table.Columns[name]; !ok {
+						group.table.Columns[name] = column
+					}
+				}
+			}
+			group.objects = append(group.objects, processedObject)
+			group.whereValues = append(group.whereValues, s.uniqueIDField.Extract(object))
+		}
+	}
+
+	tableNames := make([]string, 0, len(groups))
+	for tableName := range groups {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	for _, tableName := range tableNames {
+		group := groups[tableName]
+		//all required columns are added once per table
+		dbSchema, err := tableHelper.EnsureTableWithCaching(s.ID(), group.table)
+		if err != nil {
+			return err
+		}
+		s.ensureUnionView(dbSchema)
+
+		if err := s.updateGroup(dbSchema, group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//updateGroup splits group objects by columns set and updates them with chunks of update_batch_size records
+func (s *Snowflake) updateGroup(dbSchema *adapters.Table, group *updateGroup) error {
+	uniqueIDField := s.uniqueIDField.GetFlatFieldName()
+	for _, indexes := range updateChunks(group.objects, s.updateBatchSize) {
+		chunk := make([]map[string]interface{}, 0, len(indexes))
+		whereValues := make([]interface{}, 0, len(indexes))
+		for _, i := range indexes {
+			chunk = append(chunk, group.objects[i])
+			whereValues = append(whereValues, group.whereValues[i])
+		}
+
+		startTime := timestamp.Now()
+		var err error
+		if len(chunk) == 1 {
+			err = s.snowflakeAdapter.Update(dbSchema, chunk[0], uniqueIDField, whereValues[0])
+		} else {
+			err = s.snowflakeAdapter.UpdateBatch(dbSchema, chunk, uniqueIDField, whereValues)
+		}
+		if err != nil {
+			return err
+		}
+
+		metrics.UpdateBatchSize(s.Type(), s.ID(), len(chunk))
+		logging.Debugf("[%s] Updated %d rows in %s table in [%.2f] seconds", s.ID(), len(chunk), dbSchema.Name, timestamp.Now().Sub(startTime).Seconds())
 	}
 
 	return nil
 }
 
+//updateChunks groups objects indexes by columns set (in order of the first appearance) and splits every group
+//into chunks of batchSize indexes
+func updateChunks(objects []map[string]interface{}, batchSize int) [][]int {
+	var columnSets []string
+	objectsPerColumns := map[string][]int{}
+	for i, object := range objects {
+		columns := make([]string, 0, len(object))
+		for name := range object {
+			columns = append(columns, name)
+		}
+		sort.Strings(columns)
+		key := strings.Join(columns, ",")
+		if _, ok := objectsPerColumns[key]; !ok {
+			columnSets = append(columnSets, key)
+		}
+		objectsPerColumns[key] = append(objectsPerColumns[key], i)
+	}
+
+	var chunks [][]int
+	for _, key := range columnSets {
+		indexes := objectsPerColumns[key]
+		for start := 0; start < len(indexes); start += batchSize {
+			end := start + batchSize
+			if end > len(indexes) {
+				end = len(indexes)
+			}
+			chunks = append(chunks, indexes[start:end])
+		}
+	}
+
+	return chunks
+}
+
 //Type returns Snowflake type
 func (s *Snowflake) Type() string {
 	return SnowflakeType
@@ -292,19 +1466,37 @@ func (s *Snowflake) Type() string {
 
 //Close closes Snowflake adapter, stage adapter, fallback logger and streaming worker
 func (s *Snowflake) Close() (multiErr error) {
-	if err := s.snowflakeAdapter.Close(); err != nil {
+	//streaming worker is stopped first: in-flight inserts mustn't use the closed connection
+	if s.streamingWorker != nil {
+		if err := closeWithTimeout("streaming worker", s.closeTimeout, s.streamingWorker.Close); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", s.ID(), err))
+		}
+	}
+
+	//accumulated files must be discarded before closing the stage
+	if s.copyBatcher != nil {
+		s.copyBatcher.Close()
+	}
+
+	if s.retentionCleaner != nil {
+		s.retentionCleaner.Close()
+	}
+
+	if s.postLoadHook != nil {
+		s.postLoadHook.Close()
+	}
+
+	if err := closeWithTimeout("snowflake datasource", s.closeTimeout, s.snowflakeAdapter.Close); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing snowflake datasource: %v", s.ID(), err))
 	}
 
-	if s.stageAdapter != nil {
-		if err := s.stageAdapter.Close(); err != nil {
-			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing snowflake stage: %v", s.ID(), err))
-		}
+	if s.stageSweeper != nil {
+		s.stageSweeper.Close()
 	}
 
-	if s.streamingWorker != nil {
-		if err := s.streamingWorker.Close(); err != nil {
-			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", s.ID(), err))
+	if s.stageAdapter != nil {
+		if err := closeWithTimeout("snowflake stage", s.closeTimeout, s.stageAdapter.Close); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing snowflake stage: %v", s.ID(), err))
 		}
 	}
 
@@ -0,0 +1,120 @@
+package storages
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//s3Stage is a stage bucket with its configuration (which is used in COPY statements)
+type s3Stage struct {
+	adapter *adapters.S3
+	config  *adapters.S3Config
+}
+
+//multiStage is a Stage which uploads every file into one of several S3 buckets according to the stage selection
+//the bucket is chosen only by the file name, so DeleteObject and COPY always reference the bucket which has been used for the file
+//files in a stage folder (e.g. chunks of one table file) are chosen by the folder name, so they are loaded with one COPY
+type multiStage struct {
+	stages []*s3Stage
+	//regional is a stage index in the deployment region (region selection) or -1 (hash selection)
+	regional int
+}
+
+//newMultiStage validates all stage configs and returns multiStage with the s3 section stage as the first one
+func newMultiStage(destinationID string, primary *adapters.S3Config, additional []*adapters.S3Config, selection, deploymentRegion string) (*multiStage, error) {
+	ms := &multiStage{regional: -1}
+	configs := append([]*adapters.S3Config{primary}, additional...)
+	for i, s3Config := range configs {
+		if s3Config == nil {
+			ms.Close()
+			return nil, fmt.Errorf("Snowflake s3_stages[%d] is empty", i-1)
+		}
+		normalizeStageConfigs(s3Config, nil)
+		adapter, err := adapters.NewS3(s3Config)
+		if err != nil {
+			ms.Close()
+			if i == 0 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("Snowflake s3_stages[%d] is misconfigured: %v", i-1, err)
+		}
+		ms.stages = append(ms.stages, &s3Stage{adapter: adapter, config: s3Config})
+	}
+
+	if selection == adapters.StageSelectionRegion {
+		ms.regional = 0
+		for i, stage := range ms.stages {
+			if stage.config.Region == deploymentRegion {
+				ms.regional = i
+				break
+			}
+		}
+		if ms.stages[ms.regional].config.Region != deploymentRegion {
+			logging.Warnf("[%s] there is no stage bucket in %s deployment region: s3 section bucket %s will be used", destinationID, deploymentRegion, primary.Bucket)
+		}
+	}
+
+	return ms, nil
+}
+
+//stageFor returns the stage bucket of the file
+func (ms *multiStage) stageFor(fileName string) *s3Stage {
+	if ms.regional >= 0 {
+		return ms.stages[ms.regional]
+	}
+
+	key := fileName
+	if strings.Contains(fileName, "/") {
+		key = path.Dir(fileName)
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return ms.stages[h.Sum32()%uint32(len(ms.stages))]
+}
+
+//S3Config returns configuration of the file stage bucket
+func (ms *multiStage) S3Config(fileName string) *adapters.S3Config {
+	return ms.stageFor(fileName).config
+}
+
+//UploadBytes uploads the file into the file stage bucket
+func (ms *multiStage) UploadBytes(fileName string, fileBytes []byte) error {
+	return ms.stageFor(fileName).adapter.UploadBytes(fileName, fileBytes)
+}
+
+//DeleteObject deletes the file from the file stage bucket
+func (ms *multiStage) DeleteObject(key string) error {
+	return ms.stageFor(key).adapter.DeleteObject(key)
+}
+
+//ListObjects returns files under the prefix from all stage buckets
+func (ms *multiStage) ListObjects(prefix string) ([]*adapters.StageObject, error) {
+	var objects []*adapters.StageObject
+	for _, stage := range ms.stages {
+		bucketObjects, err := stage.adapter.ListObjects(prefix)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, bucketObjects...)
+	}
+
+	return objects, nil
+}
+
+//Close closes all stage adapters
+func (ms *multiStage) Close() (multiErr error) {
+	for _, stage := range ms.stages {
+		if err := stage.adapter.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("Error closing stage bucket %s: %v", stage.config.Bucket, err))
+		}
+	}
+
+	return multiErr
+}
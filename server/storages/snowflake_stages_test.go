@@ -0,0 +1,56 @@
+package storages
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/stretchr/testify/require"
+)
+
+func testS3StageConfig(bucket, region string) *adapters.S3Config {
+	return &adapters.S3Config{AccessKeyID: "key", SecretKey: "secret", Bucket: bucket, Region: region}
+}
+
+func TestMultiStageHashSelection(t *testing.T) {
+	ms, err := newMultiStage("test", testS3StageConfig("s3://primary/", "us-east-1"),
+		[]*adapters.S3Config{testS3StageConfig("eu", "eu-west-1")}, adapters.StageSelectionHash, "")
+	require.NoError(t, err)
+	defer ms.Close()
+
+	buckets := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		fileName := fmt.Sprintf("file-%d", i)
+		s3Config := ms.S3Config(fileName)
+		require.Equal(t, s3Config, ms.S3Config(fileName), "file must be copied and deleted from the upload bucket")
+		buckets[s3Config.Bucket] = true
+	}
+	require.Equal(t, map[string]bool{"primary": true, "eu": true}, buckets)
+
+	for i := 0; i < 10; i++ {
+		folder := fmt.Sprintf("jitsu_stage_chunks/events/%d", i)
+		s3Config := ms.S3Config(folder + "/file_0")
+		for j := 1; j < 10; j++ {
+			require.Equal(t, s3Config, ms.S3Config(fmt.Sprintf("%s/file_%d", folder, j)), "all folder files must be uploaded into one bucket")
+		}
+	}
+}
+
+func TestMultiStageRegionSelection(t *testing.T) {
+	ms, err := newMultiStage("test", testS3StageConfig("primary", "us-east-1"),
+		[]*adapters.S3Config{testS3StageConfig("eu", "eu-west-1")}, adapters.StageSelectionRegion, "eu-west-1")
+	require.NoError(t, err)
+	defer ms.Close()
+	require.Equal(t, "eu", ms.S3Config("file1").Bucket)
+	require.Equal(t, "eu", ms.S3Config("file2").Bucket)
+
+	ms, err = newMultiStage("test", testS3StageConfig("primary", "us-east-1"),
+		[]*adapters.S3Config{testS3StageConfig("eu", "eu-west-1")}, adapters.StageSelectionRegion, "ap-south-1")
+	require.NoError(t, err)
+	defer ms.Close()
+	require.Equal(t, "primary", ms.S3Config("file1").Bucket)
+
+	_, err = newMultiStage("test", testS3StageConfig("primary", "us-east-1"),
+		[]*adapters.S3Config{{Bucket: "eu"}}, adapters.StageSelectionHash, "")
+	require.Error(t, err)
+}
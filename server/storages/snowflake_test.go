@@ -0,0 +1,468 @@
+package storages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSnowflakeStage(t *testing.T) {
+	fullS3 := &adapters.S3Config{AccessKeyID: "key", SecretKey: "secret", Bucket: "bucket", Region: "us-east-1"}
+	fullGoogle := &adapters.GoogleConfig{Bucket: "bucket", KeyFile: map[string]interface{}{"type": "service_account"}}
+	tests := []struct {
+		name          string
+		s3Config      *adapters.S3Config
+		googleConfig  *adapters.GoogleConfig
+		stage         string
+		expectedS3    bool
+		expectedError string
+	}{
+		{"s3", fullS3, nil, "", true, ""},
+		{"empty s3 with google", &adapters.S3Config{}, fullGoogle, "stage", false, ""},
+		{"google", nil, fullGoogle, "stage", false, ""},
+		{"nothing", nil, nil, "stage", false, "Snowflake stage is required in batch mode: configure either s3 section (access_key_id, secret_access_key, bucket, region) or google section (gcs_bucket, key_file) with snowflake.stage"},
+		{"both", fullS3, &adapters.GoogleConfig{Bucket: "bucket"}, "stage", false, "Snowflake stage is ambiguous: both s3 and google sections are configured. Please leave only one of them"},
+		{"partial s3", &adapters.S3Config{Bucket: "bucket", Folder: "folder"}, nil, "", false, "Snowflake S3 stage is misconfigured: required parameters are missing: [s3.access_key_id, s3.secret_access_key, s3.region]"},
+		{"google without stage", nil, &adapters.GoogleConfig{Bucket: "bucket", KeyFile: ""}, "", false, "Snowflake GCS stage is misconfigured: required parameters are missing: [google.key_file, snowflake.stage]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualS3, err := validateSnowflakeStage(tt.s3Config, tt.googleConfig, tt.stage)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedS3, actualS3)
+		})
+	}
+}
+
+func TestNormalizeStageConfigs(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          adapters.S3Config
+		expectedBucket string
+		expectedFolder string
+	}{
+		{"as is", adapters.S3Config{Bucket: "bucket", Folder: "folder"}, "bucket", "folder"},
+		{"s3 prefix and slashes", adapters.S3Config{Bucket: " s3://bucket/ ", Folder: "/folder/"}, "bucket", "folder"},
+		{"bucket with path", adapters.S3Config{Bucket: "s3://bucket/path/to/"}, "bucket", "path/to"},
+		{"bucket with path and folder", adapters.S3Config{Bucket: "bucket/path", Folder: "folder"}, "bucket", "path/folder"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			googleConfig := &adapters.GoogleConfig{Bucket: "gs://" + tt.expectedBucket + "/"}
+			normalizeStageConfigs(&tt.input, googleConfig)
+			require.Equal(t, tt.expectedBucket, tt.input.Bucket)
+			require.Equal(t, tt.expectedFolder, tt.input.Folder)
+			require.Equal(t, tt.expectedBucket, googleConfig.Bucket)
+		})
+	}
+}
+
+func TestWithoutLoadMetadataColumns(t *testing.T) {
+	pkFields := map[string]bool{"id": true, loadedAtColumn: true, sourceFileColumn: true}
+	require.Equal(t, map[string]bool{"id": true}, withoutLoadMetadataColumns("test", pkFields))
+	require.Equal(t, 3, len(pkFields), "original pk fields mustn't be changed")
+	require.Equal(t, map[string]bool{}, withoutLoadMetadataColumns("test", nil))
+}
+
+//memoryStage is an in-memory adapters.Stage
+type memoryStage struct {
+	files map[string][]byte
+}
+
+func (ms *memoryStage) UploadBytes(fileName string, fileBytes []byte) error {
+	ms.files[fileName] = fileBytes
+	return nil
+}
+
+func (ms *memoryStage) DeleteObject(key string) error {
+	delete(ms.files, key)
+	return nil
+}
+
+func (ms *memoryStage) Close() error {
+	return nil
+}
+
+func TestStageFileEventsOrder(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	destination := &config.DestinationConfig{Type: "snowflake", DataLayout: &config.DataLayout{}}
+	p, err := schema.NewProcessor("test", destination, true, `events`, &schema.DummyMapper{}, []enrichment.Rule{}, schema.NewFlattener(), schema.NewTypeResolver(), identifiers.NewUniqueID("/eventn_ctx/event_id"), 0)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+	defer p.CloseJavaScriptTemplates()
+
+	var objects []map[string]interface{}
+	var expected []string
+	for i := 50; i > 0; i-- {
+		eventID := strconv.Itoa(i)
+		object := map[string]interface{}{"eventn_ctx": map[string]interface{}{"event_id": eventID}, "seq": i}
+		//different fields in different events
+		if i%3 == 0 {
+			object["field_"+eventID] = "value"
+		}
+		objects = append(objects, object)
+		expected = append(expected, eventID)
+	}
+
+	processedFiles, _, _, err := p.ProcessEvents("testfile", objects, map[string]bool{})
+	require.NoError(t, err)
+	require.Len(t, processedFiles, 1)
+	fdata := processedFiles["events"]
+
+	for _, stageFormat := range []string{adapters.StageFormatCSV, adapters.StageFormatJSON} {
+		t.Run(stageFormat, func(t *testing.T) {
+			stage := &memoryStage{files: map[string][]byte{}}
+			s := &Snowflake{stageAdapter: stage, stageFormat: stageFormat}
+
+			b, header, err := s.marshall(fdata)
+			require.NoError(t, err)
+			require.NoError(t, s.uploadToStage(context.Background(), fdata.FileName, b))
+
+			lines := strings.Split(string(stage.files[fdata.FileName]), "\n")
+			var actual []string
+			if stageFormat == adapters.StageFormatCSV {
+				eventIDIndex := -1
+				for i, field := range header {
+					if field == "eventn_ctx_event_id" {
+						eventIDIndex = i
+					}
+				}
+				require.NotEqual(t, -1, eventIDIndex)
+				//skip header
+				for _, line := range lines[1:] {
+					actual = append(actual, strings.Split(line, "||")[eventIDIndex])
+				}
+			} else {
+				for _, line := range lines {
+					row := map[string]interface{}{}
+					require.NoError(t, json.Unmarshal([]byte(line), &row))
+					actual = append(actual, row["eventn_ctx_event_id"].(string))
+				}
+			}
+
+			require.Equal(t, expected, actual, "stage file rows order isn't equal to the events order")
+		})
+	}
+}
+
+func TestStageFileJSONFieldsOrder(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	destination := &config.DestinationConfig{Type: "snowflake", DataLayout: &config.DataLayout{}}
+	p, err := schema.NewProcessor("test", destination, true, `events`, &schema.DummyMapper{}, []enrichment.Rule{}, schema.NewFlattener(), schema.NewTypeResolver(), identifiers.NewUniqueID("/eventn_ctx/event_id"), 0)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+	defer p.CloseJavaScriptTemplates()
+
+	//the same fields in different orders and partially present fields
+	inputs := []string{
+		`{"eventn_ctx": {"event_id": "1"}, "a": "a1", "b": 1, "c": true}`,
+		`{"c": false, "b": 2, "eventn_ctx": {"event_id": "2"}, "a": "a2"}`,
+		`{"b": 3, "eventn_ctx": {"event_id": "3"}}`,
+		`{"eventn_ctx": {"event_id": "4"}, "c": true, "d": "new"}`,
+	}
+	var objects []map[string]interface{}
+	for _, input := range inputs {
+		object := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(input), &object))
+		objects = append(objects, object)
+	}
+
+	processedFiles, _, _, err := p.ProcessEvents("testfile", objects, map[string]bool{})
+	require.NoError(t, err)
+	fdata := processedFiles["events"]
+
+	s := &Snowflake{stageFormat: adapters.StageFormatJSON}
+	b, header, err := s.marshall(fdata)
+	require.NoError(t, err)
+	require.Nil(t, header, "json stage files are mapped by column names")
+
+	expected := []map[string]interface{}{
+		{"eventn_ctx_event_id": "1", "a": "a1", "b": float64(1), "c": true},
+		{"eventn_ctx_event_id": "2", "a": "a2", "b": float64(2), "c": false},
+		{"eventn_ctx_event_id": "3", "b": float64(3)},
+		{"eventn_ctx_event_id": "4", "c": true, "d": "new"},
+	}
+	lines := strings.Split(string(b), "\n")
+	require.Len(t, lines, len(expected))
+	for i, line := range lines {
+		row := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &row))
+		for field, value := range expected[i] {
+			require.Equal(t, value, row[field], "row %d field %s", i, field)
+		}
+		for _, absent := range []string{"a", "b", "c", "d"} {
+			if _, ok := expected[i][absent]; !ok {
+				require.NotContains(t, row, absent, "absent fields must be loaded as NULL")
+			}
+		}
+	}
+}
+
+//concurrentStage is a thread-safe in-memory adapters.Stage which fails uploads of failedKey
+type concurrentStage struct {
+	mutex     sync.Mutex
+	files     map[string][]byte
+	failedKey string
+}
+
+func (cs *concurrentStage) UploadBytes(fileName string, fileBytes []byte) error {
+	if fileName == cs.failedKey {
+		return errors.New("upload error")
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.files[fileName] = fileBytes
+	return nil
+}
+
+func (cs *concurrentStage) DeleteObject(key string) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	delete(cs.files, key)
+	return nil
+}
+
+func (cs *concurrentStage) Close() error {
+	return nil
+}
+
+func TestUploadChunks(t *testing.T) {
+	var keys []string
+	var payloads [][]byte
+	for i := 0; i < 20; i++ {
+		keys = append(keys, fmt.Sprintf("prefix/file_%d", i))
+		payloads = append(payloads, []byte(strconv.Itoa(i)))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		stage := &concurrentStage{files: map[string][]byte{}}
+		s := &Snowflake{stageAdapter: stage, stageUploadConcurrency: 3}
+
+		require.NoError(t, s.uploadChunks(context.Background(), keys, payloads))
+		require.Len(t, stage.files, len(keys))
+		for i, key := range keys {
+			require.Equal(t, payloads[i], stage.files[key])
+		}
+	})
+
+	t.Run("failed upload", func(t *testing.T) {
+		stage := &concurrentStage{files: map[string][]byte{}, failedKey: keys[5]}
+		s := &Snowflake{stageAdapter: stage, stageUploadConcurrency: 3}
+
+		err := s.uploadChunks(context.Background(), keys, payloads)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), keys[5])
+		//canceled in-flight uploads are released after they are finished
+		require.Eventually(t, func() bool {
+			stage.mutex.Lock()
+			defer stage.mutex.Unlock()
+			return len(stage.files) == 0
+		}, time.Second, 10*time.Millisecond, "uploaded chunks must be deleted")
+	})
+}
+
+func TestStageFileTimestampType(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	destination := &config.DestinationConfig{Type: "snowflake", DataLayout: &config.DataLayout{}}
+	p, err := schema.NewProcessor("test", destination, true, `events`, &schema.DummyMapper{}, []enrichment.Rule{}, schema.NewFlattener(), schema.NewTypeResolver(), identifiers.NewUniqueID("/eventn_ctx/event_id"), 0)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+	defer p.CloseJavaScriptTemplates()
+
+	eventTime := time.Date(2022, 6, 1, 10, 30, 0, 123456000, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		timestampType string
+		expected      string
+	}{
+		{adapters.TimestampTZ, "2022-06-01T10:30:00.123456+02:00"},
+		{adapters.TimestampLTZ, "2022-06-01T10:30:00.123456+02:00"},
+		{adapters.TimestampNTZ, "2022-06-01T08:30:00.123456Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timestampType, func(t *testing.T) {
+			object := map[string]interface{}{"eventn_ctx": map[string]interface{}{"event_id": "1"}, "event_time": eventTime.Format(time.RFC3339Nano)}
+			processedFiles, _, _, err := p.ProcessEvents("testfile", []map[string]interface{}{object}, map[string]bool{})
+			require.NoError(t, err)
+
+			s := &Snowflake{stageFormat: adapters.StageFormatJSON, timestampType: tt.timestampType}
+			b, _, err := s.marshall(processedFiles["events"])
+			require.NoError(t, err)
+
+			row := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(b, &row))
+			require.Equal(t, tt.expected, row["event_time"])
+
+			//the loaded value is the same point in time
+			loaded, err := time.Parse(time.RFC3339Nano, row["event_time"].(string))
+			require.NoError(t, err)
+			require.True(t, eventTime.Equal(loaded))
+		})
+	}
+}
+
+func TestUpdateChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		objects   []map[string]interface{}
+		batchSize int
+		expected  [][]int
+	}{
+		{
+			"Empty",
+			nil,
+			100,
+			nil,
+		},
+		{
+			"One chunk",
+			[]map[string]interface{}{{"id": 1, "name": "a"}, {"name": "b", "id": 2}},
+			100,
+			[][]int{{0, 1}},
+		},
+		{
+			"Chunks of batch size",
+			[]map[string]interface{}{{"id": 1}, {"id": 2}, {"id": 3}, {"id": 4}, {"id": 5}},
+			2,
+			[][]int{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			"One record per chunk",
+			[]map[string]interface{}{{"id": 1}, {"id": 2}},
+			1,
+			[][]int{{0}, {1}},
+		},
+		{
+			"Different columns sets",
+			[]map[string]interface{}{{"id": 1, "name": "a"}, {"id": 2}, {"id": 3, "name": "c"}, {"id": 4}, {"id": 5, "name": "e"}},
+			2,
+			[][]int{{0, 2}, {4}, {1, 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, updateChunks(tt.objects, tt.batchSize))
+		})
+	}
+}
+
+//preflightStage is a stage with configurable upload and delete errors
+type preflightStage struct {
+	uploadErr error
+	deleteErr error
+	files     map[string][]byte
+}
+
+func (ps *preflightStage) UploadBytes(fileName string, fileBytes []byte) error {
+	if ps.uploadErr != nil {
+		return ps.uploadErr
+	}
+
+	ps.files[fileName] = fileBytes
+	return nil
+}
+
+func (ps *preflightStage) DeleteObject(key string) error {
+	if ps.deleteErr != nil {
+		return ps.deleteErr
+	}
+
+	delete(ps.files, key)
+	return nil
+}
+
+func (ps *preflightStage) Close() error {
+	return nil
+}
+
+func TestSnowflakePreflight(t *testing.T) {
+	tests := []struct {
+		name          string
+		withStage     bool
+		uploadErr     error
+		deleteErr     error
+		testQueryErr  error
+		expectedErr   string
+		expectedQuery bool
+	}{
+		{
+			name:          "Passed",
+			withStage:     true,
+			expectedQuery: true,
+		},
+		{
+			name:          "Passed without stage (stream mode)",
+			expectedQuery: true,
+		},
+		{
+			name:        "Stage put permission",
+			withStage:   true,
+			uploadErr:   errors.New("AccessDenied"),
+			expectedErr: "Snowflake preflight failed: error writing probe object jitsu_preflight_",
+		},
+		{
+			name:        "Stage delete permission",
+			withStage:   true,
+			deleteErr:   errors.New("AccessDenied"),
+			expectedErr: "Snowflake preflight failed: error deleting probe object jitsu_preflight_",
+		},
+		{
+			name:          "Test query",
+			withStage:     true,
+			testQueryErr:  errors.New("Object does not exist, or operation cannot be performed"),
+			expectedErr:   "Snowflake preflight failed: error running test query (check user, role and warehouse permissions): Object does not exist, or operation cannot be performed",
+			expectedQuery: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stageAdapter adapters.Stage
+			stage := &preflightStage{uploadErr: tt.uploadErr, deleteErr: tt.deleteErr, files: map[string][]byte{}}
+			if tt.withStage {
+				stageAdapter = stage
+			}
+			queried := false
+			testQuery := func() error {
+				queried = true
+				return tt.testQueryErr
+			}
+
+			err := snowflakePreflight("dest1", stageAdapter, testQuery)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+			}
+			require.Equal(t, tt.expectedQuery, queried)
+			if tt.deleteErr == nil {
+				require.Empty(t, stage.files, "probe object must be deleted")
+			}
+		})
+	}
+}
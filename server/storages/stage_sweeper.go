@@ -0,0 +1,98 @@
+package storages
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	stageSweeperInterval = 10 * time.Minute
+
+	//keptStageFilesFolder is a stage folder with markers of kept files: marker key is the folder + kept file key
+	keptStageFilesFolder = "jitsu_kept_stage_files"
+)
+
+//stageSweeper keeps track of stage files which were intentionally preserved (for debugging)
+//and deletes them from the stage when they become older than ttl
+//the registry is persisted in the stage as marker objects so files which were kept before a restart are swept as well
+type stageSweeper struct {
+	destinationID string
+	stage         adapters.ListableStage
+	ttl           time.Duration
+
+	closed chan struct{}
+}
+
+//newStageSweeper returns configured stageSweeper and starts background goroutine
+func newStageSweeper(destinationID string, stage adapters.ListableStage, ttl time.Duration) *stageSweeper {
+	ss := &stageSweeper{
+		destinationID: destinationID,
+		stage:         stage,
+		ttl:           ttl,
+		closed:        make(chan struct{}),
+	}
+	ss.start()
+	return ss
+}
+
+//start sweeps files right away (e.g. kept before the restart) and then every stageSweeperInterval
+func (ss *stageSweeper) start() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(stageSweeperInterval)
+		defer ticker.Stop()
+		for {
+			ss.sweep(timestamp.Now())
+
+			select {
+			case <-ss.closed:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+//keep registers stage file for further deletion: writes the marker object
+func (ss *stageSweeper) keep(fileName string) {
+	if err := ss.stage.UploadBytes(keptStageFilesFolder+"/"+fileName, []byte(timestamp.NowUTC())); err != nil {
+		logging.Errorf("[%s] Error registering kept stage file %s: it won't be deleted after ttl: %v", ss.destinationID, fileName, err)
+	}
+}
+
+//sweep deletes all registered files (and their markers) which are older than ttl
+func (ss *stageSweeper) sweep(now time.Time) {
+	markers, err := ss.stage.ListObjects(keptStageFilesFolder + "/")
+	if err != nil {
+		logging.Errorf("[%s] Error listing kept stage files: %v", ss.destinationID, err)
+		return
+	}
+
+	for _, marker := range markers {
+		if now.Sub(marker.LastModified) < ss.ttl {
+			continue
+		}
+
+		fileName := strings.TrimPrefix(marker.Key, keptStageFilesFolder+"/")
+		if err := ss.stage.DeleteObject(fileName); err != nil {
+			logging.Errorf("[%s] Error deleting expired stage file %s: %v", ss.destinationID, fileName, err)
+			continue
+		}
+		if err := ss.stage.DeleteObject(marker.Key); err != nil {
+			logging.Errorf("[%s] Error deleting expired stage file %s marker: %v", ss.destinationID, fileName, err)
+			continue
+		}
+
+		logging.Debugf("[%s] expired stage file %s has been deleted", ss.destinationID, fileName)
+	}
+}
+
+//Close stops background goroutine
+func (ss *stageSweeper) Close() error {
+	close(ss.closed)
+	return nil
+}
@@ -0,0 +1,74 @@
+package storages
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+//listableMemoryStage is an in-memory adapters.ListableStage
+type listableMemoryStage struct {
+	mutex sync.Mutex
+	files map[string]time.Time
+}
+
+func (lms *listableMemoryStage) UploadBytes(fileName string, fileBytes []byte) error {
+	lms.mutex.Lock()
+	defer lms.mutex.Unlock()
+	lms.files[fileName] = timestamp.Now()
+	return nil
+}
+
+func (lms *listableMemoryStage) DeleteObject(key string) error {
+	lms.mutex.Lock()
+	defer lms.mutex.Unlock()
+	delete(lms.files, key)
+	return nil
+}
+
+func (lms *listableMemoryStage) ListObjects(prefix string) ([]*adapters.StageObject, error) {
+	lms.mutex.Lock()
+	defer lms.mutex.Unlock()
+	var objects []*adapters.StageObject
+	for key, lastModified := range lms.files {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, &adapters.StageObject{Key: key, LastModified: lastModified})
+		}
+	}
+	return objects, nil
+}
+
+func (lms *listableMemoryStage) Close() error {
+	return nil
+}
+
+func TestStageSweeper(t *testing.T) {
+	stage := &listableMemoryStage{files: map[string]time.Time{}}
+	require.NoError(t, stage.UploadBytes("kept_file", []byte{}))
+	require.NoError(t, stage.UploadBytes("other_file", []byte{}))
+
+	//sweeper of the previous run
+	previous := &stageSweeper{destinationID: "test", stage: stage, ttl: time.Hour}
+	previous.keep("kept_file")
+
+	//sweeper after the restart
+	ss := &stageSweeper{destinationID: "test", stage: stage, ttl: time.Hour}
+	ss.sweep(timestamp.Now())
+	require.Len(t, stage.files, 3, "files mustn't be deleted before ttl")
+
+	ss.sweep(timestamp.Now().Add(time.Hour))
+	require.Equal(t, []string{"other_file"}, stageFileKeys(stage.files), "kept file and its marker must be deleted after ttl")
+}
+
+func stageFileKeys(m map[string]time.Time) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
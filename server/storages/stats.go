@@ -0,0 +1,69 @@
+package storages
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"go.uber.org/atomic"
+)
+
+//StorageStats is a snapshot of destination lifetime counters (since the storage creation)
+type StorageStats struct {
+	//RowsStored is a number of rows which have been stored successfully
+	RowsStored int64 `json:"rows_stored"`
+	//BytesStaged is a number of bytes which have been uploaded into the stage (e.g. Snowflake stage)
+	BytesStaged int64 `json:"bytes_staged"`
+	//Failures is a number of failed stores (table batches or streaming events)
+	Failures int64 `json:"failures"`
+	//LastSuccess is nil if there haven't been any successful stores yet
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	//LastFailure is nil if there haven't been any failed stores yet
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+}
+
+//storageStats keeps lifetime counters with atomic values so they can be updated from concurrent stores without locks
+type storageStats struct {
+	rowsStored  atomic.Int64
+	bytesStaged atomic.Int64
+	failures    atomic.Int64
+	//unix nanoseconds, 0 - never
+	lastSuccess atomic.Int64
+	lastFailure atomic.Int64
+}
+
+//stored increments rows counter and updates the last success time
+func (ss *storageStats) stored(rows int) {
+	ss.rowsStored.Add(int64(rows))
+	ss.lastSuccess.Store(timestamp.Now().UnixNano())
+}
+
+//staged increments staged bytes counter
+func (ss *storageStats) staged(bytes int) {
+	ss.bytesStaged.Add(int64(bytes))
+}
+
+//failed increments failures counter and updates the last failure time
+func (ss *storageStats) failed() {
+	ss.failures.Inc()
+	ss.lastFailure.Store(timestamp.Now().UnixNano())
+}
+
+//snapshot returns StorageStats with the current counters values
+func (ss *storageStats) snapshot() StorageStats {
+	return StorageStats{
+		RowsStored:  ss.rowsStored.Load(),
+		BytesStaged: ss.bytesStaged.Load(),
+		Failures:    ss.failures.Load(),
+		LastSuccess: unixNanoTime(ss.lastSuccess.Load()),
+		LastFailure: unixNanoTime(ss.lastFailure.Load()),
+	}
+}
+
+func unixNanoTime(nanos int64) *time.Time {
+	if nanos == 0 {
+		return nil
+	}
+
+	t := time.Unix(0, nanos).UTC()
+	return &t
+}
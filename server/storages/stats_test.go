@@ -0,0 +1,29 @@
+package storages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageStats(t *testing.T) {
+	ss := &storageStats{}
+	empty := ss.snapshot()
+	require.Equal(t, StorageStats{}, empty)
+
+	ss.stored(10)
+	ss.stored(5)
+	ss.staged(1024)
+	actual := ss.snapshot()
+	require.Equal(t, int64(15), actual.RowsStored)
+	require.Equal(t, int64(1024), actual.BytesStaged)
+	require.Equal(t, int64(0), actual.Failures)
+	require.NotNil(t, actual.LastSuccess)
+	require.Nil(t, actual.LastFailure)
+
+	ss.failed()
+	actual = ss.snapshot()
+	require.Equal(t, int64(15), actual.RowsStored)
+	require.Equal(t, int64(1), actual.Failures)
+	require.NotNil(t, actual.LastFailure)
+}
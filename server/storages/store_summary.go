@@ -0,0 +1,79 @@
+package storages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//StoreSummary is a rolled-up summary of Store results of all tables
+type StoreSummary struct {
+	Tables       int
+	FailedTables int
+	RowsCount    int
+	FailedRows   int
+	//EventsSrc is a number of stored events per source (merged across succeeded tables)
+	EventsSrc map[string]int
+	//FailedEventsSrc is a number of not stored events per source (merged across failed tables)
+	FailedEventsSrc map[string]int
+	//MaxLatency is a max table storing latency and MaxLatencyTable is the table name
+	MaxLatency      time.Duration
+	MaxLatencyTable string
+}
+
+//AggregateStoreResults returns StoreSummary of all table results
+func AggregateStoreResults(resultPerTable map[string]*StoreResult) *StoreSummary {
+	summary := &StoreSummary{EventsSrc: map[string]int{}, FailedEventsSrc: map[string]int{}}
+	for tableName, result := range resultPerTable {
+		if result == nil {
+			continue
+		}
+
+		summary.Tables++
+		summary.RowsCount += result.RowsCount
+		eventsSrc := summary.EventsSrc
+		if result.Err != nil {
+			summary.FailedTables++
+			summary.FailedRows += result.RowsCount
+			eventsSrc = summary.FailedEventsSrc
+		}
+		for src, count := range result.EventsSrc {
+			eventsSrc[src] += count
+		}
+
+		if result.Latency > summary.MaxLatency || (result.Latency == summary.MaxLatency && tableName < summary.MaxLatencyTable) {
+			summary.MaxLatency = result.Latency
+			summary.MaxLatencyTable = tableName
+		}
+	}
+
+	return summary
+}
+
+//SucceededTables returns number of successfully stored tables
+func (ss *StoreSummary) SucceededTables() int {
+	return ss.Tables - ss.FailedTables
+}
+
+//SucceededRows returns number of successfully stored rows
+func (ss *StoreSummary) SucceededRows() int {
+	return ss.RowsCount - ss.FailedRows
+}
+
+//String returns summary in log format: tables, rows, per source breakdown and the slowest table
+func (ss *StoreSummary) String() string {
+	return fmt.Sprintf("tables: %d (failed: %d) rows: %d (failed: %d) sources: [%s] failed sources: [%s] max table latency: %s (%s)",
+		ss.Tables, ss.FailedTables, ss.RowsCount, ss.FailedRows, formatEventsSrc(ss.EventsSrc), formatEventsSrc(ss.FailedEventsSrc),
+		ss.MaxLatency.Round(time.Millisecond), ss.MaxLatencyTable)
+}
+
+//formatEventsSrc returns sorted src=count pairs
+func formatEventsSrc(eventsSrc map[string]int) string {
+	var pairs []string
+	for src, count := range eventsSrc {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", src, count))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
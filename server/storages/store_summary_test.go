@@ -0,0 +1,45 @@
+package storages
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateStoreResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    map[string]*StoreResult
+		expected *StoreSummary
+	}{
+		{
+			"empty results",
+			map[string]*StoreResult{},
+			&StoreSummary{EventsSrc: map[string]int{}, FailedEventsSrc: map[string]int{}},
+		},
+		{
+			"succeeded and failed tables",
+			map[string]*StoreResult{
+				"events":    {RowsCount: 10, EventsSrc: map[string]int{"jitsu": 7, "api": 3}, Latency: 2 * time.Second},
+				"pageviews": {RowsCount: 5, EventsSrc: map[string]int{"jitsu": 5}, Latency: 3 * time.Second},
+				"orders":    {Err: errors.New("error"), RowsCount: 4, EventsSrc: map[string]int{"api": 4}, Latency: time.Second},
+			},
+			&StoreSummary{Tables: 3, FailedTables: 1, RowsCount: 19, FailedRows: 4,
+				EventsSrc: map[string]int{"jitsu": 12, "api": 3}, FailedEventsSrc: map[string]int{"api": 4},
+				MaxLatency: 3 * time.Second, MaxLatencyTable: "pageviews"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := AggregateStoreResults(tt.input)
+			require.Equal(t, tt.expected, actual)
+			require.Equal(t, tt.expected.RowsCount-tt.expected.FailedRows, actual.SucceededRows())
+			require.Equal(t, tt.expected.Tables-tt.expected.FailedTables, actual.SucceededTables())
+		})
+	}
+
+	summary := AggregateStoreResults(tests[1].input)
+	require.Equal(t, "tables: 3 (failed: 1) rows: 19 (failed: 4) sources: [api=3, jitsu=12] failed sources: [api=4] max table latency: 3s (pageviews)", summary.String())
+}
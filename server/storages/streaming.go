@@ -1,16 +1,21 @@
 package storages
 
 import (
+	"errors"
+	"fmt"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/dlq"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/utils"
 	"go.uber.org/atomic"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -27,19 +32,41 @@ type StreamingStorage interface {
 	SkipEvent(eventCtx *adapters.EventContext, err error)
 }
 
+//ErrDuplicateEvent is used for skipping events which have been already stored within the deduplication window
+var ErrDuplicateEvent = errors.New("duplicate event: event with the same unique ID has been already stored")
+
 //StreamingWorker reads events from queue and using events.StreamingStorage writes them
 type StreamingWorker struct {
 	eventQueue       events.Queue
 	processor        *schema.Processor
 	streamingStorage StreamingStorage
+	dedupCache       *dedupCache
+	partitioner      *eventPartitioner
+	sampler          *schemaSampler
 	tableHelper      []*TableHelper
+	retries          *retryCounter
+
+	//partitions are event channels of partitions goroutines (if partitioner is configured)
+	partitions []chan *queuedEvent
 
 	closed *atomic.Bool
+	done   chan struct{}
+	//closeMutex guards inFlight accounting: Close waits for events which are being processed
+	closeMutex sync.RWMutex
+	inFlight   sync.WaitGroup
+}
+
+//queuedEvent is a dequeued event which is dispatched to a partition
+type queuedEvent struct {
+	fact         events.Event
+	dequeuedTime time.Time
+	tokenID      string
 }
 
 //newStreamingWorker returns configured streaming worker
-func newStreamingWorker(eventQueue events.Queue, processor *schema.Processor, streamingStorage StreamingStorage,
-	tableHelper ...*TableHelper) (*StreamingWorker, error) {
+//dedupCache, partitioner and sampler are optional (nil if deduplication, ordering or schema inference is disabled)
+func newStreamingWorker(eventQueue events.Queue, processor *schema.Processor, streamingStorage StreamingStorage, dedupCache *dedupCache,
+	partitioner *eventPartitioner, sampler *schemaSampler, tableHelper ...*TableHelper) (*StreamingWorker, error) {
 	err := processor.InitJavaScriptTemplates()
 	if err != nil {
 		return nil, err
@@ -48,15 +75,29 @@ func newStreamingWorker(eventQueue events.Queue, processor *schema.Processor, st
 		eventQueue:       eventQueue,
 		processor:        processor,
 		streamingStorage: streamingStorage,
+		dedupCache:       dedupCache,
+		partitioner:      partitioner,
+		sampler:          sampler,
 		tableHelper:      tableHelper,
+		retries:          newRetryCounter(appconfig.Instance.StreamingMaxRetries),
 		closed:           atomic.NewBool(false),
+		done:             make(chan struct{}),
 	}, nil
 }
 
 //Run goroutine to:
 //1. read from queue
 //2. Insert in events.StreamingStorage
+//if partitioner is configured, events are dispatched to partitions goroutines: events with the same key are processed in order
 func (sw *StreamingWorker) start() {
+	dispatch := sw.process
+	if sw.partitioner != nil && !sw.streamingStorage.IsStaging() {
+		dispatch = sw.startPartitions()
+	}
+	if sw.sampler != nil && !sw.streamingStorage.IsStaging() {
+		sw.startSamplesFlushing()
+	}
+
 	safego.RunWithRestart(func() {
 		for {
 			if sw.streamingStorage.IsStaging() {
@@ -76,78 +117,333 @@ func (sw *StreamingWorker) start() {
 				continue
 			}
 
-			//dequeued event was from retry call and retry timeout hasn't come
-			if timestamp.Now().Before(dequeuedTime) {
+			if !sw.acquire() {
+				//worker has been closed while waiting for the event: it is returned into the queue
 				sw.eventQueue.ConsumeTimed(fact, dequeuedTime, tokenID)
 				continue
 			}
+			func() {
+				defer sw.inFlight.Done()
+				dispatch(fact, dequeuedTime, tokenID)
+			}()
+		}
+	})
+}
 
-			//is used in writing counters/metrics/events cache
-			eventContext := &adapters.EventContext{
-				CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
-				DestinationID: sw.streamingStorage.ID(),
-				EventID:       sw.streamingStorage.GetUniqueIDField().Extract(fact),
-				TokenID:       tokenID,
-				Src:           events.ExtractSrc(fact),
-				RawEvent:      fact,
+//startPartitions runs a goroutine per partition and returns func which dispatches event to the partition by the event key
+//every dispatched event is in-flight until it is processed: Close waits for the partitions to be drained
+func (sw *StreamingWorker) startPartitions() func(fact events.Event, dequeuedTime time.Time, tokenID string) {
+	sw.partitions = make([]chan *queuedEvent, sw.partitioner.partitions)
+	for i := range sw.partitions {
+		partition := make(chan *queuedEvent, partitionQueueSize)
+		sw.partitions[i] = partition
+		safego.RunWithRestart(func() {
+			//partition channel is closed by Close after all dispatched events have been processed
+			for qe := range partition {
+				sw.processInOrder(qe)
 			}
+		})
+	}
+	logging.Infof("[%s] events are processed in order by key in %d partitions", sw.streamingStorage.ID(), len(sw.partitions))
 
-			envelops, err := sw.processor.ProcessEvent(fact)
-			if err != nil {
-				if err == schema.ErrSkipObject {
-					if !appconfig.Instance.DisableSkipEventsWarn {
-						logging.Warnf("[%s] Event [%s]: %v", sw.streamingStorage.ID(), sw.streamingStorage.GetUniqueIDField().Extract(fact), err)
-					}
-
-					sw.streamingStorage.SkipEvent(eventContext, err)
-				} else {
-					logging.Errorf("[%s] Unable to process object %s: %v", sw.streamingStorage.ID(), fact.Serialize(), err)
-					sw.streamingStorage.ErrorEvent(true, eventContext, err)
-				}
+	return func(fact events.Event, dequeuedTime time.Time, tokenID string) {
+		sw.inFlight.Add(1)
+		sw.partitions[sw.partitioner.partition(fact)] <- &queuedEvent{fact: fact, dequeuedTime: dequeuedTime, tokenID: tokenID}
+	}
+}
 
-				continue
+//processInOrder processes the partition event. Transient errors are retried in the partition goroutine (instead of re-queueing)
+//so the next events with the same key wait for the event. If the worker is closed, the event is returned into the queue
+func (sw *StreamingWorker) processInOrder(qe *queuedEvent) {
+	defer sw.inFlight.Done()
+
+	//dequeued event was from retry call and retry timeout hasn't come
+	if timestamp.Now().Before(qe.dequeuedTime) {
+		sw.eventQueue.ConsumeTimed(qe.fact, qe.dequeuedTime, qe.tokenID)
+		return
+	}
+
+	for {
+		eventContext, err := sw.processEvent(qe.fact, qe.tokenID)
+		if err == nil {
+			return
+		}
+
+		attempt, ok := sw.retries.fail(retryKey(eventContext, qe.fact))
+		if !ok {
+			sw.fallback(eventContext, qe.fact, attempt, err)
+			return
+		}
+
+		metrics.StreamRetry(sw.processor.DestinationType(), sw.streamingStorage.ID())
+		delay := retryDelay(attempt)
+		select {
+		case <-sw.done:
+			sw.eventQueue.ConsumeTimed(qe.fact, timestamp.Now().Add(delay), qe.tokenID)
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+//process processes the event and inserts it into the storage. Transient errors are retried via the queue
+func (sw *StreamingWorker) process(fact events.Event, dequeuedTime time.Time, tokenID string) {
+	//dequeued event was from retry call and retry timeout hasn't come
+	if timestamp.Now().Before(dequeuedTime) {
+		sw.eventQueue.ConsumeTimed(fact, dequeuedTime, tokenID)
+		return
+	}
+
+	eventContext, err := sw.processEvent(fact, tokenID)
+	if err != nil {
+		sw.retry(eventContext, fact, tokenID, retryKey(eventContext, fact), err)
+	}
+}
+
+//processEvent processes the event and inserts it into the storage
+//returns transient insert error if the event should be retried (other errors are written into fallback)
+func (sw *StreamingWorker) processEvent(fact events.Event, tokenID string) (*adapters.EventContext, error) {
+	//is used in writing counters/metrics/events cache
+	eventContext := &adapters.EventContext{
+		CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
+		DestinationID: sw.streamingStorage.ID(),
+		EventID:       sw.streamingStorage.GetUniqueIDField().Extract(fact),
+		TokenID:       tokenID,
+		Src:           events.ExtractSrc(fact),
+		RawEvent:      fact,
+	}
+
+	logContext := logging.NewContext(sw.streamingStorage.ID(), eventContext.EventID)
+
+	//skip recently stored events with the same unique ID
+	if sw.dedupCache != nil && eventContext.EventID != "" && sw.dedupCache.contains(eventContext.EventID) {
+		sw.streamingStorage.SkipEvent(eventContext, ErrDuplicateEvent)
+		return eventContext, nil
+	}
+
+	envelops, err := sw.processor.ProcessEvent(fact)
+	if err != nil {
+		if schema.IsSkipError(err) {
+			if !appconfig.Instance.DisableSkipEventsWarn {
+				logContext.Warnf("Event skipped: %v", err)
 			}
-			for _, envelop := range envelops {
-				batchHeader := envelop.Header
-				flattenObject := envelop.Event
-				//don't process empty object
-				if !batchHeader.Exists() {
-					continue
-				}
 
-				table := sw.getTableHelper().MapTableSchema(batchHeader)
-				eventContext := &adapters.EventContext{
-					CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
-					DestinationID: sw.streamingStorage.ID(),
-					EventID: utils.NvlString(sw.streamingStorage.GetUniqueIDField().Extract(flattenObject),
-						sw.streamingStorage.GetUniqueIDField().Extract(fact)),
-					TokenID:        tokenID,
-					Src:            events.ExtractSrc(fact),
-					RawEvent:       fact,
-					ProcessedEvent: flattenObject,
-					Table:          table,
-				}
+			sw.streamingStorage.SkipEvent(eventContext, err)
+		} else {
+			logContext.Errorf("Unable to process object %s: %v", fact.Serialize(), err)
+			sw.streamingStorage.ErrorEvent(true, eventContext, err)
+			metrics.StreamDeadLetter(sw.processor.DestinationType(), sw.streamingStorage.ID(), ClassifyError(err))
+		}
+
+		return eventContext, nil
+	}
+	stored := true
+	var transientErr error
+	for _, envelop := range envelops {
+		batchHeader := envelop.Header
+		flattenObject := envelop.Event
+		//don't process empty object
+		if !batchHeader.Exists() {
+			continue
+		}
 
-				if err := sw.streamingStorage.Insert(eventContext); err != nil {
-					logging.Errorf("[%s] Error inserting object %s to table [%s]: %v", sw.streamingStorage.ID(), flattenObject.Serialize(), table.Name, err)
-					if IsConnectionError(err) {
-						//retry
-						sw.eventQueue.ConsumeTimed(fact, timestamp.Now().Add(20*time.Second), tokenID)
-					}
+		table := sw.getTableHelper().MapObjectTableSchema(batchHeader, flattenObject)
+		eventContext := &adapters.EventContext{
+			CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
+			DestinationID: sw.streamingStorage.ID(),
+			EventID: utils.NvlString(sw.streamingStorage.GetUniqueIDField().Extract(flattenObject),
+				sw.streamingStorage.GetUniqueIDField().Extract(fact)),
+			TokenID:        tokenID,
+			Src:            events.ExtractSrc(fact),
+			RawEvent:       fact,
+			ProcessedEvent: flattenObject,
+			Table:          table,
+		}
 
-					continue
+		//the first events of a new table are buffered and inserted after the table creation
+		if sw.sampler != nil && sw.sample(eventContext, fact, tokenID) {
+			stored = false
+			continue
+		}
+
+		if err := sw.streamingStorage.Insert(eventContext); err != nil {
+			stored = false
+			logContext.Errorf("Error inserting object %s to table [%s]: %v", flattenObject.Serialize(), table.Name, err)
+			if IsTransientError(err) {
+				transientErr = err
+			} else {
+				//bad data, config, etc.: the event has been already written into fallback (DLQ). Retries won't help
+				metrics.StreamDeadLetter(sw.processor.DestinationType(), sw.streamingStorage.ID(), ClassifyError(err))
+			}
+
+			continue
+		}
+	}
+
+	if transientErr != nil {
+		return eventContext, transientErr
+	}
+	sw.retries.reset(retryKey(eventContext, fact))
+
+	if stored && sw.dedupCache != nil && eventContext.EventID != "" {
+		sw.dedupCache.add(eventContext.EventID)
+	}
+
+	return eventContext, nil
+}
+
+//sample buffers the event of a new table (see schemaSampler). Returns false if the table exists: the event must be inserted
+//the sample is flushed if it has enough events
+func (sw *StreamingWorker) sample(eventContext *adapters.EventContext, fact events.Event, tokenID string) bool {
+	tableName := eventContext.Table.Name
+	known, sampling := sw.sampler.isKnown(tableName)
+	if known {
+		return false
+	}
+	if !sampling {
+		exists, err := sw.getTableHelper().TableExists(tableName)
+		if err != nil {
+			//the insert handles the destination error
+			return false
+		}
+		if exists {
+			sw.sampler.markKnown(tableName)
+			return false
+		}
+	}
+
+	buffered, full := sw.sampler.add(&sampledEvent{eventContext: eventContext, fact: fact, tokenID: tokenID})
+	if full != nil {
+		sw.flushSample(full)
+	}
+
+	return buffered
+}
+
+//startSamplesFlushing runs a goroutine which flushes samples which have been buffered for the max wait time
+//flushing is in-flight: Close waits for it and flushes the rest samples
+func (sw *StreamingWorker) startSamplesFlushing() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sw.done:
+				return
+			case now := <-ticker.C:
+				if !sw.acquire() {
+					return
+				}
+				for _, sample := range sw.sampler.takeExpired(now) {
+					sw.flushSample(sample)
 				}
+				sw.inFlight.Done()
+			}
+		}
+	})
+}
+
+//flushSample creates the table with the union schema of the sampled events and inserts them
+//transient insert errors are retried via the queue
+func (sw *StreamingWorker) flushSample(sample *tableSample) {
+	logging.Infof("[%s] table %s schema has been inferred from %d events", sw.streamingStorage.ID(), sample.table.Name, len(sample.events))
+	if _, err := sw.getTableHelper().EnsureTableWithCaching(sw.streamingStorage.ID(), sample.table); err != nil {
+		//every event insert ensures the table as well
+		logging.Errorf("[%s] Error creating table %s with inferred schema: %v", sw.streamingStorage.ID(), sample.table.Name, err)
+	} else {
+		sw.sampler.markKnown(sample.table.Name)
+	}
+
+	for _, event := range sample.events {
+		eventContext := event.eventContext
+		if err := sw.streamingStorage.Insert(eventContext); err != nil {
+			logging.NewContext(sw.streamingStorage.ID(), eventContext.EventID).Errorf("Error inserting sampled object %s to table [%s]: %v",
+				eventContext.ProcessedEvent.Serialize(), sample.table.Name, err)
+			if IsTransientError(err) {
+				sw.retry(eventContext, event.fact, event.tokenID, retryKey(eventContext, event.fact), err)
+			} else {
+				metrics.StreamDeadLetter(sw.processor.DestinationType(), sw.streamingStorage.ID(), ClassifyError(err))
 			}
+			continue
 		}
+
+		if sw.dedupCache != nil && eventContext.EventID != "" {
+			sw.dedupCache.add(eventContext.EventID)
+		}
+	}
+}
+
+//retryKey returns the event retries counter key: event ID or the serialized event
+func retryKey(eventContext *adapters.EventContext, fact events.Event) string {
+	return utils.NvlString(eventContext.EventID, fact.Serialize())
+}
+
+//retry re-queues the event with exponential backoff or writes it into fallback (DLQ) if max retries are exceeded
+//so a poison event can't stall the worker forever
+func (sw *StreamingWorker) retry(eventContext *adapters.EventContext, fact events.Event, tokenID, retryKey string, err error) {
+	attempt, ok := sw.retries.fail(retryKey)
+	if ok {
+		metrics.StreamRetry(sw.processor.DestinationType(), sw.streamingStorage.ID())
+		sw.eventQueue.ConsumeTimed(fact, timestamp.Now().Add(retryDelay(attempt)), tokenID)
+		return
+	}
+
+	sw.fallback(eventContext, fact, attempt, err)
+}
+
+//fallback writes the event into fallback (DLQ) when max retries are exceeded
+func (sw *StreamingWorker) fallback(eventContext *adapters.EventContext, fact events.Event, attempt int, err error) {
+	logging.NewContext(sw.streamingStorage.ID(), eventContext.EventID).Errorf("Event has been sent to fallback: max retries (%d) exceeded: %v", sw.retries.maxRetries, err)
+	metrics.StreamDeadLetter(sw.processor.DestinationType(), sw.streamingStorage.ID(), dlq.ClassificationTransient)
+	sw.streamingStorage.Fallback(&events.FailedEvent{
+		Event:          []byte(fact.Serialize()),
+		Error:          fmt.Sprintf("max retries (%d) exceeded: %v", sw.retries.maxRetries, err),
+		EventID:        eventContext.EventID,
+		Classification: dlq.ClassificationTransient,
+		Attempt:        attempt,
+		CorrelationID:  eventContext.EventID,
 	})
 }
 
+//Close stops the worker and waits for in-flight (including partitions buffered) events so they aren't inserted with the closed storage connection
 func (sw *StreamingWorker) Close() error {
-	sw.closed.Store(true)
+	sw.closeMutex.Lock()
+	closing := sw.closed.CAS(false, true)
+	if closing {
+		close(sw.done)
+	}
+	sw.closeMutex.Unlock()
+	if !closing {
+		return nil
+	}
+
+	//partitions are drained: nothing can be dispatched after in-flight events have been finished
+	sw.inFlight.Wait()
+	for _, partition := range sw.partitions {
+		close(partition)
+	}
 
+	//buffered events of new tables are stored before the storage is closed
+	if sw.sampler != nil {
+		for _, sample := range sw.sampler.takeAll() {
+			sw.flushSample(sample)
+		}
+	}
 	return nil
 }
 
+//acquire registers an in-flight event. Returns false if the worker has been closed
+func (sw *StreamingWorker) acquire() bool {
+	sw.closeMutex.RLock()
+	defer sw.closeMutex.RUnlock()
+	if sw.closed.Load() {
+		return false
+	}
+
+	sw.inFlight.Add(1)
+	return true
+}
+
 func (sw *StreamingWorker) getTableHelper() *TableHelper {
 	num := rand.Intn(len(sw.tableHelper))
 	return sw.tableHelper[num]
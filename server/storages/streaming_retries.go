@@ -0,0 +1,71 @@
+package storages
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultStreamingMaxRetries = 10
+	streamingRetryBaseDelay    = 20 * time.Second
+	streamingRetryMaxDelay     = 10 * time.Minute
+)
+
+//retryCounter is an in-memory counter of streaming insert attempts per event key
+//entries are removed when the event is stored or is sent to the dead-letter queue
+type retryCounter struct {
+	maxRetries int
+
+	mutex    *sync.Mutex
+	attempts map[string]int
+}
+
+//newRetryCounter returns configured retryCounter. Default value is used for not positive maxRetries
+func newRetryCounter(maxRetries int) *retryCounter {
+	if maxRetries <= 0 {
+		maxRetries = defaultStreamingMaxRetries
+	}
+
+	return &retryCounter{
+		maxRetries: maxRetries,
+		mutex:      &sync.Mutex{},
+		attempts:   map[string]int{},
+	}
+}
+
+//fail increments failed attempts of the event and returns attempts count and true if the event can be retried
+//the key is removed if max retries are exceeded
+func (rc *retryCounter) fail(key string) (int, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	attempt := rc.attempts[key] + 1
+	if attempt > rc.maxRetries {
+		delete(rc.attempts, key)
+		return attempt, false
+	}
+
+	rc.attempts[key] = attempt
+	return attempt, true
+}
+
+//reset removes the event key (e.g. when the event has been stored)
+func (rc *retryCounter) reset(key string) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	delete(rc.attempts, key)
+}
+
+//retryDelay returns exponential backoff delay of the attempt: 20s, 40s, 80s, .. not more than 10 minutes
+func retryDelay(attempt int) time.Duration {
+	delay := streamingRetryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= streamingRetryMaxDelay {
+			return streamingRetryMaxDelay
+		}
+	}
+
+	return delay
+}
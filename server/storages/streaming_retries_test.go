@@ -0,0 +1,37 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryCounter(t *testing.T) {
+	counter := newRetryCounter(2)
+
+	attempt, ok := counter.fail("id1")
+	require.True(t, ok)
+	require.Equal(t, 1, attempt)
+	attempt, ok = counter.fail("id1")
+	require.True(t, ok)
+	require.Equal(t, 2, attempt)
+
+	//poison event is dead-lettered and forgotten
+	attempt, ok = counter.fail("id1")
+	require.False(t, ok)
+	require.Equal(t, 3, attempt)
+	require.Empty(t, counter.attempts)
+
+	counter.fail("id2")
+	counter.reset("id2")
+	require.Empty(t, counter.attempts)
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, 20*time.Second, retryDelay(1))
+	require.Equal(t, 40*time.Second, retryDelay(2))
+	require.Equal(t, 320*time.Second, retryDelay(5))
+	require.Equal(t, 10*time.Minute, retryDelay(6))
+	require.Equal(t, 10*time.Minute, retryDelay(100))
+}
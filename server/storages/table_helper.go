@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/locks"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,6 +35,16 @@ type TableHelper struct {
 	destinationType string
 	streamMode      bool
 	maxColumns      int
+
+	sharder *TableSharder
+
+	//tablePrefix and tableSuffix are added to all table names (see TableName)
+	tablePrefix string
+	tableSuffix string
+
+	//identifierTruncator truncates table names longer than maxIdentifierLength (see TableName)
+	identifierTruncator *schema.IdentifierTruncator
+	maxIdentifierLength int
 }
 
 //NewTableHelper returns configured TableHelper instance
@@ -60,7 +71,7 @@ func NewTableHelper(dbSchema string, sqlAdapter adapters.SQLAdapter, coordinatio
 func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters.Table {
 	table := &adapters.Table{
 		Schema:   th.dbSchema,
-		Name:     batchHeader.TableName,
+		Name:     th.TableName(batchHeader.TableName),
 		Columns:  adapters.Columns{},
 		PKFields: th.pkFields,
 	}
@@ -89,6 +100,142 @@ func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters
 	return table
 }
 
+//SetTableNameAffixes enables adding prefix and suffix to all table names (data_layout.table_prefix and table_suffix)
+func (th *TableHelper) SetTableNameAffixes(prefix, suffix string) {
+	th.tablePrefix = prefix
+	th.tableSuffix = suffix
+}
+
+//SetIdentifierTruncator enables truncating over-length table names with the hash of the original name (data_layout.identifier_truncation = hash)
+func (th *TableHelper) SetIdentifierTruncator(truncator *schema.IdentifierTruncator, maxLength int) {
+	th.identifierTruncator = truncator
+	th.maxIdentifierLength = maxLength
+}
+
+//TableName returns the destination table name of the table (from the data layout or the source stream):
+//with table prefix and suffix. Over-length names are truncated if identifier truncator is set.
+//Table shards are suffixed after them (see MapObjectTableSchema): the max length is reduced by the shard suffix length
+func (th *TableHelper) TableName(tableName string) string {
+	tableName = th.tablePrefix + tableName + th.tableSuffix
+	if th.identifierTruncator != nil {
+		tableName = th.identifierTruncator.Truncate(tableName, th.maxIdentifierLength-th.sharder.suffixLength())
+	}
+
+	return tableName
+}
+
+//SetTableSharder enables writing objects into time-suffixed tables (see MapObjectTableSchema)
+func (th *TableHelper) SetTableSharder(sharder *TableSharder) {
+	th.sharder = sharder
+}
+
+//ShardTableName returns time-suffixed table name according to the object timestamp if table sharding is enabled
+//otherwise returns tableName as is
+func (th *TableHelper) ShardTableName(tableName string, object map[string]interface{}) string {
+	if th.sharder == nil {
+		return tableName
+	}
+
+	return th.sharder.ShardTableName(tableName, object)
+}
+
+//MapObjectTableSchema calls MapTableSchema and applies table sharding according to the object timestamp
+func (th *TableHelper) MapObjectTableSchema(batchHeader *schema.BatchHeader, object map[string]interface{}) *adapters.Table {
+	table := th.MapTableSchema(batchHeader)
+	if th.sharder == nil {
+		return table
+	}
+
+	table.Name = th.sharder.ShardTableName(table.Name, object)
+	if table.PrimaryKeyName != "" {
+		table.PrimaryKeyName = adapters.BuildConstraintName(table.Schema, table.Name)
+	}
+	return table
+}
+
+//TableColumns returns column names of the table from the in-memory cache or from the destination
+//tableName is the table name before adding table prefix and suffix (see TableName)
+//returns nil if the table doesn't exist or its schema can't be got
+func (th *TableHelper) TableColumns(tableName string) map[string]bool {
+	tableName = th.TableName(tableName)
+	th.RLock()
+	table, ok := th.tables[tableName]
+	th.RUnlock()
+
+	if !ok {
+		var err error
+		table, err = th.sqlAdapter.GetTableSchema(tableName)
+		if err != nil {
+			logging.Errorf("Error getting table %s schema: %v", tableName, err)
+			return nil
+		}
+		if !table.Exists() {
+			return nil
+		}
+	}
+
+	columns := make(map[string]bool, len(table.Columns))
+	for name := range table.Columns {
+		columns[name] = true
+	}
+	return columns
+}
+
+//TableExists returns true if the table is in the in-memory cache or exists in the destination
+func (th *TableHelper) TableExists(tableName string) (bool, error) {
+	th.RLock()
+	_, ok := th.tables[tableName]
+	th.RUnlock()
+	if ok {
+		return true, nil
+	}
+
+	table, err := th.sqlAdapter.GetTableSchema(tableName)
+	if err != nil {
+		return false, err
+	}
+
+	return table.Exists(), nil
+}
+
+//CachedTable returns a copy of in-memory table schema (mapped columns) and true if the table is cached
+func (th *TableHelper) CachedTable(tableName string) (*adapters.Table, bool) {
+	th.RLock()
+	defer th.RUnlock()
+
+	table, ok := th.tables[tableName]
+	if !ok {
+		return nil, false
+	}
+
+	return table.Clone(), true
+}
+
+//CachedTableNames returns names of all in-memory cached tables
+func (th *TableHelper) CachedTableNames() []string {
+	th.RLock()
+	defer th.RUnlock()
+
+	names := make([]string, 0, len(th.tables))
+	for name := range th.tables {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+//ClearCachedTable removes in-memory table schema (case insensitive) e.g. after the table has been dropped
+func (th *TableHelper) ClearCachedTable(tableName string) {
+	th.Lock()
+	defer th.Unlock()
+
+	for name := range th.tables {
+		if strings.EqualFold(name, tableName) {
+			delete(th.tables, name)
+		}
+	}
+}
+
 //EnsureTableWithCaching calls EnsureTable with cacheTable = true
 //it is used in stream destinations (because we don't have time to select table schema, but there is retry on error)
 func (th *TableHelper) EnsureTableWithCaching(destinationID string, dataSchema *adapters.Table) (*adapters.Table, error) {
@@ -1,13 +1,17 @@
 package storages
 
 import (
+	"fmt"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/parsers"
 	"github.com/spf13/viper"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -138,3 +142,124 @@ return $
 		})
 	}
 }
+
+// ddlSQLAdapter keeps a single table in memory and fails DDL like a DWH does (existing table or column)
+type ddlSQLAdapter struct {
+	sync.Mutex
+	table   *adapters.Table
+	patches int
+}
+
+func (a *ddlSQLAdapter) GetTableSchema(tableName string) (*adapters.Table, error) {
+	a.Lock()
+	defer a.Unlock()
+	if a.table == nil {
+		return &adapters.Table{Name: tableName, Columns: adapters.Columns{}, PKFields: map[string]bool{}}, nil
+	}
+	return a.table.Clone(), nil
+}
+
+func (a *ddlSQLAdapter) CreateTable(schemaToCreate *adapters.Table) error {
+	a.Lock()
+	defer a.Unlock()
+	if a.table != nil {
+		return fmt.Errorf("table %s already exists", schemaToCreate.Name)
+	}
+	a.table = schemaToCreate.Clone()
+	return nil
+}
+
+func (a *ddlSQLAdapter) PatchTableSchema(schemaToAdd *adapters.Table) error {
+	a.Lock()
+	defer a.Unlock()
+	a.patches++
+	for name, column := range schemaToAdd.Columns {
+		if _, ok := a.table.Columns[name]; ok {
+			return fmt.Errorf("column %s already exists", name)
+		}
+		a.table.Columns[name] = column
+	}
+	return nil
+}
+
+func (a *ddlSQLAdapter) BulkInsert(*adapters.Table, []map[string]interface{}) error { return nil }
+func (a *ddlSQLAdapter) BulkUpdate(*adapters.Table, []map[string]interface{}, *adapters.DeleteConditions) error {
+	return nil
+}
+func (a *ddlSQLAdapter) Truncate(string) error               { return nil }
+func (a *ddlSQLAdapter) Insert(*adapters.EventContext) error { return nil }
+func (a *ddlSQLAdapter) Close() error                        { return nil }
+
+func TestEnsureTableConcurrently(t *testing.T) {
+	sqlAdapter := &ddlSQLAdapter{}
+	tableHelper := NewTableHelper("test", sqlAdapter, coordination.NewInMemoryService("test"), map[string]bool{}, adapters.SchemaToPostgres, 0, PostgresType)
+
+	//the first chunks create the table, the rest add the same new column
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		columns := adapters.Columns{"id": typing.SQLColumn{Type: "text"}}
+		if i%2 == 1 {
+			columns["new_column"] = typing.SQLColumn{Type: "bigint"}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tableHelper.EnsureTableWithoutCaching("test_destination", &adapters.Table{Schema: "test", Name: "events", Columns: columns, PKFields: map[string]bool{}})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, adapters.Columns{"id": typing.SQLColumn{Type: "text"}, "new_column": typing.SQLColumn{Type: "bigint"}}, sqlAdapter.table.Columns)
+	require.LessOrEqual(t, sqlAdapter.patches, 1, "new column must be added once")
+}
+
+func TestTableNameAffixes(t *testing.T) {
+	tableHelper := NewTableHelper("test", nil, nil, map[string]bool{"id": true}, adapters.SchemaToPostgres, 0, PostgresType)
+	tableHelper.SetTableNameAffixes("prod_", "_v2")
+	header := &schema.BatchHeader{TableName: "events", Fields: schema.Fields{"id": schema.NewField(typing.STRING)}}
+
+	table := tableHelper.MapTableSchema(header)
+	require.Equal(t, "prod_events_v2", table.Name)
+	require.Equal(t, adapters.BuildConstraintName("test", "prod_events_v2"), table.PrimaryKeyName)
+
+	//shard suffix is added after table prefix and suffix
+	sharder, err := NewTableSharder(adapters.TableShardingDaily, nil)
+	require.NoError(t, err)
+	tableHelper.SetTableSharder(sharder)
+	eventTime := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	shard := tableHelper.MapObjectTableSchema(header, map[string]interface{}{"_timestamp": eventTime})
+	require.Equal(t, "prod_events_v2_20240102", shard.Name)
+
+	require.NoError(t, validateSnowflakeTableName(shard.Name))
+	err = validateSnowflakeTableName(strings.Repeat("a", adapters.SnowflakeMaxIdentifierLength+1))
+	require.ErrorIs(t, err, ErrBadData)
+}
+
+func TestTableNameTruncation(t *testing.T) {
+	tableHelper := NewTableHelper("test", nil, nil, nil, adapters.SchemaToSnowflake, 0, SnowflakeType)
+	tableHelper.SetTableNameAffixes("prod_", "")
+	truncator, err := schema.NewIdentifierTruncator("test", schema.IdentifierTruncationHash)
+	require.NoError(t, err)
+	tableHelper.SetIdentifierTruncator(truncator, 30)
+
+	require.Equal(t, "prod_events", tableHelper.TableName("events"))
+	longName := tableHelper.TableName("very_long_stream_name_of_source_a")
+	require.Len(t, longName, 30)
+	require.NotEqual(t, longName, tableHelper.TableName("very_long_stream_name_of_source_b"))
+	require.Equal(t, longName, tableHelper.TableName("very_long_stream_name_of_source_a"))
+
+	//shard suffix fits the limit
+	sharder, err := NewTableSharder(adapters.TableShardingDaily, nil)
+	require.NoError(t, err)
+	tableHelper.SetTableSharder(sharder)
+	header := &schema.BatchHeader{TableName: "very_long_stream_name_of_source_a", Fields: schema.Fields{"id": schema.NewField(typing.STRING)}}
+	shard := tableHelper.MapObjectTableSchema(header, map[string]interface{}{"_timestamp": time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)})
+	require.Len(t, shard.Name, 30)
+	require.True(t, strings.HasSuffix(shard.Name, "_20240102"), shard.Name)
+}
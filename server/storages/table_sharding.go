@@ -0,0 +1,97 @@
+package storages
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+var (
+	dailyShardSuffixRegexp   = regexp.MustCompile(`^(.+)_\d{8}$`)
+	monthlyShardSuffixRegexp = regexp.MustCompile(`^(.+)_\d{6}$`)
+)
+
+//TableSharder builds time-suffixed table names (shards) according to the event timestamp
+//e.g. events -> events_20240101 (daily) or events_202401 (monthly)
+type TableSharder struct {
+	layout       string
+	suffixRegexp *regexp.Regexp
+	monthly      bool
+	eventTime    func(object map[string]interface{}) time.Time
+}
+
+//NewTableSharder returns configured TableSharder instance or nil if granularity is empty
+//eventTime returns the object timestamp (if nil, _timestamp or the current time is used)
+func NewTableSharder(granularity string, eventTime func(object map[string]interface{}) time.Time) (*TableSharder, error) {
+	if eventTime == nil {
+		resolver := newEventTimeResolver("", "", timestamp.Key)
+		eventTime = func(object map[string]interface{}) time.Time {
+			t, _ := resolver.resolve(object)
+			return t
+		}
+	}
+
+	switch granularity {
+	case "":
+		return nil, nil
+	case adapters.TableShardingDaily:
+		return &TableSharder{layout: "20060102", suffixRegexp: dailyShardSuffixRegexp, eventTime: eventTime}, nil
+	case adapters.TableShardingMonthly:
+		return &TableSharder{layout: "200601", suffixRegexp: monthlyShardSuffixRegexp, monthly: true, eventTime: eventTime}, nil
+	default:
+		return nil, fmt.Errorf("Unknown table sharding granularity: %s. Available values: [%s, %s]", granularity, adapters.TableShardingDaily, adapters.TableShardingMonthly)
+	}
+}
+
+//ShardTableName returns tableName with the object timestamp suffix
+//current time is used if the object doesn't have valid timestamp
+func (ts *TableSharder) ShardTableName(tableName string, object map[string]interface{}) string {
+	return tableName + "_" + ts.eventTime(object).Format(ts.layout)
+}
+
+//suffixLength returns the length of the shard suffix (0 if sharding is disabled)
+func (ts *TableSharder) suffixLength() int {
+	if ts == nil {
+		return 0
+	}
+
+	return len(ts.layout) + 1
+}
+
+//BaseTableName returns table name without shard suffix and true if shardTableName is a shard
+func (ts *TableSharder) BaseTableName(shardTableName string) (string, bool) {
+	parts := ts.suffixRegexp.FindStringSubmatch(shardTableName)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+//IsShardOf returns true if tableName is a shard of baseTableName (case insensitive)
+func (ts *TableSharder) IsShardOf(baseTableName, tableName string) bool {
+	base, ok := ts.BaseTableName(tableName)
+	return ok && strings.EqualFold(base, baseTableName)
+}
+
+//ShardPeriod returns UTC time range [start, end) of events which are written into shardTableName
+//returns false if shardTableName isn't a shard
+func (ts *TableSharder) ShardPeriod(shardTableName string) (time.Time, time.Time, bool) {
+	if _, ok := ts.BaseTableName(shardTableName); !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start, err := time.Parse(ts.layout, shardTableName[len(shardTableName)-len(ts.layout):])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	if ts.monthly {
+		return start, start.AddDate(0, 1, 0), true
+	}
+	return start, start.AddDate(0, 0, 1), true
+}
@@ -0,0 +1,75 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardTableName(t *testing.T) {
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	eventTime := time.Date(2024, 1, 2, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		granularity string
+		object      map[string]interface{}
+		expected    string
+	}{
+		{"daily time.Time", adapters.TableShardingDaily, map[string]interface{}{timestamp.Key: eventTime}, "events_20240102"},
+		{"daily string", adapters.TableShardingDaily, map[string]interface{}{timestamp.Key: timestamp.ToISOFormat(eventTime)}, "events_20240102"},
+		{"daily other timezone", adapters.TableShardingDaily, map[string]interface{}{timestamp.Key: "2024-01-03T01:30:00+03:00"}, "events_20240102"},
+		{"monthly", adapters.TableShardingMonthly, map[string]interface{}{timestamp.Key: eventTime}, "events_202401"},
+		{"without timestamp", adapters.TableShardingDaily, map[string]interface{}{}, "events_" + timestamp.Now().UTC().Format("20060102")},
+		{"malformed timestamp", adapters.TableShardingMonthly, map[string]interface{}{timestamp.Key: "yesterday"}, "events_" + timestamp.Now().UTC().Format("200601")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sharder, err := NewTableSharder(tt.granularity, nil)
+			require.NoError(t, err)
+
+			actual := sharder.ShardTableName("events", tt.object)
+			require.Equal(t, tt.expected, actual, "Shard table names aren't equal")
+
+			base, ok := sharder.BaseTableName(actual)
+			require.True(t, ok)
+			require.Equal(t, "events", base)
+			require.True(t, sharder.IsShardOf("events", actual))
+			require.True(t, sharder.IsShardOf("EVENTS", actual))
+			require.False(t, sharder.IsShardOf("pages", actual))
+		})
+	}
+
+	sharder, err := NewTableSharder("", nil)
+	require.NoError(t, err)
+	require.Nil(t, sharder)
+
+	_, err = NewTableSharder("hourly", nil)
+	require.Error(t, err)
+}
+
+func TestShardPeriod(t *testing.T) {
+	daily, err := NewTableSharder(adapters.TableShardingDaily, nil)
+	require.NoError(t, err)
+	monthly, err := NewTableSharder(adapters.TableShardingMonthly, nil)
+	require.NoError(t, err)
+
+	start, end, ok := daily.ShardPeriod("EVENTS_20240131")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, end, ok = monthly.ShardPeriod("events_202412")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), end)
+
+	_, _, ok = daily.ShardPeriod("events")
+	require.False(t, ok)
+	_, _, ok = daily.ShardPeriod("events_20241399")
+	require.False(t, ok)
+}
@@ -2,6 +2,7 @@ package storages
 
 import (
 	"io"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/events"
@@ -25,6 +26,8 @@ const (
 	AmplitudeType       = "amplitude"
 	HubSpotType         = "hubspot"
 	DbtCloudType        = "dbtcloud"
+	FileType            = "file"
+	HTTPType            = "http"
 )
 
 //Storage is a destination representation
@@ -44,6 +47,30 @@ type Storage interface {
 	IsStaging() bool
 	IsCachingDisabled() bool
 	Clean(tableName string) error
+	//CleanOlderThan deletes tableName rows with column value older than age
+	CleanOlderThan(tableName, column string, age time.Duration) error
+	//Stats returns a snapshot of the destination lifetime counters
+	Stats() StorageStats
+}
+
+//HealthChecker is implemented by storages which can check the connection to the destination
+type HealthChecker interface {
+	//Health returns error if the destination isn't reachable
+	Health() error
+}
+
+//PatchUpdater is implemented by storages which support updating only changed columns of a record
+type PatchUpdater interface {
+	//Patch processes object and updates only changedFields columns of the record with the object unique ID
+	//changedFields are JSON paths (e.g. /user/id) of the object fields
+	Patch(object map[string]interface{}, changedFields []string) error
+}
+
+//BatchUpdater is implemented by storages which support updating many records with a few statements
+type BatchUpdater interface {
+	//UpdateBatch processes objects and updates records with the objects unique IDs
+	//updates all columns if changedFields is nil, otherwise only changed ones (see PatchUpdater)
+	UpdateBatch(objects []map[string]interface{}, changedFields []string) error
 }
 
 //StorageProxy is a storage proxy
@@ -53,9 +80,17 @@ type StorageProxy interface {
 	GetUniqueIDField() *identifiers.UniqueID
 	GetPostHandleDestinations() []string
 	GetGeoResolverID() string
+	//GetMaxStoreLatency returns max_store_latency_ms of the destination or 0 if it isn't configured
+	GetMaxStoreLatency() time.Duration
 	IsCachingDisabled() bool
+	IsCachingSkipped(event events.Event) bool
 	ID() string
 	Type() string
+	Pause()
+	Resume()
+	IsPaused() bool
+	//Health returns error if the storage isn't initialized or its HealthChecker fails
+	Health() error
 }
 
 //StoreResult is used as a Batch storing result
@@ -63,6 +98,8 @@ type StoreResult struct {
 	Err       error
 	RowsCount int
 	EventsSrc map[string]int
+	//Latency is a duration of the table storing
+	Latency time.Duration
 }
 
 //UserRecognitionConfiguration recognition configuration
@@ -6,6 +6,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
@@ -75,9 +76,10 @@ func syncStoreImpl(storage Storage, overriddenDataSchema *schema.BatchHeader, ob
 }
 
 //cleanImpl implements common table cleaning
+//tableName is the table name before adding table prefix and suffix
 func cleanImpl(storage Storage, tableName string) error {
-	adapter, _ := storage.getAdapters()
-	return adapter.Truncate(tableName)
+	adapter, tableHelper := storage.getAdapters()
+	return adapter.Truncate(tableHelper.TableName(tableName))
 }
 
 func processData(storage Storage, overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (map[string]*schema.ProcessedFile, error) {
@@ -93,9 +95,11 @@ func processData(storage Storage, overriddenDataSchema *schema.BatchHeader, obje
 			return nil, err
 		}
 
-		if len(overriddenDataSchema.Fields) > 0 {
+		//the table might be filtered by table_allowlist/table_denylist
+		flatData, ok := flatDataPerTable[overriddenDataSchema.TableName]
+		if ok && len(overriddenDataSchema.Fields) > 0 {
 			// enrich overridden schema types
-			flatDataPerTable[overriddenDataSchema.TableName].BatchHeader.Fields.OverrideTypes(overriddenDataSchema.Fields)
+			flatData.BatchHeader.Fields.OverrideTypes(overriddenDataSchema.Fields)
 		}
 
 		return flatDataPerTable, nil
@@ -116,3 +120,26 @@ func processData(storage Storage, overriddenDataSchema *schema.BatchHeader, obje
 
 	return flatDataPerTable, nil
 }
+
+//patchColumns returns batch header and processed object only with columns of changedFields (JSON paths)
+//returns nil header if there are no changed columns in the processed object
+func patchColumns(batchHeader *schema.BatchHeader, processedObject map[string]interface{}, changedFields []string) (*schema.BatchHeader, map[string]interface{}) {
+	patchHeader := &schema.BatchHeader{TableName: batchHeader.TableName, Fields: schema.Fields{}}
+	patchObject := map[string]interface{}{}
+	for _, changedField := range changedFields {
+		columnName := schema.Reformat(jsonutils.NewJSONPath(changedField).FieldName())
+		field, ok := batchHeader.Fields[columnName]
+		if !ok {
+			continue
+		}
+
+		patchHeader.Fields[columnName] = field
+		patchObject[columnName] = processedObject[columnName]
+	}
+
+	if !patchHeader.Exists() {
+		return nil, nil
+	}
+
+	return patchHeader, patchObject
+}
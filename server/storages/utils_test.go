@@ -0,0 +1,62 @@
+package storages
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchColumns(t *testing.T) {
+	batchHeader := &schema.BatchHeader{TableName: "events", Fields: schema.Fields{
+		"eventn_ctx_event_id":         schema.NewField(typing.STRING),
+		"eventn_ctx_user_internal_id": schema.NewField(typing.STRING),
+		"eventn_ctx_user_email":       schema.NewField(typing.STRING),
+		"url":                         schema.NewField(typing.STRING),
+	}}
+	processedObject := map[string]interface{}{
+		"eventn_ctx_event_id":         "1",
+		"eventn_ctx_user_internal_id": "user1",
+		"eventn_ctx_user_email":       "user1@example.com",
+		"url":                         "https://jitsu.com",
+	}
+
+	tests := []struct {
+		name           string
+		changedFields  []string
+		expectedHeader *schema.BatchHeader
+		expectedObject map[string]interface{}
+	}{
+		{
+			"changed columns",
+			[]string{"/eventn_ctx/user/internal_id", "/eventn_ctx/user/email"},
+			&schema.BatchHeader{TableName: "events", Fields: schema.Fields{
+				"eventn_ctx_user_internal_id": schema.NewField(typing.STRING),
+				"eventn_ctx_user_email":       schema.NewField(typing.STRING),
+			}},
+			map[string]interface{}{"eventn_ctx_user_internal_id": "user1", "eventn_ctx_user_email": "user1@example.com"},
+		},
+		{
+			"changed column isn't in the object",
+			[]string{"/eventn_ctx/user/internal_id", "/eventn_ctx/user/anonymous_id"},
+			&schema.BatchHeader{TableName: "events", Fields: schema.Fields{
+				"eventn_ctx_user_internal_id": schema.NewField(typing.STRING),
+			}},
+			map[string]interface{}{"eventn_ctx_user_internal_id": "user1"},
+		},
+		{
+			"no changed columns",
+			[]string{"/user/id"},
+			nil,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualHeader, actualObject := patchColumns(batchHeader, processedObject, tt.changedFields)
+			require.Equal(t, tt.expectedHeader, actualHeader, "Patch headers aren't equal")
+			require.Equal(t, tt.expectedObject, actualObject, "Patch objects aren't equal")
+		})
+	}
+}
@@ -26,7 +26,7 @@ type WebHook struct {
 }
 
 func init() {
-	RegisterStorage(StorageType{typeName: WebHookType, createFunc: NewWebHook, isSQL: false})
+	RegisterStorage(StorageType{typeName: WebHookType, createFunc: NewWebHook, isSQL: false, configSchema: webHookSchema})
 }
 
 //NewWebHook returns configured WebHook destination
@@ -75,6 +75,7 @@ func NewWebHook(config *Config) (Storage, error) {
 	wh.destinationID = config.destinationID
 	wh.processor = config.processor
 	wh.fallbackLogger = config.loggerFactory.CreateFailedLogger(config.destinationID)
+	wh.dlqLogger = config.loggerFactory.CreateDLQLogger(config.destinationID)
 	wh.eventsCache = config.eventsCache
 	wh.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 	wh.uniqueIDField = config.uniqueIDField
@@ -82,7 +83,7 @@ func NewWebHook(config *Config) (Storage, error) {
 	wh.cachingConfiguration = config.destination.CachingConfiguration
 
 	//streaming worker (queue reading)
-	wh.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, wh, tableHelper)
+	wh.streamingWorker, err = newStreamingWorker(config.eventQueue, config.processor, wh, config.dedupCache, config.eventPartitioner, config.schemaSampler, tableHelper)
 	if err != nil {
 		return nil, err
 	}
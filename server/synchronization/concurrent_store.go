@@ -0,0 +1,86 @@
+package synchronization
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+//concurrentStore runs chunks storing (SyncStore) with bounded concurrency
+//the first error stops running of new chunks. Concurrency 1 means sequential storing
+//Table creation and patching races between chunks are handled by TableHelper table locks
+type concurrentStore struct {
+	semaphore chan struct{}
+	wg        sync.WaitGroup
+
+	mutex sync.Mutex
+	err   error
+}
+
+func newConcurrentStore(concurrency int) *concurrentStore {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &concurrentStore{semaphore: make(chan struct{}, concurrency)}
+}
+
+//run waits for a free slot and runs storeFunc in a goroutine
+//returns the first error of already finished chunks: in this case storeFunc isn't run
+func (cs *concurrentStore) run(storeFunc func() error) error {
+	cs.semaphore <- struct{}{}
+	if err := cs.firstErr(); err != nil {
+		<-cs.semaphore
+		return err
+	}
+
+	cs.wg.Add(1)
+	safego.Run(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.SystemErrorf("panic during storing sync chunk: %v", r)
+				cs.setErr(fmt.Errorf("panic during storing: %v", r))
+			}
+			<-cs.semaphore
+			cs.wg.Done()
+		}()
+
+		if err := storeFunc(); err != nil {
+			cs.setErr(err)
+		}
+	})
+
+	return nil
+}
+
+//wait waits for all running chunks and returns the first error
+func (cs *concurrentStore) wait() error {
+	cs.wg.Wait()
+	return cs.firstErr()
+}
+
+func (cs *concurrentStore) setErr(err error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	if cs.err == nil {
+		cs.err = err
+	}
+}
+
+func (cs *concurrentStore) firstErr() error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	return cs.err
+}
+
+//syncStore runs storage.SyncStore and measures the number of concurrent sync loads into the destination
+func syncStore(storage storages.Storage, batchHeader *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) error {
+	metrics.IncConcurrentSyncLoads(storage.Type(), storage.ID())
+	defer metrics.DecConcurrentSyncLoads(storage.Type(), storage.ID())
+
+	return storage.SyncStore(batchHeader, objects, timeIntervalValue, false)
+}
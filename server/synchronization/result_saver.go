@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/counters"
 	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
@@ -21,6 +22,9 @@ import (
 	"github.com/jitsucom/jitsu/server/uuid"
 )
 
+//stateSaveRetryDelay is a delay before the first retry of failed state persisting (it grows with every retry)
+const stateSaveRetryDelay = time.Second
+
 //ResultSaver is a Singer/Airbyte result consumer
 //tap is a Singer tap or Airbyte source docker image
 type ResultSaver struct {
@@ -31,13 +35,19 @@ type ResultSaver struct {
 	taskLogger        *TaskLogger
 	destinations      []storages.Storage
 	metaStorage       meta.Storage
+	//is used only if meta storage isn't configured (might be nil)
+	localStateDriver driversbase.LocalStateDriver
 	//mapping stream name -> table name
 	streamTableNames map[string]string
 	configPath       string
+	//stateSaveRetries is a number of retries if state persisting fails
+	stateSaveRetries int
+	//storeConcurrency is a max number of streams which are stored concurrently
+	storeConcurrency int
 }
 
 //NewResultSaver returns configured ResultSaver instance
-func NewResultSaver(task *meta.Task, tap, collectionMetaKey, tableNamePrefix string, taskLogger *TaskLogger, destinations []storages.Storage, metaStorage meta.Storage, streamTableNames map[string]string, configPath string) *ResultSaver {
+func NewResultSaver(task *meta.Task, tap, collectionMetaKey, tableNamePrefix string, taskLogger *TaskLogger, destinations []storages.Storage, metaStorage meta.Storage, localStateDriver driversbase.LocalStateDriver, streamTableNames map[string]string, configPath string, stateSaveRetries, storeConcurrency int) *ResultSaver {
 	return &ResultSaver{
 		task:              task,
 		tap:               tap,
@@ -46,82 +56,32 @@ func NewResultSaver(task *meta.Task, tap, collectionMetaKey, tableNamePrefix str
 		taskLogger:        taskLogger,
 		destinations:      destinations,
 		metaStorage:       metaStorage,
+		localStateDriver:  localStateDriver,
 		streamTableNames:  streamTableNames,
 		configPath:        configPath,
+		stateSaveRetries:  stateSaveRetries,
+		storeConcurrency:  storeConcurrency,
 	}
 }
 
 //Consume consumes result batch and writes it to destinations and saves the State
+//streams are stored concurrently (according to storeConcurrency). The State is saved only after all streams have been stored
 func (rs *ResultSaver) Consume(representation *driversbase.CLIOutputRepresentation) error {
+	store := newConcurrentStore(rs.storeConcurrency)
 	for streamName, stream := range representation.Streams {
 		//airbyte can have empty objects
 		if len(stream.Objects) == 0 {
 			continue
 		}
 
-		tableName, ok := rs.streamTableNames[streamName]
-		if !ok {
-			tableName = rs.tableNamePrefix + streamName
-		}
-		stream.BatchHeader.TableName = schema.Reformat(tableName)
-
-		rs.taskLogger.INFO("Stream [%s] Table name [%s] key fields [%s] objects [%d]", streamName, tableName, strings.Join(stream.KeyFields, ","), len(stream.Objects))
-
-		//Note: we assume that destinations connected to 1 source can't have different unique ID configuration
-		uniqueIDField := rs.destinations[0].GetUniqueIDField()
-		stream.BatchHeader.Fields[uniqueIDField.GetFlatFieldName()] = schema.NewField(typing.STRING)
-		stream.BatchHeader.Fields[events.SrcKey] = schema.NewField(typing.STRING)
-		stream.BatchHeader.Fields[timestamp.Key] = schema.NewField(typing.TIMESTAMP)
-
-		for _, object := range stream.Objects {
-			//enrich with system fields values
-			object[events.SrcKey] = srcSource
-			object[timestamp.Key] = timestamp.NowUTC()
-
-			//calculate eventID from key fields or whole object
-			var eventID string
-			if len(stream.KeyFields) > 0 {
-				eventID = uuid.GetKeysHash(object, stream.KeyFields)
-			} else {
-				eventID = uuid.GetHash(object)
-			}
-
-			if err := uniqueIDField.Set(object, eventID); err != nil {
-				b, _ := json.Marshal(object)
-				return fmt.Errorf("Error setting unique ID field into %s: %v", string(b), err)
-			}
-		}
-
-		rowsCount := len(stream.Objects)
-		//Sync stream
-		for _, storage := range rs.destinations {
-			if stream.NeedClean {
-				err := storage.Clean(stream.BatchHeader.TableName)
-				if err != nil {
-					logging.Warnf("[%s] storage table %s cleaning failed, ignoring: %v", storage.ID(), stream.BatchHeader.TableName, err)
-				}
-				stream.NeedClean = false
-			}
-			err := storage.SyncStore(stream.BatchHeader, stream.Objects, "", false)
-			if err != nil {
-				errMsg := fmt.Sprintf("Error storing %d source objects in [%s] destination: %v", rowsCount, storage.ID(), err)
-				metrics.ErrorSourceEvents(rs.task.SourceType, rs.tap, rs.task.Source, storage.Type(), storage.ID(), rowsCount)
-				metrics.ErrorObjects(rs.task.SourceType, rs.tap, rs.task.Source, rowsCount)
-				telemetry.Error(rs.task.Source, storage.ID(), srcSource, rs.tap, rowsCount)
-				counters.ErrorPullDestinationEvents(storage.ID(), int64(rowsCount))
-				counters.ErrorPullSourceEvents(rs.task.Source, int64(rowsCount))
-				return errors.New(errMsg)
-			}
-
-			metrics.SuccessSourceEvents(rs.task.SourceType, rs.tap, rs.task.Source, storage.Type(), storage.ID(), rowsCount)
-			metrics.SuccessObjects(rs.task.SourceType, rs.tap, rs.task.Source, rowsCount)
-			telemetry.Event(rs.task.Source, storage.ID(), srcSource, rs.tap, rowsCount)
-			counters.SuccessPullDestinationEvents(storage.ID(), int64(rowsCount))
+		streamName, stream := streamName, stream
+		if err := store.run(func() error { return rs.storeStream(streamName, stream) }); err != nil {
+			store.wait()
+			return err
 		}
-
-		counters.SuccessPullSourceEvents(rs.task.Source, int64(rowsCount))
-
-		rs.taskLogger.INFO("Synchronized successfully Table [%s] key fields [%s] objects [%d]", tableName, strings.Join(stream.KeyFields, ","), len(stream.Objects))
+	}
+	if err := store.wait(); err != nil {
+		return err
 	}
 
 	//save state
@@ -133,9 +93,10 @@ func (rs *ResultSaver) Consume(representation *driversbase.CLIOutputRepresentati
 			return errors.New(errMsg)
 		}
 
-		err = rs.metaStorage.SaveSignature(rs.task.Source, rs.collectionMetaKey, driversbase.ALL.String(), string(stateJSON))
-		if err != nil {
-			errMsg := fmt.Sprintf("Unable to save source [%s] tap [%s] signature [%s]: %v", rs.task.Source, rs.tap, string(stateJSON), err)
+		//if the state isn't persisted the next sync will re-read data from the previous state (data will be duplicated)
+		//so the task must be failed
+		if err := rs.saveState(string(stateJSON)); err != nil {
+			errMsg := fmt.Sprintf("Unable to save source [%s] tap [%s] state [%s] after %d retries: %v", rs.task.Source, rs.tap, string(stateJSON), rs.stateSaveRetries, err)
 			logging.SystemError(errMsg)
 			return errors.New(errMsg)
 		}
@@ -156,6 +117,122 @@ func (rs *ResultSaver) Consume(representation *driversbase.CLIOutputRepresentati
 	return nil
 }
 
+//ConsumeConfig persists the connector config which has been updated during the sync into meta storage right away:
+//connector might invalidate previous credentials (e.g. rotated OAuth refresh token) so the config must survive
+//failed syncs, source reloads and be available on other cluster nodes
+func (rs *ResultSaver) ConsumeConfig(config map[string]interface{}) error {
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("Error marshalling source [%s] tap [%s] config: %v", rs.task.Source, rs.tap, err)
+	}
+
+	if err := rs.metaStorage.SaveSignature(rs.task.Source, rs.collectionMetaKey+ConfigSignatureSuffix, driversbase.ALL.String(), string(configBytes)); err != nil {
+		return fmt.Errorf("Unable to save source [%s] tap [%s] config: %v", rs.task.Source, rs.tap, err)
+	}
+
+	return nil
+}
+
+//storeStream enriches stream objects with system fields and stores them into all destinations
+func (rs *ResultSaver) storeStream(streamName string, stream *driversbase.StreamRepresentation) error {
+	tableName, ok := rs.streamTableNames[streamName]
+	if !ok {
+		tableName = rs.tableNamePrefix + streamName
+		//child stream table follows the parent stream table name (e.g. mapped parent stream)
+		if parentTableName, parentOk := rs.streamTableNames[stream.ParentStream]; stream.ParentStream != "" && parentOk {
+			tableName = parentTableName + strings.TrimPrefix(streamName, stream.ParentStream)
+		}
+	}
+	stream.BatchHeader.TableName = schema.Reformat(tableName)
+
+	rs.taskLogger.INFO("Stream [%s] Table name [%s] key fields [%s] objects [%d]", streamName, tableName, strings.Join(stream.KeyFields, ","), len(stream.Objects))
+
+	//Note: we assume that destinations connected to 1 source can't have different unique ID configuration
+	uniqueIDField := rs.destinations[0].GetUniqueIDField()
+	stream.BatchHeader.Fields[uniqueIDField.GetFlatFieldName()] = schema.NewField(typing.STRING)
+	stream.BatchHeader.Fields[events.SrcKey] = schema.NewField(typing.STRING)
+	stream.BatchHeader.Fields[timestamp.Key] = schema.NewField(typing.TIMESTAMP)
+
+	for _, object := range stream.Objects {
+		//enrich with system fields values
+		object[events.SrcKey] = srcSource
+		object[timestamp.Key] = timestamp.NowUTC()
+
+		//calculate eventID from key fields or whole object
+		var eventID string
+		if len(stream.KeyFields) > 0 {
+			eventID = uuid.GetKeysHash(object, stream.KeyFields)
+		} else {
+			eventID = uuid.GetHash(object)
+		}
+
+		if err := uniqueIDField.Set(object, eventID); err != nil {
+			b, _ := json.Marshal(object)
+			return fmt.Errorf("Error setting unique ID field into %s: %v", string(b), err)
+		}
+	}
+
+	rowsCount := len(stream.Objects)
+	//Sync stream
+	for _, storage := range rs.destinations {
+		if stream.NeedClean {
+			err := storage.Clean(stream.BatchHeader.TableName)
+			if err != nil {
+				logging.Warnf("[%s] storage table %s cleaning failed, ignoring: %v", storage.ID(), stream.BatchHeader.TableName, err)
+			}
+			stream.NeedClean = false
+		}
+		err := syncStore(storage, stream.BatchHeader, stream.Objects, "")
+		if err != nil {
+			errMsg := fmt.Sprintf("Error storing %d source objects in [%s] destination: %v", rowsCount, storage.ID(), err)
+			metrics.ErrorSourceEvents(rs.task.SourceType, rs.tap, rs.task.Source, storage.Type(), storage.ID(), rowsCount)
+			metrics.ErrorObjects(rs.task.SourceType, rs.tap, rs.task.Source, rowsCount)
+			telemetry.Error(rs.task.Source, storage.ID(), srcSource, rs.tap, rowsCount)
+			counters.ErrorPullDestinationEvents(storage.ID(), int64(rowsCount))
+			counters.ErrorPullSourceEvents(rs.task.Source, int64(rowsCount))
+			return errors.New(errMsg)
+		}
+
+		metrics.SuccessSourceEvents(rs.task.SourceType, rs.tap, rs.task.Source, storage.Type(), storage.ID(), rowsCount)
+		metrics.SuccessObjects(rs.task.SourceType, rs.tap, rs.task.Source, rowsCount)
+		telemetry.Event(rs.task.Source, storage.ID(), srcSource, rs.tap, rowsCount)
+		counters.SuccessPullDestinationEvents(storage.ID(), int64(rowsCount))
+	}
+
+	counters.SuccessPullSourceEvents(rs.task.Source, int64(rowsCount))
+
+	rs.taskLogger.INFO("Synchronized successfully Table [%s] key fields [%s] objects [%d]", tableName, strings.Join(stream.KeyFields, ","), len(stream.Objects))
+	return nil
+}
+
+//saveState writes the state into meta storage and into the local state file (if local state driver is configured)
+//retries stateSaveRetries times with growing delay
+func (rs *ResultSaver) saveState(state string) error {
+	var err error
+	for attempt := 0; attempt <= rs.stateSaveRetries; attempt++ {
+		if attempt > 0 {
+			rs.taskLogger.WARN("Retrying to save state (attempt %d of %d) after error: %v", attempt, rs.stateSaveRetries, err)
+			time.Sleep(time.Duration(attempt) * stateSaveRetryDelay)
+		}
+
+		if err = rs.metaStorage.SaveSignature(rs.task.Source, rs.collectionMetaKey, driversbase.ALL.String(), state); err != nil {
+			err = fmt.Errorf("error saving signature: %v", err)
+			continue
+		}
+
+		if rs.localStateDriver != nil {
+			if err = rs.localStateDriver.SaveLocalState(state); err != nil {
+				err = fmt.Errorf("error saving local state: %v", err)
+				continue
+			}
+		}
+
+		return nil
+	}
+
+	return err
+}
+
 func (rs *ResultSaver) Tap() string {
 	return rs.tap
 }
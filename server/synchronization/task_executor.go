@@ -43,11 +43,15 @@ type TaskExecutor struct {
 	stalledThreshold      time.Duration
 	lastActivityThreshold time.Duration
 	observerStalledEvery  time.Duration
-	closed                *atomic.Bool
+	//stateSaveRetries is a number of retries of failed CLI sources state persisting
+	stateSaveRetries int
+	//storeConcurrency is a max number of pulled data chunks which are stored into a destination concurrently
+	storeConcurrency int
+	closed           *atomic.Bool
 }
 
 //NewTaskExecutor returns TaskExecutor and starts 2 goroutines (monitoring and queue observer)
-func NewTaskExecutor(poolSize, stalledThresholdSeconds, stalledLastActivityThresholdMinutes, observeStalledTaskEverySeconds int,
+func NewTaskExecutor(poolSize, stalledThresholdSeconds, stalledLastActivityThresholdMinutes, observeStalledTaskEverySeconds, stateSaveRetries, storeConcurrency int,
 	sourceService *sources.Service, destinationService *destinations.Service, metaStorage meta.Storage, coordinationService *coordination.Service) (*TaskExecutor, error) {
 	executor := &TaskExecutor{
 		sourceService:         sourceService,
@@ -57,6 +61,8 @@ func NewTaskExecutor(poolSize, stalledThresholdSeconds, stalledLastActivityThres
 		stalledThreshold:      time.Duration(stalledThresholdSeconds) * time.Second,
 		lastActivityThreshold: time.Duration(stalledLastActivityThresholdMinutes) * time.Minute,
 		observerStalledEvery:  time.Duration(observeStalledTaskEverySeconds) * time.Second,
+		stateSaveRetries:      stateSaveRetries,
+		storeConcurrency:      storeConcurrency,
 		closed:                atomic.NewBool(false),
 	}
 	pool, err := ants.NewPoolWithFunc(poolSize, executor.execute)
@@ -416,8 +422,11 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 
 	collectionTableName := driver.GetCollectionTable()
 	reformattedTableName := schema.Reformat(collectionTableName)
+	//intervals are stored concurrently: every interval is stored (with deletion of the previous interval data) separately
+	store := newConcurrentStore(te.storeConcurrency)
 	for _, intervalToSync := range intervalsToSync {
 		if err := taskCloser.HandleCanceling(); err != nil {
+			store.wait()
 			return err
 		}
 
@@ -425,6 +434,7 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 
 		objects, err := driver.GetObjectsFor(intervalToSync)
 		if err != nil {
+			store.wait()
 			return fmt.Errorf("Error [%s] synchronization: %v", intervalToSync.String(), err)
 		}
 
@@ -436,38 +446,55 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 			object[timestamp.Key] = timestamp.NowUTC()
 			if err := uniqueIDField.Set(object, uuid.GetHash(object)); err != nil {
 				b, _ := json.Marshal(object)
+				store.wait()
 				return fmt.Errorf("Error setting unique ID field into %s: %v", string(b), err)
 			}
 			events.EnrichWithCollection(object, task.Collection)
 			events.EnrichWithTimeInterval(object, intervalToSync.String(), intervalToSync.LowerEndpoint(), intervalToSync.UpperEndpoint())
 		}
-		rowsCount := len(objects)
-		for _, storage := range destinationStorages {
-			err := storage.SyncStore(&schema.BatchHeader{TableName: reformattedTableName}, objects, intervalToSync.String(), false)
-			if err != nil {
-				metrics.ErrorSourceEvents(task.SourceType, metrics.EmptySourceTap, task.Source, storage.Type(), storage.ID(), rowsCount)
-				metrics.ErrorObjects(task.SourceType, metrics.EmptySourceTap, task.Source, rowsCount)
-				telemetry.Error(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
-				counters.ErrorPullDestinationEvents(storage.ID(), int64(rowsCount))
-				counters.ErrorPullSourceEvents(task.Source, int64(rowsCount))
-				return fmt.Errorf("Error storing %d source objects in [%s] destination: %v. All %d objects haven't been stored", rowsCount, storage.ID(), err, rowsCount)
-			}
 
-			metrics.SuccessSourceEvents(task.SourceType, metrics.EmptySourceTap, task.Source, storage.Type(), storage.ID(), rowsCount)
-			metrics.SuccessObjects(task.SourceType, metrics.EmptySourceTap, task.Source, rowsCount)
-			telemetry.Event(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
-			counters.SuccessPullDestinationEvents(storage.ID(), int64(rowsCount))
+		interval := intervalToSync
+		err = store.run(func() error {
+			return te.storeInterval(task, taskLogger, driver, destinationStorages, reformattedTableName, interval, objects, now, refreshWindow)
+		})
+		if err != nil {
+			store.wait()
+			return err
 		}
+	}
 
-		counters.SuccessPullSourceEvents(task.Source, int64(rowsCount))
+	return store.wait()
+}
 
-		if err := te.metaStorage.SaveSignature(task.Source, collectionMetaKey, intervalToSync.String(), intervalToSync.CalculateSignatureFrom(now, refreshWindow)); err != nil {
-			logging.SystemErrorf("Unable to save source: [%s] collection: [%s] meta key: [%s] signature: %v", task.Source, task.Collection, collectionMetaKey, err)
+//storeInterval stores interval objects into all destinations and saves the interval signature
+func (te *TaskExecutor) storeInterval(task *meta.Task, taskLogger *TaskLogger, driver driversbase.Driver, destinationStorages []storages.Storage,
+	tableName string, interval *driversbase.TimeInterval, objects []map[string]interface{}, now time.Time, refreshWindow time.Duration) error {
+	rowsCount := len(objects)
+	for _, storage := range destinationStorages {
+		err := syncStore(storage, &schema.BatchHeader{TableName: tableName}, objects, interval.String())
+		if err != nil {
+			metrics.ErrorSourceEvents(task.SourceType, metrics.EmptySourceTap, task.Source, storage.Type(), storage.ID(), rowsCount)
+			metrics.ErrorObjects(task.SourceType, metrics.EmptySourceTap, task.Source, rowsCount)
+			telemetry.Error(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
+			counters.ErrorPullDestinationEvents(storage.ID(), int64(rowsCount))
+			counters.ErrorPullSourceEvents(task.Source, int64(rowsCount))
+			return fmt.Errorf("Error storing %d source objects of interval [%s] in [%s] destination: %v. All %d objects haven't been stored", rowsCount, interval.String(), storage.ID(), err, rowsCount)
 		}
 
-		taskLogger.INFO("Interval [%s] has been synchronized!", intervalToSync.String())
+		metrics.SuccessSourceEvents(task.SourceType, metrics.EmptySourceTap, task.Source, storage.Type(), storage.ID(), rowsCount)
+		metrics.SuccessObjects(task.SourceType, metrics.EmptySourceTap, task.Source, rowsCount)
+		telemetry.Event(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
+		counters.SuccessPullDestinationEvents(storage.ID(), int64(rowsCount))
 	}
 
+	counters.SuccessPullSourceEvents(task.Source, int64(rowsCount))
+
+	collectionMetaKey := driver.GetCollectionMetaKey()
+	if err := te.metaStorage.SaveSignature(task.Source, collectionMetaKey, interval.String(), interval.CalculateSignatureFrom(now, refreshWindow)); err != nil {
+		logging.SystemErrorf("Unable to save source: [%s] collection: [%s] meta key: [%s] signature: %v", task.Source, task.Collection, collectionMetaKey, err)
+	}
+
+	taskLogger.INFO("Interval [%s] has been synchronized!", interval.String())
 	return nil
 }
 
@@ -486,6 +513,18 @@ func (te *TaskExecutor) syncCLI(task *meta.Task, taskLogger *TaskLogger, cliDriv
 		return fmt.Errorf("Error getting persisted config from meta storage: %v", err)
 	}
 
+	localStateDriver, _ := cliDriver.(driversbase.LocalStateDriver)
+	if state == "" && localStateDriver != nil {
+		state, err = te.loadLocalState(task, taskLogger, cliDriver, localStateDriver)
+		if err != nil {
+			return err
+		}
+	}
+	//local state is used only if meta storage isn't configured
+	if te.metaStorage.Type() != meta.DummyType {
+		localStateDriver = nil
+	}
+
 	if state != "" {
 		taskLogger.INFO("Running synchronization with state: %s", state)
 	} else {
@@ -495,12 +534,13 @@ func (te *TaskExecutor) syncCLI(task *meta.Task, taskLogger *TaskLogger, cliDriv
 		taskLogger.INFO("Loaded persisted config from meta storage.")
 	}
 
-	rs := NewResultSaver(task, cliDriver.GetTap(), cliDriver.GetCollectionMetaKey(), cliDriver.GetTableNamePrefix(), taskLogger, destinationStorages, te.metaStorage, cliDriver.GetStreamTableNameMapping(), cliDriver.GetConfigPath())
+	rs := NewResultSaver(task, cliDriver.GetTap(), cliDriver.GetCollectionMetaKey(), cliDriver.GetTableNamePrefix(), taskLogger, destinationStorages, te.metaStorage, localStateDriver, cliDriver.GetStreamTableNameMapping(), cliDriver.GetConfigPath(), te.stateSaveRetries, te.storeConcurrency)
 
 	err = cliDriver.Load(config, state, taskLogger, rs, taskCloser)
 	if err != nil {
-		if err == ErrTaskHasBeenCanceled {
-			return err
+		//the sync has been stopped by the cancel task request: the task status is changed by the request handler
+		if err == ErrTaskHasBeenCanceled || err == driversbase.ErrSyncTaskCanceled {
+			return ErrTaskHasBeenCanceled
 		}
 
 		return fmt.Errorf("Error synchronization: %v", err)
@@ -509,6 +549,30 @@ func (te *TaskExecutor) syncCLI(task *meta.Task, taskLogger *TaskLogger, cliDriv
 	return nil
 }
 
+//loadLocalState returns state from the local file if exists
+//if meta storage is configured - migrates the local state into it
+func (te *TaskExecutor) loadLocalState(task *meta.Task, taskLogger *TaskLogger, cliDriver driversbase.CLIDriver, localStateDriver driversbase.LocalStateDriver) (string, error) {
+	state, err := localStateDriver.LoadLocalState()
+	if err != nil {
+		return "", err
+	}
+
+	if state == "" || te.metaStorage.Type() == meta.DummyType {
+		return state, nil
+	}
+
+	taskLogger.INFO("Migrating local state into meta storage: %s", state)
+	if err := te.metaStorage.SaveSignature(task.Source, cliDriver.GetCollectionMetaKey(), driversbase.ALL.String(), state); err != nil {
+		return "", fmt.Errorf("Error migrating local state into meta storage: %v", err)
+	}
+
+	if err := localStateDriver.ClearLocalState(); err != nil {
+		logging.SystemErrorf("[%s] error clearing migrated local state: %v", task.Source, err)
+	}
+
+	return state, nil
+}
+
 func (te *TaskExecutor) Close() error {
 	te.closed.Store(true)
 
@@ -14,6 +14,7 @@ import (
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/dlq"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
@@ -138,7 +139,7 @@ func NewSuiteBuilder(t *testing.T) SuiteBuilder {
 		recognitionService:               dummyRecognitionService,
 		destinationService:               destinationService,
 		systemService:                    systemService,
-		eventsCache:                      caching.NewEventsCache(true, metaStorage, 100, 1, 100),
+		eventsCache:                      caching.NewEventsCache(true, metaStorage, 100, 1, 100, 1000),
 		geoService:                       geo.NewTestService(nil),
 	}
 }
@@ -169,7 +170,7 @@ func (sb *suiteBuilder) WithMetaStorage(t *testing.T) SuiteBuilder {
 
 	sb.metaStorage = metaStorage
 
-	sb.eventsCache = caching.NewEventsCache(true, metaStorage, 100, 1, 100)
+	sb.eventsCache = caching.NewEventsCache(true, metaStorage, 100, 1, 100, 1000)
 	return sb
 }
 
@@ -219,7 +220,7 @@ func (sb *suiteBuilder) Build(t *testing.T) Suit {
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
 	router := routers.SetupRouter("", sb.metaStorage, sb.destinationService, sources.NewTestService(), synchronization.NewTestTaskService(),
-		fallback.NewTestService(), coordination.NewInMemoryService(""), sb.eventsCache, sb.systemService,
+		fallback.NewTestService(), dlq.NewService("/tmp"), coordination.NewInMemoryService(""), sb.eventsCache, sb.systemService,
 		sb.segmentRequestFieldsMapper, sb.segmentCompatRequestFieldsMapper, processorHolder, multiplexingService, walService, sb.geoService, nil)
 
 	server := &http.Server{
@@ -0,0 +1,68 @@
+package timestamp
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//unixMillisThreshold is a boundary between unix time numbers in seconds and in milliseconds
+//(1e11 seconds is the year 5138)
+const unixMillisThreshold = 1e11
+
+//stringLayouts are layouts of string event timestamps in the parsing order
+var stringLayouts = []string{Layout, time.RFC3339Nano, LogsLayout, GolangLayout, DashDayLayout}
+
+//FromField returns UTC time from the object field value (see ParseValue)
+//returns false if the field is missing or the value can't be parsed
+func FromField(object map[string]interface{}, field string) (time.Time, bool) {
+	value, ok := object[field]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return ParseValue(value)
+}
+
+//ParseValue returns UTC time from the event timestamp value of any supported format:
+//time.Time, strings (ISO, RFC3339, '2006-01-02 15:04:05' or '2006-01-02') and unix time numbers (seconds or milliseconds)
+//returns false if the value can't be parsed
+func ParseValue(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC(), true
+	case *time.Time:
+		if v != nil {
+			return v.UTC(), true
+		}
+	case string:
+		for _, layout := range stringLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.UTC(), true
+			}
+		}
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return fromUnix(f), true
+		}
+	case float64:
+		return fromUnix(v), true
+	case float32:
+		return fromUnix(float64(v)), true
+	case int:
+		return fromUnix(float64(v)), true
+	case int64:
+		return fromUnix(float64(v)), true
+	}
+
+	return time.Time{}, false
+}
+
+//fromUnix returns UTC time from unix time in seconds or milliseconds
+func fromUnix(value float64) time.Time {
+	if value >= unixMillisThreshold || value <= -unixMillisThreshold {
+		return time.UnixMilli(int64(value)).UTC()
+	}
+
+	seconds := int64(value)
+	return time.Unix(seconds, int64((value-float64(seconds))*float64(time.Second))).UTC()
+}
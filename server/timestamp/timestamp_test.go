@@ -1,7 +1,9 @@
 package timestamp
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -18,3 +20,44 @@ func TestFreezing(t *testing.T) {
 
 	require.NotEqual(t, frozenTime, Now(), "Now() should provide real time after unfreezing")
 }
+
+func TestParseValue(t *testing.T) {
+	expected := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"time.Time", expected},
+		{"time.Time in other timezone", expected.In(time.FixedZone("UTC+3", 3*60*60))},
+		{"pointer", &expected},
+		{"ISO", "2024-01-02T03:04:05.000000Z"},
+		{"RFC3339 with offset", "2024-01-02T06:04:05+03:00"},
+		{"logs layout", "2024-01-02 03:04:05"},
+		{"unix seconds", float64(expected.Unix())},
+		{"unix seconds int", int(expected.Unix())},
+		{"unix milliseconds", expected.UnixMilli()},
+		{"json number", json.Number("1704164645")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, ok := ParseValue(tt.value)
+			require.True(t, ok)
+			require.Equal(t, expected, actual)
+		})
+	}
+
+	day, ok := ParseValue("2024-01-02")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), day)
+
+	for _, malformed := range []interface{}{"yesterday", true, nil, map[string]interface{}{}} {
+		_, ok := ParseValue(malformed)
+		require.False(t, ok, "%v shouldn't be parsed", malformed)
+	}
+
+	_, ok = FromField(map[string]interface{}{"created_at": "2024-01-02 03:04:05"}, "updated_at")
+	require.False(t, ok, "missing field shouldn't be parsed")
+	actual, ok := FromField(map[string]interface{}{"created_at": "2024-01-02 03:04:05"}, "created_at")
+	require.True(t, ok)
+	require.Equal(t, expected, actual)
+}
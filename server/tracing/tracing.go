@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName      = "github.com/jitsucom/jitsu/server"
+	shutdownTimeout = 5 * time.Second
+
+	destinationIDKey = attribute.Key("jitsu.destination_id")
+	sourceIDKey      = attribute.Key("jitsu.source_id")
+	tableKey         = attribute.Key("jitsu.table")
+	dockerImageKey   = attribute.Key("jitsu.docker_image")
+)
+
+//Service is an OpenTelemetry tracer provider with OTLP HTTP exporter
+type Service struct {
+	provider *sdktrace.TracerProvider
+}
+
+//Init configures global OpenTelemetry tracer provider with OTLP HTTP exporter from server.tracing section
+//returns nil if otlp_endpoint isn't configured: spans are no-op by default
+func Init(ctx context.Context, serviceName string, viper *viper.Viper) (*Service, error) {
+	if viper == nil || viper.GetString("otlp_endpoint") == "" {
+		return nil, nil
+	}
+
+	endpoint := viper.GetString("otlp_endpoint")
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if viper.GetBool("insecure") {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating OTLP exporter: %v", err)
+	}
+
+	sampleRatio := 1.0
+	if viper.IsSet("sample_ratio") {
+		sampleRatio = viper.GetFloat64("sample_ratio")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	logging.Infof("Tracing is enabled. OTLP endpoint: %s, sample ratio: %v", endpoint, sampleRatio)
+	return &Service{provider: provider}, nil
+}
+
+//StartSpan starts a span which is a child of the span from ctx (if exists)
+//it is a no-op span if tracing isn't configured
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+//EndSpan records err (if not nil) and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+//DestinationID returns destination id span attribute
+func DestinationID(destinationID string) attribute.KeyValue {
+	return destinationIDKey.String(destinationID)
+}
+
+//SourceID returns source id span attribute
+func SourceID(sourceID string) attribute.KeyValue {
+	return sourceIDKey.String(sourceID)
+}
+
+//Table returns table name span attribute
+func Table(tableName string) attribute.KeyValue {
+	return tableKey.String(tableName)
+}
+
+//DockerImage returns docker image span attribute
+func DockerImage(dockerImage string) attribute.KeyValue {
+	return dockerImageKey.String(dockerImage)
+}
+
+//Close flushes buffered spans and shuts down the tracer provider
+func (s *Service) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("Error shutting down tracer provider: %v", err)
+	}
+
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/storages"
 	"go.uber.org/atomic"
+	"sort"
 	"sync"
 )
 
@@ -203,6 +204,18 @@ func (rs *RecognitionService) reprocessAnonymousEvents(destinationID string, ide
 		return nil
 	}
 
+	if batchUpdater, ok := storage.(storages.BatchUpdater); ok && len(eventsMap) > 1 {
+		err := rs.reprocessAnonymousEventsBatch(destinationID, batchUpdater, configuration, identifiers, eventsMap)
+		if err == nil {
+			return nil
+		}
+		if storages.IsConnectionError(err) {
+			return err
+		}
+
+		logging.Warnf("[%s] Error updating %d recognition events with batch: %v. Events will be updated one by one", destinationID, len(eventsMap), err)
+	}
+
 	for storedEventID, storedSerializedEvent := range eventsMap {
 		event, err := rs.deserialize(storedSerializedEvent)
 		if err != nil {
@@ -216,7 +229,7 @@ func (rs *RecognitionService) reprocessAnonymousEvents(destinationID string, ide
 			continue
 		}
 
-		if err := storage.Update(event); err != nil {
+		if err := updateRecognizedEvent(storage, event, identifiers.IdentificationValues); err != nil {
 			if storages.IsConnectionError(err) {
 				return err
 			}
@@ -248,6 +261,74 @@ func (rs *RecognitionService) reprocessAnonymousEvents(destinationID string, ide
 	return nil
 }
 
+//reprocessAnonymousEventsBatch updates all anonymous events with recognized identification values using batchUpdater
+//and deletes them from the storage. Events which can't be deserialized or enriched are skipped (kept in the storage)
+func (rs *RecognitionService) reprocessAnonymousEventsBatch(destinationID string, batchUpdater storages.BatchUpdater, configuration *storages.UserRecognitionConfiguration,
+	identifiers EventIdentifiers, eventsMap map[string]string) error {
+	storedEventIDs := make([]string, 0, len(eventsMap))
+	events := make([]map[string]interface{}, 0, len(eventsMap))
+	for storedEventID, storedSerializedEvent := range eventsMap {
+		event, err := rs.deserialize(storedSerializedEvent)
+		if err != nil {
+			logging.SystemErrorf("[%s] error deserializing event [%s]: %v", destinationID, storedSerializedEvent, err)
+			continue
+		}
+
+		if err = configuration.IdentificationJSONPathes.Set(event, identifiers.IdentificationValues); err != nil {
+			logging.Errorf("[%s] Error setting recognized user id into event: %s with json path rule [%s]: %v",
+				destinationID, storedSerializedEvent, configuration.IdentificationJSONPathes.String(), err)
+			continue
+		}
+
+		storedEventIDs = append(storedEventIDs, storedEventID)
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := batchUpdater.UpdateBatch(events, recognizedFields(identifiers.IdentificationValues)); err != nil {
+		return err
+	}
+
+	for _, storedEventID := range storedEventIDs {
+		if err := rs.storage.DeleteAnonymousEvent(destinationID, identifiers.AnonymousID, storedEventID); err != nil {
+			return fmt.Errorf("error deleting anonymous events id [%s]: %v", storedEventID, err)
+		}
+
+		rs.mutex.Lock()
+		delete(rs.eventRetries, storedEventID)
+		rs.mutex.Unlock()
+	}
+
+	return nil
+}
+
+//updateRecognizedEvent updates only identification columns if the storage supports patching
+//otherwise updates the whole event
+func updateRecognizedEvent(storage storages.Storage, event map[string]interface{}, identificationValues map[string]interface{}) error {
+	patchUpdater, ok := storage.(storages.PatchUpdater)
+	if !ok {
+		return storage.Update(event)
+	}
+
+	return patchUpdater.Patch(event, recognizedFields(identificationValues))
+}
+
+//recognizedFields returns sorted JSON paths of filled identification values
+func recognizedFields(identificationValues map[string]interface{}) []string {
+	changedFields := make([]string, 0, len(identificationValues))
+	for path, value := range identificationValues {
+		if value != nil {
+			changedFields = append(changedFields, path)
+		}
+	}
+	sort.Strings(changedFields)
+
+	return changedFields
+}
+
 func (rs *RecognitionService) processRecognitionPayload(rp *RecognitionPayload) error {
 	destinationIdentifiers := rs.getDestinationsForRecognition(rp.Event, rp.EventID, rp.DestinationIDs)
 
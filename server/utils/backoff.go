@@ -0,0 +1,51 @@
+package utils
+
+import "time"
+
+//Backoff calculates exponentially increasing delays: initial*2, initial*4, initial*8, ... but not more than max
+//it isn't thread-safe
+type Backoff struct {
+	initial  time.Duration
+	max      time.Duration
+	attempts int
+}
+
+//NewBackoff returns Backoff instance. max is ignored if it is less than initial
+func NewBackoff(initial, max time.Duration) *Backoff {
+	if max < initial {
+		max = initial
+	}
+	return &Backoff{initial: initial, max: max}
+}
+
+//Next increments attempts count and returns the next delay
+func (b *Backoff) Next() time.Duration {
+	b.attempts++
+	return ExponentialDelay(b.initial, b.attempts, b.max)
+}
+
+//Reset resets attempts count: the next delay will be initial*2
+func (b *Backoff) Reset() {
+	b.attempts = 0
+}
+
+//Attempts returns count of Next calls since the last Reset
+func (b *Backoff) Attempts() int {
+	return b.attempts
+}
+
+//ExponentialDelay returns base * 2^attempt but not more than max
+func ExponentialDelay(base time.Duration, attempt int, max time.Duration) time.Duration {
+	delay := base
+	for i := 0; i < attempt; i++ {
+		if delay >= max/2 {
+			return max
+		}
+		delay *= 2
+	}
+
+	if delay > max {
+		return max
+	}
+	return delay
+}
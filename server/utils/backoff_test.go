@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	backoff := NewBackoff(time.Second, 10*time.Second)
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delays = append(delays, backoff.Next())
+	}
+	require.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}, delays)
+	require.Equal(t, 5, backoff.Attempts())
+
+	backoff.Reset()
+	require.Equal(t, 0, backoff.Attempts())
+	require.Equal(t, 2*time.Second, backoff.Next())
+
+	//max less than initial
+	require.Equal(t, time.Minute, NewBackoff(time.Minute, time.Second).Next())
+
+	//overflow protection
+	require.Equal(t, time.Hour, ExponentialDelay(time.Second, 100, time.Hour))
+}